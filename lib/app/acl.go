@@ -243,6 +243,45 @@ func (r *ApplicationsACL) FetchIndexFile() (io.Reader, error) {
 	return r.applications.FetchIndexFile()
 }
 
+// FetchRepositoryIndexFile returns index file data of the specified chart repository.
+func (r *ApplicationsACL) FetchRepositoryIndexFile(repository string) (io.Reader, error) {
+	if err := r.check(repository, teleservices.VerbRead); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return r.applications.FetchRepositoryIndexFile(repository)
+}
+
+// FetchChartProvenance returns the provenance file of the specified chart.
+func (r *ApplicationsACL) FetchChartProvenance(locator loc.Locator) (io.Reader, error) {
+	if err := r.checkApp(locator, teleservices.VerbRead); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return r.applications.FetchChartProvenance(locator)
+}
+
+// UploadChart creates an application from the uploaded Helm chart archive
+// and adds it to the index of the chart repository
+func (r *ApplicationsACL) UploadChart(req UploadChartRequest) (*Application, error) {
+	if err := r.check(req.Repository, teleservices.VerbCreate); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if req.Force {
+		if err := r.check(req.Repository, teleservices.VerbUpdate); err != nil {
+			return nil, trace.Wrap(err)
+		}
+	}
+	return r.applications.UploadChart(req)
+}
+
+// UploadChartProvenance stores the provenance file for a chart
+// previously uploaded to the specified repository
+func (r *ApplicationsACL) UploadChartProvenance(repository string, provenance io.Reader) error {
+	if err := r.check(repository, teleservices.VerbUpdate); err != nil {
+		return trace.Wrap(err)
+	}
+	return r.applications.UploadChartProvenance(repository, provenance)
+}
+
 // check checks whether the user has the requested permissions to read write apps
 func (r *ApplicationsACL) check(repoName, verb string) error {
 	return r.checker.CheckAccessToRule(r.repoContext(repoName), teledefaults.Namespace, storage.KindApp, verb, false)
@@ -115,6 +115,43 @@ type Applications interface {
 
 	// FetchIndexFile returns Helm chart repository index file data.
 	FetchIndexFile() (io.Reader, error)
+
+	// FetchRepositoryIndexFile returns index file data of the specified chart repository.
+	FetchRepositoryIndexFile(repository string) (io.Reader, error)
+
+	// FetchChartProvenance returns the provenance file of the specified chart.
+	FetchChartProvenance(loc.Locator) (io.Reader, error)
+
+	// UploadChart creates an application from the uploaded Helm chart archive
+	// and adds it to the index of the chart repository
+	UploadChart(UploadChartRequest) (*Application, error)
+
+	// UploadChartProvenance stores the provenance file for a chart
+	// previously uploaded to the specified repository
+	UploadChartProvenance(repository string, provenance io.Reader) error
+}
+
+// UploadChartRequest describes a request to upload a Helm chart
+type UploadChartRequest struct {
+	// Repository is the chart repository to upload the chart to
+	Repository string
+	// Chart streams the chart archive
+	Chart io.Reader
+	// Provenance optionally streams the chart provenance file
+	Provenance io.Reader
+	// Force replaces the existing chart with the same name and version
+	Force bool
+}
+
+// Check validates this request
+func (r UploadChartRequest) Check() error {
+	if r.Repository == "" {
+		return trace.BadParameter("missing parameter Repository")
+	}
+	if r.Chart == nil {
+		return trace.BadParameter("missing parameter Chart")
+	}
+	return nil
 }
 
 // ListAppsRequest is a request to show applications in a repository
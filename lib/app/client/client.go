@@ -30,6 +30,7 @@ import (
 
 	"github.com/gravitational/gravity/lib/app"
 	serviceapi "github.com/gravitational/gravity/lib/app/api"
+	"github.com/gravitational/gravity/lib/defaults"
 	"github.com/gravitational/gravity/lib/httplib"
 	"github.com/gravitational/gravity/lib/loc"
 	"github.com/gravitational/gravity/lib/storage"
@@ -385,9 +386,13 @@ func (c *Client) DeleteAppHookJob(ctx context.Context, ref app.HookRef) error {
 // FetchChart returns Helm chart package with the specified application.
 //
 // GET charts/:name
+// GET helm/:repository/charts/:name
 func (c *Client) FetchChart(locator loc.Locator) (io.ReadCloser, error) {
-	return c.getFile(c.Endpoint("charts", helmutils.ToChartFilename(
-		locator.Name, locator.Version)), url.Values{})
+	filename := helmutils.ToChartFilename(locator.Name, locator.Version)
+	if locator.Repository != defaults.SystemAccountOrg {
+		return c.getFile(c.Endpoint("helm", locator.Repository, "charts", filename), url.Values{})
+	}
+	return c.getFile(c.Endpoint("charts", filename), url.Values{})
 }
 
 // FetchIndexFile returns Helm chart repository index file data.
@@ -397,6 +402,70 @@ func (c *Client) FetchIndexFile() (io.Reader, error) {
 	return c.getFile(c.Endpoint("charts", "index.yaml"), url.Values{})
 }
 
+// FetchRepositoryIndexFile returns index file data of the specified chart repository.
+//
+// GET helm/:repository/index.yaml
+func (c *Client) FetchRepositoryIndexFile(repository string) (io.Reader, error) {
+	return c.getFile(c.Endpoint("helm", repository, "index.yaml"), url.Values{})
+}
+
+// FetchChartProvenance returns the provenance file of the specified chart.
+//
+// GET helm/:repository/charts/:name.prov
+func (c *Client) FetchChartProvenance(locator loc.Locator) (io.Reader, error) {
+	filename := helmutils.ToChartFilename(locator.Name, locator.Version) + ".prov"
+	return c.getFile(c.Endpoint("helm", locator.Repository, "charts", filename), url.Values{})
+}
+
+// UploadChart creates an application from the uploaded Helm chart archive
+// and adds it to the index of the chart repository
+//
+// POST helm/:repository/api/charts
+func (c *Client) UploadChart(req app.UploadChartRequest) (*app.Application, error) {
+	files := []roundtrip.File{{
+		Name:     "chart",
+		Filename: "chart.tgz",
+		Reader:   req.Chart,
+	}}
+	if req.Provenance != nil {
+		files = append(files, roundtrip.File{
+			Name:     "prov",
+			Filename: "chart.tgz.prov",
+			Reader:   req.Provenance,
+		})
+	}
+	endpoint := c.Endpoint("helm", req.Repository, "api", "charts")
+	if req.Force {
+		endpoint += "?force=true"
+	}
+	out, err := c.PostForm(endpoint, url.Values{}, files...)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var app app.Application
+	if err = json.Unmarshal(out.Bytes(), &app); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &app, nil
+}
+
+// UploadChartProvenance stores the provenance file for a chart
+// previously uploaded to the specified repository
+//
+// POST helm/:repository/api/prov
+func (c *Client) UploadChartProvenance(repository string, provenance io.Reader) error {
+	_, err := c.PostForm(c.Endpoint("helm", repository, "api", "prov"), url.Values{},
+		roundtrip.File{
+			Name:     "prov",
+			Filename: "chart.tgz.prov",
+			Reader:   provenance,
+		})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return nil
+}
+
 // POST app/v1/applications/:repository_id
 func (c *Client) CreateApp(locator loc.Locator, reader io.Reader, labels map[string]string) (*app.Application, error) {
 	return c.createApp(locator, nil, reader, labels, false)
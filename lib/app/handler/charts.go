@@ -19,11 +19,16 @@ package handler
 import (
 	"io"
 	"net/http"
+	"strconv"
+	"strings"
 
+	"github.com/gravitational/gravity/lib/app"
 	"github.com/gravitational/gravity/lib/defaults"
 	"github.com/gravitational/gravity/lib/loc"
 	helmutils "github.com/gravitational/gravity/lib/utils/helm"
 
+	"github.com/gravitational/form"
+	"github.com/gravitational/roundtrip"
 	"github.com/gravitational/trace"
 	"github.com/julienschmidt/httprouter"
 )
@@ -76,7 +81,182 @@ func (h *WebHandler) fetchChart(w http.ResponseWriter, r *http.Request, p httpro
 	if err != nil {
 		return trace.Wrap(err)
 	}
-	reader, err := context.applications.FetchChart(*locator)
+	return writeChart(w, *locator, context)
+}
+
+/* getRepositoryIndexFile returns the index file of the specified chart repository.
+
+   GET /helm/:repository/index.yaml
+   GET /app/v1/helm/:repository/index.yaml
+*/
+func (h *WebHandler) getRepositoryIndexFile(w http.ResponseWriter, r *http.Request, p httprouter.Params, context *handlerContext) error {
+	reader, err := context.applications.FetchRepositoryIndexFile(p.ByName("repository"))
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	w.Header().Set("Content-Type", "application/yaml")
+	_, err = io.Copy(w, reader)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return nil
+}
+
+/* fetchRepositoryChart returns Helm chart archive or its provenance file
+   from the specified chart repository.
+
+   GET /helm/:repository/charts/:name
+   GET /app/v1/helm/:repository/charts/:name
+
+   The name parameter is either the chart archive filename formatted
+   as "<name>-<ver>.tgz" or the provenance filename formatted as
+   "<name>-<ver>.tgz.prov".
+*/
+func (h *WebHandler) fetchRepositoryChart(w http.ResponseWriter, r *http.Request, p httprouter.Params, context *handlerContext) error {
+	name := p.ByName("name")
+	if name == "" {
+		return trace.BadParameter("empty chart filename")
+	}
+	chartName, chartVersion, err := helmutils.ParseChartFilename(strings.TrimSuffix(name, provenanceSuffix))
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	locator, err := loc.NewLocator(p.ByName("repository"), chartName, chartVersion)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if !strings.HasSuffix(name, provenanceSuffix) {
+		return writeChart(w, *locator, context)
+	}
+	reader, err := context.applications.FetchChartProvenance(*locator)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	w.Header().Set("Content-Type", "application/pgp-signature")
+	_, err = io.Copy(w, reader)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return nil
+}
+
+/* uploadChart creates an application from the uploaded Helm chart archive.
+
+   POST /helm/:repository/api/charts
+   POST /app/v1/helm/:repository/api/charts
+
+   The request is compatible with ChartMuseum API used by "helm push":
+   the chart archive is either sent as the request body or as the "chart"
+   file of a multipart form with an optional provenance file in the "prov"
+   file. The optional "force" parameter replaces the existing chart.
+
+   Success response:
+
+   {
+     "package": application_package,
+     "manifest": application_manifest
+   }
+*/
+func (h *WebHandler) uploadChart(w http.ResponseWriter, r *http.Request, p httprouter.Params, context *handlerContext) error {
+	req := app.UploadChartRequest{
+		Repository: p.ByName("repository"),
+	}
+	if !isMultipartForm(r) {
+		req.Chart = r.Body
+	} else {
+		var charts, provenances form.Files
+		err := form.Parse(r,
+			form.FileSlice("chart", &charts),
+			form.FileSlice("prov", &provenances),
+		)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		defer charts.Close()
+		defer provenances.Close()
+		if len(charts) != 1 {
+			return trace.BadParameter("expected a single chart file but got %v", len(charts))
+		}
+		req.Chart = charts[0]
+		if len(provenances) > 1 {
+			return trace.BadParameter("expected a single provenance file but got %v", len(provenances))
+		}
+		if len(provenances) == 1 {
+			req.Provenance = provenances[0]
+		}
+	}
+	force, err := parseForce(r)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	req.Force = force
+	application, err := context.applications.UploadChart(req)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	roundtrip.ReplyJSON(w, http.StatusCreated, application)
+	return nil
+}
+
+/* uploadChartProvenance stores the provenance file for a previously
+   uploaded chart.
+
+   POST /helm/:repository/api/prov
+   POST /app/v1/helm/:repository/api/prov
+
+   The provenance file is either sent as the request body or as the "prov"
+   file of a multipart form.
+*/
+func (h *WebHandler) uploadChartProvenance(w http.ResponseWriter, r *http.Request, p httprouter.Params, context *handlerContext) error {
+	reader := io.Reader(r.Body)
+	if isMultipartForm(r) {
+		var provenances form.Files
+		if err := form.Parse(r, form.FileSlice("prov", &provenances)); err != nil {
+			return trace.Wrap(err)
+		}
+		defer provenances.Close()
+		if len(provenances) != 1 {
+			return trace.BadParameter("expected a single provenance file but got %v", len(provenances))
+		}
+		reader = provenances[0]
+	}
+	err := context.applications.UploadChartProvenance(p.ByName("repository"), reader)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	roundtrip.ReplyJSON(w, http.StatusCreated, "provenance saved")
+	return nil
+}
+
+/* deleteChart deletes the specified chart and removes it from
+   the repository index.
+
+   DELETE /helm/:repository/api/charts/:name/:version
+   DELETE /app/v1/helm/:repository/api/charts/:name/:version
+*/
+func (h *WebHandler) deleteChart(w http.ResponseWriter, r *http.Request, p httprouter.Params, context *handlerContext) error {
+	locator, err := loc.NewLocator(p.ByName("repository"), p.ByName("name"), p.ByName("version"))
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	force, err := parseForce(r)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	err = context.applications.DeleteApp(app.DeleteRequest{
+		Package: *locator,
+		Force:   force,
+	})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	roundtrip.ReplyJSON(w, http.StatusOK, "chart deleted")
+	return nil
+}
+
+// writeChart writes the archive of the chart specified with locator to w
+func writeChart(w http.ResponseWriter, locator loc.Locator, context *handlerContext) error {
+	reader, err := context.applications.FetchChart(locator)
 	if err != nil {
 		return trace.Wrap(err)
 	}
@@ -88,3 +268,24 @@ func (h *WebHandler) fetchChart(w http.ResponseWriter, r *http.Request, p httpro
 	}
 	return nil
 }
+
+// parseForce returns the value of the optional "force" request parameter
+func parseForce(r *http.Request) (bool, error) {
+	value := r.URL.Query().Get("force")
+	if value == "" {
+		_, ok := r.URL.Query()["force"]
+		return ok, nil
+	}
+	force, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, trace.BadParameter("invalid force parameter %q", value)
+	}
+	return force, nil
+}
+
+func isMultipartForm(r *http.Request) bool {
+	return strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data")
+}
+
+// provenanceSuffix is the filename suffix of chart provenance files
+const provenanceSuffix = ".prov"
@@ -112,6 +112,19 @@ func NewWebHandler(cfg WebHandlerConfig) (*WebHandler, error) {
 	h.GET("/charts/:name", h.needsAuth(h.fetchChart))
 	h.GET("/app/v1/charts/:name", h.needsAuth(h.fetchChart)) // Alias for /charts/:name for easier testing.
 
+	// Per-repository Helm chart repository handlers.
+	h.GET("/helm/:repository/index.yaml", h.needsAuth(h.getRepositoryIndexFile))
+	h.GET("/helm/:repository/charts/:name", h.needsAuth(h.fetchRepositoryChart))
+	h.POST("/helm/:repository/api/charts", h.needsAuth(h.uploadChart))
+	h.POST("/helm/:repository/api/prov", h.needsAuth(h.uploadChartProvenance))
+	h.DELETE("/helm/:repository/api/charts/:name/:version", h.needsAuth(h.deleteChart))
+	// Aliases for /helm/:repository handlers used by the app service client.
+	h.GET("/app/v1/helm/:repository/index.yaml", h.needsAuth(h.getRepositoryIndexFile))
+	h.GET("/app/v1/helm/:repository/charts/:name", h.needsAuth(h.fetchRepositoryChart))
+	h.POST("/app/v1/helm/:repository/api/charts", h.needsAuth(h.uploadChart))
+	h.POST("/app/v1/helm/:repository/api/prov", h.needsAuth(h.uploadChartProvenance))
+	h.DELETE("/app/v1/helm/:repository/api/charts/:name/:version", h.needsAuth(h.deleteChart))
+
 	return h, nil
 }
 
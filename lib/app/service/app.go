@@ -47,6 +47,7 @@ import (
 	"github.com/gravitational/trace"
 	log "github.com/sirupsen/logrus"
 	"k8s.io/client-go/kubernetes"
+	helmprovenance "k8s.io/helm/pkg/provenance"
 )
 
 // Config defines the set of configuration attributes for an application interface
@@ -782,6 +783,101 @@ func (r *applications) FetchIndexFile() (io.Reader, error) {
 	return r.Charts.GetIndexFile()
 }
 
+// FetchRepositoryIndexFile returns index file data of the specified chart repository.
+func (r *applications) FetchRepositoryIndexFile(repository string) (io.Reader, error) {
+	return r.Charts.GetRepositoryIndexFile(repository)
+}
+
+// FetchChartProvenance returns the provenance file of the specified chart.
+func (r *applications) FetchChartProvenance(locator loc.Locator) (io.Reader, error) {
+	return r.Charts.GetProvenance(locator)
+}
+
+// UploadChart creates an application from the uploaded Helm chart archive
+// and adds it to the index of the chart repository
+func (r *applications) UploadChart(req appservice.UploadChartRequest) (*appservice.Application, error) {
+	if err := req.Check(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if r.Charts == nil {
+		return nil, trace.NotImplemented("chart repository is not enabled")
+	}
+	chartPackage, err := helm.ConvertChart(req.Chart, req.Repository)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	defer chartPackage.Close()
+	var provenance *helm.Provenance
+	if req.Provenance != nil {
+		provenance, err = readProvenance(req.Provenance)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		if err := checkProvenance(*provenance, chartPackage.Locator, chartPackage.Digest); err != nil {
+			return nil, trace.Wrap(err)
+		}
+	}
+	r.Infof("Upload chart %v.", chartPackage.Locator)
+	app, err := r.createApp(chartPackage.Locator, chartPackage, chartPackage.Manifest, nil, "", req.Force)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if provenance != nil {
+		if err := r.Charts.UpsertProvenance(chartPackage.Locator, provenance.Data); err != nil {
+			return nil, trace.Wrap(err)
+		}
+	}
+	return app, nil
+}
+
+// UploadChartProvenance stores the provenance file for a chart
+// previously uploaded to the specified repository
+func (r *applications) UploadChartProvenance(repository string, reader io.Reader) error {
+	provenance, err := readProvenance(reader)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	locator, err := provenance.Locator(repository)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	chart, err := r.Charts.FetchChart(*locator)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	defer chart.Close()
+	digest, err := helmprovenance.Digest(chart)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if err := checkProvenance(*provenance, *locator, digest); err != nil {
+		return trace.Wrap(err)
+	}
+	return r.Charts.UpsertProvenance(*locator, provenance.Data)
+}
+
+func readProvenance(reader io.Reader) (*helm.Provenance, error) {
+	data, err := ioutil.ReadAll(io.LimitReader(reader, defaults.MaxChartArchiveSize))
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	provenance, err := helm.ParseProvenance(data)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return provenance, nil
+}
+
+// checkProvenance makes sure the provenance file describes the chart
+// with the specified locator and archive digest
+func checkProvenance(provenance helm.Provenance, locator loc.Locator, digest string) error {
+	if provenance.Metadata.Name != locator.Name || provenance.Metadata.Version != locator.Version {
+		return trace.BadParameter("provenance file is for chart %v:%v, not %v:%v",
+			provenance.Metadata.Name, provenance.Metadata.Version, locator.Name, locator.Version)
+	}
+	return trace.Wrap(provenance.Check(digest))
+}
+
 func (r *applications) resolveManifest(manifestBytes []byte) (*schema.Manifest, error) {
 	manifest, err := schema.ParseManifestYAMLNoValidate(manifestBytes)
 	if err != nil {
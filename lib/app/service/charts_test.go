@@ -17,7 +17,9 @@ limitations under the License.
 package service
 
 import (
+	"bytes"
 	"io/ioutil"
+	"strings"
 
 	"github.com/gravitational/gravity/lib/app"
 	"github.com/gravitational/gravity/lib/app/service/test"
@@ -27,8 +29,11 @@ import (
 	"github.com/gravitational/gravity/lib/storage"
 
 	"github.com/ghodss/yaml"
+	"github.com/gravitational/trace"
+	"golang.org/x/crypto/openpgp"
 	check "gopkg.in/check.v1"
 	"k8s.io/helm/pkg/chartutil"
+	"k8s.io/helm/pkg/provenance"
 	"k8s.io/helm/pkg/repo"
 )
 
@@ -125,6 +130,151 @@ func (s *chartsSuite) TestFetchChart(c *check.C) {
 	compare.DeepCompare(c, chart, test.Chart(alpine))
 }
 
+func (s *chartsSuite) TestUploadChart(c *check.C) {
+	alpine := loc.MustParseLocator("example.com/alpine:0.1.0")
+	chartPath := s.saveChart(c, alpine)
+	chartBytes, err := ioutil.ReadFile(chartPath)
+	c.Assert(err, check.IsNil)
+
+	// Upload the chart into a custom repository...
+	application, err := s.apps.UploadChart(app.UploadChartRequest{
+		Repository: alpine.Repository,
+		Chart:      bytes.NewReader(chartBytes),
+	})
+	c.Assert(err, check.IsNil)
+	c.Assert(application.Package, check.Equals, alpine)
+
+	// ... and verify it has been added to the index of this repository only.
+	index := s.getRepositoryIndex(c, alpine.Repository)
+	c.Assert(index.Has(alpine.Name, alpine.Version), check.Equals, true)
+	c.Assert(index.Entries[alpine.Name][0].URLs, check.DeepEquals,
+		[]string{"/helm/example.com/charts/alpine-0.1.0.tgz"})
+	c.Assert(len(s.getIndex(c).Entries), check.Equals, 0)
+
+	// The chart is served unmodified.
+	reader, err := s.apps.FetchChart(alpine)
+	c.Assert(err, check.IsNil)
+	defer reader.Close()
+	data, err := ioutil.ReadAll(reader)
+	c.Assert(err, check.IsNil)
+	c.Assert(data, check.DeepEquals, chartBytes)
+
+	// The same chart can only be uploaded again with force.
+	_, err = s.apps.UploadChart(app.UploadChartRequest{
+		Repository: alpine.Repository,
+		Chart:      bytes.NewReader(chartBytes),
+	})
+	c.Assert(trace.IsAlreadyExists(err), check.Equals, true, check.Commentf("%v", err))
+	_, err = s.apps.UploadChart(app.UploadChartRequest{
+		Repository: alpine.Repository,
+		Chart:      bytes.NewReader(chartBytes),
+		Force:      true,
+	})
+	c.Assert(err, check.IsNil)
+
+	// Deleting the application removes it from the index.
+	err = s.apps.DeleteApp(app.DeleteRequest{Package: alpine})
+	c.Assert(err, check.IsNil)
+	index = s.getRepositoryIndex(c, alpine.Repository)
+	c.Assert(len(index.Entries), check.Equals, 0)
+}
+
+func (s *chartsSuite) TestUploadChartProvenance(c *check.C) {
+	alpine := loc.MustParseLocator("example.com/alpine:0.1.0")
+	chartPath := s.saveChart(c, alpine)
+	chartBytes, err := ioutil.ReadFile(chartPath)
+	c.Assert(err, check.IsNil)
+	entity, err := openpgp.NewEntity("test", "", "test@example.com", nil)
+	c.Assert(err, check.IsNil)
+	signatory := &provenance.Signatory{Entity: entity}
+	signature, err := signatory.ClearSign(chartPath)
+	c.Assert(err, check.IsNil)
+
+	// Provenance of another chart is rejected.
+	nginxPath := s.saveChart(c, loc.MustParseLocator("example.com/nginx:0.1.0"))
+	nginxSignature, err := signatory.ClearSign(nginxPath)
+	c.Assert(err, check.IsNil)
+	_, err = s.apps.UploadChart(app.UploadChartRequest{
+		Repository: alpine.Repository,
+		Chart:      bytes.NewReader(chartBytes),
+		Provenance: strings.NewReader(nginxSignature),
+	})
+	c.Assert(trace.IsBadParameter(err), check.Equals, true, check.Commentf("%v", err))
+
+	// Upload the chart with its provenance file...
+	_, err = s.apps.UploadChart(app.UploadChartRequest{
+		Repository: alpine.Repository,
+		Chart:      bytes.NewReader(chartBytes),
+		Provenance: strings.NewReader(signature),
+	})
+	c.Assert(err, check.IsNil)
+
+	// ... and verify it is returned back as-is.
+	reader, err := s.apps.FetchChartProvenance(alpine)
+	c.Assert(err, check.IsNil)
+	data, err := ioutil.ReadAll(reader)
+	c.Assert(err, check.IsNil)
+	c.Assert(string(data), check.Equals, signature)
+
+	// The provenance file can be replaced separately.
+	err = s.apps.UploadChartProvenance(alpine.Repository, strings.NewReader(signature))
+	c.Assert(err, check.IsNil)
+	err = s.apps.UploadChartProvenance(alpine.Repository, strings.NewReader(nginxSignature))
+	c.Assert(trace.IsNotFound(err), check.Equals, true, check.Commentf("%v", err))
+
+	// Replacing the chart without a provenance file removes the stale one.
+	_, err = s.apps.UploadChart(app.UploadChartRequest{
+		Repository: alpine.Repository,
+		Chart:      bytes.NewReader(chartBytes),
+		Force:      true,
+	})
+	c.Assert(err, check.IsNil)
+	_, err = s.apps.FetchChartProvenance(alpine)
+	c.Assert(trace.IsNotFound(err), check.Equals, true, check.Commentf("%v", err))
+
+	// Replacing the chart with a provenance file stores the new one.
+	_, err = s.apps.UploadChart(app.UploadChartRequest{
+		Repository: alpine.Repository,
+		Chart:      bytes.NewReader(chartBytes),
+		Provenance: strings.NewReader(signature),
+		Force:      true,
+	})
+	c.Assert(err, check.IsNil)
+	reader, err = s.apps.FetchChartProvenance(alpine)
+	c.Assert(err, check.IsNil)
+	data, err = ioutil.ReadAll(reader)
+	c.Assert(err, check.IsNil)
+	c.Assert(string(data), check.Equals, signature)
+
+	// Deleting the application removes its provenance file.
+	err = s.apps.DeleteApp(app.DeleteRequest{Package: alpine})
+	c.Assert(err, check.IsNil)
+	_, err = s.apps.FetchChartProvenance(alpine)
+	c.Assert(trace.IsNotFound(err), check.Equals, true, check.Commentf("%v", err))
+}
+
+// saveChart saves the test chart for the specified locator
+// as an archive and returns the path to the archive
+func (s *chartsSuite) saveChart(c *check.C, locator loc.Locator) (path string) {
+	chart := test.Chart(locator)
+	chart.Files = nil
+	path, err := chartutil.Save(chart, c.MkDir())
+	c.Assert(err, check.IsNil)
+	return path
+}
+
+// getRepositoryIndex returns the index file of the specified chart repository.
+func (s *chartsSuite) getRepositoryIndex(c *check.C, repository string) repo.IndexFile {
+	reader, err := s.apps.FetchRepositoryIndexFile(repository)
+	c.Assert(err, check.IsNil)
+	indexFileBytes, err := ioutil.ReadAll(reader)
+	c.Assert(err, check.IsNil)
+	var indexFile repo.IndexFile
+	err = yaml.Unmarshal(indexFileBytes, &indexFile)
+	c.Assert(err, check.IsNil)
+	return indexFile
+}
+
 // getIndex returns the suite's app service's chart repo index file.
 //
 // The index file can also be retrieved directly from the backend in the
@@ -18,7 +18,9 @@ package service
 
 import (
 	"context"
+	"io"
 	"io/ioutil"
+	"path/filepath"
 	"sync"
 	"time"
 
@@ -28,6 +30,7 @@ import (
 	"github.com/gravitational/gravity/lib/pack"
 	"github.com/gravitational/gravity/lib/run"
 	"github.com/gravitational/gravity/lib/schema"
+	"github.com/gravitational/gravity/lib/state"
 	"github.com/gravitational/gravity/lib/utils"
 
 	"github.com/cenkalti/backoff"
@@ -53,6 +56,9 @@ type PackagePullRequest struct {
 	Upsert bool
 	// MetadataOnly allows to pull only package metadata without body
 	MetadataOnly bool
+	// DownloadDir is the directory for partially downloaded packages.
+	// Defaults to the downloads directory in the local state directory
+	DownloadDir string
 }
 
 // CheckAndSetDefaults checks the package pull request and sets some defaults
@@ -60,6 +66,13 @@ func (r *PackagePullRequest) CheckAndSetDefaults() error {
 	if r.FieldLogger == nil {
 		r.FieldLogger = logrus.StandardLogger()
 	}
+	if r.DownloadDir == "" {
+		downloadDir, err := defaultDownloadDir()
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		r.DownloadDir = downloadDir
+	}
 	return nil
 }
 
@@ -90,6 +103,9 @@ type AppPullRequest struct {
 	// If < 0, the number of tasks is unrestricted.
 	// If in [0,1], the tasks are executed sequentially.
 	Parallel int
+	// DownloadDir is the directory for partially downloaded packages.
+	// Defaults to the downloads directory in the local state directory
+	DownloadDir string
 }
 
 // CheckAndSetDefaults checks the app pull request and sets some defaults
@@ -97,6 +113,13 @@ func (r *AppPullRequest) CheckAndSetDefaults() error {
 	if r.FieldLogger == nil {
 		r.FieldLogger = logrus.StandardLogger()
 	}
+	if r.DownloadDir == "" {
+		downloadDir, err := defaultDownloadDir()
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		r.DownloadDir = downloadDir
+	}
 	return nil
 }
 
@@ -113,6 +136,7 @@ func (r *AppPullRequest) Clone(locator loc.Locator) AppPullRequest {
 		Progress:     r.Progress,
 		Parallel:     r.Parallel,
 		MetadataOnly: r.MetadataOnly,
+		DownloadDir:  r.DownloadDir,
 	}
 }
 
@@ -135,6 +159,7 @@ func pullPackageHandler(loc loc.Locator, req AppPullRequest, state *pullState) f
 			Upsert:       req.Upsert,
 			Progress:     req.Progress,
 			MetadataOnly: req.MetadataOnly,
+			DownloadDir:  req.DownloadDir,
 		}, state)
 		if !trace.IsAlreadyExists(err) {
 			return trace.Wrap(err)
@@ -184,18 +209,17 @@ func pullPackage(req PackagePullRequest) (*pack.PackageEnvelope, error) {
 	if req.MetadataOnly {
 		env, err = req.SrcPack.ReadPackageEnvelope(req.Package)
 	} else {
-		env, reader, err = req.SrcPack.ReadPackage(req.Package)
+		env, reader, err = packageReader{
+			FieldLogger: req.FieldLogger,
+			src:         req.SrcPack,
+			dst:         req.DstPack,
+			downloadDir: req.DownloadDir,
+			progress:    req.Progress,
+		}.read(req.Package)
 	}
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
-
-	if req.Progress != nil {
-		reader = utils.TeeReadCloser(reader, &pack.ProgressWriter{
-			Size: env.SizeBytes,
-			R:    req.Progress,
-		})
-	}
 	defer reader.Close()
 
 	err = req.DstPack.UpsertRepository(env.Locator.Repository, time.Time{})
@@ -307,18 +331,17 @@ func pullApp(req AppPullRequest, state *pullState) (*app.Application, error) {
 	if req.MetadataOnly {
 		env, err = req.SrcPack.ReadPackageEnvelope(req.Package)
 	} else {
-		env, reader, err = req.SrcPack.ReadPackage(req.Package)
+		env, reader, err = packageReader{
+			FieldLogger: req.FieldLogger,
+			src:         req.SrcPack,
+			dst:         req.DstPack,
+			downloadDir: req.DownloadDir,
+			progress:    req.Progress,
+		}.read(req.Package)
 	}
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
-
-	if req.Progress != nil {
-		reader = utils.TeeReadCloser(reader, &pack.ProgressWriter{
-			Size: env.SizeBytes,
-			R:    req.Progress,
-		})
-	}
 	defer reader.Close()
 
 	if req.Upsert {
@@ -383,6 +406,96 @@ func pullAppDeps(req AppPullRequest, manifest schema.Manifest, state *pullState)
 	return nil
 }
 
+// packageReader reads packages from the source package service
+type packageReader struct {
+	logrus.FieldLogger
+	// src is the package service to read packages from
+	src pack.PackageService
+	// dst is the package service the packages are pulled into
+	dst pack.PackageService
+	// downloadDir is the directory for partially downloaded packages
+	downloadDir string
+	// progress is optional progress reporter
+	progress pack.ProgressReporter
+}
+
+// read reads the specified package from the source package service.
+//
+// If the source supports delta transfer and the destination already has other
+// versions of the package, only the difference against those versions is transferred.
+// Otherwise, if the source supports range reads, the package is downloaded into
+// the download directory so an interrupted download can be resumed on retry.
+// Falls back to reading the whole package
+func (r packageReader) read(locator loc.Locator) (*pack.PackageEnvelope, io.ReadCloser, error) {
+	env, reader, err := r.readDelta(locator)
+	if err == nil {
+		return env, r.withProgress(env, reader), nil
+	}
+	if !trace.IsNotFound(err) {
+		r.WithError(err).Warnf("Failed to pull package %v as delta, will pull the whole package.", locator)
+	}
+	env, reader, err = pack.DownloadPackage(r.src, locator, r.downloadDir, r.progress)
+	if err == nil {
+		return env, reader, nil
+	}
+	switch {
+	case trace.IsNotImplemented(err):
+	case trace.IsAccessDenied(err):
+		r.WithError(err).Warnf("Failed to download package %v into %v, will pull the whole package.",
+			locator, r.downloadDir)
+	default:
+		return nil, nil, trace.Wrap(err)
+	}
+	env, reader, err = r.src.ReadPackage(locator)
+	if err != nil {
+		return nil, nil, trace.Wrap(err)
+	}
+	return env, r.withProgress(env, reader), nil
+}
+
+func (r packageReader) readDelta(locator loc.Locator) (*pack.PackageEnvelope, io.ReadCloser, error) {
+	deltaReader, ok := r.src.(pack.DeltaReader)
+	if !ok {
+		return nil, nil, trace.NotFound("package service does not support delta transfer")
+	}
+	bases, err := pack.FindDeltaBases(r.dst, locator)
+	if err != nil {
+		return nil, nil, trace.Wrap(err)
+	}
+	if len(bases) == 0 {
+		return nil, nil, trace.NotFound("no base versions for %v", locator)
+	}
+	r.Infof("Pulling package %v as delta against %v.", locator, bases)
+	env, delta, err := deltaReader.ReadPackageDelta(locator, bases)
+	if err != nil {
+		return nil, nil, trace.Wrap(err)
+	}
+	defer delta.Close()
+	reader, err := pack.ApplyPackageDelta(r.dst, *env, delta)
+	if err != nil {
+		return nil, nil, trace.Wrap(err)
+	}
+	return env, reader, nil
+}
+
+func (r packageReader) withProgress(env *pack.PackageEnvelope, reader io.ReadCloser) io.ReadCloser {
+	if r.progress == nil {
+		return reader
+	}
+	return utils.TeeReadCloser(reader, &pack.ProgressWriter{
+		Size: env.SizeBytes,
+		R:    r.progress,
+	})
+}
+
+func defaultDownloadDir() (string, error) {
+	stateDir, err := state.GetStateDir()
+	if err != nil {
+		return "", trace.Wrap(err)
+	}
+	return filepath.Join(stateDir, defaults.DownloadsDir), nil
+}
+
 func newPullState() *pullState {
 	return &pullState{
 		packages: make(map[loc.Locator]struct{}),
@@ -0,0 +1,91 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package autoscale defines the provider-agnostic integration of the cluster
+// with the autoscaling groups of cloud providers.
+//
+// The autoscaler runs on master nodes. It publishes the parameters instances
+// of the group need to join the cluster and removes the nodes terminated by
+// the group from the cluster.
+//
+// AWS autoscaling groups are supported with lifecycle hooks, see lib/autoscale/aws.
+// Providers that do not deliver lifecycle notifications implement Group and
+// are handled by the autoscaler returned by New, which polls the group for
+// terminated instances: GCE managed instance groups (lib/autoscale/gce)
+// and Azure virtual machine scale sets (lib/autoscale/azure).
+//
+// Before a node is removed, Safety evaluates whether the removal is safe
+// and drains the node, delays the removal or rejects it.
+package autoscale
+
+import (
+	"context"
+
+	"github.com/gravitational/gravity/lib/ops"
+)
+
+// Autoscaler integrates the cluster with an autoscaling group
+type Autoscaler interface {
+	// ProcessEvents removes the nodes terminated by the autoscaling group
+	// from the cluster until the context is cancelled
+	ProcessEvents(ctx context.Context, operator Operator)
+	// PublishDiscovery periodically publishes the parameters instances of
+	// the autoscaling group use to join the cluster until the context is cancelled
+	PublishDiscovery(ctx context.Context, operator ops.Operator)
+}
+
+// Discovery provides access to the published join parameters
+// on the instances of the autoscaling group
+type Discovery interface {
+	// GetJoinToken returns the cluster join token
+	GetJoinToken(ctx context.Context) (string, error)
+	// GetServiceURL returns the URL of the cluster service to join
+	GetServiceURL(ctx context.Context) (string, error)
+}
+
+// Operator is a simplified operator interface to mock in tests
+type Operator interface {
+	GetLocalSite() (*ops.Site, error)
+	CreateSiteShrinkOperation(context.Context, ops.CreateSiteShrinkOperationRequest) (*ops.SiteOperationKey, error)
+}
+
+// Group is an autoscaling group of a cloud provider
+// that does not deliver instance lifecycle notifications
+type Group interface {
+	// ListInstances returns the instances of the group
+	ListInstances(ctx context.Context) ([]Instance, error)
+	// PublishJoinParameters publishes the join parameters where
+	// the instances of the group can read them
+	PublishJoinParameters(ctx context.Context, params JoinParameters) error
+}
+
+// Instance describes an instance of an autoscaling group
+type Instance struct {
+	// ID is the cloud provider instance ID
+	ID string
+	// Name is the instance hostname
+	Name string
+	// Terminating is true if the group is removing the instance
+	Terminating bool
+}
+
+// JoinParameters describes the parameters an instance needs to join the cluster
+type JoinParameters struct {
+	// Token is the cluster join token
+	Token string
+	// ServiceURL is the URL of the cluster service to join
+	ServiceURL string
+}
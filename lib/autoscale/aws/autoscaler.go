@@ -20,6 +20,7 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/gravitational/gravity/lib/autoscale"
 	gaws "github.com/gravitational/gravity/lib/cloudprovider/aws"
 	"github.com/gravitational/gravity/lib/defaults"
 	"github.com/gravitational/gravity/lib/utils"
@@ -54,7 +55,7 @@ const (
 type Autoscaler struct {
 	// Config is Autoscaler config
 	Config
-	// QueueURL is SQS queue name with notifications
+	// QueueURL is the URL of the SQS queue with notifications
 	QueueURL string
 	*log.Entry
 
@@ -78,8 +79,13 @@ type Config struct {
 	Queue SQS
 	// Cloud is Elastic Compute Cloud, AWS cloud service
 	Cloud EC2
+	// Lifecycle completes and extends the lifecycle actions of the autoscaling group
+	Lifecycle Lifecycle
 	// NewLocalInstance is used to retrieve local instance metadata
 	NewLocalInstance NewLocalInstance
+	// Safety evaluates whether the nodes of terminating instances can be
+	// removed from the cluster. Nodes are removed without checks if unspecified
+	Safety autoscale.Checker
 }
 
 // CheckAndSetDefaults checks and sets default values
@@ -118,6 +124,9 @@ func New(cfg Config) (*Autoscaler, error) {
 	if cfg.Cloud == nil {
 		cfg.Cloud = ec2.New(sess)
 	}
+	if cfg.Lifecycle == nil {
+		cfg.Lifecycle = NewLifecycle(sess)
+	}
 	a := &Autoscaler{
 		Config: cfg,
 		Entry:  log.WithFields(log.Fields{trace.Component: "autoscale"}),
@@ -135,6 +144,49 @@ func (a *Autoscaler) DeleteEvent(ctx context.Context, event HookEvent) error {
 	return trace.Wrap(err)
 }
 
+// RecordHeartbeat extends the timeout of the lifecycle action associated with event
+func (a *Autoscaler) RecordHeartbeat(ctx context.Context, event HookEvent) error {
+	a.Debugf("RecordHeartbeat(%v)", event.InstanceID)
+	if !event.hasLifecycleAction() {
+		return nil
+	}
+	_, err := a.Lifecycle.RecordLifecycleActionHeartbeatWithContext(ctx, &RecordLifecycleActionHeartbeatInput{
+		AutoScalingGroupName: aws.String(event.GroupName),
+		LifecycleHookName:    aws.String(event.HookName),
+		LifecycleActionToken: aws.String(event.ActionToken),
+		InstanceId:           aws.String(event.InstanceID),
+	})
+	err = convertLifecycleError(err)
+	if trace.IsNotFound(err) {
+		a.WithField("instance", event.InstanceID).Info("Lifecycle action is no longer active.")
+		return nil
+	}
+	return trace.Wrap(err)
+}
+
+// CompleteLifecycleAction completes the lifecycle action associated with event
+// with the specified result, either LifecycleActionContinue or LifecycleActionAbandon.
+// The action that has already been completed or has timed out is ignored
+func (a *Autoscaler) CompleteLifecycleAction(ctx context.Context, event HookEvent, result string) error {
+	a.Debugf("CompleteLifecycleAction(%v, %v)", event.InstanceID, result)
+	if !event.hasLifecycleAction() {
+		return nil
+	}
+	_, err := a.Lifecycle.CompleteLifecycleActionWithContext(ctx, &CompleteLifecycleActionInput{
+		AutoScalingGroupName:  aws.String(event.GroupName),
+		LifecycleHookName:     aws.String(event.HookName),
+		LifecycleActionToken:  aws.String(event.ActionToken),
+		LifecycleActionResult: aws.String(result),
+		InstanceId:            aws.String(event.InstanceID),
+	})
+	err = convertLifecycleError(err)
+	if trace.IsNotFound(err) {
+		a.WithField("instance", event.InstanceID).Info("Lifecycle action is no longer active.")
+		return nil
+	}
+	return trace.Wrap(err)
+}
+
 // TurnOffSourceDestination check turns off source destination check on the instance
 // that is necessary for K8s to function properly
 func (a *Autoscaler) TurnOffSourceDestinationCheck(ctx context.Context, instanceID string) error {
@@ -21,6 +21,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/gravitational/gravity/lib/autoscale"
 	gaws "github.com/gravitational/gravity/lib/cloudprovider/aws"
 	"github.com/gravitational/gravity/lib/ops"
 	"github.com/gravitational/gravity/lib/storage"
@@ -55,8 +56,9 @@ func (s *AutoscalerSuite) TestInstanceTerminate(c *check.C) {
 		NewLocalInstance: func() (*gaws.Instance, error) {
 			return instance, nil
 		},
-		Queue: queue,
-		Cloud: ec,
+		Queue:     queue,
+		Cloud:     ec,
+		Lifecycle: &mockLifecycle{},
 	})
 	c.Assert(err, check.IsNil)
 	c.Assert(a, check.NotNil)
@@ -74,7 +76,8 @@ func (s *AutoscalerSuite) TestInstanceTerminate(c *check.C) {
 			Servers: []storage.Server{server},
 		},
 	})
-	go a.ProcessEvents(ctx, queue.url, op)
+	a.QueueURL = queue.url
+	go a.ProcessEvents(ctx, op)
 
 	// send terminated event
 	msg := &message{
@@ -122,8 +125,9 @@ func (s *AutoscalerSuite) TestInstanceLaunching(c *check.C) {
 		NewLocalInstance: func() (*gaws.Instance, error) {
 			return instance, nil
 		},
-		Queue: queue,
-		Cloud: ec,
+		Queue:     queue,
+		Cloud:     ec,
+		Lifecycle: &mockLifecycle{},
 	})
 	c.Assert(err, check.IsNil)
 	c.Assert(a, check.NotNil)
@@ -135,7 +139,8 @@ func (s *AutoscalerSuite) TestInstanceLaunching(c *check.C) {
 		Domain:       "example.com",
 		ClusterState: storage.ClusterState{},
 	})
-	go a.ProcessEvents(ctx, queue.url, op)
+	a.QueueURL = queue.url
+	go a.ProcessEvents(ctx, op)
 
 	// send launched event
 	instanceID := "instance-1"
@@ -170,6 +175,112 @@ func (s *AutoscalerSuite) TestInstanceLaunching(c *check.C) {
 	}
 }
 
+func (s *AutoscalerSuite) TestDelayedRemovalExtendsLifecycleAction(c *check.C) {
+	a, lifecycle, queue, op := newTerminationAutoscaler(c, autoscale.DecisionDelay)
+
+	c.Assert(a.processEvent(context.TODO(), op, terminationEvent()), check.IsNil)
+	c.Assert(lifecycle.heartbeats, check.DeepEquals, []string{"instance-to-delete"})
+	c.Assert(lifecycle.completions, check.HasLen, 0)
+	c.Assert(op.shrinksC, check.HasLen, 0)
+	// the message is kept to have the removal re-evaluated
+	c.Assert(queue.deletedC, check.HasLen, 0)
+}
+
+func (s *AutoscalerSuite) TestRejectedRemovalAbandonsLifecycleAction(c *check.C) {
+	a, lifecycle, queue, op := newTerminationAutoscaler(c, autoscale.DecisionReject)
+	checker := a.Safety.(*mockChecker)
+
+	c.Assert(a.processEvent(context.TODO(), op, terminationEvent()), check.IsNil)
+	c.Assert(lifecycle.heartbeats, check.HasLen, 0)
+	c.Assert(lifecycle.completions, check.DeepEquals, []string{LifecycleActionAbandon})
+	// the node is removed once the instance has been terminated
+	c.Assert(checker.terminated, check.DeepEquals, []string{"instance-to-delete.hostname"})
+	c.Assert(op.shrinksC, check.HasLen, 1)
+	c.Assert((<-op.shrinksC).Servers, check.DeepEquals, []string{"instance-to-delete.hostname"})
+	c.Assert(queue.deletedC, check.HasLen, 1)
+}
+
+func (s *AutoscalerSuite) TestDrainedRemovalContinuesLifecycleAction(c *check.C) {
+	a, lifecycle, queue, op := newTerminationAutoscaler(c, autoscale.DecisionDrain)
+	checker := a.Safety.(*mockChecker)
+
+	c.Assert(a.processEvent(context.TODO(), op, terminationEvent()), check.IsNil)
+	c.Assert(lifecycle.completions, check.DeepEquals, []string{LifecycleActionContinue})
+	c.Assert(checker.terminated, check.HasLen, 0)
+	c.Assert(op.shrinksC, check.HasLen, 1)
+	c.Assert(queue.deletedC, check.HasLen, 1)
+}
+
+func newTerminationAutoscaler(c *check.C, decision autoscale.Decision) (*Autoscaler, *mockLifecycle, *mockQueue, *mockOperator) {
+	lifecycle := &mockLifecycle{}
+	queue := newMockQueue("queue-1")
+	a, err := New(Config{
+		ClusterName: "bob",
+		NewLocalInstance: func() (*gaws.Instance, error) {
+			return &gaws.Instance{ID: "instance-1"}, nil
+		},
+		Queue: queue,
+		Cloud: newMockEC2(&ec2.Instance{
+			InstanceId: aws.String("instance-to-delete"),
+		}),
+		Lifecycle: lifecycle,
+		Safety:    &mockChecker{verdict: autoscale.Verdict{Decision: decision}},
+	})
+	c.Assert(err, check.IsNil)
+	op := newMockOperator(ops.Site{
+		AccountID: "1",
+		Domain:    "example.com",
+		ClusterState: storage.ClusterState{
+			Servers: []storage.Server{{
+				InstanceID: "instance-to-delete",
+				Hostname:   "instance-to-delete.hostname",
+			}},
+		},
+	})
+	return a, lifecycle, queue, op
+}
+
+func terminationEvent() HookEvent {
+	return HookEvent{
+		QueueURL:      "queue-1",
+		ReceiptHandle: "message-1",
+		InstanceID:    "instance-to-delete",
+		Type:          InstanceTerminating,
+		GroupName:     "bob-workers",
+		HookName:      "bob-terminate",
+		ActionToken:   "token-1",
+	}
+}
+
+type mockLifecycle struct {
+	heartbeats  []string
+	completions []string
+}
+
+func (m *mockLifecycle) RecordLifecycleActionHeartbeatWithContext(ctx aws.Context, input *RecordLifecycleActionHeartbeatInput, opts ...request.Option) (*RecordLifecycleActionHeartbeatOutput, error) {
+	m.heartbeats = append(m.heartbeats, aws.StringValue(input.InstanceId))
+	return &RecordLifecycleActionHeartbeatOutput{}, nil
+}
+
+func (m *mockLifecycle) CompleteLifecycleActionWithContext(ctx aws.Context, input *CompleteLifecycleActionInput, opts ...request.Option) (*CompleteLifecycleActionOutput, error) {
+	m.completions = append(m.completions, aws.StringValue(input.LifecycleActionResult))
+	return &CompleteLifecycleActionOutput{}, nil
+}
+
+type mockChecker struct {
+	verdict    autoscale.Verdict
+	terminated []string
+}
+
+func (m *mockChecker) PrepareRemoval(ctx context.Context, cluster ops.Site, server storage.Server) (*autoscale.Verdict, error) {
+	return &m.verdict, nil
+}
+
+func (m *mockChecker) RecordTermination(server storage.Server) error {
+	m.terminated = append(m.terminated, server.Hostname)
+	return nil
+}
+
 func newMockQueue(url string) *mockQueue {
 	return &mockQueue{
 		url:       url,
@@ -21,6 +21,7 @@ import (
 	"encoding/json"
 	"regexp"
 
+	"github.com/gravitational/gravity/lib/autoscale"
 	"github.com/gravitational/gravity/lib/ops"
 
 	"github.com/aws/aws-sdk-go/aws"
@@ -41,6 +42,18 @@ type HookEvent struct {
 	InstanceID string `json:"EC2InstanceId"`
 	// Type is event type
 	Type string `json:"LifecycleTransition"`
+	// GroupName is the name of the autoscaling group
+	GroupName string `json:"AutoScalingGroupName,omitempty"`
+	// HookName is the name of the lifecycle hook
+	HookName string `json:"LifecycleHookName,omitempty"`
+	// ActionToken identifies the lifecycle action
+	ActionToken string `json:"LifecycleActionToken,omitempty"`
+}
+
+// hasLifecycleAction returns true if the event identifies a lifecycle action
+// that has to be completed
+func (e HookEvent) hasLifecycleAction() bool {
+	return e.GroupName != "" && e.HookName != ""
 }
 
 // GetQueueURL returns queue URL associated with this cluster
@@ -60,9 +73,10 @@ func (a *Autoscaler) GetQueueURL(ctx context.Context) (string, error) {
 	return aws.StringValue(out.QueueUrl), nil
 }
 
-// ProcessEvents listens for events on SQS queue that are sent by the auto scaling
-// group lifecycle hooks.
-func (a *Autoscaler) ProcessEvents(ctx context.Context, queueURL string, operator Operator) {
+// ProcessEvents listens for events on SQS queue specified with QueueURL
+// that are sent by the auto scaling group lifecycle hooks.
+func (a *Autoscaler) ProcessEvents(ctx context.Context, operator Operator) {
+	queueURL := a.QueueURL
 	a.WithField("queue", queueURL).Info("Start processing events.")
 	for {
 		out, err := a.Queue.ReceiveMessageWithContext(ctx, &sqs.ReceiveMessageInput{
@@ -107,9 +121,40 @@ func (a *Autoscaler) processEvent(ctx context.Context, operator Operator, event
 			return trace.Wrap(err)
 		}
 	case InstanceTerminating:
-		if err := a.ensureInstanceTerminated(ctx, event); err != nil {
+		verdict, err := a.prepareRemoval(ctx, operator, event)
+		if err != nil {
 			return trace.Wrap(err)
 		}
+		switch verdict.Decision {
+		case autoscale.DecisionDelay:
+			// Extend the lifecycle hook timeout and keep the message in the queue
+			// to have it delivered again after the visibility timeout
+			if err := a.RecordHeartbeat(ctx, event); err != nil {
+				return trace.Wrap(err)
+			}
+			return nil
+		case autoscale.DecisionReject:
+			// The autoscaling group terminates the instance regardless of the
+			// lifecycle action result, so the action is abandoned instead of
+			// holding the termination until the hook times out.
+			// The node is removed once the instance is gone
+			if err := a.CompleteLifecycleAction(ctx, event, LifecycleActionAbandon); err != nil {
+				return trace.Wrap(err)
+			}
+			if err := a.ensureInstanceTerminated(ctx, event); err != nil {
+				return trace.Wrap(err)
+			}
+			if err := a.recordTermination(operator, event); err != nil {
+				a.WithError(err).Warn("Failed to record node termination.")
+			}
+		default:
+			if err := a.CompleteLifecycleAction(ctx, event, LifecycleActionContinue); err != nil {
+				return trace.Wrap(err)
+			}
+			if err := a.ensureInstanceTerminated(ctx, event); err != nil {
+				return trace.Wrap(err)
+			}
+		}
 		if err := a.removeInstance(ctx, operator, event); err != nil && !trace.IsNotFound(err) {
 			return trace.Wrap(err)
 		}
@@ -148,6 +193,48 @@ func (a *Autoscaler) ensureInstanceTerminated(ctx context.Context, event HookEve
 	return nil
 }
 
+// prepareRemoval evaluates whether the node of the terminating instance
+// can be removed from the cluster and drains it if it can
+func (a *Autoscaler) prepareRemoval(ctx context.Context, operator Operator, event HookEvent) (*autoscale.Verdict, error) {
+	if a.Safety == nil {
+		return &autoscale.Verdict{Decision: autoscale.DecisionDrain}, nil
+	}
+	cluster, err := operator.GetLocalSite()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	server, err := ops.FindServerByInstanceID(cluster, event.InstanceID)
+	if err != nil {
+		if trace.IsNotFound(err) {
+			// Not a cluster node, nothing to check
+			return &autoscale.Verdict{Decision: autoscale.DecisionDrain}, nil
+		}
+		return nil, trace.Wrap(err)
+	}
+	verdict, err := a.Safety.PrepareRemoval(ctx, *cluster, *server)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return verdict, nil
+}
+
+// recordTermination records that the node of the terminated instance
+// is removed from the cluster despite the verdict
+func (a *Autoscaler) recordTermination(operator Operator, event HookEvent) error {
+	if a.Safety == nil {
+		return nil
+	}
+	cluster, err := operator.GetLocalSite()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	server, err := ops.FindServerByInstanceID(cluster, event.InstanceID)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return trace.Wrap(a.Safety.RecordTermination(*server))
+}
+
 func (a *Autoscaler) removeInstance(ctx context.Context, operator Operator, event HookEvent) error {
 	cluster, err := operator.GetLocalSite()
 	if err != nil {
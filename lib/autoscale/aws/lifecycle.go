@@ -0,0 +1,156 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/aws/client/metadata"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/signer/v4"
+	"github.com/aws/aws-sdk-go/private/protocol"
+	"github.com/aws/aws-sdk-go/private/protocol/query"
+
+	"github.com/gravitational/trace"
+)
+
+const (
+	// LifecycleActionContinue lets the autoscaling group proceed with the lifecycle action
+	LifecycleActionContinue = "CONTINUE"
+	// LifecycleActionAbandon tells the autoscaling group that the lifecycle action
+	// has been abandoned. The instance is terminated regardless for termination hooks
+	LifecycleActionAbandon = "ABANDON"
+)
+
+// NewLifecycle returns a client for the lifecycle hooks of AWS autoscaling groups.
+//
+// Only the lifecycle action calls of the Auto Scaling API are implemented
+func NewLifecycle(p client.ConfigProvider, cfgs ...*aws.Config) *LifecycleClient {
+	c := p.ClientConfig(autoscalingServiceName, cfgs...)
+	svc := &LifecycleClient{
+		Client: client.New(
+			*c.Config,
+			metadata.ClientInfo{
+				ServiceName:   autoscalingServiceName,
+				SigningName:   c.SigningName,
+				SigningRegion: c.SigningRegion,
+				Endpoint:      c.Endpoint,
+				APIVersion:    "2011-01-01",
+			},
+			c.Handlers,
+		),
+	}
+	svc.Handlers.Sign.PushBackNamed(v4.SignRequestHandler)
+	svc.Handlers.Build.PushBackNamed(query.BuildHandler)
+	svc.Handlers.Unmarshal.PushBackNamed(query.UnmarshalHandler)
+	svc.Handlers.UnmarshalMeta.PushBackNamed(query.UnmarshalMetaHandler)
+	svc.Handlers.UnmarshalError.PushBackNamed(query.UnmarshalErrorHandler)
+	return svc
+}
+
+// LifecycleClient implements Lifecycle with the AWS Auto Scaling API
+type LifecycleClient struct {
+	*client.Client
+}
+
+// RecordLifecycleActionHeartbeatWithContext extends the timeout of the lifecycle hook
+func (c *LifecycleClient) RecordLifecycleActionHeartbeatWithContext(ctx aws.Context, input *RecordLifecycleActionHeartbeatInput, opts ...request.Option) (*RecordLifecycleActionHeartbeatOutput, error) {
+	out := &RecordLifecycleActionHeartbeatOutput{}
+	req := c.newRequest("RecordLifecycleActionHeartbeat", input, out)
+	req.SetContext(ctx)
+	req.ApplyOptions(opts...)
+	return out, req.Send()
+}
+
+// CompleteLifecycleActionWithContext completes the lifecycle action with the specified result
+func (c *LifecycleClient) CompleteLifecycleActionWithContext(ctx aws.Context, input *CompleteLifecycleActionInput, opts ...request.Option) (*CompleteLifecycleActionOutput, error) {
+	out := &CompleteLifecycleActionOutput{}
+	req := c.newRequest("CompleteLifecycleAction", input, out)
+	req.SetContext(ctx)
+	req.ApplyOptions(opts...)
+	return out, req.Send()
+}
+
+func (c *LifecycleClient) newRequest(name string, params, data interface{}) *request.Request {
+	req := c.NewRequest(&request.Operation{
+		Name:       name,
+		HTTPMethod: "POST",
+		HTTPPath:   "/",
+	}, params, data)
+	// Both calls return an empty result
+	req.Handlers.Unmarshal.Remove(query.UnmarshalHandler)
+	req.Handlers.Unmarshal.PushBackNamed(protocol.UnmarshalDiscardBodyHandler)
+	return req
+}
+
+// RecordLifecycleActionHeartbeatInput identifies the lifecycle action to extend
+type RecordLifecycleActionHeartbeatInput struct {
+	_ struct{} `type:"structure"`
+	// AutoScalingGroupName is the name of the autoscaling group
+	AutoScalingGroupName *string `min:"1" type:"string" required:"true"`
+	// InstanceId is the ID of the instance
+	InstanceId *string `min:"1" type:"string"`
+	// LifecycleActionToken identifies the lifecycle action
+	LifecycleActionToken *string `min:"36" type:"string"`
+	// LifecycleHookName is the name of the lifecycle hook
+	LifecycleHookName *string `min:"1" type:"string" required:"true"`
+}
+
+// RecordLifecycleActionHeartbeatOutput is the empty result of RecordLifecycleActionHeartbeat
+type RecordLifecycleActionHeartbeatOutput struct {
+	_ struct{} `type:"structure"`
+}
+
+// CompleteLifecycleActionInput identifies the lifecycle action to complete
+type CompleteLifecycleActionInput struct {
+	_ struct{} `type:"structure"`
+	// AutoScalingGroupName is the name of the autoscaling group
+	AutoScalingGroupName *string `min:"1" type:"string" required:"true"`
+	// InstanceId is the ID of the instance
+	InstanceId *string `min:"1" type:"string"`
+	// LifecycleActionResult is either CONTINUE or ABANDON
+	LifecycleActionResult *string `type:"string" required:"true"`
+	// LifecycleActionToken identifies the lifecycle action
+	LifecycleActionToken *string `min:"36" type:"string"`
+	// LifecycleHookName is the name of the lifecycle hook
+	LifecycleHookName *string `min:"1" type:"string" required:"true"`
+}
+
+// CompleteLifecycleActionOutput is the empty result of CompleteLifecycleAction
+type CompleteLifecycleActionOutput struct {
+	_ struct{} `type:"structure"`
+}
+
+// convertLifecycleError converts the error returned by the lifecycle calls.
+// The Auto Scaling API reports the lifecycle action that has been completed
+// or has timed out as a validation error, it is returned as NotFound
+func convertLifecycleError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if awsErr, ok := err.(awserr.Error); ok && awsErr.Code() == errCodeValidationError {
+		return trace.NotFound("%v", awsErr.Error())
+	}
+	return trace.Wrap(err)
+}
+
+// errCodeValidationError is the error code of the invalid Auto Scaling API requests
+const errCodeValidationError = "ValidationError"
+
+// autoscalingServiceName is the name of the AWS Auto Scaling service
+const autoscalingServiceName = "autoscaling"
@@ -17,10 +17,8 @@ limitations under the License.
 package aws
 
 import (
-	"context"
-
+	"github.com/gravitational/gravity/lib/autoscale"
 	gaws "github.com/gravitational/gravity/lib/cloudprovider/aws"
-	"github.com/gravitational/gravity/lib/ops"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/request"
@@ -49,10 +47,13 @@ type EC2 interface {
 	WaitUntilInstanceTerminatedWithContext(aws.Context, *ec2.DescribeInstancesInput, ...request.WaiterOption) error
 }
 
-// Operator is a simplified operator interface to mock in tests
-type Operator interface {
-	GetLocalSite() (*ops.Site, error)
-	CreateSiteShrinkOperation(context.Context, ops.CreateSiteShrinkOperationRequest) (*ops.SiteOperationKey, error)
+// Lifecycle is an interface representing the lifecycle hooks of AWS autoscaling groups
+type Lifecycle interface {
+	RecordLifecycleActionHeartbeatWithContext(aws.Context, *RecordLifecycleActionHeartbeatInput, ...request.Option) (*RecordLifecycleActionHeartbeatOutput, error)
+	CompleteLifecycleActionWithContext(aws.Context, *CompleteLifecycleActionInput, ...request.Option) (*CompleteLifecycleActionOutput, error)
 }
 
+// Operator is a simplified operator interface to mock in tests
+type Operator = autoscale.Operator
+
 type NewLocalInstance func() (*gaws.Instance, error)
@@ -0,0 +1,235 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package azure
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/Azure/go-autorest/autorest/adal"
+	"github.com/gravitational/trace"
+)
+
+// Compute is the subset of the Azure compute API used by the autoscaler
+type Compute interface {
+	// ListVMs returns the virtual machines of the scale set
+	ListVMs(ctx context.Context, scaleSet ScaleSet) ([]VM, error)
+	// GetTags returns the tags of the scale set
+	GetTags(ctx context.Context, scaleSet ScaleSet) (map[string]string, error)
+	// UpdateTags replaces the tags of the scale set
+	UpdateTags(ctx context.Context, scaleSet ScaleSet, tags map[string]string) error
+}
+
+// ScaleSet identifies a virtual machine scale set
+type ScaleSet struct {
+	// SubscriptionID is the ID of the subscription
+	SubscriptionID string
+	// ResourceGroup is the name of the resource group
+	ResourceGroup string
+	// Name is the name of the scale set
+	Name string
+}
+
+// String returns the scale set resource ID
+func (r ScaleSet) String() string {
+	return path.Join("/subscriptions", r.SubscriptionID, "resourceGroups", r.ResourceGroup,
+		"providers/Microsoft.Compute/virtualMachineScaleSets", r.Name)
+}
+
+// VM describes a virtual machine of a scale set
+type VM struct {
+	// InstanceID is the ID of the virtual machine in the scale set
+	InstanceID string `json:"instanceId"`
+	// Name is the resource name of the virtual machine
+	Name string `json:"name"`
+	// Properties are the virtual machine properties
+	Properties VMProperties `json:"properties"`
+}
+
+// VMProperties describes the properties of a virtual machine
+type VMProperties struct {
+	// ProvisioningState is the provisioning state of the virtual machine
+	ProvisioningState string `json:"provisioningState"`
+	// OSProfile describes the operating system settings
+	OSProfile struct {
+		// ComputerName is the hostname of the virtual machine
+		ComputerName string `json:"computerName"`
+	} `json:"osProfile"`
+}
+
+// ProvisioningStateDeleting is the provisioning state of
+// the virtual machine being deleted from the scale set
+const ProvisioningStateDeleting = "Deleting"
+
+// NewCompute returns a new client of the Azure compute API
+// authenticated with the managed identity of the virtual machine
+func NewCompute() (*compute, error) {
+	endpoint, err := adal.GetMSIVMEndpoint()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	token, err := adal.NewServicePrincipalTokenFromMSI(endpoint, managementURL)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &compute{
+		client:  &http.Client{Transport: &tokenTransport{token: token}},
+		baseURL: managementURL,
+	}, nil
+}
+
+// ListVMs returns the virtual machines of the scale set
+func (r *compute) ListVMs(ctx context.Context, scaleSet ScaleSet) ([]VM, error) {
+	var vms []VM
+	endpoint := r.endpoint(scaleSet, "virtualMachines")
+	for endpoint != "" {
+		var out struct {
+			Value    []VM   `json:"value"`
+			NextLink string `json:"nextLink"`
+		}
+		if err := r.do(ctx, http.MethodGet, endpoint, nil, &out); err != nil {
+			return nil, trace.Wrap(err)
+		}
+		vms = append(vms, out.Value...)
+		endpoint = out.NextLink
+	}
+	return vms, nil
+}
+
+// GetTags returns the tags of the scale set
+func (r *compute) GetTags(ctx context.Context, scaleSet ScaleSet) (map[string]string, error) {
+	var out struct {
+		Tags map[string]string `json:"tags"`
+	}
+	if err := r.do(ctx, http.MethodGet, r.endpoint(scaleSet), nil, &out); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return out.Tags, nil
+}
+
+// UpdateTags replaces the tags of the scale set
+func (r *compute) UpdateTags(ctx context.Context, scaleSet ScaleSet, tags map[string]string) error {
+	in := map[string]interface{}{"tags": tags}
+	return trace.Wrap(r.do(ctx, http.MethodPatch, r.endpoint(scaleSet), in, nil))
+}
+
+func (r *compute) endpoint(scaleSet ScaleSet, elems ...string) string {
+	query := url.Values{"api-version": []string{apiVersion}}
+	return strings.TrimSuffix(r.baseURL, "/") + path.Join(append([]string{scaleSet.String()}, elems...)...) +
+		"?" + query.Encode()
+}
+
+func (r *compute) do(ctx context.Context, method, endpoint string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, endpoint, body)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	req = req.WithContext(ctx)
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if err := convertResponse(resp.StatusCode, data); err != nil {
+		return trace.Wrap(err)
+	}
+	if out == nil {
+		return nil
+	}
+	return trace.Wrap(json.Unmarshal(data, out))
+}
+
+// convertResponse converts the error response of the Azure API
+func convertResponse(code int, data []byte) error {
+	if code >= http.StatusOK && code < http.StatusMultipleChoices {
+		return nil
+	}
+	var out struct {
+		Error struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	message := string(data)
+	if err := json.Unmarshal(data, &out); err == nil && out.Error.Message != "" {
+		message = out.Error.Message
+	}
+	switch code {
+	case http.StatusNotFound:
+		return trace.NotFound("%v", message)
+	case http.StatusConflict:
+		return trace.AlreadyExists("%v", message)
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return trace.AccessDenied("%v", message)
+	}
+	return trace.BadParameter("compute API error (%v): %v", code, message)
+}
+
+type compute struct {
+	client *http.Client
+	// baseURL is the URL of the resource manager API
+	baseURL string
+}
+
+// tokenTransport authenticates requests with the managed identity token
+type tokenTransport struct {
+	token *adal.ServicePrincipalToken
+}
+
+// RoundTrip adds the authorization header to the request
+func (r *tokenTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := r.token.EnsureFreshWithContext(req.Context()); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	req2 := new(http.Request)
+	*req2 = *req
+	req2.Header = make(http.Header, len(req.Header))
+	for key, values := range req.Header {
+		req2.Header[key] = values
+	}
+	req2.Header.Set("Authorization", "Bearer "+r.token.OAuthToken())
+	return http.DefaultTransport.RoundTrip(req2)
+}
+
+const (
+	// managementURL is the URL of the Azure resource manager API
+	managementURL = "https://management.azure.com/"
+	// apiVersion is the version of the compute API
+	apiVersion = "2018-06-01"
+)
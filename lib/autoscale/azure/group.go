@@ -0,0 +1,195 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package azure
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+
+	"github.com/gravitational/gravity/lib/autoscale"
+
+	"github.com/gravitational/trace"
+	"github.com/sirupsen/logrus"
+)
+
+// Config is the configuration of the Azure virtual machine scale set
+type Config struct {
+	// ClusterName is the name of the cluster
+	ClusterName string
+	// ScaleSet identifies the scale set
+	ScaleSet ScaleSet
+	// Compute is the Azure compute API client
+	Compute Compute
+	// FieldLogger is used for logging
+	logrus.FieldLogger
+}
+
+// CheckAndSetDefaults checks and sets default values
+func (c *Config) CheckAndSetDefaults() error {
+	if c.ClusterName == "" {
+		return trace.BadParameter("missing parameter ClusterName")
+	}
+	if c.ScaleSet.SubscriptionID == "" || c.ScaleSet.ResourceGroup == "" || c.ScaleSet.Name == "" {
+		return trace.BadParameter("scale set requires subscription ID, resource group and name")
+	}
+	if c.Compute == nil {
+		return trace.BadParameter("missing parameter Compute")
+	}
+	if c.FieldLogger == nil {
+		c.FieldLogger = logrus.WithFields(logrus.Fields{
+			trace.Component: "autoscale:azure",
+			"scale-set":     c.ScaleSet.Name,
+		})
+	}
+	return nil
+}
+
+// NewGroup returns a new virtual machine scale set
+func NewGroup(config Config) (*Group, error) {
+	if err := config.CheckAndSetDefaults(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &Group{Config: config}, nil
+}
+
+// NewLocalGroup returns the scale set of the cluster configured with
+// the ScaleSetTag tag of this virtual machine.
+// The scale set is expected in the resource group of the virtual machine
+func NewLocalGroup(ctx context.Context, clusterName string) (*Group, error) {
+	metadata, err := GetInstanceMetadata(ctx)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	name, ok := metadata.Tag(ScaleSetTag)
+	if !ok {
+		return nil, trace.NotFound("virtual machine tag %v is not set", ScaleSetTag)
+	}
+	compute, err := NewCompute()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return NewGroup(Config{
+		ClusterName: clusterName,
+		ScaleSet: ScaleSet{
+			SubscriptionID: metadata.SubscriptionID,
+			ResourceGroup:  metadata.ResourceGroup,
+			Name:           name,
+		},
+		Compute: compute,
+	})
+}
+
+// Group is an Azure virtual machine scale set
+type Group struct {
+	// Config is the group configuration
+	Config
+}
+
+// ListInstances returns the virtual machines of the scale set.
+// Virtual machines being deleted are marked as terminating
+func (r *Group) ListInstances(ctx context.Context) ([]autoscale.Instance, error) {
+	vms, err := r.Compute.ListVMs(ctx, r.ScaleSet)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	instances := make([]autoscale.Instance, 0, len(vms))
+	for _, vm := range vms {
+		instances = append(instances, autoscale.Instance{
+			ID:          vm.InstanceID,
+			Name:        vm.Properties.OSProfile.ComputerName,
+			Terminating: vm.Properties.ProvisioningState == ProvisioningStateDeleting,
+		})
+	}
+	return instances, nil
+}
+
+// PublishJoinParameters publishes the join parameters in the tags
+// of the scale set which are inherited by its virtual machines
+func (r *Group) PublishJoinParameters(ctx context.Context, params autoscale.JoinParameters) error {
+	tags, err := r.Compute.GetTags(ctx, r.ScaleSet)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if tags == nil {
+		tags = make(map[string]string)
+	}
+	tags[TokenTag(r.ClusterName)] = params.Token
+	tags[ServiceURLTag(r.ClusterName)] = params.ServiceURL
+	return trace.Wrap(r.Compute.UpdateTags(ctx, r.ScaleSet, tags))
+}
+
+// NewDiscovery returns the discovery of the join parameters
+// published in the tags of the virtual machine for the specified cluster
+func NewDiscovery(clusterName string) *Discovery {
+	return &Discovery{
+		ClusterName:         clusterName,
+		GetInstanceMetadata: GetInstanceMetadata,
+	}
+}
+
+// Discovery reads the join parameters from the tags of the virtual machine
+type Discovery struct {
+	// ClusterName is the name of the cluster
+	ClusterName string
+	// GetInstanceMetadata returns the metadata of the virtual machine
+	GetInstanceMetadata func(context.Context) (*InstanceMetadata, error)
+}
+
+// GetJoinToken returns the cluster join token
+func (r *Discovery) GetJoinToken(ctx context.Context) (string, error) {
+	return r.get(ctx, TokenTag(r.ClusterName))
+}
+
+// GetServiceURL returns the URL of the cluster service to join
+func (r *Discovery) GetServiceURL(ctx context.Context) (string, error) {
+	return r.get(ctx, ServiceURLTag(r.ClusterName))
+}
+
+func (r *Discovery) get(ctx context.Context, tag string) (string, error) {
+	metadata, err := r.GetInstanceMetadata(ctx)
+	if err != nil {
+		return "", trace.Wrap(err)
+	}
+	value, ok := metadata.Tag(tag)
+	if !ok {
+		return "", trace.NotFound("virtual machine tag %v is not set", tag)
+	}
+	return value, nil
+}
+
+// TokenTag returns the tag of the cluster join token
+func TokenTag(clusterName string) string {
+	return fmt.Sprintf("gravity-%v-token", safeName(clusterName))
+}
+
+// ServiceURLTag returns the tag of the cluster service URL
+func ServiceURLTag(clusterName string) string {
+	return fmt.Sprintf("gravity-%v-service", safeName(clusterName))
+}
+
+// safeName returns the cluster name with the characters
+// not accepted in tag names replaced with dashes
+func safeName(clusterName string) string {
+	return unsafeTagChars.ReplaceAllString(clusterName, "-")
+}
+
+var unsafeTagChars = regexp.MustCompile("[^a-zA-Z0-9_-]")
+
+// ScaleSetTag is the tag of master virtual machines
+// with the name of the scale set of the cluster
+const ScaleSetTag = "gravity-scale-set"
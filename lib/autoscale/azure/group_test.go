@@ -0,0 +1,177 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package azure
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gravitational/gravity/lib/autoscale"
+
+	"github.com/gravitational/trace"
+	"gopkg.in/check.v1"
+)
+
+func TestAzure(t *testing.T) { check.TestingT(t) }
+
+type AzureSuite struct{}
+
+var _ = check.Suite(&AzureSuite{})
+
+func (s *AzureSuite) TestListsInstances(c *check.C) {
+	compute := &mockCompute{
+		vms: []VM{newVM("0", "node-1", "Succeeded"), newVM("1", "node-2", ProvisioningStateDeleting)},
+	}
+	group := s.newGroup(c, compute)
+	instances, err := group.ListInstances(context.TODO())
+	c.Assert(err, check.IsNil)
+	c.Assert(instances, check.DeepEquals, []autoscale.Instance{
+		{ID: "0", Name: "node-1"},
+		{ID: "1", Name: "node-2", Terminating: true},
+	})
+}
+
+func (s *AzureSuite) TestPublishesJoinParameters(c *check.C) {
+	compute := &mockCompute{tags: map[string]string{"env": "prod"}}
+	group := s.newGroup(c, compute)
+	err := group.PublishJoinParameters(context.TODO(), autoscale.JoinParameters{
+		Token:      "token",
+		ServiceURL: "https://10.0.0.1:3009",
+	})
+	c.Assert(err, check.IsNil)
+	c.Assert(compute.tags, check.DeepEquals, map[string]string{
+		"env":                         "prod",
+		"gravity-example-com-token":   "token",
+		"gravity-example-com-service": "https://10.0.0.1:3009",
+	})
+
+	discovery := &Discovery{
+		ClusterName: "example.com",
+		GetInstanceMetadata: func(context.Context) (*InstanceMetadata, error) {
+			return &InstanceMetadata{
+				Tags: "env:prod;gravity-example-com-service:https://10.0.0.1:3009;gravity-example-com-token:token",
+			}, nil
+		},
+	}
+	token, err := discovery.GetJoinToken(context.TODO())
+	c.Assert(err, check.IsNil)
+	c.Assert(token, check.Equals, "token")
+	serviceURL, err := discovery.GetServiceURL(context.TODO())
+	c.Assert(err, check.IsNil)
+	c.Assert(serviceURL, check.Equals, "https://10.0.0.1:3009")
+
+	discovery.ClusterName = "other"
+	_, err = discovery.GetJoinToken(context.TODO())
+	c.Assert(trace.IsNotFound(err), check.Equals, true)
+}
+
+func (s *AzureSuite) TestComputeAPI(c *check.C) {
+	var updated map[string]map[string]string
+	var server *httptest.Server
+	prefix := "/subscriptions/sub/resourceGroups/group/providers/Microsoft.Compute/virtualMachineScaleSets/nodes"
+	mux := http.NewServeMux()
+	mux.HandleFunc(prefix+"/virtualMachines", func(w http.ResponseWriter, r *http.Request) {
+		c.Assert(r.URL.Query().Get("api-version"), check.Equals, apiVersion)
+		if r.URL.Query().Get("page") == "" {
+			writeJSON(w, map[string]interface{}{
+				"value":    []VM{newVM("0", "node-1", "Succeeded")},
+				"nextLink": server.URL + prefix + "/virtualMachines?api-version=" + apiVersion + "&page=2",
+			})
+			return
+		}
+		writeJSON(w, map[string]interface{}{
+			"value": []VM{newVM("1", "node-2", ProvisioningStateDeleting)},
+		})
+	})
+	mux.HandleFunc(prefix, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			writeJSON(w, map[string]interface{}{"tags": map[string]string{"env": "prod"}})
+		case http.MethodPatch:
+			c.Assert(json.NewDecoder(r.Body).Decode(&updated), check.IsNil)
+			writeJSON(w, updated)
+		}
+	})
+	server = httptest.NewServer(mux)
+	defer server.Close()
+	api := &compute{client: server.Client(), baseURL: server.URL + "/"}
+	scaleSet := ScaleSet{SubscriptionID: "sub", ResourceGroup: "group", Name: "nodes"}
+
+	vms, err := api.ListVMs(context.TODO(), scaleSet)
+	c.Assert(err, check.IsNil)
+	c.Assert(vms, check.DeepEquals, []VM{
+		newVM("0", "node-1", "Succeeded"),
+		newVM("1", "node-2", ProvisioningStateDeleting),
+	})
+
+	tags, err := api.GetTags(context.TODO(), scaleSet)
+	c.Assert(err, check.IsNil)
+	tags["key"] = "value"
+	c.Assert(api.UpdateTags(context.TODO(), scaleSet, tags), check.IsNil)
+	c.Assert(updated, check.DeepEquals, map[string]map[string]string{
+		"tags": {"env": "prod", "key": "value"},
+	})
+
+	_, err = api.GetTags(context.TODO(), ScaleSet{SubscriptionID: "sub", ResourceGroup: "group", Name: "missing"})
+	c.Assert(trace.IsNotFound(err), check.Equals, true, check.Commentf("%v", err))
+}
+
+func (s *AzureSuite) newGroup(c *check.C, compute Compute) *Group {
+	group, err := NewGroup(Config{
+		ClusterName: "example.com",
+		ScaleSet:    ScaleSet{SubscriptionID: "sub", ResourceGroup: "group", Name: "nodes"},
+		Compute:     compute,
+	})
+	c.Assert(err, check.IsNil)
+	return group
+}
+
+type mockCompute struct {
+	vms  []VM
+	tags map[string]string
+}
+
+func (r *mockCompute) ListVMs(context.Context, ScaleSet) ([]VM, error) {
+	return r.vms, nil
+}
+
+func (r *mockCompute) GetTags(context.Context, ScaleSet) (map[string]string, error) {
+	tags := make(map[string]string)
+	for key, value := range r.tags {
+		tags[key] = value
+	}
+	return tags, nil
+}
+
+func (r *mockCompute) UpdateTags(ctx context.Context, scaleSet ScaleSet, tags map[string]string) error {
+	r.tags = tags
+	return nil
+}
+
+func newVM(id, computerName, state string) VM {
+	vm := VM{InstanceID: id, Name: "nodes_" + id}
+	vm.Properties.ProvisioningState = state
+	vm.Properties.OSProfile.ComputerName = computerName
+	return vm
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,120 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package azure
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gravitational/trace"
+)
+
+// InstanceMetadata describes the virtual machine as reported
+// by the Azure instance metadata service
+type InstanceMetadata struct {
+	// SubscriptionID is the ID of the subscription
+	SubscriptionID string `json:"subscriptionId"`
+	// ResourceGroup is the name of the resource group
+	ResourceGroup string `json:"resourceGroupName"`
+	// Name is the name of the virtual machine
+	Name string `json:"name"`
+	// ScaleSetName is the name of the scale set of the virtual machine
+	ScaleSetName string `json:"vmScaleSetName"`
+	// Tags lists the tags of the virtual machine in key1:value1;key2:value2 format
+	Tags string `json:"tags"`
+}
+
+// Tag returns the value of the tag specified with key
+func (r InstanceMetadata) Tag(key string) (value string, ok bool) {
+	for _, tag := range strings.Split(r.Tags, ";") {
+		parts := strings.SplitN(tag, ":", 2)
+		if len(parts) == 2 && parts[0] == key {
+			return parts[1], true
+		}
+	}
+	return "", false
+}
+
+// GetInstanceMetadata queries the instance metadata service
+// of the virtual machine
+func GetInstanceMetadata(ctx context.Context) (*InstanceMetadata, error) {
+	req, err := http.NewRequest(http.MethodGet, instanceMetadataURL, nil)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	req.Header.Set("Metadata", "true")
+	resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, trace.ConvertSystemError(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, trace.BadParameter("instance metadata service returned %v", resp.Status)
+	}
+	var metadata InstanceMetadata
+	if err := json.NewDecoder(resp.Body).Decode(&metadata); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &metadata, nil
+}
+
+// GetPrivateIP returns the private IP address of the primary
+// network interface of the virtual machine
+func GetPrivateIP(ctx context.Context) (string, error) {
+	req, err := http.NewRequest(http.MethodGet, privateIPURL, nil)
+	if err != nil {
+		return "", trace.Wrap(err)
+	}
+	req.Header.Set("Metadata", "true")
+	resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return "", trace.ConvertSystemError(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", trace.BadParameter("instance metadata service returned %v", resp.Status)
+	}
+	addr, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", trace.Wrap(err)
+	}
+	return strings.TrimSpace(string(addr)), nil
+}
+
+// OnAzure returns true if the process is running on an Azure virtual machine
+func OnAzure(ctx context.Context) bool {
+	ctx, cancel := context.WithTimeout(ctx, metadataTimeout)
+	defer cancel()
+	_, err := GetInstanceMetadata(ctx)
+	return err == nil
+}
+
+const (
+	// instanceMetadataURL is the endpoint of the instance metadata service
+	// returning the compute metadata of the virtual machine
+	instanceMetadataURL = "http://169.254.169.254/metadata/instance/compute?api-version=2018-10-01"
+	// privateIPURL is the endpoint of the instance metadata service
+	// returning the private IP address of the primary network interface
+	privateIPURL = "http://169.254.169.254/metadata/instance/network/interface/0/ipv4/ipAddress/0/privateIpAddress?api-version=2018-10-01&format=text"
+	// metadataTimeout is the time to wait for the instance metadata
+	// service to respond when detecting Azure
+	metadataTimeout = 2 * time.Second
+)
@@ -0,0 +1,57 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package autoscale
+
+import (
+	"fmt"
+
+	"github.com/gravitational/gravity/lib/constants"
+
+	"github.com/gravitational/trace"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// GetServiceURL returns the URL of the cluster service exposed
+// by the cloud load balancer that instances use to join the cluster
+func GetServiceURL(client kubernetes.Interface) (string, error) {
+	service, err := client.CoreV1().Services(constants.KubeSystemNamespace).Get(constants.GravityServiceName, v1.GetOptions{})
+	if err != nil {
+		return "", trace.Wrap(err)
+	}
+	var port int32
+	for _, p := range service.Spec.Ports {
+		if p.Name == constants.GravityServicePortName {
+			port = p.Port
+			break
+		}
+	}
+	if port == 0 {
+		return "", trace.NotFound("no port %q found for service %q", constants.GravityServicePortName, constants.GravityServiceName)
+	}
+	for _, ingress := range service.Status.LoadBalancer.Ingress {
+		// AWS load balancers are addressed by hostname while
+		// GCE and Azure load balancers are assigned an IP address
+		if ingress.Hostname != "" {
+			return fmt.Sprintf("https://%v:%v", ingress.Hostname, port), nil
+		}
+		if ingress.IP != "" {
+			return fmt.Sprintf("https://%v:%v", ingress.IP, port), nil
+		}
+	}
+	return "", trace.NotFound("ingress load balancer not found for %v", constants.GravityServiceName)
+}
@@ -0,0 +1,243 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gce
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+
+	"github.com/gravitational/trace"
+	"golang.org/x/oauth2/google"
+)
+
+// Compute is the subset of the GCE compute API used by the autoscaler
+type Compute interface {
+	// ListManagedInstances returns the instances of the managed instance group
+	// specified with groupPath relative to the project, for example
+	// zones/us-central1-a/instanceGroupManagers/nodes
+	ListManagedInstances(ctx context.Context, project, groupPath string) ([]ManagedInstance, error)
+	// GetProjectMetadata returns the common instance metadata of the project
+	GetProjectMetadata(ctx context.Context, project string) (*Metadata, error)
+	// SetProjectMetadata replaces the common instance metadata of the project.
+	// The metadata fingerprint must match the current one
+	SetProjectMetadata(ctx context.Context, project string, metadata Metadata) error
+}
+
+// ManagedInstance describes an instance of a managed instance group
+type ManagedInstance struct {
+	// Instance is the URL of the instance resource
+	Instance string `json:"instance"`
+	// ID is the unique instance ID
+	ID string `json:"id"`
+	// CurrentAction is the action the group manager is performing on the instance
+	CurrentAction string `json:"currentAction"`
+}
+
+// Name returns the name of the instance
+func (r ManagedInstance) Name() string {
+	return path.Base(r.Instance)
+}
+
+// Metadata is the GCE metadata resource
+type Metadata struct {
+	// Fingerprint is the hash of the metadata used for optimistic locking
+	Fingerprint string `json:"fingerprint,omitempty"`
+	// Items lists the metadata entries
+	Items []MetadataItem `json:"items,omitempty"`
+}
+
+// Get returns the value of the metadata entry specified with key
+func (r Metadata) Get(key string) (value string, ok bool) {
+	for _, item := range r.Items {
+		if item.Key == key {
+			return item.Value, true
+		}
+	}
+	return "", false
+}
+
+// Set sets the value of the metadata entry specified with key
+func (r *Metadata) Set(key, value string) {
+	for i, item := range r.Items {
+		if item.Key == key {
+			r.Items[i].Value = value
+			return
+		}
+	}
+	r.Items = append(r.Items, MetadataItem{Key: key, Value: value})
+}
+
+// MetadataItem is a single metadata entry
+type MetadataItem struct {
+	// Key is the entry key
+	Key string `json:"key"`
+	// Value is the entry value
+	Value string `json:"value"`
+}
+
+const (
+	// ActionDeleting is the managed instance action
+	// when the instance is being deleted
+	ActionDeleting = "DELETING"
+	// ActionAbandoning is the managed instance action
+	// when the instance is being removed from the group
+	ActionAbandoning = "ABANDONING"
+)
+
+// NewCompute returns a new client of the GCE compute API that uses
+// the application default credentials, the instance service account on GCE
+func NewCompute(ctx context.Context) (*compute, error) {
+	client, err := google.DefaultClient(ctx, computeScope)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &compute{client: client, baseURL: computeURL}, nil
+}
+
+// ListManagedInstances returns the instances of the managed instance group
+func (r *compute) ListManagedInstances(ctx context.Context, project, groupPath string) ([]ManagedInstance, error) {
+	var instances []ManagedInstance
+	var pageToken string
+	for {
+		query := url.Values{}
+		if pageToken != "" {
+			query.Set("pageToken", pageToken)
+		}
+		var out struct {
+			ManagedInstances []ManagedInstance `json:"managedInstances"`
+			NextPageToken    string            `json:"nextPageToken"`
+		}
+		err := r.do(ctx, http.MethodPost,
+			r.endpoint(query, "projects", project, groupPath, "listManagedInstances"), nil, &out)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		instances = append(instances, out.ManagedInstances...)
+		if out.NextPageToken == "" {
+			return instances, nil
+		}
+		pageToken = out.NextPageToken
+	}
+}
+
+// GetProjectMetadata returns the common instance metadata of the project
+func (r *compute) GetProjectMetadata(ctx context.Context, project string) (*Metadata, error) {
+	var out struct {
+		CommonInstanceMetadata Metadata `json:"commonInstanceMetadata"`
+	}
+	err := r.do(ctx, http.MethodGet, r.endpoint(nil, "projects", project), nil, &out)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &out.CommonInstanceMetadata, nil
+}
+
+// SetProjectMetadata replaces the common instance metadata of the project
+func (r *compute) SetProjectMetadata(ctx context.Context, project string, metadata Metadata) error {
+	err := r.do(ctx, http.MethodPost,
+		r.endpoint(nil, "projects", project, "setCommonInstanceMetadata"), metadata, nil)
+	return trace.Wrap(err)
+}
+
+func (r *compute) endpoint(query url.Values, elems ...string) string {
+	endpoint := r.baseURL + path.Join(elems...)
+	if len(query) != 0 {
+		endpoint = endpoint + "?" + query.Encode()
+	}
+	return endpoint
+}
+
+func (r *compute) do(ctx context.Context, method, endpoint string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, endpoint, body)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	req = req.WithContext(ctx)
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if err := convertResponse(resp.StatusCode, data); err != nil {
+		return trace.Wrap(err)
+	}
+	if out == nil {
+		return nil
+	}
+	return trace.Wrap(json.Unmarshal(data, out))
+}
+
+// convertResponse converts the error response of the compute API
+func convertResponse(code int, data []byte) error {
+	if code >= http.StatusOK && code < http.StatusMultipleChoices {
+		return nil
+	}
+	var out struct {
+		Error struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	message := string(data)
+	if err := json.Unmarshal(data, &out); err == nil && out.Error.Message != "" {
+		message = out.Error.Message
+	}
+	switch code {
+	case http.StatusNotFound:
+		return trace.NotFound("%v", message)
+	case http.StatusConflict:
+		return trace.AlreadyExists("%v", message)
+	case http.StatusPreconditionFailed:
+		return trace.CompareFailed("%v", message)
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return trace.AccessDenied("%v", message)
+	}
+	return trace.BadParameter("compute API error (%v): %v", code, message)
+}
+
+type compute struct {
+	client *http.Client
+	// baseURL is the URL of the compute API
+	baseURL string
+}
+
+const (
+	// computeURL is the URL of the GCE compute API
+	computeURL = "https://www.googleapis.com/compute/v1/"
+	// computeScope is the OAuth2 scope of the GCE compute API
+	computeScope = "https://www.googleapis.com/auth/compute"
+)
@@ -0,0 +1,204 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gce
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+
+	"github.com/gravitational/gravity/lib/autoscale"
+
+	"cloud.google.com/go/compute/metadata"
+	"github.com/gravitational/trace"
+	"github.com/sirupsen/logrus"
+)
+
+// Config is the configuration of the GCE managed instance group
+type Config struct {
+	// ClusterName is the name of the cluster
+	ClusterName string
+	// Project is the ID of the GCE project
+	Project string
+	// GroupPath is the path of the managed instance group relative
+	// to the project, for example zones/us-central1-a/instanceGroupManagers/nodes
+	GroupPath string
+	// Compute is the GCE compute API client
+	Compute Compute
+	// FieldLogger is used for logging
+	logrus.FieldLogger
+}
+
+// CheckAndSetDefaults checks and sets default values
+func (c *Config) CheckAndSetDefaults() error {
+	if c.ClusterName == "" {
+		return trace.BadParameter("missing parameter ClusterName")
+	}
+	if c.Project == "" {
+		return trace.BadParameter("missing parameter Project")
+	}
+	if c.GroupPath == "" {
+		return trace.BadParameter("missing parameter GroupPath")
+	}
+	if c.Compute == nil {
+		return trace.BadParameter("missing parameter Compute")
+	}
+	if c.FieldLogger == nil {
+		c.FieldLogger = logrus.WithFields(logrus.Fields{
+			trace.Component: "autoscale:gce",
+			"group":         c.GroupPath,
+		})
+	}
+	return nil
+}
+
+// NewGroup returns a new managed instance group
+func NewGroup(config Config) (*Group, error) {
+	if err := config.CheckAndSetDefaults(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &Group{Config: config}, nil
+}
+
+// NewLocalGroup returns the managed instance group of the cluster
+// configured with the GroupAttribute metadata attribute of this instance
+func NewLocalGroup(ctx context.Context, clusterName string) (*Group, error) {
+	project, err := metadata.ProjectID()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	groupPath, err := metadata.InstanceAttributeValue(GroupAttribute)
+	if err != nil {
+		if _, ok := err.(metadata.NotDefinedError); ok {
+			return nil, trace.NotFound("instance attribute %v is not set", GroupAttribute)
+		}
+		return nil, trace.Wrap(err)
+	}
+	compute, err := NewCompute(ctx)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return NewGroup(Config{
+		ClusterName: clusterName,
+		Project:     project,
+		GroupPath:   groupPath,
+		Compute:     compute,
+	})
+}
+
+// Group is a GCE managed instance group
+type Group struct {
+	// Config is the group configuration
+	Config
+}
+
+// ListInstances returns the instances of the group.
+// Instances being deleted or abandoned by the group are marked as terminating
+func (r *Group) ListInstances(ctx context.Context) ([]autoscale.Instance, error) {
+	managed, err := r.Compute.ListManagedInstances(ctx, r.Project, r.GroupPath)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	instances := make([]autoscale.Instance, 0, len(managed))
+	for _, instance := range managed {
+		instances = append(instances, autoscale.Instance{
+			ID:   instance.ID,
+			Name: instance.Name(),
+			Terminating: instance.CurrentAction == ActionDeleting ||
+				instance.CurrentAction == ActionAbandoning,
+		})
+	}
+	return instances, nil
+}
+
+// PublishJoinParameters publishes the join parameters
+// in the project metadata
+func (r *Group) PublishJoinParameters(ctx context.Context, params autoscale.JoinParameters) error {
+	projectMetadata, err := r.Compute.GetProjectMetadata(ctx, r.Project)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	projectMetadata.Set(TokenKey(r.ClusterName), params.Token)
+	projectMetadata.Set(ServiceURLKey(r.ClusterName), params.ServiceURL)
+	// The update is rejected if the metadata has been modified since
+	// it was read, it will be retried with the next publish
+	err = r.Compute.SetProjectMetadata(ctx, r.Project, *projectMetadata)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return nil
+}
+
+// NewDiscovery returns the discovery of the join parameters
+// published in the project metadata for the specified cluster
+func NewDiscovery(clusterName string) *Discovery {
+	return &Discovery{
+		ClusterName:           clusterName,
+		ProjectAttributeValue: metadata.ProjectAttributeValue,
+	}
+}
+
+// Discovery reads the join parameters from the project metadata
+type Discovery struct {
+	// ClusterName is the name of the cluster
+	ClusterName string
+	// ProjectAttributeValue returns the value of the project metadata attribute
+	ProjectAttributeValue func(attr string) (string, error)
+}
+
+// GetJoinToken returns the cluster join token
+func (r *Discovery) GetJoinToken(context.Context) (string, error) {
+	return r.get(TokenKey(r.ClusterName))
+}
+
+// GetServiceURL returns the URL of the cluster service to join
+func (r *Discovery) GetServiceURL(context.Context) (string, error) {
+	return r.get(ServiceURLKey(r.ClusterName))
+}
+
+func (r *Discovery) get(key string) (string, error) {
+	value, err := r.ProjectAttributeValue(key)
+	if err != nil {
+		if _, ok := err.(metadata.NotDefinedError); ok {
+			return "", trace.NotFound("project attribute %v is not set", key)
+		}
+		return "", trace.Wrap(err)
+	}
+	return value, nil
+}
+
+// TokenKey returns the project metadata key of the cluster join token
+func TokenKey(clusterName string) string {
+	return fmt.Sprintf("gravity-%v-token", safeName(clusterName))
+}
+
+// ServiceURLKey returns the project metadata key of the cluster service URL
+func ServiceURLKey(clusterName string) string {
+	return fmt.Sprintf("gravity-%v-service", safeName(clusterName))
+}
+
+// safeName returns the cluster name with the characters
+// not accepted in metadata keys replaced with dashes
+func safeName(clusterName string) string {
+	return unsafeKeyChars.ReplaceAllString(clusterName, "-")
+}
+
+var unsafeKeyChars = regexp.MustCompile("[^a-zA-Z0-9_-]")
+
+// GroupAttribute is the instance metadata attribute of master instances
+// with the path of the managed instance group of the cluster
+const GroupAttribute = "gravity-instance-group"
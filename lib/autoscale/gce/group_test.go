@@ -0,0 +1,199 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gce
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gravitational/gravity/lib/autoscale"
+
+	"cloud.google.com/go/compute/metadata"
+	"github.com/gravitational/trace"
+	"gopkg.in/check.v1"
+)
+
+func TestGCE(t *testing.T) { check.TestingT(t) }
+
+type GCESuite struct{}
+
+var _ = check.Suite(&GCESuite{})
+
+func (s *GCESuite) TestListsInstances(c *check.C) {
+	compute := &mockCompute{
+		instances: []ManagedInstance{
+			{ID: "1", Instance: instanceURL("node-1"), CurrentAction: "NONE"},
+			{ID: "2", Instance: instanceURL("node-2"), CurrentAction: ActionDeleting},
+			{ID: "3", Instance: instanceURL("node-3"), CurrentAction: ActionAbandoning},
+		},
+	}
+	group := s.newGroup(c, compute)
+	instances, err := group.ListInstances(context.TODO())
+	c.Assert(err, check.IsNil)
+	c.Assert(instances, check.DeepEquals, []autoscale.Instance{
+		{ID: "1", Name: "node-1"},
+		{ID: "2", Name: "node-2", Terminating: true},
+		{ID: "3", Name: "node-3", Terminating: true},
+	})
+}
+
+func (s *GCESuite) TestPublishesJoinParameters(c *check.C) {
+	compute := &mockCompute{
+		metadata: Metadata{
+			Fingerprint: "abc",
+			Items: []MetadataItem{
+				{Key: "ssh-keys", Value: "key"},
+				{Key: "gravity-example-com-token", Value: "old-token"},
+			},
+		},
+	}
+	group := s.newGroup(c, compute)
+	err := group.PublishJoinParameters(context.TODO(), autoscale.JoinParameters{
+		Token:      "token",
+		ServiceURL: "https://10.0.0.1:3009",
+	})
+	c.Assert(err, check.IsNil)
+	c.Assert(compute.metadata, check.DeepEquals, Metadata{
+		Fingerprint: "abc",
+		Items: []MetadataItem{
+			{Key: "ssh-keys", Value: "key"},
+			{Key: "gravity-example-com-token", Value: "token"},
+			{Key: "gravity-example-com-service", Value: "https://10.0.0.1:3009"},
+		},
+	})
+
+	discovery := &Discovery{
+		ClusterName: "example.com",
+		ProjectAttributeValue: func(attr string) (string, error) {
+			value, ok := compute.metadata.Get(attr)
+			if !ok {
+				return "", metadata.NotDefinedError(attr)
+			}
+			return value, nil
+		},
+	}
+	token, err := discovery.GetJoinToken(context.TODO())
+	c.Assert(err, check.IsNil)
+	c.Assert(token, check.Equals, "token")
+	serviceURL, err := discovery.GetServiceURL(context.TODO())
+	c.Assert(err, check.IsNil)
+	c.Assert(serviceURL, check.Equals, "https://10.0.0.1:3009")
+
+	discovery.ClusterName = "other"
+	_, err = discovery.GetJoinToken(context.TODO())
+	c.Assert(trace.IsNotFound(err), check.Equals, true)
+}
+
+func (s *GCESuite) TestComputeAPI(c *check.C) {
+	var setMetadata Metadata
+	mux := http.NewServeMux()
+	mux.HandleFunc("/projects/project/zones/zone/instanceGroupManagers/nodes/listManagedInstances",
+		func(w http.ResponseWriter, r *http.Request) {
+			c.Assert(r.Method, check.Equals, http.MethodPost)
+			if r.URL.Query().Get("pageToken") == "" {
+				writeJSON(w, map[string]interface{}{
+					"managedInstances": []ManagedInstance{{ID: "1", Instance: instanceURL("node-1")}},
+					"nextPageToken":    "next",
+				})
+				return
+			}
+			writeJSON(w, map[string]interface{}{
+				"managedInstances": []ManagedInstance{{ID: "2", Instance: instanceURL("node-2")}},
+			})
+		})
+	mux.HandleFunc("/projects/project", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]interface{}{
+			"commonInstanceMetadata": Metadata{Fingerprint: "abc"},
+		})
+	})
+	mux.HandleFunc("/projects/project/setCommonInstanceMetadata", func(w http.ResponseWriter, r *http.Request) {
+		c.Assert(json.NewDecoder(r.Body).Decode(&setMetadata), check.IsNil)
+		if setMetadata.Fingerprint != "abc" {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			writeJSON(w, map[string]interface{}{
+				"error": map[string]string{"message": "fingerprint mismatch"},
+			})
+			return
+		}
+		writeJSON(w, map[string]string{"kind": "compute#operation"})
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	api := &compute{client: server.Client(), baseURL: server.URL + "/"}
+
+	instances, err := api.ListManagedInstances(context.TODO(), "project", "zones/zone/instanceGroupManagers/nodes")
+	c.Assert(err, check.IsNil)
+	c.Assert(instances, check.HasLen, 2)
+	c.Assert(instances[1].Name(), check.Equals, "node-2")
+
+	projectMetadata, err := api.GetProjectMetadata(context.TODO(), "project")
+	c.Assert(err, check.IsNil)
+	projectMetadata.Set("key", "value")
+	c.Assert(api.SetProjectMetadata(context.TODO(), "project", *projectMetadata), check.IsNil)
+	c.Assert(setMetadata, check.DeepEquals, Metadata{
+		Fingerprint: "abc",
+		Items:       []MetadataItem{{Key: "key", Value: "value"}},
+	})
+
+	err = api.SetProjectMetadata(context.TODO(), "project", Metadata{Fingerprint: "stale"})
+	c.Assert(trace.IsCompareFailed(err), check.Equals, true, check.Commentf("%v", err))
+}
+
+func (s *GCESuite) newGroup(c *check.C, compute Compute) *Group {
+	group, err := NewGroup(Config{
+		ClusterName: "example.com",
+		Project:     "project",
+		GroupPath:   "zones/zone/instanceGroupManagers/nodes",
+		Compute:     compute,
+	})
+	c.Assert(err, check.IsNil)
+	return group
+}
+
+type mockCompute struct {
+	instances []ManagedInstance
+	metadata  Metadata
+}
+
+func (r *mockCompute) ListManagedInstances(context.Context, string, string) ([]ManagedInstance, error) {
+	return r.instances, nil
+}
+
+func (r *mockCompute) GetProjectMetadata(context.Context, string) (*Metadata, error) {
+	metadata := Metadata{Fingerprint: r.metadata.Fingerprint}
+	metadata.Items = append(metadata.Items, r.metadata.Items...)
+	return &metadata, nil
+}
+
+func (r *mockCompute) SetProjectMetadata(ctx context.Context, project string, metadata Metadata) error {
+	if metadata.Fingerprint != r.metadata.Fingerprint {
+		return trace.CompareFailed("fingerprint mismatch")
+	}
+	r.metadata = metadata
+	return nil
+}
+
+func instanceURL(name string) string {
+	return "https://www.googleapis.com/compute/v1/projects/project/zones/zone/instances/" + name
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,295 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package autoscale
+
+import (
+	"context"
+	"time"
+
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/ops"
+	"github.com/gravitational/gravity/lib/storage"
+
+	"github.com/gravitational/trace"
+	"github.com/sirupsen/logrus"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Config is the configuration of the autoscaler for a group
+type Config struct {
+	// Group is the autoscaling group
+	Group Group
+	// Client is the kubernetes client used to discover the cluster service.
+	// Only required to publish discovery information
+	Client kubernetes.Interface
+	// GetServiceURL returns the URL of the cluster service.
+	// Defaults to the URL of the cluster service load balancer
+	GetServiceURL func() (string, error)
+	// Safety evaluates whether the nodes can be removed from the cluster.
+	// Nodes are removed without checks if unspecified
+	Safety Checker
+	// Members persists the nodes observed as members of the group.
+	// Defaults to a ConfigMap if Client is set and to memory otherwise
+	Members MemberStore
+	// PollInterval is the interval between group polls
+	PollInterval time.Duration
+	// FieldLogger is used for logging
+	logrus.FieldLogger
+}
+
+// CheckAndSetDefaults checks and sets default values
+func (c *Config) CheckAndSetDefaults() error {
+	if c.Group == nil {
+		return trace.BadParameter("missing parameter Group")
+	}
+	if c.GetServiceURL == nil {
+		c.GetServiceURL = func() (string, error) {
+			if c.Client == nil {
+				return "", trace.BadParameter("missing parameter Client")
+			}
+			return GetServiceURL(c.Client)
+		}
+	}
+	if c.Members == nil {
+		if c.Client != nil {
+			c.Members = NewConfigMapMembers(c.Client)
+		} else {
+			c.Members = newMemoryMembers()
+		}
+	}
+	if c.PollInterval == 0 {
+		c.PollInterval = defaults.AutoscaleGroupPollInterval
+	}
+	if c.FieldLogger == nil {
+		c.FieldLogger = logrus.WithField(trace.Component, "autoscale")
+	}
+	return nil
+}
+
+// New returns a new autoscaler for the group that does not deliver
+// instance lifecycle notifications.
+//
+// The autoscaler polls the group and removes the nodes which instances
+// are being removed from the group or have disappeared from the group.
+// Nodes that have never been observed as members of the group, for example
+// masters that are not part of it, are never removed. The observed members
+// are persisted with the configured MemberStore to survive restarts
+// of the autoscaler and changes of the leader
+func New(config Config) (*groupAutoscaler, error) {
+	if err := config.CheckAndSetDefaults(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &groupAutoscaler{
+		Config:   config,
+		removing: make(map[string]struct{}),
+	}, nil
+}
+
+// ProcessEvents polls the group and removes the nodes
+// terminated by the group from the cluster
+func (a *groupAutoscaler) ProcessEvents(ctx context.Context, operator Operator) {
+	a.Info("Start watching autoscaling group.")
+	ticker := time.NewTicker(a.PollInterval)
+	defer ticker.Stop()
+	for {
+		if err := a.processGroup(ctx, operator); err != nil {
+			a.Errorf("Failed to process autoscaling group: %v.", trace.DebugReport(err))
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			a.Info("Stop watching autoscaling group.")
+			return
+		}
+	}
+}
+
+// PublishDiscovery periodically publishes the join parameters
+// for the instances of the group
+func (a *groupAutoscaler) PublishDiscovery(ctx context.Context, operator ops.Operator) {
+	a.Info("Start publishing discovery info.")
+	if err := a.syncDiscovery(ctx, operator, true); err != nil {
+		a.Errorf("Failed to publish discovery: %v.", trace.DebugReport(err))
+	}
+	publishTicker := time.NewTicker(defaults.DiscoveryPublishInterval)
+	defer publishTicker.Stop()
+	resyncTicker := time.NewTicker(defaults.DiscoveryResyncInterval)
+	defer resyncTicker.Stop()
+	for {
+		var force bool
+		select {
+		case <-ctx.Done():
+			a.Info("Stop publishing discovery info.")
+			return
+		case <-publishTicker.C:
+		case <-resyncTicker.C:
+			force = true
+		}
+		if err := a.syncDiscovery(ctx, operator, force); err != nil {
+			a.Errorf("Failed to publish discovery: %v.", trace.DebugReport(err))
+		}
+	}
+}
+
+// processGroup removes the nodes which instances are being removed
+// or have disappeared from the group
+func (a *groupAutoscaler) processGroup(ctx context.Context, operator Operator) error {
+	instances, err := a.Group.ListInstances(ctx)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	cluster, err := operator.GetLocalSite()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	members, err := a.Members.GetMembers()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	var changed bool
+	servers := make(map[string]struct{})
+	for _, server := range cluster.ClusterState.Servers {
+		servers[server.Hostname] = struct{}{}
+		instance := findInstance(instances, server)
+		switch {
+		case instance != nil:
+			if _, ok := members[server.Hostname]; !ok {
+				members[server.Hostname] = struct{}{}
+				changed = true
+			}
+			if !instance.Terminating {
+				continue
+			}
+		default:
+			if _, ok := members[server.Hostname]; !ok {
+				// Not a member of the group
+				continue
+			}
+		}
+		if _, ok := a.removing[server.Hostname]; ok {
+			continue
+		}
+		if a.Safety != nil {
+			if instance == nil {
+				// The instance that has already disappeared can be neither
+				// drained nor kept, including the one which removal has been
+				// delayed or rejected, so the node is removed regardless
+				if err := a.Safety.RecordTermination(server); err != nil {
+					a.WithError(err).WithField("node", server.Hostname).Warn("Failed to record node termination.")
+				}
+			} else {
+				verdict, err := a.Safety.PrepareRemoval(ctx, *cluster, server)
+				if err != nil {
+					a.WithError(err).WithField("node", server.Hostname).Warn("Failed to prepare node removal.")
+					continue
+				}
+				if verdict.Decision != DecisionDrain {
+					// Re-evaluated with the next poll
+					continue
+				}
+			}
+		}
+		if err := a.removeServer(ctx, operator, *cluster, server); err != nil {
+			a.WithError(err).WithField("node", server.Hostname).Warn("Failed to remove node.")
+			continue
+		}
+		a.removing[server.Hostname] = struct{}{}
+	}
+	// Forget the nodes that have left the cluster
+	for hostname := range members {
+		if _, ok := servers[hostname]; !ok {
+			delete(members, hostname)
+			changed = true
+		}
+	}
+	for hostname := range a.removing {
+		if _, ok := servers[hostname]; !ok {
+			delete(a.removing, hostname)
+		}
+	}
+	if changed {
+		return trace.Wrap(a.Members.SetMembers(members))
+	}
+	return nil
+}
+
+func (a *groupAutoscaler) removeServer(ctx context.Context, operator Operator, cluster ops.Site, server storage.Server) error {
+	key, err := operator.CreateSiteShrinkOperation(ctx,
+		ops.CreateSiteShrinkOperationRequest{
+			AccountID:   cluster.AccountID,
+			SiteDomain:  cluster.Domain,
+			Servers:     []string{server.Hostname},
+			Force:       true,
+			NodeRemoved: true,
+		})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	a.WithField("operation", key.OperationID).Infof("Initiated shrink operation for node %v.", server.Hostname)
+	return nil
+}
+
+// syncDiscovery publishes the join parameters if they have changed
+// since the last time or if force is set
+func (a *groupAutoscaler) syncDiscovery(ctx context.Context, operator ops.Operator, force bool) error {
+	cluster, err := operator.GetLocalSite()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	joinToken, err := operator.GetExpandToken(cluster.Key())
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	serviceURL, err := a.GetServiceURL()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	params := JoinParameters{
+		Token:      joinToken.Token,
+		ServiceURL: serviceURL,
+	}
+	if params == a.published && !force {
+		return nil
+	}
+	if err := a.Group.PublishJoinParameters(ctx, params); err != nil {
+		return trace.Wrap(err)
+	}
+	a.WithField("service-url", serviceURL).Debug("Published join parameters.")
+	a.published = params
+	return nil
+}
+
+// findInstance returns the instance of the specified server
+// or nil if the server is not an instance of the group
+func findInstance(instances []Instance, server storage.Server) *Instance {
+	for i, instance := range instances {
+		if (server.InstanceID != "" && instance.ID == server.InstanceID) ||
+			instance.Name == server.Hostname {
+			return &instances[i]
+		}
+	}
+	return nil
+}
+
+type groupAutoscaler struct {
+	// Config is the autoscaler configuration
+	Config
+	// removing is the set of nodes being removed from the cluster
+	removing map[string]struct{}
+	// published is the last published join parameters
+	published JoinParameters
+}
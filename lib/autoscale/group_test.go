@@ -0,0 +1,227 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package autoscale
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gravitational/gravity/lib/ops"
+	"github.com/gravitational/gravity/lib/storage"
+
+	"github.com/gravitational/trace"
+	"gopkg.in/check.v1"
+)
+
+func TestAutoscale(t *testing.T) { check.TestingT(t) }
+
+type GroupSuite struct{}
+
+var _ = check.Suite(&GroupSuite{})
+
+func (s *GroupSuite) TestRemovesTerminatingInstances(c *check.C) {
+	group := &mockGroup{instances: []Instance{
+		{ID: "1", Name: "node-1"},
+		{ID: "2", Name: "node-2", Terminating: true},
+	}}
+	operator := newMockOperator("master", "node-1", "node-2")
+	a, err := New(Config{Group: group})
+	c.Assert(err, check.IsNil)
+
+	c.Assert(a.processGroup(context.TODO(), operator), check.IsNil)
+	c.Assert(operator.shrinks, check.DeepEquals, []string{"node-2"})
+
+	// the shrink operation is not repeated while the node is being removed
+	c.Assert(a.processGroup(context.TODO(), operator), check.IsNil)
+	c.Assert(operator.shrinks, check.DeepEquals, []string{"node-2"})
+}
+
+func (s *GroupSuite) TestRemovesVanishedInstances(c *check.C) {
+	group := &mockGroup{instances: []Instance{
+		{ID: "1", Name: "node-1"},
+		{ID: "2", Name: "node-2"},
+	}}
+	operator := newMockOperator("master", "node-1", "node-2")
+	a, err := New(Config{Group: group})
+	c.Assert(err, check.IsNil)
+
+	c.Assert(a.processGroup(context.TODO(), operator), check.IsNil)
+	c.Assert(operator.shrinks, check.HasLen, 0)
+
+	group.instances = group.instances[:1]
+	c.Assert(a.processGroup(context.TODO(), operator), check.IsNil)
+	// master has never been a member of the group and is kept
+	c.Assert(operator.shrinks, check.DeepEquals, []string{"node-2"})
+}
+
+func (s *GroupSuite) TestRemovesInstancesVanishedDuringRestart(c *check.C) {
+	group := &mockGroup{instances: []Instance{
+		{ID: "1", Name: "node-1"},
+		{ID: "2", Name: "node-2"},
+	}}
+	operator := newMockOperator("master", "node-1", "node-2")
+	members := newMemoryMembers()
+	a, err := New(Config{Group: group, Members: members})
+	c.Assert(err, check.IsNil)
+	c.Assert(a.processGroup(context.TODO(), operator), check.IsNil)
+
+	// the instance disappears while another autoscaler takes over
+	group.instances = group.instances[:1]
+	a, err = New(Config{Group: group, Members: members})
+	c.Assert(err, check.IsNil)
+	c.Assert(a.processGroup(context.TODO(), operator), check.IsNil)
+	c.Assert(operator.shrinks, check.DeepEquals, []string{"node-2"})
+
+	// the node is forgotten once it has left the cluster
+	operator.cluster.ClusterState.Servers = operator.cluster.ClusterState.Servers[:2]
+	c.Assert(a.processGroup(context.TODO(), operator), check.IsNil)
+	hostnames, err := members.GetMembers()
+	c.Assert(err, check.IsNil)
+	c.Assert(hostnames, check.DeepEquals, map[string]struct{}{"node-1": {}})
+}
+
+func (s *GroupSuite) TestRemovesVanishedInstancesDespiteVerdict(c *check.C) {
+	group := &mockGroup{instances: []Instance{
+		{ID: "1", Name: "node-1", Terminating: true},
+	}}
+	operator := newMockOperator("node-1")
+	checker := &mockChecker{verdict: Verdict{Decision: DecisionReject}}
+	a, err := New(Config{Group: group, Safety: checker})
+	c.Assert(err, check.IsNil)
+
+	c.Assert(a.processGroup(context.TODO(), operator), check.IsNil)
+	c.Assert(operator.shrinks, check.HasLen, 0)
+
+	// the instance is deleted by the group regardless of the verdict
+	group.instances = nil
+	c.Assert(a.processGroup(context.TODO(), operator), check.IsNil)
+	c.Assert(checker.terminated, check.DeepEquals, []string{"node-1"})
+	c.Assert(operator.shrinks, check.DeepEquals, []string{"node-1"})
+}
+
+func (s *GroupSuite) TestRetriesFailedShrink(c *check.C) {
+	group := &mockGroup{instances: []Instance{
+		{ID: "1", Name: "node-1", Terminating: true},
+	}}
+	operator := newMockOperator("node-1")
+	operator.err = trace.CompareFailed("another operation is in progress")
+	a, err := New(Config{Group: group})
+	c.Assert(err, check.IsNil)
+
+	c.Assert(a.processGroup(context.TODO(), operator), check.IsNil)
+	c.Assert(operator.shrinks, check.HasLen, 0)
+
+	operator.err = nil
+	c.Assert(a.processGroup(context.TODO(), operator), check.IsNil)
+	c.Assert(operator.shrinks, check.DeepEquals, []string{"node-1"})
+}
+
+func (s *GroupSuite) TestPublishesChangedJoinParameters(c *check.C) {
+	group := &mockGroup{}
+	serviceURL := "https://10.0.0.1:3009"
+	a, err := New(Config{
+		Group: group,
+		GetServiceURL: func() (string, error) {
+			return serviceURL, nil
+		},
+	})
+	c.Assert(err, check.IsNil)
+	operator := &mockTokenOperator{token: "token-1"}
+
+	c.Assert(a.syncDiscovery(context.TODO(), operator, false), check.IsNil)
+	c.Assert(a.syncDiscovery(context.TODO(), operator, false), check.IsNil)
+	c.Assert(group.published, check.DeepEquals, []JoinParameters{
+		{Token: "token-1", ServiceURL: "https://10.0.0.1:3009"},
+	})
+
+	serviceURL = "https://10.0.0.2:3009"
+	c.Assert(a.syncDiscovery(context.TODO(), operator, false), check.IsNil)
+	c.Assert(a.syncDiscovery(context.TODO(), operator, true), check.IsNil)
+	c.Assert(group.published, check.DeepEquals, []JoinParameters{
+		{Token: "token-1", ServiceURL: "https://10.0.0.1:3009"},
+		{Token: "token-1", ServiceURL: "https://10.0.0.2:3009"},
+		{Token: "token-1", ServiceURL: "https://10.0.0.2:3009"},
+	})
+}
+
+type mockChecker struct {
+	verdict    Verdict
+	terminated []string
+}
+
+func (r *mockChecker) PrepareRemoval(ctx context.Context, cluster ops.Site, server storage.Server) (*Verdict, error) {
+	return &r.verdict, nil
+}
+
+func (r *mockChecker) RecordTermination(server storage.Server) error {
+	r.terminated = append(r.terminated, server.Hostname)
+	return nil
+}
+
+type mockGroup struct {
+	instances []Instance
+	published []JoinParameters
+}
+
+func (r *mockGroup) ListInstances(context.Context) ([]Instance, error) {
+	return r.instances, nil
+}
+
+func (r *mockGroup) PublishJoinParameters(ctx context.Context, params JoinParameters) error {
+	r.published = append(r.published, params)
+	return nil
+}
+
+func newMockOperator(hostnames ...string) *mockOperator {
+	cluster := ops.Site{AccountID: "1", Domain: "example.com"}
+	for _, hostname := range hostnames {
+		cluster.ClusterState.Servers = append(cluster.ClusterState.Servers,
+			storage.Server{Hostname: hostname})
+	}
+	return &mockOperator{cluster: cluster}
+}
+
+type mockOperator struct {
+	cluster ops.Site
+	shrinks []string
+	err     error
+}
+
+func (r *mockOperator) GetLocalSite() (*ops.Site, error) {
+	return &r.cluster, nil
+}
+
+func (r *mockOperator) CreateSiteShrinkOperation(ctx context.Context, req ops.CreateSiteShrinkOperationRequest) (*ops.SiteOperationKey, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	r.shrinks = append(r.shrinks, req.Servers...)
+	return &ops.SiteOperationKey{OperationID: "1"}, nil
+}
+
+type mockTokenOperator struct {
+	ops.Operator
+	token string
+}
+
+func (r *mockTokenOperator) GetLocalSite() (*ops.Site, error) {
+	return &ops.Site{AccountID: "1", Domain: "example.com"}, nil
+}
+
+func (r *mockTokenOperator) GetExpandToken(ops.SiteKey) (*storage.ProvisioningToken, error) {
+	return &storage.ProvisioningToken{Token: r.token}, nil
+}
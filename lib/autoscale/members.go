@@ -0,0 +1,136 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package autoscale
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/gravitational/gravity/lib/constants"
+	"github.com/gravitational/gravity/lib/defaults"
+
+	"github.com/gravitational/rigging"
+	"github.com/gravitational/trace"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// MemberStore persists the hostnames of the nodes observed as members
+// of the autoscaling group, so the nodes which instances disappear from
+// the group while the autoscaler is not running, or after another master
+// has taken over, are still removed from the cluster
+type MemberStore interface {
+	// GetMembers returns the hostnames of the members
+	GetMembers() (map[string]struct{}, error)
+	// SetMembers replaces the hostnames of the members
+	SetMembers(members map[string]struct{}) error
+}
+
+// NewConfigMapMembers returns the member store backed by a ConfigMap
+// in the kube-system namespace
+func NewConfigMapMembers(client kubernetes.Interface) *configMapMembers {
+	return &configMapMembers{client: client}
+}
+
+// GetMembers returns the hostnames of the members
+func (r *configMapMembers) GetMembers() (map[string]struct{}, error) {
+	config, err := r.client.CoreV1().ConfigMaps(defaults.KubeSystemNamespace).Get(
+		constants.AutoscaleMembersConfigMap, metav1.GetOptions{})
+	err = rigging.ConvertError(err)
+	if err != nil && !trace.IsNotFound(err) {
+		return nil, trace.Wrap(err)
+	}
+	members := make(map[string]struct{})
+	if config == nil || err != nil {
+		return members, nil
+	}
+	for _, hostname := range strings.Fields(config.Data[membersKey]) {
+		members[hostname] = struct{}{}
+	}
+	return members, nil
+}
+
+// SetMembers replaces the hostnames of the members
+func (r *configMapMembers) SetMembers(members map[string]struct{}) error {
+	client := r.client.CoreV1().ConfigMaps(defaults.KubeSystemNamespace)
+	config := &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      constants.AutoscaleMembersConfigMap,
+			Namespace: defaults.KubeSystemNamespace,
+		},
+		Data: map[string]string{
+			membersKey: strings.Join(sortedHostnames(members), "\n"),
+		},
+	}
+	_, err := client.Create(config)
+	err = rigging.ConvertError(err)
+	if err == nil || !trace.IsAlreadyExists(err) {
+		return trace.Wrap(err)
+	}
+	_, err = client.Update(config)
+	return trace.Wrap(rigging.ConvertError(err))
+}
+
+type configMapMembers struct {
+	client kubernetes.Interface
+}
+
+// newMemoryMembers returns the member store that keeps the members in memory
+func newMemoryMembers() *memoryMembers {
+	return &memoryMembers{members: make(map[string]struct{})}
+}
+
+// GetMembers returns the hostnames of the members
+func (r *memoryMembers) GetMembers() (map[string]struct{}, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return copyHostnames(r.members), nil
+}
+
+// SetMembers replaces the hostnames of the members
+func (r *memoryMembers) SetMembers(members map[string]struct{}) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.members = copyHostnames(members)
+	return nil
+}
+
+type memoryMembers struct {
+	mu      sync.Mutex
+	members map[string]struct{}
+}
+
+func copyHostnames(hostnames map[string]struct{}) map[string]struct{} {
+	result := make(map[string]struct{}, len(hostnames))
+	for hostname := range hostnames {
+		result[hostname] = struct{}{}
+	}
+	return result
+}
+
+func sortedHostnames(hostnames map[string]struct{}) (result []string) {
+	for hostname := range hostnames {
+		result = append(result, hostname)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// membersKey is the ConfigMap key with the newline-separated member hostnames
+const membersKey = "members"
@@ -0,0 +1,423 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package autoscale
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	libkubernetes "github.com/gravitational/gravity/lib/kubernetes"
+	"github.com/gravitational/gravity/lib/ops"
+	"github.com/gravitational/gravity/lib/schema"
+	"github.com/gravitational/gravity/lib/storage"
+
+	"github.com/gravitational/rigging"
+	"github.com/gravitational/trace"
+	"github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	policy "k8s.io/api/policy/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Decision is the outcome of the scale-down safety evaluation
+type Decision string
+
+const (
+	// DecisionDrain means the node can be safely removed after it has been drained
+	DecisionDrain Decision = "Drain"
+	// DecisionDelay means the node cannot be removed at the moment
+	// and the removal should be retried later
+	DecisionDelay Decision = "Delay"
+	// DecisionReject means the node cannot be removed automatically
+	// and requires operator intervention
+	DecisionReject Decision = "Reject"
+)
+
+// Verdict describes the result of the scale-down safety evaluation
+type Verdict struct {
+	// Decision is the evaluation outcome
+	Decision Decision
+	// Reasons explains the decision
+	Reasons []string
+}
+
+// String returns a textual representation of the verdict
+func (r Verdict) String() string {
+	if len(r.Reasons) == 0 {
+		return string(r.Decision)
+	}
+	return fmt.Sprintf("%v: %v", r.Decision, strings.Join(r.Reasons, "; "))
+}
+
+// Checker evaluates whether the nodes terminated by the autoscaling group
+// can be removed from the cluster
+type Checker interface {
+	// PrepareRemoval evaluates whether the server can be safely removed from
+	// the cluster and drains the node if it can
+	PrepareRemoval(ctx context.Context, cluster ops.Site, server storage.Server) (*Verdict, error)
+	// RecordTermination records that the node is removed from the cluster
+	// because its instance has been terminated regardless of the verdict
+	RecordTermination(server storage.Server) error
+}
+
+// SafetyConfig is the configuration of the scale-down safety checks
+type SafetyConfig struct {
+	// Client is the kubernetes client
+	Client *kubernetes.Clientset
+	// FieldLogger is used for logging
+	logrus.FieldLogger
+}
+
+// CheckAndSetDefaults checks and sets default values
+func (c *SafetyConfig) CheckAndSetDefaults() error {
+	if c.Client == nil {
+		return trace.BadParameter("missing parameter Client")
+	}
+	if c.FieldLogger == nil {
+		c.FieldLogger = logrus.WithField(trace.Component, "autoscale:safety")
+	}
+	return nil
+}
+
+// NewSafety returns a new scale-down safety checker
+func NewSafety(config SafetyConfig) (*Safety, error) {
+	if err := config.CheckAndSetDefaults(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &Safety{
+		SafetyConfig: config,
+		verdicts:     make(map[string]string),
+	}, nil
+}
+
+// Safety evaluates whether the nodes terminated by the autoscaling
+// group can be removed from the cluster without disrupting it.
+//
+// A node is not removed if the removal would break the etcd quorum,
+// leave fewer masters than the application manifest requires or lose
+// data of a local persistent volume. The removal is delayed while
+// pod disruption budgets do not allow to evict the pods of the node.
+// Otherwise the node is drained before it is removed
+type Safety struct {
+	// SafetyConfig is the checker configuration
+	SafetyConfig
+	// mu guards verdicts
+	mu sync.Mutex
+	// verdicts maps node names to the last reported verdicts
+	verdicts map[string]string
+}
+
+// PrepareRemoval evaluates whether the server can be safely removed from
+// the cluster and drains the node if it can. Events explaining the decision
+// are recorded for the node.
+// The server should only be removed if the decision is DecisionDrain
+func (r *Safety) PrepareRemoval(ctx context.Context, cluster ops.Site, server storage.Server) (*Verdict, error) {
+	state, err := r.getRemovalState(cluster, server)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	verdict := evaluateRemoval(*state)
+	r.WithField("node", server.Hostname).Infof("Scale-down verdict: %v.", verdict)
+	if err := r.recordEvent(server, verdict); err != nil {
+		r.WithError(err).Warn("Failed to record scale-down event.")
+	}
+	if verdict.Decision != DecisionDrain {
+		return &verdict, nil
+	}
+	if err := libkubernetes.Drain(ctx, r.Client, server.KubeNodeID()); err != nil {
+		return nil, trace.Wrap(err, "failed to drain node %v", server.Hostname)
+	}
+	return &verdict, nil
+}
+
+// getRemovalState queries the cluster state relevant to the removal of the server
+func (r *Safety) getRemovalState(cluster ops.Site, server storage.Server) (*removalState, error) {
+	state := removalState{
+		cluster:    cluster,
+		server:     server,
+		readyNodes: make(map[string]bool),
+		volumes:    make(map[string]v1.PersistentVolume),
+	}
+	nodes, err := r.Client.CoreV1().Nodes().List(metav1.ListOptions{})
+	if err != nil {
+		return nil, rigging.ConvertError(err)
+	}
+	for _, node := range nodes.Items {
+		state.readyNodes[node.Name] = isNodeReady(node)
+	}
+	pods, err := r.Client.CoreV1().Pods(metav1.NamespaceAll).List(metav1.ListOptions{
+		FieldSelector: fields.OneTermEqualSelector("spec.nodeName", server.KubeNodeID()).String(),
+	})
+	if err != nil {
+		return nil, rigging.ConvertError(err)
+	}
+	state.pods = pods.Items
+	for _, pod := range state.pods {
+		for _, volume := range pod.Spec.Volumes {
+			if volume.PersistentVolumeClaim == nil {
+				continue
+			}
+			claim, err := r.Client.CoreV1().PersistentVolumeClaims(pod.Namespace).Get(
+				volume.PersistentVolumeClaim.ClaimName, metav1.GetOptions{})
+			if err != nil {
+				return nil, rigging.ConvertError(err)
+			}
+			if claim.Spec.VolumeName == "" {
+				continue
+			}
+			pv, err := r.Client.CoreV1().PersistentVolumes().Get(claim.Spec.VolumeName, metav1.GetOptions{})
+			if err != nil {
+				return nil, rigging.ConvertError(err)
+			}
+			state.volumes[claimKey(pod.Namespace, claim.Name)] = *pv
+		}
+	}
+	budgets, err := r.Client.PolicyV1beta1().PodDisruptionBudgets(metav1.NamespaceAll).List(metav1.ListOptions{})
+	if err != nil {
+		return nil, rigging.ConvertError(err)
+	}
+	state.budgets = budgets.Items
+	return &state, nil
+}
+
+// recordEvent records the kubernetes event explaining the verdict
+// on the node unless the same verdict has been reported already
+func (r *Safety) recordEvent(server storage.Server, verdict Verdict) error {
+	nodeName := server.KubeNodeID()
+	r.mu.Lock()
+	if r.verdicts[nodeName] == verdict.String() {
+		r.mu.Unlock()
+		return nil
+	}
+	r.verdicts[nodeName] = verdict.String()
+	r.mu.Unlock()
+	eventType := v1.EventTypeWarning
+	message := fmt.Sprintf("Autoscaler will not remove node %v: %v.",
+		server.Hostname, strings.Join(verdict.Reasons, "; "))
+	switch verdict.Decision {
+	case DecisionDrain:
+		eventType = v1.EventTypeNormal
+		message = fmt.Sprintf("Autoscaler is draining node %v before removing it from the cluster.", server.Hostname)
+	case DecisionDelay:
+		message = fmt.Sprintf("Autoscaler delayed removal of node %v: %v.",
+			server.Hostname, strings.Join(verdict.Reasons, "; "))
+	}
+	return r.createEvent(server, "ScaleDown"+string(verdict.Decision), eventType, message)
+}
+
+// RecordTermination records the event on the node which instance has been
+// terminated. The cloud provider terminates the instance regardless of
+// the verdict, so the node is removed from the cluster even if its removal
+// has been delayed or rejected
+func (r *Safety) RecordTermination(server storage.Server) error {
+	nodeName := server.KubeNodeID()
+	r.mu.Lock()
+	previous := r.verdicts[nodeName]
+	delete(r.verdicts, nodeName)
+	r.mu.Unlock()
+	message := fmt.Sprintf("Instance of node %v has been terminated, autoscaler is removing the node from the cluster.",
+		server.Hostname)
+	if previous != "" && previous != string(DecisionDrain) {
+		message = fmt.Sprintf("Instance of node %v has been terminated despite the scale-down verdict (%v), autoscaler is removing the node from the cluster.",
+			server.Hostname, previous)
+	}
+	return r.createEvent(server, "ScaleDownTerminated", v1.EventTypeWarning, message)
+}
+
+// createEvent creates the kubernetes event on the node of the server
+func (r *Safety) createEvent(server storage.Server, reason, eventType, message string) error {
+	nodeName := server.KubeNodeID()
+	now := metav1.NewTime(time.Now())
+	_, err := r.Client.CoreV1().Events(metav1.NamespaceDefault).Create(&v1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: nodeName + ".",
+			Namespace:    metav1.NamespaceDefault,
+		},
+		InvolvedObject: v1.ObjectReference{
+			Kind: "Node",
+			Name: nodeName,
+		},
+		Reason:         reason,
+		Message:        message,
+		Type:           eventType,
+		Source:         v1.EventSource{Component: eventComponent},
+		FirstTimestamp: now,
+		LastTimestamp:  now,
+		Count:          1,
+	})
+	return rigging.ConvertError(err)
+}
+
+// removalState describes the cluster state relevant to the removal of a node
+type removalState struct {
+	// cluster is the cluster the node is removed from
+	cluster ops.Site
+	// server is the server being removed
+	server storage.Server
+	// readyNodes maps kubernetes node names to their readiness
+	readyNodes map[string]bool
+	// pods lists the pods scheduled on the node
+	pods []v1.Pod
+	// volumes maps persistent volume claims of the pods to the bound volumes
+	volumes map[string]v1.PersistentVolume
+	// budgets lists the pod disruption budgets of the cluster
+	budgets []policy.PodDisruptionBudget
+}
+
+// evaluateRemoval decides whether the server can be removed given the cluster state
+func evaluateRemoval(state removalState) Verdict {
+	var rejected, delayed []string
+	if state.server.IsMaster() {
+		rejected = append(rejected, checkMasters(state)...)
+	}
+	rejected = append(rejected, checkLocalVolumes(state)...)
+	delayed = append(delayed, checkDisruptionBudgets(state)...)
+	switch {
+	case len(rejected) != 0:
+		return Verdict{Decision: DecisionReject, Reasons: rejected}
+	case len(delayed) != 0:
+		return Verdict{Decision: DecisionDelay, Reasons: delayed}
+	}
+	return Verdict{Decision: DecisionDrain}
+}
+
+// checkMasters verifies that removing the master keeps the etcd quorum
+// and the number of masters the application manifest requires
+func checkMasters(state removalState) (reasons []string) {
+	masters := state.cluster.Masters()
+	var healthy int
+	for _, master := range masters {
+		if master.Hostname != state.server.Hostname && state.readyNodes[master.KubeNodeID()] {
+			healthy++
+		}
+	}
+	if quorum := len(masters)/2 + 1; healthy < quorum {
+		reasons = append(reasons, fmt.Sprintf(
+			"etcd would lose quorum: %v of the other %v members are healthy, %v required",
+			healthy, len(masters)-1, quorum))
+	}
+	if required := minMasters(state.cluster.App.Manifest); len(masters)-1 < required {
+		reasons = append(reasons, fmt.Sprintf(
+			"%v masters would remain, the application requires at least %v",
+			len(masters)-1, required))
+	}
+	return reasons
+}
+
+// checkLocalVolumes verifies that no pod on the node uses
+// a persistent volume local to the node
+func checkLocalVolumes(state removalState) (reasons []string) {
+	for _, pod := range state.pods {
+		for _, volume := range pod.Spec.Volumes {
+			if volume.PersistentVolumeClaim == nil {
+				continue
+			}
+			pv, ok := state.volumes[claimKey(pod.Namespace, volume.PersistentVolumeClaim.ClaimName)]
+			if !ok || (pv.Spec.Local == nil && pv.Spec.HostPath == nil) {
+				continue
+			}
+			reasons = append(reasons, fmt.Sprintf(
+				"pod %v/%v stores data in local persistent volume %v",
+				pod.Namespace, pod.Name, pv.Name))
+		}
+	}
+	return reasons
+}
+
+// checkDisruptionBudgets verifies that the pods on the node
+// can be evicted without violating pod disruption budgets
+func checkDisruptionBudgets(state removalState) (reasons []string) {
+	for _, pod := range state.pods {
+		if isDaemonSetPod(pod) || isTerminated(pod) {
+			continue
+		}
+		for _, budget := range state.budgets {
+			if budget.Namespace != pod.Namespace || budget.Status.PodDisruptionsAllowed > 0 {
+				continue
+			}
+			selector, err := metav1.LabelSelectorAsSelector(budget.Spec.Selector)
+			if err != nil || selector.Empty() || !selector.Matches(labels.Set(pod.Labels)) {
+				continue
+			}
+			reasons = append(reasons, fmt.Sprintf(
+				"disruption budget %v/%v does not allow to evict pod %v",
+				budget.Namespace, budget.Name, pod.Name))
+		}
+	}
+	return reasons
+}
+
+// minMasters returns the minimum number of masters required by the
+// application manifest: the number of master nodes in its smallest flavor.
+// A cluster always requires at least one master
+func minMasters(manifest schema.Manifest) int {
+	required := 0
+	if manifest.Installer != nil {
+		for i, flavor := range manifest.Installer.Flavors.Items {
+			var masters int
+			for _, node := range flavor.Nodes {
+				profile, err := manifest.NodeProfiles.ByName(node.Profile)
+				if err == nil && profile.ServiceRole == schema.ServiceRoleMaster {
+					masters += node.Count
+				}
+			}
+			if i == 0 || masters < required {
+				required = masters
+			}
+		}
+	}
+	if required < 1 {
+		return 1
+	}
+	return required
+}
+
+func isNodeReady(node v1.Node) bool {
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == v1.NodeReady {
+			return condition.Status == v1.ConditionTrue
+		}
+	}
+	return false
+}
+
+func isDaemonSetPod(pod v1.Pod) bool {
+	for _, ref := range pod.OwnerReferences {
+		if ref.Kind == rigging.KindDaemonSet {
+			return true
+		}
+	}
+	return false
+}
+
+func isTerminated(pod v1.Pod) bool {
+	return pod.Status.Phase == v1.PodSucceeded || pod.Status.Phase == v1.PodFailed
+}
+
+func claimKey(namespace, name string) string {
+	return namespace + "/" + name
+}
+
+// eventComponent is the source component of the events
+// recorded by the autoscaler
+const eventComponent = "gravity-autoscaler"
@@ -0,0 +1,174 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package autoscale
+
+import (
+	"fmt"
+
+	"github.com/gravitational/gravity/lib/ops"
+	"github.com/gravitational/gravity/lib/schema"
+	"github.com/gravitational/gravity/lib/storage"
+
+	"gopkg.in/check.v1"
+	v1 "k8s.io/api/core/v1"
+	policy "k8s.io/api/policy/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type SafetySuite struct{}
+
+var _ = check.Suite(&SafetySuite{})
+
+func (s *SafetySuite) TestDrainsSafeNode(c *check.C) {
+	state := newRemovalState(3, "node-1")
+	state.pods = []v1.Pod{newPod("app", map[string]string{"app": "web"})}
+	state.budgets = []policy.PodDisruptionBudget{newBudget("web", "web", 1)}
+	c.Assert(evaluateRemoval(state), check.DeepEquals, Verdict{Decision: DecisionDrain})
+}
+
+func (s *SafetySuite) TestRejectsQuorumLoss(c *check.C) {
+	state := newRemovalState(3, "master-1")
+	state.readyNodes["master-2"] = false
+	verdict := evaluateRemoval(state)
+	c.Assert(verdict.Decision, check.Equals, DecisionReject)
+	c.Assert(verdict.Reasons, check.DeepEquals, []string{
+		"etcd would lose quorum: 1 of the other 2 members are healthy, 2 required",
+	})
+
+	state.readyNodes["master-2"] = true
+	c.Assert(evaluateRemoval(state), check.DeepEquals, Verdict{Decision: DecisionDrain})
+}
+
+func (s *SafetySuite) TestRejectsMastersBelowManifestMinimum(c *check.C) {
+	state := newRemovalState(3, "master-1")
+	state.cluster.App.Manifest = schema.Manifest{
+		NodeProfiles: schema.NodeProfiles{
+			{Name: "master", ServiceRole: schema.ServiceRoleMaster},
+			{Name: "node"},
+		},
+		Installer: &schema.Installer{
+			Flavors: schema.Flavors{
+				Items: []schema.Flavor{
+					{Name: "large", Nodes: []schema.FlavorNode{{Profile: "master", Count: 5}}},
+					{Name: "small", Nodes: []schema.FlavorNode{
+						{Profile: "master", Count: 3},
+						{Profile: "node", Count: 2},
+					}},
+				},
+			},
+		},
+	}
+	verdict := evaluateRemoval(state)
+	c.Assert(verdict.Decision, check.Equals, DecisionReject)
+	c.Assert(verdict.Reasons, check.DeepEquals, []string{
+		"2 masters would remain, the application requires at least 3",
+	})
+}
+
+func (s *SafetySuite) TestRejectsLocalVolumes(c *check.C) {
+	state := newRemovalState(1, "node-1")
+	pod := newPod("db", nil)
+	pod.Spec.Volumes = []v1.Volume{
+		{Name: "data", VolumeSource: v1.VolumeSource{
+			PersistentVolumeClaim: &v1.PersistentVolumeClaimVolumeSource{ClaimName: "data"},
+		}},
+		{Name: "cache", VolumeSource: v1.VolumeSource{
+			PersistentVolumeClaim: &v1.PersistentVolumeClaimVolumeSource{ClaimName: "cache"},
+		}},
+	}
+	state.pods = []v1.Pod{pod}
+	state.volumes = map[string]v1.PersistentVolume{
+		"default/data": {
+			ObjectMeta: metav1.ObjectMeta{Name: "local-pv"},
+			Spec: v1.PersistentVolumeSpec{PersistentVolumeSource: v1.PersistentVolumeSource{
+				Local: &v1.LocalVolumeSource{Path: "/mnt/data"},
+			}},
+		},
+		"default/cache": {
+			ObjectMeta: metav1.ObjectMeta{Name: "network-pv"},
+			Spec: v1.PersistentVolumeSpec{PersistentVolumeSource: v1.PersistentVolumeSource{
+				NFS: &v1.NFSVolumeSource{Server: "nfs", Path: "/cache"},
+			}},
+		},
+	}
+	verdict := evaluateRemoval(state)
+	c.Assert(verdict.Decision, check.Equals, DecisionReject)
+	c.Assert(verdict.Reasons, check.DeepEquals, []string{
+		"pod default/db stores data in local persistent volume local-pv",
+	})
+}
+
+func (s *SafetySuite) TestDelaysBlockedEviction(c *check.C) {
+	state := newRemovalState(1, "node-1")
+	daemon := newPod("agent", map[string]string{"app": "web"})
+	daemon.OwnerReferences = []metav1.OwnerReference{{Kind: "DaemonSet", Name: "agent"}}
+	state.pods = []v1.Pod{newPod("web-1", map[string]string{"app": "web"}), daemon}
+	state.budgets = []policy.PodDisruptionBudget{newBudget("web", "web", 0), newBudget("db", "db", 0)}
+	verdict := evaluateRemoval(state)
+	c.Assert(verdict.Decision, check.Equals, DecisionDelay)
+	c.Assert(verdict.Reasons, check.DeepEquals, []string{
+		"disruption budget default/web does not allow to evict pod web-1",
+	})
+}
+
+// newRemovalState returns the state of a cluster with the specified number
+// of ready masters and a regular node node-1 where the server specified
+// with hostname is being removed
+func newRemovalState(masters int, hostname string) removalState {
+	state := removalState{
+		cluster:    ops.Site{AccountID: "1", Domain: "example.com"},
+		readyNodes: make(map[string]bool),
+	}
+	servers := []storage.Server{{
+		Hostname:    "node-1",
+		Nodename:    "node-1",
+		ClusterRole: string(schema.ServiceRoleNode),
+	}}
+	for i := 1; i <= masters; i++ {
+		hostname := fmt.Sprintf("master-%v", i)
+		servers = append(servers, storage.Server{
+			Hostname:    hostname,
+			Nodename:    hostname,
+			ClusterRole: string(schema.ServiceRoleMaster),
+		})
+	}
+	for _, server := range servers {
+		state.readyNodes[server.KubeNodeID()] = true
+		if server.Hostname == hostname {
+			state.server = server
+		}
+	}
+	state.cluster.ClusterState.Servers = servers
+	return state
+}
+
+func newPod(name string, labels map[string]string) v1.Pod {
+	return v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: metav1.NamespaceDefault, Labels: labels},
+		Status:     v1.PodStatus{Phase: v1.PodRunning},
+	}
+}
+
+func newBudget(name, app string, allowed int32) policy.PodDisruptionBudget {
+	return policy.PodDisruptionBudget{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: metav1.NamespaceDefault},
+		Spec: policy.PodDisruptionBudgetSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": app}},
+		},
+		Status: policy.PodDisruptionBudgetStatus{PodDisruptionsAllowed: allowed},
+	}
+}
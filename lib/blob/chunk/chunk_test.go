@@ -0,0 +1,133 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package chunk
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"testing"
+
+	"github.com/gravitational/gravity/lib/blob"
+
+	"github.com/gravitational/trace"
+	"gopkg.in/check.v1"
+)
+
+func TestChunk(t *testing.T) { check.TestingT(t) }
+
+type ChunkSuite struct{}
+
+var _ = check.Suite(&ChunkSuite{})
+
+func (s *ChunkSuite) TestChunkSizeLimits(c *check.C) {
+	config := Config{MinSize: 100, AvgSize: 256, MaxSize: 1000}
+	data := randomData(100 * 1024)
+	chunks := split(c, data, config)
+	var joined []byte
+	for i, chunk := range chunks {
+		if i != len(chunks)-1 {
+			c.Assert(len(chunk) >= config.MinSize, check.Equals, true)
+		}
+		c.Assert(len(chunk) <= config.MaxSize, check.Equals, true)
+		joined = append(joined, chunk...)
+	}
+	c.Assert(bytes.Equal(joined, data), check.Equals, true)
+}
+
+func (s *ChunkSuite) TestBoundariesAreContentDefined(c *check.C) {
+	config := Config{MinSize: 64, AvgSize: 256, MaxSize: 1024}
+	data := randomData(64 * 1024)
+	// insert a few bytes at the beginning which shifts all offsets
+	shifted := append([]byte("prefix"), data...)
+
+	hashes := make(map[string]bool)
+	for _, chunk := range split(c, data, config) {
+		hashes[string(chunk)] = true
+	}
+	chunks := split(c, shifted, config)
+	var shared int
+	for _, chunk := range chunks {
+		if hashes[string(chunk)] {
+			shared++
+		}
+	}
+	c.Assert(shared >= len(chunks)-2, check.Equals, true,
+		check.Commentf("only %v of %v chunks are shared", shared, len(chunks)))
+}
+
+func (s *ChunkSuite) TestReaderSeeks(c *check.C) {
+	data := randomData(10 * 1024)
+	config := Config{MinSize: 64, AvgSize: 256, MaxSize: 1024}
+	store := make(map[string][]byte)
+	var manifest Manifest
+	for i, chunk := range split(c, data, config) {
+		hash := string(rune('a' + i))
+		store[hash] = chunk
+		manifest.Add(hash, int64(len(chunk)))
+	}
+	c.Assert(manifest.Check(), check.IsNil)
+	r := NewReader(manifest, func(hash string) (blob.ReadSeekCloser, error) {
+		chunk, ok := store[hash]
+		if !ok {
+			return nil, trace.NotFound("chunk %v not found", hash)
+		}
+		return nopCloser{bytes.NewReader(chunk)}, nil
+	})
+	defer r.Close()
+
+	out, err := ioutil.ReadAll(r)
+	c.Assert(err, check.IsNil)
+	c.Assert(bytes.Equal(out, data), check.Equals, true)
+
+	for _, offset := range []int64{0, 1, 1000, 5000, int64(len(data)) - 1} {
+		_, err = r.Seek(offset, io.SeekStart)
+		c.Assert(err, check.IsNil)
+		out, err = ioutil.ReadAll(r)
+		c.Assert(err, check.IsNil)
+		c.Assert(bytes.Equal(out, data[offset:]), check.Equals, true, check.Commentf("offset %v", offset))
+	}
+	size, err := r.Seek(0, io.SeekEnd)
+	c.Assert(err, check.IsNil)
+	c.Assert(size, check.Equals, int64(len(data)))
+}
+
+func split(c *check.C, data []byte, config Config) (chunks [][]byte) {
+	chunker, err := NewChunker(bytes.NewReader(data), config)
+	c.Assert(err, check.IsNil)
+	for {
+		chunk, err := chunker.Next()
+		if err == io.EOF {
+			return chunks
+		}
+		c.Assert(err, check.IsNil)
+		chunks = append(chunks, chunk)
+	}
+}
+
+func randomData(size int) []byte {
+	data := make([]byte, size)
+	rand.New(rand.NewSource(1)).Read(data)
+	return data
+}
+
+type nopCloser struct {
+	*bytes.Reader
+}
+
+func (nopCloser) Close() error { return nil }
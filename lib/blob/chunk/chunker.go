@@ -0,0 +1,159 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package chunk implements content-defined chunking of BLOBs.
+//
+// Chunk boundaries are determined by a rolling gear hash computed over the
+// data so that an insertion or a removal in one part of a BLOB only
+// affects the chunks around the change and the chunks of two similar BLOBs
+// (e.g. two versions of the same package) are mostly identical
+package chunk
+
+import (
+	"io"
+
+	"github.com/gravitational/trace"
+)
+
+// Config defines the chunk size limits
+type Config struct {
+	// MinSize is the minimum chunk size in bytes
+	MinSize int
+	// AvgSize is the target average chunk size in bytes.
+	// Must be a power of two
+	AvgSize int
+	// MaxSize is the maximum chunk size in bytes
+	MaxSize int
+}
+
+// CheckAndSetDefaults validates this configuration and sets defaults
+func (r *Config) CheckAndSetDefaults() error {
+	if r.AvgSize == 0 {
+		r.AvgSize = DefaultAvgSize
+	}
+	if r.MinSize == 0 {
+		r.MinSize = r.AvgSize / 4
+	}
+	if r.MaxSize == 0 {
+		r.MaxSize = r.AvgSize * 4
+	}
+	if r.AvgSize&(r.AvgSize-1) != 0 {
+		return trace.BadParameter("average chunk size should be a power of two, got %v", r.AvgSize)
+	}
+	if r.MinSize < 1 || r.MinSize > r.AvgSize || r.AvgSize > r.MaxSize {
+		return trace.BadParameter("chunk sizes should satisfy 0 < min(%v) <= avg(%v) <= max(%v)",
+			r.MinSize, r.AvgSize, r.MaxSize)
+	}
+	return nil
+}
+
+// NewChunker returns a new chunker that splits the data read from the
+// provided reader into content-defined chunks
+func NewChunker(r io.Reader, config Config) (*Chunker, error) {
+	if err := config.CheckAndSetDefaults(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &Chunker{
+		Config: config,
+		r:      r,
+		buf:    make([]byte, 0, config.MaxSize),
+		mask:   uint64(config.AvgSize - 1),
+	}, nil
+}
+
+// Chunker splits a stream of data into content-defined chunks
+type Chunker struct {
+	// Config defines the chunk size limits
+	Config
+	r    io.Reader
+	buf  []byte
+	eof  bool
+	mask uint64
+}
+
+// Next returns the next chunk of data.
+// The returned slice is only valid until the next call to Next.
+// Returns io.EOF when there is no more data
+func (r *Chunker) Next() ([]byte, error) {
+	if err := r.fill(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if len(r.buf) == 0 {
+		return nil, io.EOF
+	}
+	n := r.cutPoint(r.buf)
+	chunk := make([]byte, n)
+	copy(chunk, r.buf[:n])
+	r.buf = append(r.buf[:0], r.buf[n:]...)
+	return chunk, nil
+}
+
+// fill reads from the underlying reader until the buffer is full
+// or the reader is exhausted
+func (r *Chunker) fill() error {
+	for !r.eof && len(r.buf) < cap(r.buf) {
+		n, err := r.r.Read(r.buf[len(r.buf):cap(r.buf)])
+		r.buf = r.buf[:len(r.buf)+n]
+		if err == io.EOF {
+			r.eof = true
+			break
+		}
+		if err != nil {
+			return trace.ConvertSystemError(err)
+		}
+	}
+	return nil
+}
+
+// cutPoint returns the length of the next chunk at the start of data
+func (r *Chunker) cutPoint(data []byte) int {
+	if len(data) <= r.MinSize {
+		return len(data)
+	}
+	limit := len(data)
+	if limit > r.MaxSize {
+		limit = r.MaxSize
+	}
+	var hash uint64
+	for i := r.MinSize; i < limit; i++ {
+		hash = (hash << 1) + gear[data[i]]
+		if hash&r.mask == 0 {
+			return i + 1
+		}
+	}
+	return limit
+}
+
+// gear is the table of random values used to compute the rolling hash.
+// The values are generated deterministically as chunk boundaries
+// must not change between versions
+var gear = func() (table [256]uint64) {
+	// splitmix64
+	seed := uint64(0x9e3779b97f4a7c15)
+	for i := range table {
+		seed += 0x9e3779b97f4a7c15
+		z := seed
+		z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+		z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+		table[i] = z ^ (z >> 31)
+	}
+	return table
+}()
+
+const (
+	// DefaultAvgSize is the default average chunk size
+	DefaultAvgSize = 2 * 1024 * 1024
+)
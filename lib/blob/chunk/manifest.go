@@ -0,0 +1,184 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package chunk
+
+import (
+	"io"
+	"sort"
+
+	"github.com/gravitational/gravity/lib/blob"
+
+	"github.com/gravitational/trace"
+)
+
+// Manifest describes a BLOB stored as a sequence of chunks
+type Manifest struct {
+	// SizeBytes is the BLOB size in bytes
+	SizeBytes int64 `json:"size_bytes"`
+	// Chunks lists the BLOB chunks in order
+	Chunks []Chunk `json:"chunks"`
+}
+
+// Chunk describes a single BLOB chunk
+type Chunk struct {
+	// SHA512 is the half SHA512 hash of the chunk data
+	SHA512 string `json:"sha512"`
+	// Offset is the offset of the chunk within the BLOB
+	Offset int64 `json:"offset"`
+	// SizeBytes is the chunk size in bytes
+	SizeBytes int64 `json:"size_bytes"`
+}
+
+// Add appends a chunk with the specified hash and size to the manifest
+func (r *Manifest) Add(hash string, size int64) {
+	r.Chunks = append(r.Chunks, Chunk{
+		SHA512:    hash,
+		Offset:    r.SizeBytes,
+		SizeBytes: size,
+	})
+	r.SizeBytes += size
+}
+
+// Check makes sure the manifest is consistent
+func (r Manifest) Check() error {
+	var offset int64
+	for _, chunk := range r.Chunks {
+		if chunk.SHA512 == "" {
+			return trace.BadParameter("chunk at offset %v is missing hash", chunk.Offset)
+		}
+		if chunk.Offset != offset {
+			return trace.BadParameter("chunk %v is at offset %v, expected %v",
+				chunk.SHA512, chunk.Offset, offset)
+		}
+		offset += chunk.SizeBytes
+	}
+	if offset != r.SizeBytes {
+		return trace.BadParameter("chunks add up to %v bytes, expected %v", offset, r.SizeBytes)
+	}
+	return nil
+}
+
+// OpenFunc opens the chunk with the specified hash
+type OpenFunc func(hash string) (blob.ReadSeekCloser, error)
+
+// NewReader returns a reader that reassembles the BLOB described by
+// the manifest from its chunks opened with the provided function.
+// Chunks are opened lazily when read
+func NewReader(manifest Manifest, open OpenFunc) blob.ReadSeekCloser {
+	return &reader{
+		manifest: manifest,
+		open:     open,
+	}
+}
+
+type reader struct {
+	manifest Manifest
+	open     OpenFunc
+	// offset is the current offset within the BLOB
+	offset int64
+	// index is the index of the currently open chunk
+	index int
+	// current is the currently open chunk or nil
+	current blob.ReadSeekCloser
+}
+
+// Read reads up to len(p) bytes of the BLOB into p
+func (r *reader) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	if r.offset >= r.manifest.SizeBytes {
+		return 0, io.EOF
+	}
+	if r.current == nil {
+		if err := r.openChunk(); err != nil {
+			return 0, trace.Wrap(err)
+		}
+	}
+	n, err := r.current.Read(p)
+	r.offset += int64(n)
+	if err == io.EOF {
+		chunk := r.manifest.Chunks[r.index]
+		if r.offset < chunk.Offset+chunk.SizeBytes {
+			return n, trace.BadParameter("chunk %v is truncated", chunk.SHA512)
+		}
+		err = nil
+	}
+	if err != nil {
+		return n, trace.ConvertSystemError(err)
+	}
+	if chunk := r.manifest.Chunks[r.index]; r.offset >= chunk.Offset+chunk.SizeBytes {
+		r.closeChunk()
+	}
+	return n, nil
+}
+
+// Seek sets the offset for the next Read
+func (r *reader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += r.offset
+	case io.SeekEnd:
+		offset += r.manifest.SizeBytes
+	default:
+		return 0, trace.BadParameter("unsupported whence %v", whence)
+	}
+	if offset < 0 {
+		return 0, trace.BadParameter("negative offset %v", offset)
+	}
+	if offset != r.offset {
+		r.closeChunk()
+		r.offset = offset
+	}
+	return r.offset, nil
+}
+
+// Close closes the currently open chunk
+func (r *reader) Close() error {
+	r.closeChunk()
+	return nil
+}
+
+// openChunk opens the chunk at the current offset
+func (r *reader) openChunk() error {
+	chunks := r.manifest.Chunks
+	r.index = sort.Search(len(chunks), func(i int) bool {
+		return chunks[i].Offset+chunks[i].SizeBytes > r.offset
+	})
+	if r.index == len(chunks) {
+		return trace.BadParameter("offset %v is out of range", r.offset)
+	}
+	chunk := chunks[r.index]
+	f, err := r.open(chunk.SHA512)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if _, err := f.Seek(r.offset-chunk.Offset, io.SeekStart); err != nil {
+		f.Close()
+		return trace.ConvertSystemError(err)
+	}
+	r.current = f
+	return nil
+}
+
+func (r *reader) closeChunk() {
+	if r.current != nil {
+		r.current.Close()
+		r.current = nil
+	}
+}
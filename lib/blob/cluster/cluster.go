@@ -113,6 +113,7 @@ func New(config Config) (blob.Objects, error) {
 		go c.periodically("heartbeat", c.heartbeat)
 		go c.periodically("purgeDeleted", c.purgeDeletedObjects)
 		go c.periodically("fetchNew", c.fetchNewObjects)
+		go c.periodically("repair", c.repairObjects)
 	}
 
 	return c, nil
@@ -169,6 +170,7 @@ func (c *cluster) localPeer() storage.Peer {
 		ID:            c.ID,
 		AdvertiseAddr: c.AdvertiseAddr,
 		LastHeartbeat: c.Clock.Now().UTC(),
+		WriteFactor:   c.WriteFactor,
 	}
 }
 
@@ -283,6 +285,105 @@ func (c *cluster) fetchObject(hash string) error {
 	return trace.NewAggregate(errors...)
 }
 
+// repairObjects re-replicates the objects stored on fewer active peers
+// than required by the write factor, e.g. after a peer has disappeared.
+// An object is only replicated by the active peer with the lowest ID among
+// the peers that have it, so the peers do not repair the same object concurrently
+func (c *cluster) repairObjects() error {
+	objects, err := c.Backend.GetObjects()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	peers, err := c.getPeers(nil)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	var errors []error
+	for _, hash := range objects {
+		err := c.repairObject(hash, peers)
+		if err != nil {
+			c.Warningf("Failed to repair object %v: %v.", hash, trace.DebugReport(err))
+			errors = append(errors, err)
+		}
+	}
+	return trace.NewAggregate(errors...)
+}
+
+func (c *cluster) repairObject(hash string, peers []storage.Peer) error {
+	ids, err := c.Backend.GetObjectPeers(hash)
+	if err != nil {
+		if trace.IsNotFound(err) {
+			return nil
+		}
+		return trace.Wrap(err)
+	}
+	var holders, targets []storage.Peer
+	for _, p := range peers {
+		if utils.StringInSlice(ids, p.ID) {
+			holders = append(holders, p)
+		} else {
+			targets = append(targets, p)
+		}
+	}
+	if len(holders) == 0 || len(holders) >= c.WriteFactor {
+		return nil
+	}
+	sort.Slice(holders, func(i, j int) bool { return holders[i].ID < holders[j].ID })
+	if holders[0].ID != c.ID {
+		return nil
+	}
+	if len(targets) == 0 {
+		c.Debugf("Not enough active peers to replicate %v.", hash)
+		return nil
+	}
+	f, err := c.Local.OpenBLOB(hash)
+	if err != nil {
+		if trace.IsNotFound(err) {
+			return nil
+		}
+		return trace.Wrap(err)
+	}
+	defer f.Close()
+	c.Infof("Object %v is stored on %v of %v peers, replicating.", hash, len(holders), c.WriteFactor)
+	var replicated []string
+	var errors []error
+	for _, p := range targets {
+		if len(holders)+len(replicated) >= c.WriteFactor {
+			break
+		}
+		_, err := f.Seek(0, 0)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		peerClient, err := c.GetPeer(p)
+		if err != nil {
+			errors = append(errors, err)
+			continue
+		}
+		_, err = peerClient.WriteBLOB(f)
+		if err != nil {
+			errors = append(errors, err)
+			continue
+		}
+		replicated = append(replicated, p.ID)
+	}
+	if len(replicated) != 0 {
+		c.Infof("Replicated %v to %v.", hash, replicated)
+		err = c.Backend.UpsertObjectPeers(hash, replicated, 0)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+	}
+	if len(errors) != 0 {
+		return trace.Wrap(trace.NewAggregate(errors...),
+			"object %v is stored on %v of %v peers", hash, len(holders)+len(replicated), c.WriteFactor)
+	}
+	if len(holders)+len(replicated) < c.WriteFactor {
+		c.Debugf("Not enough active peers to replicate %v.", hash)
+	}
+	return nil
+}
+
 // WriteBLOB writes object to the storage, returns object envelope
 func (c *cluster) WriteBLOB(data io.Reader) (*blob.Envelope, error) {
 	// get peers
@@ -420,7 +521,7 @@ func (c *cluster) getPeers(ids []string) ([]storage.Peer, error) {
 	if len(in) == 0 {
 		in = []storage.Peer{c.localPeer()}
 	}
-	missedWindow := time.Duration(c.MissedHeartbeats) * c.HeartbeatPeriod
+	missedWindow := c.missedWindow()
 	out := make([]storage.Peer, 0, len(in))
 	for _, p := range in {
 		// Skip non-local peer
@@ -430,7 +531,7 @@ func (c *cluster) getPeers(ids []string) ([]storage.Peer, error) {
 				continue
 			}
 			// if it's last heartbeat is older than the acceptance time frame
-			if c.Clock.Now().UTC().Sub(p.LastHeartbeat) > missedWindow {
+			if !isActive(p, c.Clock.Now(), missedWindow) {
 				c.Infof("Excluding %v, missed heartbeat window %v, last heartbeat: %v.", p.ID, missedWindow, p.LastHeartbeat)
 				continue
 			}
@@ -441,6 +542,16 @@ func (c *cluster) getPeers(ids []string) ([]storage.Peer, error) {
 	return out, nil
 }
 
+func (c *cluster) missedWindow() time.Duration {
+	return time.Duration(c.MissedHeartbeats) * c.HeartbeatPeriod
+}
+
+// isActive returns true if the peer has sent a heartbeat
+// within the missedWindow before now
+func isActive(p storage.Peer, now time.Time, missedWindow time.Duration) bool {
+	return now.UTC().Sub(p.LastHeartbeat) <= missedWindow
+}
+
 // peerSorter makes sure local peer always goes first
 // and guarantees deterministic peer order
 type peerSorter struct {
@@ -51,9 +51,12 @@ type ClusterSinglePeer struct {
 	suite   suite.BLOBSuite
 	dir     string
 	cluster *cluster
+	// chunked specifies whether the local storage stores BLOBs as chunks
+	chunked bool
 }
 
 var _ = Suite(&ClusterSinglePeer{})
+var _ = Suite(&ClusterSinglePeer{chunked: true})
 var _ = Suite(&ClusterMultiPeers{})
 var _ = Suite(&RPCSuite{})
 
@@ -74,7 +77,7 @@ func (s *ClusterSinglePeer) SetUpTest(c *C) {
 	})
 	c.Assert(err, IsNil)
 
-	local, err := fs.New(s.dir)
+	local, err := fs.NewWithConfig(fs.Config{Path: s.dir, Chunked: s.chunked})
 	c.Assert(err, IsNil)
 
 	obj, err := New(Config{
@@ -210,6 +213,10 @@ func (s *ClusterMultiPeers) TestCleanup(c *C) {
 	s.clusterSuite.Cleanup(c)
 }
 
+func (s *ClusterMultiPeers) TestRepair(c *C) {
+	s.clusterSuite.Repair(c)
+}
+
 type RPCSuite struct {
 	suite        suite.BLOBSuite
 	clusterSuite clusterSuite
@@ -402,3 +409,47 @@ func (s *clusterSuite) Cleanup(c *C) {
 		c.Assert(trace.IsNotFound(err), Equals, true, Commentf("%#v", err))
 	}
 }
+
+func (s *clusterSuite) Repair(c *C) {
+	peer1, peer3 := s.objects[0], s.objects[2]
+
+	data := []byte("hello, there, cluster!")
+
+	envelope, err := s.clients[0].WriteBLOB(bytes.NewBuffer(data))
+	c.Assert(err, IsNil)
+	// the object is stored on as many peers as required by the write factor
+	s.assertObjectStatus(c, []string{"0", "1"}, ObjectHealthy)
+
+	// second peer disappears
+	s.clock.Advance(missedHeartbeats*heartbeatPeriod + heartbeatPeriod)
+	c.Assert(peer1.heartbeat(), IsNil)
+	c.Assert(peer3.heartbeat(), IsNil)
+	s.assertObjectStatus(c, []string{"0"}, ObjectUnderReplicated)
+
+	// only the active peer with a copy of the object replicates it
+	c.Assert(peer3.repairObjects(), IsNil)
+	s.assertObjectStatus(c, []string{"0"}, ObjectUnderReplicated)
+	c.Assert(peer1.repairObjects(), IsNil)
+	s.assertObjectStatus(c, []string{"0", "2"}, ObjectHealthy)
+
+	f, err := peer3.Local.OpenBLOB(envelope.SHA512)
+	c.Assert(err, IsNil)
+	out, err := ioutil.ReadAll(f)
+	c.Assert(err, IsNil)
+	c.Assert(f.Close(), IsNil)
+	c.Assert(string(out), Equals, string(data))
+}
+
+func (s *clusterSuite) assertObjectStatus(c *C, activePeers []string, state string) {
+	status, err := GetStatus(StatusConfig{
+		Backend:          s.objects[0].Backend,
+		Clock:            s.clock,
+		HeartbeatPeriod:  heartbeatPeriod,
+		MissedHeartbeats: missedHeartbeats,
+	})
+	c.Assert(err, IsNil)
+	c.Assert(status.WriteFactor, Equals, peersCount-1)
+	c.Assert(status.Objects, HasLen, 1)
+	c.Assert(status.Objects[0].ActivePeers, DeepEquals, activePeers)
+	c.Assert(status.Objects[0].State, Equals, state)
+}
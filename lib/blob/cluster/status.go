@@ -0,0 +1,157 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"sort"
+	"time"
+
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/utils"
+
+	"github.com/gravitational/trace"
+	"github.com/jonboulle/clockwork"
+)
+
+// StatusConfig describes configuration to query the replication status
+type StatusConfig struct {
+	// Backend is a discovery and metadata backend
+	Backend storage.Backend
+	// Clock is used to determine active peers
+	Clock clockwork.Clock
+	// HeartbeatPeriod defines the period between heartbeats
+	HeartbeatPeriod time.Duration
+	// MissedHeartbeats is how many heartbeats the peer
+	// should miss before it is considered inactive
+	MissedHeartbeats int
+}
+
+func (r *StatusConfig) checkAndSetDefaults() error {
+	if r.Backend == nil {
+		return trace.BadParameter("missing parameter Backend")
+	}
+	if r.Clock == nil {
+		r.Clock = clockwork.NewRealClock()
+	}
+	if r.HeartbeatPeriod == 0 {
+		r.HeartbeatPeriod = defaults.HeartbeatPeriod
+	}
+	if r.MissedHeartbeats == 0 {
+		r.MissedHeartbeats = defaults.MissedHeartbeats
+	}
+	return nil
+}
+
+// Status describes the replication health of the cluster object storage
+type Status struct {
+	// Peers lists the storage peers
+	Peers []PeerStatus `json:"peers"`
+	// WriteFactor is the number of peers an object is required to be replicated to.
+	// It is the largest write factor of all active peers
+	WriteFactor int `json:"write_factor"`
+	// Objects lists the replication status of each object
+	Objects []ObjectStatus `json:"objects"`
+}
+
+// PeerStatus describes a storage peer
+type PeerStatus struct {
+	storage.Peer
+	// Active specifies whether the peer is sending heartbeats
+	Active bool `json:"active"`
+}
+
+// ObjectStatus describes the replication status of a single object
+type ObjectStatus struct {
+	// Hash is the object hash
+	Hash string `json:"hash"`
+	// Peers lists IDs of peers the object has been stored on
+	Peers []string `json:"peers"`
+	// ActivePeers lists IDs of active peers the object has been stored on
+	ActivePeers []string `json:"active_peers"`
+	// State is the object replication state
+	State string `json:"state"`
+}
+
+const (
+	// ObjectHealthy is the state of an object replicated to the required
+	// number of active peers
+	ObjectHealthy = "healthy"
+	// ObjectUnderReplicated is the state of an object replicated to fewer
+	// active peers than required
+	ObjectUnderReplicated = "under-replicated"
+	// ObjectUnavailable is the state of an object not available on any active peer
+	ObjectUnavailable = "unavailable"
+)
+
+// GetStatus returns the replication status of all objects in the cluster storage
+func GetStatus(config StatusConfig) (*Status, error) {
+	if err := config.checkAndSetDefaults(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	peers, err := config.Backend.GetPeers()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	sort.Slice(peers, func(i, j int) bool { return peers[i].ID < peers[j].ID })
+	status := Status{WriteFactor: defaults.WriteFactor}
+	missedWindow := time.Duration(config.MissedHeartbeats) * config.HeartbeatPeriod
+	var active []string
+	for _, peer := range peers {
+		peerStatus := PeerStatus{
+			Peer:   peer,
+			Active: isActive(peer, config.Clock.Now(), missedWindow),
+		}
+		if peerStatus.Active {
+			active = append(active, peer.ID)
+			if peer.WriteFactor > status.WriteFactor {
+				status.WriteFactor = peer.WriteFactor
+			}
+		}
+		status.Peers = append(status.Peers, peerStatus)
+	}
+	hashes, err := config.Backend.GetObjects()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	for _, hash := range hashes {
+		ids, err := config.Backend.GetObjectPeers(hash)
+		if err != nil && !trace.IsNotFound(err) {
+			return nil, trace.Wrap(err)
+		}
+		object := ObjectStatus{Hash: hash, Peers: ids}
+		for _, id := range ids {
+			if utils.StringInSlice(active, id) {
+				object.ActivePeers = append(object.ActivePeers, id)
+			}
+		}
+		object.State = objectState(len(object.ActivePeers), status.WriteFactor)
+		status.Objects = append(status.Objects, object)
+	}
+	return &status, nil
+}
+
+func objectState(replicas, writeFactor int) string {
+	switch {
+	case replicas == 0:
+		return ObjectUnavailable
+	case replicas < writeFactor:
+		return ObjectUnderReplicated
+	default:
+		return ObjectHealthy
+	}
+}
@@ -18,34 +18,106 @@ package fs
 
 import (
 	"crypto/sha512"
+	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 	"sort"
+	"strconv"
 
 	"github.com/gravitational/gravity/lib/blob"
+	"github.com/gravitational/gravity/lib/blob/chunk"
 	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/utils"
 
-	log "github.com/sirupsen/logrus"
+	teleutils "github.com/gravitational/teleport/lib/utils"
 	"github.com/gravitational/trace"
+	log "github.com/sirupsen/logrus"
 )
 
+// Config defines the file system BLOB storage configuration
+type Config struct {
+	// Path is the storage root directory
+	Path string
+	// Chunked enables storing new BLOBs as content-defined chunks.
+	// By default, BLOBs are stored as whole files.
+	// Chunked BLOBs remain readable after chunking has been disabled,
+	// but not by versions that do not support chunked storage
+	Chunked bool
+	// Chunking optionally overrides the chunk size limits
+	Chunking chunk.Config
+}
+
+// CheckAndSetDefaults validates this configuration and sets defaults
+func (r *Config) CheckAndSetDefaults() error {
+	if r.Path == "" {
+		return trace.BadParameter("missing Path parameter")
+	}
+	if err := r.Chunking.CheckAndSetDefaults(); err != nil {
+		return trace.Wrap(err)
+	}
+	return nil
+}
+
+// New returns a new file system BLOB storage in the specified directory
 func New(path string) (blob.Objects, error) {
-	if path == "" {
-		return nil, trace.BadParameter("missing Path parameter")
+	return NewWithConfig(Config{Path: path})
+}
+
+// NewWithConfig returns a new file system BLOB storage with the specified configuration.
+//
+// If chunking is enabled, BLOBs are split into content-defined chunks and every
+// unique chunk is stored only once so that similar BLOBs (e.g. several
+// versions of the same package) share most of the storage.
+// The storage keeps the number of BLOBs referencing each chunk so that
+// the chunks are removed once the last BLOB referencing them is deleted.
+// BLOBs stored as whole files remain readable in either mode
+func NewWithConfig(config Config) (blob.Objects, error) {
+	if err := config.CheckAndSetDefaults(); err != nil {
+		return nil, trace.Wrap(err)
 	}
-	o := &objects{dir: path}
+	o := &objects{Config: config, dir: config.Path}
 	for _, d := range []string{o.tempDir(), o.blobDir()} {
 		if err := os.MkdirAll(d, defaults.SharedDirMask); err != nil {
 			return nil, trace.Wrap(err)
 		}
 	}
+	if err := o.init(); err != nil {
+		return nil, trace.Wrap(err)
+	}
 	return o, nil
 }
 
+// init creates the chunk storage directories if chunking is enabled
+// or the storage already has chunked BLOBs
+func (o *objects) init() error {
+	_, err := os.Stat(o.manifestDir())
+	if err != nil && !os.IsNotExist(err) {
+		return trace.ConvertSystemError(err)
+	}
+	hasChunks := err == nil
+	if !o.Chunked && !hasChunks {
+		return nil
+	}
+	for _, d := range []string{o.manifestDir(), o.chunkDir()} {
+		if err := os.MkdirAll(d, defaults.SharedDirMask); err != nil {
+			return trace.ConvertSystemError(err)
+		}
+	}
+	_, err = os.Stat(o.refDir())
+	if err == nil {
+		return nil
+	}
+	if !os.IsNotExist(err) {
+		return trace.ConvertSystemError(err)
+	}
+	return trace.Wrap(o.rebuildRefs())
+}
+
 type objects struct {
+	Config
 	dir string
 }
 
@@ -57,13 +129,53 @@ func (o *objects) blobDir() string {
 	return filepath.Join(o.dir, "blobs")
 }
 
+func (o *objects) manifestDir() string {
+	return filepath.Join(o.dir, "manifests")
+}
+
+func (o *objects) chunkDir() string {
+	return filepath.Join(o.dir, "chunks")
+}
+
+func (o *objects) refDir() string {
+	return filepath.Join(o.dir, "refs")
+}
+
+func (o *objects) lockPath() string {
+	return filepath.Join(o.dir, "chunks.lock")
+}
+
+func (o *objects) refLockPath() string {
+	return filepath.Join(o.dir, "refs.lock")
+}
+
 // hashDir helps us to organize the blobs in the folder -
 // instead of putting all blobs in one folder, we
 // will put them in 4096 folders, groping by first 3 strings
 // of the sha512 hash - this will allow to scale in cases
 // when there are too many files in one directory
 func (o *objects) hashDir(h string) string {
-	return filepath.Join(o.blobDir(), h[0:3])
+	return hashDir(o.blobDir(), h)
+}
+
+func (o *objects) blobPath(hash string) string {
+	return filepath.Join(o.hashDir(hash), hash)
+}
+
+func (o *objects) manifestPath(hash string) string {
+	return filepath.Join(hashDir(o.manifestDir(), hash), hash)
+}
+
+func (o *objects) chunkPath(hash string) string {
+	return filepath.Join(hashDir(o.chunkDir(), hash), hash)
+}
+
+func (o *objects) refPath(hash string) string {
+	return filepath.Join(hashDir(o.refDir(), hash), hash)
+}
+
+func hashDir(dir, h string) string {
+	return filepath.Join(dir, h[0:3])
 }
 
 func (o *objects) Close() error {
@@ -72,29 +184,37 @@ func (o *objects) Close() error {
 
 // GetBLOBs returns a list of BLOBs in the storage
 func (o *objects) GetBLOBs() ([]string, error) {
-	var out []string
-	blobDir := o.blobDir()
-	err := filepath.Walk(blobDir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			log.Warningf("error while traversing %v: %v", blobDir, err)
-			return nil
-		}
-		if info.IsDir() {
-			return nil
-		}
-		_, name := filepath.Split(info.Name())
-		out = append(out, name)
-		return nil
-	})
+	blobs, err := listFiles(o.blobDir())
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
-	sort.Strings(out)
-	return out, nil
+	if _, err := os.Stat(o.manifestDir()); os.IsNotExist(err) {
+		// storage has no chunked BLOBs
+		return blobs, nil
+	}
+	manifests, err := listFiles(o.manifestDir())
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if len(manifests) == 0 {
+		return blobs, nil
+	}
+	out := utils.NewStringSet()
+	out.AddSlice(blobs)
+	out.AddSlice(manifests)
+	return out.Slice(), nil
 }
 
 // WriteBLOB writes object to the storage, returns object envelope
 func (o *objects) WriteBLOB(data io.Reader) (*blob.Envelope, error) {
+	if o.Chunked {
+		return o.writeChunked(data)
+	}
+	return o.writeFile(data)
+}
+
+// writeFile writes the object as a whole file
+func (o *objects) writeFile(data io.Reader) (*blob.Envelope, error) {
 	// step1 : write data and compute it's hash to the temporary file,
 	// then move it to the proper location based on it's hash
 	f, err := ioutil.TempFile(o.tempDir(), "blob")
@@ -142,10 +262,171 @@ func (o *objects) WriteBLOB(data io.Reader) (*blob.Envelope, error) {
 	}, nil
 }
 
+// writeChunked splits the object into chunks, writes the chunks that
+// are not in the storage yet and then writes the object manifest
+func (o *objects) writeChunked(data io.Reader) (*blob.Envelope, error) {
+	hash, manifest, removed, err := o.writeChunks(data)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	// chunks dropped by a rewrite of the object can only be removed
+	// with the exclusive lock
+	if err := o.removeUnreferencedChunks(removed); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	targetPath := o.manifestPath(hash)
+	fileInfo, err := os.Stat(targetPath)
+	if err != nil {
+		return nil, trace.ConvertSystemError(err)
+	}
+	return &blob.Envelope{
+		SizeBytes: manifest.SizeBytes,
+		SHA512:    hash,
+		Modified:  fileInfo.ModTime().UTC(),
+	}, nil
+}
+
+// writeChunks writes the chunks and the manifest of the object.
+// Returns the object hash, its manifest and the chunks no longer
+// referenced by the object if it has been rewritten
+func (o *objects) writeChunks(data io.Reader) (hash string, manifest chunk.Manifest, removed []string, err error) {
+	// chunks are shared between objects so they can only be
+	// removed when no object is being written
+	unlock, err := o.lock(o.lockPath(), false)
+	if err != nil {
+		return "", manifest, nil, trace.Wrap(err)
+	}
+	defer unlock()
+
+	hasher := sha512.New()
+	chunker, err := chunk.NewChunker(io.TeeReader(data, hasher), o.Chunking)
+	if err != nil {
+		return "", manifest, nil, trace.Wrap(err)
+	}
+	for {
+		buf, err := chunker.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", manifest, nil, trace.Wrap(err)
+		}
+		chunkHash, err := o.writeChunk(buf)
+		if err != nil {
+			return "", manifest, nil, trace.Wrap(err)
+		}
+		manifest.Add(chunkHash, int64(len(buf)))
+	}
+	hash = fmt.Sprintf("%x", hasher.Sum(nil)[:sha512.Size/2])
+	removed, err = o.writeManifest(hash, manifest)
+	if err != nil {
+		return "", manifest, nil, trace.Wrap(err)
+	}
+	// the object might have been stored as a whole file by a previous
+	// version, remove the file as the object is now stored as chunks
+	if err := os.Remove(o.blobPath(hash)); err != nil && !os.IsNotExist(err) {
+		log.Warnf("Failed to remove %v: %v.", o.blobPath(hash), err)
+	}
+	return hash, manifest, removed, nil
+}
+
+// writeManifest writes the manifest of the object with the specified hash
+// and updates the reference counts of the chunks.
+// Returns the chunks of the previous manifest of the object whose
+// reference counts have been decremented
+func (o *objects) writeManifest(hash string, manifest chunk.Manifest) (removed []string, err error) {
+	// multiple objects might be written concurrently
+	unlock, err := o.lock(o.refLockPath(), true)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	defer unlock()
+	existing, _, err := o.readManifest(hash)
+	if err != nil && !trace.IsNotFound(err) {
+		return nil, trace.Wrap(err)
+	}
+	added := uniqueChunks(manifest)
+	if existing != nil {
+		// the object is being rewritten, possibly with different chunks
+		removed = uniqueChunks(*existing)
+	}
+	for _, hash := range added {
+		if err := o.addRef(hash, 1); err != nil {
+			return nil, trace.Wrap(err)
+		}
+	}
+	bytes, err := json.Marshal(manifest)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if err := o.writeFileAtomic(o.manifestPath(hash), bytes); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	for _, hash := range removed {
+		if err := o.addRef(hash, -1); err != nil {
+			return nil, trace.Wrap(err)
+		}
+	}
+	return removed, nil
+}
+
+// writeChunk writes the chunk with the specified data unless it is
+// already in the storage and returns the chunk hash
+func (o *objects) writeChunk(data []byte) (hash string, err error) {
+	hash, err = utils.SHA512Half(data)
+	if err != nil {
+		return "", trace.Wrap(err)
+	}
+	targetPath := o.chunkPath(hash)
+	if _, err := os.Stat(targetPath); err == nil {
+		return hash, nil
+	}
+	if err := o.writeFileAtomic(targetPath, data); err != nil {
+		return "", trace.Wrap(err)
+	}
+	return hash, nil
+}
+
+// writeFileAtomic writes the data to a temporary file and then
+// moves it to the specified path
+func (o *objects) writeFileAtomic(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), defaults.SharedDirMask); err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	f, err := ioutil.TempFile(o.tempDir(), "chunk")
+	if err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	defer f.Close()
+	if _, err := f.Write(data); err != nil {
+		os.Remove(f.Name())
+		return trace.ConvertSystemError(err)
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return trace.ConvertSystemError(err)
+	}
+	if err := os.Rename(f.Name(), path); err != nil {
+		os.Remove(f.Name())
+		return trace.ConvertSystemError(err)
+	}
+	return nil
+}
+
 // GetBLOBEnvelope returns file information identified by hash
 func (o *objects) GetBLOBEnvelope(hash string) (*blob.Envelope, error) {
-	targetPath := filepath.Join(o.hashDir(hash), hash)
-	fileInfo, err := os.Stat(targetPath)
+	manifest, fileInfo, err := o.readManifest(hash)
+	if err == nil {
+		return &blob.Envelope{
+			SizeBytes: manifest.SizeBytes,
+			SHA512:    hash,
+			Modified:  fileInfo.ModTime().UTC(),
+		}, nil
+	}
+	if !trace.IsNotFound(err) {
+		return nil, trace.Wrap(err)
+	}
+	fileInfo, err = os.Stat(o.blobPath(hash))
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
@@ -158,7 +439,14 @@ func (o *objects) GetBLOBEnvelope(hash string) (*blob.Envelope, error) {
 
 // OpenBLOB opens file identified by hash and returns reader
 func (o *objects) OpenBLOB(hash string) (blob.ReadSeekCloser, error) {
-	f, err := os.Open(filepath.Join(o.hashDir(hash), hash))
+	manifest, _, err := o.readManifest(hash)
+	if err == nil {
+		return chunk.NewReader(*manifest, o.openChunk), nil
+	}
+	if !trace.IsNotFound(err) {
+		return nil, trace.Wrap(err)
+	}
+	f, err := os.Open(o.blobPath(hash))
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
@@ -167,9 +455,268 @@ func (o *objects) OpenBLOB(hash string) (blob.ReadSeekCloser, error) {
 
 // DeleteBLOB deletes BLOB from the storage
 func (o *objects) DeleteBLOB(hash string) error {
-	err := os.Remove(filepath.Join(o.hashDir(hash), hash))
+	err := o.deleteChunked(hash)
+	if err == nil || !trace.IsNotFound(err) {
+		return trace.Wrap(err)
+	}
+	err = os.Remove(o.blobPath(hash))
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return nil
+}
+
+// deleteChunked deletes the manifest of the object with the specified hash
+// and removes the chunks no longer referenced by other objects.
+// Returns trace.NotFound if the object is not stored as chunks
+func (o *objects) deleteChunked(hash string) error {
+	if _, err := os.Stat(o.manifestPath(hash)); err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	unlock, err := o.lock(o.lockPath(), true)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	defer unlock()
+	manifest, _, err := o.readManifest(hash)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if err := os.Remove(o.manifestPath(hash)); err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	for _, hash := range uniqueChunks(*manifest) {
+		if err := o.releaseChunk(hash); err != nil {
+			return trace.Wrap(err)
+		}
+	}
+	return nil
+}
+
+// releaseChunk decrements the reference count of the specified chunk
+// and removes the chunk if it is no longer referenced.
+// Requires the exclusive lock
+func (o *objects) releaseChunk(hash string) error {
+	if err := o.addRef(hash, -1); err != nil {
+		return trace.Wrap(err)
+	}
+	return trace.Wrap(o.removeChunkIfUnreferenced(hash))
+}
+
+// removeUnreferencedChunks removes the specified chunks that are no longer
+// referenced by any object
+func (o *objects) removeUnreferencedChunks(hashes []string) error {
+	if len(hashes) == 0 {
+		return nil
+	}
+	unlock, err := o.lock(o.lockPath(), true)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	defer unlock()
+	for _, hash := range hashes {
+		if err := o.removeChunkIfUnreferenced(hash); err != nil {
+			return trace.Wrap(err)
+		}
+	}
+	return nil
+}
+
+// removeChunkIfUnreferenced removes the specified chunk if it is no longer
+// referenced by any object.
+// Requires the exclusive lock
+func (o *objects) removeChunkIfUnreferenced(hash string) error {
+	refs, err := o.readRef(hash)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if refs > 0 {
+		return nil
+	}
+	if err := os.Remove(o.chunkPath(hash)); err != nil && !os.IsNotExist(err) {
+		return trace.ConvertSystemError(err)
+	}
+	return nil
+}
+
+// addRef adds delta to the reference count of the specified chunk
+func (o *objects) addRef(hash string, delta int) error {
+	refs, err := o.readRef(hash)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return trace.Wrap(o.writeRef(hash, refs+delta))
+}
+
+// readRef returns the reference count of the specified chunk
+func (o *objects) readRef(hash string) (int, error) {
+	bytes, err := ioutil.ReadFile(o.refPath(hash))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, trace.ConvertSystemError(err)
+	}
+	refs, err := strconv.Atoi(string(bytes))
+	if err != nil {
+		return 0, trace.Wrap(err, "invalid reference count of chunk %v", hash)
+	}
+	return refs, nil
+}
+
+// writeRef sets the reference count of the specified chunk
+func (o *objects) writeRef(hash string, refs int) error {
+	if refs <= 0 {
+		err := os.Remove(o.refPath(hash))
+		if err != nil && !os.IsNotExist(err) {
+			return trace.ConvertSystemError(err)
+		}
+		return nil
+	}
+	return trace.Wrap(o.writeFileAtomic(o.refPath(hash), []byte(strconv.Itoa(refs))))
+}
+
+// rebuildRefs computes the reference counts of all chunks from the object
+// manifests and removes the chunks not referenced by any object
+func (o *objects) rebuildRefs() error {
+	unlock, err := o.lock(o.lockPath(), true)
 	if err != nil {
 		return trace.Wrap(err)
 	}
+	defer unlock()
+	if _, err := os.Stat(o.refDir()); err == nil {
+		// rebuilt by another process
+		return nil
+	}
+	hashes, err := listFiles(o.manifestDir())
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	refs := make(map[string]int)
+	for _, hash := range hashes {
+		manifest, _, err := o.readManifest(hash)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		for _, chunkHash := range uniqueChunks(*manifest) {
+			refs[chunkHash]++
+		}
+	}
+	chunks, err := listFiles(o.chunkDir())
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	for _, hash := range chunks {
+		if refs[hash] > 0 {
+			continue
+		}
+		if err := os.Remove(o.chunkPath(hash)); err != nil && !os.IsNotExist(err) {
+			return trace.ConvertSystemError(err)
+		}
+	}
+	// write the reference counts into a temporary directory first so
+	// that an interrupted rebuild is restarted the next time
+	tempDir, err := ioutil.TempDir(o.tempDir(), "refs")
+	if err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	defer os.RemoveAll(tempDir)
+	for hash, count := range refs {
+		path := filepath.Join(hashDir(tempDir, hash), hash)
+		if err := os.MkdirAll(filepath.Dir(path), defaults.SharedDirMask); err != nil {
+			return trace.ConvertSystemError(err)
+		}
+		err := ioutil.WriteFile(path, []byte(strconv.Itoa(count)), defaults.SharedReadMask)
+		if err != nil {
+			return trace.ConvertSystemError(err)
+		}
+	}
+	if err := os.Rename(tempDir, o.refDir()); err != nil {
+		return trace.ConvertSystemError(err)
+	}
 	return nil
 }
+
+// uniqueChunks returns the hashes of unique chunks of the specified manifest
+func uniqueChunks(manifest chunk.Manifest) []string {
+	hashes := utils.NewStringSet()
+	for _, chunk := range manifest.Chunks {
+		hashes.Add(chunk.SHA512)
+	}
+	return hashes.Slice()
+}
+
+func (o *objects) readManifest(hash string) (*chunk.Manifest, os.FileInfo, error) {
+	path := o.manifestPath(hash)
+	bytes, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, nil, trace.ConvertSystemError(err)
+	}
+	fileInfo, err := os.Stat(path)
+	if err != nil {
+		return nil, nil, trace.ConvertSystemError(err)
+	}
+	var manifest chunk.Manifest
+	if err := json.Unmarshal(bytes, &manifest); err != nil {
+		return nil, nil, trace.Wrap(err, "failed to read manifest of %v", hash)
+	}
+	if err := manifest.Check(); err != nil {
+		return nil, nil, trace.Wrap(err, "invalid manifest of %v", hash)
+	}
+	return &manifest, fileInfo, nil
+}
+
+func (o *objects) openChunk(hash string) (blob.ReadSeekCloser, error) {
+	f, err := os.Open(o.chunkPath(hash))
+	if err != nil {
+		return nil, trace.ConvertSystemError(err)
+	}
+	return f, nil
+}
+
+// lock acquires the specified lock file. Writers acquire the shared lock on chunks
+// while removing unused chunks requires the exclusive lock.
+// Returns the function to release the lock
+func (o *objects) lock(path string, exclusive bool) (unlock func(), err error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, defaults.SharedReadWriteMask)
+	if err != nil {
+		return nil, trace.ConvertSystemError(err)
+	}
+	if exclusive {
+		err = teleutils.FSWriteLock(f)
+	} else {
+		err = teleutils.FSReadLock(f)
+	}
+	if err != nil {
+		f.Close()
+		return nil, trace.Wrap(err)
+	}
+	return func() {
+		if err := teleutils.FSUnlock(f); err != nil {
+			log.Warnf("Failed to unlock %v: %v.", f.Name(), err)
+		}
+		f.Close()
+	}, nil
+}
+
+// listFiles returns the sorted names of all files in the specified directory tree
+func listFiles(dir string) ([]string, error) {
+	var out []string
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			log.Warningf("error while traversing %v: %v", dir, err)
+			return nil
+		}
+		if info.IsDir() {
+			return nil
+		}
+		_, name := filepath.Split(info.Name())
+		out = append(out, name)
+		return nil
+	})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	sort.Strings(out)
+	return out, nil
+}
@@ -17,9 +17,15 @@ limitations under the License.
 package fs
 
 import (
+	"bytes"
+	"io/ioutil"
+	"math/rand"
 	"os"
+	"path/filepath"
+	"sort"
 	"testing"
 
+	"github.com/gravitational/gravity/lib/blob/chunk"
 	"github.com/gravitational/gravity/lib/blob/suite"
 
 	log "github.com/sirupsen/logrus"
@@ -60,3 +66,168 @@ func (s *FSSuite) TestBLOBWriteTwice(c *C) {
 func (s *FSSuite) TestBLOBList(c *C) {
 	s.suite.BLOBList(c)
 }
+
+type ChunkedFSSuite struct {
+	suite   suite.BLOBSuite
+	dir     string
+	objects *objects
+}
+
+var _ = Suite(&ChunkedFSSuite{})
+
+func (s *ChunkedFSSuite) SetUpTest(c *C) {
+	s.dir = c.MkDir()
+	obj, err := NewWithConfig(Config{
+		Path:     s.dir,
+		Chunked:  true,
+		Chunking: chunk.Config{MinSize: 256, AvgSize: 1024, MaxSize: 4096},
+	})
+	c.Assert(err, IsNil)
+	s.objects = obj.(*objects)
+	s.suite.Objects = obj
+}
+
+func (s *ChunkedFSSuite) TestBLOB(c *C) {
+	s.suite.BLOB(c)
+}
+
+func (s *ChunkedFSSuite) TestBLOBSeek(c *C) {
+	s.suite.BLOBSeek(c)
+}
+
+func (s *ChunkedFSSuite) TestBLOBWriteTwice(c *C) {
+	s.suite.BLOBWriteTwice(c)
+}
+
+func (s *ChunkedFSSuite) TestBLOBList(c *C) {
+	s.suite.BLOBList(c)
+}
+
+func (s *ChunkedFSSuite) TestSharesChunks(c *C) {
+	data1 := make([]byte, 64*1024)
+	rand.New(rand.NewSource(1)).Read(data1)
+	// data2 differs from data1 in a few bytes in the middle
+	data2 := append([]byte(nil), data1...)
+	copy(data2[32*1024:], []byte("changed"))
+
+	e1, err := s.objects.WriteBLOB(bytes.NewReader(data1))
+	c.Assert(err, IsNil)
+	chunks1 := s.listChunks(c)
+
+	e2, err := s.objects.WriteBLOB(bytes.NewReader(data2))
+	c.Assert(err, IsNil)
+	chunks2 := s.listChunks(c)
+	added := len(chunks2) - len(chunks1)
+	c.Assert(added > 0, Equals, true)
+	c.Assert(added < len(chunks1)/4, Equals, true,
+		Commentf("expected most chunks to be shared, %v of %v added", added, len(chunks1)))
+
+	s.assertContents(c, e1.SHA512, data1)
+	s.assertContents(c, e2.SHA512, data2)
+
+	c.Assert(s.objects.DeleteBLOB(e1.SHA512), IsNil)
+	s.assertContents(c, e2.SHA512, data2)
+	c.Assert(len(s.listChunks(c)) <= len(chunks1), Equals, true)
+
+	c.Assert(s.objects.DeleteBLOB(e2.SHA512), IsNil)
+	c.Assert(s.listChunks(c), HasLen, 0)
+}
+
+func (s *ChunkedFSSuite) TestReadsWholeFileBLOBs(c *C) {
+	legacy, err := New(s.dir)
+	c.Assert(err, IsNil)
+	data := []byte("hello, legacy blob")
+	e, err := legacy.WriteBLOB(bytes.NewReader(data))
+	c.Assert(err, IsNil)
+
+	s.assertContents(c, e.SHA512, data)
+	envelope, err := s.objects.GetBLOBEnvelope(e.SHA512)
+	c.Assert(err, IsNil)
+	c.Assert(envelope, DeepEquals, e)
+
+	// rewriting the object converts it to chunks
+	_, err = s.objects.WriteBLOB(bytes.NewReader(data))
+	c.Assert(err, IsNil)
+	_, err = os.Stat(s.objects.blobPath(e.SHA512))
+	c.Assert(os.IsNotExist(err), Equals, true)
+	s.assertContents(c, e.SHA512, data)
+
+	hashes, err := s.objects.GetBLOBs()
+	c.Assert(err, IsNil)
+	c.Assert(hashes, DeepEquals, []string{e.SHA512})
+}
+
+func (s *ChunkedFSSuite) TestRebuildsChunkReferences(c *C) {
+	data1 := make([]byte, 16*1024)
+	rand.New(rand.NewSource(1)).Read(data1)
+	data2 := append(append([]byte(nil), data1...), []byte("suffix")...)
+	e1, err := s.objects.WriteBLOB(bytes.NewReader(data1))
+	c.Assert(err, IsNil)
+	e2, err := s.objects.WriteBLOB(bytes.NewReader(data2))
+	c.Assert(err, IsNil)
+	// write the same object again
+	_, err = s.objects.WriteBLOB(bytes.NewReader(data1))
+	c.Assert(err, IsNil)
+
+	// reference counts are rebuilt from the manifests
+	// if the storage has none
+	c.Assert(os.RemoveAll(s.objects.refDir()), IsNil)
+	orphan := filepath.Join(s.dir, "chunks", "abc", "abcdef")
+	c.Assert(os.MkdirAll(filepath.Dir(orphan), 0755), IsNil)
+	c.Assert(ioutil.WriteFile(orphan, nil, 0644), IsNil)
+	obj, err := NewWithConfig(Config{Path: s.dir})
+	c.Assert(err, IsNil)
+	s.objects = obj.(*objects)
+	_, err = os.Stat(orphan)
+	c.Assert(os.IsNotExist(err), Equals, true)
+
+	c.Assert(s.objects.DeleteBLOB(e2.SHA512), IsNil)
+	s.assertContents(c, e1.SHA512, data1)
+	c.Assert(s.objects.DeleteBLOB(e1.SHA512), IsNil)
+	c.Assert(s.listChunks(c), HasLen, 0)
+}
+
+func (s *ChunkedFSSuite) TestRewriteRemovesDroppedChunks(c *C) {
+	data := make([]byte, 64*1024)
+	rand.New(rand.NewSource(1)).Read(data)
+	e, err := s.objects.WriteBLOB(bytes.NewReader(data))
+	c.Assert(err, IsNil)
+	oldChunks := s.listChunks(c)
+
+	// rewriting the object with different chunk sizes replaces its chunks
+	obj, err := NewWithConfig(Config{
+		Path:     s.dir,
+		Chunked:  true,
+		Chunking: chunk.Config{MinSize: 2048, AvgSize: 8192, MaxSize: 16384},
+	})
+	c.Assert(err, IsNil)
+	s.objects = obj.(*objects)
+	_, err = s.objects.WriteBLOB(bytes.NewReader(data))
+	c.Assert(err, IsNil)
+	s.assertContents(c, e.SHA512, data)
+
+	manifest, _, err := s.objects.readManifest(e.SHA512)
+	c.Assert(err, IsNil)
+	newChunks := uniqueChunks(*manifest)
+	sort.Strings(newChunks)
+	c.Assert(newChunks, Not(DeepEquals), oldChunks)
+	c.Assert(s.listChunks(c), DeepEquals, newChunks)
+
+	c.Assert(s.objects.DeleteBLOB(e.SHA512), IsNil)
+	c.Assert(s.listChunks(c), HasLen, 0)
+}
+
+func (s *ChunkedFSSuite) assertContents(c *C, hash string, expected []byte) {
+	r, err := s.objects.OpenBLOB(hash)
+	c.Assert(err, IsNil)
+	defer r.Close()
+	data, err := ioutil.ReadAll(r)
+	c.Assert(err, IsNil)
+	c.Assert(bytes.Equal(data, expected), Equals, true)
+}
+
+func (s *ChunkedFSSuite) listChunks(c *C) []string {
+	chunks, err := listFiles(filepath.Join(s.dir, "chunks"))
+	c.Assert(err, IsNil)
+	return chunks
+}
@@ -30,8 +30,10 @@ import (
 	"github.com/gravitational/gravity/lib/app"
 	"github.com/gravitational/gravity/lib/app/service"
 	blobfs "github.com/gravitational/gravity/lib/blob/fs"
+	"github.com/gravitational/gravity/lib/checks"
 	"github.com/gravitational/gravity/lib/constants"
 	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/helm"
 	"github.com/gravitational/gravity/lib/loc"
 	"github.com/gravitational/gravity/lib/localenv"
 	"github.com/gravitational/gravity/lib/pack"
@@ -71,6 +73,9 @@ type Config struct {
 	Overwrite bool
 	// Repository represents the source package repository
 	Repository string
+	// Hub is the URL of the hub to download runtimes from.
+	// Defaults to the S3-backed hub if unspecified
+	Hub string
 	// SkipVersionCheck allows to skip tele/runtime compatibility check
 	SkipVersionCheck bool
 	// VendorReq combines vendoring options
@@ -158,7 +163,7 @@ func New(config Config) (*Builder, error) {
 		if err != nil {
 			return nil, trace.Wrap(err)
 		}
-		manifest, err = generateManifest(chart)
+		manifest, err = helm.GenerateManifest(chart)
 		if err != nil {
 			return nil, trace.Wrap(err)
 		}
@@ -174,6 +179,12 @@ func New(config Config) (*Builder, error) {
 				trace.Unwrap(err)) // show original parsing error
 		}
 	}
+	for _, profile := range manifest.NodeProfiles {
+		err = checks.ValidateChecks(profile.Requirements.Checks)
+		if err != nil {
+			return nil, trace.Wrap(err, "invalid checks in node profile %q", profile.Name)
+		}
+	}
 	b := &Builder{
 		Config:   config,
 		Manifest: *manifest,
@@ -510,5 +521,8 @@ type GetRepositoryFunc func(*Builder) (string, error)
 
 // getRepository returns package source repository for the provided builder
 func getRepository(b *Builder) (string, error) {
+	if b.Hub != "" {
+		return b.Hub, nil
+	}
 	return fmt.Sprintf("s3://%v", defaults.HubBucket), nil
 }
@@ -48,29 +48,30 @@ type NewSyncerFunc func(*Builder) (Syncer, error)
 //
 // Satisfies NewSyncerFunc type.
 func NewSyncer(b *Builder) (Syncer, error) {
-	return newS3Syncer()
+	return newHubSyncer(b.Hub, b.Insecure)
 }
 
-// s3Syncer synchronizes local package cache with S3 bucket
-type s3Syncer struct {
-	// hub provides access to runtimes stored in S3 bucket
+// hubSyncer synchronizes local package cache with the hub
+type hubSyncer struct {
+	// hub provides access to runtimes stored in the hub
 	hub hub.Hub
 }
 
-// newS3Syncer returns a syncer that syncs packages with S3 bucket
-func newS3Syncer() (*s3Syncer, error) {
-	hub, err := hub.New(hub.Config{})
+// newHubSyncer returns a syncer that syncs packages with the hub
+// at the specified URL, or the default S3 hub if the URL is empty
+func newHubSyncer(hubURL string, insecure bool) (*hubSyncer, error) {
+	hub, err := hub.NewForURL(hubURL, insecure)
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
-	return &s3Syncer{
+	return &hubSyncer{
 		hub: hub,
 	}, nil
 }
 
 // Sync makes sure that local cache has all required dependencies for the
 // selected runtime
-func (s *s3Syncer) Sync(builder *Builder, runtimeVersion *semver.Version) error {
+func (s *hubSyncer) Sync(builder *Builder, runtimeVersion *semver.Version) error {
 	tarball, err := s.hub.Get(loc.Locator{
 		Repository: defaults.SystemAccountOrg,
 		Name:       defaults.TelekubePackage,
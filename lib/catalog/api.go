@@ -18,6 +18,7 @@ package catalog
 
 import (
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -61,15 +62,31 @@ func Search(req SearchRequest) (*SearchResult, error) {
 		}
 		catalogs = append(catalogs, local)
 	}
+	result := &SearchResult{
+		Apps: make(map[string][]app.Application),
+	}
 	if req.Remote {
-		remote, err := NewRemote()
+		federated, err := LoadFederated()
 		if err != nil {
 			return nil, trace.Wrap(err)
 		}
-		catalogs = append(catalogs, remote)
-	}
-	result := &SearchResult{
-		Apps: make(map[string][]app.Application),
+		if federated != nil {
+			// Federated catalog reports the source of each application
+			// as its repository
+			apps, err := federated.Search(req.Pattern)
+			if err != nil {
+				return nil, trace.Wrap(err)
+			}
+			for _, app := range apps {
+				result.Apps[app.Package.Repository] = append(result.Apps[app.Package.Repository], app)
+			}
+		} else {
+			remote, err := NewRemote()
+			if err != nil {
+				return nil, trace.Wrap(err)
+			}
+			catalogs = append(catalogs, remote)
+		}
 	}
 	for _, catalog := range catalogs {
 		apps, err := catalog.Search(req.Pattern)
@@ -101,26 +118,47 @@ func (r *DownloadResult) Close() error {
 }
 
 // Download downloads the specified application and returns its path.
+//
+// If the federated catalog is configured, applications from the local cluster
+// repository or without a repository are downloaded from the federated catalog
+// while a repository that names a catalog source selects that source.
 func Download(req DownloadRequest) (*DownloadResult, error) {
 	log.Debugf("%#v", req)
 	localCluster, err := localenv.LocalCluster()
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
-	var catalog Catalog
-	switch req.Application.Repository {
-	case "", localCluster.Domain: // local cluster app is requested
+	federated, err := LoadFederated()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	name, version := req.Application.Name, req.Application.Version
+	var reader io.ReadCloser
+	switch repository := req.Application.Repository; {
+	case federated != nil && federated.HasSource(repository): // app from a specific source is requested
+		reader, err = federated.DownloadFrom(repository, name, version)
+	case federated != nil && (repository == "" || repository == localCluster.Domain):
+		reader, err = federated.Download(name, version)
+	case repository == "" || repository == localCluster.Domain: // local cluster app is requested
+		var catalog Catalog
 		catalog, err = NewLocal()
 		if err != nil {
 			return nil, trace.Wrap(err)
 		}
+		reader, err = catalog.Download(name, version)
 	default: // app from remote catalog (Ops Center) is requested
-		catalog, err = NewRemoteFor(req.Application.Repository)
+		if federated != nil {
+			if err := federated.CheckRemote(repository); err != nil {
+				return nil, trace.Wrap(err)
+			}
+		}
+		var catalog Catalog
+		catalog, err = NewRemoteFor(repository)
 		if err != nil {
 			return nil, trace.Wrap(err)
 		}
+		reader, err = catalog.Download(name, version)
 	}
-	reader, err := catalog.Download(req.Application.Name, req.Application.Version)
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
@@ -0,0 +1,212 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package catalog
+
+import (
+	"io/ioutil"
+	"os"
+
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/httplib"
+	"github.com/gravitational/gravity/lib/hub"
+
+	"github.com/ghodss/yaml"
+	"github.com/gravitational/trace"
+)
+
+// FederationConfig describes the sources of the federated catalog.
+//
+// Example:
+//
+//	sources:
+//	- type: local
+//	  priority: 100
+//	- name: internal
+//	  type: helm
+//	  url: https://charts.example.com
+//	  priority: 50
+//	- name: vendor
+//	  type: hub
+//	  url: https://hub.vendor.com/gravity
+//	trustedKeys:
+//	- /etc/gravity/keys/vendor.asc
+type FederationConfig struct {
+	// Sources lists the catalog sources.
+	Sources []SourceConfig `json:"sources"`
+	// TrustedKeys lists paths to the armored PGP public keys application
+	// images are required to be signed with.
+	TrustedKeys []string `json:"trustedKeys,omitempty"`
+}
+
+// SourceConfig describes a single catalog source.
+type SourceConfig struct {
+	// Name is the source name. It is used as a repository in application
+	// locators to download from this source explicitly.
+	//
+	// For Ops Center sources, this is the name of the trusted Ops Center.
+	// For the local cluster, the name defaults to the cluster name.
+	Name string `json:"name,omitempty"`
+	// Type is the source type, one of local, opscenter, hub or helm.
+	Type string `json:"type"`
+	// URL is the address of a hub or a Helm chart repository.
+	URL string `json:"url,omitempty"`
+	// Priority is the source priority, sources with higher priorities win.
+	Priority int `json:"priority,omitempty"`
+	// Insecure turns off TLS verification when connecting to the source.
+	Insecure bool `json:"insecure,omitempty"`
+}
+
+// Check validates the source configuration.
+func (c SourceConfig) Check() error {
+	switch c.Type {
+	case SourceLocal:
+	case SourceOpsCenter:
+		if c.Name == "" {
+			return trace.BadParameter("Ops Center source requires the name of the Ops Center")
+		}
+	case SourceHub, SourceHelm:
+		if c.Name == "" {
+			return trace.BadParameter("%v source requires a name", c.Type)
+		}
+		if c.URL == "" {
+			return trace.BadParameter("%v source %q requires a URL", c.Type, c.Name)
+		}
+	default:
+		return trace.BadParameter("unsupported catalog source type %q, expected one of %v",
+			c.Type, []string{SourceLocal, SourceOpsCenter, SourceHub, SourceHelm})
+	}
+	return nil
+}
+
+// ParseFederationConfig parses the federated catalog configuration.
+func ParseFederationConfig(data []byte) (*FederationConfig, error) {
+	var config FederationConfig
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, trace.Wrap(err, "invalid catalog configuration")
+	}
+	if len(config.Sources) == 0 {
+		return nil, trace.BadParameter("catalog configuration has no sources")
+	}
+	for _, source := range config.Sources {
+		if err := source.Check(); err != nil {
+			return nil, trace.Wrap(err)
+		}
+	}
+	return &config, nil
+}
+
+// NewFederatedFromFile returns the federated catalog configured in the file
+// at the specified path. Returns trace.NotFound if the file does not exist.
+func NewFederatedFromFile(path string) (*federated, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, trace.ConvertSystemError(err)
+	}
+	config, err := ParseFederationConfig(data)
+	if err != nil {
+		return nil, trace.Wrap(err, "failed to read catalog configuration from %v", path)
+	}
+	return NewFederatedFromConfig(*config)
+}
+
+// NewFederatedFromConfig returns the federated catalog for the provided configuration.
+func NewFederatedFromConfig(config FederationConfig) (*federated, error) {
+	var sources []Source
+	for _, sourceConfig := range config.Sources {
+		catalog, err := newSourceCatalog(sourceConfig)
+		if err != nil {
+			return nil, trace.Wrap(err, "failed to configure catalog source %v", sourceConfig.Name)
+		}
+		sources = append(sources, Source{
+			Catalog:  catalog,
+			Priority: sourceConfig.Priority,
+		})
+	}
+	trustedKeys, err := LoadTrustedKeys(config.TrustedKeys...)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return NewFederated(FederatedConfig{
+		Sources:     sources,
+		TrustedKeys: trustedKeys,
+	})
+}
+
+// LoadFederated returns the federated catalog configured on this host.
+// Returns nil if the federated catalog has not been configured.
+func LoadFederated() (*federated, error) {
+	_, err := os.Stat(defaults.CatalogConfigPath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	federated, err := NewFederatedFromFile(defaults.CatalogConfigPath)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return federated, nil
+}
+
+func newSourceCatalog(config SourceConfig) (Catalog, error) {
+	switch config.Type {
+	case SourceLocal:
+		local, err := NewLocal()
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		if config.Name == "" {
+			return local, nil
+		}
+		return &namedCatalog{Catalog: local, name: config.Name}, nil
+	case SourceOpsCenter:
+		return NewRemoteFor(config.Name)
+	case SourceHub:
+		hub, err := hub.NewForURL(config.URL, config.Insecure)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		return NewHubCatalog(config.Name, hub), nil
+	case SourceHelm:
+		return NewHelmRepo(HelmRepoConfig{
+			Name:   config.Name,
+			URL:    config.URL,
+			Client: httplib.GetClient(config.Insecure),
+		})
+	}
+	return nil, trace.BadParameter("unsupported catalog source type %q", config.Type)
+}
+
+// namedCatalog overrides the name of the catalog.
+type namedCatalog struct {
+	Catalog
+	name string
+}
+
+// GetName returns the catalog name.
+func (c *namedCatalog) GetName() string {
+	return c.name
+}
+
+const (
+	// SourceLocal is the source type of the local cluster catalog.
+	SourceLocal = "local"
+	// SourceOpsCenter is the source type of a trusted Ops Center catalog.
+	SourceOpsCenter = "opscenter"
+	// SourceHub is the source type of a hub, see lib/hub.
+	SourceHub = "hub"
+	// SourceHelm is the source type of a Helm chart repository serving application images.
+	SourceHelm = "helm"
+)
@@ -0,0 +1,277 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package catalog
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+
+	"github.com/gravitational/gravity/lib/app"
+	"github.com/gravitational/gravity/lib/utils"
+
+	"github.com/coreos/go-semver/semver"
+	"github.com/gravitational/trace"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/crypto/openpgp"
+)
+
+// Source is a catalog aggregated by the federated catalog.
+type Source struct {
+	// Catalog is the source catalog.
+	Catalog
+	// Priority is the source priority. When an application version is found
+	// in several sources, the one with the highest priority is used.
+	Priority int
+}
+
+// FederatedConfig is the federated catalog configuration.
+type FederatedConfig struct {
+	// Name is the catalog name.
+	Name string
+	// Sources lists the catalogs to aggregate.
+	Sources []Source
+	// TrustedKeys lists the keys application images are required
+	// to be signed with. Signatures are not verified if empty.
+	TrustedKeys openpgp.EntityList
+	// FieldLogger is used for logging.
+	logrus.FieldLogger
+}
+
+// CheckAndSetDefaults validates the config and sets defaults.
+func (c *FederatedConfig) CheckAndSetDefaults() error {
+	if len(c.Sources) == 0 {
+		return trace.BadParameter("at least one catalog source is required")
+	}
+	names := make(map[string]struct{})
+	for _, source := range c.Sources {
+		if _, ok := names[source.GetName()]; ok {
+			return trace.BadParameter("duplicate catalog source %q", source.GetName())
+		}
+		names[source.GetName()] = struct{}{}
+	}
+	if c.Name == "" {
+		c.Name = federatedCatalogName
+	}
+	if c.FieldLogger == nil {
+		c.FieldLogger = log.WithField("catalog", c.Name)
+	}
+	return nil
+}
+
+// NewFederated returns a catalog that aggregates the configured sources.
+//
+// Search results are merged by version: versions found in several sources
+// are reported once, from the source with the highest priority.
+// The repository of each found application is set to the name of its source.
+//
+// Downloads are served by the source with the highest priority that has
+// the requested version and are verified against the trusted keys, if any.
+func NewFederated(config FederatedConfig) (*federated, error) {
+	if err := config.CheckAndSetDefaults(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	sources := make([]Source, len(config.Sources))
+	copy(sources, config.Sources)
+	// Sources with equal priorities retain the configured order
+	sort.SliceStable(sources, func(i, j int) bool {
+		return sources[i].Priority > sources[j].Priority
+	})
+	config.Sources = sources
+	return &federated{FederatedConfig: config}, nil
+}
+
+// federated implements Catalog by aggregating several catalogs.
+type federated struct {
+	// FederatedConfig is the catalog configuration.
+	FederatedConfig
+}
+
+// Search searches for applications in all sources.
+//
+// A source that fails to respond is skipped unless all sources fail.
+func (c *federated) Search(pattern string) ([]app.Application, error) {
+	seen := make(map[string]struct{})
+	var result []app.Application
+	var errors []error
+	for _, source := range c.Sources {
+		apps, err := source.Search(pattern)
+		if err != nil {
+			c.WithError(err).WithField("source", source.GetName()).Warn("Failed to search catalog source.")
+			errors = append(errors, trace.Wrap(err, "failed to search %v", source.GetName()))
+			continue
+		}
+		for _, item := range apps {
+			key := item.Package.Name + ":" + item.Package.Version
+			if _, ok := seen[key]; ok {
+				continue
+			}
+			seen[key] = struct{}{}
+			item.Package.Repository = source.GetName()
+			result = append(result, item)
+		}
+	}
+	if len(errors) == len(c.Sources) {
+		return nil, trace.NewAggregate(errors...)
+	}
+	sortByVersion(result)
+	return result, nil
+}
+
+// Download downloads the specified application from the source with the
+// highest priority that has it.
+func (c *federated) Download(name, version string) (io.ReadCloser, error) {
+	for _, source := range c.Sources {
+		rc, err := c.download(source, name, version)
+		if err == nil {
+			return rc, nil
+		}
+		if !trace.IsNotFound(err) {
+			return nil, trace.Wrap(err)
+		}
+		c.WithField("source", source.GetName()).Debugf("%v:%v not found: %v.", name, version, err)
+	}
+	return nil, trace.NotFound("application %v:%v not found in any of the catalogs: %v",
+		name, version, c.sourceNames())
+}
+
+// DownloadFrom downloads the specified application from the source with
+// the specified name.
+func (c *federated) DownloadFrom(sourceName, name, version string) (io.ReadCloser, error) {
+	for _, source := range c.Sources {
+		if source.GetName() == sourceName {
+			return c.download(source, name, version)
+		}
+	}
+	return nil, trace.NotFound("catalog source %q is not configured", sourceName)
+}
+
+// HasSource returns true if the source with the specified name is configured.
+func (c *federated) HasSource(name string) bool {
+	return utils.StringInSlice(c.sourceNames(), name)
+}
+
+// CheckRemote returns an error if applications from the specified
+// repository, which is not one of the configured sources, cannot be
+// downloaded because they would bypass signature verification.
+func (c *federated) CheckRemote(repository string) error {
+	if len(c.TrustedKeys) == 0 {
+		return nil
+	}
+	return trace.BadParameter("repository %q is not one of the configured "+
+		"catalog sources %v and signature verification is required", repository, c.sourceNames())
+}
+
+// GetName returns the catalog name.
+func (c *federated) GetName() string {
+	return c.Name
+}
+
+// download downloads the application from the source into a temporary file
+// and verifies its signature
+func (c *federated) download(source Source, name, version string) (io.ReadCloser, error) {
+	rc, err := source.Download(name, version)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	defer rc.Close()
+	f, err := ioutil.TempFile("", "app")
+	if err != nil {
+		return nil, trace.ConvertSystemError(err)
+	}
+	readCloser := &utils.CleanupReadCloser{
+		ReadCloser: f,
+		Cleanup: func() {
+			if err := os.Remove(f.Name()); err != nil {
+				c.Warnf("Failed to remove %v: %v.", f.Name(), err)
+			}
+		},
+	}
+	if _, err := io.Copy(f, rc); err != nil {
+		readCloser.Close()
+		return nil, trace.Wrap(err)
+	}
+	if err := c.verify(source, name, version, f); err != nil {
+		readCloser.Close()
+		return nil, trace.Wrap(err)
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		readCloser.Close()
+		return nil, trace.Wrap(err)
+	}
+	return readCloser, nil
+}
+
+// verify verifies the signature of the application image in f
+// if trusted keys are configured
+func (c *federated) verify(source Source, name, version string, f *os.File) error {
+	if len(c.TrustedKeys) == 0 {
+		return nil
+	}
+	signed, ok := source.Catalog.(SignedCatalog)
+	if !ok {
+		return trace.AccessDenied("catalog %v does not provide signatures, "+
+			"cannot verify %v:%v", source.GetName(), name, version)
+	}
+	signature, err := signed.GetSignature(name, version)
+	if err != nil {
+		if trace.IsNotFound(err) {
+			return trace.AccessDenied("%v:%v in catalog %v is not signed",
+				name, version, source.GetName())
+		}
+		return trace.Wrap(err)
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return trace.Wrap(err)
+	}
+	signer, err := verifySignature(c.TrustedKeys, f, signature)
+	if err != nil {
+		return trace.Wrap(err, "failed to verify %v:%v from catalog %v",
+			name, version, source.GetName())
+	}
+	c.WithField("source", source.GetName()).Infof("Verified signature of %v:%v by %v.",
+		name, version, signerName(signer))
+	return nil
+}
+
+func (c *federated) sourceNames() (names []string) {
+	for _, source := range c.Sources {
+		names = append(names, source.GetName())
+	}
+	return names
+}
+
+// sortByVersion sorts applications by name and by version, newest first.
+// Versions that are not valid semvers are ordered lexicographically.
+func sortByVersion(apps []app.Application) {
+	sort.SliceStable(apps, func(i, j int) bool {
+		a, b := apps[i].Package, apps[j].Package
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		versionA, errA := semver.NewVersion(a.Version)
+		versionB, errB := semver.NewVersion(b.Version)
+		if errA != nil || errB != nil {
+			return a.Version > b.Version
+		}
+		return versionB.LessThan(*versionA)
+	})
+}
+
+// federatedCatalogName is the default name of the federated catalog
+const federatedCatalogName = "federated"
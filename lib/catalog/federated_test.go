@@ -0,0 +1,339 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package catalog
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"time"
+
+	"github.com/gravitational/gravity/lib/app"
+	"github.com/gravitational/gravity/lib/archive"
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/hub"
+	"github.com/gravitational/gravity/lib/schema"
+
+	"github.com/gravitational/trace"
+	"golang.org/x/crypto/openpgp"
+	check "gopkg.in/check.v1"
+)
+
+type federatedSuite struct {
+	trusted   *openpgp.Entity
+	untrusted *openpgp.Entity
+}
+
+var _ = check.Suite(&federatedSuite{})
+
+func (s *federatedSuite) SetUpSuite(c *check.C) {
+	var err error
+	s.trusted, err = openpgp.NewEntity("trusted", "", "trusted@example.com", nil)
+	c.Assert(err, check.IsNil)
+	s.untrusted, err = openpgp.NewEntity("untrusted", "", "untrusted@example.com", nil)
+	c.Assert(err, check.IsNil)
+}
+
+func (s *federatedSuite) TestSearchMergesByVersion(c *check.C) {
+	vendor := newTestCatalog("vendor").add("nginx", "1.0.0").add("nginx", "2.0.0").add("redis", "5.0.0")
+	internal := newTestCatalog("internal").add("nginx", "2.0.0").add("nginx", "2.1.0")
+	federated, err := NewFederated(FederatedConfig{
+		Sources: []Source{
+			{Catalog: vendor, Priority: 10},
+			{Catalog: internal, Priority: 20},
+		},
+	})
+	c.Assert(err, check.IsNil)
+
+	apps, err := federated.Search("")
+	c.Assert(err, check.IsNil)
+	c.Assert(appList(apps), check.DeepEquals, []string{
+		"internal/nginx:2.1.0",
+		"internal/nginx:2.0.0",
+		"vendor/nginx:1.0.0",
+		"vendor/redis:5.0.0",
+	})
+
+	apps, err = federated.Search("red")
+	c.Assert(err, check.IsNil)
+	c.Assert(appList(apps), check.DeepEquals, []string{"vendor/redis:5.0.0"})
+}
+
+func (s *federatedSuite) TestSearchSkipsFailedSources(c *check.C) {
+	failing := newTestCatalog("failing")
+	failing.err = trace.ConnectionProblem(nil, "unavailable")
+	federated, err := NewFederated(FederatedConfig{
+		Sources: []Source{
+			{Catalog: failing, Priority: 10},
+			{Catalog: newTestCatalog("vendor").add("nginx", "1.0.0")},
+		},
+	})
+	c.Assert(err, check.IsNil)
+	apps, err := federated.Search("")
+	c.Assert(err, check.IsNil)
+	c.Assert(appList(apps), check.DeepEquals, []string{"vendor/nginx:1.0.0"})
+
+	federated, err = NewFederated(FederatedConfig{Sources: []Source{{Catalog: failing}}})
+	c.Assert(err, check.IsNil)
+	_, err = federated.Search("")
+	c.Assert(err, check.NotNil)
+}
+
+func (s *federatedSuite) TestDownload(c *check.C) {
+	vendor := newTestCatalog("vendor").add("nginx", "1.0.0").add("nginx", "2.0.0")
+	internal := newTestCatalog("internal").add("nginx", "2.0.0")
+	federated, err := NewFederated(FederatedConfig{
+		Sources: []Source{
+			{Catalog: vendor},
+			{Catalog: internal, Priority: 1},
+		},
+	})
+	c.Assert(err, check.IsNil)
+
+	c.Assert(s.download(c, federated, "nginx", "2.0.0"), check.Equals, "internal/nginx:2.0.0")
+	// Falls back to the source that has the version
+	c.Assert(s.download(c, federated, "nginx", "1.0.0"), check.Equals, "vendor/nginx:1.0.0")
+
+	rc, err := federated.DownloadFrom("vendor", "nginx", "2.0.0")
+	c.Assert(err, check.IsNil)
+	c.Assert(readAll(c, rc), check.Equals, "vendor/nginx:2.0.0")
+
+	_, err = federated.Download("nginx", "3.0.0")
+	c.Assert(trace.IsNotFound(err), check.Equals, true, check.Commentf("%v", err))
+	_, err = federated.DownloadFrom("unknown", "nginx", "1.0.0")
+	c.Assert(trace.IsNotFound(err), check.Equals, true, check.Commentf("%v", err))
+}
+
+func (s *federatedSuite) TestVerifiesSignatures(c *check.C) {
+	vendor := newTestCatalog("vendor").add("nginx", "1.0.0").add("nginx", "2.0.0").add("nginx", "3.0.0")
+	vendor.sign(c, "nginx", "1.0.0", s.trusted)
+	vendor.sign(c, "nginx", "2.0.0", s.untrusted)
+	unsigned := &unsignedCatalog{newTestCatalog("unsigned").add("redis", "1.0.0")}
+	federated, err := NewFederated(FederatedConfig{
+		Sources:     []Source{{Catalog: vendor}, {Catalog: unsigned}},
+		TrustedKeys: openpgp.EntityList{s.trusted},
+	})
+	c.Assert(err, check.IsNil)
+
+	c.Assert(s.download(c, federated, "nginx", "1.0.0"), check.Equals, "vendor/nginx:1.0.0")
+	for _, version := range []string{"2.0.0", "3.0.0"} {
+		_, err = federated.Download("nginx", version)
+		c.Assert(trace.IsAccessDenied(err), check.Equals, true, check.Commentf("%v: %v", version, err))
+	}
+	_, err = federated.Download("redis", "1.0.0")
+	c.Assert(trace.IsAccessDenied(err), check.Equals, true, check.Commentf("%v", err))
+
+	// Tampered image
+	vendor.images["nginx:1.0.0"] = []byte("tampered")
+	_, err = federated.Download("nginx", "1.0.0")
+	c.Assert(trace.IsAccessDenied(err), check.Equals, true, check.Commentf("%v", err))
+}
+
+func (s *federatedSuite) TestRejectsRemoteWithTrustedKeys(c *check.C) {
+	vendor := newTestCatalog("vendor").add("nginx", "1.0.0")
+	federated, err := NewFederated(FederatedConfig{
+		Sources: []Source{{Catalog: vendor}},
+	})
+	c.Assert(err, check.IsNil)
+	c.Assert(federated.CheckRemote("opscenter.example.com"), check.IsNil)
+
+	federated, err = NewFederated(FederatedConfig{
+		Sources:     []Source{{Catalog: vendor}},
+		TrustedKeys: openpgp.EntityList{s.trusted},
+	})
+	c.Assert(err, check.IsNil)
+	err = federated.CheckRemote("opscenter.example.com")
+	c.Assert(trace.IsBadParameter(err), check.Equals, true, check.Commentf("%v", err))
+}
+
+func (s *federatedSuite) TestHelmRepo(c *check.C) {
+	dir := c.MkDir()
+	installer := s.publish(c, dir, "nginx", "1.0.0")
+	server := httptest.NewServer(http.FileServer(http.Dir(dir)))
+	defer server.Close()
+	repo, err := NewHelmRepo(HelmRepoConfig{Name: "charts", URL: server.URL})
+	c.Assert(err, check.IsNil)
+	federated, err := NewFederated(FederatedConfig{
+		Sources:     []Source{{Catalog: repo}},
+		TrustedKeys: openpgp.EntityList{s.trusted},
+	})
+	c.Assert(err, check.IsNil)
+
+	apps, err := federated.Search("ng")
+	c.Assert(err, check.IsNil)
+	c.Assert(appList(apps), check.DeepEquals, []string{"charts/nginx:1.0.0"})
+	c.Assert(apps[0].Manifest.Kind, check.Equals, schema.KindApplication)
+
+	c.Assert(s.download(c, federated, "nginx", "1.0.0"), check.Equals, string(installer))
+}
+
+func (s *federatedSuite) TestParsesConfig(c *check.C) {
+	config, err := ParseFederationConfig([]byte(`
+sources:
+- type: local
+  priority: 10
+- name: vendor
+  type: hub
+  url: https://hub.example.com
+trustedKeys:
+- /etc/gravity/keys/vendor.asc
+`))
+	c.Assert(err, check.IsNil)
+	c.Assert(*config, check.DeepEquals, FederationConfig{
+		Sources: []SourceConfig{
+			{Type: SourceLocal, Priority: 10},
+			{Name: "vendor", Type: SourceHub, URL: "https://hub.example.com"},
+		},
+		TrustedKeys: []string{"/etc/gravity/keys/vendor.asc"},
+	})
+
+	for _, data := range []string{
+		"sources: []",
+		"sources: [{type: unknown}]",
+		"sources: [{type: hub, name: vendor}]",
+		"sources: [{type: opscenter}]",
+	} {
+		_, err = ParseFederationConfig([]byte(data))
+		c.Assert(trace.IsBadParameter(err), check.Equals, true, check.Commentf("%q: %v", data, err))
+	}
+}
+
+// publish publishes an application image signed with the trusted key
+// into the hub in dir and returns the image contents
+func (s *federatedSuite) publish(c *check.C, dir, name, version string) []byte {
+	manifest := fmt.Sprintf("apiVersion: bundle.gravitational.io/v2\nkind: Application\nmetadata:\n  name: %v\n  resourceVersion: %v\n",
+		name, version)
+	installer := archive.MustCreateMemArchive([]*archive.Item{
+		archive.ItemFromString(defaults.ManifestFileName, manifest),
+	}).Bytes()
+	path := filepath.Join(c.MkDir(), "installer.tar")
+	c.Assert(ioutil.WriteFile(path, installer, defaults.SharedReadMask), check.IsNil)
+	c.Assert(ioutil.WriteFile(path+".asc", sign(c, installer, s.trusted), defaults.SharedReadMask), check.IsNil)
+	_, err := hub.Publish(hub.PublishConfig{Dir: dir, Path: path})
+	c.Assert(err, check.IsNil)
+	return installer
+}
+
+func (s *federatedSuite) download(c *check.C, catalog Catalog, name, version string) string {
+	rc, err := catalog.Download(name, version)
+	c.Assert(err, check.IsNil)
+	return readAll(c, rc)
+}
+
+func readAll(c *check.C, rc io.ReadCloser) string {
+	defer rc.Close()
+	data, err := ioutil.ReadAll(rc)
+	c.Assert(err, check.IsNil)
+	return string(data)
+}
+
+func appList(apps []app.Application) (result []string) {
+	for _, app := range apps {
+		result = append(result, app.Package.String())
+	}
+	return result
+}
+
+func sign(c *check.C, data []byte, signer *openpgp.Entity) []byte {
+	var signature bytes.Buffer
+	c.Assert(openpgp.ArmoredDetachSign(&signature, signer, bytes.NewReader(data), nil), check.IsNil)
+	return signature.Bytes()
+}
+
+func newTestCatalog(name string) *testCatalog {
+	return &testCatalog{
+		name:       name,
+		images:     make(map[string][]byte),
+		signatures: make(map[string][]byte),
+	}
+}
+
+// testCatalog is an in-memory catalog of signed application images.
+// The contents of each image is its fully-qualified name
+type testCatalog struct {
+	name       string
+	apps       []app.Application
+	images     map[string][]byte
+	signatures map[string][]byte
+	err        error
+}
+
+func (r *testCatalog) add(name, version string) *testCatalog {
+	r.apps = append(r.apps, newApplication(name, version, schema.KindApplication, "", timeNow, 0))
+	r.images[name+":"+version] = []byte(fmt.Sprintf("%v/%v:%v", r.name, name, version))
+	return r
+}
+
+func (r *testCatalog) sign(c *check.C, name, version string, signer *openpgp.Entity) {
+	key := name + ":" + version
+	r.signatures[key] = sign(c, r.images[key], signer)
+}
+
+func (r *testCatalog) Search(pattern string) (result []app.Application, err error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	for _, app := range r.apps {
+		if bytes.Contains([]byte(app.Package.Name), []byte(pattern)) {
+			result = append(result, app)
+		}
+	}
+	return result, nil
+}
+
+func (r *testCatalog) Download(name, version string) (io.ReadCloser, error) {
+	image, ok := r.images[name+":"+version]
+	if !ok {
+		return nil, trace.NotFound("%v:%v not found", name, version)
+	}
+	return ioutil.NopCloser(bytes.NewReader(image)), nil
+}
+
+func (r *testCatalog) GetSignature(name, version string) ([]byte, error) {
+	signature, ok := r.signatures[name+":"+version]
+	if !ok {
+		return nil, trace.NotFound("%v:%v is not signed", name, version)
+	}
+	return signature, nil
+}
+
+func (r *testCatalog) GetName() string {
+	return r.name
+}
+
+// unsignedCatalog is a catalog that does not support signatures
+type unsignedCatalog struct {
+	catalog *testCatalog
+}
+
+func (r *unsignedCatalog) Search(pattern string) ([]app.Application, error) {
+	return r.catalog.Search(pattern)
+}
+
+func (r *unsignedCatalog) Download(name, version string) (io.ReadCloser, error) {
+	return r.catalog.Download(name, version)
+}
+
+func (r *unsignedCatalog) GetName() string {
+	return r.catalog.GetName()
+}
+
+var timeNow = time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
@@ -173,13 +173,9 @@ type hubLister struct {
 	hub hub.Hub
 }
 
-// NewLister returns a lister with S3 hub backend.
-func NewLister() (*hubLister, error) {
-	hub, err := hub.New(hub.Config{})
-	if err != nil {
-		return nil, trace.Wrap(err)
-	}
-	return &hubLister{hub: hub}, nil
+// NewHubLister returns a lister for the provided hub.
+func NewHubLister(hub hub.Hub) *hubLister {
+	return &hubLister{hub: hub}
 }
 
 // List returns application and cluster images from the hub.
@@ -0,0 +1,80 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package catalog
+
+import (
+	"bytes"
+	"io"
+	"os"
+
+	"github.com/gravitational/trace"
+	"golang.org/x/crypto/openpgp"
+)
+
+// SignedCatalog is implemented by catalogs that serve detached signatures
+// of application images.
+type SignedCatalog interface {
+	// GetSignature returns the detached PGP signature of the specified
+	// application image, armored or binary.
+	GetSignature(name, version string) ([]byte, error)
+}
+
+// LoadTrustedKeys reads the PGP public keys from the specified armored keyring files.
+func LoadTrustedKeys(paths ...string) (openpgp.EntityList, error) {
+	var keys openpgp.EntityList
+	for _, path := range paths {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, trace.BadParameter("failed to read trusted keys from %v: %v", path, err)
+		}
+		entities, err := openpgp.ReadArmoredKeyRing(f)
+		f.Close()
+		if err != nil {
+			return nil, trace.BadParameter("failed to read trusted keys from %v: %v", path, err)
+		}
+		keys = append(keys, entities...)
+	}
+	return keys, nil
+}
+
+// verifySignature verifies that the detached signature of the data read
+// from signed has been made by one of the keys in the keyring.
+// Returns the entity that made the signature.
+func verifySignature(keyring openpgp.KeyRing, signed io.Reader, signature []byte) (*openpgp.Entity, error) {
+	var signer *openpgp.Entity
+	var err error
+	if bytes.HasPrefix(bytes.TrimSpace(signature), []byte(armorHeader)) {
+		signer, err = openpgp.CheckArmoredDetachedSignature(keyring, signed, bytes.NewReader(signature))
+	} else {
+		signer, err = openpgp.CheckDetachedSignature(keyring, signed, bytes.NewReader(signature))
+	}
+	if err != nil {
+		return nil, trace.AccessDenied("signature verification failed: %v", err)
+	}
+	return signer, nil
+}
+
+// signerName returns the name of the first identity of the entity
+func signerName(entity *openpgp.Entity) string {
+	for name := range entity.Identities {
+		return name
+	}
+	return entity.PrimaryKey.KeyIdString()
+}
+
+// armorHeader is the header of an armored PGP block
+const armorHeader = "-----BEGIN PGP"
@@ -0,0 +1,275 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package catalog
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gravitational/gravity/lib/app"
+	"github.com/gravitational/gravity/lib/constants"
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/hub"
+	"github.com/gravitational/gravity/lib/loc"
+	"github.com/gravitational/gravity/lib/pack"
+	"github.com/gravitational/gravity/lib/schema"
+
+	"github.com/ghodss/yaml"
+	"github.com/gravitational/trace"
+	"k8s.io/helm/pkg/repo"
+)
+
+// NewHubCatalog returns an application catalog backed by the provided hub.
+func NewHubCatalog(name string, hub hub.Hub) *hubCatalog {
+	return &hubCatalog{
+		name: name,
+		hub:  hub,
+	}
+}
+
+// hubCatalog implements Catalog on top of a hub.
+type hubCatalog struct {
+	name string
+	hub  hub.Hub
+}
+
+// Search searches for applications in the hub.
+func (c *hubCatalog) Search(pattern string) (result []app.Application, err error) {
+	items, err := c.hub.List(true)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	for _, item := range items {
+		if strings.Contains(item.Name, pattern) {
+			result = append(result, newApplication(item.Name, item.Version, item.Type,
+				item.Description, item.Created, item.SizeBytes))
+		}
+	}
+	return result, nil
+}
+
+// Download downloads the specified application from the hub.
+func (c *hubCatalog) Download(name, version string) (io.ReadCloser, error) {
+	return c.hub.Get(loc.Locator{
+		Repository: defaults.SystemAccountOrg,
+		Name:       name,
+		Version:    version,
+	})
+}
+
+// GetSignature returns the detached signature of the specified application.
+func (c *hubCatalog) GetSignature(name, version string) ([]byte, error) {
+	return c.hub.GetSignature(name, version)
+}
+
+// GetName returns the catalog name.
+func (c *hubCatalog) GetName() string {
+	return c.name
+}
+
+// HelmRepoConfig is the configuration of a catalog backed by a Helm chart repository.
+type HelmRepoConfig struct {
+	// Name is the catalog name.
+	Name string
+	// URL is the chart repository URL.
+	URL string
+	// Client is an optional HTTP client.
+	Client *http.Client
+}
+
+// CheckAndSetDefaults validates the config and sets defaults.
+func (c *HelmRepoConfig) CheckAndSetDefaults() error {
+	if c.Name == "" {
+		return trace.BadParameter("missing Name")
+	}
+	if c.URL == "" {
+		return trace.BadParameter("missing URL")
+	}
+	if c.Client == nil {
+		c.Client = http.DefaultClient
+	}
+	return nil
+}
+
+// NewHelmRepo returns an application catalog backed by a Helm chart repository
+// that serves application images, such as the chart repository of a cluster.
+//
+// Application images are located using the URLs in the repository index and
+// their signatures are expected next to them with the .asc extension.
+func NewHelmRepo(config HelmRepoConfig) (*helmRepoCatalog, error) {
+	if err := config.CheckAndSetDefaults(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	baseURL, err := url.Parse(strings.TrimSuffix(config.URL, "/") + "/")
+	if err != nil {
+		return nil, trace.Wrap(err, "invalid repository URL %q", config.URL)
+	}
+	return &helmRepoCatalog{
+		HelmRepoConfig: config,
+		baseURL:        baseURL,
+	}, nil
+}
+
+// helmRepoCatalog implements Catalog on top of a Helm chart repository.
+type helmRepoCatalog struct {
+	// HelmRepoConfig is the catalog configuration.
+	HelmRepoConfig
+	// baseURL is the repository URL relative chart URLs are resolved against.
+	baseURL *url.URL
+}
+
+// Search searches for applications in the repository index.
+func (c *helmRepoCatalog) Search(pattern string) (result []app.Application, err error) {
+	indexFile, err := c.getIndexFile()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	for name, versions := range indexFile.Entries {
+		if !strings.Contains(name, pattern) {
+			continue
+		}
+		for _, entry := range versions {
+			size, _ := strconv.ParseInt(entry.Annotations[constants.AnnotationSize], 10, 64)
+			result = append(result, newApplication(entry.Name, entry.Version,
+				entry.Annotations[constants.AnnotationKind], entry.Description,
+				entry.Created, size))
+		}
+	}
+	return result, nil
+}
+
+// Download downloads the specified application from the repository.
+func (c *helmRepoCatalog) Download(name, version string) (io.ReadCloser, error) {
+	imageURL, err := c.imageURL(name, version)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return c.get(imageURL)
+}
+
+// GetSignature returns the detached signature of the specified application.
+func (c *helmRepoCatalog) GetSignature(name, version string) ([]byte, error) {
+	imageURL, err := c.imageURL(name, version)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	rc, err := c.get(imageURL + ".asc")
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	defer rc.Close()
+	signature, err := ioutil.ReadAll(rc)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return signature, nil
+}
+
+// GetName returns the catalog name.
+func (c *helmRepoCatalog) GetName() string {
+	return c.Name
+}
+
+func (c *helmRepoCatalog) getIndexFile() (*repo.IndexFile, error) {
+	rc, err := c.get(c.resolve("index.yaml"))
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	defer rc.Close()
+	bytes, err := ioutil.ReadAll(rc)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var indexFile repo.IndexFile
+	if err := yaml.Unmarshal(bytes, &indexFile); err != nil {
+		return nil, trace.Wrap(err, "invalid repository index in %v", c.URL)
+	}
+	return &indexFile, nil
+}
+
+// imageURL returns the URL of the specified application image
+func (c *helmRepoCatalog) imageURL(name, version string) (string, error) {
+	indexFile, err := c.getIndexFile()
+	if err != nil {
+		return "", trace.Wrap(err)
+	}
+	entry, err := indexFile.Get(name, version)
+	if err != nil {
+		return "", trace.NotFound("application %v:%v not found in %v", name, version, c.Name)
+	}
+	if len(entry.URLs) == 0 {
+		return "", trace.NotFound("no URL for application %v:%v in %v", name, version, c.Name)
+	}
+	return c.resolve(entry.URLs[0]), nil
+}
+
+// resolve returns the URL for the specified reference
+// relative to the repository URL
+func (c *helmRepoCatalog) resolve(ref string) string {
+	refURL, err := url.Parse(ref)
+	if err != nil {
+		return ref
+	}
+	return c.baseURL.ResolveReference(refURL).String()
+}
+
+func (c *helmRepoCatalog) get(url string) (io.ReadCloser, error) {
+	resp, err := c.Client.Get(url)
+	if err != nil {
+		return nil, trace.ConvertSystemError(err)
+	}
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		resp.Body.Close()
+		return nil, trace.NotFound("%v not found", url)
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		resp.Body.Close()
+		return nil, trace.BadParameter("failed to fetch %v: %v", url, resp.Status)
+	}
+	return resp.Body, nil
+}
+
+// newApplication returns the application item for an application image
+// listed in a hub or a repository index.
+func newApplication(name, version, kind, description string, created time.Time, size int64) app.Application {
+	locator := loc.Locator{
+		Repository: defaults.SystemAccountOrg,
+		Name:       name,
+		Version:    version,
+	}
+	var manifest schema.Manifest
+	manifest.Kind = kind
+	manifest.Metadata = schema.Metadata{
+		Name:            name,
+		ResourceVersion: version,
+		Description:     strings.TrimSpace(description),
+	}
+	return app.Application{
+		Package: locator,
+		PackageEnvelope: pack.PackageEnvelope{
+			Locator:   locator,
+			SizeBytes: size,
+			Created:   created,
+		},
+		Manifest: manifest,
+	}
+}
@@ -0,0 +1,522 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package checks
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/schema"
+	"github.com/gravitational/gravity/lib/utils"
+
+	sigar "github.com/cloudfoundry/gosigar"
+	"github.com/gravitational/satellite/agent/health"
+	"github.com/gravitational/satellite/agent/proto/agentpb"
+	"github.com/gravitational/satellite/monitoring"
+	"github.com/gravitational/trace"
+)
+
+func init() {
+	RegisterChecker(CheckKernelModules, newKernelModulesChecker)
+	RegisterChecker(CheckSysctl, newSysctlChecker)
+	RegisterChecker(CheckFilesystem, newFilesystemChecker)
+	RegisterChecker(CheckMountOptions, newMountOptionsChecker)
+	RegisterChecker(CheckDiskPerformance, newDiskPerformanceChecker)
+}
+
+const (
+	// CheckKernelModules is the type of the check that verifies
+	// that kernel modules are loaded
+	CheckKernelModules = "kernelModules"
+	// CheckSysctl is the type of the check that verifies kernel parameters
+	CheckSysctl = "sysctl"
+	// CheckFilesystem is the type of the check that verifies the type
+	// and features of the filesystem a path is on
+	CheckFilesystem = "filesystem"
+	// CheckMountOptions is the type of the check that verifies the options
+	// of the filesystem mount a path is on
+	CheckMountOptions = "mountOptions"
+	// CheckDiskPerformance is the type of the check that benchmarks
+	// the synchronous write performance of the disk a path is on
+	CheckDiskPerformance = "diskPerformance"
+)
+
+// KernelModulesSpec is the specification of the kernel modules check
+type KernelModulesSpec struct {
+	// Modules lists the required kernel modules
+	Modules []monitoring.ModuleRequest `json:"modules"`
+}
+
+func newKernelModulesChecker(check schema.Check, config CheckerConfig) (health.Checker, error) {
+	var spec KernelModulesSpec
+	if err := decodeSpec(check, &spec); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if len(spec.Modules) == 0 {
+		return nil, trace.BadParameter("at least one kernel module is required")
+	}
+	for _, module := range spec.Modules {
+		if module.Name == "" {
+			return nil, trace.BadParameter("kernel module name is required")
+		}
+	}
+	return monitoring.NewKernelModuleChecker(spec.Modules...), nil
+}
+
+// SysctlSpec is the specification of the kernel parameters check
+type SysctlSpec struct {
+	// Params lists the kernel parameters to verify
+	Params []SysctlParam `json:"params"`
+}
+
+// SysctlParam describes the expected value of a kernel parameter
+type SysctlParam struct {
+	// Name is the parameter name, e.g. net.ipv4.ip_forward
+	Name string `json:"name"`
+	// Value is the exact expected parameter value
+	Value string `json:"value,omitempty"`
+	// Min is the minimum value of a numeric parameter
+	Min *int64 `json:"min,omitempty"`
+}
+
+func newSysctlChecker(check schema.Check, config CheckerConfig) (health.Checker, error) {
+	var spec SysctlSpec
+	if err := decodeSpec(check, &spec); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if len(spec.Params) == 0 {
+		return nil, trace.BadParameter("at least one parameter is required")
+	}
+	for _, param := range spec.Params {
+		if param.Name == "" {
+			return nil, trace.BadParameter("parameter name is required")
+		}
+		if (param.Value == "") == (param.Min == nil) {
+			return nil, trace.BadParameter("parameter %v requires either value or min", param.Name)
+		}
+	}
+	return &sysctlChecker{spec: spec, sysctl: monitoring.Sysctl}, nil
+}
+
+// sysctlChecker verifies kernel parameters
+type sysctlChecker struct {
+	spec SysctlSpec
+	// sysctl returns the value of the kernel parameter
+	sysctl func(name string) (string, error)
+}
+
+// Name returns the name of this checker
+func (r *sysctlChecker) Name() string { return sysctlCheckerID }
+
+// Check verifies the values of the kernel parameters
+func (r *sysctlChecker) Check(ctx context.Context, reporter health.Reporter) {
+	var failed bool
+	for _, param := range r.spec.Params {
+		value, err := r.sysctl(param.Name)
+		if err != nil {
+			reporter.Add(monitoring.NewProbeFromErr(r.Name(),
+				fmt.Sprintf("failed to read kernel parameter %v", param.Name), err))
+			failed = true
+			continue
+		}
+		if err := param.check(value); err != nil {
+			reporter.Add(newFailedProbe(r.Name(), err.Error()))
+			failed = true
+		}
+	}
+	if !failed {
+		reporter.Add(monitoring.NewSuccessProbe(r.Name()))
+	}
+}
+
+func (r SysctlParam) check(value string) error {
+	if r.Min == nil {
+		if value != r.Value {
+			return trace.BadParameter("kernel parameter %v is %q, expected %q",
+				r.Name, value, r.Value)
+		}
+		return nil
+	}
+	actual, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+	if err != nil {
+		return trace.BadParameter("kernel parameter %v has non-numeric value %q", r.Name, value)
+	}
+	if actual < *r.Min {
+		return trace.BadParameter("kernel parameter %v is %v, expected at least %v",
+			r.Name, actual, *r.Min)
+	}
+	return nil
+}
+
+// FilesystemSpec is the specification of the filesystem check
+type FilesystemSpec struct {
+	// Path is the path to verify the filesystem of
+	Path string `json:"path"`
+	// Types lists the acceptable filesystem types, e.g. xfs
+	Types []string `json:"types,omitempty"`
+	// DType requires the filesystem to support d_type, which for XFS
+	// means it has been created with ftype=1
+	DType bool `json:"dType,omitempty"`
+}
+
+func newFilesystemChecker(check schema.Check, config CheckerConfig) (health.Checker, error) {
+	var spec FilesystemSpec
+	if err := decodeSpec(check, &spec); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if spec.Path == "" {
+		return nil, trace.BadParameter("path is required")
+	}
+	if len(spec.Types) == 0 && !spec.DType {
+		return nil, trace.BadParameter("either types or dType is required")
+	}
+	spec.Path = config.path(spec.Path)
+	return &filesystemChecker{spec: spec, mounts: getMounts}, nil
+}
+
+// filesystemChecker verifies the filesystem a path is on
+type filesystemChecker struct {
+	spec   FilesystemSpec
+	mounts mountsFunc
+}
+
+// Name returns the name of this checker
+func (r *filesystemChecker) Name() string { return filesystemCheckerID }
+
+// Check verifies the type and features of the filesystem
+func (r *filesystemChecker) Check(ctx context.Context, reporter health.Reporter) {
+	path, err := existingParent(r.spec.Path)
+	if err != nil {
+		reporter.Add(monitoring.NewProbeFromErr(r.Name(), "failed to find path", err))
+		return
+	}
+	if len(r.spec.Types) != 0 {
+		mount, err := findMount(path, r.mounts)
+		if err != nil {
+			reporter.Add(monitoring.NewProbeFromErr(r.Name(),
+				fmt.Sprintf("failed to find filesystem for %v", path), err))
+			return
+		}
+		if !utils.StringInSlice(r.spec.Types, mount.SysTypeName) {
+			reporter.Add(newFailedProbe(r.Name(), fmt.Sprintf(
+				"%v is on %v filesystem mounted at %v, expected one of %v",
+				r.spec.Path, mount.SysTypeName, mount.DirName, strings.Join(r.spec.Types, ", "))))
+			return
+		}
+	}
+	if r.spec.DType {
+		monitoring.NewDTypeChecker(path).Check(ctx, reporter)
+		return
+	}
+	reporter.Add(monitoring.NewSuccessProbe(r.Name()))
+}
+
+// MountOptionsSpec is the specification of the mount options check
+type MountOptionsSpec struct {
+	// Path is the path to verify the mount options of
+	Path string `json:"path"`
+	// Options lists the required mount options, e.g. noatime or prjquota
+	Options []string `json:"options"`
+}
+
+func newMountOptionsChecker(check schema.Check, config CheckerConfig) (health.Checker, error) {
+	var spec MountOptionsSpec
+	if err := decodeSpec(check, &spec); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if spec.Path == "" {
+		return nil, trace.BadParameter("path is required")
+	}
+	if len(spec.Options) == 0 {
+		return nil, trace.BadParameter("at least one mount option is required")
+	}
+	spec.Path = config.path(spec.Path)
+	return &mountOptionsChecker{spec: spec, mounts: getMounts}, nil
+}
+
+// mountOptionsChecker verifies the options of the filesystem mount a path is on
+type mountOptionsChecker struct {
+	spec   MountOptionsSpec
+	mounts mountsFunc
+}
+
+// Name returns the name of this checker
+func (r *mountOptionsChecker) Name() string { return mountOptionsCheckerID }
+
+// Check verifies that the filesystem is mounted with the required options
+func (r *mountOptionsChecker) Check(ctx context.Context, reporter health.Reporter) {
+	path, err := existingParent(r.spec.Path)
+	if err != nil {
+		reporter.Add(monitoring.NewProbeFromErr(r.Name(), "failed to find path", err))
+		return
+	}
+	mount, err := findMount(path, r.mounts)
+	if err != nil {
+		reporter.Add(monitoring.NewProbeFromErr(r.Name(),
+			fmt.Sprintf("failed to find filesystem for %v", path), err))
+		return
+	}
+	options := utils.NewStringSet()
+	for _, option := range strings.Split(mount.Options, ",") {
+		options.Add(option)
+	}
+	var missing []string
+	for _, option := range r.spec.Options {
+		if !options.Has(option) {
+			missing = append(missing, option)
+		}
+	}
+	if len(missing) != 0 {
+		reporter.Add(newFailedProbe(r.Name(), fmt.Sprintf(
+			"filesystem mounted at %v is missing mount options: %v",
+			mount.DirName, strings.Join(missing, ", "))))
+		return
+	}
+	reporter.Add(monitoring.NewSuccessProbe(r.Name()))
+}
+
+// DiskPerformanceSpec is the specification of the disk performance check
+type DiskPerformanceSpec struct {
+	// Path is the directory to benchmark the disk of
+	Path string `json:"path"`
+	// MinIOPS is the minimum number of synchronous writes per second
+	MinIOPS float64 `json:"minIOPS,omitempty"`
+	// MaxFsyncLatency is the maximum 99th percentile latency
+	// of a synchronous write, e.g. 10ms
+	MaxFsyncLatency string `json:"maxFsyncLatency,omitempty"`
+	// Writes is the number of synchronous writes to perform
+	Writes int `json:"writes,omitempty"`
+}
+
+func newDiskPerformanceChecker(check schema.Check, config CheckerConfig) (health.Checker, error) {
+	var spec DiskPerformanceSpec
+	if err := decodeSpec(check, &spec); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if spec.Path == "" {
+		return nil, trace.BadParameter("path is required")
+	}
+	checker := &diskPerformanceChecker{
+		path:    config.path(spec.Path),
+		minIOPS: spec.MinIOPS,
+		writes:  spec.Writes,
+	}
+	if spec.MaxFsyncLatency != "" {
+		latency, err := time.ParseDuration(spec.MaxFsyncLatency)
+		if err != nil {
+			return nil, trace.BadParameter("invalid maxFsyncLatency %q: %v", spec.MaxFsyncLatency, err)
+		}
+		checker.maxLatency = latency
+	}
+	if checker.minIOPS == 0 && checker.maxLatency == 0 {
+		return nil, trace.BadParameter("either minIOPS or maxFsyncLatency is required")
+	}
+	if checker.writes <= 0 {
+		checker.writes = defaults.DiskBenchmarkWrites
+	}
+	return checker, nil
+}
+
+// diskPerformanceChecker benchmarks synchronous writes to a disk
+type diskPerformanceChecker struct {
+	path       string
+	minIOPS    float64
+	maxLatency time.Duration
+	writes     int
+}
+
+// Name returns the name of this checker
+func (r *diskPerformanceChecker) Name() string { return diskPerformanceCheckerID }
+
+// Check benchmarks the disk and compares the results with the requirements
+func (r *diskPerformanceChecker) Check(ctx context.Context, reporter health.Reporter) {
+	path, err := existingParent(r.path)
+	if err != nil {
+		reporter.Add(monitoring.NewProbeFromErr(r.Name(), "failed to find path", err))
+		return
+	}
+	stats, err := benchmarkDisk(ctx, path, r.writes)
+	if err != nil {
+		reporter.Add(monitoring.NewProbeFromErr(r.Name(),
+			fmt.Sprintf("failed to benchmark disk at %v", path), err))
+		return
+	}
+	var failed bool
+	if r.minIOPS != 0 && stats.iops < r.minIOPS {
+		reporter.Add(newFailedProbe(r.Name(), fmt.Sprintf(
+			"disk at %v sustains %.0f synchronous writes per second, expected at least %.0f",
+			path, stats.iops, r.minIOPS)))
+		failed = true
+	}
+	if r.maxLatency != 0 && stats.latency > r.maxLatency {
+		reporter.Add(newFailedProbe(r.Name(), fmt.Sprintf(
+			"disk at %v has 99th percentile fsync latency of %v, expected at most %v",
+			path, stats.latency, r.maxLatency)))
+		failed = true
+	}
+	if !failed {
+		reporter.Add(monitoring.NewSuccessProbe(r.Name()))
+	}
+}
+
+// diskStats describes the results of the disk benchmark
+type diskStats struct {
+	// iops is the number of synchronous writes per second
+	iops float64
+	// latency is the 99th percentile latency of a synchronous write
+	latency time.Duration
+}
+
+// benchmarkDisk performs the specified number of small synchronous
+// writes in a temporary file in dir, similar to writes of a write-ahead log
+func benchmarkDisk(ctx context.Context, dir string, writes int) (*diskStats, error) {
+	f, err := ioutil.TempFile(dir, "diskcheck")
+	if err != nil {
+		return nil, trace.ConvertSystemError(err)
+	}
+	defer func() {
+		f.Close()
+		os.Remove(f.Name())
+	}()
+	buf := make([]byte, defaults.DiskBenchmarkWriteSize)
+	latencies := make([]time.Duration, 0, writes)
+	start := time.Now()
+	for i := 0; i < writes; i++ {
+		select {
+		case <-ctx.Done():
+			return nil, trace.Wrap(ctx.Err())
+		default:
+		}
+		writeStart := time.Now()
+		if _, err := f.Write(buf); err != nil {
+			return nil, trace.ConvertSystemError(err)
+		}
+		if err := f.Sync(); err != nil {
+			return nil, trace.ConvertSystemError(err)
+		}
+		latencies = append(latencies, time.Since(writeStart))
+	}
+	elapsed := time.Since(start)
+	return &diskStats{
+		iops:    float64(writes) / elapsed.Seconds(),
+		latency: percentile(latencies, 0.99),
+	}, nil
+}
+
+// percentile returns the specified percentile of the durations
+func percentile(durations []time.Duration, p float64) time.Duration {
+	if len(durations) == 0 {
+		return 0
+	}
+	sorted := append([]time.Duration{}, durations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	index := int(math.Ceil(p*float64(len(sorted)))) - 1
+	if index < 0 {
+		index = 0
+	}
+	return sorted[index]
+}
+
+// mountsFunc returns the list of active mounts
+type mountsFunc func() ([]sigar.FileSystem, error)
+
+func getMounts() ([]sigar.FileSystem, error) {
+	var mounts sigar.FileSystemList
+	if err := mounts.Get(); err != nil {
+		return nil, trace.ConvertSystemError(err)
+	}
+	return mounts.List, nil
+}
+
+// findMount returns the mount the specified path is on
+func findMount(path string, mounts mountsFunc) (*sigar.FileSystem, error) {
+	path, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return nil, trace.ConvertSystemError(err)
+	}
+	list, err := mounts()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var result *sigar.FileSystem
+	for i, mount := range list {
+		// ignore rootfs to find the actual filesystem the path is on
+		if mount.SysTypeName == "rootfs" || !isSubpath(path, mount.DirName) {
+			continue
+		}
+		// the longest matching mount point wins, later mounts shadow earlier ones
+		if result == nil || len(mount.DirName) >= len(result.DirName) {
+			result = &list[i]
+		}
+	}
+	if result == nil {
+		return nil, trace.NotFound("no filesystem found for %v", path)
+	}
+	return result, nil
+}
+
+// isSubpath returns true if path is dir or is inside dir
+func isSubpath(path, dir string) bool {
+	if dir == "/" || path == dir {
+		return true
+	}
+	return strings.HasPrefix(path, dir+"/")
+}
+
+// existingParent returns the path itself if it exists,
+// or its closest existing parent directory
+func existingParent(path string) (string, error) {
+	path = filepath.Clean(path)
+	for {
+		_, err := os.Stat(path)
+		if err == nil {
+			return path, nil
+		}
+		if !os.IsNotExist(err) {
+			return "", trace.ConvertSystemError(err)
+		}
+		parent := filepath.Dir(path)
+		if parent == path {
+			return "", trace.NotFound("no existing parent for %v", path)
+		}
+		path = parent
+	}
+}
+
+func newFailedProbe(checker, detail string) *agentpb.Probe {
+	return &agentpb.Probe{
+		Checker: checker,
+		Detail:  detail,
+		Status:  agentpb.Probe_Failed,
+	}
+}
+
+const (
+	sysctlCheckerID          = "sysctl"
+	filesystemCheckerID      = "filesystem"
+	mountOptionsCheckerID    = "mount-options"
+	diskPerformanceCheckerID = "disk-performance"
+)
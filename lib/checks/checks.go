@@ -89,6 +89,12 @@ func ValidateManifest(
 	failedProbes = append(failedProbes, failed...)
 
 	failedProbes = append(failedProbes, schema.ValidateKubelet(profile, manifest)...)
+
+	failed, err = RunChecks(context.TODO(), profile.Requirements.Checks, CheckerConfig{StateDir: stateDir})
+	if err != nil {
+		errors = append(errors, trace.Wrap(err, "error running profile checks"))
+	}
+	failedProbes = append(failedProbes, failed...)
 	return failedProbes, trace.NewAggregate(errors...)
 }
 
@@ -147,6 +153,8 @@ type LocalChecksResult struct {
 	Fixed []*agentpb.Probe
 	// Fixable is a list of probes that can be attempted to auto-fix
 	Fixable []*agentpb.Probe
+	// Warnings is a list of failed probes that do not prevent the operation
+	Warnings []*agentpb.Probe
 }
 
 // GetFailed returns a list of all failed probes
@@ -184,23 +192,26 @@ func ValidateLocal(req LocalChecksRequest) (*LocalChecksResult, error) {
 	}
 
 	failedProbes = append(failedProbes, RunBasicChecks(req.Context, req.Options)...)
+	failedProbes, warnings := SplitWarnings(failedProbes)
 	if len(failedProbes) == 0 {
-		return &LocalChecksResult{}, nil
+		return &LocalChecksResult{Warnings: warnings}, nil
 	}
 
 	if !req.AutoFix {
 		failed, fixable := autofix.GetFixable(failedProbes)
 		return &LocalChecksResult{
-			Failed:  failed,
-			Fixable: fixable,
+			Failed:   failed,
+			Fixable:  fixable,
+			Warnings: warnings,
 		}, nil
 	}
 
 	// try to auto-fix some of the issues
 	fixed, unfixed := autofix.Fix(req.Context, failedProbes, req.Progress)
 	return &LocalChecksResult{
-		Failed: unfixed,
-		Fixed:  fixed,
+		Failed:   unfixed,
+		Fixed:    fixed,
+		Warnings: warnings,
 	}, nil
 }
 
@@ -215,6 +226,10 @@ func RunLocalChecks(req LocalChecksRequest) error {
 	if err != nil {
 		return trace.Wrap(err)
 	}
+	if len(result.Warnings) != 0 {
+		req.PrintWarn(nil, "The following pre-flight checks produced warnings:\n%v",
+			FormatFailedChecks(result.Warnings))
+	}
 	if len(result.GetFailed()) != 0 {
 		return trace.BadParameter(fmt.Sprintf("The following pre-flight checks failed:\n%v",
 			FormatFailedChecks(result.GetFailed())))
@@ -378,6 +393,10 @@ func (r *checker) Run(ctx context.Context) error {
 			errors = append(errors,
 				trace.BadParameter("failed to validate remote node %v", server))
 		}
+		failed, warnings := SplitWarnings(failed)
+		for _, probe := range warnings {
+			log.Warnf("%v check warning: %v.", server, formatProbe(*probe))
+		}
 		if len(failed) != 0 {
 			errors = append(errors, trace.BadParameter("%v failed checks:\n%v",
 				server, FormatFailedChecks(failed)))
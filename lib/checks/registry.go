@@ -0,0 +1,171 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package checks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/schema"
+
+	"github.com/gravitational/satellite/agent/health"
+	"github.com/gravitational/satellite/agent/proto/agentpb"
+	"github.com/gravitational/satellite/monitoring"
+	"github.com/gravitational/trace"
+)
+
+// CheckerFactory creates a checker for the specified typed check
+type CheckerFactory func(check schema.Check, config CheckerConfig) (health.Checker, error)
+
+// CheckerConfig describes the node environment typed checks are executed in
+type CheckerConfig struct {
+	// StateDir is the gravity state directory on the node.
+	// Paths in check specifications that refer to the default state
+	// directory are translated to this directory
+	StateDir string
+}
+
+// path returns the path translated to the node state directory
+func (r CheckerConfig) path(path string) string {
+	if r.StateDir == "" || !strings.HasPrefix(path, defaults.GravityDir) {
+		return path
+	}
+	return r.StateDir + strings.TrimPrefix(path, defaults.GravityDir)
+}
+
+// RegisterChecker registers the factory for typed checks of the specified type.
+// Registering a factory for an existing type replaces the previous factory
+func RegisterChecker(checkType string, factory CheckerFactory) {
+	registry.Lock()
+	defer registry.Unlock()
+	registry.factories[checkType] = factory
+}
+
+// RegisteredCheckers returns the sorted list of registered check types
+func RegisteredCheckers() (types []string) {
+	registry.RLock()
+	defer registry.RUnlock()
+	for checkType := range registry.factories {
+		types = append(types, checkType)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// NewCheckers creates checkers for the specified typed checks.
+// Failed probes reported by the checkers have their severity set
+// according to the check configuration
+func NewCheckers(checks []schema.Check, config CheckerConfig) (checkers []health.Checker, err error) {
+	for _, check := range checks {
+		factory, ok := getFactory(check.Type)
+		if !ok {
+			return nil, trace.BadParameter("unknown check type %q, supported types are: %v",
+				check.Type, strings.Join(RegisteredCheckers(), ", "))
+		}
+		checker, err := factory(check, config)
+		if err != nil {
+			return nil, trace.Wrap(err, "invalid %v check", check.Type)
+		}
+		checkers = append(checkers, &typedChecker{Checker: checker, check: check})
+	}
+	return checkers, nil
+}
+
+// ValidateChecks makes sure the specified typed checks have known types
+// and valid specifications
+func ValidateChecks(checks []schema.Check) error {
+	_, err := NewCheckers(checks, CheckerConfig{})
+	return trace.Wrap(err)
+}
+
+// RunChecks executes the specified typed checks and returns the list of failed probes
+func RunChecks(ctx context.Context, checks []schema.Check, config CheckerConfig) ([]*agentpb.Probe, error) {
+	checkers, err := NewCheckers(checks, config)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var probes health.Probes
+	monitoring.NewCompositeChecker("typed checks", checkers).Check(ctx, &probes)
+	return probes.GetFailed(), nil
+}
+
+// SplitWarnings splits the specified failed probes into fatal failures and warnings
+func SplitWarnings(probes []*agentpb.Probe) (failed, warnings []*agentpb.Probe) {
+	for _, probe := range probes {
+		if probe.Severity == agentpb.Probe_Warning {
+			warnings = append(warnings, probe)
+		} else {
+			failed = append(failed, probe)
+		}
+	}
+	return failed, warnings
+}
+
+// typedChecker sets the severity and the description of the check
+// on the failed probes reported by the underlying checker
+type typedChecker struct {
+	health.Checker
+	check schema.Check
+}
+
+// Check runs the underlying checker and reports its probes to reporter
+func (r *typedChecker) Check(ctx context.Context, reporter health.Reporter) {
+	var probes health.Probes
+	r.Checker.Check(ctx, &probes)
+	for _, probe := range probes {
+		if probe.Status == agentpb.Probe_Failed {
+			probe.Severity = agentpb.Probe_Critical
+			if r.check.IsWarning() {
+				probe.Severity = agentpb.Probe_Warning
+			}
+			if r.check.Description != "" {
+				probe.Detail = fmt.Sprintf("%v: %v", r.check.Description, probe.Detail)
+			}
+		}
+		reporter.Add(probe)
+	}
+}
+
+func getFactory(checkType string) (CheckerFactory, bool) {
+	registry.RLock()
+	defer registry.RUnlock()
+	factory, ok := registry.factories[checkType]
+	return factory, ok
+}
+
+// decodeSpec decodes the specification of the check into spec
+func decodeSpec(check schema.Check, spec interface{}) error {
+	if len(check.Spec) == 0 {
+		return trace.BadParameter("missing spec")
+	}
+	if err := json.Unmarshal(check.Spec, spec); err != nil {
+		return trace.BadParameter("failed to parse spec: %v", err)
+	}
+	return nil
+}
+
+var registry = struct {
+	sync.RWMutex
+	factories map[string]CheckerFactory
+}{
+	factories: make(map[string]CheckerFactory),
+}
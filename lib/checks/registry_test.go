@@ -0,0 +1,134 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package checks
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/gravitational/gravity/lib/schema"
+
+	sigar "github.com/cloudfoundry/gosigar"
+	"github.com/gravitational/satellite/agent/health"
+	"github.com/gravitational/satellite/agent/proto/agentpb"
+	"github.com/gravitational/trace"
+	. "gopkg.in/check.v1"
+)
+
+type RegistrySuite struct{}
+
+var _ = Suite(&RegistrySuite{})
+
+func (s *RegistrySuite) TestValidatesChecks(c *C) {
+	err := ValidateChecks([]schema.Check{
+		{Type: CheckFilesystem, Spec: spec(c, FilesystemSpec{Path: "/var/lib/gravity", Types: []string{"xfs"}, DType: true})},
+		{Type: CheckDiskPerformance, Spec: spec(c, DiskPerformanceSpec{Path: "/var/lib/gravity", MaxFsyncLatency: "10ms"})},
+		{Type: CheckKernelModules, Spec: spec(c, KernelModulesSpec{})},
+	})
+	c.Assert(trace.IsBadParameter(err), Equals, true, Commentf("%v", err))
+
+	err = ValidateChecks([]schema.Check{{Type: "unknown"}})
+	c.Assert(trace.IsBadParameter(err), Equals, true, Commentf("%v", err))
+
+	err = ValidateChecks([]schema.Check{
+		{Type: CheckDiskPerformance, Spec: spec(c, DiskPerformanceSpec{Path: "/var/lib/gravity", MaxFsyncLatency: "fast"})},
+	})
+	c.Assert(trace.IsBadParameter(err), Equals, true, Commentf("%v", err))
+}
+
+func (s *RegistrySuite) TestSetsSeverity(c *C) {
+	RegisterChecker("test", func(check schema.Check, config CheckerConfig) (health.Checker, error) {
+		return &sysctlChecker{
+			spec: SysctlSpec{Params: []SysctlParam{{Name: "vm.max_map_count", Min: int64p(262144)}}},
+			sysctl: func(string) (string, error) {
+				return "65530", nil
+			},
+		}, nil
+	})
+	failed, err := RunChecks(context.TODO(), []schema.Check{
+		{Type: "test", Description: "elasticsearch requirement", Severity: schema.CheckSeverityWarning},
+		{Type: "test"},
+	}, CheckerConfig{})
+	c.Assert(err, IsNil)
+	c.Assert(failed, HasLen, 2)
+	c.Assert(failed[0].Severity, Equals, agentpb.Probe_Warning)
+	c.Assert(failed[0].Detail, Equals, "elasticsearch requirement: "+
+		"kernel parameter vm.max_map_count is 65530, expected at least 262144")
+	c.Assert(failed[1].Severity, Equals, agentpb.Probe_Critical)
+
+	failed, warnings := SplitWarnings(failed)
+	c.Assert(failed, HasLen, 1)
+	c.Assert(warnings, HasLen, 1)
+}
+
+func (s *RegistrySuite) TestTranslatesStateDir(c *C) {
+	config := CheckerConfig{StateDir: "/data/gravity"}
+	c.Assert(config.path("/var/lib/gravity/planet/etcd"), Equals, "/data/gravity/planet/etcd")
+	c.Assert(config.path("/var/lib/data"), Equals, "/var/lib/data")
+}
+
+func (s *RegistrySuite) TestChecksFilesystem(c *C) {
+	dir := c.MkDir()
+	mounts := func() ([]sigar.FileSystem, error) {
+		return []sigar.FileSystem{
+			{DirName: "/", SysTypeName: "ext4", Options: "rw,relatime"},
+			{DirName: dir, SysTypeName: "xfs", Options: "rw,noatime,prjquota"},
+		}, nil
+	}
+	var probes health.Probes
+	checker := &filesystemChecker{
+		spec:   FilesystemSpec{Path: dir + "/etcd", Types: []string{"ext4"}},
+		mounts: mounts,
+	}
+	checker.Check(context.TODO(), &probes)
+	c.Assert(probes.GetFailed(), HasLen, 1)
+
+	probes = nil
+	checker.spec.Types = []string{"xfs"}
+	checker.Check(context.TODO(), &probes)
+	c.Assert(probes.GetFailed(), HasLen, 0)
+
+	probes = nil
+	optionsChecker := &mountOptionsChecker{
+		spec:   MountOptionsSpec{Path: dir, Options: []string{"noatime", "pquota", "prjquota"}},
+		mounts: mounts,
+	}
+	optionsChecker.Check(context.TODO(), &probes)
+	c.Assert(probes.GetFailed(), HasLen, 1)
+	c.Assert(probes.GetFailed()[0].Detail, Matches, ".*missing mount options: pquota")
+}
+
+func (s *RegistrySuite) TestBenchmarksDisk(c *C) {
+	stats, err := benchmarkDisk(context.TODO(), c.MkDir(), 10)
+	c.Assert(err, IsNil)
+	c.Assert(stats.iops > 0, Equals, true)
+	c.Assert(stats.latency > 0, Equals, true)
+
+	c.Assert(percentile([]time.Duration{3, 1, 2, 4}, 0.99), Equals, time.Duration(4))
+	c.Assert(percentile([]time.Duration{3, 1, 2, 4}, 0.5), Equals, time.Duration(2))
+}
+
+func spec(c *C, spec interface{}) json.RawMessage {
+	data, err := json.Marshal(spec)
+	c.Assert(err, IsNil)
+	return data
+}
+
+func int64p(value int64) *int64 {
+	return &value
+}
@@ -105,6 +105,9 @@ const (
 	// ETCDBackend defines storage backend as Etcd
 	ETCDBackend = "etcd"
 
+	// SQLiteBackend defines storage backend as SQLite
+	SQLiteBackend = "sqlite"
+
 	// WebAssetsPackage names the web assets package
 	WebAssetsPackage = "web-assets"
 
@@ -546,12 +549,55 @@ const (
 	// MonitoringTypeAlertTarget specifies the value of the component label for monitoring alert targets
 	MonitoringTypeAlertTarget = "alert-target"
 
+	// AlertReceiverSecretPrefix is the name prefix of Secrets with named
+	// alert targets that receive alerts from the cluster alert dispatcher
+	AlertReceiverSecretPrefix = "alert-receiver-"
+
+	// AlertWatcherConfigMap is the name of the ConfigMap with the levels
+	// of the alert events dispatched to the alert targets
+	AlertWatcherConfigMap = "alert-watcher"
+
+	// MonitoringTypeAlertReceiver specifies the value of the component label for named alert targets
+	MonitoringTypeAlertReceiver = "alert-receiver"
+
 	// MonitoringTypeAlert specifies the value of the component label for monitoring alerts
 	MonitoringTypeAlert = "alert"
 
 	// MonitoringTypeSMTP specifies the value of the component label for monitoring SMTP updates
 	MonitoringTypeSMTP = "smtp"
 
+	// RemoteWriteSecretPrefix is the name prefix of Secrets with metrics remote-write targets
+	RemoteWriteSecretPrefix = "remote-write-"
+
+	// MonitoringTypeRemoteWrite specifies the value of the component label for metrics remote-write targets
+	MonitoringTypeRemoteWrite = "remote-write"
+
+	// RecordingRuleConfigMapPrefix is the name prefix of ConfigMaps with metrics recording rules
+	RecordingRuleConfigMapPrefix = "recording-rule-"
+
+	// MonitoringTypeRecordingRule specifies the value of the component label for metrics recording rules
+	MonitoringTypeRecordingRule = "recording-rule"
+
+	// PrometheusConfigMap specifies the name of the ConfigMap with the Prometheus configuration
+	// generated from the retention policy, remote-write targets and recording rules
+	PrometheusConfigMap = "prometheus-gravity"
+
+	// PrometheusConfigSecret specifies the name of the Secret with the Prometheus configuration
+	// generated from the remote-write targets
+	PrometheusConfigSecret = "prometheus-gravity"
+
+	// MonitoringTypePrometheus specifies the value of the component label for the generated Prometheus configuration
+	MonitoringTypePrometheus = "prometheus"
+
+	// PrometheusRetentionKey is the key of the retention duration in the Prometheus configuration ConfigMap
+	PrometheusRetentionKey = "retention"
+
+	// PrometheusRemoteWriteKey is the key of the remote-write configuration in the Prometheus configuration Secret
+	PrometheusRemoteWriteKey = "remote_write.yml"
+
+	// PrometheusRulesKey is the key of the recording rules file in the Prometheus configuration ConfigMap
+	PrometheusRulesKey = "rules.yml"
+
 	// ResourceSpecKey specifies the name of the key with raw resource specification
 	ResourceSpecKey = "spec"
 
@@ -687,6 +733,9 @@ const (
 	//
 	// Used in audit events.
 	ServiceAutoscaler = "@autoscaler"
+	// AutoscaleMembersConfigMap is the name of the ConfigMap with the nodes
+	// observed as members of the autoscaling group
+	AutoscaleMembersConfigMap = "autoscale-members"
 	// ServiceStatusChecker is the name of the service that periodically
 	// checks cluster health status and activates/deactivates it.
 	//
@@ -135,6 +135,9 @@ const (
 	// MaxExpandConcurrency is the number of servers that can be joining the cluster concurrently
 	MaxExpandConcurrency = 5
 
+	// MaxReportConcurrency is the number of servers to collect diagnostics from concurrently
+	MaxReportConcurrency = 10
+
 	// DownloadRetryPeriod is the period between failed retry attempts
 	DownloadRetryPeriod = 5 * time.Second
 
@@ -198,6 +201,14 @@ const (
 	// request during the preflight test
 	AgentValidationTimeout = 1 * time.Minute
 
+	// DiskBenchmarkWrites is the default number of synchronous writes
+	// performed by the disk performance preflight check
+	DiskBenchmarkWrites = 200
+
+	// DiskBenchmarkWriteSize is the size of a single write performed by
+	// the disk performance preflight check, close to a typical etcd WAL entry
+	DiskBenchmarkWriteSize = 2300
+
 	// AgentHealthCheckTimeout specifies the maximum amount of time for a health check
 	AgentHealthCheckTimeout = 5 * time.Second
 
@@ -231,6 +242,9 @@ const (
 	// EnvironmentPath is the path to the environment file
 	EnvironmentPath = "/etc/environment"
 
+	// CatalogConfigPath is the path to the federated application catalog configuration
+	CatalogConfigPath = "/etc/gravity/catalog.yaml"
+
 	// ProfilingInterval defines the frequency of taking state snapshots (debugging)
 	ProfilingInterval = 1 * time.Minute
 
@@ -377,6 +391,9 @@ const (
 	// TempDir is the place for temp files and folders
 	TempDir = "tmp"
 
+	// DownloadsDir is the place for partially downloaded packages
+	DownloadsDir = "downloads"
+
 	// ResourcesDir is the name of the directory where apps store their resources such as app manifest
 	ResourcesDir = "resources"
 
@@ -425,6 +442,9 @@ const (
 	// GravityDBFile is a default file name for gravity sqlite DB file
 	GravityDBFile = "gravity.db"
 
+	// GravitySQLiteFile is a default file name for gravity SQLite database file
+	GravitySQLiteFile = "gravity.sqlite"
+
 	// SystemAccountID is the ID of the system account
 	SystemAccountID = "00000000-0000-0000-0000-000000000001"
 	// SystemAccountOrg is the default name of Gravitational organization
@@ -512,6 +532,8 @@ const (
 
 	// LogForwardersConfigMap is the name of the config map that contains log forwarders configuration
 	LogForwardersConfigMap = "log-forwarders"
+	// LogForwardersSecret is the name of the secret that contains log forwarders credentials
+	LogForwardersSecret = "log-forwarders"
 
 	// GrafanaServiceName is the name of Grafana service
 	GrafanaServiceName = "grafana"
@@ -526,6 +548,18 @@ const (
 	InfluxDBAdminUser = "root"
 	// InfluxDBAdminPassword is the InfluxDB admin user password
 	InfluxDBAdminPassword = "root"
+	// InfluxDBDatabase is the InfluxDB database with cluster metrics
+	InfluxDBDatabase = "k8s"
+	// InfluxDBRetentionPolicy is the InfluxDB retention policy with high-resolution metrics
+	InfluxDBRetentionPolicy = "default"
+
+	// PrometheusServiceName is the name of the Prometheus service in the monitoring namespace.
+	// If the service exists, metrics retention and forwarding are managed for Prometheus
+	PrometheusServiceName = "prometheus"
+	// MonitoringBackendCacheTTL is how long the detected metrics backend is cached for
+	MonitoringBackendCacheTTL = time.Minute
+	// PrometheusRetention is the default retention of Prometheus metrics
+	PrometheusRetention = 15 * 24 * time.Hour
 
 	// WriteFactor is a default amount of acknowledged writes for object storage
 	// to be considered successfull
@@ -560,6 +594,25 @@ const (
 	// SiteStatusCheckInterval is how often local gravity site will invoke app status hook
 	SiteStatusCheckInterval = 1 * time.Minute
 
+	// StatusSampleInterval is how often local gravity site samples the cluster
+	// health to record it in the status history
+	StatusSampleInterval = 1 * time.Minute
+
+	// StatusHistoryCapacity is the maximum number of health transitions kept
+	// in the cluster status history. Oldest transitions are evicted first
+	StatusHistoryCapacity = 10000
+
+	// StatusHistoryPeriod is the default period to display the cluster status history for
+	StatusHistoryPeriod = 24 * time.Hour
+
+	// PruneKeepOperations is the default number of most recent operations
+	// exempt from pruning of stale operation data
+	PruneKeepOperations = 10
+
+	// PruneKeepPeriod is the default period of time recent operations
+	// are exempt from pruning of stale operation data
+	PruneKeepPeriod = 30 * 24 * time.Hour
+
 	// OfflineCheckInterval is how often OpsCenter checks whether its sites are online/offline
 	OfflineCheckInterval = 10 * time.Second
 
@@ -620,6 +673,9 @@ const (
 	// ReportTarball is the name of the gzipped tarball with collected site report information
 	ReportTarball = "report.tar.gz"
 
+	// ClusterReportIndex is the name of the file with the summary of the cluster-wide report collection
+	ClusterReportIndex = "index.json"
+
 	// ServiceSubnet is a subnet dedicated to the services in cluster
 	ServiceSubnet = "10.100.0.0/16"
 	// PodSubnet is a subnet dedicated to the pods in the cluster
@@ -757,6 +813,19 @@ const (
 	// DecoderBufferSize is the size of the buffer used when decoding YAML resources
 	DecoderBufferSize = 1024 * 1024
 
+	// AlertTargetTimeout is the timeout to deliver an alert to an alert target
+	AlertTargetTimeout = 10 * time.Second
+
+	// KapacitorURL is the URL of the Kapacitor API of the cluster monitoring system
+	KapacitorURL = "http://kapacitor.monitoring.svc.cluster.local:9092"
+
+	// AlertWatchInterval is how often the alert events are queried from Kapacitor
+	AlertWatchInterval = 15 * time.Second
+
+	// MaxChartArchiveSize is the maximum size of a Helm chart archive
+	// accepted by the cluster chart repository
+	MaxChartArchiveSize = 10 * 1024 * 1024
+
 	// DiskCapacity is the minimum required free disk space for some default directories
 	DiskCapacity = "5GB"
 	// DiskTransferRate is the minimum required disk speed for some default locations
@@ -1076,6 +1145,9 @@ var (
 	DiscoveryPublishInterval = 5 * time.Second
 	// DiscoveryResyncInterval specifies the frequency to force publish cluster discovery details
 	DiscoveryResyncInterval = 10 * time.Minute
+	// AutoscaleGroupPollInterval specifies the frequency to poll autoscaling groups
+	// of cloud providers that do not deliver instance lifecycle notifications
+	AutoscaleGroupPollInterval = 30 * time.Second
 
 	// CACertificateExpiry is the validity period of self-signed CA generated
 	// for clusters during installation
@@ -1094,6 +1166,10 @@ var (
 	// an operation experiencing transient errors
 	TransientErrorTimeout = 15 * time.Minute
 
+	// DeltaBaseVersions is the maximum number of locally available versions
+	// of a package advertised as bases for delta package transfer
+	DeltaBaseVersions = 3
+
 	// NodeStatusTimeout specifies the maximum amount of time to wait for
 	// healthy node status
 	NodeStatusTimeout = 5 * time.Minute
@@ -29,10 +29,8 @@ import (
 	"github.com/gravitational/gravity/lib/pack"
 	"github.com/gravitational/gravity/lib/rpc"
 	"github.com/gravitational/gravity/lib/storage"
-	"github.com/gravitational/gravity/lib/utils"
 
 	"github.com/gravitational/trace"
-	"github.com/pborman/uuid"
 	"github.com/sirupsen/logrus"
 	"google.golang.org/grpc/credentials"
 )
@@ -65,6 +63,9 @@ type FSMConfig struct {
 	DebugMode bool
 	// Insecure turns on FSM insecure mode
 	Insecure bool
+	// ParallelPerServer optionally enables concurrent plan execution
+	// with the specified number of phases executing per server
+	ParallelPerServer int
 }
 
 // CheckAndSetDefaults validates expand FSM configuration and sets defaults
@@ -118,9 +119,10 @@ func NewFSM(config FSMConfig) (*fsm.FSM, error) {
 		FieldLogger: logger,
 	}
 	fsm, err := fsm.New(fsm.Config{
-		Engine: engine,
-		Runner: config.Runner,
-		Logger: logger,
+		Engine:            engine,
+		Runner:            config.Runner,
+		Logger:            logger,
+		ParallelPerServer: config.ParallelPerServer,
 	})
 	if err != nil {
 		return nil, trace.Wrap(err)
@@ -154,15 +156,7 @@ func (e *fsmEngine) GetExecutor(p fsm.ExecutorParams, remote fsm.Remote) (fsm.Ph
 
 // ChangePhaseState updates the phase state based on the provided parameters
 func (e *fsmEngine) ChangePhaseState(ctx context.Context, change fsm.StateChange) error {
-	planChange := storage.PlanChange{
-		ID:          uuid.New(),
-		ClusterName: e.OperationKey.SiteDomain,
-		OperationID: e.OperationKey.OperationID,
-		PhaseID:     change.Phase,
-		NewState:    change.State,
-		Error:       utils.ToRawTrace(change.Error),
-		Created:     time.Now().UTC(),
-	}
+	planChange := fsm.NewPlanChange(e.OperationKey.SiteDomain, e.OperationKey.OperationID, change)
 	_, err := e.JoinBackend.CreateOperationPlanChange(planChange)
 	if err != nil {
 		return trace.Wrap(err)
@@ -18,9 +18,11 @@ package phases
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/gravitational/gravity/lib/clients"
 	"github.com/gravitational/gravity/lib/constants"
+	"github.com/gravitational/gravity/lib/defaults"
 	"github.com/gravitational/gravity/lib/fsm"
 	"github.com/gravitational/gravity/lib/httplib"
 	"github.com/gravitational/gravity/lib/loc"
@@ -106,6 +108,15 @@ func (p *agentStartExecutor) getProxyClient(ctx context.Context) (*client.ProxyC
 	return proxyClient, nil
 }
 
+// Describe returns the description of changes this phase makes
+func (p *agentStartExecutor) Describe(ctx context.Context) (*fsm.PhaseDescription, error) {
+	return &fsm.PhaseDescription{
+		Servers: []string{p.Master.Hostname},
+		Units:   []string{defaults.GravityRPCAgentServiceName},
+		Actions: []string{fmt.Sprintf("Deploy RPC agent on master node %v", p.Master.AdvertiseIP)},
+	}, nil
+}
+
 // Rollback is no-op for this phase
 func (*agentStartExecutor) Rollback(ctx context.Context) error {
 	return nil
@@ -166,6 +177,15 @@ func (p *agentStopExecutor) Execute(ctx context.Context) error {
 	return nil
 }
 
+// Describe returns the description of changes this phase makes
+func (p *agentStopExecutor) Describe(ctx context.Context) (*fsm.PhaseDescription, error) {
+	return &fsm.PhaseDescription{
+		Servers: []string{p.Master.Hostname},
+		Units:   []string{defaults.GravityRPCAgentServiceName},
+		Actions: []string{fmt.Sprintf("Stop RPC agent on master node %v", p.Master.AdvertiseIP)},
+	}, nil
+}
+
 // Rollback is no-op for this phase
 func (*agentStopExecutor) Rollback(ctx context.Context) error {
 	return nil
@@ -105,6 +105,15 @@ func (p *etcdExecutor) Execute(ctx context.Context) error {
 	return nil
 }
 
+// Describe returns the description of changes this phase makes
+func (p *etcdExecutor) Describe(ctx context.Context) (*fsm.PhaseDescription, error) {
+	return &fsm.PhaseDescription{
+		Servers: []string{p.Phase.Data.Server.Hostname, p.Master.Hostname},
+		Actions: []string{fmt.Sprintf("Add etcd member https://%v:%v",
+			p.Phase.Data.Server.AdvertiseIP, defaults.EtcdPeerPort)},
+	}, nil
+}
+
 // Rollback removes the joined node from the cluster's etcd cluster
 func (p *etcdExecutor) Rollback(ctx context.Context) error {
 	p.Progress.NextStep("Restoring etcd data")
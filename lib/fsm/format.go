@@ -20,12 +20,15 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"sort"
 	"strings"
 	"text/tabwriter"
 	"time"
 
 	"github.com/gravitational/gravity/lib/constants"
+	"github.com/gravitational/gravity/lib/loc"
 	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/utils"
 	"github.com/gravitational/gravity/tool/common"
 
 	"github.com/gravitational/trace"
@@ -59,7 +62,7 @@ func FormatOperationPlanJSON(w io.Writer, plan storage.OperationPlan) error {
 func FormatOperationPlanText(w io.Writer, plan storage.OperationPlan) {
 	var t tabwriter.Writer
 	t.Init(w, 0, 10, 5, ' ', 0)
-	common.PrintTableHeader(&t, []string{"Phase", "Description", "State", "Node", "Requires", "Updated"})
+	common.PrintTableHeader(&t, []string{"Phase", "Description", "State", "Node", "Requires", "Updated", "Duration"})
 	for _, phase := range plan.Phases {
 		printPhase(&t, phase, 0)
 	}
@@ -75,7 +78,7 @@ func printPhase(w io.Writer, phase storage.OperationPhase, indent int) {
 	} else if phase.GetState() == storage.OperationPhaseStateFailed || phase.GetState() == storage.OperationPhaseStateRolledBack {
 		marker = constants.FailureMark
 	}
-	fmt.Fprintf(w, "%v%v %v\t%v\t%v\t%v\t%v\t%v\n",
+	fmt.Fprintf(w, "%v%v %v\t%v\t%v\t%v\t%v\t%v\t%v\n",
 		strings.Repeat("  ", indent),
 		marker,
 		formatName(phase.ID),
@@ -83,7 +86,8 @@ func printPhase(w io.Writer, phase storage.OperationPhase, indent int) {
 		formatState(phase.GetState()),
 		formatNode(phase),
 		formatRequires(phase.Requires),
-		formatTimestamp(phase.GetLastUpdateTime()))
+		formatTimestamp(phase.GetLastUpdateTime()),
+		formatDuration(phase.GetDuration()))
 	for _, subPhase := range phase.Phases {
 		printPhase(w, subPhase, indent+1)
 	}
@@ -115,6 +119,13 @@ func formatTimestamp(t time.Time) string {
 	return t.Format(constants.HumanDateFormat)
 }
 
+func formatDuration(d time.Duration) string {
+	if d == 0 {
+		return "-"
+	}
+	return d.Round(time.Second).String()
+}
+
 func formatState(state string) string {
 	switch state {
 	case storage.OperationPhaseStateUnstarted:
@@ -131,3 +142,122 @@ func formatState(state string) string {
 		return "Unknown"
 	}
 }
+
+// FormatOperationPlanTimeline outputs all execution and rollback attempts
+// of the plan phases in chronological order
+func FormatOperationPlanTimeline(w io.Writer, plan storage.OperationPlan) {
+	var entries []timelineEntry
+	for _, phase := range FlattenPlan(&plan) {
+		for _, attempt := range phase.History {
+			entries = append(entries, timelineEntry{phaseID: phase.ID, PhaseAttempt: attempt})
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].time().Before(entries[j].time())
+	})
+	var start time.Time
+	if len(entries) != 0 {
+		start = entries[0].time()
+	}
+	var t tabwriter.Writer
+	t.Init(w, 0, 10, 5, ' ', 0)
+	common.PrintTableHeader(&t, []string{"Phase", "Action", "State", "Server", "User", "Started", "Offset", "Duration", "Error"})
+	for _, entry := range entries {
+		fmt.Fprintf(&t, "%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\n",
+			entry.phaseID,
+			entry.Action,
+			formatState(entry.State),
+			formatValue(entry.Server),
+			formatValue(entry.User),
+			formatTimestamp(entry.Started),
+			"+"+entry.time().Sub(start).Round(time.Second).String(),
+			formatDuration(entry.Duration()),
+			formatError(entry.Error))
+	}
+	t.Flush()
+}
+
+// timelineEntry is a single phase attempt on the operation timeline
+type timelineEntry struct {
+	storage.PhaseAttempt
+	phaseID string
+}
+
+// time returns the time the entry is placed at on the timeline
+func (r timelineEntry) time() time.Time {
+	if r.Started.IsZero() {
+		return r.Finished
+	}
+	return r.Started
+}
+
+func formatValue(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
+
+func formatError(rawErr *trace.RawTrace) string {
+	if rawErr == nil {
+		return "-"
+	}
+	var err trace.TraceErr
+	if errUnmarshal := utils.UnmarshalError(rawErr.Err, &err); errUnmarshal != nil || err.Err == nil {
+		return "-"
+	}
+	return err.Err.Error()
+}
+
+// FormatSimulationReportText outputs the specified plan simulation report
+// in a tabular format
+func FormatSimulationReportText(w io.Writer, report SimulationReport) {
+	var t tabwriter.Writer
+	t.Init(w, 0, 10, 5, ' ', 0)
+	common.PrintTableHeader(&t, []string{"Phase", "Node", "Precheck", "Servers", "Packages", "Units", "Actions"})
+	for _, phase := range report.Phases {
+		fmt.Fprintf(&t, "%v\t%v\t%v\t%v\t%v\t%v\t%v\n",
+			phase.PhaseID,
+			formatSimulationNode(phase),
+			phase.PreCheck,
+			formatList(phase.Servers),
+			formatPackages(phase.Packages),
+			formatList(phase.Units),
+			formatList(phase.Actions))
+	}
+	t.Flush()
+}
+
+// FormatSimulationReportJSON outputs the specified plan simulation report as JSON
+func FormatSimulationReportJSON(w io.Writer, report SimulationReport) error {
+	bytes, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if _, err := w.Write(bytes); err != nil {
+		return trace.Wrap(err)
+	}
+	return nil
+}
+
+func formatSimulationNode(phase PhaseSimulation) string {
+	if phase.Node == "" {
+		return "-"
+	}
+	return phase.Node
+}
+
+func formatPackages(packages []loc.Locator) string {
+	var items []string
+	for _, pkg := range packages {
+		items = append(items, pkg.String())
+	}
+	return formatList(items)
+}
+
+func formatList(items []string) string {
+	if len(items) == 0 {
+		return "-"
+	}
+	return strings.Join(items, ",")
+}
@@ -114,6 +114,12 @@ type Config struct {
 	Insecure bool
 	// Logger allows to override default logger
 	Logger logrus.FieldLogger
+	// ParallelPerServer enables concurrent plan execution.
+	// If set, ExecutePlan executes all phases whose requirements have
+	// completed at the same time, with at most the specified number
+	// of phases executing on any single server.
+	// If unspecified, the plan phases are executed in order
+	ParallelPerServer int
 }
 
 // CheckAndSetDefaults makes sure the config is valid and sets some defaults
@@ -124,6 +130,9 @@ func (c *Config) CheckAndSetDefaults() error {
 	if c.Logger == nil {
 		c.Logger = logrus.WithField(trace.Component, "fsm")
 	}
+	if c.ParallelPerServer < 0 {
+		return trace.BadParameter("ParallelPerServer cannot be negative")
+	}
 	return nil
 }
 
@@ -139,8 +148,13 @@ func New(config Config) (*FSM, error) {
 	}, nil
 }
 
-// ExecutePlan iterates over all phases of the plan and executes them in order
+// ExecutePlan iterates over all phases of the plan and executes them in order.
+// If the FSM has been configured for concurrent execution, phases are
+// scheduled based on their requirements instead
 func (f *FSM) ExecutePlan(ctx context.Context, progress utils.Progress, force bool) error {
+	if f.ParallelPerServer > 0 {
+		return trace.Wrap(f.executePlanConcurrently(ctx, progress, force))
+	}
 	plan, err := f.GetPlan()
 	if err != nil {
 		return trace.Wrap(err)
@@ -320,8 +334,10 @@ func (f *FSM) executePhase(ctx context.Context, p Params, phase storage.Operatio
 			// if the remote upgrade phase is successfull, we need to mark it in our local database
 			// because etcd might not be available to synchronize the changes back to us
 			err = f.ChangePhaseState(ctx, StateChange{
-				Phase: phase.ID,
-				State: storage.OperationPhaseStateCompleted,
+				Phase:  phase.ID,
+				State:  storage.OperationPhaseStateCompleted,
+				Action: storage.PhaseActionExecute,
+				Server: execServer.Hostname,
 			})
 		}
 
@@ -409,8 +425,9 @@ func (f *FSM) executeOnePhase(ctx context.Context, p Params, phase storage.Opera
 
 	err = f.ChangePhaseState(ctx,
 		StateChange{
-			Phase: phase.ID,
-			State: storage.OperationPhaseStateInProgress,
+			Phase:  phase.ID,
+			State:  storage.OperationPhaseStateInProgress,
+			Action: storage.PhaseActionExecute,
 		})
 	if err != nil {
 		return trace.Wrap(err)
@@ -423,9 +440,10 @@ func (f *FSM) executeOnePhase(ctx context.Context, p Params, phase storage.Opera
 		executor.Errorf("Phase execution failed: %v.", err)
 		if err := f.ChangePhaseState(ctx,
 			StateChange{
-				Phase: phase.ID,
-				State: storage.OperationPhaseStateFailed,
-				Error: trace.Wrap(err),
+				Phase:  phase.ID,
+				State:  storage.OperationPhaseStateFailed,
+				Error:  trace.Wrap(err),
+				Action: storage.PhaseActionExecute,
 			}); err != nil {
 			return trace.Wrap(err)
 		}
@@ -440,8 +458,9 @@ func (f *FSM) executeOnePhase(ctx context.Context, p Params, phase storage.Opera
 
 	err = f.ChangePhaseState(ctx,
 		StateChange{
-			Phase: phase.ID,
-			State: storage.OperationPhaseStateCompleted,
+			Phase:  phase.ID,
+			State:  storage.OperationPhaseStateCompleted,
+			Action: storage.PhaseActionExecute,
 		})
 	if err != nil {
 		return trace.Wrap(err)
@@ -466,8 +485,9 @@ func (f *FSM) rollbackPhase(ctx context.Context, p Params, phase storage.Operati
 
 	err = f.ChangePhaseState(ctx,
 		StateChange{
-			Phase: phase.ID,
-			State: storage.OperationPhaseStateInProgress,
+			Phase:  phase.ID,
+			State:  storage.OperationPhaseStateInProgress,
+			Action: storage.PhaseActionRollback,
 		})
 	if err != nil {
 		return trace.Wrap(err)
@@ -478,9 +498,10 @@ func (f *FSM) rollbackPhase(ctx context.Context, p Params, phase storage.Operati
 		executor.Errorf("Phase %v rollback failed: %v.", phase.ID, err)
 		if err := f.ChangePhaseState(ctx,
 			StateChange{
-				Phase: phase.ID,
-				State: storage.OperationPhaseStateFailed,
-				Error: trace.Wrap(err),
+				Phase:  phase.ID,
+				State:  storage.OperationPhaseStateFailed,
+				Error:  trace.Wrap(err),
+				Action: storage.PhaseActionRollback,
 			}); err != nil {
 			return trace.Wrap(err)
 		}
@@ -489,8 +510,9 @@ func (f *FSM) rollbackPhase(ctx context.Context, p Params, phase storage.Operati
 
 	err = f.ChangePhaseState(ctx,
 		StateChange{
-			Phase: phase.ID,
-			State: storage.OperationPhaseStateRolledBack,
+			Phase:  phase.ID,
+			State:  storage.OperationPhaseStateRolledBack,
+			Action: storage.PhaseActionRollback,
 		})
 	if err != nil {
 		return trace.Wrap(err)
@@ -533,6 +555,11 @@ type StateChange struct {
 	State string
 	// Error is the error that happened during phase execution
 	Error trace.Error
+	// Action is the action that caused the change, either execute or rollback
+	Action string
+	// Server is the optional name of the server the phase has been executed on.
+	// Defaults to this server
+	Server string
 }
 
 // String returns a textual representation of this state change
@@ -0,0 +1,312 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fsm
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/utils"
+
+	"github.com/gravitational/trace"
+)
+
+// CheckPlan validates dependencies between phases of the provided plan.
+//
+// It returns an error if the plan has duplicate phase IDs, if phase requirements
+// form a cycle or if some phases can never be executed because they
+// depend on a phase that is part of a cycle
+func CheckPlan(plan storage.OperationPlan) error {
+	graph, err := newPlanGraph(&plan)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return trace.Wrap(graph.check())
+}
+
+// executePlanConcurrently executes the plan by scheduling every executable
+// phase as soon as all phases it requires have completed.
+// At most f.ParallelPerServer phases are executed on any server at any given time
+func (f *FSM) executePlanConcurrently(ctx context.Context, progress utils.Progress, force bool) error {
+	plan, err := f.GetPlan()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	graph, err := newPlanGraph(plan)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if err := graph.check(); err != nil {
+		return trace.Wrap(err)
+	}
+
+	completed := make(map[string]bool)
+	var pending []*storage.OperationPhase
+	for _, phase := range graph.phases {
+		if phase.IsCompleted() && !force {
+			completed[phase.ID] = true
+			continue
+		}
+		pending = append(pending, phase)
+	}
+
+	resultsCh := make(chan phaseResult, len(pending))
+	running := make(map[string]int)
+	var inflight int
+	var errors []error
+	for {
+		if len(errors) == 0 && ctx.Err() == nil {
+			var waiting []*storage.OperationPhase
+			for _, phase := range pending {
+				server := phaseServerAddr(*phase)
+				if !graph.isReady(phase.ID, completed) || running[server] >= f.ParallelPerServer {
+					waiting = append(waiting, phase)
+					continue
+				}
+				running[server]++
+				inflight++
+				f.Debugf("Scheduling phase %q.", phase.ID)
+				go func(phaseID, server string) {
+					err := f.ExecutePhase(ctx, Params{
+						PhaseID:  phaseID,
+						Progress: progress,
+						Force:    force,
+					})
+					resultsCh <- phaseResult{phaseID: phaseID, server: server, err: err}
+				}(phase.ID, server)
+			}
+			pending = waiting
+		}
+		if inflight == 0 {
+			break
+		}
+		result := <-resultsCh
+		inflight--
+		running[result.server]--
+		if result.err != nil {
+			f.Warnf("Failed to execute phase %q: %v.", result.phaseID, trace.DebugReport(result.err))
+			errors = append(errors, trace.Wrap(result.err, "failed to execute phase %q", result.phaseID))
+			continue
+		}
+		completed[result.phaseID] = true
+	}
+	if len(errors) != 0 {
+		return trace.NewAggregate(errors...)
+	}
+	if err := ctx.Err(); err != nil {
+		return trace.Wrap(err)
+	}
+	if len(pending) != 0 {
+		return trace.BadParameter("failed to schedule phases %v", phaseIDs(pending))
+	}
+	return nil
+}
+
+// newPlanGraph builds the dependency graph for the specified plan.
+//
+// Only phases without sub-phases are part of the graph. A phase inherits
+// the requirements of all its parents and a requirement on a composite phase
+// is a requirement on all its sub-phases.
+// A phase that does not explicitly list any requirements and is part of a
+// sequential (non-parallel) parent implicitly requires the preceding sibling
+// phase in order to retain the semantics of sequential plan execution.
+// The top-level phases are considered to be sequential
+func newPlanGraph(plan *storage.OperationPlan) (*planGraph, error) {
+	graph := &planGraph{
+		requires: make(map[string][]string),
+	}
+	index := make(map[string]*storage.OperationPhase)
+	for _, phase := range FlattenPlan(plan) {
+		if _, ok := index[phase.ID]; ok {
+			return nil, trace.BadParameter("duplicate phase %q", phase.ID)
+		}
+		index[phase.ID] = phase
+	}
+	direct := make(map[string][]string)
+	graph.addPhases(plan.Phases, false, nil, direct)
+	for _, phase := range graph.phases {
+		set := utils.NewStringSet()
+		for _, id := range direct[phase.ID] {
+			required, ok := index[id]
+			if !ok {
+				// Requirements on phases missing from the plan are ignored
+				// in the same way as by the sequential execution
+				continue
+			}
+			for _, leaf := range leafPhases(required) {
+				set.Add(leaf.ID)
+			}
+		}
+		graph.requires[phase.ID] = set.Slice()
+	}
+	return graph, nil
+}
+
+func (r *planGraph) addPhases(phases []storage.OperationPhase, parallel bool, inherited []string, direct map[string][]string) {
+	for i := range phases {
+		phase := &phases[i]
+		requires := append(append([]string(nil), inherited...), phase.Requires...)
+		if !parallel && len(phase.Requires) == 0 && i > 0 {
+			requires = append(requires, phases[i-1].ID)
+		}
+		if phase.HasSubphases() {
+			r.addPhases(phase.Phases, phase.Parallel, requires, direct)
+			continue
+		}
+		r.phases = append(r.phases, phase)
+		direct[phase.ID] = requires
+	}
+}
+
+// check makes sure that every phase in the graph can eventually be executed
+func (r *planGraph) check() error {
+	completed := make(map[string]bool)
+	for {
+		var progressed bool
+		for _, phase := range r.phases {
+			if !completed[phase.ID] && r.isReady(phase.ID, completed) {
+				completed[phase.ID] = true
+				progressed = true
+			}
+		}
+		if !progressed {
+			break
+		}
+	}
+	if len(completed) == len(r.phases) {
+		return nil
+	}
+	cycle := r.findCycle(completed)
+	var unreachable []string
+	for _, phase := range r.phases {
+		if !completed[phase.ID] && !utils.StringInSlice(cycle, phase.ID) {
+			unreachable = append(unreachable, phase.ID)
+		}
+	}
+	if len(unreachable) != 0 {
+		return trace.BadParameter("dependency cycle %v, phases %v can never be executed",
+			strings.Join(cycle, " -> "), unreachable)
+	}
+	return trace.BadParameter("dependency cycle %v", strings.Join(cycle, " -> "))
+}
+
+// findCycle returns a dependency cycle among the phases not marked as completed.
+// The first and the last element of the returned path are the same phase
+func (r *planGraph) findCycle(completed map[string]bool) []string {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make(map[string]int)
+	var path []string
+	var visit func(id string) []string
+	visit = func(id string) []string {
+		state[id] = visiting
+		path = append(path, id)
+		for _, required := range r.requires[id] {
+			if completed[required] {
+				continue
+			}
+			switch state[required] {
+			case visiting:
+				for i := range path {
+					if path[i] == required {
+						return append(append([]string(nil), path[i:]...), required)
+					}
+				}
+			case unvisited:
+				if cycle := visit(required); cycle != nil {
+					return cycle
+				}
+			}
+		}
+		path = path[:len(path)-1]
+		state[id] = visited
+		return nil
+	}
+	for _, phase := range r.phases {
+		if completed[phase.ID] || state[phase.ID] != unvisited {
+			continue
+		}
+		if cycle := visit(phase.ID); cycle != nil {
+			return cycle
+		}
+	}
+	return nil
+}
+
+// isReady returns true if all phases required by the specified phase have completed
+func (r *planGraph) isReady(phaseID string, completed map[string]bool) bool {
+	for _, required := range r.requires[phaseID] {
+		if !completed[required] {
+			return false
+		}
+	}
+	return true
+}
+
+// planGraph describes dependencies between executable phases of an operation plan
+type planGraph struct {
+	// phases lists all phases without sub-phases in plan order
+	phases []*storage.OperationPhase
+	// requires maps a phase ID to the IDs of phases that
+	// need to complete before the phase can be executed
+	requires map[string][]string
+}
+
+type phaseResult struct {
+	phaseID string
+	server  string
+	err     error
+}
+
+// leafPhases returns all phases without sub-phases starting at the specified phase
+func leafPhases(phase *storage.OperationPhase) (result []*storage.OperationPhase) {
+	if !phase.HasSubphases() {
+		return []*storage.OperationPhase{phase}
+	}
+	for i := range phase.Phases {
+		result = append(result, leafPhases(&phase.Phases[i])...)
+	}
+	return result
+}
+
+// phaseServerAddr returns the address of the server the specified phase
+// is executed on or an empty string if the phase is executed locally
+func phaseServerAddr(phase storage.OperationPhase) string {
+	if phase.Data == nil {
+		return ""
+	}
+	if phase.Data.ExecServer != nil {
+		return phase.Data.ExecServer.AdvertiseIP
+	}
+	if phase.Data.Server != nil {
+		return phase.Data.Server.AdvertiseIP
+	}
+	return ""
+}
+
+func phaseIDs(phases []*storage.OperationPhase) (ids []string) {
+	for _, phase := range phases {
+		ids = append(ids, phase.ID)
+	}
+	sort.Strings(ids)
+	return ids
+}
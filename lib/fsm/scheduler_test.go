@@ -0,0 +1,245 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fsm
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/utils"
+
+	"github.com/gravitational/trace"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/check.v1"
+)
+
+func TestFSM(t *testing.T) { check.TestingT(t) }
+
+type SchedulerSuite struct{}
+
+var _ = check.Suite(&SchedulerSuite{})
+
+func (s *SchedulerSuite) TestRequirementsAreInherited(c *check.C) {
+	plan := storage.OperationPlan{
+		Phases: []storage.OperationPhase{
+			{ID: "/init"},
+			{
+				ID:       "/masters",
+				Requires: []string{"/init"},
+				Phases: []storage.OperationPhase{
+					{ID: "/masters/node-1"},
+					{ID: "/masters/node-2"},
+				},
+			},
+			{
+				ID:       "/nodes",
+				Requires: []string{"/masters"},
+				Parallel: true,
+				Phases: []storage.OperationPhase{
+					{ID: "/nodes/node-3"},
+					{ID: "/nodes/node-4"},
+				},
+			},
+		},
+	}
+	graph, err := newPlanGraph(&plan)
+	c.Assert(err, check.IsNil)
+	c.Assert(graph.check(), check.IsNil)
+	c.Assert(graph.requires, check.DeepEquals, map[string][]string{
+		"/init":           {},
+		"/masters/node-1": {"/init"},
+		"/masters/node-2": {"/init", "/masters/node-1"},
+		"/nodes/node-3":   {"/masters/node-1", "/masters/node-2"},
+		"/nodes/node-4":   {"/masters/node-1", "/masters/node-2"},
+	})
+}
+
+func (s *SchedulerSuite) TestDetectsCycles(c *check.C) {
+	plan := storage.OperationPlan{
+		Phases: []storage.OperationPhase{
+			{ID: "/init"},
+			{ID: "/a", Requires: []string{"/b"}},
+			{ID: "/b", Requires: []string{"/a"}},
+			{ID: "/c", Requires: []string{"/b"}},
+		},
+	}
+	err := CheckPlan(plan)
+	c.Assert(trace.IsBadParameter(err), check.Equals, true, check.Commentf("%v", err))
+	c.Assert(err, check.ErrorMatches,
+		`dependency cycle /a -> /b -> /a, phases \[/c\] can never be executed`)
+}
+
+func (s *SchedulerSuite) TestDetectsRequirementOnParent(c *check.C) {
+	plan := storage.OperationPlan{
+		Phases: []storage.OperationPhase{
+			{
+				ID: "/masters",
+				Phases: []storage.OperationPhase{
+					{ID: "/masters/node-1", Requires: []string{"/masters"}},
+				},
+			},
+		},
+	}
+	err := CheckPlan(plan)
+	c.Assert(err, check.ErrorMatches, `dependency cycle /masters/node-1 -> /masters/node-1`)
+}
+
+func (s *SchedulerSuite) TestExecutesIndependentPhasesConcurrently(c *check.C) {
+	var wg sync.WaitGroup
+	wg.Add(2)
+	started := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(started)
+	}()
+	barrier := func(context.Context) error {
+		wg.Done()
+		select {
+		case <-started:
+			return nil
+		case <-time.After(5 * time.Second):
+			return trace.LimitExceeded("phases were not executed concurrently")
+		}
+	}
+	engine := newTestEngine(storage.OperationPlan{
+		Phases: []storage.OperationPhase{
+			{ID: "/init"},
+			{ID: "/a", Requires: []string{"/init"}},
+			{ID: "/b", Requires: []string{"/init"}},
+			{ID: "/c", Requires: []string{"/a", "/b"}},
+		},
+	}, map[string]func(context.Context) error{
+		"/a": barrier,
+		"/b": barrier,
+	})
+	machine, err := New(Config{Engine: engine, ParallelPerServer: 2})
+	c.Assert(err, check.IsNil)
+
+	err = machine.ExecutePlan(context.TODO(), utils.NewNopProgress(), false)
+	c.Assert(err, check.IsNil)
+	c.Assert(engine.executed[0], check.Equals, "/init")
+	c.Assert(engine.executed[3], check.Equals, "/c")
+	plan, err := engine.GetPlan()
+	c.Assert(err, check.IsNil)
+	c.Assert(IsCompleted(plan), check.Equals, true)
+}
+
+func (s *SchedulerSuite) TestStopsSchedulingOnFailure(c *check.C) {
+	engine := newTestEngine(storage.OperationPlan{
+		Phases: []storage.OperationPhase{
+			{ID: "/init"},
+			{ID: "/a", Requires: []string{"/init"}},
+			{ID: "/b", Requires: []string{"/a"}},
+		},
+	}, map[string]func(context.Context) error{
+		"/a": func(context.Context) error { return trace.BadParameter("failure") },
+	})
+	machine, err := New(Config{Engine: engine, ParallelPerServer: 1})
+	c.Assert(err, check.IsNil)
+
+	err = machine.ExecutePlan(context.TODO(), utils.NewNopProgress(), false)
+	c.Assert(err, check.NotNil)
+	c.Assert(engine.executed, check.DeepEquals, []string{"/init", "/a"})
+}
+
+func newTestEngine(plan storage.OperationPlan, fns map[string]func(context.Context) error) *testEngine {
+	return &testEngine{
+		plan:   plan,
+		fns:    fns,
+		states: make(map[string]string),
+	}
+}
+
+// testEngine is the FSM engine that executes phases with the configured functions
+type testEngine struct {
+	sync.Mutex
+	plan     storage.OperationPlan
+	fns      map[string]func(context.Context) error
+	states   map[string]string
+	executed []string
+}
+
+func (r *testEngine) GetExecutor(params ExecutorParams, _ Remote) (PhaseExecutor, error) {
+	return &testExecutor{
+		FieldLogger: logrus.WithField("phase", params.Phase.ID),
+		engine:      r,
+		phaseID:     params.Phase.ID,
+	}, nil
+}
+
+func (r *testEngine) ChangePhaseState(_ context.Context, change StateChange) error {
+	r.Lock()
+	defer r.Unlock()
+	r.states[change.Phase] = change.State
+	return nil
+}
+
+func (r *testEngine) GetPlan() (*storage.OperationPlan, error) {
+	r.Lock()
+	defer r.Unlock()
+	plan := r.plan
+	plan.Phases = copyPhases(r.plan.Phases)
+	for _, phase := range FlattenPlan(&plan) {
+		phase.State = r.states[phase.ID]
+	}
+	return &plan, nil
+}
+
+func (r *testEngine) RunCommand(context.Context, RemoteRunner, storage.Server, Params) error {
+	return trace.NotImplemented("not implemented")
+}
+
+func (r *testEngine) Complete(error) error {
+	return nil
+}
+
+func (r *testEngine) execute(ctx context.Context, phaseID string) error {
+	r.Lock()
+	fn := r.fns[phaseID]
+	r.executed = append(r.executed, phaseID)
+	r.Unlock()
+	if fn == nil {
+		return nil
+	}
+	return fn(ctx)
+}
+
+func copyPhases(phases []storage.OperationPhase) []storage.OperationPhase {
+	result := make([]storage.OperationPhase, len(phases))
+	copy(result, phases)
+	for i := range result {
+		result[i].Phases = copyPhases(phases[i].Phases)
+	}
+	return result
+}
+
+type testExecutor struct {
+	logrus.FieldLogger
+	engine  *testEngine
+	phaseID string
+}
+
+func (r *testExecutor) PreCheck(context.Context) error  { return nil }
+func (r *testExecutor) PostCheck(context.Context) error { return nil }
+func (r *testExecutor) Rollback(context.Context) error  { return nil }
+
+func (r *testExecutor) Execute(ctx context.Context) error {
+	return r.engine.execute(ctx, r.phaseID)
+}
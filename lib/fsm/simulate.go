@@ -0,0 +1,247 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fsm
+
+import (
+	"context"
+
+	"github.com/gravitational/gravity/lib/loc"
+	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/systeminfo"
+	"github.com/gravitational/gravity/lib/utils"
+
+	"github.com/gravitational/trace"
+)
+
+// PhaseDescriber is implemented by phase executors that can describe
+// the changes they would make without actually executing
+type PhaseDescriber interface {
+	// Describe returns the description of changes the phase makes when executed
+	Describe(context.Context) (*PhaseDescription, error)
+}
+
+// PhaseDescription describes changes an operation phase makes when executed
+type PhaseDescription struct {
+	// Servers lists the names of servers the phase operates on
+	Servers []string `json:"servers,omitempty"`
+	// Packages lists the packages the phase installs, updates or removes
+	Packages []loc.Locator `json:"packages,omitempty"`
+	// Units lists the systemd units the phase installs, updates or restarts
+	Units []string `json:"units,omitempty"`
+	// Actions lists human-readable descriptions of actions the phase performs
+	Actions []string `json:"actions,omitempty"`
+}
+
+// Merge adds the items from the specified description that are
+// not yet in this description
+func (r *PhaseDescription) Merge(other PhaseDescription) {
+	for _, server := range other.Servers {
+		if !utils.StringInSlice(r.Servers, server) {
+			r.Servers = append(r.Servers, server)
+		}
+	}
+	for _, pkg := range other.Packages {
+		if !hasLocator(r.Packages, pkg) {
+			r.Packages = append(r.Packages, pkg)
+		}
+	}
+	for _, unit := range other.Units {
+		if !utils.StringInSlice(r.Units, unit) {
+			r.Units = append(r.Units, unit)
+		}
+	}
+	for _, action := range other.Actions {
+		if !utils.StringInSlice(r.Actions, action) {
+			r.Actions = append(r.Actions, action)
+		}
+	}
+}
+
+// SimulationReport describes the outcome of simulated plan execution
+type SimulationReport struct {
+	// OperationID is the ID of the operation the plan belongs to
+	OperationID string `json:"operation_id"`
+	// OperationType is the type of the operation the plan belongs to
+	OperationType string `json:"operation_type"`
+	// Phases lists the simulation results for all simulated phases in plan order
+	Phases []PhaseSimulation `json:"phases"`
+}
+
+// PhaseSimulation describes the outcome of simulating a single phase
+type PhaseSimulation struct {
+	// PhaseID is the ID of the simulated phase
+	PhaseID string `json:"phase_id"`
+	// Description is the phase description
+	Description string `json:"description,omitempty"`
+	// Node is the address of the node the phase is executed on.
+	// Empty if the phase is executed locally
+	Node string `json:"node,omitempty"`
+	// Completed is whether the phase has already been completed
+	// and would be skipped during execution
+	Completed bool `json:"completed,omitempty"`
+	// PreCheck is the outcome of the phase precheck
+	PreCheck string `json:"precheck"`
+	// PhaseDescription describes the changes the phase makes
+	PhaseDescription
+}
+
+// SimulatePhase walks the specified phase and its sub-phases and reports
+// what each of them would do if executed, without actually executing them.
+//
+// For each phase that is executed on this node, the phase precheck is run
+func (f *FSM) SimulatePhase(ctx context.Context, p Params) (*SimulationReport, error) {
+	plan, err := f.GetPlan()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return f.SimulatePlanPhase(ctx, *plan, p)
+}
+
+// SimulatePlanPhase is like SimulatePhase but walks the specified plan
+// instead of the one returned by the engine. It is used to simulate
+// operations which plan has not been persisted yet
+func (f *FSM) SimulatePlanPhase(ctx context.Context, plan storage.OperationPlan, p Params) (*SimulationReport, error) {
+	err := p.CheckAndSetDefaults()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var phases []*storage.OperationPhase
+	if p.PhaseID == RootPhase {
+		for i := range plan.Phases {
+			phases = append(phases, leafPhases(&plan.Phases[i])...)
+		}
+	} else {
+		phase, err := FindPhase(&plan, p.PhaseID)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		phases = leafPhases(phase)
+	}
+	report := &SimulationReport{
+		OperationID:   plan.OperationID,
+		OperationType: plan.OperationType,
+	}
+	for _, phase := range phases {
+		result, err := f.simulatePhase(ctx, plan, *phase, p)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		report.Phases = append(report.Phases, *result)
+	}
+	return report, nil
+}
+
+func (f *FSM) simulatePhase(ctx context.Context, plan storage.OperationPlan, phase storage.OperationPhase, p Params) (*PhaseSimulation, error) {
+	result := &PhaseSimulation{
+		PhaseID:          phase.ID,
+		Description:      phase.Description,
+		Node:             phaseServerAddr(phase),
+		Completed:        phase.IsCompleted() && !p.Force,
+		PhaseDescription: describePhase(phase),
+	}
+	executor, err := f.GetExecutor(ExecutorParams{
+		Plan:     plan,
+		Phase:    phase,
+		Progress: p.Progress,
+	}, f)
+	if err != nil {
+		f.WithError(err).Warnf("Failed to create executor for phase %q.", phase.ID)
+		result.PreCheck = trace.UserMessage(err)
+		return result, nil
+	}
+	if describer, ok := executor.(PhaseDescriber); ok {
+		description, err := describer.Describe(ctx)
+		if err != nil {
+			return nil, trace.Wrap(err, "failed to describe phase %q", phase.ID)
+		}
+		result.Merge(*description)
+	}
+	switch {
+	case result.Completed:
+		result.PreCheck = simulationPreCheckSkipped
+	case result.Node != "" && !isLocalAddr(result.Node):
+		result.PreCheck = simulationPreCheckRemote
+	default:
+		if err := executor.PreCheck(ctx); err != nil {
+			result.PreCheck = trace.UserMessage(err)
+		} else {
+			result.PreCheck = simulationPreCheckOK
+		}
+	}
+	return result, nil
+}
+
+// describePhase returns the description of the specified phase
+// based on the data attached to it
+func describePhase(phase storage.OperationPhase) (result PhaseDescription) {
+	if phase.Data == nil {
+		return result
+	}
+	data := phase.Data
+	for _, server := range []*storage.Server{data.Server, data.ExecServer, data.Master} {
+		if server != nil {
+			result.Merge(PhaseDescription{Servers: []string{server.Hostname}})
+		}
+	}
+	for _, pkg := range []*loc.Locator{data.Package, data.InstalledPackage, data.RuntimePackage} {
+		if pkg != nil {
+			result.Merge(PhaseDescription{Packages: []loc.Locator{*pkg}})
+		}
+	}
+	if data.Update != nil {
+		for _, server := range data.Update.Servers {
+			result.Merge(PhaseDescription{Servers: []string{server.Hostname}})
+			if server.Runtime.Update != nil {
+				result.Merge(PhaseDescription{Packages: []loc.Locator{
+					server.Runtime.Update.Package,
+					server.Runtime.Update.ConfigPackage,
+				}})
+			}
+			if server.Teleport.Update != nil {
+				result.Merge(PhaseDescription{Packages: []loc.Locator{
+					server.Teleport.Update.Package,
+					server.Teleport.Update.NodeConfigPackage,
+				}})
+			}
+		}
+	}
+	return result
+}
+
+func hasLocator(locators []loc.Locator, locator loc.Locator) bool {
+	for _, l := range locators {
+		if l.IsEqualTo(locator) {
+			return true
+		}
+	}
+	return false
+}
+
+func isLocalAddr(addr string) bool {
+	return systeminfo.HasInterface(addr) == nil
+}
+
+const (
+	// simulationPreCheckOK indicates that the phase precheck has succeeded
+	simulationPreCheckOK = "ok"
+	// simulationPreCheckSkipped indicates that the phase precheck was not
+	// run because the phase has already been completed
+	simulationPreCheckSkipped = "skipped"
+	// simulationPreCheckRemote indicates that the phase precheck was not
+	// run because the phase is executed on a different node
+	simulationPreCheckRemote = "remote"
+)
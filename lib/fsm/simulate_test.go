@@ -0,0 +1,80 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fsm
+
+import (
+	"context"
+
+	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/utils"
+
+	"gopkg.in/check.v1"
+)
+
+type SimulateSuite struct{}
+
+var _ = check.Suite(&SimulateSuite{})
+
+func (s *SimulateSuite) TestSimulatesPlanWithoutExecuting(c *check.C) {
+	engine := newTestEngine(storage.OperationPlan{
+		OperationID: "op-1",
+		Phases: []storage.OperationPhase{
+			{ID: "/init"},
+			{
+				ID: "/masters",
+				Phases: []storage.OperationPhase{
+					{ID: "/masters/node-1"},
+				},
+			},
+		},
+	}, nil)
+	engine.states["/init"] = storage.OperationPhaseStateCompleted
+	machine, err := New(Config{Engine: engine})
+	c.Assert(err, check.IsNil)
+
+	report, err := machine.SimulatePhase(context.TODO(), Params{
+		PhaseID:  RootPhase,
+		Progress: utils.NewNopProgress(),
+	})
+	c.Assert(err, check.IsNil)
+	c.Assert(engine.executed, check.HasLen, 0)
+	c.Assert(report.OperationID, check.Equals, "op-1")
+	c.Assert(report.Phases, check.DeepEquals, []PhaseSimulation{
+		{PhaseID: "/init", Completed: true, PreCheck: simulationPreCheckSkipped},
+		{PhaseID: "/masters/node-1", PreCheck: simulationPreCheckOK},
+	})
+}
+
+func (s *SimulateSuite) TestSimulatesSpecifiedPlan(c *check.C) {
+	engine := newTestEngine(storage.OperationPlan{OperationID: "op-1"}, nil)
+	machine, err := New(Config{Engine: engine})
+	c.Assert(err, check.IsNil)
+
+	report, err := machine.SimulatePlanPhase(context.TODO(), storage.OperationPlan{
+		OperationID: "op-2",
+		Phases:      []storage.OperationPhase{{ID: "/init"}},
+	}, Params{
+		PhaseID:  RootPhase,
+		Progress: utils.NewNopProgress(),
+	})
+	c.Assert(err, check.IsNil)
+	c.Assert(engine.executed, check.HasLen, 0)
+	c.Assert(report.OperationID, check.Equals, "op-2")
+	c.Assert(report.Phases, check.DeepEquals, []PhaseSimulation{
+		{PhaseID: "/init", PreCheck: simulationPreCheckOK},
+	})
+}
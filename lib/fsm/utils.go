@@ -17,11 +17,18 @@ limitations under the License.
 package fsm
 
 import (
+	"os"
+	"time"
+
 	"github.com/gravitational/gravity/lib/ops"
 	"github.com/gravitational/gravity/lib/schema"
 	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/systeminfo"
+	"github.com/gravitational/gravity/lib/utils"
 
 	"github.com/gravitational/trace"
+	"github.com/pborman/uuid"
+	"github.com/sirupsen/logrus"
 )
 
 // CanRollback checks if specified phase can be rolled back
@@ -123,10 +130,34 @@ func ResolvePlan(plan storage.OperationPlan, changelog storage.PlanChangelog) *s
 			allPhases[i].Updated = latest.Created
 			allPhases[i].Error = latest.Error
 		}
+		allPhases[i].History = changelog.Attempts(phase.ID)
 	}
 	return &plan
 }
 
+// NewPlanChange returns a new plan changelog entry for the specified
+// state change of a phase of the given operation.
+//
+// The entry records this server and the current user as the origin of the change
+func NewPlanChange(clusterName, operationID string, change StateChange) storage.PlanChange {
+	server := change.Server
+	if server == "" {
+		server = localHostname()
+	}
+	return storage.PlanChange{
+		ID:          uuid.New(),
+		ClusterName: clusterName,
+		OperationID: operationID,
+		PhaseID:     change.Phase,
+		NewState:    change.State,
+		Error:       utils.ToRawTrace(change.Error),
+		Created:     time.Now().UTC(),
+		Action:      change.Action,
+		Server:      server,
+		User:        currentUser(),
+	}
+}
+
 // DiffChangelog returns a list of changelog entries from "local" that are missing from "remote"
 func DiffChangelog(local, remote storage.PlanChangelog) []storage.PlanChange {
 	remoteEntries := make(map[string]struct{})
@@ -208,6 +239,24 @@ func OperationKey(plan storage.OperationPlan) ops.SiteOperationKey {
 	}
 }
 
+func localHostname() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		logrus.WithError(err).Warn("Failed to determine hostname.")
+		return ""
+	}
+	return hostname
+}
+
+func currentUser() string {
+	user, err := systeminfo.GetRealUser()
+	if err != nil {
+		logrus.WithError(err).Warn("Failed to determine current user.")
+		return ""
+	}
+	return user.Name
+}
+
 func addPhases(phase *storage.OperationPhase, result *[]*storage.OperationPhase) {
 	// add the phase itself
 	*result = append(*result, phase)
@@ -49,12 +49,19 @@ type Repository interface {
 	FetchChart(loc.Locator) (io.ReadCloser, error)
 	// GetIndexFile returns the chart repository index file.
 	GetIndexFile() (io.Reader, error)
-	// AddToIndex adds the specified application to the repository index.
+	// GetRepositoryIndexFile returns the index file of the specified repository.
+	GetRepositoryIndexFile(repository string) (io.Reader, error)
+	// AddToIndex adds the specified application to the index of its repository.
 	AddToIndex(locator loc.Locator, upsert bool) error
-	// RemoveFromIndex removes the specified application from the repository index.
+	// RemoveFromIndex removes the specified application from the index
+	// of its repository along with its provenance file.
 	RemoveFromIndex(loc.Locator) error
-	// RebuildIndex fully rebuilds the chart repository index.
+	// RebuildIndex fully rebuilds the index of each chart repository.
 	RebuildIndex() error
+	// GetProvenance returns the provenance file of the specified chart.
+	GetProvenance(loc.Locator) (io.Reader, error)
+	// UpsertProvenance creates or replaces the provenance file of the specified chart.
+	UpsertProvenance(locator loc.Locator, data []byte) error
 }
 
 // Config is the chart repository configuration.
@@ -100,6 +107,16 @@ func (r *clusterRepository) FetchChart(locator loc.Locator) (io.ReadCloser, erro
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
+	// Applications created from uploaded charts keep the original chart
+	// archive so it is served as-is and matches its provenance file.
+	data, err := ioutil.ReadFile(filepath.Join(tmpDir, chartArchiveDir,
+		helmutils.ToChartFilename(locator.Name, locator.Version)))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, trace.ConvertSystemError(err)
+	}
+	if err == nil {
+		return ioutil.NopCloser(bytes.NewReader(data)), nil
+	}
 	// Load and package a Helm chart.
 	chart, err := chartutil.LoadDir(filepath.Join(tmpDir, "resources"))
 	if err != nil {
@@ -127,7 +144,12 @@ func (r *clusterRepository) FetchChart(locator loc.Locator) (io.ReadCloser, erro
 
 // GetIndexFile returns the chart repository index file.
 func (r *clusterRepository) GetIndexFile() (io.Reader, error) {
-	indexFile, err := r.Backend.GetIndexFile()
+	return r.GetRepositoryIndexFile(defaults.SystemAccountOrg)
+}
+
+// GetRepositoryIndexFile returns the index file of the specified repository.
+func (r *clusterRepository) GetRepositoryIndexFile(repository string) (io.Reader, error) {
+	indexFile, err := r.Backend.GetRepositoryIndexFile(repository)
 	if err != nil && !trace.IsNotFound(err) {
 		return nil, trace.Wrap(err)
 	}
@@ -152,14 +174,14 @@ func (r *clusterRepository) AddToIndex(locator loc.Locator, upsert bool) error {
 	if err != nil {
 		return trace.Wrap(err)
 	}
-	indexFile, err := r.Backend.GetIndexFile()
+	indexFile, err := r.Backend.GetRepositoryIndexFile(locator.Repository)
 	if err != nil && !trace.IsNotFound(err) {
 		return trace.Wrap(err)
 	}
 	if trace.IsNotFound(err) {
 		indexFile = repo.NewIndexFile()
-		indexFile.Add(chart.Metadata, r.chartURL(chart), "", digest)
-		return r.Backend.CompareAndSwapIndexFile(indexFile, nil)
+		indexFile.Add(chart.Metadata, r.chartURL(locator.Repository, chart), "", digest)
+		return r.Backend.CompareAndSwapRepositoryIndexFile(locator.Repository, indexFile, nil)
 	}
 	if indexFile.Has(chart.Metadata.Name, chart.Metadata.Version) {
 		if !upsert {
@@ -167,25 +189,36 @@ func (r *clusterRepository) AddToIndex(locator loc.Locator, upsert bool) error {
 				chart.Metadata.Name, chart.Metadata.Version)
 		}
 		// Delete the old entry first because "add" will not check for dupes.
+		// The provenance file of the replaced chart is removed as well since
+		// it does not match the new chart archive
 		err := r.RemoveFromIndex(locator)
 		if err != nil {
 			return trace.Wrap(err)
 		}
-		indexFile, err = r.Backend.GetIndexFile()
+		indexFile, err = r.Backend.GetRepositoryIndexFile(locator.Repository)
 		if err != nil {
 			return trace.Wrap(err)
 		}
 	}
 	prevIndexFile := helmutils.CopyIndexFile(*indexFile)
-	indexFile.Add(chart.Metadata, r.chartURL(chart), "", digest)
+	indexFile.Add(chart.Metadata, r.chartURL(locator.Repository, chart), "", digest)
 	indexFile.SortEntries()
-	return r.Backend.CompareAndSwapIndexFile(indexFile, prevIndexFile)
+	return r.Backend.CompareAndSwapRepositoryIndexFile(locator.Repository, indexFile, prevIndexFile)
 }
 
-// RemoveFromIndex removes the specified application from the repository index.
+// RemoveFromIndex removes the specified application from the index
+// of its repository along with its provenance file.
 func (r *clusterRepository) RemoveFromIndex(locator loc.Locator) error {
+	err := r.Backend.DeleteChartProvenance(locator.Repository, locator.Name, locator.Version)
+	if err != nil && !trace.IsNotFound(err) {
+		return trace.Wrap(err)
+	}
+	return r.removeFromIndex(locator)
+}
+
+func (r *clusterRepository) removeFromIndex(locator loc.Locator) error {
 	r.Infof("Removing %v from chart repo index.", locator)
-	indexFile, err := r.Backend.GetIndexFile()
+	indexFile, err := r.Backend.GetRepositoryIndexFile(locator.Repository)
 	if err != nil && !trace.IsNotFound(err) {
 		return trace.Wrap(err)
 	}
@@ -210,17 +243,33 @@ L:
 			break L
 		}
 	}
-	return r.Backend.CompareAndSwapIndexFile(indexFile, prevIndexFile)
+	return r.Backend.CompareAndSwapRepositoryIndexFile(locator.Repository, indexFile, prevIndexFile)
 }
 
-// RebuildIndex fully rebuilds chart repository index.
+// RebuildIndex fully rebuilds the index of each chart repository.
 //
 // This method is for development/debugging or disaster-recovery cases only
 // (e.g. if index gets corrupted) as it iterates over all cluster packages.
 func (r *clusterRepository) RebuildIndex() error {
 	r.Warn("Rebuilding chart repository index.")
+	repositories, err := r.Packages.GetRepositories()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if !utils.StringInSlice(repositories, defaults.SystemAccountOrg) {
+		repositories = append(repositories, defaults.SystemAccountOrg)
+	}
+	for _, repository := range repositories {
+		if err := r.rebuildIndex(repository); err != nil {
+			return trace.Wrap(err)
+		}
+	}
+	return nil
+}
+
+func (r *clusterRepository) rebuildIndex(repository string) error {
 	indexFile := repo.NewIndexFile()
-	err := pack.ForeachPackageInRepo(r.Packages, defaults.SystemAccountOrg,
+	err := pack.ForeachPackageInRepo(r.Packages, repository,
 		func(e pack.PackageEnvelope) error {
 			// Skip not application images.
 			if len(e.Manifest) == 0 {
@@ -242,14 +291,33 @@ func (r *clusterRepository) RebuildIndex() error {
 				return trace.Wrap(err)
 			}
 			r.Debugf("Adding to the index: %v.", e.Locator)
-			indexFile.Add(chart.Metadata, r.chartURL(chart), "", digest)
+			indexFile.Add(chart.Metadata, r.chartURL(repository, chart), "", digest)
 			return nil
 		})
-	if err != nil {
+	if err != nil && !trace.IsNotFound(err) {
 		return trace.Wrap(err)
 	}
 	indexFile.SortEntries()
-	return r.Backend.UpsertIndexFile(*indexFile)
+	return r.Backend.UpsertRepositoryIndexFile(repository, *indexFile)
+}
+
+// GetProvenance returns the provenance file of the specified chart.
+func (r *clusterRepository) GetProvenance(locator loc.Locator) (io.Reader, error) {
+	data, err := r.Backend.GetChartProvenance(locator.Repository, locator.Name, locator.Version)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return bytes.NewReader(data), nil
+}
+
+// UpsertProvenance creates or replaces the provenance file of the specified chart.
+func (r *clusterRepository) UpsertProvenance(locator loc.Locator, data []byte) error {
+	r.Infof("Updating provenance of %v.", locator)
+	err := r.Backend.UpsertChartProvenance(locator.Repository, locator.Name, locator.Version, data)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return nil
 }
 
 // chartForLocator returns the specified application as a Helm chart archive.
@@ -267,14 +335,20 @@ func (r *clusterRepository) chartForLocator(locator loc.Locator) (*chart.Chart,
 }
 
 // chartURL returns URL of the specified chart in the repository.
-func (r *clusterRepository) chartURL(chart *chart.Chart) string {
-	return fmt.Sprintf("%v/charts/%v", r.Packages.PortalURL(),
-		helmutils.ToChartFilename(chart.Metadata.Name, chart.Metadata.Version))
+//
+// Charts of the default repository are served under the original /charts
+// prefix, all other repositories are served under /helm/<repository>
+func (r *clusterRepository) chartURL(repository string, chart *chart.Chart) string {
+	filename := helmutils.ToChartFilename(chart.Metadata.Name, chart.Metadata.Version)
+	if repository == defaults.SystemAccountOrg {
+		return fmt.Sprintf("%v/charts/%v", r.Packages.PortalURL(), filename)
+	}
+	return fmt.Sprintf("%v/helm/%v/charts/%v", r.Packages.PortalURL(), repository, filename)
 }
 
-// digest returns a sha256 hash of the specified application data.
+// digest returns a sha256 hash of the specified application's chart archive.
 func (r *clusterRepository) digest(locator loc.Locator) (string, error) {
-	_, reader, err := r.Packages.ReadPackage(locator)
+	reader, err := r.FetchChart(locator)
 	if err != nil {
 		return "", trace.Wrap(err)
 	}
@@ -0,0 +1,221 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helm
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/loc"
+	"github.com/gravitational/gravity/lib/utils"
+	helmutils "github.com/gravitational/gravity/lib/utils/helm"
+
+	dockerarchive "github.com/docker/docker/pkg/archive"
+	"github.com/ghodss/yaml"
+	"github.com/gravitational/trace"
+	"golang.org/x/crypto/openpgp/clearsign"
+	"k8s.io/helm/pkg/chartutil"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+	"k8s.io/helm/pkg/provenance"
+)
+
+// ChartPackage is an application package converted from a Helm chart archive
+type ChartPackage struct {
+	// ReadCloser streams the application package data
+	io.ReadCloser
+	// Locator is the locator of the application package
+	Locator loc.Locator
+	// Manifest is the application manifest generated for the chart
+	Manifest []byte
+	// Digest is the sha256 digest of the original chart archive
+	Digest string
+}
+
+// ConvertChart converts the Helm chart archive read from reader into
+// an application package in the specified repository.
+//
+// The package layout matches that of applications built from charts:
+// chart files are placed in the resources directory along with the generated
+// application manifest. The original archive is kept in the package so
+// it can be served unmodified to match its provenance file.
+//
+// The caller is responsible for closing the returned package
+func ConvertChart(reader io.Reader, repository string) (*ChartPackage, error) {
+	data, err := ioutil.ReadAll(io.LimitReader(reader, defaults.MaxChartArchiveSize+1))
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if len(data) > defaults.MaxChartArchiveSize {
+		return nil, trace.BadParameter("chart archive exceeds the maximum size of %v bytes",
+			defaults.MaxChartArchiveSize)
+	}
+	chart, err := chartutil.LoadArchive(bytes.NewReader(data))
+	if err != nil {
+		return nil, trace.Wrap(err, "failed to load chart archive")
+	}
+	if err := checkChartMetadata(chart); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	locator, err := loc.NewLocator(repository, chart.Metadata.Name, chart.Metadata.Version)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	manifest, err := GenerateManifest(chart)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	manifestBytes, err := yaml.Marshal(manifest)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	digest, err := provenance.Digest(bytes.NewReader(data))
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	dir, err := ioutil.TempDir("", "chart")
+	if err != nil {
+		return nil, trace.ConvertSystemError(err)
+	}
+	if err := writeChartPackage(dir, chart, data, manifestBytes); err != nil {
+		os.RemoveAll(dir)
+		return nil, trace.Wrap(err)
+	}
+	packageReader, err := dockerarchive.Tar(dir, dockerarchive.Gzip)
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, trace.Wrap(err)
+	}
+	return &ChartPackage{
+		ReadCloser: &utils.CleanupReadCloser{
+			ReadCloser: packageReader,
+			Cleanup: func() {
+				os.RemoveAll(dir)
+			},
+		},
+		Locator:  *locator,
+		Manifest: manifestBytes,
+		Digest:   digest,
+	}, nil
+}
+
+// Provenance describes a parsed chart provenance file
+type Provenance struct {
+	// Metadata is the metadata of the signed chart
+	Metadata chart.Metadata
+	// Files maps the chart archive filename to its digest
+	Files map[string]string
+	// Data is the original provenance file data
+	Data []byte
+}
+
+// ParseProvenance parses the provenance file data.
+//
+// The signature itself is not verified: the provenance file is kept
+// as-is for clients to verify with their keyrings
+func ParseProvenance(data []byte) (*Provenance, error) {
+	block, _ := clearsign.Decode(data)
+	if block == nil {
+		return nil, trace.BadParameter("provenance file is not a signed message")
+	}
+	parts := bytes.Split(block.Plaintext, []byte("\n...\n"))
+	if len(parts) < 2 {
+		return nil, trace.BadParameter("provenance message must have at least two parts")
+	}
+	var metadata chart.Metadata
+	if err := yaml.Unmarshal(parts[0], &metadata); err != nil {
+		return nil, trace.Wrap(err, "failed to parse chart metadata in provenance file")
+	}
+	var sums provenance.SumCollection
+	if err := yaml.Unmarshal(parts[1], &sums); err != nil {
+		return nil, trace.Wrap(err, "failed to parse file digests in provenance file")
+	}
+	if metadata.Name == "" || metadata.Version == "" {
+		return nil, trace.BadParameter("provenance file is missing chart name or version")
+	}
+	return &Provenance{
+		Metadata: metadata,
+		Files:    sums.Files,
+		Data:     data,
+	}, nil
+}
+
+// Locator returns the locator of the signed chart in the specified repository
+func (r Provenance) Locator(repository string) (*loc.Locator, error) {
+	return loc.NewLocator(repository, r.Metadata.Name, r.Metadata.Version)
+}
+
+// Check makes sure the provenance file describes the chart archive
+// with the specified digest
+func (r Provenance) Check(digest string) error {
+	filename := helmutils.ToChartFilename(r.Metadata.Name, r.Metadata.Version)
+	sum, ok := r.Files[filename]
+	if !ok {
+		return trace.BadParameter("provenance file has no digest for %v", filename)
+	}
+	if sum != "sha256:"+digest {
+		return trace.BadParameter("digest of %v does not match provenance file", filename)
+	}
+	return nil
+}
+
+func checkChartMetadata(chart *chart.Chart) error {
+	if chart.Metadata == nil {
+		return trace.BadParameter("chart archive is missing Chart.yaml")
+	}
+	if chart.Metadata.Name == "" {
+		return trace.BadParameter("chart name is required")
+	}
+	if chart.Metadata.Version == "" {
+		return trace.BadParameter("chart version is required")
+	}
+	return nil
+}
+
+// writeChartPackage writes the contents of the application package
+// for the specified chart into dir
+func writeChartPackage(dir string, chart *chart.Chart, data, manifest []byte) error {
+	if err := chartutil.SaveDir(chart, dir); err != nil {
+		return trace.Wrap(err)
+	}
+	err := os.Rename(filepath.Join(dir, chart.Metadata.Name), filepath.Join(dir, defaults.ResourcesDir))
+	if err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	err = ioutil.WriteFile(filepath.Join(dir, defaults.ResourcesDir, defaults.ManifestFileName),
+		manifest, defaults.SharedReadMask)
+	if err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	if err := os.MkdirAll(filepath.Join(dir, chartArchiveDir), defaults.SharedDirMask); err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	err = ioutil.WriteFile(filepath.Join(dir, chartArchiveDir,
+		helmutils.ToChartFilename(chart.Metadata.Name, chart.Metadata.Version)),
+		data, defaults.SharedReadMask)
+	if err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	return nil
+}
+
+// chartArchiveDir is the directory in the application package
+// with the original archive of the uploaded chart
+const chartArchiveDir = "chart"
@@ -17,18 +17,18 @@ limitations under the License.
 package hub
 
 import (
-	"bufio"
 	"crypto/sha256"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"net/url"
 	"os"
-	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/gravitational/gravity/lib/constants"
-	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/httplib"
 	"github.com/gravitational/gravity/lib/loc"
 	"github.com/gravitational/gravity/lib/utils"
 
@@ -38,18 +38,11 @@ import (
 	"github.com/gravitational/trace"
 	"github.com/sirupsen/logrus"
 	"k8s.io/helm/pkg/repo"
-
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/credentials"
-	"github.com/aws/aws-sdk-go/aws/session"
-	"github.com/aws/aws-sdk-go/service/s3"
-	"github.com/aws/aws-sdk-go/service/s3/s3iface"
-	"github.com/aws/aws-sdk-go/service/s3/s3manager"
 )
 
 // Hub defines an interface for the hub that stores Telekube application installers
 //
-// The current hub implementation is backed by S3 and all application installers
+// The default hub implementation is backed by S3 and all application installers
 // are stored in the bucket of the following structure:
 //
 // hub.gravitational.io
@@ -69,6 +62,10 @@ import (
 // The index file, index.yaml, provides information about installers stored
 // in the bucket and is updated every time a new version is published. The
 // index file format is the same as Helm's chart repository index file.
+//
+// A hub with the same layout can also be served from a local directory or
+// any static HTTP server, see NewDir and NewHTTP. Such hubs are populated
+// with Publish.
 type Hub interface {
 	// List returns a list of applications in the hub
 	List(withPrereleases bool) ([]App, error)
@@ -78,6 +75,9 @@ type Hub interface {
 	Get(loc.Locator) (io.ReadCloser, error)
 	// GetLatestVersion returns latest version of the specified application
 	GetLatestVersion(name string) (string, error)
+	// GetSignature returns the detached PGP signature of the specified
+	// application installer if it has been published with one
+	GetSignature(name, version string) ([]byte, error)
 }
 
 // App represents a single application item in the hub
@@ -96,75 +96,73 @@ type App struct {
 	Type string `json:"type"`
 }
 
-// s3Hub is the S3-backed hub implementation
-type s3Hub struct {
-	// Config is the hub configuration
-	Config
-	// downloader is the S3 download manager
-	downloader *s3manager.Downloader
+// NewForURL returns a hub for the specified URL.
+//
+// An empty URL selects the default S3-backed hub, http:// and https:// URLs
+// select a hub served by a static HTTP server and file:// URLs or plain paths
+// select a hub in a local directory.
+// insecure turns off TLS verification for the HTTP hub
+func NewForURL(hubURL string, insecure bool) (Hub, error) {
+	if hubURL == "" {
+		return New(Config{})
+	}
+	u, err := url.Parse(hubURL)
+	if err != nil {
+		return nil, trace.Wrap(err, "invalid hub URL %q", hubURL)
+	}
+	switch u.Scheme {
+	case "http", "https":
+		return NewHTTP(HTTPConfig{
+			URL:    hubURL,
+			Client: httplib.GetClient(insecure),
+		})
+	case "file":
+		return NewDir(DirConfig{Dir: u.Path})
+	case "":
+		return NewDir(DirConfig{Dir: hubURL})
+	default:
+		return nil, trace.BadParameter("unsupported hub URL scheme %q, "+
+			"expected http, https or file", u.Scheme)
+	}
 }
 
-// Config is the S3-backed hub configuration
-type Config struct {
-	// Bucket is the S3 bucket name
-	Bucket string
-	// Prefix is the S3 path prefix
-	Prefix string
-	// Region is the S3 region
-	Region string
+// hub implements the Hub on top of a store that provides
+// access to the files in the hub layout
+type hub struct {
 	// FieldLogger is used for logging
 	logrus.FieldLogger
-	// S3 is optional S3 API client
-	S3 s3iface.S3API
-}
-
-// CheckAndSetDefaults validates config and sets defaults
-func (c *Config) CheckAndSetDefaults() error {
-	if c.Bucket == "" {
-		c.Bucket = defaults.HubBucket
-	}
-	if c.Prefix == "" {
-		c.Prefix = defaults.HubTelekubePrefix
-	}
-	if c.Region == "" {
-		c.Region = defaults.AWSRegion
-	}
-	if c.FieldLogger == nil {
-		c.FieldLogger = logrus.WithField(trace.Component, "s3hub")
-	}
-	if c.S3 == nil {
-		session, err := session.NewSession(&aws.Config{
-			Region:      aws.String(c.Region),
-			Credentials: credentials.AnonymousCredentials,
-		})
-		if err != nil {
-			return trace.Wrap(err)
-		}
-		c.S3 = s3.New(session)
-	}
-	return nil
+	// store provides access to the hub files
+	store store
 }
 
-// New returns a new S3-backed hub instance
-func New(config Config) (*s3Hub, error) {
-	err := config.CheckAndSetDefaults()
-	if err != nil {
-		return nil, trace.Wrap(err)
-	}
-	return &s3Hub{
-		Config:     config,
-		downloader: s3manager.NewDownloaderWithClient(config.S3),
-	}, nil
+// store provides read access to the files of a hub.
+// Paths are relative to the hub root and use forward slashes
+type store interface {
+	// open returns the contents of the file with the specified path.
+	// Returns trace.NotFound if the file does not exist
+	open(path string) (io.ReadCloser, error)
+	// download downloads the file with the specified path into f
+	// and returns the number of bytes written
+	download(f *os.File, path string) (int64, error)
+	// String returns the location of the store
+	String() string
 }
 
 // List returns a list of applications in the hub
-func (h *s3Hub) List(withPrereleases bool) (items []App, err error) {
+func (h *hub) List(withPrereleases bool) (items []App, err error) {
 	indexFile, err := h.getIndexFile()
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
-	for _, entryVersions := range indexFile.Entries {
-		for _, entry := range entryVersions {
+	// index entries are kept in a map so iterate over them in the order
+	// of application names to return a stable list
+	names := make([]string, 0, len(indexFile.Entries))
+	for name := range indexFile.Entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, entry := range indexFile.Entries[name] {
 			items = append(items, App{
 				Name:        entry.Name,
 				Version:     entry.Version,
@@ -178,7 +176,7 @@ func (h *s3Hub) List(withPrereleases bool) (items []App, err error) {
 }
 
 // Downloads downloads the specified application installer into provided file
-func (h *s3Hub) Download(f *os.File, locator loc.Locator, progress utils.Progress) (err error) {
+func (h *hub) Download(f *os.File, locator loc.Locator, progress utils.Progress) (err error) {
 	version := locator.Version
 	// in case the provided version is a special 'latest' or 'stable' label,
 	// we need to look into respective bucket to find out the actual version
@@ -192,16 +190,12 @@ func (h *s3Hub) Download(f *os.File, locator loc.Locator, progress utils.Progres
 		return trace.Wrap(err)
 	}
 	progress.NextStep(fmt.Sprintf("Downloading %v:%v", locator.Name, locator.Version))
-	h.Infof("Downloading: %v.", h.appPath(locator.Name, locator.Version))
-	n, err := h.downloader.Download(f, &s3.GetObjectInput{
-		Bucket: aws.String(h.Bucket),
-		Key:    aws.String(h.appPath(locator.Name, locator.Version)),
-	})
+	h.Infof("Downloading: %v.", appPath(locator.Name, locator.Version))
+	n, err := h.store.download(f, appPath(locator.Name, locator.Version))
 	if err != nil {
-		err := utils.ConvertS3Error(err)
 		if trace.IsNotFound(err) {
 			return trace.NotFound("application %v:%v not found in %v, use 'tele ls' to see available applications",
-				locator.Name, locator.Version, h.Bucket)
+				locator.Name, locator.Version, h.store)
 		}
 		return trace.Wrap(err)
 	}
@@ -213,7 +207,7 @@ func (h *s3Hub) Download(f *os.File, locator loc.Locator, progress utils.Progres
 }
 
 // Get returns application installer tarball of the specified version
-func (h *s3Hub) Get(locator loc.Locator) (io.ReadCloser, error) {
+func (h *hub) Get(locator loc.Locator) (io.ReadCloser, error) {
 	tarFile, err := ioutil.TempFile("", locator.Name)
 	if err != nil {
 		return nil, trace.Wrap(err)
@@ -231,20 +225,22 @@ func (h *s3Hub) Get(locator loc.Locator) (io.ReadCloser, error) {
 		readCloser.Close()
 		return nil, trace.Wrap(err)
 	}
+	// stores other than S3 write the file sequentially
+	if _, err := tarFile.Seek(0, io.SeekStart); err != nil {
+		readCloser.Close()
+		return nil, trace.Wrap(err)
+	}
 	return readCloser, nil
 }
 
 // getIndexFile returns the hub's index file.
-func (h *s3Hub) getIndexFile() (*repo.IndexFile, error) {
-	object, err := h.S3.GetObject(&s3.GetObjectInput{
-		Bucket: aws.String(h.Bucket),
-		Key:    aws.String(filepath.Join(h.Prefix, indexFileName)),
-	})
+func (h *hub) getIndexFile() (*repo.IndexFile, error) {
+	rc, err := h.store.open(indexFileName)
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
-	defer object.Body.Close()
-	bytes, err := ioutil.ReadAll(object.Body)
+	defer rc.Close()
+	bytes, err := ioutil.ReadAll(rc)
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
@@ -256,28 +252,8 @@ func (h *s3Hub) getIndexFile() (*repo.IndexFile, error) {
 	return &indexFile, nil
 }
 
-// appsBucket returns sub-bucket where all applications are stored
-func (h *s3Hub) appsBucket() string {
-	return fmt.Sprintf("%v/app", h.Prefix)
-}
-
-// appBucket returns sub-bucket where the specified application is stored
-func (h *s3Hub) appBucket(name, version string) string {
-	return fmt.Sprintf("%v/%v/%v/linux/x86_64", h.appsBucket(), name, version)
-}
-
-// appBucketPath returns path to the specified application in the hub
-func (h *s3Hub) appPath(name, version string) string {
-	return fmt.Sprintf("%v/%v", h.appBucket(name, version), makeFilename(name, version))
-}
-
-// shaPath returns path to the checksum file of the specified application in the hub
-func (h *s3Hub) shaPath(name, version string) string {
-	return h.appPath(name, version) + ".sha256"
-}
-
 // GetLatestVersion returns the latest version of the specified application in the hub
-func (h *s3Hub) GetLatestVersion(name string) (string, error) {
+func (h *hub) GetLatestVersion(name string) (string, error) {
 	indexFile, err := h.getIndexFile()
 	if err != nil {
 		return "", trace.Wrap(err)
@@ -303,7 +279,7 @@ func (h *s3Hub) GetLatestVersion(name string) (string, error) {
 }
 
 // getStableVersion returns the stable version of the specified application in the hub
-func (h *s3Hub) getStableVersion(name string) (string, error) {
+func (h *hub) getStableVersion(name string) (string, error) {
 	indexFile, err := h.getIndexFile()
 	if err != nil {
 		return "", trace.Wrap(err)
@@ -331,8 +307,26 @@ func (h *s3Hub) getStableVersion(name string) (string, error) {
 	return stableVersion.String(), nil
 }
 
+// GetSignature returns the detached PGP signature of the specified application installer
+func (h *hub) GetSignature(name, version string) ([]byte, error) {
+	rc, err := h.store.open(signaturePath(name, version))
+	if err != nil {
+		if trace.IsNotFound(err) {
+			return nil, trace.NotFound("no signature published for %v:%v in %v",
+				name, version, h.store)
+		}
+		return nil, trace.Wrap(err)
+	}
+	defer rc.Close()
+	signature, err := ioutil.ReadAll(rc)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return signature, nil
+}
+
 // verifyChecksum verifies the checksum of the downloaded installer file
-func (h *s3Hub) verifyChecksum(name, version, path string) error {
+func (h *hub) verifyChecksum(name, version, path string) error {
 	storedChecksum, err := h.getChecksum(name, version)
 	if err != nil {
 		return trace.Wrap(err)
@@ -356,20 +350,17 @@ func (h *s3Hub) verifyChecksum(name, version, path string) error {
 }
 
 // getChecksum fetches the sha256 checksum of the specified application from the hub
-func (h *s3Hub) getChecksum(name, version string) (string, error) {
-	object, err := h.S3.GetObject(&s3.GetObjectInput{
-		Bucket: aws.String(h.Bucket),
-		Key:    aws.String(h.shaPath(name, version)),
-	})
+func (h *hub) getChecksum(name, version string) (string, error) {
+	rc, err := h.store.open(shaPath(name, version))
 	if err != nil {
 		return "", trace.Wrap(err)
 	}
-	defer object.Body.Close()
+	defer rc.Close()
 	// even though the file should only contain the checksum, read in only
 	// first 64 bytes (checksum block size) to be on a safe side
 	checksum := make([]byte, sha256.BlockSize)
-	n, err := bufio.NewReader(object.Body).Read(checksum)
-	if err != nil {
+	n, err := io.ReadFull(rc, checksum)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
 		return "", trace.Wrap(err)
 	}
 	if n != sha256.BlockSize {
@@ -379,6 +370,29 @@ func (h *s3Hub) getChecksum(name, version string) (string, error) {
 	return string(checksum), nil
 }
 
+// appDir returns the directory where the specified application is stored
+// relative to the hub root
+func appDir(name, version string) string {
+	return fmt.Sprintf("app/%v/%v/linux/x86_64", name, version)
+}
+
+// appPath returns path to the specified application relative to the hub root
+func appPath(name, version string) string {
+	return fmt.Sprintf("%v/%v", appDir(name, version), makeFilename(name, version))
+}
+
+// shaPath returns path to the checksum file of the specified application
+// relative to the hub root
+func shaPath(name, version string) string {
+	return appPath(name, version) + ".sha256"
+}
+
+// signaturePath returns path to the detached signature file of the specified
+// application relative to the hub root
+func signaturePath(name, version string) string {
+	return appPath(name, version) + signatureExt
+}
+
 // makeFilename returns the name of the file under which the application
 // specified by the provided locator is stored in the hub
 func makeFilename(name, version string) string {
@@ -388,4 +402,6 @@ func makeFilename(name, version string) string {
 const (
 	// indexFileName is the repository index file name.
 	indexFileName = "index.yaml"
+	// signatureExt is the extension of the armored detached signature file
+	signatureExt = ".asc"
 )
@@ -0,0 +1,289 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hub
+
+import (
+	"archive/tar"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gravitational/gravity/lib/archive"
+	"github.com/gravitational/gravity/lib/constants"
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/schema"
+
+	"github.com/coreos/go-semver/semver"
+	dockerarchive "github.com/docker/docker/pkg/archive"
+	"github.com/ghodss/yaml"
+	"github.com/gravitational/trace"
+	"github.com/sirupsen/logrus"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+	"k8s.io/helm/pkg/repo"
+)
+
+// PublishConfig describes an installer to publish into a directory hub
+type PublishConfig struct {
+	// Dir is the hub root directory
+	Dir string
+	// Path is the path to the installer tarball
+	Path string
+	// FieldLogger is used for logging
+	logrus.FieldLogger
+}
+
+// CheckAndSetDefaults validates config and sets defaults
+func (c *PublishConfig) CheckAndSetDefaults() error {
+	if c.Dir == "" {
+		return trace.BadParameter("missing parameter Dir")
+	}
+	if c.Path == "" {
+		return trace.BadParameter("missing parameter Path")
+	}
+	if c.FieldLogger == nil {
+		c.FieldLogger = logrus.WithField(trace.Component, "publish")
+	}
+	return nil
+}
+
+// Publish copies the installer tarball into the hub in the specified directory
+// together with its checksum file and adds it to the hub index, creating
+// the index if necessary. The detached signature of the installer, if found
+// next to it with the .asc extension, is published as well. An installer previously published with the same
+// name and version is replaced.
+//
+// The resulting directory can be used with NewDir or served by any static
+// HTTP server and used with NewHTTP
+func Publish(config PublishConfig) (*App, error) {
+	if err := config.CheckAndSetDefaults(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	manifest, err := manifestFromInstaller(config.Path)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	name := manifest.Metadata.Name
+	version := manifest.Metadata.ResourceVersion
+	if _, err := semver.NewVersion(version); err != nil {
+		return nil, trace.BadParameter("installer %v has invalid version %q: %v",
+			config.Path, version, err)
+	}
+	logger := config.WithField("app", fmt.Sprintf("%v:%v", name, version))
+
+	dir := filepath.Join(config.Dir, filepath.FromSlash(appDir(name, version)))
+	if err := os.MkdirAll(dir, defaults.SharedDirMask); err != nil {
+		return nil, trace.ConvertSystemError(err)
+	}
+	size, checksum, err := copyInstaller(config.Path,
+		filepath.Join(config.Dir, filepath.FromSlash(appPath(name, version))))
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	err = writeFile(filepath.Join(config.Dir, filepath.FromSlash(shaPath(name, version))),
+		[]byte(checksum))
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	logger.WithField("checksum", checksum).Info("Copied installer.")
+	signed, err := copySignature(config.Path+signatureExt,
+		filepath.Join(config.Dir, filepath.FromSlash(signaturePath(name, version))))
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if signed {
+		logger.Info("Copied installer signature.")
+	}
+
+	indexFile, err := readIndexFile(config.Dir)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	removeIndexEntry(indexFile, name, version)
+	indexFile.Add(&chart.Metadata{
+		Name:        name,
+		Version:     version,
+		Description: manifest.Metadata.Description,
+		Annotations: map[string]string{
+			constants.AnnotationKind: manifest.ImageType(),
+			constants.AnnotationLogo: manifest.Logo,
+			constants.AnnotationSize: fmt.Sprintf("%v", size),
+		},
+	}, makeFilename(name, version), appDir(name, version), fmt.Sprintf("sha256:%v", checksum))
+	indexFile.SortEntries()
+	entry, err := indexFile.Get(name, version)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	indexFile.Generated = time.Now()
+	bytes, err := yaml.Marshal(indexFile)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if err := writeFile(filepath.Join(config.Dir, indexFileName), bytes); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	logger.Info("Updated hub index.")
+	return &App{
+		Name:        name,
+		Version:     version,
+		Created:     entry.Created,
+		SizeBytes:   size,
+		Description: strings.TrimSpace(manifest.Metadata.Description),
+		Type:        manifest.ImageType(),
+	}, nil
+}
+
+// manifestFromInstaller reads the application manifest
+// from the root of the specified installer tarball
+func manifestFromInstaller(path string) (*schema.Manifest, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, trace.ConvertSystemError(err)
+	}
+	defer f.Close()
+	decompressed, err := dockerarchive.DecompressStream(f)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	defer decompressed.Close()
+	var manifestBytes []byte
+	err = archive.TarGlob(tar.NewReader(decompressed), ".", []string{defaults.ManifestFileName},
+		func(match string, file io.Reader) error {
+			if match != defaults.ManifestFileName {
+				return nil
+			}
+			manifestBytes, err = ioutil.ReadAll(file)
+			if err != nil {
+				return trace.Wrap(err)
+			}
+			return archive.Abort
+		})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if manifestBytes == nil {
+		return nil, trace.NotFound("%v is not an installer: no application manifest %v found",
+			path, defaults.ManifestFileName)
+	}
+	manifest, err := schema.ParseManifestYAMLNoValidate(manifestBytes)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return manifest, nil
+}
+
+// copyInstaller copies the installer from path to targetPath and returns
+// its size and the sha256 checksum
+func copyInstaller(path, targetPath string) (size int64, checksum string, err error) {
+	src, err := os.Open(path)
+	if err != nil {
+		return 0, "", trace.ConvertSystemError(err)
+	}
+	defer src.Close()
+	tmpPath := targetPath + ".tmp"
+	dst, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, defaults.SharedReadMask)
+	if err != nil {
+		return 0, "", trace.ConvertSystemError(err)
+	}
+	defer os.Remove(tmpPath)
+	hash := sha256.New()
+	size, err = io.Copy(io.MultiWriter(dst, hash), src)
+	if err != nil {
+		dst.Close()
+		return 0, "", trace.ConvertSystemError(err)
+	}
+	if err := dst.Close(); err != nil {
+		return 0, "", trace.ConvertSystemError(err)
+	}
+	if err := os.Rename(tmpPath, targetPath); err != nil {
+		return 0, "", trace.ConvertSystemError(err)
+	}
+	return size, fmt.Sprintf("%x", hash.Sum(nil)), nil
+}
+
+// copySignature copies the signature file from path to targetPath if it exists.
+// A stale signature at targetPath is removed otherwise.
+// Returns true if the signature has been copied
+func copySignature(path, targetPath string) (bool, error) {
+	signature, err := ioutil.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return false, trace.ConvertSystemError(err)
+		}
+		if err := os.Remove(targetPath); err != nil && !os.IsNotExist(err) {
+			return false, trace.ConvertSystemError(err)
+		}
+		return false, nil
+	}
+	if err := writeFile(targetPath, signature); err != nil {
+		return false, trace.Wrap(err)
+	}
+	return true, nil
+}
+
+// readIndexFile returns the index file of the hub in the specified directory.
+// Returns a new empty index if the hub does not have one yet
+func readIndexFile(dir string) (*repo.IndexFile, error) {
+	h, err := NewDir(DirConfig{Dir: dir})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	indexFile, err := h.getIndexFile()
+	if err != nil && !trace.IsNotFound(err) {
+		return nil, trace.Wrap(err)
+	}
+	if indexFile == nil {
+		return repo.NewIndexFile(), nil
+	}
+	if indexFile.APIVersion == "" {
+		indexFile.APIVersion = repo.APIVersionV1
+	}
+	if indexFile.Entries == nil {
+		indexFile.Entries = make(map[string]repo.ChartVersions)
+	}
+	return indexFile, nil
+}
+
+// removeIndexEntry removes the entry with the specified name and version
+// from the index
+func removeIndexEntry(indexFile *repo.IndexFile, name, version string) {
+	var versions repo.ChartVersions
+	for _, entry := range indexFile.Entries[name] {
+		if entry.Version != version {
+			versions = append(versions, entry)
+		}
+	}
+	indexFile.Entries[name] = versions
+}
+
+// writeFile atomically replaces the contents of the file at path with data
+func writeFile(path string, data []byte) error {
+	tmpPath := path + ".tmp"
+	if err := ioutil.WriteFile(tmpPath, data, defaults.SharedReadMask); err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return trace.ConvertSystemError(err)
+	}
+	return nil
+}
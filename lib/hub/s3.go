@@ -0,0 +1,135 @@
+/*
+Copyright 2018 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hub
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/utils"
+
+	"github.com/gravitational/trace"
+	"github.com/sirupsen/logrus"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+)
+
+// Config is the S3-backed hub configuration
+type Config struct {
+	// Bucket is the S3 bucket name
+	Bucket string
+	// Prefix is the S3 path prefix
+	Prefix string
+	// Region is the S3 region
+	Region string
+	// FieldLogger is used for logging
+	logrus.FieldLogger
+	// S3 is optional S3 API client
+	S3 s3iface.S3API
+}
+
+// CheckAndSetDefaults validates config and sets defaults
+func (c *Config) CheckAndSetDefaults() error {
+	if c.Bucket == "" {
+		c.Bucket = defaults.HubBucket
+	}
+	if c.Prefix == "" {
+		c.Prefix = defaults.HubTelekubePrefix
+	}
+	if c.Region == "" {
+		c.Region = defaults.AWSRegion
+	}
+	if c.FieldLogger == nil {
+		c.FieldLogger = logrus.WithField(trace.Component, "s3hub")
+	}
+	if c.S3 == nil {
+		session, err := session.NewSession(&aws.Config{
+			Region:      aws.String(c.Region),
+			Credentials: credentials.AnonymousCredentials,
+		})
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		c.S3 = s3.New(session)
+	}
+	return nil
+}
+
+// New returns a new S3-backed hub instance
+func New(config Config) (*hub, error) {
+	err := config.CheckAndSetDefaults()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &hub{
+		FieldLogger: config.FieldLogger,
+		store: &s3Store{
+			Config:     config,
+			downloader: s3manager.NewDownloaderWithClient(config.S3),
+		},
+	}, nil
+}
+
+// s3Store provides access to the hub files in an S3 bucket
+type s3Store struct {
+	// Config is the hub configuration
+	Config
+	// downloader is the S3 download manager
+	downloader *s3manager.Downloader
+}
+
+// open returns the contents of the file with the specified path
+func (s *s3Store) open(relpath string) (io.ReadCloser, error) {
+	object, err := s.S3.GetObject(&s3.GetObjectInput{
+		Bucket: aws.String(s.Bucket),
+		Key:    aws.String(s.key(relpath)),
+	})
+	if err != nil {
+		return nil, trace.Wrap(utils.ConvertS3Error(err))
+	}
+	return object.Body, nil
+}
+
+// download downloads the file with the specified path into f
+func (s *s3Store) download(f *os.File, relpath string) (int64, error) {
+	n, err := s.downloader.Download(f, &s3.GetObjectInput{
+		Bucket: aws.String(s.Bucket),
+		Key:    aws.String(s.key(relpath)),
+	})
+	if err != nil {
+		return 0, trace.Wrap(utils.ConvertS3Error(err))
+	}
+	return n, nil
+}
+
+// String returns the bucket location
+func (s *s3Store) String() string {
+	return fmt.Sprintf("s3://%v/%v", s.Bucket, s.Prefix)
+}
+
+// key returns the S3 object key for the specified path
+func (s *s3Store) key(relpath string) string {
+	return path.Join(s.Prefix, relpath)
+}
@@ -0,0 +1,198 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hub
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/gravitational/trace"
+	"github.com/sirupsen/logrus"
+)
+
+// DirConfig is the configuration of a hub in a local directory
+type DirConfig struct {
+	// Dir is the hub root directory
+	Dir string
+	// FieldLogger is used for logging
+	logrus.FieldLogger
+}
+
+// CheckAndSetDefaults validates config and sets defaults
+func (c *DirConfig) CheckAndSetDefaults() error {
+	if c.Dir == "" {
+		return trace.BadParameter("missing parameter Dir")
+	}
+	if c.FieldLogger == nil {
+		c.FieldLogger = logrus.WithField(trace.Component, "dirhub")
+	}
+	return nil
+}
+
+// NewDir returns a new hub that serves installers from a local directory
+// with the hub layout
+func NewDir(config DirConfig) (*hub, error) {
+	if err := config.CheckAndSetDefaults(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &hub{
+		FieldLogger: config.FieldLogger,
+		store:       dirStore{dir: config.Dir},
+	}, nil
+}
+
+// HTTPConfig is the configuration of a hub served by a static HTTP server
+type HTTPConfig struct {
+	// URL is the URL of the hub root
+	URL string
+	// Client is an optional HTTP client
+	Client *http.Client
+	// FieldLogger is used for logging
+	logrus.FieldLogger
+}
+
+// CheckAndSetDefaults validates config and sets defaults
+func (c *HTTPConfig) CheckAndSetDefaults() error {
+	if c.URL == "" {
+		return trace.BadParameter("missing parameter URL")
+	}
+	if c.Client == nil {
+		c.Client = http.DefaultClient
+	}
+	if c.FieldLogger == nil {
+		c.FieldLogger = logrus.WithField(trace.Component, "httphub")
+	}
+	return nil
+}
+
+// NewHTTP returns a new hub that serves installers from a static HTTP
+// server, for example nginx or a generic Artifactory repository,
+// that hosts files with the hub layout
+func NewHTTP(config HTTPConfig) (*hub, error) {
+	if err := config.CheckAndSetDefaults(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	baseURL, err := url.Parse(config.URL)
+	if err != nil {
+		return nil, trace.Wrap(err, "invalid hub URL %q", config.URL)
+	}
+	return &hub{
+		FieldLogger: config.FieldLogger,
+		store: httpStore{
+			baseURL: *baseURL,
+			client:  config.Client,
+		},
+	}, nil
+}
+
+// dirStore provides access to the hub files in a local directory
+type dirStore struct {
+	dir string
+}
+
+// open returns the contents of the file with the specified path
+func (s dirStore) open(relpath string) (io.ReadCloser, error) {
+	f, err := os.Open(s.path(relpath))
+	if err != nil {
+		return nil, trace.ConvertSystemError(err)
+	}
+	return f, nil
+}
+
+// download copies the file with the specified path into f
+func (s dirStore) download(f *os.File, relpath string) (int64, error) {
+	rc, err := s.open(relpath)
+	if err != nil {
+		return 0, trace.Wrap(err)
+	}
+	defer rc.Close()
+	n, err := io.Copy(f, rc)
+	if err != nil {
+		return n, trace.ConvertSystemError(err)
+	}
+	return n, nil
+}
+
+// String returns the hub directory
+func (s dirStore) String() string {
+	return s.dir
+}
+
+func (s dirStore) path(relpath string) string {
+	return filepath.Join(s.dir, filepath.FromSlash(relpath))
+}
+
+// httpStore provides access to the hub files on a static HTTP server
+type httpStore struct {
+	baseURL url.URL
+	client  *http.Client
+}
+
+// open returns the contents of the file with the specified path
+func (s httpStore) open(relpath string) (io.ReadCloser, error) {
+	fileURL := s.url(relpath)
+	resp, err := s.client.Get(fileURL)
+	if err != nil {
+		return nil, trace.ConvertSystemError(err)
+	}
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		resp.Body.Close()
+		return nil, trace.NotFound("%v not found", fileURL)
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+		resp.Body.Close()
+		return nil, trace.BadParameter("failed to fetch %v: %v %s",
+			fileURL, resp.Status, body)
+	}
+	return resp.Body, nil
+}
+
+// download downloads the file with the specified path into f
+func (s httpStore) download(f *os.File, relpath string) (int64, error) {
+	rc, err := s.open(relpath)
+	if err != nil {
+		return 0, trace.Wrap(err)
+	}
+	defer rc.Close()
+	n, err := io.Copy(f, rc)
+	if err != nil {
+		return n, trace.Wrap(err, "failed to download %v", s.url(relpath))
+	}
+	return n, nil
+}
+
+// String returns the hub URL
+func (s httpStore) String() string {
+	return s.baseURL.String()
+}
+
+// url returns the URL of the file with the specified path
+func (s httpStore) url(relpath string) string {
+	u := s.baseURL
+	u.Path = path.Join("/", u.Path, relpath)
+	return u.String()
+}
+
+// maxErrorBodySize limits the amount of the response body
+// included into the error message
+const maxErrorBodySize = 512
@@ -0,0 +1,139 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hub
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/gravitational/gravity/lib/archive"
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/loc"
+	"github.com/gravitational/gravity/lib/schema"
+
+	"github.com/gravitational/trace"
+	check "gopkg.in/check.v1"
+)
+
+type StaticHubSuite struct {
+	dir string
+}
+
+var _ = check.Suite(&StaticHubSuite{})
+
+func (s *StaticHubSuite) SetUpTest(c *check.C) {
+	s.dir = c.MkDir()
+	s.publish(c, "app", "1.0.0", schema.KindApplication)
+	s.publish(c, "app", "2.0.0-beta.1", schema.KindApplication)
+	s.publish(c, "cluster", "1.0.0", schema.KindCluster)
+}
+
+func (s *StaticHubSuite) TestDirHub(c *check.C) {
+	hub, err := NewForURL(s.dir, false)
+	c.Assert(err, check.IsNil)
+	s.assertHub(c, hub)
+}
+
+func (s *StaticHubSuite) TestHTTPHub(c *check.C) {
+	server := httptest.NewServer(http.StripPrefix("/hub/", http.FileServer(http.Dir(s.dir))))
+	defer server.Close()
+	hub, err := NewForURL(server.URL+"/hub", false)
+	c.Assert(err, check.IsNil)
+	s.assertHub(c, hub)
+
+	_, err = hub.Get(loc.Locator{Name: "app", Version: "3.0.0"})
+	c.Assert(trace.IsNotFound(err), check.Equals, true, check.Commentf("%v", err))
+}
+
+func (s *StaticHubSuite) TestRepublishReplacesEntry(c *check.C) {
+	s.publish(c, "app", "1.0.0", schema.KindCluster)
+	hub, err := NewDir(DirConfig{Dir: s.dir})
+	c.Assert(err, check.IsNil)
+	apps, err := hub.List(true)
+	c.Assert(err, check.IsNil)
+	c.Assert(apps, check.HasLen, 3)
+	app := s.download(c, hub, "app", "1.0.0")
+	c.Assert(string(app), check.Matches, "(?s).*kind: Cluster.*")
+}
+
+func (s *StaticHubSuite) TestDetectsChecksumMismatch(c *check.C) {
+	path := filepath.Join(s.dir, filepath.FromSlash(appPath("app", "1.0.0")))
+	c.Assert(ioutil.WriteFile(path, []byte("tampered"), defaults.SharedReadMask), check.IsNil)
+	hub, err := NewDir(DirConfig{Dir: s.dir})
+	c.Assert(err, check.IsNil)
+	_, err = hub.Get(loc.Locator{Name: "app", Version: "1.0.0"})
+	c.Assert(err, check.ErrorMatches, "(?s).*checksum mismatch.*")
+}
+
+func (s *StaticHubSuite) assertHub(c *check.C, hub Hub) {
+	apps, err := hub.List(true)
+	c.Assert(err, check.IsNil)
+	for i := range apps {
+		c.Assert(apps[i].Created.IsZero(), check.Equals, false)
+		apps[i].Created = time.Time{}
+	}
+	c.Assert(apps, check.DeepEquals, []App{
+		{Name: "app", Version: "2.0.0-beta.1", Description: "app 2.0.0-beta.1", Type: schema.KindApplication},
+		{Name: "app", Version: "1.0.0", Description: "app 1.0.0", Type: schema.KindApplication},
+		{Name: "cluster", Version: "1.0.0", Description: "cluster 1.0.0", Type: schema.KindCluster},
+	})
+
+	version, err := hub.GetLatestVersion("app")
+	c.Assert(err, check.IsNil)
+	c.Assert(version, check.Equals, "2.0.0-beta.1")
+
+	c.Assert(string(s.download(c, hub, "app", loc.StableVersion)), check.Matches, "(?s).*resourceVersion: 1.0.0.*")
+	c.Assert(string(s.download(c, hub, "app", loc.LatestVersion)), check.Matches, "(?s).*resourceVersion: 2.0.0-beta.1.*")
+}
+
+func (s *StaticHubSuite) download(c *check.C, hub Hub, name, version string) []byte {
+	reader, err := hub.Get(loc.Locator{Repository: defaults.SystemAccountOrg, Name: name, Version: version})
+	c.Assert(err, check.IsNil)
+	defer reader.Close()
+	bytes, err := ioutil.ReadAll(reader)
+	c.Assert(err, check.IsNil)
+	return bytes
+}
+
+// publish publishes an installer with the specified application manifest
+// into the hub directory. The installer consists of the manifest only
+func (s *StaticHubSuite) publish(c *check.C, name, version, kind string) {
+	manifest := fmt.Sprintf(`apiVersion: bundle.gravitational.io/v2
+kind: %v
+metadata:
+  name: %v
+  resourceVersion: %v
+  description: %v %v
+`, kind, name, version, name, version)
+	installer := archive.MustCreateMemArchive([]*archive.Item{
+		archive.ItemFromString(defaults.ManifestFileName, manifest),
+	})
+	path := filepath.Join(c.MkDir(), "installer.tar")
+	c.Assert(ioutil.WriteFile(path, installer.Bytes(), defaults.SharedReadMask), check.IsNil)
+	app, err := Publish(PublishConfig{Dir: s.dir, Path: path})
+	c.Assert(err, check.IsNil)
+	c.Assert(app.Name, check.Equals, name)
+	c.Assert(app.Version, check.Equals, version)
+	c.Assert(app.SizeBytes, check.Equals, int64(installer.Len()))
+	_, err = os.Stat(filepath.Join(s.dir, filepath.FromSlash(shaPath(name, version))))
+	c.Assert(err, check.IsNil)
+}
@@ -33,7 +33,6 @@ import (
 	"github.com/gravitational/gravity/lib/utils"
 
 	"github.com/gravitational/trace"
-	"github.com/pborman/uuid"
 	"github.com/sirupsen/logrus"
 	"google.golang.org/grpc/credentials"
 )
@@ -82,6 +81,9 @@ type FSMConfig struct {
 	ReportProgress bool
 	// DNSConfig specifies the DNS configuration to use
 	DNSConfig storage.DNSConfig
+	// ParallelPerServer optionally enables concurrent plan execution
+	// with the specified number of phases executing per server
+	ParallelPerServer int
 }
 
 // Check validates install FSM config and sets some defaults
@@ -148,10 +150,11 @@ func NewFSM(config FSMConfig) (*fsm.FSM, error) {
 	}
 	runner := fsm.NewAgentRunner(config.Credentials)
 	fsm, err := fsm.New(fsm.Config{
-		Engine:   engine,
-		Runner:   runner,
-		Insecure: config.Insecure,
-		Logger:   logger,
+		Engine:            engine,
+		Runner:            runner,
+		Insecure:          config.Insecure,
+		Logger:            logger,
+		ParallelPerServer: config.ParallelPerServer,
 	})
 	if err != nil {
 		return nil, trace.Wrap(err)
@@ -214,15 +217,7 @@ func (f *fsmEngine) Complete(fsmErr error) error {
 // ChangePhaseState creates an operation plan changelog entry
 func (f *fsmEngine) ChangePhaseState(ctx context.Context, change fsm.StateChange) error {
 	err := f.Operator.CreateOperationPlanChange(f.operation.Key(),
-		storage.PlanChange{
-			ID:          uuid.New(),
-			ClusterName: f.operation.SiteDomain,
-			OperationID: f.operation.ID,
-			PhaseID:     change.Phase,
-			NewState:    change.State,
-			Error:       utils.ToRawTrace(change.Error),
-			Created:     time.Now().UTC(),
-		})
+		fsm.NewPlanChange(f.operation.SiteDomain, f.operation.ID, change))
 	if err != nil {
 		return trace.Wrap(err)
 	}
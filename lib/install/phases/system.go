@@ -18,10 +18,13 @@ package phases
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/gravitational/gravity/lib/constants"
 	"github.com/gravitational/gravity/lib/fsm"
+	"github.com/gravitational/gravity/lib/loc"
 	"github.com/gravitational/gravity/lib/ops"
+	"github.com/gravitational/gravity/lib/systemservice"
 	"github.com/gravitational/gravity/lib/utils"
 
 	"github.com/gravitational/configure"
@@ -72,6 +75,18 @@ func (p *systemExecutor) Execute(ctx context.Context) error {
 	return trace.Wrap(err, "failed to install system service: %s", string(out))
 }
 
+// Describe returns the description of changes this phase makes
+func (p *systemExecutor) Describe(ctx context.Context) (*fsm.PhaseDescription, error) {
+	locator := *p.Phase.Data.Package
+	return &fsm.PhaseDescription{
+		Servers:  []string{p.Phase.Data.Server.Hostname},
+		Packages: []loc.Locator{locator},
+		Units:    []string{systemservice.PackageServiceName(locator)},
+		Actions: []string{fmt.Sprintf("Install system service %v:%v",
+			locator.Name, locator.Version)},
+	}, nil
+}
+
 // Rollback is no-op for this phase
 func (*systemExecutor) Rollback(ctx context.Context) error {
 	return nil
@@ -104,6 +104,8 @@ type LocalEnvironment struct {
 
 	// Backend is the local backend client
 	Backend storage.Backend
+	// BackendType is the type of the local backend
+	BackendType string
 	// Objects is the local objects storage client
 	Objects blob.Objects
 	// Packages is the local package service
@@ -172,6 +174,7 @@ func (env *LocalEnvironment) init() error {
 	if err != nil {
 		return trace.Wrap(err)
 	}
+	env.BackendType = constants.BoltBackend
 
 	if env.DNS.IsEmpty() {
 		dns, err := storage.GetDNSConfig(env.Backend, storage.LegacyDNSConfig)
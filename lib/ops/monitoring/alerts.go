@@ -0,0 +1,465 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package monitoring
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/storage"
+
+	"github.com/gravitational/trace"
+	"github.com/sirupsen/logrus"
+)
+
+// AlertEvent describes an alert triggered by the monitoring system
+type AlertEvent struct {
+	// Name is the name of the alert
+	Name string `json:"name"`
+	// ID identifies the alert event, events of the same alert
+	// for the same group share the ID
+	ID string `json:"id"`
+	// Message is the alert message
+	Message string `json:"message"`
+	// Details optionally provides details about the alert
+	Details string `json:"details,omitempty"`
+	// Level is the alert level: OK, INFO, WARNING or CRITICAL
+	Level string `json:"level"`
+	// Time is the time of the event
+	Time time.Time `json:"time"`
+	// Labels are the labels (tags) of the alert
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// Resolved returns true if this event resolves a previously triggered alert
+func (r AlertEvent) Resolved() bool {
+	return r.Level == AlertLevelOK
+}
+
+// DispatchConfig describes configuration to dispatch an alert event
+type DispatchConfig struct {
+	// Targets lists the configured alert targets
+	Targets []storage.AlertTarget
+	// Client is the HTTP client to send alerts with
+	Client *http.Client
+	// FieldLogger is used for logging
+	logrus.FieldLogger
+}
+
+func (r *DispatchConfig) checkAndSetDefaults() {
+	if r.Client == nil {
+		r.Client = &http.Client{Timeout: defaults.AlertTargetTimeout}
+	}
+	if r.FieldLogger == nil {
+		r.FieldLogger = logrus.WithField(trace.Component, "alerts")
+	}
+}
+
+// Dispatch sends the alert event to each alert target that matches it.
+// Email targets are skipped as emails are sent by the monitoring system itself.
+// Returns the aggregated error of the targets that failed to receive the alert
+func Dispatch(ctx context.Context, config DispatchConfig, event AlertEvent) error {
+	config.checkAndSetDefaults()
+	var errors []error
+	for _, target := range config.Targets {
+		if target.GetTargetType() == storage.AlertTargetEmail {
+			continue
+		}
+		if !target.Matches(event.Name, event.Labels) {
+			continue
+		}
+		logger := config.WithFields(logrus.Fields{
+			"target": target.GetName(),
+			"alert":  event.Name,
+		})
+		notifier, err := newNotifier(target, config.Client)
+		if err != nil {
+			logger.WithError(err).Warn("Invalid alert target.")
+			errors = append(errors, trace.Wrap(err, "invalid alert target %v", target.GetName()))
+			continue
+		}
+		if err := notifier.Notify(ctx, event); err != nil {
+			logger.WithError(err).Warn("Failed to send alert.")
+			errors = append(errors, trace.Wrap(err, "failed to send alert to %v", target.GetName()))
+			continue
+		}
+		logger.Debug("Sent alert.")
+	}
+	return trace.NewAggregate(errors...)
+}
+
+// Notifier sends alert events to an alert target
+type Notifier interface {
+	// Notify sends the alert event to the target
+	Notify(context.Context, AlertEvent) error
+}
+
+func newNotifier(target storage.AlertTarget, client *http.Client) (Notifier, error) {
+	spec, ok := target.(*storage.AlertTargetV3)
+	if !ok {
+		return nil, trace.BadParameter("unsupported alert target %T", target)
+	}
+	switch {
+	case spec.Spec.Webhook != nil:
+		return newWebhookNotifier(*spec.Spec.Webhook, client)
+	case spec.Spec.Slack != nil:
+		return &slackNotifier{target: *spec.Spec.Slack, client: client}, nil
+	case spec.Spec.PagerDuty != nil:
+		return &pagerDutyNotifier{target: *spec.Spec.PagerDuty, client: client}, nil
+	case spec.Spec.OpsGenie != nil:
+		return &opsGenieNotifier{target: *spec.Spec.OpsGenie, client: client}, nil
+	}
+	return nil, trace.BadParameter("alert target %v has no type", target.GetName())
+}
+
+func newWebhookNotifier(target storage.WebhookTarget, client *http.Client) (*webhookNotifier, error) {
+	notifier := &webhookNotifier{target: target, client: client}
+	if target.Body != "" {
+		tpl, err := template.New("body").Parse(target.Body)
+		if err != nil {
+			return nil, trace.BadParameter("invalid webhook body template: %v", err)
+		}
+		notifier.body = tpl
+	}
+	return notifier, nil
+}
+
+// Notify sends the alert event to the webhook.
+// The request body is rendered from the body template with the event,
+// or is the JSON-encoded event if no template has been specified
+func (r *webhookNotifier) Notify(ctx context.Context, event AlertEvent) error {
+	var body bytes.Buffer
+	if r.body != nil {
+		if err := r.body.Execute(&body, event); err != nil {
+			return trace.Wrap(err, "failed to render webhook body")
+		}
+	} else {
+		if err := json.NewEncoder(&body).Encode(event); err != nil {
+			return trace.Wrap(err)
+		}
+	}
+	method := r.target.Method
+	if method == "" {
+		method = http.MethodPost
+	}
+	req, err := http.NewRequest(method, r.target.URL, bytes.NewReader(body.Bytes()))
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range r.target.Headers {
+		req.Header.Set(key, value)
+	}
+	if r.target.Secret != "" {
+		req.Header.Set(WebhookSignatureHeader, "sha256="+Sign(body.Bytes(), r.target.Secret))
+	}
+	return trace.Wrap(send(ctx, r.client, req))
+}
+
+// Sign returns the hex-encoded HMAC-SHA256 signature of data with the specified secret
+func Sign(data []byte, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(data)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Notify posts the alert event as a message to the Slack incoming webhook
+func (r *slackNotifier) Notify(ctx context.Context, event AlertEvent) error {
+	fields := make([]slackField, 0, len(event.Labels))
+	for _, key := range sortedKeys(event.Labels) {
+		fields = append(fields, slackField{Title: key, Value: event.Labels[key], Short: true})
+	}
+	text := event.Details
+	if text == "" {
+		text = event.Message
+	}
+	message := slackMessage{
+		Channel:  r.target.Channel,
+		Username: r.target.Username,
+		Text:     fmt.Sprintf("[%v] %v", event.Level, event.Name),
+		Attachments: []slackAttachment{{
+			Color:     slackColor(event.Level),
+			Title:     event.Message,
+			Text:      text,
+			Fields:    fields,
+			Timestamp: event.Time.Unix(),
+		}},
+	}
+	req, err := newJSONRequest(r.target.URL, message)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return trace.Wrap(send(ctx, r.client, req))
+}
+
+// Notify triggers or resolves the PagerDuty incident for the alert event
+// using the Events API v2
+func (r *pagerDutyNotifier) Notify(ctx context.Context, event AlertEvent) error {
+	pdEvent := pagerDutyEvent{
+		RoutingKey:  r.target.RoutingKey,
+		EventAction: "trigger",
+		DedupKey:    event.ID,
+	}
+	if event.Resolved() {
+		pdEvent.EventAction = "resolve"
+	} else {
+		source := event.Labels["host"]
+		if source == "" {
+			source = defaults.SystemAccountOrg
+		}
+		pdEvent.Payload = &pagerDutyPayload{
+			Summary:   event.Message,
+			Source:    source,
+			Severity:  pagerDutySeverity(event.Level),
+			Timestamp: event.Time.Format(time.RFC3339),
+			Component: event.Name,
+			CustomDetails: map[string]interface{}{
+				"details": event.Details,
+				"labels":  event.Labels,
+			},
+		}
+	}
+	req, err := newJSONRequest(r.target.URL, pdEvent)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return trace.Wrap(send(ctx, r.client, req))
+}
+
+// Notify creates or closes the OpsGenie alert for the alert event
+func (r *opsGenieNotifier) Notify(ctx context.Context, event AlertEvent) error {
+	var req *http.Request
+	var err error
+	if event.Resolved() {
+		req, err = newJSONRequest(fmt.Sprintf("%v/%v/close?identifierType=alias",
+			strings.TrimSuffix(r.target.URL, "/"), url.PathEscape(event.ID)),
+			opsGenieClose{Source: defaults.SystemAccountOrg})
+	} else {
+		alert := opsGenieAlert{
+			Message:     truncate(event.Message, opsGenieMaxMessageLength),
+			Alias:       event.ID,
+			Description: event.Details,
+			Priority:    opsGeniePriority(event.Level),
+			Source:      defaults.SystemAccountOrg,
+			Details:     event.Labels,
+		}
+		if alert.Message == "" {
+			alert.Message = event.Name
+		}
+		for _, team := range r.target.Teams {
+			alert.Responders = append(alert.Responders, opsGenieResponder{Name: team, Type: "team"})
+		}
+		for _, key := range sortedKeys(event.Labels) {
+			alert.Tags = append(alert.Tags, fmt.Sprintf("%v:%v", key, event.Labels[key]))
+		}
+		req, err = newJSONRequest(r.target.URL, alert)
+	}
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	req.Header.Set("Authorization", "GenieKey "+r.target.APIKey)
+	return trace.Wrap(send(ctx, r.client, req))
+}
+
+func newJSONRequest(url string, message interface{}) (*http.Request, error) {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return req, nil
+}
+
+func send(ctx context.Context, client *http.Client, req *http.Request) error {
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+		return trace.BadParameter("%v responded with %v: %s", req.URL.Host, resp.Status, bytes.TrimSpace(body))
+	}
+	return nil
+}
+
+func slackColor(level string) string {
+	switch level {
+	case AlertLevelOK:
+		return "good"
+	case AlertLevelCritical:
+		return "danger"
+	case AlertLevelWarning:
+		return "warning"
+	default:
+		return "#439fe0"
+	}
+}
+
+func pagerDutySeverity(level string) string {
+	switch level {
+	case AlertLevelCritical:
+		return "critical"
+	case AlertLevelWarning:
+		return "warning"
+	default:
+		return "info"
+	}
+}
+
+func opsGeniePriority(level string) string {
+	switch level {
+	case AlertLevelCritical:
+		return "P1"
+	case AlertLevelWarning:
+		return "P3"
+	default:
+		return "P5"
+	}
+}
+
+func sortedKeys(labels map[string]string) []string {
+	keys := make([]string, 0, len(labels))
+	for key := range labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func truncate(s string, length int) string {
+	if len(s) <= length {
+		return s
+	}
+	return s[:length]
+}
+
+type webhookNotifier struct {
+	target storage.WebhookTarget
+	client *http.Client
+	body   *template.Template
+}
+
+type slackNotifier struct {
+	target storage.SlackTarget
+	client *http.Client
+}
+
+type pagerDutyNotifier struct {
+	target storage.PagerDutyTarget
+	client *http.Client
+}
+
+type opsGenieNotifier struct {
+	target storage.OpsGenieTarget
+	client *http.Client
+}
+
+type slackMessage struct {
+	Channel     string            `json:"channel,omitempty"`
+	Username    string            `json:"username,omitempty"`
+	Text        string            `json:"text"`
+	Attachments []slackAttachment `json:"attachments"`
+}
+
+type slackAttachment struct {
+	Color     string       `json:"color"`
+	Title     string       `json:"title"`
+	Text      string       `json:"text"`
+	Fields    []slackField `json:"fields,omitempty"`
+	Timestamp int64        `json:"ts"`
+}
+
+type slackField struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+	Short bool   `json:"short"`
+}
+
+type pagerDutyEvent struct {
+	RoutingKey  string            `json:"routing_key"`
+	EventAction string            `json:"event_action"`
+	DedupKey    string            `json:"dedup_key"`
+	Payload     *pagerDutyPayload `json:"payload,omitempty"`
+}
+
+type pagerDutyPayload struct {
+	Summary       string                 `json:"summary"`
+	Source        string                 `json:"source"`
+	Severity      string                 `json:"severity"`
+	Timestamp     string                 `json:"timestamp"`
+	Component     string                 `json:"component"`
+	CustomDetails map[string]interface{} `json:"custom_details"`
+}
+
+type opsGenieAlert struct {
+	Message     string              `json:"message"`
+	Alias       string              `json:"alias"`
+	Description string              `json:"description,omitempty"`
+	Priority    string              `json:"priority"`
+	Source      string              `json:"source"`
+	Responders  []opsGenieResponder `json:"responders,omitempty"`
+	Tags        []string            `json:"tags,omitempty"`
+	Details     map[string]string   `json:"details,omitempty"`
+}
+
+type opsGenieResponder struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+type opsGenieClose struct {
+	Source string `json:"source"`
+}
+
+const (
+	// AlertLevelOK is the level of a resolved alert
+	AlertLevelOK = "OK"
+	// AlertLevelInfo is the level of an informational alert
+	AlertLevelInfo = "INFO"
+	// AlertLevelWarning is the level of a warning alert
+	AlertLevelWarning = "WARNING"
+	// AlertLevelCritical is the level of a critical alert
+	AlertLevelCritical = "CRITICAL"
+
+	// WebhookSignatureHeader is the request header with the signature
+	// of the webhook request body
+	WebhookSignatureHeader = "X-Gravity-Signature"
+
+	// opsGenieMaxMessageLength is the maximum length of the OpsGenie alert message
+	opsGenieMaxMessageLength = 130
+	// maxErrorBodySize limits the size of the error response included into errors
+	maxErrorBodySize = 1024
+)
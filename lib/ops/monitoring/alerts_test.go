@@ -0,0 +1,188 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package monitoring
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gravitational/gravity/lib/storage"
+
+	teleservices "github.com/gravitational/teleport/lib/services"
+	. "gopkg.in/check.v1"
+)
+
+func TestMonitoring(t *testing.T) { TestingT(t) }
+
+type AlertsSuite struct {
+	server   *httptest.Server
+	requests chan *capturedRequest
+}
+
+var _ = Suite(&AlertsSuite{})
+
+func (s *AlertsSuite) SetUpTest(c *C) {
+	s.requests = make(chan *capturedRequest, 10)
+	s.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		c.Assert(err, IsNil)
+		s.requests <- &capturedRequest{method: r.Method, path: r.URL.RequestURI(), header: r.Header, body: body}
+	}))
+}
+
+func (s *AlertsSuite) TearDownTest(c *C) {
+	s.server.Close()
+}
+
+func (s *AlertsSuite) TestSignsWebhook(c *C) {
+	target := newTarget("hook", storage.AlertTargetSpecV3{
+		Webhook: &storage.WebhookTarget{
+			URL:     s.server.URL + "/hook",
+			Headers: map[string]string{"X-Token": "token"},
+			Body:    `{"text": "{{.Level}}: {{.Message}}"}`,
+			Secret:  "s3cr3t",
+		},
+	})
+	err := Dispatch(context.TODO(), DispatchConfig{Targets: []storage.AlertTarget{target}}, testEvent)
+	c.Assert(err, IsNil)
+
+	req := <-s.requests
+	c.Assert(req.method, Equals, http.MethodPost)
+	c.Assert(req.path, Equals, "/hook")
+	c.Assert(string(req.body), Equals, `{"text": "CRITICAL: CPU usage is high"}`)
+	c.Assert(req.header.Get("X-Token"), Equals, "token")
+	c.Assert(req.header.Get(WebhookSignatureHeader), Equals, "sha256="+Sign(req.body, "s3cr3t"))
+}
+
+func (s *AlertsSuite) TestSendsPagerDutyEvents(c *C) {
+	target := newTarget("oncall", storage.AlertTargetSpecV3{
+		PagerDuty: &storage.PagerDutyTarget{RoutingKey: "key", URL: s.server.URL + "/v2/enqueue"},
+	})
+	config := DispatchConfig{Targets: []storage.AlertTarget{target}}
+	c.Assert(Dispatch(context.TODO(), config, testEvent), IsNil)
+
+	var event pagerDutyEvent
+	c.Assert(json.Unmarshal((<-s.requests).body, &event), IsNil)
+	c.Assert(event.RoutingKey, Equals, "key")
+	c.Assert(event.EventAction, Equals, "trigger")
+	c.Assert(event.DedupKey, Equals, testEvent.ID)
+	c.Assert(event.Payload.Severity, Equals, "critical")
+	c.Assert(event.Payload.Source, Equals, "node-1")
+
+	resolved := testEvent
+	resolved.Level = AlertLevelOK
+	c.Assert(Dispatch(context.TODO(), config, resolved), IsNil)
+	event = pagerDutyEvent{}
+	c.Assert(json.Unmarshal((<-s.requests).body, &event), IsNil)
+	c.Assert(event.EventAction, Equals, "resolve")
+	c.Assert(event.DedupKey, Equals, testEvent.ID)
+	c.Assert(event.Payload, IsNil)
+}
+
+func (s *AlertsSuite) TestSendsOpsGenieAlerts(c *C) {
+	target := newTarget("genie", storage.AlertTargetSpecV3{
+		OpsGenie: &storage.OpsGenieTarget{APIKey: "key", URL: s.server.URL + "/v2/alerts", Teams: []string{"ops"}},
+	})
+	config := DispatchConfig{Targets: []storage.AlertTarget{target}}
+	c.Assert(Dispatch(context.TODO(), config, testEvent), IsNil)
+
+	req := <-s.requests
+	c.Assert(req.path, Equals, "/v2/alerts")
+	c.Assert(req.header.Get("Authorization"), Equals, "GenieKey key")
+	var alert opsGenieAlert
+	c.Assert(json.Unmarshal(req.body, &alert), IsNil)
+	c.Assert(alert.Alias, Equals, testEvent.ID)
+	c.Assert(alert.Priority, Equals, "P1")
+	c.Assert(alert.Responders, DeepEquals, []opsGenieResponder{{Name: "ops", Type: "team"}})
+
+	resolved := testEvent
+	resolved.Level = AlertLevelOK
+	c.Assert(Dispatch(context.TODO(), config, resolved), IsNil)
+	req = <-s.requests
+	c.Assert(req.path, Equals, "/v2/alerts/high-cpu:host=node-1/close?identifierType=alias")
+}
+
+func (s *AlertsSuite) TestRoutesAlerts(c *C) {
+	slack := newTarget("chat", storage.AlertTargetSpecV3{
+		Slack: &storage.SlackTarget{URL: s.server.URL + "/slack"},
+		Route: &storage.AlertRoute{Labels: map[string]string{"team": "db"}},
+	})
+	hook := newTarget("hook", storage.AlertTargetSpecV3{
+		Webhook: &storage.WebhookTarget{URL: s.server.URL + "/hook"},
+		Route:   &storage.AlertRoute{Alerts: []string{"high-cpu"}},
+	})
+	email := &storage.AlertTargetV2{Spec: storage.AlertTargetSpecV2{Email: "ops@example.com"}}
+	config := DispatchConfig{Targets: []storage.AlertTarget{email, slack, hook}}
+	c.Assert(Dispatch(context.TODO(), config, testEvent), IsNil)
+
+	req := <-s.requests
+	c.Assert(req.path, Equals, "/hook")
+	var event AlertEvent
+	c.Assert(json.Unmarshal(req.body, &event), IsNil)
+	c.Assert(event, DeepEquals, testEvent)
+	c.Assert(s.requests, HasLen, 0)
+}
+
+func (s *AlertsSuite) TestReportsFailedTargets(c *C) {
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "invalid routing key", http.StatusBadRequest)
+	}))
+	defer failing.Close()
+	config := DispatchConfig{Targets: []storage.AlertTarget{
+		newTarget("oncall", storage.AlertTargetSpecV3{
+			PagerDuty: &storage.PagerDutyTarget{RoutingKey: "key", URL: failing.URL},
+		}),
+		newTarget("hook", storage.AlertTargetSpecV3{
+			Webhook: &storage.WebhookTarget{URL: s.server.URL},
+		}),
+	}}
+	err := Dispatch(context.TODO(), config, testEvent)
+	c.Assert(err, NotNil)
+	c.Assert(err.Error(), Matches, "(?s).*invalid routing key.*oncall.*")
+	// The alert is still delivered to the other targets
+	c.Assert(<-s.requests, NotNil)
+}
+
+func newTarget(name string, spec storage.AlertTargetSpecV3) storage.AlertTarget {
+	return &storage.AlertTargetV3{
+		Kind:     storage.KindAlertTarget,
+		Version:  teleservices.V3,
+		Metadata: teleservices.Metadata{Name: name},
+		Spec:     spec,
+	}
+}
+
+type capturedRequest struct {
+	method string
+	path   string
+	header http.Header
+	body   []byte
+}
+
+var testEvent = AlertEvent{
+	Name:    "high-cpu",
+	ID:      "high-cpu:host=node-1",
+	Message: "CPU usage is high",
+	Level:   AlertLevelCritical,
+	Time:    time.Date(2019, 3, 1, 10, 0, 0, 0, time.UTC),
+	Labels:  map[string]string{"host": "node-1"},
+}
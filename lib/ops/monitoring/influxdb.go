@@ -24,6 +24,8 @@ import (
 	"time"
 
 	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/storage"
+
 	"github.com/gravitational/roundtrip"
 	"github.com/gravitational/teleport/lib/httplib"
 	"github.com/gravitational/trace"
@@ -144,3 +146,42 @@ var (
 	// updateQuery is InfluxDB query to update retention policy
 	updateQuery = "alter retention policy %v on k8s duration %vh"
 )
+
+// GetRemoteWrites returns the list of remote-write targets.
+// Not supported by InfluxDB
+func (i *influxDB) GetRemoteWrites() ([]storage.RemoteWrite, error) {
+	return nil, trace.NotImplemented(influxDBUnsupported, "remote-write targets")
+}
+
+// UpsertRemoteWrite creates or updates the remote-write target.
+// Not supported by InfluxDB
+func (i *influxDB) UpsertRemoteWrite(storage.RemoteWrite) error {
+	return trace.NotImplemented(influxDBUnsupported, "remote-write targets")
+}
+
+// DeleteRemoteWrite deletes the remote-write target specified with name.
+// Not supported by InfluxDB
+func (i *influxDB) DeleteRemoteWrite(name string) error {
+	return trace.NotImplemented(influxDBUnsupported, "remote-write targets")
+}
+
+// GetRecordingRules returns the list of recording rules.
+// Not supported by InfluxDB
+func (i *influxDB) GetRecordingRules() ([]storage.RecordingRule, error) {
+	return nil, trace.NotImplemented(influxDBUnsupported, "recording rules")
+}
+
+// UpsertRecordingRule creates or updates the recording rule.
+// Not supported by InfluxDB
+func (i *influxDB) UpsertRecordingRule(storage.RecordingRule) error {
+	return trace.NotImplemented(influxDBUnsupported, "recording rules")
+}
+
+// DeleteRecordingRule deletes the recording rule specified with name.
+// Not supported by InfluxDB
+func (i *influxDB) DeleteRecordingRule(name string) error {
+	return trace.NotImplemented(influxDBUnsupported, "recording rules")
+}
+
+// influxDBUnsupported is the error message for features InfluxDB does not provide
+const influxDBUnsupported = "%v are only supported by the Prometheus metrics backend"
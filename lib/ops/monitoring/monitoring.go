@@ -17,11 +17,15 @@ limitations under the License.
 package monitoring
 
 import (
+	"sync"
 	"time"
 
 	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/storage"
+
 	"github.com/gravitational/rigging"
 	"github.com/gravitational/trace"
+	"github.com/jonboulle/clockwork"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
@@ -33,6 +37,42 @@ type Monitoring interface {
 	GetRetentionPolicies() ([]RetentionPolicy, error)
 	// UpdateRetentionPolicy updates a retention policy
 	UpdateRetentionPolicy(RetentionPolicy) error
+	// GetRemoteWrites returns the list of remote-write targets
+	GetRemoteWrites() ([]storage.RemoteWrite, error)
+	// UpsertRemoteWrite creates or updates the remote-write target
+	UpsertRemoteWrite(storage.RemoteWrite) error
+	// DeleteRemoteWrite deletes the remote-write target specified with name
+	DeleteRemoteWrite(name string) error
+	// GetRecordingRules returns the list of recording rules
+	GetRecordingRules() ([]storage.RecordingRule, error)
+	// UpsertRecordingRule creates or updates the recording rule
+	UpsertRecordingRule(storage.RecordingRule) error
+	// DeleteRecordingRule deletes the recording rule specified with name
+	DeleteRecordingRule(name string) error
+}
+
+// NewProvider returns the monitoring provider for the metrics backend deployed
+// in the cluster: Prometheus if the monitoring namespace has the Prometheus service
+// and InfluxDB otherwise.
+//
+// The detected backend is cached for defaults.MonitoringBackendCacheTTL,
+// after which it is detected again since the monitoring application
+// can be installed or replaced after the provider has been created.
+// client can be nil outside of Kubernetes in which case InfluxDB is always used
+func NewProvider(client corev1.CoreV1Interface) (Monitoring, error) {
+	influxDB, err := NewInfluxDB()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	provider := &provider{
+		client:   client,
+		influxDB: influxDB,
+		clock:    clockwork.NewRealClock(),
+	}
+	if client != nil {
+		provider.prometheus = NewPrometheus(client)
+	}
+	return provider, nil
 }
 
 // RetentionPolicy represents a single retention policy
@@ -58,3 +98,123 @@ func GetNamespace(client corev1.CoreV1Interface) (string, error) {
 	}
 	return "", trace.NotFound("service %q was not found", defaults.GrafanaServiceName)
 }
+
+// provider dispatches to the monitoring provider of the metrics backend
+// deployed in the cluster
+type provider struct {
+	client     corev1.CoreV1Interface
+	influxDB   Monitoring
+	prometheus Monitoring
+	clock      clockwork.Clock
+
+	// mu guards the fields below
+	mu sync.Mutex
+	// cached is the last detected backend
+	cached Monitoring
+	// detected is the time the cached backend has been detected at
+	detected time.Time
+}
+
+// GetRetentionPolicies returns a list of retention policies
+func (r *provider) GetRetentionPolicies() ([]RetentionPolicy, error) {
+	backend, err := r.backend()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return backend.GetRetentionPolicies()
+}
+
+// UpdateRetentionPolicy updates a retention policy
+func (r *provider) UpdateRetentionPolicy(policy RetentionPolicy) error {
+	backend, err := r.backend()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return backend.UpdateRetentionPolicy(policy)
+}
+
+// GetRemoteWrites returns the list of remote-write targets
+func (r *provider) GetRemoteWrites() ([]storage.RemoteWrite, error) {
+	backend, err := r.backend()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return backend.GetRemoteWrites()
+}
+
+// UpsertRemoteWrite creates or updates the remote-write target
+func (r *provider) UpsertRemoteWrite(target storage.RemoteWrite) error {
+	backend, err := r.backend()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return backend.UpsertRemoteWrite(target)
+}
+
+// DeleteRemoteWrite deletes the remote-write target specified with name
+func (r *provider) DeleteRemoteWrite(name string) error {
+	backend, err := r.backend()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return backend.DeleteRemoteWrite(name)
+}
+
+// GetRecordingRules returns the list of recording rules
+func (r *provider) GetRecordingRules() ([]storage.RecordingRule, error) {
+	backend, err := r.backend()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return backend.GetRecordingRules()
+}
+
+// UpsertRecordingRule creates or updates the recording rule
+func (r *provider) UpsertRecordingRule(rule storage.RecordingRule) error {
+	backend, err := r.backend()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return backend.UpsertRecordingRule(rule)
+}
+
+// DeleteRecordingRule deletes the recording rule specified with name
+func (r *provider) DeleteRecordingRule(name string) error {
+	backend, err := r.backend()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return backend.DeleteRecordingRule(name)
+}
+
+// backend returns the monitoring provider of the deployed metrics backend
+func (r *provider) backend() (Monitoring, error) {
+	if r.client == nil {
+		return r.influxDB, nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := r.clock.Now()
+	if r.cached != nil && now.Sub(r.detected) < defaults.MonitoringBackendCacheTTL {
+		return r.cached, nil
+	}
+	backend, err := r.detectBackend()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	r.cached, r.detected = backend, now
+	return backend, nil
+}
+
+// detectBackend queries the cluster for the deployed metrics backend
+func (r *provider) detectBackend() (Monitoring, error) {
+	_, err := r.client.Services(defaults.MonitoringNamespace).Get(defaults.PrometheusServiceName, metav1.GetOptions{})
+	err = rigging.ConvertError(err)
+	if err == nil {
+		return r.prometheus, nil
+	}
+	if trace.IsNotFound(err) {
+		return r.influxDB, nil
+	}
+	return nil, trace.Wrap(err)
+}
@@ -0,0 +1,542 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package monitoring
+
+import (
+	"sort"
+	"time"
+
+	"github.com/gravitational/gravity/lib/constants"
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/storage"
+
+	"github.com/ghodss/yaml"
+	"github.com/gravitational/rigging"
+	"github.com/gravitational/trace"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubelabels "k8s.io/apimachinery/pkg/labels"
+	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+)
+
+// prometheus manages the configuration of a Prometheus server
+// shipped with the cluster application.
+//
+// Recording rules are stored as resources in ConfigMaps of the monitoring
+// namespace and remote-write targets, which carry credentials, in Secrets.
+// After every change, the provider regenerates the Prometheus configuration
+// ConfigMap with the following keys:
+//
+//   - retention - the retention duration for the --storage.tsdb.retention flag
+//   - rules.yml - the recording rules file to add to rule_files
+//
+// and the Prometheus configuration Secret of the same name with the key:
+//
+//   - remote_write.yml - the remote_write section of the Prometheus configuration
+type prometheus struct {
+	client  corev1.ConfigMapInterface
+	secrets corev1.SecretInterface
+}
+
+// NewPrometheus returns a new Prometheus monitoring provider
+func NewPrometheus(client corev1.CoreV1Interface) Monitoring {
+	return &prometheus{
+		client:  client.ConfigMaps(defaults.MonitoringNamespace),
+		secrets: client.Secrets(defaults.MonitoringNamespace),
+	}
+}
+
+// GetRetentionPolicies returns the Prometheus retention policy
+func (r *prometheus) GetRetentionPolicies() ([]RetentionPolicy, error) {
+	retention := defaults.PrometheusRetention
+	config, err := r.client.Get(constants.PrometheusConfigMap, metav1.GetOptions{})
+	err = rigging.ConvertError(err)
+	if err != nil && !trace.IsNotFound(err) {
+		return nil, trace.Wrap(err)
+	}
+	if config != nil && config.Data[constants.PrometheusRetentionKey] != "" {
+		retention, err = time.ParseDuration(config.Data[constants.PrometheusRetentionKey])
+		if err != nil {
+			return nil, trace.Wrap(err, "failed to parse retention duration")
+		}
+	}
+	return []RetentionPolicy{{Name: PrometheusRetentionPolicy, Duration: retention}}, nil
+}
+
+// UpdateRetentionPolicy updates the Prometheus retention policy
+func (r *prometheus) UpdateRetentionPolicy(policy RetentionPolicy) error {
+	if policy.Name != PrometheusRetentionPolicy {
+		return trace.BadParameter("Prometheus supports only the %q retention policy, got %q",
+			PrometheusRetentionPolicy, policy.Name)
+	}
+	return r.updateConfig(map[string]string{
+		constants.PrometheusRetentionKey: formatDuration(policy.Duration),
+	})
+}
+
+// GetRemoteWrites returns the list of remote-write targets
+func (r *prometheus) GetRemoteWrites() (targets []storage.RemoteWrite, err error) {
+	err = r.listSecretResources(constants.MonitoringTypeRemoteWrite, func(data []byte) error {
+		target, err := storage.UnmarshalRemoteWrite(data)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		targets = append(targets, target)
+		return nil
+	})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return targets, nil
+}
+
+// UpsertRemoteWrite creates or updates the remote-write target.
+// The credentials omitted from the target are kept from the existing target
+// with the same name so that a target read back without credentials can be updated
+func (r *prometheus) UpsertRemoteWrite(target storage.RemoteWrite) error {
+	existing, err := r.getRemoteWrite(target.GetName())
+	if err != nil && !trace.IsNotFound(err) {
+		return trace.Wrap(err)
+	}
+	if existing != nil {
+		target = withStoredCredentials(target, existing)
+	}
+	data, err := storage.MarshalRemoteWrite(target)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	err = r.upsertSecretResource(constants.RemoteWriteSecretPrefix+target.GetName(),
+		constants.MonitoringTypeRemoteWrite, data)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return trace.Wrap(r.syncRemoteWrites())
+}
+
+// getRemoteWrite returns the remote-write target specified with name
+func (r *prometheus) getRemoteWrite(name string) (storage.RemoteWrite, error) {
+	secret, err := r.secrets.Get(constants.RemoteWriteSecretPrefix+name, metav1.GetOptions{})
+	if err != nil {
+		return nil, trace.Wrap(rigging.ConvertError(err))
+	}
+	data, ok := secret.Data[constants.ResourceSpecKey]
+	if !ok {
+		return nil, trace.NotFound("remote-write target %q not found", name)
+	}
+	target, err := storage.UnmarshalRemoteWrite(data)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return target, nil
+}
+
+// withStoredCredentials returns a copy of the target with the credentials
+// it omits taken from the stored target: the password if both use basic
+// authentication with the same user, the bearer token otherwise
+func withStoredCredentials(target, stored storage.RemoteWrite) storage.RemoteWrite {
+	v2, ok := target.(*storage.RemoteWriteV2)
+	if !ok {
+		return target
+	}
+	out := *v2
+	storedSpec := stored.GetSpec()
+	switch basicAuth := out.Spec.BasicAuth; {
+	case basicAuth != nil:
+		if basicAuth.Password == "" && storedSpec.BasicAuth != nil &&
+			storedSpec.BasicAuth.Username == basicAuth.Username {
+			withPassword := *basicAuth
+			withPassword.Password = storedSpec.BasicAuth.Password
+			out.Spec.BasicAuth = &withPassword
+		}
+	case out.Spec.BearerToken == "":
+		out.Spec.BearerToken = storedSpec.BearerToken
+	}
+	return &out
+}
+
+// DeleteRemoteWrite deletes the remote-write target specified with name
+func (r *prometheus) DeleteRemoteWrite(name string) error {
+	err := rigging.ConvertError(r.secrets.Delete(constants.RemoteWriteSecretPrefix+name, nil))
+	if err != nil {
+		if trace.IsNotFound(err) {
+			return trace.NotFound("remote-write target %q not found", name)
+		}
+		return trace.Wrap(err)
+	}
+	return trace.Wrap(r.syncRemoteWrites())
+}
+
+// GetRecordingRules returns the list of recording rules
+func (r *prometheus) GetRecordingRules() (rules []storage.RecordingRule, err error) {
+	err = r.listResources(constants.MonitoringTypeRecordingRule, func(data []byte) error {
+		rule, err := storage.UnmarshalRecordingRule(data)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		rules = append(rules, rule)
+		return nil
+	})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return rules, nil
+}
+
+// UpsertRecordingRule creates or updates the recording rule
+func (r *prometheus) UpsertRecordingRule(rule storage.RecordingRule) error {
+	data, err := storage.MarshalRecordingRule(rule)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	err = r.upsertResource(constants.RecordingRuleConfigMapPrefix+rule.GetName(),
+		constants.MonitoringTypeRecordingRule, data)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return trace.Wrap(r.syncRecordingRules())
+}
+
+// DeleteRecordingRule deletes the recording rule specified with name
+func (r *prometheus) DeleteRecordingRule(name string) error {
+	err := rigging.ConvertError(r.client.Delete(constants.RecordingRuleConfigMapPrefix+name, nil))
+	if err != nil {
+		if trace.IsNotFound(err) {
+			return trace.NotFound("recording rule %q not found", name)
+		}
+		return trace.Wrap(err)
+	}
+	return trace.Wrap(r.syncRecordingRules())
+}
+
+// syncRemoteWrites regenerates the remote_write section of the Prometheus configuration
+func (r *prometheus) syncRemoteWrites() error {
+	targets, err := r.GetRemoteWrites()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	data, err := RenderRemoteWrites(targets)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return r.updateSecretConfig(map[string][]byte{
+		constants.PrometheusRemoteWriteKey: data,
+	})
+}
+
+// syncRecordingRules regenerates the Prometheus recording rules file
+func (r *prometheus) syncRecordingRules() error {
+	rules, err := r.GetRecordingRules()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	data, err := RenderRecordingRules(rules)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return r.updateConfig(map[string]string{
+		constants.PrometheusRulesKey: string(data),
+	})
+}
+
+// listResources invokes fn with the resource specification of each ConfigMap
+// labeled with the specified monitoring type
+func (r *prometheus) listResources(monitoringType string, fn func(data []byte) error) error {
+	labels := kubelabels.Set{
+		constants.MonitoringType: monitoringType,
+	}
+	configmaps, err := r.client.List(metav1.ListOptions{LabelSelector: labels.String()})
+	if err != nil {
+		return trace.Wrap(rigging.ConvertError(err))
+	}
+	sort.Slice(configmaps.Items, func(i, j int) bool {
+		return configmaps.Items[i].Name < configmaps.Items[j].Name
+	})
+	for _, config := range configmaps.Items {
+		data, ok := config.Data[constants.ResourceSpecKey]
+		if !ok {
+			continue
+		}
+		if err := fn([]byte(data)); err != nil {
+			return trace.Wrap(err)
+		}
+	}
+	return nil
+}
+
+// listSecretResources invokes fn with the resource specification of each Secret
+// labeled with the specified monitoring type
+func (r *prometheus) listSecretResources(monitoringType string, fn func(data []byte) error) error {
+	labels := kubelabels.Set{
+		constants.MonitoringType: monitoringType,
+	}
+	secrets, err := r.secrets.List(metav1.ListOptions{LabelSelector: labels.String()})
+	if err != nil {
+		return trace.Wrap(rigging.ConvertError(err))
+	}
+	sort.Slice(secrets.Items, func(i, j int) bool {
+		return secrets.Items[i].Name < secrets.Items[j].Name
+	})
+	for _, secret := range secrets.Items {
+		data, ok := secret.Data[constants.ResourceSpecKey]
+		if !ok {
+			continue
+		}
+		if err := fn(data); err != nil {
+			return trace.Wrap(err)
+		}
+	}
+	return nil
+}
+
+// upsertResource creates or updates the ConfigMap specified with name
+// with the resource specification given with data
+func (r *prometheus) upsertResource(name, monitoringType string, data []byte) error {
+	return r.upsertConfigMap(&v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: defaults.MonitoringNamespace,
+			Labels: map[string]string{
+				constants.MonitoringType: monitoringType,
+			},
+		},
+		Data: map[string]string{
+			constants.ResourceSpecKey: string(data),
+		},
+	})
+}
+
+// upsertSecretResource creates or updates the Secret specified with name
+// with the resource specification given with data
+func (r *prometheus) upsertSecretResource(name, monitoringType string, data []byte) error {
+	return r.upsertSecret(&v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: defaults.MonitoringNamespace,
+			Labels: map[string]string{
+				constants.MonitoringType: monitoringType,
+			},
+		},
+		Data: map[string][]byte{
+			constants.ResourceSpecKey: data,
+		},
+		Type: v1.SecretTypeOpaque,
+	})
+}
+
+// updateConfig updates the specified keys in the Prometheus configuration ConfigMap
+// retaining the other keys
+func (r *prometheus) updateConfig(values map[string]string) error {
+	config, err := r.client.Get(constants.PrometheusConfigMap, metav1.GetOptions{})
+	err = rigging.ConvertError(err)
+	if err != nil && !trace.IsNotFound(err) {
+		return trace.Wrap(err)
+	}
+	if err != nil {
+		config = &v1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      constants.PrometheusConfigMap,
+				Namespace: defaults.MonitoringNamespace,
+				Labels: map[string]string{
+					constants.MonitoringType: constants.MonitoringTypePrometheus,
+				},
+			},
+		}
+	}
+	if config.Data == nil {
+		config.Data = make(map[string]string)
+	}
+	for key, value := range values {
+		config.Data[key] = value
+	}
+	return r.upsertConfigMap(config)
+}
+
+// updateSecretConfig updates the specified keys in the Prometheus configuration Secret
+// retaining the other keys
+func (r *prometheus) updateSecretConfig(values map[string][]byte) error {
+	secret, err := r.secrets.Get(constants.PrometheusConfigSecret, metav1.GetOptions{})
+	err = rigging.ConvertError(err)
+	if err != nil && !trace.IsNotFound(err) {
+		return trace.Wrap(err)
+	}
+	if err != nil {
+		secret = &v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      constants.PrometheusConfigSecret,
+				Namespace: defaults.MonitoringNamespace,
+				Labels: map[string]string{
+					constants.MonitoringType: constants.MonitoringTypePrometheus,
+				},
+			},
+			Type: v1.SecretTypeOpaque,
+		}
+	}
+	if secret.Data == nil {
+		secret.Data = make(map[string][]byte)
+	}
+	for key, value := range values {
+		secret.Data[key] = value
+	}
+	return r.upsertSecret(secret)
+}
+
+func (r *prometheus) upsertConfigMap(config *v1.ConfigMap) error {
+	_, err := r.client.Create(config)
+	err = rigging.ConvertError(err)
+	if err == nil {
+		return nil
+	}
+	if !trace.IsAlreadyExists(err) {
+		return trace.Wrap(err)
+	}
+	_, err = r.client.Update(config)
+	return trace.Wrap(rigging.ConvertError(err))
+}
+
+func (r *prometheus) upsertSecret(secret *v1.Secret) error {
+	_, err := r.secrets.Create(secret)
+	err = rigging.ConvertError(err)
+	if err == nil {
+		return nil
+	}
+	if !trace.IsAlreadyExists(err) {
+		return trace.Wrap(err)
+	}
+	_, err = r.secrets.Update(secret)
+	return trace.Wrap(rigging.ConvertError(err))
+}
+
+// RenderRemoteWrites renders the remote_write section of the Prometheus configuration
+// for the specified remote-write targets
+func RenderRemoteWrites(targets []storage.RemoteWrite) ([]byte, error) {
+	config := remoteWriteSection{RemoteWrite: []remoteWriteConfig{}}
+	for _, target := range targets {
+		spec := target.GetSpec()
+		remoteWrite := remoteWriteConfig{
+			URL:         spec.URL,
+			BearerToken: spec.BearerToken,
+		}
+		if spec.RemoteTimeout != nil {
+			remoteWrite.RemoteTimeout = formatDuration(spec.RemoteTimeout.Duration)
+		}
+		if spec.BasicAuth != nil {
+			remoteWrite.BasicAuth = &basicAuthConfig{
+				Username: spec.BasicAuth.Username,
+				Password: spec.BasicAuth.Password,
+			}
+		}
+		if spec.InsecureSkipVerify {
+			remoteWrite.TLSConfig = &tlsConfig{InsecureSkipVerify: true}
+		}
+		config.RemoteWrite = append(config.RemoteWrite, remoteWrite)
+	}
+	data, err := yaml.Marshal(config)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return data, nil
+}
+
+// RenderRecordingRules renders the Prometheus rules file with the specified
+// recording rules. Each rule is placed into a separate group named after the rule
+func RenderRecordingRules(rules []storage.RecordingRule) ([]byte, error) {
+	config := ruleGroups{Groups: []ruleGroup{}}
+	for _, rule := range rules {
+		spec := rule.GetSpec()
+		group := ruleGroup{
+			Name: rule.GetName(),
+			Rules: []recordingRule{{
+				Record: spec.Record,
+				Expr:   spec.Expr,
+				Labels: spec.Labels,
+			}},
+		}
+		if spec.Interval != nil {
+			group.Interval = formatDuration(spec.Interval.Duration)
+		}
+		config.Groups = append(config.Groups, group)
+	}
+	data, err := yaml.Marshal(config)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return data, nil
+}
+
+// remoteWriteSection is the remote_write section of the Prometheus configuration
+type remoteWriteSection struct {
+	// RemoteWrite lists the remote-write configurations
+	RemoteWrite []remoteWriteConfig `json:"remote_write"`
+}
+
+// remoteWriteConfig is a single Prometheus remote-write configuration
+type remoteWriteConfig struct {
+	// URL is the URL of the remote-write endpoint
+	URL string `json:"url"`
+	// RemoteTimeout is the timeout of requests to the endpoint
+	RemoteTimeout string `json:"remote_timeout,omitempty"`
+	// BasicAuth specifies the basic authentication credentials
+	BasicAuth *basicAuthConfig `json:"basic_auth,omitempty"`
+	// BearerToken specifies the bearer authentication token
+	BearerToken string `json:"bearer_token,omitempty"`
+	// TLSConfig specifies the TLS settings
+	TLSConfig *tlsConfig `json:"tls_config,omitempty"`
+}
+
+// basicAuthConfig is the Prometheus basic authentication configuration
+type basicAuthConfig struct {
+	// Username is the name of the user
+	Username string `json:"username"`
+	// Password is the user password
+	Password string `json:"password,omitempty"`
+}
+
+// tlsConfig is the Prometheus TLS configuration
+type tlsConfig struct {
+	// InsecureSkipVerify disables the verification of the server certificate
+	InsecureSkipVerify bool `json:"insecure_skip_verify"`
+}
+
+// ruleGroups is the Prometheus rules file
+type ruleGroups struct {
+	// Groups lists the rule groups
+	Groups []ruleGroup `json:"groups"`
+}
+
+// ruleGroup is a group of Prometheus rules evaluated with the same interval
+type ruleGroup struct {
+	// Name is the group name
+	Name string `json:"name"`
+	// Interval is how often the rules in the group are evaluated
+	Interval string `json:"interval,omitempty"`
+	// Rules lists the rules of the group
+	Rules []recordingRule `json:"rules"`
+}
+
+// recordingRule is a single Prometheus recording rule
+type recordingRule struct {
+	// Record is the name of the recorded time series
+	Record string `json:"record"`
+	// Expr is the PromQL expression to evaluate
+	Expr string `json:"expr"`
+	// Labels specifies the labels to add to the time series
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// PrometheusRetentionPolicy is the name of the single retention policy
+// supported by Prometheus
+const PrometheusRetentionPolicy = "default"
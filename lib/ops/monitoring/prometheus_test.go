@@ -0,0 +1,179 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package monitoring
+
+import (
+	"time"
+
+	"github.com/gravitational/gravity/lib/compare"
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/storage"
+
+	teleservices "github.com/gravitational/teleport/lib/services"
+	"github.com/jonboulle/clockwork"
+	. "gopkg.in/check.v1"
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+)
+
+type PrometheusSuite struct{}
+
+var _ = Suite(&PrometheusSuite{})
+
+func (*PrometheusSuite) TestRendersRemoteWrites(c *C) {
+	data, err := RenderRemoteWrites([]storage.RemoteWrite{
+		storage.NewRemoteWrite("thanos", storage.RemoteWriteSpecV2{
+			URL:           "https://thanos:19291/api/v1/receive",
+			RemoteTimeout: &teleservices.Duration{Duration: 30 * time.Second},
+			BasicAuth:     &storage.RemoteWriteBasicAuth{Username: "gravity", Password: "secret"},
+		}),
+		storage.NewRemoteWrite("cortex", storage.RemoteWriteSpecV2{
+			URL:                "https://cortex/api/prom/push",
+			BearerToken:        "token",
+			InsecureSkipVerify: true,
+		}),
+	})
+	c.Assert(err, IsNil)
+	compare.DeepCompare(c, string(data), `remote_write:
+- basic_auth:
+    password: secret
+    username: gravity
+  remote_timeout: 30s
+  url: https://thanos:19291/api/v1/receive
+- bearer_token: token
+  tls_config:
+    insecure_skip_verify: true
+  url: https://cortex/api/prom/push
+`)
+
+	data, err = RenderRemoteWrites(nil)
+	c.Assert(err, IsNil)
+	c.Assert(string(data), Equals, "remote_write: []\n")
+}
+
+func (*PrometheusSuite) TestKeepsStoredRemoteWriteCredentials(c *C) {
+	stored := storage.NewRemoteWrite("thanos", storage.RemoteWriteSpecV2{
+		URL:       "https://thanos:19291/api/v1/receive",
+		BasicAuth: &storage.RemoteWriteBasicAuth{Username: "gravity", Password: "secret"},
+	})
+	// the target read back without credentials keeps the stored password
+	target := withStoredCredentials(storage.RemoteWriteWithoutSecrets(stored), stored)
+	c.Assert(target.GetSpec(), DeepEquals, stored.GetSpec())
+
+	// a different user or an explicit password replaces the stored one
+	target = withStoredCredentials(storage.NewRemoteWrite("thanos", storage.RemoteWriteSpecV2{
+		URL:       "https://thanos:19291/api/v1/receive",
+		BasicAuth: &storage.RemoteWriteBasicAuth{Username: "admin"},
+	}), stored)
+	c.Assert(target.GetSpec().BasicAuth, DeepEquals, &storage.RemoteWriteBasicAuth{Username: "admin"})
+	target = withStoredCredentials(storage.NewRemoteWrite("thanos", storage.RemoteWriteSpecV2{
+		URL:       "https://thanos:19291/api/v1/receive",
+		BasicAuth: &storage.RemoteWriteBasicAuth{Username: "gravity", Password: "changed"},
+	}), stored)
+	c.Assert(target.GetSpec().BasicAuth.Password, Equals, "changed")
+
+	stored = storage.NewRemoteWrite("cortex", storage.RemoteWriteSpecV2{
+		URL:         "https://cortex/api/prom/push",
+		BearerToken: "token",
+	})
+	target = withStoredCredentials(storage.RemoteWriteWithoutSecrets(stored), stored)
+	c.Assert(target.GetSpec().BearerToken, Equals, "token")
+	// switching to basic authentication drops the stored token
+	target = withStoredCredentials(storage.NewRemoteWrite("cortex", storage.RemoteWriteSpecV2{
+		URL:       "https://cortex/api/prom/push",
+		BasicAuth: &storage.RemoteWriteBasicAuth{Username: "gravity", Password: "secret"},
+	}), stored)
+	c.Assert(target.GetSpec().BearerToken, Equals, "")
+	c.Assert(target.CheckAndSetDefaults(), IsNil)
+}
+
+func (*PrometheusSuite) TestRendersRecordingRules(c *C) {
+	data, err := RenderRecordingRules([]storage.RecordingRule{
+		storage.NewRecordingRule("cpu", storage.RecordingRuleSpecV2{
+			Record:   "node:cpu_usage:rate5m",
+			Expr:     "rate(node_cpu_seconds_total[5m])",
+			Labels:   map[string]string{"team": "ops"},
+			Interval: &teleservices.Duration{Duration: time.Minute},
+		}),
+	})
+	c.Assert(err, IsNil)
+	compare.DeepCompare(c, string(data), `groups:
+- interval: 1m
+  name: cpu
+  rules:
+  - expr: rate(node_cpu_seconds_total[5m])
+    labels:
+      team: ops
+    record: node:cpu_usage:rate5m
+`)
+}
+
+func (*PrometheusSuite) TestCachesDetectedBackend(c *C) {
+	client := &servicesClient{}
+	clock := clockwork.NewFakeClock()
+	influxDB, prometheus := &prometheus{}, &prometheus{}
+	provider := &provider{
+		client:     client,
+		influxDB:   influxDB,
+		prometheus: prometheus,
+		clock:      clock,
+	}
+
+	backend, err := provider.backend()
+	c.Assert(err, IsNil)
+	c.Assert(backend, Equals, Monitoring(influxDB))
+
+	client.prometheus = true
+	backend, err = provider.backend()
+	c.Assert(err, IsNil)
+	c.Assert(backend, Equals, Monitoring(influxDB), Commentf("expected cached backend"))
+	c.Assert(client.calls, Equals, 1)
+
+	clock.Advance(defaults.MonitoringBackendCacheTTL)
+	backend, err = provider.backend()
+	c.Assert(err, IsNil)
+	c.Assert(backend, Equals, Monitoring(prometheus))
+	c.Assert(client.calls, Equals, 2)
+}
+
+// servicesClient is a Kubernetes client that only serves the Prometheus service
+type servicesClient struct {
+	corev1.CoreV1Interface
+	// prometheus specifies whether the Prometheus service exists
+	prometheus bool
+	// calls counts the service queries
+	calls int
+}
+
+func (r *servicesClient) Services(namespace string) corev1.ServiceInterface {
+	return &serviceGetter{client: r}
+}
+
+type serviceGetter struct {
+	corev1.ServiceInterface
+	client *servicesClient
+}
+
+func (r *serviceGetter) Get(name string, options metav1.GetOptions) (*v1.Service, error) {
+	r.client.calls++
+	if !r.client.prometheus {
+		return nil, errors.NewNotFound(v1.Resource("services"), name)
+	}
+	return &v1.Service{ObjectMeta: metav1.ObjectMeta{Name: name}}, nil
+}
@@ -0,0 +1,235 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package monitoring
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/utils"
+
+	"github.com/gravitational/trace"
+)
+
+// KnownMeasurements maps the InfluxDB measurements collected by the cluster
+// monitoring system to their fields
+var KnownMeasurements = map[string][]string{
+	"cpu/limit":                     {storage.AlertFieldValue},
+	"cpu/node_allocatable":          {storage.AlertFieldValue},
+	"cpu/node_capacity":             {storage.AlertFieldValue},
+	"cpu/node_reservation":          {storage.AlertFieldValue},
+	"cpu/node_utilization":          {storage.AlertFieldValue},
+	"cpu/request":                   {storage.AlertFieldValue},
+	"cpu/usage":                     {storage.AlertFieldValue},
+	"cpu/usage_rate":                {storage.AlertFieldValue},
+	"filesystem/available":          {storage.AlertFieldValue},
+	"filesystem/inodes":             {storage.AlertFieldValue},
+	"filesystem/inodes_free":        {storage.AlertFieldValue},
+	"filesystem/limit":              {storage.AlertFieldValue},
+	"filesystem/usage":              {storage.AlertFieldValue},
+	"memory/cache":                  {storage.AlertFieldValue},
+	"memory/limit":                  {storage.AlertFieldValue},
+	"memory/major_page_faults_rate": {storage.AlertFieldValue},
+	"memory/node_allocatable":       {storage.AlertFieldValue},
+	"memory/node_capacity":          {storage.AlertFieldValue},
+	"memory/node_reservation":       {storage.AlertFieldValue},
+	"memory/node_utilization":       {storage.AlertFieldValue},
+	"memory/page_faults_rate":       {storage.AlertFieldValue},
+	"memory/request":                {storage.AlertFieldValue},
+	"memory/rss":                    {storage.AlertFieldValue},
+	"memory/usage":                  {storage.AlertFieldValue},
+	"memory/working_set":            {storage.AlertFieldValue},
+	"network/rx_errors_rate":        {storage.AlertFieldValue},
+	"network/rx_rate":               {storage.AlertFieldValue},
+	"network/tx_errors_rate":        {storage.AlertFieldValue},
+	"network/tx_rate":               {storage.AlertFieldValue},
+	"restart_count":                 {storage.AlertFieldValue},
+	"uptime":                        {storage.AlertFieldValue},
+}
+
+// CompileAlert generates the kapacitor TICKscript for the alert defined
+// with a metric and a threshold.
+//
+// The metric is aggregated over the alert duration for each group of
+// series and the alert is triggered at the alert severity when the
+// aggregated value satisfies the comparison with the threshold.
+// Alert labels and severity are added as tags to the alert data
+func CompileAlert(alert storage.Alert) (string, error) {
+	spec := alert.GetSpec()
+	if !spec.IsStructured() {
+		return "", trace.BadParameter("alert %v does not specify a metric", alert.GetName())
+	}
+	fields, ok := KnownMeasurements[spec.Metric]
+	if !ok {
+		return "", trace.BadParameter("unknown metric %q, known metrics are: %v",
+			spec.Metric, knownMetrics())
+	}
+	if !utils.StringInSlice(fields, spec.Field) {
+		return "", trace.BadParameter("unknown field %q of metric %q, known fields are: %v",
+			spec.Field, spec.Metric, fields)
+	}
+	if spec.Duration == nil {
+		return "", trace.BadParameter("missing parameter Duration")
+	}
+	level, ok := severityLevels[spec.Severity]
+	if !ok {
+		return "", trace.BadParameter("unsupported severity %q", spec.Severity)
+	}
+	tags := make(map[string]string, len(spec.Labels)+1)
+	for key, value := range spec.Labels {
+		tags[key] = value
+	}
+	tags[severityLabel] = spec.Severity
+	every := spec.Duration.Duration
+	if every > alertEvery {
+		every = alertEvery
+	}
+	var buf bytes.Buffer
+	err := tickscriptTemplate.Execute(&buf, tickscriptParams{
+		Name:        alert.GetName(),
+		Database:    defaults.InfluxDBDatabase,
+		Retention:   defaults.InfluxDBRetentionPolicy,
+		Metric:      spec.Metric,
+		Field:       spec.Field,
+		Aggregation: spec.Aggregation,
+		Comparison:  spec.Comparison,
+		Threshold:   strconv.FormatFloat(spec.Threshold, 'f', -1, 64),
+		Period:      formatDuration(spec.Duration.Duration),
+		Every:       formatDuration(every),
+		Level:       level,
+		Filter:      sortedPairs(spec.Filter),
+		GroupBy:     spec.GroupBy,
+		Tags:        sortedPairs(tags),
+	})
+	if err != nil {
+		return "", trace.Wrap(err)
+	}
+	return buf.String(), nil
+}
+
+// formatDuration formats the duration as a TICKscript duration literal
+func formatDuration(d time.Duration) string {
+	switch {
+	case d%time.Hour == 0:
+		return fmt.Sprintf("%vh", int64(d/time.Hour))
+	case d%time.Minute == 0:
+		return fmt.Sprintf("%vm", int64(d/time.Minute))
+	default:
+		return fmt.Sprintf("%vs", int64(d/time.Second))
+	}
+}
+
+// quote formats s as a TICKscript string literal
+func quote(s string) string {
+	return "'" + strings.Replace(strings.Replace(s, `\`, `\\`, -1), "'", `\'`, -1) + "'"
+}
+
+// reference formats s as a TICKscript field or tag reference
+func reference(s string) string {
+	return `"` + strings.Replace(strings.Replace(s, `\`, `\\`, -1), `"`, `\"`, -1) + `"`
+}
+
+func sortedPairs(m map[string]string) (pairs []pair) {
+	for _, key := range sortedKeys(m) {
+		pairs = append(pairs, pair{Key: key, Value: m[key]})
+	}
+	return pairs
+}
+
+func knownMetrics() []string {
+	metrics := make([]string, 0, len(KnownMeasurements))
+	for metric := range KnownMeasurements {
+		metrics = append(metrics, metric)
+	}
+	sort.Strings(metrics)
+	return metrics
+}
+
+type tickscriptParams struct {
+	Name        string
+	Database    string
+	Retention   string
+	Metric      string
+	Field       string
+	Aggregation string
+	Comparison  string
+	Threshold   string
+	Period      string
+	Every       string
+	Level       string
+	Filter      []pair
+	GroupBy     []string
+	Tags        []pair
+}
+
+type pair struct {
+	Key   string
+	Value string
+}
+
+var tickscriptTemplate = template.Must(template.New("tickscript").Funcs(template.FuncMap{
+	"quote":     quote,
+	"reference": reference,
+}).Parse(`// Generated from alert {{.Name}}, do not edit
+var data = stream
+    |from()
+        .database({{quote .Database}})
+        .retentionPolicy({{quote .Retention}})
+        .measurement({{quote .Metric}})
+{{- if .GroupBy}}
+        .groupBy({{range $i, $tag := .GroupBy}}{{if $i}}, {{end}}{{quote $tag}}{{end}})
+{{- end}}
+{{- if .Filter}}
+        .where(lambda: {{range $i, $tag := .Filter}}{{if $i}} AND {{end}}{{reference $tag.Key}} == {{quote $tag.Value}}{{end}})
+{{- end}}
+    |window()
+        .period({{.Period}})
+        .every({{.Every}})
+    |{{.Aggregation}}({{quote .Field}})
+        .as('value')
+
+data
+    |default()
+{{- range .Tags}}
+        .tag({{quote .Key}}, {{quote .Value}})
+{{- end}}
+    |alert()
+        .id({{quote (print .Name ":{{ .Group }}")}})
+        .message({{quote (print .Name " is {{ .Level }}: " .Aggregation "(" .Metric ") " .Comparison " " .Threshold " over " .Period ", value {{ index .Fields \"value\" }}")}})
+        .{{.Level}}(lambda: "value" {{.Comparison}} {{.Threshold}})
+        .stateChangesOnly()
+`))
+
+var severityLevels = map[string]string{
+	storage.AlertSeverityCritical: "crit",
+	storage.AlertSeverityWarning:  "warn",
+	storage.AlertSeverityInfo:     "info",
+}
+
+const (
+	// severityLabel is the label with the severity of the alert
+	severityLabel = "severity"
+	// alertEvery is the maximum interval between evaluations of an alert
+	alertEvery = time.Minute
+)
@@ -0,0 +1,122 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package monitoring
+
+import (
+	"time"
+
+	"github.com/gravitational/gravity/lib/compare"
+	"github.com/gravitational/gravity/lib/storage"
+
+	teleservices "github.com/gravitational/teleport/lib/services"
+	"github.com/gravitational/trace"
+	. "gopkg.in/check.v1"
+)
+
+type TICKscriptSuite struct{}
+
+var _ = Suite(&TICKscriptSuite{})
+
+func (*TICKscriptSuite) TestCompilesAlert(c *C) {
+	alert := newAlert("high-cpu", storage.AlertSpecV2{
+		Metric:     "cpu/usage_rate",
+		Comparison: ">",
+		Threshold:  0.9,
+		Duration:   &teleservices.Duration{Duration: 10 * time.Minute},
+		Severity:   storage.AlertSeverityWarning,
+		Filter:     map[string]string{"type": "node"},
+		GroupBy:    []string{"nodename"},
+		Labels:     map[string]string{"team": "platform"},
+	})
+	c.Assert(alert.CheckAndSetDefaults(), IsNil)
+
+	script, err := CompileAlert(alert)
+	c.Assert(err, IsNil)
+	compare.DeepCompare(c, script, `// Generated from alert high-cpu, do not edit
+var data = stream
+    |from()
+        .database('k8s')
+        .retentionPolicy('default')
+        .measurement('cpu/usage_rate')
+        .groupBy('nodename')
+        .where(lambda: "type" == 'node')
+    |window()
+        .period(10m)
+        .every(1m)
+    |mean('value')
+        .as('value')
+
+data
+    |default()
+        .tag('severity', 'warning')
+        .tag('team', 'platform')
+    |alert()
+        .id('high-cpu:{{ .Group }}')
+        .message('high-cpu is {{ .Level }}: mean(cpu/usage_rate) > 0.9 over 10m, value {{ index .Fields "value" }}')
+        .warn(lambda: "value" > 0.9)
+        .stateChangesOnly()
+`)
+}
+
+func (*TICKscriptSuite) TestEscapesStrings(c *C) {
+	alert := newAlert("restarts", storage.AlertSpecV2{
+		Metric:      "restart_count",
+		Aggregation: "max",
+		Comparison:  ">=",
+		Threshold:   3,
+		Duration:    &teleservices.Duration{Duration: 30 * time.Second},
+		Filter:      map[string]string{"pod_name": "it's"},
+	})
+	c.Assert(alert.CheckAndSetDefaults(), IsNil)
+
+	script, err := CompileAlert(alert)
+	c.Assert(err, IsNil)
+	c.Assert(script, Matches, `(?s).*\.where\(lambda: "pod_name" == 'it\\'s'\).*`)
+	c.Assert(script, Matches, `(?s).*\.period\(30s\)\s+\.every\(30s\).*`)
+	c.Assert(script, Matches, `(?s).*\|max\('value'\).*\.crit\(lambda: "value" >= 3\).*`)
+}
+
+func (*TICKscriptSuite) TestValidatesMetric(c *C) {
+	alert := newAlert("unknown", storage.AlertSpecV2{
+		Metric:     "cpu/usage_ratio",
+		Comparison: ">",
+	})
+	c.Assert(alert.CheckAndSetDefaults(), IsNil)
+	_, err := CompileAlert(alert)
+	c.Assert(trace.IsBadParameter(err), Equals, true, Commentf("%v", err))
+
+	alert = newAlert("unknown-field", storage.AlertSpecV2{
+		Metric:     "cpu/usage_rate",
+		Field:      "usage",
+		Comparison: ">",
+	})
+	c.Assert(alert.CheckAndSetDefaults(), IsNil)
+	_, err = CompileAlert(alert)
+	c.Assert(trace.IsBadParameter(err), Equals, true, Commentf("%v", err))
+
+	_, err = CompileAlert(newAlert("formula", storage.AlertSpecV2{Formula: "stream"}))
+	c.Assert(trace.IsBadParameter(err), Equals, true, Commentf("%v", err))
+}
+
+func newAlert(name string, spec storage.AlertSpecV2) storage.Alert {
+	return &storage.AlertV2{
+		Kind:     storage.KindAlert,
+		Version:  teleservices.V2,
+		Metadata: teleservices.Metadata{Name: name},
+		Spec:     spec,
+	}
+}
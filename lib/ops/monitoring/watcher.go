@@ -0,0 +1,345 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package monitoring
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gravitational/gravity/lib/constants"
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/storage"
+
+	"github.com/gravitational/rigging"
+	"github.com/gravitational/trace"
+	"github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+)
+
+// AlertWatcherConfig is the configuration of the alert watcher
+type AlertWatcherConfig struct {
+	// Dispatch sends the alert event to the alert targets
+	Dispatch func(AlertEvent) error
+	// State persists the levels of the dispatched alert events
+	State AlertState
+	// KapacitorURL is the URL of the Kapacitor API
+	KapacitorURL string
+	// Client is the HTTP client to query Kapacitor with
+	Client *http.Client
+	// Interval is the interval between Kapacitor queries
+	Interval time.Duration
+	// FieldLogger is used for logging
+	logrus.FieldLogger
+}
+
+// CheckAndSetDefaults checks and sets default values
+func (r *AlertWatcherConfig) CheckAndSetDefaults() error {
+	if r.Dispatch == nil {
+		return trace.BadParameter("missing parameter Dispatch")
+	}
+	if r.State == nil {
+		return trace.BadParameter("missing parameter State")
+	}
+	if r.KapacitorURL == "" {
+		r.KapacitorURL = defaults.KapacitorURL
+	}
+	if r.Client == nil {
+		r.Client = &http.Client{Timeout: defaults.AlertTargetTimeout}
+	}
+	if r.Interval == 0 {
+		r.Interval = defaults.AlertWatchInterval
+	}
+	if r.FieldLogger == nil {
+		r.FieldLogger = logrus.WithField(trace.Component, "alerts")
+	}
+	return nil
+}
+
+// NewAlertWatcher returns a new watcher that delivers the alerts
+// triggered by Kapacitor to the alert targets
+func NewAlertWatcher(config AlertWatcherConfig) (*AlertWatcher, error) {
+	if err := config.CheckAndSetDefaults(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &AlertWatcher{AlertWatcherConfig: config}, nil
+}
+
+// AlertWatcher periodically queries the alert events of all Kapacitor
+// topics and dispatches the events which level has changed since the last
+// time. This delivers the builtin alerts as well as the alerts configured
+// with alert resources without the need to add handlers to their TICKscripts.
+//
+// Events that fail to be dispatched are retried with the next query
+type AlertWatcher struct {
+	// AlertWatcherConfig is the watcher configuration
+	AlertWatcherConfig
+}
+
+// Run dispatches the alert events until the context is cancelled
+func (r *AlertWatcher) Run(ctx context.Context) {
+	r.Info("Start watching alerts.")
+	ticker := time.NewTicker(r.Interval)
+	defer ticker.Stop()
+	for {
+		if err := r.sync(ctx); err != nil {
+			r.Warnf("Failed to dispatch alerts: %v.", trace.DebugReport(err))
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			r.Info("Stop watching alerts.")
+			return
+		}
+	}
+}
+
+// sync dispatches the alert events which level differs from the dispatched one
+func (r *AlertWatcher) sync(ctx context.Context) error {
+	levels, err := r.State.GetLevels()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	var topics kapacitorTopics
+	if err := r.get(ctx, "/kapacitor/v1/alerts/topics", &topics); err != nil {
+		return trace.Wrap(err)
+	}
+	var changed bool
+	seen := make(map[string]struct{})
+	for _, topic := range topics.Topics {
+		var events kapacitorEvents
+		err := r.get(ctx, "/kapacitor/v1/alerts/topics/"+url.PathEscape(topic.ID)+"/events", &events)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		for _, kevent := range events.Events {
+			key := topic.ID + "/" + kevent.ID
+			seen[key] = struct{}{}
+			previous, ok := levels[key]
+			level := kevent.State.Level
+			if previous == level {
+				continue
+			}
+			if ok || level != AlertLevelOK {
+				event := newAlertEvent(kevent, previous)
+				if err := r.Dispatch(event); err != nil {
+					r.WithError(err).WithField("alert", event.ID).Warn("Failed to dispatch alert.")
+					continue
+				}
+			}
+			levels[key] = level
+			changed = true
+		}
+	}
+	// Forget the events that are no longer reported
+	for key := range levels {
+		if _, ok := seen[key]; !ok {
+			delete(levels, key)
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+	return trace.Wrap(r.State.SetLevels(levels))
+}
+
+func (r *AlertWatcher) get(ctx context.Context, path string, out interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, strings.TrimSuffix(r.KapacitorURL, "/")+path, nil)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	resp, err := r.Client.Do(req.WithContext(ctx))
+	if err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return trace.BadParameter("Kapacitor returned %v for %v", resp.Status, path)
+	}
+	return trace.Wrap(json.NewDecoder(resp.Body).Decode(out))
+}
+
+// newAlertEvent returns the alert event for the Kapacitor event.
+//
+// The alert name is taken from the event ID which is formatted as "<name>:<group>"
+// by default. The severity label is derived from the event level, or from
+// the previous level if the event resolves the alert
+func newAlertEvent(event kapacitorEvent, previousLevel string) AlertEvent {
+	level := event.State.Level
+	if level == AlertLevelOK {
+		level = previousLevel
+	}
+	labels := make(map[string]string)
+	if severity, ok := levelSeverities[level]; ok {
+		labels[severityLabel] = severity
+	}
+	return AlertEvent{
+		Name:    strings.SplitN(event.ID, ":", 2)[0],
+		ID:      event.ID,
+		Message: event.State.Message,
+		Details: event.State.Details,
+		Level:   event.State.Level,
+		Time:    event.State.Time,
+		Labels:  labels,
+	}
+}
+
+// AlertLabels returns the labels of the alert event extended with the labels
+// and the severity of the alert with the same name if the alert is defined
+// with a metric
+func AlertLabels(alerts []storage.Alert, event AlertEvent) map[string]string {
+	labels := make(map[string]string, len(event.Labels))
+	for key, value := range event.Labels {
+		labels[key] = value
+	}
+	for _, alert := range alerts {
+		spec := alert.GetSpec()
+		if alert.GetName() != event.Name || !spec.IsStructured() {
+			continue
+		}
+		for key, value := range spec.Labels {
+			labels[key] = value
+		}
+		labels[severityLabel] = spec.Severity
+	}
+	return labels
+}
+
+// AlertState persists the levels of the dispatched alert events
+type AlertState interface {
+	// GetLevels returns the levels of the alert events by event keys
+	GetLevels() (map[string]string, error)
+	// SetLevels replaces the levels of the alert events
+	SetLevels(levels map[string]string) error
+}
+
+// NewConfigMapAlertState returns the alert state stored
+// in a ConfigMap of the monitoring namespace
+func NewConfigMapAlertState(client corev1.CoreV1Interface) AlertState {
+	return &configMapAlertState{client: client.ConfigMaps(defaults.MonitoringNamespace)}
+}
+
+// GetLevels returns the levels of the alert events by event keys
+func (r *configMapAlertState) GetLevels() (map[string]string, error) {
+	config, err := r.client.Get(constants.AlertWatcherConfigMap, metav1.GetOptions{})
+	err = rigging.ConvertError(err)
+	if err != nil && !trace.IsNotFound(err) {
+		return nil, trace.Wrap(err)
+	}
+	levels := make(map[string]string)
+	if err != nil || config.Data[alertLevelsKey] == "" {
+		return levels, nil
+	}
+	if err := json.Unmarshal([]byte(config.Data[alertLevelsKey]), &levels); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return levels, nil
+}
+
+// SetLevels replaces the levels of the alert events
+func (r *configMapAlertState) SetLevels(levels map[string]string) error {
+	data, err := json.Marshal(levels)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	config := &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      constants.AlertWatcherConfigMap,
+			Namespace: defaults.MonitoringNamespace,
+		},
+		Data: map[string]string{
+			alertLevelsKey: string(data),
+		},
+	}
+	_, err = r.client.Create(config)
+	err = rigging.ConvertError(err)
+	if err == nil || !trace.IsAlreadyExists(err) {
+		return trace.Wrap(err)
+	}
+	_, err = r.client.Update(config)
+	return trace.Wrap(rigging.ConvertError(err))
+}
+
+type configMapAlertState struct {
+	client corev1.ConfigMapInterface
+}
+
+// GetLevels returns the levels of the alert events by event keys
+func (r *memoryAlertState) GetLevels() (map[string]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	levels := make(map[string]string, len(r.levels))
+	for key, level := range r.levels {
+		levels[key] = level
+	}
+	return levels, nil
+}
+
+// SetLevels replaces the levels of the alert events
+func (r *memoryAlertState) SetLevels(levels map[string]string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.levels = make(map[string]string, len(levels))
+	for key, level := range levels {
+		r.levels[key] = level
+	}
+	return nil
+}
+
+type memoryAlertState struct {
+	mu     sync.Mutex
+	levels map[string]string
+}
+
+// kapacitorTopics is the list of alert topics returned by Kapacitor
+type kapacitorTopics struct {
+	Topics []struct {
+		ID string `json:"id"`
+	} `json:"topics"`
+}
+
+// kapacitorEvents is the list of alert events of a topic returned by Kapacitor
+type kapacitorEvents struct {
+	Events []kapacitorEvent `json:"events"`
+}
+
+// kapacitorEvent is the alert event returned by Kapacitor
+type kapacitorEvent struct {
+	ID    string `json:"id"`
+	State struct {
+		Level   string    `json:"level"`
+		Message string    `json:"message"`
+		Details string    `json:"details"`
+		Time    time.Time `json:"time"`
+	} `json:"state"`
+}
+
+var levelSeverities = map[string]string{
+	AlertLevelCritical: storage.AlertSeverityCritical,
+	AlertLevelWarning:  storage.AlertSeverityWarning,
+	AlertLevelInfo:     storage.AlertSeverityInfo,
+}
+
+// alertLevelsKey is the ConfigMap key with the JSON-encoded alert levels
+const alertLevelsKey = "levels"
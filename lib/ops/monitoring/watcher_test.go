@@ -0,0 +1,146 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package monitoring
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	"github.com/gravitational/gravity/lib/storage"
+
+	teleservices "github.com/gravitational/teleport/lib/services"
+	"github.com/gravitational/trace"
+	. "gopkg.in/check.v1"
+)
+
+type WatcherSuite struct {
+	server *httptest.Server
+	// events maps topics to the JSON-encoded events
+	events map[string]string
+}
+
+var _ = Suite(&WatcherSuite{})
+
+func (s *WatcherSuite) SetUpTest(c *C) {
+	s.events = make(map[string]string)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/kapacitor/v1/alerts/topics", func(w http.ResponseWriter, r *http.Request) {
+		var topics []string
+		for topic := range s.events {
+			topics = append(topics, fmt.Sprintf(`{"id": %q}`, topic))
+		}
+		fmt.Fprintf(w, `{"topics": [%v]}`, strings.Join(topics, ", "))
+	})
+	mux.HandleFunc("/kapacitor/v1/alerts/topics/", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"events": [%v]}`, s.events["main:high-cpu:alert2"])
+	})
+	s.server = httptest.NewServer(mux)
+}
+
+func (s *WatcherSuite) TearDownTest(c *C) {
+	s.server.Close()
+}
+
+func (s *WatcherSuite) TestDispatchesLevelChanges(c *C) {
+	var dispatched []AlertEvent
+	var dispatchErr error
+	state := &memoryAlertState{}
+	watcher, err := NewAlertWatcher(AlertWatcherConfig{
+		Dispatch: func(event AlertEvent) error {
+			if dispatchErr != nil {
+				return dispatchErr
+			}
+			dispatched = append(dispatched, event)
+			return nil
+		},
+		State:        state,
+		KapacitorURL: s.server.URL,
+	})
+	c.Assert(err, IsNil)
+
+	// alerts that have never fired are not dispatched
+	s.events["main:high-cpu:alert2"] = kapacitorEventJSON("OK")
+	c.Assert(watcher.sync(context.TODO()), IsNil)
+	c.Assert(dispatched, HasLen, 0)
+
+	s.events["main:high-cpu:alert2"] = kapacitorEventJSON("CRITICAL")
+	c.Assert(watcher.sync(context.TODO()), IsNil)
+	c.Assert(watcher.sync(context.TODO()), IsNil)
+	c.Assert(dispatched, DeepEquals, []AlertEvent{{
+		Name:    "high-cpu",
+		ID:      "high-cpu:host=node-1",
+		Message: "high-cpu is CRITICAL",
+		Level:   AlertLevelCritical,
+		Time:    time.Date(2019, 3, 1, 10, 0, 0, 0, time.UTC),
+		Labels:  map[string]string{"severity": storage.AlertSeverityCritical},
+	}})
+
+	// failed events are retried
+	s.events["main:high-cpu:alert2"] = kapacitorEventJSON("OK")
+	dispatchErr = trace.ConnectionProblem(nil, "target is unavailable")
+	c.Assert(watcher.sync(context.TODO()), IsNil)
+	c.Assert(dispatched, HasLen, 1)
+
+	// the dispatched levels survive the restart of the watcher
+	dispatchErr = nil
+	watcher, err = NewAlertWatcher(watcher.AlertWatcherConfig)
+	c.Assert(err, IsNil)
+	c.Assert(watcher.sync(context.TODO()), IsNil)
+	c.Assert(dispatched, HasLen, 2)
+	c.Assert(dispatched[1].Resolved(), Equals, true)
+	// resolved events keep the severity to match the same routes
+	c.Assert(dispatched[1].Labels, DeepEquals, map[string]string{"severity": storage.AlertSeverityCritical})
+
+	// events of deleted alerts are forgotten
+	delete(s.events, "main:high-cpu:alert2")
+	c.Assert(watcher.sync(context.TODO()), IsNil)
+	levels, err := state.GetLevels()
+	c.Assert(err, IsNil)
+	c.Assert(levels, HasLen, 0)
+}
+
+func (s *WatcherSuite) TestAddsLabelsOfStructuredAlerts(c *C) {
+	alert := newAlert("high-cpu", storage.AlertSpecV2{
+		Metric:      "cpu/usage_rate",
+		Field:       storage.AlertFieldValue,
+		Aggregation: "mean",
+		Comparison:  ">",
+		Threshold:   90,
+		Duration:    &teleservices.Duration{Duration: 5 * time.Minute},
+		Severity:    storage.AlertSeverityWarning,
+		Labels:      map[string]string{"team": "platform"},
+	})
+	event := AlertEvent{
+		Name:   "high-cpu",
+		Labels: map[string]string{"severity": storage.AlertSeverityCritical},
+	}
+	c.Assert(AlertLabels([]storage.Alert{alert}, event), DeepEquals, map[string]string{
+		"severity": storage.AlertSeverityWarning,
+		"team":     "platform",
+	})
+	event.Name = "disk-space"
+	c.Assert(AlertLabels([]storage.Alert{alert}, event), DeepEquals, event.Labels)
+}
+
+func kapacitorEventJSON(level string) string {
+	return fmt.Sprintf(`{"id": "high-cpu:host=node-1", "state": {"level": %q, "message": "high-cpu is %v", "time": "2019-03-01T10:00:00Z"}}`,
+		level, level)
+}
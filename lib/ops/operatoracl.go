@@ -571,11 +571,11 @@ func (o *OperatorACL) GetSiteOperationCrashReport(key SiteOperationKey) (io.Read
 	return o.operator.GetSiteOperationCrashReport(key)
 }
 
-func (o *OperatorACL) GetSiteReport(key SiteKey) (io.ReadCloser, error) {
-	if err := o.ClusterAction(key.SiteDomain, storage.KindCluster, teleservices.VerbRead); err != nil {
+func (o *OperatorACL) GetSiteReport(req GetClusterReportRequest) (io.ReadCloser, error) {
+	if err := o.ClusterAction(req.SiteDomain, storage.KindCluster, teleservices.VerbRead); err != nil {
 		return nil, trace.Wrap(err)
 	}
-	return o.operator.GetSiteReport(key)
+	return o.operator.GetSiteReport(req)
 }
 
 func (o *OperatorACL) ValidateDomainName(domainName string) error {
@@ -787,11 +787,60 @@ func (o *OperatorACL) UpdateAlertTarget(key SiteKey, target storage.AlertTarget)
 	return o.operator.UpdateAlertTarget(key, target)
 }
 
-func (o *OperatorACL) DeleteAlertTarget(key SiteKey) error {
+func (o *OperatorACL) DeleteAlertTarget(key SiteKey, name string) error {
 	if err := o.ClusterAction(key.SiteDomain, storage.KindAlertTarget, teleservices.VerbUpdate); err != nil {
 		return trace.Wrap(err)
 	}
-	return o.operator.DeleteAlertTarget(key)
+	return o.operator.DeleteAlertTarget(key, name)
+}
+
+func (o *OperatorACL) DispatchAlert(key SiteKey, event monitoring.AlertEvent) error {
+	if err := o.ClusterAction(key.SiteDomain, storage.KindAlert, teleservices.VerbCreate); err != nil {
+		return trace.Wrap(err)
+	}
+	return o.operator.DispatchAlert(key, event)
+}
+
+func (o *OperatorACL) GetRemoteWrites(key SiteKey) ([]storage.RemoteWrite, error) {
+	if err := o.ClusterAction(key.SiteDomain, storage.KindRemoteWrite, teleservices.VerbList); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return o.operator.GetRemoteWrites(key)
+}
+
+func (o *OperatorACL) UpsertRemoteWrite(key SiteKey, target storage.RemoteWrite) error {
+	if err := o.ClusterAction(key.SiteDomain, storage.KindRemoteWrite, teleservices.VerbUpdate); err != nil {
+		return trace.Wrap(err)
+	}
+	return o.operator.UpsertRemoteWrite(key, target)
+}
+
+func (o *OperatorACL) DeleteRemoteWrite(key SiteKey, name string) error {
+	if err := o.ClusterAction(key.SiteDomain, storage.KindRemoteWrite, teleservices.VerbDelete); err != nil {
+		return trace.Wrap(err)
+	}
+	return o.operator.DeleteRemoteWrite(key, name)
+}
+
+func (o *OperatorACL) GetRecordingRules(key SiteKey) ([]storage.RecordingRule, error) {
+	if err := o.ClusterAction(key.SiteDomain, storage.KindRecordingRule, teleservices.VerbList); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return o.operator.GetRecordingRules(key)
+}
+
+func (o *OperatorACL) UpsertRecordingRule(key SiteKey, rule storage.RecordingRule) error {
+	if err := o.ClusterAction(key.SiteDomain, storage.KindRecordingRule, teleservices.VerbUpdate); err != nil {
+		return trace.Wrap(err)
+	}
+	return o.operator.UpsertRecordingRule(key, rule)
+}
+
+func (o *OperatorACL) DeleteRecordingRule(key SiteKey, name string) error {
+	if err := o.ClusterAction(key.SiteDomain, storage.KindRecordingRule, teleservices.VerbDelete); err != nil {
+		return trace.Wrap(err)
+	}
+	return o.operator.DeleteRecordingRule(key, name)
 }
 
 // GetClusterEnvironmentVariables retrieves the cluster runtime environment variables
@@ -303,7 +303,7 @@ type Sites interface {
 	CompleteFinalInstallStep(CompleteFinalInstallStepRequest) error
 
 	// GetSiteReport returns a tarball that contains all debugging information gathered for the site
-	GetSiteReport(SiteKey) (io.ReadCloser, error)
+	GetSiteReport(GetClusterReportRequest) (io.ReadCloser, error)
 
 	// SignTLSKey signs X509 Public Key with X509 certificate authority of this site
 	SignTLSKey(TLSSignRequest) (*TLSSignResponse, error)
@@ -445,6 +445,25 @@ type ActivateSiteRequest struct {
 	StartApp bool `json:"start_app"`
 }
 
+// GetClusterReportRequest is a request to collect the cluster diagnostics report
+type GetClusterReportRequest struct {
+	// SiteKey identifies the cluster
+	SiteKey
+	// Since limits the collected logs to the specified period.
+	// All logs are collected if unspecified
+	Since time.Duration `json:"since,omitempty"`
+	// Namespaces limits kubernetes diagnostics to the specified namespaces.
+	// All namespaces are collected if unspecified
+	Namespaces []string `json:"namespaces,omitempty"`
+	// Nodes limits node diagnostics to the nodes with the specified
+	// hostnames or advertise addresses.
+	// All nodes are collected if unspecified
+	Nodes []string `json:"nodes,omitempty"`
+	// RedactPatterns lists regular expressions to redact from the report
+	// in addition to the builtin ones
+	RedactPatterns []string `json:"redact_patterns,omitempty"`
+}
+
 // CompleteFinalInstallStepRequest is a request to mark site final install step as completed
 type CompleteFinalInstallStepRequest struct {
 	// AccountID is the ID of the account the site belongs to
@@ -1747,8 +1766,23 @@ type Monitoring interface {
 	GetAlertTargets(SiteKey) ([]storage.AlertTarget, error)
 	// UpdateAlertTarget updates cluster's alert target to the specified
 	UpdateAlertTarget(SiteKey, storage.AlertTarget) error
-	// DeleteAlertTarget deletes the monitoring alert target
-	DeleteAlertTarget(SiteKey) error
+	// DeleteAlertTarget deletes the monitoring alert target specified with name.
+	// The email alert target is deleted if name is empty
+	DeleteAlertTarget(key SiteKey, name string) error
+	// DispatchAlert sends the alert event to the alert targets matching it
+	DispatchAlert(SiteKey, monitoring.AlertEvent) error
+	// GetRemoteWrites returns the list of metrics remote-write targets
+	GetRemoteWrites(SiteKey) ([]storage.RemoteWrite, error)
+	// UpsertRemoteWrite creates or updates the metrics remote-write target
+	UpsertRemoteWrite(SiteKey, storage.RemoteWrite) error
+	// DeleteRemoteWrite deletes the metrics remote-write target specified with name
+	DeleteRemoteWrite(key SiteKey, name string) error
+	// GetRecordingRules returns the list of metrics recording rules
+	GetRecordingRules(SiteKey) ([]storage.RecordingRule, error)
+	// UpsertRecordingRule creates or updates the metrics recording rule
+	UpsertRecordingRule(SiteKey, storage.RecordingRule) error
+	// DeleteRecordingRule deletes the metrics recording rule specified with name
+	DeleteRecordingRule(key SiteKey, name string) error
 }
 
 // UpdateRetentionPolicyRequest is a request to update retention policy
@@ -675,8 +675,16 @@ func (c *Client) GetSiteOperationCrashReport(key ops.SiteOperationKey) (io.ReadC
 	return file.Body(), nil
 }
 
-func (c *Client) GetSiteReport(key ops.SiteKey) (io.ReadCloser, error) {
-	file, err := c.GetFile(c.Endpoint("accounts", key.AccountID, "sites", key.SiteDomain, "report"), url.Values{})
+func (c *Client) GetSiteReport(req ops.GetClusterReportRequest) (io.ReadCloser, error) {
+	params := url.Values{
+		"namespace": req.Namespaces,
+		"node":      req.Nodes,
+		"redact":    req.RedactPatterns,
+	}
+	if req.Since != 0 {
+		params.Set("since", req.Since.String())
+	}
+	file, err := c.GetFile(c.Endpoint("accounts", req.AccountID, "sites", req.SiteDomain, "report"), params)
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
@@ -1116,9 +1124,102 @@ func (c *Client) UpdateAlertTarget(key ops.SiteKey, target storage.AlertTarget)
 	return trace.Wrap(err)
 }
 
-// DeleteAlertTarget deletes the cluster monitoring alert target
-func (c *Client) DeleteAlertTarget(key ops.SiteKey) error {
-	_, err := c.Delete(c.Endpoint("accounts", key.AccountID, "sites", key.SiteDomain, "monitoring", "alert-targets"))
+// DeleteAlertTarget deletes the cluster monitoring alert target specified with name.
+// The email alert target is deleted if name is empty
+func (c *Client) DeleteAlertTarget(key ops.SiteKey, name string) error {
+	if name == "" {
+		_, err := c.Delete(c.Endpoint("accounts", key.AccountID, "sites", key.SiteDomain, "monitoring", "alert-targets"))
+		return trace.Wrap(err)
+	}
+	_, err := c.Delete(c.Endpoint("accounts", key.AccountID, "sites", key.SiteDomain, "monitoring", "alert-targets", name))
+	return trace.Wrap(err)
+}
+
+// DispatchAlert sends the alert event to the matching cluster alert targets
+func (c *Client) DispatchAlert(key ops.SiteKey, event monitoring.AlertEvent) error {
+	_, err := c.PostJSON(c.Endpoint("accounts", key.AccountID, "sites", key.SiteDomain, "monitoring", "alerts", "dispatch"), event)
+	return trace.Wrap(err)
+}
+
+// GetRemoteWrites returns the list of metrics remote-write targets for the cluster
+func (c *Client) GetRemoteWrites(key ops.SiteKey) ([]storage.RemoteWrite, error) {
+	response, err := c.Get(c.Endpoint(
+		"accounts", key.AccountID, "sites", key.SiteDomain, "monitoring", "remote-writes"), url.Values{})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+
+	var items []json.RawMessage
+	if err = json.Unmarshal(response.Bytes(), &items); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	targets := make([]storage.RemoteWrite, len(items))
+	for i, item := range items {
+		target, err := storage.UnmarshalRemoteWrite(item)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		targets[i] = target
+	}
+	return targets, nil
+}
+
+// UpsertRemoteWrite creates or updates the metrics remote-write target
+func (c *Client) UpsertRemoteWrite(key ops.SiteKey, target storage.RemoteWrite) error {
+	bytes, err := storage.MarshalRemoteWrite(target)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	_, err = c.PutJSON(c.Endpoint("accounts", key.AccountID, "sites", key.SiteDomain, "monitoring", "remote-writes"),
+		&UpsertResourceRawReq{Resource: bytes})
+	return trace.Wrap(err)
+}
+
+// DeleteRemoteWrite deletes the metrics remote-write target specified with name
+func (c *Client) DeleteRemoteWrite(key ops.SiteKey, name string) error {
+	_, err := c.Delete(c.Endpoint("accounts", key.AccountID, "sites", key.SiteDomain, "monitoring", "remote-writes", name))
+	return trace.Wrap(err)
+}
+
+// GetRecordingRules returns the list of metrics recording rules for the cluster
+func (c *Client) GetRecordingRules(key ops.SiteKey) ([]storage.RecordingRule, error) {
+	response, err := c.Get(c.Endpoint(
+		"accounts", key.AccountID, "sites", key.SiteDomain, "monitoring", "recording-rules"), url.Values{})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+
+	var items []json.RawMessage
+	if err = json.Unmarshal(response.Bytes(), &items); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	rules := make([]storage.RecordingRule, len(items))
+	for i, item := range items {
+		rule, err := storage.UnmarshalRecordingRule(item)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		rules[i] = rule
+	}
+	return rules, nil
+}
+
+// UpsertRecordingRule creates or updates the metrics recording rule
+func (c *Client) UpsertRecordingRule(key ops.SiteKey, rule storage.RecordingRule) error {
+	bytes, err := storage.MarshalRecordingRule(rule)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	_, err = c.PutJSON(c.Endpoint("accounts", key.AccountID, "sites", key.SiteDomain, "monitoring", "recording-rules"),
+		&UpsertResourceRawReq{Resource: bytes})
+	return trace.Wrap(err)
+}
+
+// DeleteRecordingRule deletes the metrics recording rule specified with name
+func (c *Client) DeleteRecordingRule(key ops.SiteKey, name string) error {
+	_, err := c.Delete(c.Endpoint("accounts", key.AccountID, "sites", key.SiteDomain, "monitoring", "recording-rules", name))
 	return trace.Wrap(err)
 }
 
@@ -21,6 +21,7 @@ import (
 	"net/http"
 
 	"github.com/gravitational/gravity/lib/ops"
+	"github.com/gravitational/gravity/lib/ops/monitoring"
 	"github.com/gravitational/gravity/lib/ops/opsclient"
 	"github.com/gravitational/gravity/lib/storage"
 
@@ -194,6 +195,7 @@ func (h *WebHandler) updateAlertTarget(w http.ResponseWriter, r *http.Request, p
 /* deleteAlertTarget deletes cluster's monitoring alert target
 
    DELETE /portal/v1/accounts/:account_id/sites/:site_domain/monitoring/alert-targets
+   DELETE /portal/v1/accounts/:account_id/sites/:site_domain/monitoring/alert-targets/:name
 
    Success Response:
 
@@ -202,7 +204,7 @@ func (h *WebHandler) updateAlertTarget(w http.ResponseWriter, r *http.Request, p
      }
 */
 func (h *WebHandler) deleteAlertTarget(w http.ResponseWriter, r *http.Request, p httprouter.Params, context *HandlerContext) error {
-	err := context.Operator.DeleteAlertTarget(siteKey(p))
+	err := context.Operator.DeleteAlertTarget(siteKey(p), p.ByName("name"))
 	if err != nil {
 		return trace.Wrap(err)
 	}
@@ -210,3 +212,163 @@ func (h *WebHandler) deleteAlertTarget(w http.ResponseWriter, r *http.Request, p
 	roundtrip.ReplyJSON(w, http.StatusOK, statusOK("alert target deleted"))
 	return nil
 }
+
+/* dispatchAlert sends the alert event to the matching alert targets
+
+   POST /portal/v1/accounts/:account_id/sites/:site_domain/monitoring/alerts/dispatch
+
+   Input: monitoring.AlertEvent
+
+   Success Response:
+
+     {
+       "message": "alert dispatched"
+     }
+*/
+func (h *WebHandler) dispatchAlert(w http.ResponseWriter, r *http.Request, p httprouter.Params, context *HandlerContext) error {
+	var event monitoring.AlertEvent
+	if err := telehttplib.ReadJSON(r, &event); err != nil {
+		return trace.Wrap(err)
+	}
+
+	err := context.Operator.DispatchAlert(siteKey(p), event)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	roundtrip.ReplyJSON(w, http.StatusOK, statusOK("alert dispatched"))
+	return nil
+}
+
+/* getRemoteWrites returns the list of metrics remote-write targets for the cluster
+
+     GET /portal/v1/accounts/:account_id/sites/:site_domain/monitoring/remote-writes
+
+   Success Response:
+
+     []storage.RemoteWrite
+*/
+func (h *WebHandler) getRemoteWrites(w http.ResponseWriter, r *http.Request, p httprouter.Params, context *HandlerContext) error {
+	targets, err := context.Operator.GetRemoteWrites(siteKey(p))
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	roundtrip.ReplyJSON(w, http.StatusOK, targets)
+	return nil
+}
+
+/* upsertRemoteWrite creates or updates the metrics remote-write target
+
+     PUT /portal/v1/accounts/:account_id/sites/:site_domain/monitoring/remote-writes
+
+   Success Response:
+
+     {
+       "message": "remote-write target updated"
+     }
+*/
+func (h *WebHandler) upsertRemoteWrite(w http.ResponseWriter, r *http.Request, p httprouter.Params, context *HandlerContext) error {
+	var req opsclient.UpsertResourceRawReq
+	if err := telehttplib.ReadJSON(r, &req); err != nil {
+		return trace.Wrap(err)
+	}
+
+	target, err := storage.UnmarshalRemoteWrite(req.Resource)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	err = context.Operator.UpsertRemoteWrite(siteKey(p), target)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	roundtrip.ReplyJSON(w, http.StatusOK, statusOK("remote-write target updated"))
+	return nil
+}
+
+/* deleteRemoteWrite deletes the metrics remote-write target specified with name
+
+     DELETE /portal/v1/accounts/:account_id/sites/:site_domain/monitoring/remote-writes/:name
+
+   Success Response:
+
+     {
+       "message": "remote-write target deleted"
+     }
+*/
+func (h *WebHandler) deleteRemoteWrite(w http.ResponseWriter, r *http.Request, p httprouter.Params, context *HandlerContext) error {
+	err := context.Operator.DeleteRemoteWrite(siteKey(p), p.ByName("name"))
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	roundtrip.ReplyJSON(w, http.StatusOK, statusOK("remote-write target deleted"))
+	return nil
+}
+
+/* getRecordingRules returns the list of metrics recording rules for the cluster
+
+     GET /portal/v1/accounts/:account_id/sites/:site_domain/monitoring/recording-rules
+
+   Success Response:
+
+     []storage.RecordingRule
+*/
+func (h *WebHandler) getRecordingRules(w http.ResponseWriter, r *http.Request, p httprouter.Params, context *HandlerContext) error {
+	rules, err := context.Operator.GetRecordingRules(siteKey(p))
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	roundtrip.ReplyJSON(w, http.StatusOK, rules)
+	return nil
+}
+
+/* upsertRecordingRule creates or updates the metrics recording rule
+
+     PUT /portal/v1/accounts/:account_id/sites/:site_domain/monitoring/recording-rules
+
+   Success Response:
+
+     {
+       "message": "recording rule updated"
+     }
+*/
+func (h *WebHandler) upsertRecordingRule(w http.ResponseWriter, r *http.Request, p httprouter.Params, context *HandlerContext) error {
+	var req opsclient.UpsertResourceRawReq
+	if err := telehttplib.ReadJSON(r, &req); err != nil {
+		return trace.Wrap(err)
+	}
+
+	rule, err := storage.UnmarshalRecordingRule(req.Resource)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	err = context.Operator.UpsertRecordingRule(siteKey(p), rule)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	roundtrip.ReplyJSON(w, http.StatusOK, statusOK("recording rule updated"))
+	return nil
+}
+
+/* deleteRecordingRule deletes the metrics recording rule specified with name
+
+     DELETE /portal/v1/accounts/:account_id/sites/:site_domain/monitoring/recording-rules/:name
+
+   Success Response:
+
+     {
+       "message": "recording rule deleted"
+     }
+*/
+func (h *WebHandler) deleteRecordingRule(w http.ResponseWriter, r *http.Request, p httprouter.Params, context *HandlerContext) error {
+	err := context.Operator.DeleteRecordingRule(siteKey(p), p.ByName("name"))
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	roundtrip.ReplyJSON(w, http.StatusOK, statusOK("recording rule deleted"))
+	return nil
+}
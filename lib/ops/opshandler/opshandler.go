@@ -234,6 +234,14 @@ func NewWebHandler(cfg WebHandlerConfig) (*WebHandler, error) {
 	h.GET("/portal/v1/accounts/:account_id/sites/:site_domain/monitoring/alert-targets", h.needsAuth(h.getAlertTargets))
 	h.PUT("/portal/v1/accounts/:account_id/sites/:site_domain/monitoring/alert-targets", h.needsAuth(h.updateAlertTarget))
 	h.DELETE("/portal/v1/accounts/:account_id/sites/:site_domain/monitoring/alert-targets", h.needsAuth(h.deleteAlertTarget))
+	h.DELETE("/portal/v1/accounts/:account_id/sites/:site_domain/monitoring/alert-targets/:name", h.needsAuth(h.deleteAlertTarget))
+	h.GET("/portal/v1/accounts/:account_id/sites/:site_domain/monitoring/remote-writes", h.needsAuth(h.getRemoteWrites))
+	h.PUT("/portal/v1/accounts/:account_id/sites/:site_domain/monitoring/remote-writes", h.needsAuth(h.upsertRemoteWrite))
+	h.DELETE("/portal/v1/accounts/:account_id/sites/:site_domain/monitoring/remote-writes/:name", h.needsAuth(h.deleteRemoteWrite))
+	h.GET("/portal/v1/accounts/:account_id/sites/:site_domain/monitoring/recording-rules", h.needsAuth(h.getRecordingRules))
+	h.PUT("/portal/v1/accounts/:account_id/sites/:site_domain/monitoring/recording-rules", h.needsAuth(h.upsertRecordingRule))
+	h.DELETE("/portal/v1/accounts/:account_id/sites/:site_domain/monitoring/recording-rules/:name", h.needsAuth(h.deleteRecordingRule))
+	h.POST("/portal/v1/accounts/:account_id/sites/:site_domain/monitoring/alerts/dispatch", h.needsAuth(h.dispatchAlert))
 
 	// environment variables
 	h.GET("/portal/v1/accounts/:account_id/sites/:site_domain/envars", h.needsAuth(h.getEnvironmentVariables))
@@ -952,10 +960,24 @@ func (h *WebHandler) activateSite(w http.ResponseWriter, r *http.Request, p http
 
 /* getSiteReport returns a tarball with collected information about the site
 
-   GET /portal/v1/accounts/:account_id/sites/:site_domain/report
+   GET /portal/v1/accounts/:account_id/sites/:site_domain/report?since=<duration>&namespace=<namespace>&node=<node>&redact=<pattern>
 */
 func (h *WebHandler) getSiteReport(w http.ResponseWriter, r *http.Request, p httprouter.Params, context *HandlerContext) error {
-	report, err := context.Operator.GetSiteReport(siteKey(p))
+	query := r.URL.Query()
+	req := ops.GetClusterReportRequest{
+		SiteKey:        siteKey(p),
+		Namespaces:     query["namespace"],
+		Nodes:          query["node"],
+		RedactPatterns: query["redact"],
+	}
+	if since := query.Get("since"); since != "" {
+		var err error
+		req.Since, err = time.ParseDuration(since)
+		if err != nil {
+			return trace.BadParameter("invalid since value %q: %v", since, err)
+		}
+	}
+	report, err := context.Operator.GetSiteReport(req)
 	if err != nil {
 		return trace.Wrap(err)
 	}
@@ -416,12 +416,12 @@ func (r *Router) GetSiteOperationCrashReport(key ops.SiteOperationKey) (io.ReadC
 	return client.GetSiteOperationCrashReport(key)
 }
 
-func (r *Router) GetSiteReport(key ops.SiteKey) (io.ReadCloser, error) {
-	client, err := r.PickClient(key.SiteDomain)
+func (r *Router) GetSiteReport(req ops.GetClusterReportRequest) (io.ReadCloser, error) {
+	client, err := r.PickClient(req.SiteDomain)
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
-	return client.GetSiteReport(key)
+	return client.GetSiteReport(req)
 }
 
 // ValidateServers runs pre-installation checks
@@ -658,12 +658,75 @@ func (r *Router) UpdateAlertTarget(key ops.SiteKey, target storage.AlertTarget)
 }
 
 // DeleteAlertTarget deletes the cluster monitoring alert target
-func (r *Router) DeleteAlertTarget(key ops.SiteKey) error {
+func (r *Router) DeleteAlertTarget(key ops.SiteKey, name string) error {
+	client, err := r.RemoteClient(key.SiteDomain)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return client.DeleteAlertTarget(key, name)
+}
+
+// DispatchAlert sends the alert event to the matching cluster alert targets
+func (r *Router) DispatchAlert(key ops.SiteKey, event monitoring.AlertEvent) error {
+	client, err := r.PickClient(key.SiteDomain)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return client.DispatchAlert(key, event)
+}
+
+// GetRemoteWrites returns the list of metrics remote-write targets
+func (r *Router) GetRemoteWrites(key ops.SiteKey) ([]storage.RemoteWrite, error) {
+	client, err := r.RemoteClient(key.SiteDomain)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return client.GetRemoteWrites(key)
+}
+
+// UpsertRemoteWrite creates or updates the metrics remote-write target
+func (r *Router) UpsertRemoteWrite(key ops.SiteKey, target storage.RemoteWrite) error {
+	client, err := r.RemoteClient(key.SiteDomain)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return client.UpsertRemoteWrite(key, target)
+}
+
+// DeleteRemoteWrite deletes the metrics remote-write target specified with name
+func (r *Router) DeleteRemoteWrite(key ops.SiteKey, name string) error {
+	client, err := r.RemoteClient(key.SiteDomain)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return client.DeleteRemoteWrite(key, name)
+}
+
+// GetRecordingRules returns the list of metrics recording rules
+func (r *Router) GetRecordingRules(key ops.SiteKey) ([]storage.RecordingRule, error) {
+	client, err := r.RemoteClient(key.SiteDomain)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return client.GetRecordingRules(key)
+}
+
+// UpsertRecordingRule creates or updates the metrics recording rule
+func (r *Router) UpsertRecordingRule(key ops.SiteKey, rule storage.RecordingRule) error {
+	client, err := r.RemoteClient(key.SiteDomain)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return client.UpsertRecordingRule(key, rule)
+}
+
+// DeleteRecordingRule deletes the metrics recording rule specified with name
+func (r *Router) DeleteRecordingRule(key ops.SiteKey, name string) error {
 	client, err := r.RemoteClient(key.SiteDomain)
 	if err != nil {
 		return trace.Wrap(err)
 	}
-	return client.DeleteAlertTarget(key)
+	return client.DeleteRecordingRule(key, name)
 }
 
 // GetClusterEnvironmentVariables retrieves the cluster runtime environment variables
@@ -300,7 +300,7 @@ func NewAgentPeerStore(backend storage.Backend, users users.Users,
 	return &AgentPeerStore{
 		FieldLogger: log,
 		teleport:    teleport,
-		groups:      make(map[ops.SiteOperationKey]agentGroup),
+		groups:      make(map[ops.SiteOperationKey]*agentGroup),
 		backend:     backend,
 		users:       users,
 	}
@@ -473,7 +473,7 @@ func (r *AgentPeerStore) getOrCreateGroup(key ops.SiteOperationKey) (*agentGroup
 	r.mu.Lock()
 	defer r.mu.Unlock()
 	if group, ok := r.groups[key]; ok {
-		return &group, nil
+		return group, nil
 	}
 
 	group, err := r.addGroup(key)
@@ -487,7 +487,7 @@ func (r *AgentPeerStore) getGroup(key ops.SiteOperationKey) (*agentGroup, error)
 	r.mu.Lock()
 	defer r.mu.Unlock()
 	if group, ok := r.groups[key]; ok {
-		return &group, nil
+		return group, nil
 	}
 
 	return nil, trace.NotFound("no execution group for %v", key)
@@ -513,15 +513,14 @@ func (r *AgentPeerStore) addGroup(key ops.SiteOperationKey) (*agentGroup, error)
 		return nil, trace.Wrap(err)
 	}
 	group.Start()
-	agentGroup := agentGroup{
+	agentGroup := &agentGroup{
 		AgentGroup: *group,
 		watchCh:    make(chan rpcserver.Peer),
 		hostnames:  make(map[string]string),
 	}
 	r.WithField("key", key).Debug("Added group.")
-	// FIXME: assignment copies lock value
 	r.groups[key] = agentGroup
-	return &agentGroup, nil
+	return agentGroup, nil
 }
 
 // AgentPeerStore manages groups of agents based on operation context.
@@ -532,7 +531,7 @@ type AgentPeerStore struct {
 	users    users.Users
 	teleport ops.TeleportProxyService
 	mu       sync.Mutex
-	groups   map[ops.SiteOperationKey]agentGroup
+	groups   map[ops.SiteOperationKey]*agentGroup
 }
 
 func (r *agentGroup) add(p rpcserver.Peer, hostname string) {
@@ -346,11 +346,11 @@ func (s *AgentSuite) token(tok string) string {
 	return fmt.Sprintf("%v.%v", tok, s.key.OperationID)
 }
 
-func newTestAgentGroup(c *C, addr, hostname string) agentGroup {
+func newTestAgentGroup(c *C, addr, hostname string) *agentGroup {
 	group, err := rpcserver.NewAgentGroup(rpcserver.AgentGroupConfig{}, []rpcserver.Peer{testPeer{addr: addr}})
 	c.Assert(err, IsNil)
 
-	return agentGroup{
+	return &agentGroup{
 		AgentGroup: *group,
 		hostnames:  map[string]string{addr: hostname},
 	}
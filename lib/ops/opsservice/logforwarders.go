@@ -24,6 +24,7 @@ import (
 
 	"github.com/gravitational/rigging"
 	"github.com/gravitational/trace"
+	"k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
 )
@@ -52,12 +53,17 @@ func (o *Operator) UpdateLogForwarders(key ops.SiteKey, forwarders []storage.Log
 			"this operator does not support log forwarders management")
 	}
 
-	forwardersV2 := make([]storage.LogForwarder, len(forwarders))
-	for i := range forwarders {
-		forwardersV2[i] = storage.NewLogForwarderFromV1(forwarders[i])
+	existing, err := o.cfg.LogForwarders.Get()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	forwardersV2, err := mergeLogForwarders(existing, forwarders)
+	if err != nil {
+		return trace.Wrap(err)
 	}
 
-	err := o.cfg.LogForwarders.Replace(forwardersV2)
+	err = o.cfg.LogForwarders.Replace(forwardersV2)
 	if err != nil {
 		return trace.Wrap(err)
 	}
@@ -70,6 +76,31 @@ func (o *Operator) UpdateLogForwarders(key ops.SiteKey, forwarders []storage.Log
 	return nil
 }
 
+// mergeLogForwarders converts the legacy forwarders into the new format.
+// A legacy forwarder that names an existing forwarder only updates its address
+// and protocol so the settings the legacy format lacks are kept
+func mergeLogForwarders(existing []storage.LogForwarder, forwarders []storage.LogForwarderV1) ([]storage.LogForwarder, error) {
+	byName := make(map[string]storage.LogForwarder, len(existing))
+	for _, forwarder := range existing {
+		byName[forwarder.GetName()] = forwarder
+	}
+	result := make([]storage.LogForwarder, 0, len(forwarders))
+	for _, forwarderV1 := range forwarders {
+		forwarder := storage.NewLogForwarderFromV1(forwarderV1)
+		if v2, ok := byName[forwarder.GetName()].(*storage.LogForwarderV2); ok {
+			merged := *v2
+			merged.Spec.Address = forwarderV1.Address
+			merged.Spec.Protocol = forwarderV1.Protocol
+			forwarder = &merged
+		}
+		if err := forwarder.CheckAndSetDefaults(); err != nil {
+			return nil, trace.Wrap(err)
+		}
+		result = append(result, forwarder)
+	}
+	return result, nil
+}
+
 // CreateLogForwarder creates a new log forwarder
 func (o *Operator) CreateLogForwarder(key ops.SiteKey, forwarder storage.LogForwarder) error {
 	if o.cfg.LogForwarders == nil {
@@ -77,6 +108,10 @@ func (o *Operator) CreateLogForwarder(key ops.SiteKey, forwarder storage.LogForw
 			"this operator does not support log forwarders management")
 	}
 
+	if err := forwarder.CheckAndSetDefaults(); err != nil {
+		return trace.Wrap(err)
+	}
+
 	err := o.cfg.LogForwarders.Create(forwarder)
 	if err != nil {
 		return trace.Wrap(err)
@@ -97,6 +132,10 @@ func (o *Operator) UpdateLogForwarder(key ops.SiteKey, forwarder storage.LogForw
 			"this operator does not support log forwarders management")
 	}
 
+	if err := forwarder.CheckAndSetDefaults(); err != nil {
+		return trace.Wrap(err)
+	}
+
 	err := o.cfg.LogForwarders.Update(forwarder)
 	if err != nil {
 		return trace.Wrap(err)
@@ -156,7 +195,8 @@ func NewLogForwardersControl(client *kubernetes.Clientset) LogForwardersControl
 	return &logForwardersControl{client}
 }
 
-// Get returns a list of log forwarders from config map
+// Get returns a list of log forwarders from config map.
+// Credentials are not returned, only references to them
 func (c *logForwardersControl) Get() ([]storage.LogForwarder, error) {
 	configMap, err := c.client.Core().ConfigMaps(defaults.KubeSystemNamespace).Get(
 		defaults.LogForwardersConfigMap, metav1.GetOptions{})
@@ -170,7 +210,7 @@ func (c *logForwardersControl) Get() ([]storage.LogForwarder, error) {
 		if err != nil {
 			return nil, trace.Wrap(err)
 		}
-		forwarders = append(forwarders, forwarder)
+		forwarders = append(forwarders, storage.LogForwarderWithoutSecrets(forwarder))
 	}
 
 	return forwarders, nil
@@ -184,8 +224,18 @@ func (c *logForwardersControl) Replace(forwarders []storage.LogForwarder) error
 		return rigging.ConvertError(err)
 	}
 
+	secret, err := c.getSecret()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
 	configMap.Data = make(map[string]string)
+	secretData := make(map[string][]byte)
 	for _, forwarder := range forwarders {
+		forwarder, err = storeSecrets(forwarder, secret.Data, secretData)
+		if err != nil {
+			return trace.Wrap(err)
+		}
 		bytes, err := storage.GetLogForwarderMarshaler().Marshal(forwarder)
 		if err != nil {
 			return trace.Wrap(err)
@@ -193,6 +243,11 @@ func (c *logForwardersControl) Replace(forwarders []storage.LogForwarder) error
 		configMap.Data[forwarder.GetName()] = string(bytes)
 	}
 
+	secret.Data = secretData
+	if err := c.updateSecret(secret); err != nil {
+		return trace.Wrap(err)
+	}
+
 	_, err = c.client.Core().ConfigMaps(defaults.KubeSystemNamespace).Update(configMap)
 	if err != nil {
 		return rigging.ConvertError(err)
@@ -214,36 +269,67 @@ func (c *logForwardersControl) Create(forwarder storage.LogForwarder) error {
 		return trace.AlreadyExists("log forwarder %q already exists", forwarder.GetName())
 	}
 
-	bytes, err := storage.GetLogForwarderMarshaler().Marshal(forwarder)
+	return trace.Wrap(c.upsert(configMap, forwarder))
+}
+
+// Update updates an existing log forwarder in the k8s config map
+func (c *logForwardersControl) Update(forwarder storage.LogForwarder) error {
+	configMap, err := c.client.Core().ConfigMaps(defaults.KubeSystemNamespace).Get(
+		defaults.LogForwardersConfigMap, metav1.GetOptions{})
 	if err != nil {
-		return trace.Wrap(err)
+		return rigging.ConvertError(err)
 	}
 
-	if configMap.Data == nil {
-		configMap.Data = make(map[string]string)
+	_, ok := configMap.Data[forwarder.GetName()]
+	if !ok {
+		return trace.NotFound("log forwarder %q not found", forwarder.GetName())
 	}
 
-	configMap.Data[forwarder.GetName()] = string(bytes)
+	return trace.Wrap(c.upsert(configMap, forwarder))
+}
+
+// Delete deletes the specified log forwarder from the k8s config map
+// along with its credentials
+func (c *logForwardersControl) Delete(name string) error {
+	configMap, err := c.client.Core().ConfigMaps(defaults.KubeSystemNamespace).Get(
+		defaults.LogForwardersConfigMap, metav1.GetOptions{})
+	if err != nil {
+		return rigging.ConvertError(err)
+	}
+
+	_, ok := configMap.Data[name]
+	if !ok {
+		return trace.NotFound("log forwarder %q not found", name)
+	}
+
+	delete(configMap.Data, name)
 
 	_, err = c.client.Core().ConfigMaps(defaults.KubeSystemNamespace).Update(configMap)
 	if err != nil {
 		return rigging.ConvertError(err)
 	}
 
-	return nil
+	secret, err := c.getSecret()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	secret.Data = withoutSecretsOf(secret.Data, name)
+	return trace.Wrap(c.updateSecret(secret))
 }
 
-// Update updates an existing log forwarder in the k8s config map
-func (c *logForwardersControl) Update(forwarder storage.LogForwarder) error {
-	configMap, err := c.client.Core().ConfigMaps(defaults.KubeSystemNamespace).Get(
-		defaults.LogForwardersConfigMap, metav1.GetOptions{})
+// upsert writes the credentials of the forwarder into the forwarders secret
+// and the forwarder itself, with references to the credentials, into the config map
+func (c *logForwardersControl) upsert(configMap *v1.ConfigMap, forwarder storage.LogForwarder) error {
+	secret, err := c.getSecret()
 	if err != nil {
-		return rigging.ConvertError(err)
+		return trace.Wrap(err)
 	}
 
-	_, ok := configMap.Data[forwarder.GetName()]
-	if !ok {
-		return trace.NotFound("log forwarder %q not found", forwarder.GetName())
+	secretData := withoutSecretsOf(secret.Data, forwarder.GetName())
+	forwarder, err = storeSecrets(forwarder, secret.Data, secretData)
+	if err != nil {
+		return trace.Wrap(err)
 	}
 
 	bytes, err := storage.GetLogForwarderMarshaler().Marshal(forwarder)
@@ -251,6 +337,11 @@ func (c *logForwardersControl) Update(forwarder storage.LogForwarder) error {
 		return trace.Wrap(err)
 	}
 
+	secret.Data = secretData
+	if err := c.updateSecret(secret); err != nil {
+		return trace.Wrap(err)
+	}
+
 	if configMap.Data == nil {
 		configMap.Data = make(map[string]string)
 	}
@@ -265,29 +356,123 @@ func (c *logForwardersControl) Update(forwarder storage.LogForwarder) error {
 	return nil
 }
 
-// Delete deletes the specified log forwarder from the k8s config map
-func (c *logForwardersControl) Delete(name string) error {
-	configMap, err := c.client.Core().ConfigMaps(defaults.KubeSystemNamespace).Get(
-		defaults.LogForwardersConfigMap, metav1.GetOptions{})
-	if err != nil {
-		return rigging.ConvertError(err)
+// getSecret returns the secret with log forwarders credentials
+// or a new empty secret if it does not exist yet
+func (c *logForwardersControl) getSecret() (*v1.Secret, error) {
+	secret, err := c.client.Core().Secrets(defaults.KubeSystemNamespace).Get(
+		defaults.LogForwardersSecret, metav1.GetOptions{})
+	err = rigging.ConvertError(err)
+	if err == nil {
+		return secret, nil
 	}
+	if !trace.IsNotFound(err) {
+		return nil, trace.Wrap(err)
+	}
+	return &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      defaults.LogForwardersSecret,
+			Namespace: defaults.KubeSystemNamespace,
+		},
+		Type: v1.SecretTypeOpaque,
+	}, nil
+}
 
-	_, ok := configMap.Data[name]
+// updateSecret creates or updates the secret with log forwarders credentials
+func (c *logForwardersControl) updateSecret(secret *v1.Secret) error {
+	client := c.client.Core().Secrets(defaults.KubeSystemNamespace)
+	if secret.ResourceVersion == "" {
+		_, err := client.Create(secret)
+		return trace.Wrap(rigging.ConvertError(err))
+	}
+	_, err := client.Update(secret)
+	return trace.Wrap(rigging.ConvertError(err))
+}
+
+// storeSecrets moves the credentials of the forwarder into data and returns
+// a copy of the forwarder that references them instead.
+// Credentials the forwarder only references are carried over from existing
+func storeSecrets(forwarder storage.LogForwarder, existing, data map[string][]byte) (storage.LogForwarder, error) {
+	v2, ok := forwarder.(*storage.LogForwarderV2)
 	if !ok {
-		return trace.NotFound("log forwarder %q not found", name)
+		return forwarder, nil
+	}
+	out := *v2
+	name := out.GetName()
+	if out.Spec.TLS != nil {
+		tlsConfig := *out.Spec.TLS
+		ref, err := storeSecret(name, logForwarderClientKey, tlsConfig.ClientKey, tlsConfig.ClientKeyRef, existing, data)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		tlsConfig.ClientKeyRef = ref
+		out.Spec.TLS = &tlsConfig
+	}
+	if out.Spec.HTTP != nil {
+		endpoint := *out.Spec.HTTP
+		ref, err := storeSecret(name, logForwarderToken, endpoint.Token, endpoint.TokenRef, existing, data)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		endpoint.TokenRef = ref
+		ref, err = storeSecret(name, logForwarderPassword, endpoint.Password, endpoint.PasswordRef, existing, data)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		endpoint.PasswordRef = ref
+		out.Spec.HTTP = &endpoint
 	}
+	return storage.LogForwarderWithoutSecrets(&out), nil
+}
 
-	delete(configMap.Data, name)
+// storeSecret stores a single credential of the named forwarder in data
+// and returns the reference to it
+func storeSecret(name, field, value string, ref *storage.LogForwarderSecretRef, existing, data map[string][]byte) (*storage.LogForwarderSecretRef, error) {
+	key := logForwarderSecretKey(name, field)
+	switch {
+	case value != "":
+		data[key] = []byte(value)
+	case ref != nil:
+		stored, ok := existing[ref.Key]
+		if ref.Name != defaults.LogForwardersSecret || !ok {
+			return nil, trace.NotFound("%v of log forwarder %q is not found in secret %v/%v, specify it explicitly",
+				field, name, ref.Name, ref.Key)
+		}
+		data[key] = stored
+	default:
+		return nil, nil
+	}
+	return &storage.LogForwarderSecretRef{
+		Name: defaults.LogForwardersSecret,
+		Key:  key,
+	}, nil
+}
 
-	_, err = c.client.Core().ConfigMaps(defaults.KubeSystemNamespace).Update(configMap)
-	if err != nil {
-		return rigging.ConvertError(err)
+// withoutSecretsOf returns a copy of the secret data without credentials of the named forwarder
+func withoutSecretsOf(data map[string][]byte, name string) map[string][]byte {
+	out := make(map[string][]byte, len(data))
+	for key, value := range data {
+		out[key] = value
 	}
+	for _, field := range []string{logForwarderClientKey, logForwarderToken, logForwarderPassword} {
+		delete(out, logForwarderSecretKey(name, field))
+	}
+	return out
+}
 
-	return nil
+// logForwarderSecretKey returns the key of the named forwarder credential in the secret
+func logForwarderSecretKey(name, field string) string {
+	return name + "." + field
 }
 
+const (
+	// logForwarderClientKey names the TLS client key of a log forwarder in the secret
+	logForwarderClientKey = "client_key"
+	// logForwarderToken names the Splunk token of a log forwarder in the secret
+	logForwarderToken = "token"
+	// logForwarderPassword names the Elasticsearch password of a log forwarder in the secret
+	logForwarderPassword = "password"
+)
+
 // Reload forces log collector to reload forwarder configuration
 func (c *logForwardersControl) Reload() error {
 	err := c.client.Core().Pods(defaults.KubeSystemNamespace).DeleteCollection(nil, metav1.ListOptions{
@@ -0,0 +1,93 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package opsservice
+
+import (
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/storage"
+
+	"github.com/gravitational/trace"
+	"gopkg.in/check.v1"
+)
+
+type LogForwardersSuite struct{}
+
+var _ = check.Suite(&LogForwardersSuite{})
+
+func (s *LogForwardersSuite) TestMovesCredentialsToSecret(c *check.C) {
+	forwarder := storage.NewLogForwarder("splunk", "https://splunk.example.com:8088", storage.LogForwarderProtocolHTTP)
+	forwarder.(*storage.LogForwarderV2).Spec.HTTP = &storage.LogForwarderHTTP{
+		Type:  storage.LogForwarderSplunk,
+		Token: "token",
+	}
+	existing := map[string][]byte{
+		"splunk.password": []byte("stale"),
+		"other.token":     []byte("other"),
+	}
+
+	data := withoutSecretsOf(existing, forwarder.GetName())
+	stored, err := storeSecrets(forwarder, existing, data)
+	c.Assert(err, check.IsNil)
+	c.Assert(data, check.DeepEquals, map[string][]byte{
+		"splunk.token": []byte("token"),
+		"other.token":  []byte("other"),
+	})
+	endpoint := stored.(*storage.LogForwarderV2).Spec.HTTP
+	c.Assert(endpoint.Token, check.Equals, "")
+	c.Assert(endpoint.TokenRef, check.DeepEquals, &storage.LogForwarderSecretRef{
+		Name: defaults.LogForwardersSecret,
+		Key:  "splunk.token",
+	})
+	c.Assert(endpoint.PasswordRef, check.IsNil)
+	c.Assert(stored.CheckAndSetDefaults(), check.IsNil)
+
+	// a forwarder read back without credentials keeps the stored ones
+	updated := make(map[string][]byte)
+	_, err = storeSecrets(stored, data, updated)
+	c.Assert(err, check.IsNil)
+	c.Assert(updated, check.DeepEquals, map[string][]byte{"splunk.token": []byte("token")})
+
+	_, err = storeSecrets(stored, existing, make(map[string][]byte))
+	c.Assert(trace.IsNotFound(err), check.Equals, true)
+}
+
+func (s *LogForwardersSuite) TestMergesLegacyForwarders(c *check.C) {
+	existing := storage.NewLogForwarder("splunk.example.com_514", "splunk.example.com:514", "tcp")
+	existing.(*storage.LogForwarderV2).Spec.Format = storage.LogForwarderFormatJSON
+	existing.(*storage.LogForwarderV2).Spec.TLS = &storage.LogForwarderTLS{
+		ServerName: "splunk.example.com",
+	}
+
+	merged, err := mergeLogForwarders([]storage.LogForwarder{existing}, []storage.LogForwarderV1{
+		{Address: "splunk.example.com:514", Protocol: "tcp"},
+		{Address: "syslog.example.com:514", Protocol: "udp"},
+	})
+	c.Assert(err, check.IsNil)
+	c.Assert(merged, check.HasLen, 2)
+	spec := merged[0].(*storage.LogForwarderV2).Spec
+	c.Assert(spec.Format, check.Equals, storage.LogForwarderFormatJSON)
+	c.Assert(spec.TLS, check.DeepEquals, existing.(*storage.LogForwarderV2).Spec.TLS)
+	c.Assert(merged[1].GetName(), check.Equals, "syslog.example.com_514")
+	c.Assert(merged[1].(*storage.LogForwarderV2).Spec.Protocol, check.Equals, "udp")
+
+	// the merged forwarder is validated
+	_, err = mergeLogForwarders([]storage.LogForwarder{existing}, []storage.LogForwarderV1{
+		{Address: "splunk.example.com:514", Protocol: "udp"},
+	})
+	c.Assert(trace.IsBadParameter(err), check.Equals, true, check.Commentf("%v", err))
+	c.Assert(existing.(*storage.LogForwarderV2).Spec.Protocol, check.Equals, "tcp")
+}
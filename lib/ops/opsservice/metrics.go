@@ -0,0 +1,128 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package opsservice
+
+import (
+	"strings"
+	"time"
+
+	"github.com/gravitational/gravity/lib/ops"
+	"github.com/gravitational/gravity/lib/storage"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	operationsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "gravity_operations_total",
+			Help: "Number of cluster operations that have moved into a state, by operation type and state",
+		},
+		[]string{"type", "state"},
+	)
+	operationDurations = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name: "gravity_operation_duration_seconds",
+			Help: "Duration of completed cluster operations, by operation type and final state",
+			// lowest bucket start of upper bound 10 sec with factor 2
+			// highest bucket start of 10 sec * 2^11 == 5.7 hours
+			Buckets: prometheus.ExponentialBuckets(10, 2, 12),
+		},
+		[]string{"type", "state"},
+	)
+	phaseDurations = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name: "gravity_phase_duration_seconds",
+			Help: "Duration of operation plan phase executions, by top-level phase and resulting state",
+			// lowest bucket start of upper bound 0.1 sec with factor 2
+			// highest bucket start of 0.1 sec * 2^15 == 54.6 minutes
+			Buckets: prometheus.ExponentialBuckets(0.1, 2, 16),
+		},
+		[]string{"phase", "state"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(operationsTotal)
+	prometheus.MustRegister(operationDurations)
+	prometheus.MustRegister(phaseDurations)
+}
+
+// observeOperation records the transition of the operation into a new state.
+// prevState is the state of the operation before the transition
+func observeOperation(operation ops.SiteOperation, prevState string, now time.Time) {
+	if operation.State == prevState {
+		return
+	}
+	operationsTotal.WithLabelValues(operation.Type, operation.State).Inc()
+	if !operation.IsFinished() || isFinishedState(prevState) || operation.Created.IsZero() {
+		return
+	}
+	operationDurations.WithLabelValues(operation.Type, operation.State).
+		Observe(now.Sub(operation.Created).Seconds())
+}
+
+// observePhase records the duration of the phase attempt completed with the specified change.
+// changelog is the operation plan changelog including the change
+func observePhase(change storage.PlanChange, changelog storage.PlanChangelog) {
+	attempts := changelog.Attempts(change.PhaseID)
+	if len(attempts) == 0 {
+		return
+	}
+	attempt := attempts[len(attempts)-1]
+	if attempt.Started.IsZero() || !attempt.Finished.Equal(change.Created) {
+		return
+	}
+	phaseDurations.WithLabelValues(topLevelPhase(change.PhaseID), change.NewState).
+		Observe(attempt.Finished.Sub(attempt.Started).Seconds())
+}
+
+// topLevelPhase returns the ID of the top-level phase of the phase
+// specified with phaseID, e.g. "/masters" for "/masters/node-1/kubelet".
+// Sub-phases are aggregated to keep the number of label values bounded
+func topLevelPhase(phaseID string) string {
+	parts := strings.SplitN(strings.TrimPrefix(phaseID, "/"), "/", 2)
+	return "/" + parts[0]
+}
+
+func isFinishedState(state string) bool {
+	return state == ops.OperationStateCompleted || state == ops.OperationStateFailed
+}
+
+var agentsDesc = prometheus.NewDesc(
+	"gravity_rpc_agents",
+	"Number of RPC agents in the agent groups of active operations",
+	nil, nil,
+)
+
+// Describe sends the descriptors of the agent metrics to ch.
+// Implements prometheus.Collector
+func (r *AgentPeerStore) Describe(ch chan<- *prometheus.Desc) {
+	ch <- agentsDesc
+}
+
+// Collect sends the number of agents connected to this process to ch.
+// Implements prometheus.Collector
+func (r *AgentPeerStore) Collect(ch chan<- prometheus.Metric) {
+	r.mu.Lock()
+	var agents int
+	for _, group := range r.groups {
+		agents += group.NumPeers()
+	}
+	r.mu.Unlock()
+	ch <- prometheus.MustNewConstMetric(agentsDesc, prometheus.GaugeValue, float64(agents))
+}
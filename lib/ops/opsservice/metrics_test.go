@@ -0,0 +1,84 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package opsservice
+
+import (
+	"time"
+
+	"github.com/gravitational/gravity/lib/ops"
+	"github.com/gravitational/gravity/lib/storage"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"gopkg.in/check.v1"
+)
+
+type MetricsSuite struct{}
+
+var _ = check.Suite(&MetricsSuite{})
+
+func (s *MetricsSuite) TestObservesOperations(c *check.C) {
+	created := time.Date(2019, time.March, 1, 10, 0, 0, 0, time.UTC)
+	operation := ops.SiteOperation{
+		Type:    "operation_metrics_test",
+		State:   ops.OperationStateCompleted,
+		Created: created,
+	}
+	observeOperation(operation, ops.OperationStateCompleted, created.Add(time.Minute))
+	c.Assert(counterValue(c, operationsTotal.WithLabelValues(operation.Type, operation.State)), check.Equals, float64(0))
+
+	observeOperation(operation, "", created.Add(time.Minute))
+	c.Assert(counterValue(c, operationsTotal.WithLabelValues(operation.Type, operation.State)), check.Equals, float64(1))
+	sum, count := histogramValue(c, operationDurations.WithLabelValues(operation.Type, operation.State))
+	c.Assert(count, check.Equals, uint64(1))
+	c.Assert(sum, check.Equals, time.Minute.Seconds())
+
+	// moving between final states does not count the duration again
+	operation.State = ops.OperationStateFailed
+	observeOperation(operation, ops.OperationStateCompleted, created.Add(2*time.Minute))
+	c.Assert(counterValue(c, operationsTotal.WithLabelValues(operation.Type, operation.State)), check.Equals, float64(1))
+	_, count = histogramValue(c, operationDurations.WithLabelValues(operation.Type, operation.State))
+	c.Assert(count, check.Equals, uint64(0))
+}
+
+func (s *MetricsSuite) TestObservesPhases(c *check.C) {
+	started := time.Date(2019, time.March, 1, 10, 0, 0, 0, time.UTC)
+	changelog := storage.PlanChangelog{
+		{PhaseID: "/metricstest/node-1/kubelet", NewState: storage.OperationPhaseStateInProgress, Created: started},
+		{PhaseID: "/metricstest/node-1/kubelet", NewState: storage.OperationPhaseStateCompleted, Created: started.Add(30 * time.Second)},
+		{PhaseID: "/metricstest/node-2", NewState: storage.OperationPhaseStateCompleted, Created: started.Add(time.Minute)},
+	}
+	for _, change := range changelog {
+		observePhase(change, changelog)
+	}
+	sum, count := histogramValue(c, phaseDurations.WithLabelValues("/metricstest", storage.OperationPhaseStateCompleted))
+	// the phase completed without execution has no duration
+	c.Assert(count, check.Equals, uint64(1))
+	c.Assert(sum, check.Equals, float64(30))
+}
+
+func counterValue(c *check.C, metric prometheus.Metric) float64 {
+	var out dto.Metric
+	c.Assert(metric.Write(&out), check.IsNil)
+	return out.GetCounter().GetValue()
+}
+
+func histogramValue(c *check.C, metric prometheus.Metric) (sum float64, count uint64) {
+	var out dto.Metric
+	c.Assert(metric.Write(&out), check.IsNil)
+	return out.GetHistogram().GetSampleSum(), out.GetHistogram().GetSampleCount()
+}
@@ -17,6 +17,8 @@ limitations under the License.
 package opsservice
 
 import (
+	"context"
+
 	"github.com/gravitational/gravity/lib/constants"
 	"github.com/gravitational/gravity/lib/defaults"
 	"github.com/gravitational/gravity/lib/ops"
@@ -48,6 +50,53 @@ func (o *Operator) UpdateRetentionPolicy(req ops.UpdateRetentionPolicyRequest) e
 	})
 }
 
+// GetRemoteWrites returns the list of metrics remote-write targets.
+//
+// Credentials of the remote-write targets are not returned
+func (o *Operator) GetRemoteWrites(key ops.SiteKey) ([]storage.RemoteWrite, error) {
+	targets, err := o.cfg.Monitoring.GetRemoteWrites()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	for i, target := range targets {
+		targets[i] = storage.RemoteWriteWithoutSecrets(target)
+	}
+	return targets, nil
+}
+
+// UpsertRemoteWrite creates or updates the metrics remote-write target.
+//
+// Credentials omitted from an existing target are kept
+func (o *Operator) UpsertRemoteWrite(key ops.SiteKey, target storage.RemoteWrite) error {
+	if err := target.CheckAndSetDefaults(); err != nil {
+		return trace.Wrap(err)
+	}
+	return o.cfg.Monitoring.UpsertRemoteWrite(target)
+}
+
+// DeleteRemoteWrite deletes the metrics remote-write target specified with name
+func (o *Operator) DeleteRemoteWrite(key ops.SiteKey, name string) error {
+	return o.cfg.Monitoring.DeleteRemoteWrite(name)
+}
+
+// GetRecordingRules returns the list of metrics recording rules
+func (o *Operator) GetRecordingRules(key ops.SiteKey) ([]storage.RecordingRule, error) {
+	return o.cfg.Monitoring.GetRecordingRules()
+}
+
+// UpsertRecordingRule creates or updates the metrics recording rule
+func (o *Operator) UpsertRecordingRule(key ops.SiteKey, rule storage.RecordingRule) error {
+	if err := rule.CheckAndSetDefaults(); err != nil {
+		return trace.Wrap(err)
+	}
+	return o.cfg.Monitoring.UpsertRecordingRule(rule)
+}
+
+// DeleteRecordingRule deletes the metrics recording rule specified with name
+func (o *Operator) DeleteRecordingRule(key ops.SiteKey, name string) error {
+	return o.cfg.Monitoring.DeleteRecordingRule(name)
+}
+
 // GetAlerts returns a list of configured monitoring alerts
 func (o *Operator) GetAlerts(key ops.SiteKey) (alerts []storage.Alert, err error) {
 	client, err := o.GetKubeClient()
@@ -78,6 +127,10 @@ func (o *Operator) GetAlerts(key ops.SiteKey) (alerts []storage.Alert, err error
 			errors = append(errors, err)
 			continue
 		}
+		if alert.GetSpec().IsStructured() {
+			// Hide the generated formula
+			alert.SetFormula("")
+		}
 		alerts = append(alerts, alert)
 	}
 
@@ -88,13 +141,28 @@ func (o *Operator) GetAlerts(key ops.SiteKey) (alerts []storage.Alert, err error
 	return alerts, nil
 }
 
-// UpdateAlert updates the specified monitoring alert
+// UpdateAlert updates the specified monitoring alert.
+//
+// If the alert is defined with a metric, the kapacitor formula
+// is generated from the alert specification
 func (o *Operator) UpdateAlert(key ops.SiteKey, alert storage.Alert) error {
 	client, err := o.GetKubeClient()
 	if err != nil {
 		return trace.Wrap(err)
 	}
 
+	if err := alert.CheckAndSetDefaults(); err != nil {
+		return trace.Wrap(err)
+	}
+
+	if alert.GetSpec().IsStructured() {
+		formula, err := monitoring.CompileAlert(alert)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		alert.SetFormula(formula)
+	}
+
 	data, err := storage.MarshalAlert(alert)
 	if err != nil {
 		return trace.Wrap(err)
@@ -140,7 +208,9 @@ func (o *Operator) DeleteAlert(key ops.SiteKey, name string) error {
 	return trace.Wrap(rigging.ConvertError(err))
 }
 
-// GetAlertTargets returns a list of configured monitoring alert targets
+// GetAlertTargets returns a list of configured monitoring alert targets.
+//
+// Credentials of the alert targets are not returned
 func (o *Operator) GetAlertTargets(key ops.SiteKey) (targets []storage.AlertTarget, err error) {
 	client, err := o.GetKubeClient()
 	if err != nil {
@@ -149,22 +219,36 @@ func (o *Operator) GetAlertTargets(key ops.SiteKey) (targets []storage.AlertTarg
 
 	data, err := getConfigMap(client.Core().ConfigMaps(defaults.MonitoringNamespace),
 		constants.AlertTargetConfigMap)
-	if err != nil {
-		if trace.IsNotFound(err) {
-			return nil, trace.NotFound("alert target not found")
-		}
+	if err != nil && !trace.IsNotFound(err) {
 		return nil, trace.Wrap(err)
 	}
+	if err == nil {
+		target, err := storage.UnmarshalAlertTarget([]byte(data))
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		targets = append(targets, target)
+	}
 
-	target, err := storage.UnmarshalAlertTarget([]byte(data))
+	receivers, err := getAlertReceivers(client.Core().Secrets(defaults.MonitoringNamespace))
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
+	for _, receiver := range receivers {
+		targets = append(targets, storage.AlertTargetWithoutSecrets(receiver))
+	}
+	if len(targets) == 0 {
+		return nil, trace.NotFound("alert target not found")
+	}
 
-	return []storage.AlertTarget{target}, nil
+	return targets, nil
 }
 
-// UpdateAlertTarget updates the cluster monitoring alert target
+// UpdateAlertTarget updates the cluster monitoring alert target.
+//
+// The email alert target is stored in the ConfigMap consumed by the monitoring
+// system, while all other alert targets carry credentials and are stored
+// in separate Secrets. They receive alerts from DispatchAlert
 func (o *Operator) UpdateAlertTarget(key ops.SiteKey, target storage.AlertTarget) error {
 	client, err := o.GetKubeClient()
 	if err != nil {
@@ -176,6 +260,11 @@ func (o *Operator) UpdateAlertTarget(key ops.SiteKey, target storage.AlertTarget
 		return trace.Wrap(err)
 	}
 
+	if target.GetTargetType() != storage.AlertTargetEmail {
+		return updateAlertReceiver(client.Core().Secrets(defaults.MonitoringNamespace),
+			target.GetName(), data)
+	}
+
 	labels := map[string]string{
 		constants.MonitoringType: constants.MonitoringTypeAlertTarget,
 	}
@@ -183,20 +272,132 @@ func (o *Operator) UpdateAlertTarget(key ops.SiteKey, target storage.AlertTarget
 		constants.AlertTargetConfigMap, defaults.MonitoringNamespace, string(data), labels)
 }
 
-// DeleteAlertTarget deletes the cluster monitoring alert target
-func (o *Operator) DeleteAlertTarget(key ops.SiteKey) error {
+// DeleteAlertTarget deletes the cluster monitoring alert target specified with name.
+// The email alert target is deleted if name is empty or names the email alert target
+func (o *Operator) DeleteAlertTarget(key ops.SiteKey, name string) error {
 	client, err := o.GetKubeClient()
 	if err != nil {
 		return trace.Wrap(err)
 	}
 
-	err = rigging.ConvertError(client.Core().ConfigMaps(defaults.MonitoringNamespace).Delete(constants.AlertTargetConfigMap, nil))
+	configMaps := client.Core().ConfigMaps(defaults.MonitoringNamespace)
+	if name != "" {
+		err = rigging.ConvertError(client.Core().Secrets(defaults.MonitoringNamespace).Delete(
+			alertReceiverSecret(name), nil))
+		if err == nil || !trace.IsNotFound(err) {
+			return trace.Wrap(err)
+		}
+		// Fall back to the email alert target with this name
+		data, err := getConfigMap(configMaps, constants.AlertTargetConfigMap)
+		if err != nil && !trace.IsNotFound(err) {
+			return trace.Wrap(err)
+		}
+		if err != nil {
+			return trace.NotFound("alert target %q not found", name)
+		}
+		target, err := storage.UnmarshalAlertTarget([]byte(data))
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		if target.GetName() != name {
+			return trace.NotFound("alert target %q not found", name)
+		}
+	}
+
+	err = rigging.ConvertError(configMaps.Delete(constants.AlertTargetConfigMap, nil))
 	if trace.IsNotFound(err) {
 		return trace.NotFound("no alert targets found")
 	}
 	return trace.Wrap(err)
 }
 
+// DispatchAlert sends the alert event to the alert targets matching it.
+//
+// If the event belongs to an alert defined with a metric, the labels
+// of the alert are added to the event
+func (o *Operator) DispatchAlert(key ops.SiteKey, event monitoring.AlertEvent) error {
+	client, err := o.GetKubeClient()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	targets, err := getAlertReceivers(client.Core().Secrets(defaults.MonitoringNamespace))
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if len(targets) == 0 {
+		return nil
+	}
+
+	alerts, err := o.GetAlerts(key)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	event.Labels = monitoring.AlertLabels(alerts, event)
+
+	return monitoring.Dispatch(context.TODO(), monitoring.DispatchConfig{
+		Targets:     targets,
+		FieldLogger: o.WithField("alert", event.Name),
+	}, event)
+}
+
+// getAlertReceivers returns the alert targets stored in alert receiver Secrets
+func getAlertReceivers(client corev1.SecretInterface) (targets []storage.AlertTarget, err error) {
+	labels := kubelabels.Set{
+		constants.MonitoringType: constants.MonitoringTypeAlertReceiver,
+	}
+	secrets, err := client.List(metav1.ListOptions{LabelSelector: labels.String()})
+	if err != nil {
+		return nil, trace.Wrap(rigging.ConvertError(err))
+	}
+
+	for _, secret := range secrets.Items {
+		data, ok := secret.Data[constants.ResourceSpecKey]
+		if !ok {
+			continue
+		}
+		target, err := storage.UnmarshalAlertTarget(data)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		targets = append(targets, target)
+	}
+	return targets, nil
+}
+
+func updateAlertReceiver(client corev1.SecretInterface, name string, data []byte) error {
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      alertReceiverSecret(name),
+			Namespace: defaults.MonitoringNamespace,
+			Labels: map[string]string{
+				constants.MonitoringType: constants.MonitoringTypeAlertReceiver,
+			},
+		},
+		Data: map[string][]byte{
+			constants.ResourceSpecKey: data,
+		},
+		Type: v1.SecretTypeOpaque,
+	}
+
+	_, err := client.Create(secret)
+	err = rigging.ConvertError(err)
+	if err == nil {
+		return nil
+	}
+
+	if !trace.IsAlreadyExists(err) {
+		return trace.Wrap(err)
+	}
+
+	_, err = client.Update(secret)
+	return trace.Wrap(rigging.ConvertError(err))
+}
+
+func alertReceiverSecret(name string) string {
+	return constants.AlertReceiverSecretPrefix + name
+}
+
 func getConfigMap(client corev1.ConfigMapInterface, name string) (string, error) {
 	config, err := client.Get(name, metav1.GetOptions{})
 	if err != nil {
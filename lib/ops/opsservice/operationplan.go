@@ -26,7 +26,11 @@ import (
 
 // CreateOperationPlan saves the provided operation plan
 func (o *Operator) CreateOperationPlan(key ops.SiteOperationKey, plan storage.OperationPlan) error {
-	_, err := o.backend().CreateOperationPlan(plan)
+	err := fsm.CheckPlan(plan)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	_, err = o.backend().CreateOperationPlan(plan)
 	if err != nil {
 		return trace.Wrap(err)
 	}
@@ -39,6 +43,14 @@ func (o *Operator) CreateOperationPlanChange(key ops.SiteOperationKey, change st
 	if err != nil {
 		return trace.Wrap(err)
 	}
+	if change.NewState != storage.OperationPhaseStateInProgress {
+		changelog, err := o.backend().GetOperationPlanChangelog(key.SiteDomain, key.OperationID)
+		if err != nil {
+			o.Warnf("Failed to retrieve changelog of operation %v: %v.", key, trace.DebugReport(err))
+			return nil
+		}
+		observePhase(change, changelog)
+	}
 	return nil
 }
 
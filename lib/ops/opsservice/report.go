@@ -25,7 +25,7 @@ import (
 	"io"
 	"io/ioutil"
 	"os"
-	"path/filepath"
+	"time"
 
 	"github.com/gravitational/gravity/lib/app"
 	"github.com/gravitational/gravity/lib/archive"
@@ -63,10 +63,10 @@ func (s *site) getSiteOperationCrashReport(op ops.SiteOperation) (io.ReadCloser,
 	}
 
 	runner := s.agentRunner(ctx)
-	return s.getReport(runner, remoteServers, master)
+	return s.getReport(runner, remoteServers, master, ops.GetClusterReportRequest{})
 }
 
-func (s *site) getSiteReport() (io.ReadCloser, error) {
+func (s *site) getSiteReport(req ops.GetClusterReportRequest) (io.ReadCloser, error) {
 	const noRetry = 1
 	servers, err := s.getTeleportServersWithTimeout(
 		nil,
@@ -93,41 +93,52 @@ func (s *site) getSiteReport() (io.ReadCloser, error) {
 		remoteServers = append(remoteServers, teleportServer)
 	}
 
-	return s.getReport(teleportRunner, remoteServers, master)
+	return s.getReport(teleportRunner, remoteServers, master, req)
 }
 
-func (s *site) getReport(runner remoteRunner, servers []remoteServer, master remoteServer) (io.ReadCloser, error) {
+func (s *site) getReport(runner remoteRunner, servers []remoteServer, master remoteServer, req ops.GetClusterReportRequest) (io.ReadCloser, error) {
+	redactor, err := report.NewRedactor(req.RedactPatterns)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+
 	dir, err := ioutil.TempDir("", "report")
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
 
-	err = runCollectors(*s, dir, runner)
+	reportWriter := redactor.Writer(report.NewFileWriter(dir))
+	err = runCollectors(*s, reportWriter)
 	if err != nil {
 		// Intermediate steps in diagnostics collection are not fatal
 		// to collect all possible pieces in best-effort
 		log.Errorf("failed to run cluster collectors: %v", trace.DebugReport(err))
 	}
 
-	collectOperationsLogs(*s, dir)
+	collectOperationsLogs(*s, reportWriter, req.Since)
 
 	if len(servers) > 0 {
 		// Use the first master server to collect kubernetes diagnostics
+		// regardless of the node filter
 		server := master
 		if server == nil {
 			server = servers[0]
 			log.Warningf("no master servers, collecting kubernetes diagnostics from %v", server)
 		}
 		serverRunner := &serverRunner{server: server, runner: runner}
-		reportWriter := getReportWriterForServer(dir, server)
-		s.collectKubernetesInfo(reportWriter, serverRunner)
+		s.collectKubernetesInfo(getReportWriterForServer(reportWriter, server), serverRunner, req)
 
-		err = s.collectDebugInfoFromServers(dir, servers, runner)
+		servers = filterReportServers(servers, req.Nodes)
+		err = s.collectDebugInfoFromServers(reportWriter, servers, runner, req)
 		if err != nil {
 			log.Errorf("failed to collect diagnostics from some nodes: %v", trace.DebugReport(err))
 		}
 	}
 
+	if err := redactor.WriteManifest(report.NewFileWriter(dir)); err != nil {
+		log.Errorf("failed to write redaction manifest: %v", trace.DebugReport(err))
+	}
+
 	// use a pipe to avoid allocating a buffer
 	reader, writer := io.Pipe()
 	gzWriter := gzip.NewWriter(writer)
@@ -148,20 +159,19 @@ func (s *site) getReport(runner remoteRunner, servers []remoteServer, master rem
 }
 
 // collectDebugInfoFromServers collects diagnostic information from servers
-// and stores each piece into a file in directory dir.
+// and stores each piece using the specified report writer.
 // Files are named using the following pattern:
 //
 //   <server-name>-<resource>
 //
-func (s *site) collectDebugInfoFromServers(dir string, servers []remoteServer, runner remoteRunner) error {
+func (s *site) collectDebugInfoFromServers(reportWriter report.Writer, servers []remoteServer, runner remoteRunner, req ops.GetClusterReportRequest) error {
 	err := s.executeOnServers(context.TODO(), servers, func(c context.Context, server remoteServer) error {
 		log.Debugf("collectDebugInfo for %v", server)
 		r := &serverRunner{
 			server: server,
 			runner: runner,
 		}
-		reportWriter := getReportWriterForServer(dir, server)
-		err := s.collectDebugInfo(reportWriter, r)
+		err := s.collectDebugInfo(getReportWriterForServer(reportWriter, server), r, req)
 		return trace.Wrap(err)
 	})
 	if err != nil {
@@ -170,38 +180,89 @@ func (s *site) collectDebugInfoFromServers(dir string, servers []remoteServer, r
 	return nil
 }
 
-func (s *site) collectDebugInfo(reportWriter report.Writer, runner *serverRunner) error {
+func (s *site) collectDebugInfo(reportWriter report.Writer, runner *serverRunner, req ops.GetClusterReportRequest) error {
 	w, err := reportWriter("debug-logs.tar")
 	if err != nil {
 		return trace.Wrap(err)
 	}
 	defer w.Close()
 
-	err = runner.RunStream(w, s.gravityCommand("system", "report",
-		fmt.Sprintf("--filter=%v", constants.ReportFilterSystem),
-		"--compressed")...)
+	err = runner.RunStream(w, s.gravityCommand(SystemReportArgs(req, constants.ReportFilterSystem)...)...)
 	if err != nil {
 		return trace.Wrap(err, "failed to collect diagnostics")
 	}
 	return nil
 }
 
-func (s *site) collectKubernetesInfo(reportWriter report.Writer, runner *serverRunner) error {
+func (s *site) collectKubernetesInfo(reportWriter report.Writer, runner *serverRunner, req ops.GetClusterReportRequest) error {
 	w, err := reportWriter("k8s-logs.tar")
 	if err != nil {
 		return trace.Wrap(err)
 	}
 	defer w.Close()
 
-	err = runner.RunStream(w, s.gravityCommand("system", "report",
-		fmt.Sprintf("--filter=%v", constants.ReportFilterKubernetes), "--compressed")...)
+	err = runner.RunStream(w, s.gravityCommand(SystemReportArgs(req, constants.ReportFilterKubernetes)...)...)
 	if err != nil {
 		return trace.Wrap(err, "failed to collect kubernetes diagnostics")
 	}
 	return nil
 }
 
-func runCollectors(site site, dir string, runner remoteRunner) error {
+// SystemReportArgs returns the arguments of the system report command
+// that collects the specified diagnostics on a node
+func SystemReportArgs(req ops.GetClusterReportRequest, filters ...string) []string {
+	args := []string{"system", "report"}
+	for _, filter := range filters {
+		args = append(args, fmt.Sprintf("--filter=%v", filter))
+	}
+	args = append(args, "--compressed")
+	if req.Since != 0 {
+		args = append(args, fmt.Sprintf("--since=%v", req.Since))
+	}
+	for _, namespace := range req.Namespaces {
+		args = append(args, fmt.Sprintf("--namespace=%v", namespace))
+	}
+	// patterns are encoded to survive the remote shell
+	for _, pattern := range report.EncodeRedactPatterns(req.RedactPatterns) {
+		args = append(args, fmt.Sprintf("--encoded-redact=%v", pattern))
+	}
+	return args
+}
+
+// FilterReportServers returns the servers that match the specified
+// hostnames or addresses. Returns all servers if nodes is empty
+func FilterReportServers(servers []storage.Server, nodes []string) (result []storage.Server) {
+	if len(nodes) == 0 {
+		return servers
+	}
+	for _, server := range servers {
+		if isReportNode(nodes, server.Hostname, server.AdvertiseIP) {
+			result = append(result, server)
+		}
+	}
+	return result
+}
+
+// filterReportServers is FilterReportServers for remote servers
+func filterReportServers(servers []remoteServer, nodes []string) (result []remoteServer) {
+	if len(nodes) == 0 {
+		return servers
+	}
+	for _, server := range servers {
+		if isReportNode(nodes, server.HostName(), utils.ExtractHost(server.Address())) {
+			result = append(result, server)
+		}
+	}
+	return result
+}
+
+// isReportNode returns whether the server with the specified hostname
+// and address is one of the specified nodes
+func isReportNode(nodes []string, hostname, addr string) bool {
+	return utils.StringInSlice(nodes, hostname) || utils.StringInSlice(nodes, addr)
+}
+
+func runCollectors(site site, reportWriter report.Writer) error {
 	storageSite, err := site.service.cfg.Backend.GetSite(site.domainName)
 	if err != nil {
 		return trace.Wrap(err)
@@ -211,7 +272,6 @@ func runCollectors(site site, dir string, runner remoteRunner) error {
 		collectSiteInfo(*storageSite),
 		collectDumpHook,
 	}
-	reportWriter := report.NewFileWriter(dir)
 
 	// collect information from all collectors
 	for _, collector := range collectors {
@@ -223,15 +283,19 @@ func runCollectors(site site, dir string, runner remoteRunner) error {
 	return nil
 }
 
-func collectOperationsLogs(site site, dir string) error {
+// collectOperationsLogs collects logs of the cluster operations updated
+// during the specified period. Logs of all operations are collected if since is 0
+func collectOperationsLogs(site site, reportWriter report.Writer, since time.Duration) error {
 	operations, err := site.service.GetSiteOperations(site.key)
 	if err != nil {
 		return trace.Wrap(err, "failed to get cluster operations")
 	}
 
-	reportWriter := report.NewFileWriter(dir)
-
+	now := site.clock().UtcNow()
 	for _, op := range operations {
+		if since != 0 && operationUpdated(op).Before(now.Add(-since)) {
+			continue
+		}
 		operation := ops.SiteOperation(op)
 		err = collectOperationLogs(site, operation, reportWriter)
 		if err != nil {
@@ -303,12 +367,21 @@ func collectOperationLogs(site site, operation ops.SiteOperation, reportWriter r
 	return trace.Wrap(err)
 }
 
+// operationUpdated returns the time the specified operation was last updated
+func operationUpdated(op storage.SiteOperation) time.Time {
+	if op.Updated.IsZero() {
+		return op.Created
+	}
+	return op.Updated
+}
+
 type collectorFn func(report.Writer, site) error
 
-func getReportWriterForServer(dir string, server remoteServer) report.Writer {
+// getReportWriterForServer returns a report writer that prefixes
+// the names of the files with the name of the specified server
+func getReportWriterForServer(reportWriter report.Writer, server remoteServer) report.Writer {
 	return func(name string) (io.WriteCloser, error) {
-		fileName := filepath.Join(dir, fmt.Sprintf("%s-%s", server.HostName(), name))
-		return report.NewPendingFileWriter(fileName), nil
+		return reportWriter(fmt.Sprintf("%s-%s", server.HostName(), name))
 	}
 }
 
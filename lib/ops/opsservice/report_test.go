@@ -20,8 +20,11 @@ import (
 	"bytes"
 	"encoding/json"
 	"io"
+	"time"
 
+	"github.com/gravitational/gravity/lib/constants"
 	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/ops"
 	"github.com/gravitational/gravity/lib/storage"
 
 	"gopkg.in/check.v1"
@@ -49,6 +52,32 @@ func (s *ReportSuite) TestClusterInfo(c *check.C) {
 	c.Assert(fromReport.License, check.Equals, "redacted")
 }
 
+func (s *ReportSuite) TestFiltersReportServers(c *check.C) {
+	servers := []storage.Server{
+		{Hostname: "node-1", AdvertiseIP: "192.168.1.1"},
+		{Hostname: "node-2", AdvertiseIP: "192.168.1.2"},
+		{Hostname: "node-3", AdvertiseIP: "192.168.1.3"},
+	}
+	c.Assert(FilterReportServers(servers, nil), check.DeepEquals, servers)
+	c.Assert(FilterReportServers(servers, []string{"node-1", "192.168.1.3"}), check.DeepEquals,
+		[]storage.Server{servers[0], servers[2]})
+	c.Assert(FilterReportServers(servers, []string{"node-4"}), check.HasLen, 0)
+}
+
+func (s *ReportSuite) TestSystemReportArgs(c *check.C) {
+	args := SystemReportArgs(ops.GetClusterReportRequest{
+		Since:      time.Hour,
+		Namespaces: []string{"kube-system"},
+	}, constants.ReportFilterSystem, constants.ReportFilterKubernetes)
+	c.Assert(args, check.DeepEquals, []string{
+		"system", "report",
+		"--filter=system", "--filter=kubernetes",
+		"--compressed",
+		"--since=1h0m0s",
+		"--namespace=kube-system",
+	})
+}
+
 type nopCloser struct {
 	io.Writer
 }
@@ -1081,17 +1081,17 @@ func (o *Operator) GetSiteOperationCrashReport(key ops.SiteOperationKey) (io.Rea
 	case ops.OperationInstall:
 		return site.getSiteOperationCrashReport(*op)
 	default:
-		return site.getSiteReport()
+		return site.getSiteReport(ops.GetClusterReportRequest{SiteKey: key.SiteKey()})
 	}
 }
 
-func (o *Operator) GetSiteReport(key ops.SiteKey) (io.ReadCloser, error) {
-	site, err := o.openSite(key)
+func (o *Operator) GetSiteReport(req ops.GetClusterReportRequest) (io.ReadCloser, error) {
+	site, err := o.openSite(req.SiteKey)
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
 
-	return site.getSiteReport()
+	return site.getSiteReport(req)
 }
 
 func (o *Operator) GetSiteOperationProgress(key ops.SiteOperationKey) (*ops.ProgressEntry, error) {
@@ -574,9 +574,14 @@ func (s *site) setOperationState(key ops.SiteOperationKey, state string) (*ops.S
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
+	prevState := operation.State
 	operation.State = state
 	operation, err = s.updateSiteOperation(operation)
-	return operation, trace.Wrap(err)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	observeOperation(*operation, prevState, s.clock().UtcNow())
+	return operation, nil
 }
 
 func (s *site) createSiteOperation(o *ops.SiteOperation) (*ops.SiteOperation, error) {
@@ -597,6 +602,7 @@ func (s *site) createSiteOperation(o *ops.SiteOperation) (*ops.SiteOperation, er
 		return nil, trace.Wrap(err)
 	}
 
+	observeOperation(ops.SiteOperation(*out), "", progressEntry.Created)
 	return (*ops.SiteOperation)(out), nil
 }
 
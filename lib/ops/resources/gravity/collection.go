@@ -301,12 +301,22 @@ func (c *logForwardersCollection) Resources() (resources []teleservices.UnknownR
 // WriteText serializes collection in human-friendly text format
 func (c *logForwardersCollection) WriteText(w io.Writer) error {
 	t := goterm.NewTable(0, 10, 5, ' ', 0)
-	common.PrintTableHeader(t, []string{"Name", "Address", "Protocol"})
+	common.PrintTableHeader(t, []string{"Name", "Address", "Protocol", "Format", "TLS"})
 	for _, forwarder := range c.logForwarders {
-		fmt.Fprintf(t, "%v\t%v\t%v\n",
+		format := forwarder.GetFormat()
+		if format == "" {
+			format = "-"
+		}
+		tls := "no"
+		if forwarder.GetTLS() != nil {
+			tls = "yes"
+		}
+		fmt.Fprintf(t, "%v\t%v\t%v\t%v\t%v\n",
 			forwarder.GetName(),
 			forwarder.GetAddress(),
-			forwarder.GetProtocol())
+			forwarder.GetProtocol(),
+			format,
+			tls)
 	}
 	_, err := io.WriteString(w, t.String())
 	return trace.Wrap(err)
@@ -433,9 +443,14 @@ type smtpConfigCollection []storage.SMTPConfig
 // WriteText serializes collection in human-friendly text format
 func (r alertCollection) WriteText(w io.Writer) error {
 	t := goterm.NewTable(0, 10, 5, ' ', 0)
-	common.PrintTableHeader(t, []string{"Name", "Formula"})
+	common.PrintTableHeader(t, []string{"Name", "Severity", "Condition"})
 	for _, alert := range r {
-		fmt.Fprintf(t, "%v\t%v\n", alert.GetName(), alert.GetFormula())
+		spec := alert.GetSpec()
+		severity := spec.Severity
+		if severity == "" {
+			severity = "-"
+		}
+		fmt.Fprintf(t, "%v\t%v\t%v\n", alert.GetName(), severity, spec.Condition())
 	}
 	_, err := io.WriteString(w, t.String())
 	return trace.Wrap(err)
@@ -475,9 +490,13 @@ type alertCollection []storage.Alert
 // WriteText serializes collection in human-friendly text format
 func (r alertTargetCollection) WriteText(w io.Writer) error {
 	t := goterm.NewTable(0, 10, 5, ' ', 0)
-	common.PrintTableHeader(t, []string{"Email"})
+	common.PrintTableHeader(t, []string{"Name", "Type", "Email"})
 	for _, target := range r {
-		fmt.Fprintf(t, "%v\n", target.GetEmail())
+		email := target.GetEmail()
+		if email == "" {
+			email = "-"
+		}
+		fmt.Fprintf(t, "%v\t%v\t%v\n", target.GetName(), target.GetTargetType(), email)
 	}
 	_, err := io.WriteString(w, t.String())
 	return trace.Wrap(err)
@@ -514,6 +533,141 @@ func (c alertTargetCollection) Resources() (resources []teleservices.UnknownReso
 
 type alertTargetCollection []storage.AlertTarget
 
+// WriteText serializes collection in human-friendly text format
+func (r retentionPolicyCollection) WriteText(w io.Writer) error {
+	t := goterm.NewTable(0, 10, 5, ' ', 0)
+	common.PrintTableHeader(t, []string{"Name", "Duration"})
+	for _, policy := range r {
+		fmt.Fprintf(t, "%v\t%v\n", policy.GetName(), policy.GetDuration())
+	}
+	_, err := io.WriteString(w, t.String())
+	return trace.Wrap(err)
+}
+
+// WriteJSON serializes collection into JSON format
+func (r retentionPolicyCollection) WriteJSON(w io.Writer) error {
+	return utils.WriteJSON(r, w)
+}
+
+// WriteYAML serializes collection into YAML format
+func (r retentionPolicyCollection) WriteYAML(w io.Writer) error {
+	return utils.WriteYAML(r, w)
+}
+
+func (r retentionPolicyCollection) ToMarshal() interface{} {
+	if len(r) == 1 {
+		return r[0]
+	}
+	return r
+}
+
+// Resources returns the resources collection in the generic format
+func (c retentionPolicyCollection) Resources() (resources []teleservices.UnknownResource, err error) {
+	for _, item := range c {
+		resource, err := utils.ToUnknownResource(item)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		resources = append(resources, *resource)
+	}
+	return resources, nil
+}
+
+type retentionPolicyCollection []storage.RetentionPolicy
+
+// WriteText serializes collection in human-friendly text format
+func (r remoteWriteCollection) WriteText(w io.Writer) error {
+	t := goterm.NewTable(0, 10, 5, ' ', 0)
+	common.PrintTableHeader(t, []string{"Name", "URL", "Auth"})
+	for _, target := range r {
+		spec := target.GetSpec()
+		auth := "-"
+		switch {
+		case spec.BasicAuth != nil:
+			auth = "basic"
+		case spec.BearerToken != "":
+			auth = "bearer token"
+		}
+		fmt.Fprintf(t, "%v\t%v\t%v\n", target.GetName(), spec.URL, auth)
+	}
+	_, err := io.WriteString(w, t.String())
+	return trace.Wrap(err)
+}
+
+// WriteJSON serializes collection into JSON format
+func (r remoteWriteCollection) WriteJSON(w io.Writer) error {
+	return utils.WriteJSON(r, w)
+}
+
+// WriteYAML serializes collection into YAML format
+func (r remoteWriteCollection) WriteYAML(w io.Writer) error {
+	return utils.WriteYAML(r, w)
+}
+
+func (r remoteWriteCollection) ToMarshal() interface{} {
+	if len(r) == 1 {
+		return r[0]
+	}
+	return r
+}
+
+// Resources returns the resources collection in the generic format
+func (c remoteWriteCollection) Resources() (resources []teleservices.UnknownResource, err error) {
+	for _, item := range c {
+		resource, err := utils.ToUnknownResource(item)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		resources = append(resources, *resource)
+	}
+	return resources, nil
+}
+
+type remoteWriteCollection []storage.RemoteWrite
+
+// WriteText serializes collection in human-friendly text format
+func (r recordingRuleCollection) WriteText(w io.Writer) error {
+	t := goterm.NewTable(0, 10, 5, ' ', 0)
+	common.PrintTableHeader(t, []string{"Name", "Record", "Expression"})
+	for _, rule := range r {
+		spec := rule.GetSpec()
+		fmt.Fprintf(t, "%v\t%v\t%v\n", rule.GetName(), spec.Record, spec.Expr)
+	}
+	_, err := io.WriteString(w, t.String())
+	return trace.Wrap(err)
+}
+
+// WriteJSON serializes collection into JSON format
+func (r recordingRuleCollection) WriteJSON(w io.Writer) error {
+	return utils.WriteJSON(r, w)
+}
+
+// WriteYAML serializes collection into YAML format
+func (r recordingRuleCollection) WriteYAML(w io.Writer) error {
+	return utils.WriteYAML(r, w)
+}
+
+func (r recordingRuleCollection) ToMarshal() interface{} {
+	if len(r) == 1 {
+		return r[0]
+	}
+	return r
+}
+
+// Resources returns the resources collection in the generic format
+func (c recordingRuleCollection) Resources() (resources []teleservices.UnknownResource, err error) {
+	for _, item := range c {
+		resource, err := utils.ToUnknownResource(item)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		resources = append(resources, *resource)
+	}
+	return resources, nil
+}
+
+type recordingRuleCollection []storage.RecordingRule
+
 type authGatewayCollection struct {
 	item storage.AuthGateway
 }
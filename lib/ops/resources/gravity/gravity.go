@@ -220,6 +220,50 @@ func (r *Resources) Create(ctx context.Context, req resources.CreateRequest) err
 			return trace.Wrap(err)
 		}
 		r.Printf("Updated monitoring alert target %q\n", target.GetName())
+	case storage.KindRetentionPolicy:
+		policy, err := storage.UnmarshalRetentionPolicy(req.Resource.Raw)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		if err := policy.CheckAndSetDefaults(); err != nil {
+			return trace.Wrap(err)
+		}
+		err = r.Operator.UpdateRetentionPolicy(ops.UpdateRetentionPolicyRequest{
+			AccountID:  r.cluster.AccountID,
+			SiteDomain: r.cluster.Domain,
+			Name:       policy.GetName(),
+			Duration:   policy.GetDuration(),
+		})
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		r.Printf("Updated metrics retention policy %q\n", policy.GetName())
+	case storage.KindRemoteWrite:
+		target, err := storage.UnmarshalRemoteWrite(req.Resource.Raw)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		if err := target.CheckAndSetDefaults(); err != nil {
+			return trace.Wrap(err)
+		}
+		err = r.Operator.UpsertRemoteWrite(r.cluster.Key(), target)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		r.Printf("Updated metrics remote-write target %q\n", target.GetName())
+	case storage.KindRecordingRule:
+		rule, err := storage.UnmarshalRecordingRule(req.Resource.Raw)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		if err := rule.CheckAndSetDefaults(); err != nil {
+			return trace.Wrap(err)
+		}
+		err = r.Operator.UpsertRecordingRule(r.cluster.Key(), rule)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		r.Printf("Updated metrics recording rule %q\n", rule.GetName())
 	case storage.KindAuthGateway:
 		gw, err := storage.UnmarshalAuthGateway(req.Resource.Raw)
 		if err != nil {
@@ -377,6 +421,51 @@ func (r *Resources) GetCollection(req resources.ListRequest) (resources.Collecti
 			return nil, trace.Wrap(err)
 		}
 		return alertTargetCollection(alertTargets), nil
+	case storage.KindRetentionPolicy:
+		policies, err := r.Operator.GetRetentionPolicies(r.cluster.Key())
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		var filtered []storage.RetentionPolicy
+		for _, policy := range policies {
+			if req.Name == "" || policy.Name == req.Name {
+				filtered = append(filtered, storage.NewRetentionPolicy(policy.Name, policy.Duration))
+			}
+		}
+		if req.Name != "" && len(filtered) == 0 {
+			return nil, trace.NotFound("retention policy %q is not found", req.Name)
+		}
+		return retentionPolicyCollection(filtered), nil
+	case storage.KindRemoteWrite:
+		targets, err := r.Operator.GetRemoteWrites(r.cluster.Key())
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		var filtered []storage.RemoteWrite
+		for _, target := range targets {
+			if req.Name == "" || target.GetName() == req.Name {
+				filtered = append(filtered, target)
+			}
+		}
+		if req.Name != "" && len(filtered) == 0 {
+			return nil, trace.NotFound("remote-write target %q is not found", req.Name)
+		}
+		return remoteWriteCollection(filtered), nil
+	case storage.KindRecordingRule:
+		rules, err := r.Operator.GetRecordingRules(r.cluster.Key())
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		var filtered []storage.RecordingRule
+		for _, rule := range rules {
+			if req.Name == "" || rule.GetName() == req.Name {
+				filtered = append(filtered, rule)
+			}
+		}
+		if req.Name != "" && len(filtered) == 0 {
+			return nil, trace.NotFound("recording rule %q is not found", req.Name)
+		}
+		return recordingRuleCollection(filtered), nil
 	case storage.KindRuntimeEnvironment:
 		env, err := r.Operator.GetClusterEnvironmentVariables(r.cluster.Key())
 		if err != nil {
@@ -465,13 +554,33 @@ func (r *Resources) Remove(ctx context.Context, req resources.RemoveRequest) err
 		}
 		r.Printf("Alert %q has been deleted\n", req.Name)
 	case storage.KindAlertTarget:
-		if err := r.Operator.DeleteAlertTarget(r.cluster.Key()); err != nil {
+		if err := r.Operator.DeleteAlertTarget(r.cluster.Key(), req.Name); err != nil {
+			if trace.IsNotFound(err) && req.Force {
+				return nil
+			}
+			return trace.Wrap(err)
+		}
+		if req.Name != "" {
+			r.Printf("Alert target %q has been deleted\n", req.Name)
+		} else {
+			r.Println("Alert target has been deleted")
+		}
+	case storage.KindRemoteWrite:
+		if err := r.Operator.DeleteRemoteWrite(r.cluster.Key(), req.Name); err != nil {
+			if trace.IsNotFound(err) && req.Force {
+				return nil
+			}
+			return trace.Wrap(err)
+		}
+		r.Printf("Remote-write target %q has been deleted\n", req.Name)
+	case storage.KindRecordingRule:
+		if err := r.Operator.DeleteRecordingRule(r.cluster.Key(), req.Name); err != nil {
 			if trace.IsNotFound(err) && req.Force {
 				return nil
 			}
 			return trace.Wrap(err)
 		}
-		r.Println("Alert target has been deleted")
+		r.Printf("Recording rule %q has been deleted\n", req.Name)
 	case storage.KindRuntimeEnvironment, storage.KindClusterConfiguration:
 		err := r.ClusterOperationHandler.RemoveResource(req)
 		return trace.Wrap(err)
@@ -524,6 +633,12 @@ func Validate(resource storage.UnknownResource) (err error) {
 		_, err = storage.UnmarshalAlert(resource.Raw)
 	case storage.KindAlertTarget:
 		_, err = storage.UnmarshalAlertTarget(resource.Raw)
+	case storage.KindRetentionPolicy:
+		_, err = storage.UnmarshalRetentionPolicy(resource.Raw)
+	case storage.KindRemoteWrite:
+		_, err = storage.UnmarshalRemoteWrite(resource.Raw)
+	case storage.KindRecordingRule:
+		_, err = storage.UnmarshalRecordingRule(resource.Raw)
 	case storage.KindAuthGateway:
 		_, err = storage.UnmarshalAuthGateway(resource.Raw)
 	case storage.KindRuntimeEnvironment:
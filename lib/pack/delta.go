@@ -0,0 +1,204 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pack
+
+import (
+	"crypto/sha512"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+
+	"github.com/gravitational/gravity/lib/blob/chunk"
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/loc"
+	"github.com/gravitational/gravity/lib/pack/delta"
+
+	"github.com/gravitational/trace"
+	log "github.com/sirupsen/logrus"
+)
+
+// DeltaReader is implemented by package services that can transfer
+// a package as a delta against other versions of the same package
+type DeltaReader interface {
+	// ReadPackageDelta returns the envelope of the specified package and
+	// its contents encoded as a delta against the specified base packages.
+	// Base packages the service does not have are ignored
+	ReadPackageDelta(loc loc.Locator, bases []loc.Locator) (*PackageEnvelope, io.ReadCloser, error)
+}
+
+// ReadPackageDelta returns the envelope of the specified package and
+// its contents encoded as a delta against the specified base packages
+// from the given package service.
+// Base packages missing from the package service are ignored
+func ReadPackageDelta(packages PackageService, locator loc.Locator, bases []loc.Locator) (*PackageEnvelope, io.ReadCloser, error) {
+	envelope, reader, err := packages.ReadPackage(locator)
+	if err != nil {
+		return nil, nil, trace.Wrap(err)
+	}
+	var readers []delta.Base
+	closers := []io.Closer{reader}
+	closeAll := func() {
+		for _, closer := range closers {
+			closer.Close()
+		}
+	}
+	for _, base := range bases {
+		_, baseReader, err := packages.ReadPackage(base)
+		if err != nil {
+			if trace.IsNotFound(err) {
+				continue
+			}
+			closeAll()
+			return nil, nil, trace.Wrap(err)
+		}
+		closers = append(closers, baseReader)
+		readers = append(readers, delta.Base{Name: base.String(), Reader: baseReader})
+	}
+	pr, pw := io.Pipe()
+	go func() {
+		defer closeAll()
+		stats, err := delta.Encode(pw, reader, readers, chunk.Config{})
+		if err != nil {
+			pw.CloseWithError(err)
+			return
+		}
+		log.Debugf("Encoded %v as delta against %v bases: %v bytes copied, %v bytes sent.",
+			locator, len(readers), stats.CopiedBytes, stats.LiteralBytes)
+		pw.Close()
+	}()
+	return envelope, pr, nil
+}
+
+// ApplyPackageDelta reconstructs the package described by envelope from
+// the delta read from r using the base packages from the given package service.
+//
+// The reconstructed package is verified against the envelope checksum.
+// Returns the reader for the reconstructed package contents
+func ApplyPackageDelta(packages PackageService, envelope PackageEnvelope, r io.Reader) (io.ReadCloser, error) {
+	f, err := ioutil.TempFile("", "package-delta")
+	if err != nil {
+		return nil, trace.ConvertSystemError(err)
+	}
+	var closers []io.Closer
+	defer func() {
+		for _, closer := range closers {
+			closer.Close()
+		}
+	}()
+	open := func(name string) (io.ReadSeeker, error) {
+		locator, err := loc.ParseLocator(name)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		_, reader, err := packages.ReadPackage(*locator)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		closers = append(closers, reader)
+		if readSeeker, ok := reader.(io.ReadSeeker); ok {
+			return readSeeker, nil
+		}
+		// base packages are read more than once so they need to be seekable
+		spooled, err := newTempFile(reader)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		closers = append(closers, spooled)
+		return spooled, nil
+	}
+	hasher := sha512.New()
+	err = delta.Decode(io.MultiWriter(f, hasher), r, open)
+	if err == nil {
+		hash := fmt.Sprintf("%x", hasher.Sum(nil)[:sha512.Size/2])
+		if hash != envelope.SHA512 {
+			err = trace.BadParameter("checksum mismatch for %v: expected %v, got %v",
+				envelope.Locator, envelope.SHA512, hash)
+		}
+	}
+	if err == nil {
+		_, err = f.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return nil, trace.Wrap(err)
+	}
+	return &tempFile{File: f}, nil
+}
+
+// FindDeltaBases returns the versions of the specified package available
+// in the given package service that can be used as bases for delta transfer.
+// The newest versions are returned first
+func FindDeltaBases(packages PackageService, locator loc.Locator) ([]loc.Locator, error) {
+	envelopes, err := packages.GetPackages(locator.Repository)
+	if err != nil {
+		if trace.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, trace.Wrap(err)
+	}
+	var bases []loc.Locator
+	for _, envelope := range envelopes {
+		if envelope.Locator.Name != locator.Name || envelope.Locator.Version == locator.Version {
+			continue
+		}
+		if _, err := envelope.Locator.SemVer(); err != nil {
+			continue
+		}
+		bases = append(bases, envelope.Locator)
+	}
+	sort.Slice(bases, func(i, j int) bool {
+		vi, _ := bases[i].SemVer()
+		vj, _ := bases[j].SemVer()
+		return Less(vj, vi)
+	})
+	if len(bases) > defaults.DeltaBaseVersions {
+		bases = bases[:defaults.DeltaBaseVersions]
+	}
+	return bases, nil
+}
+
+func newTempFile(r io.Reader) (*tempFile, error) {
+	f, err := ioutil.TempFile("", "package")
+	if err != nil {
+		return nil, trace.ConvertSystemError(err)
+	}
+	file := &tempFile{File: f}
+	if _, err := io.Copy(f, r); err != nil {
+		file.Close()
+		return nil, trace.ConvertSystemError(err)
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		file.Close()
+		return nil, trace.ConvertSystemError(err)
+	}
+	return file, nil
+}
+
+// tempFile is a temporary file removed when closed
+type tempFile struct {
+	*os.File
+}
+
+// Close closes and removes the file
+func (r *tempFile) Close() error {
+	err := r.File.Close()
+	os.Remove(r.File.Name())
+	return trace.ConvertSystemError(err)
+}
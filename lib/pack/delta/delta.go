@@ -0,0 +1,339 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package delta implements encoding of a BLOB as a delta against
+// a set of base BLOBs the receiving side already has.
+//
+// Both the BLOB and the bases are split into content-defined chunks.
+// The delta is a sequence of instructions to either copy a chunk
+// found in one of the bases or insert the literal chunk data.
+//
+// The delta has the following binary format:
+//
+//	magic         - 8 bytes, "GRVDLT01"
+//	header length - uvarint
+//	header        - JSON-encoded header
+//	instructions  - sequence of instructions terminated by the end instruction
+//
+// Each instruction starts with a single byte that specifies its type:
+//
+//	copy    (1) - uvarint hash length, hash, uvarint chunk size
+//	literal (2) - uvarint data length, data
+//	end     (0)
+package delta
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+
+	"github.com/gravitational/gravity/lib/blob/chunk"
+	"github.com/gravitational/gravity/lib/utils"
+
+	"github.com/gravitational/trace"
+)
+
+// Base is a named BLOB the delta is computed against
+type Base struct {
+	// Name identifies the base BLOB for the receiving side
+	Name string
+	// Reader provides the base BLOB data
+	Reader io.Reader
+}
+
+// Header describes the delta
+type Header struct {
+	// Chunking specifies the chunk size limits used to compute the delta.
+	// The receiving side needs to use the same limits to split the bases
+	Chunking chunk.Config `json:"chunking"`
+	// Bases lists the names of the bases the delta references
+	Bases []string `json:"bases"`
+}
+
+// Stats describes the computed delta
+type Stats struct {
+	// CopiedBytes is the number of bytes referenced from bases
+	CopiedBytes int64
+	// LiteralBytes is the number of bytes included in the delta as is
+	LiteralBytes int64
+}
+
+// Encode writes the delta of target against the specified bases to w
+func Encode(w io.Writer, target io.Reader, bases []Base, config chunk.Config) (*Stats, error) {
+	if err := config.CheckAndSetDefaults(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	known := utils.NewStringSet()
+	header := Header{Chunking: config}
+	for _, base := range bases {
+		err := forEachChunk(base.Reader, config, func(data []byte, _ int64) error {
+			hash, err := utils.SHA512Half(data)
+			if err != nil {
+				return trace.Wrap(err)
+			}
+			known.Add(hash)
+			return nil
+		})
+		if err != nil {
+			return nil, trace.Wrap(err, "failed to read base %v", base.Name)
+		}
+		header.Bases = append(header.Bases, base.Name)
+	}
+	bw := bufio.NewWriter(w)
+	enc := &encoder{w: bw}
+	if err := enc.writeHeader(header); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var stats Stats
+	err := forEachChunk(target, config, func(data []byte, _ int64) error {
+		hash, err := utils.SHA512Half(data)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		if known.Has(hash) {
+			stats.CopiedBytes += int64(len(data))
+			return trace.Wrap(enc.writeCopy(hash, len(data)))
+		}
+		stats.LiteralBytes += int64(len(data))
+		return trace.Wrap(enc.writeLiteral(data))
+	})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if err := enc.writeEnd(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if err := bw.Flush(); err != nil {
+		return nil, trace.ConvertSystemError(err)
+	}
+	return &stats, nil
+}
+
+// OpenFunc opens the base with the specified name
+type OpenFunc func(name string) (io.ReadSeeker, error)
+
+// Decode reconstructs the target from the delta read from r and writes it to w.
+// Bases referenced by the delta are opened with the provided function
+func Decode(w io.Writer, r io.Reader, open OpenFunc) error {
+	dec := &decoder{r: bufio.NewReader(r)}
+	header, err := dec.readHeader()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if err := header.Chunking.CheckAndSetDefaults(); err != nil {
+		return trace.Wrap(err)
+	}
+	index := make(map[string]chunkLocation)
+	var readers []io.ReadSeeker
+	for i, name := range header.Bases {
+		reader, err := open(name)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		readers = append(readers, reader)
+		err = forEachChunk(reader, header.Chunking, func(data []byte, offset int64) error {
+			hash, err := utils.SHA512Half(data)
+			if err != nil {
+				return trace.Wrap(err)
+			}
+			index[hash] = chunkLocation{base: i, offset: offset, size: int64(len(data))}
+			return nil
+		})
+		if err != nil {
+			return trace.Wrap(err, "failed to read base %v", name)
+		}
+	}
+	for {
+		op, err := dec.r.ReadByte()
+		if err != nil {
+			return trace.BadParameter("delta is truncated: %v", err)
+		}
+		switch op {
+		case opEnd:
+			return nil
+		case opCopy:
+			hash, size, err := dec.readCopy()
+			if err != nil {
+				return trace.Wrap(err)
+			}
+			location, ok := index[hash]
+			if !ok || location.size != size {
+				return trace.NotFound("chunk %v not found in any of the bases", hash)
+			}
+			reader := readers[location.base]
+			if _, err := reader.Seek(location.offset, io.SeekStart); err != nil {
+				return trace.ConvertSystemError(err)
+			}
+			if _, err := io.CopyN(w, reader, size); err != nil {
+				return trace.ConvertSystemError(err)
+			}
+		case opLiteral:
+			size, err := binary.ReadUvarint(dec.r)
+			if err != nil {
+				return trace.BadParameter("delta is truncated: %v", err)
+			}
+			if _, err := io.CopyN(w, dec.r, int64(size)); err != nil {
+				return trace.BadParameter("delta is truncated: %v", err)
+			}
+		default:
+			return trace.BadParameter("unknown delta instruction %v", op)
+		}
+	}
+}
+
+// forEachChunk splits the data from r into chunks and invokes fn for every chunk
+func forEachChunk(r io.Reader, config chunk.Config, fn func(data []byte, offset int64) error) error {
+	chunker, err := chunk.NewChunker(r, config)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	var offset int64
+	for {
+		data, err := chunker.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		if err := fn(data, offset); err != nil {
+			return trace.Wrap(err)
+		}
+		offset += int64(len(data))
+	}
+}
+
+type chunkLocation struct {
+	base   int
+	offset int64
+	size   int64
+}
+
+type encoder struct {
+	w   *bufio.Writer
+	buf [binary.MaxVarintLen64]byte
+}
+
+func (r *encoder) writeHeader(header Header) error {
+	data, err := json.Marshal(header)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if _, err := r.w.Write(magic); err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	return trace.Wrap(r.writeBytes(data))
+}
+
+func (r *encoder) writeCopy(hash string, size int) error {
+	if err := r.w.WriteByte(opCopy); err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	if err := r.writeBytes([]byte(hash)); err != nil {
+		return trace.Wrap(err)
+	}
+	return trace.Wrap(r.writeUvarint(uint64(size)))
+}
+
+func (r *encoder) writeLiteral(data []byte) error {
+	if err := r.w.WriteByte(opLiteral); err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	return trace.Wrap(r.writeBytes(data))
+}
+
+func (r *encoder) writeEnd() error {
+	return trace.ConvertSystemError(r.w.WriteByte(opEnd))
+}
+
+func (r *encoder) writeBytes(data []byte) error {
+	if err := r.writeUvarint(uint64(len(data))); err != nil {
+		return trace.Wrap(err)
+	}
+	_, err := r.w.Write(data)
+	return trace.ConvertSystemError(err)
+}
+
+func (r *encoder) writeUvarint(value uint64) error {
+	n := binary.PutUvarint(r.buf[:], value)
+	_, err := r.w.Write(r.buf[:n])
+	return trace.ConvertSystemError(err)
+}
+
+type decoder struct {
+	r *bufio.Reader
+}
+
+func (r *decoder) readHeader() (*Header, error) {
+	prefix := make([]byte, len(magic))
+	if _, err := io.ReadFull(r.r, prefix); err != nil {
+		return nil, trace.BadParameter("failed to read delta header: %v", err)
+	}
+	if !bytes.Equal(prefix, magic) {
+		return nil, trace.BadParameter("unsupported delta format")
+	}
+	data, err := r.readBytes(maxHeaderSize)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var header Header
+	if err := json.Unmarshal(data, &header); err != nil {
+		return nil, trace.Wrap(err, "failed to read delta header")
+	}
+	return &header, nil
+}
+
+func (r *decoder) readCopy() (hash string, size int64, err error) {
+	data, err := r.readBytes(maxHashSize)
+	if err != nil {
+		return "", 0, trace.Wrap(err)
+	}
+	value, err := binary.ReadUvarint(r.r)
+	if err != nil {
+		return "", 0, trace.BadParameter("delta is truncated: %v", err)
+	}
+	return string(data), int64(value), nil
+}
+
+func (r *decoder) readBytes(limit uint64) ([]byte, error) {
+	size, err := binary.ReadUvarint(r.r)
+	if err != nil {
+		return nil, trace.BadParameter("delta is truncated: %v", err)
+	}
+	if size > limit {
+		return nil, trace.BadParameter("delta field size %v exceeds the limit of %v", size, limit)
+	}
+	data := make([]byte, size)
+	if _, err := io.ReadFull(r.r, data); err != nil {
+		return nil, trace.BadParameter("delta is truncated: %v", err)
+	}
+	return data, nil
+}
+
+var magic = []byte("GRVDLT01")
+
+const (
+	opEnd     byte = 0
+	opCopy    byte = 1
+	opLiteral byte = 2
+
+	// maxHeaderSize limits the size of the delta header
+	maxHeaderSize = 1024 * 1024
+	// maxHashSize limits the size of a chunk hash
+	maxHashSize = 1024
+)
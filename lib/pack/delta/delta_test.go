@@ -0,0 +1,113 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package delta
+
+import (
+	"bytes"
+	"io"
+	"math/rand"
+	"testing"
+
+	"github.com/gravitational/gravity/lib/blob/chunk"
+
+	"github.com/gravitational/trace"
+	"gopkg.in/check.v1"
+)
+
+func TestDelta(t *testing.T) { check.TestingT(t) }
+
+type DeltaSuite struct{}
+
+var _ = check.Suite(&DeltaSuite{})
+
+func (s *DeltaSuite) TestRoundTrip(c *check.C) {
+	base := randomData(256 * 1024)
+	// target shares most of the data with the base
+	target := append([]byte{}, base[:100*1024]...)
+	target = append(target, randomData(8*1024)...)
+	target = append(target, base[120*1024:]...)
+
+	var buf bytes.Buffer
+	stats, err := Encode(&buf, bytes.NewReader(target),
+		[]Base{{Name: "base", Reader: bytes.NewReader(base)}}, testConfig)
+	c.Assert(err, check.IsNil)
+	c.Assert(stats.CopiedBytes+stats.LiteralBytes, check.Equals, int64(len(target)))
+	c.Assert(stats.CopiedBytes > stats.LiteralBytes, check.Equals, true,
+		check.Commentf("expected most of the data to be copied from the base: %+v", stats))
+	c.Assert(int64(buf.Len()) < int64(len(target))/2, check.Equals, true)
+
+	var out bytes.Buffer
+	err = Decode(&out, &buf, func(name string) (io.ReadSeeker, error) {
+		c.Assert(name, check.Equals, "base")
+		return bytes.NewReader(base), nil
+	})
+	c.Assert(err, check.IsNil)
+	c.Assert(bytes.Equal(out.Bytes(), target), check.Equals, true)
+}
+
+func (s *DeltaSuite) TestRoundTripWithoutBases(c *check.C) {
+	target := randomData(64 * 1024)
+
+	var buf bytes.Buffer
+	stats, err := Encode(&buf, bytes.NewReader(target), nil, testConfig)
+	c.Assert(err, check.IsNil)
+	c.Assert(stats.LiteralBytes, check.Equals, int64(len(target)))
+
+	var out bytes.Buffer
+	err = Decode(&out, &buf, func(name string) (io.ReadSeeker, error) {
+		return nil, trace.NotFound("unexpected base %v", name)
+	})
+	c.Assert(err, check.IsNil)
+	c.Assert(bytes.Equal(out.Bytes(), target), check.Equals, true)
+}
+
+func (s *DeltaSuite) TestFailsOnMismatchedBase(c *check.C) {
+	base := randomData(64 * 1024)
+
+	var buf bytes.Buffer
+	_, err := Encode(&buf, bytes.NewReader(base),
+		[]Base{{Name: "base", Reader: bytes.NewReader(base)}}, testConfig)
+	c.Assert(err, check.IsNil)
+
+	// receiving side has different contents for the base
+	err = Decode(&bytes.Buffer{}, &buf, func(string) (io.ReadSeeker, error) {
+		return bytes.NewReader(randomData(64 * 1024)), nil
+	})
+	c.Assert(trace.IsNotFound(err), check.Equals, true, check.Commentf("%v", err))
+}
+
+func (s *DeltaSuite) TestFailsOnTruncatedDelta(c *check.C) {
+	target := randomData(16 * 1024)
+
+	var buf bytes.Buffer
+	_, err := Encode(&buf, bytes.NewReader(target), nil, testConfig)
+	c.Assert(err, check.IsNil)
+
+	truncated := bytes.NewReader(buf.Bytes()[:buf.Len()-1])
+	err = Decode(&bytes.Buffer{}, truncated, nil)
+	c.Assert(trace.IsBadParameter(err), check.Equals, true, check.Commentf("%v", err))
+}
+
+func randomData(size int) []byte {
+	data := make([]byte, size)
+	random.Read(data)
+	return data
+}
+
+var random = rand.New(rand.NewSource(1))
+
+var testConfig = chunk.Config{MinSize: 1024, AvgSize: 4096, MaxSize: 16 * 1024}
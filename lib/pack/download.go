@@ -0,0 +1,180 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pack
+
+import (
+	"crypto/sha512"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/loc"
+
+	"github.com/gravitational/trace"
+	log "github.com/sirupsen/logrus"
+)
+
+// RangeReader is implemented by package services that can read
+// package contents starting at an arbitrary offset
+type RangeReader interface {
+	// ReadPackageRange returns the envelope of the specified package and
+	// the reader for its contents starting at the specified offset
+	ReadPackageRange(loc loc.Locator, offset int64) (*PackageEnvelope, io.ReadCloser, error)
+}
+
+// DownloadPackage downloads the specified package from the given package service
+// into the directory dir and returns the reader for the downloaded contents.
+//
+// The package is downloaded into a partial file named after the package checksum.
+// If the download is interrupted, the partial file is kept so that the next
+// attempt resumes the download from where the previous one stopped.
+// If the server does not serve the requested range, the download starts over.
+// The downloaded contents are verified against the package checksum upon completion.
+//
+// The partial file is removed when the returned reader is closed.
+// Returns trace.NotImplemented if the package service cannot read package ranges
+func DownloadPackage(packages PackageService, locator loc.Locator, dir string, progress ProgressReporter) (*PackageEnvelope, io.ReadCloser, error) {
+	rangeReader, ok := packages.(RangeReader)
+	if !ok {
+		return nil, nil, trace.NotImplemented("package service does not support range reads")
+	}
+	envelope, err := packages.ReadPackageEnvelope(locator)
+	if err != nil {
+		return nil, nil, trace.Wrap(err)
+	}
+	if envelope.SHA512 == "" {
+		return nil, nil, trace.NotImplemented("package %v has no checksum", locator)
+	}
+	if progress == nil {
+		progress = &DiscardReporter{}
+	}
+	if err := os.MkdirAll(dir, defaults.PrivateDirMask); err != nil {
+		return nil, nil, trace.ConvertSystemError(err)
+	}
+	path := PartialDownloadPath(dir, *envelope)
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, defaults.PrivateFileMask)
+	if err != nil {
+		return nil, nil, trace.ConvertSystemError(err)
+	}
+	file := &tempFile{File: f}
+	offset, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		f.Close()
+		return nil, nil, trace.ConvertSystemError(err)
+	}
+	if offset > envelope.SizeBytes {
+		// partial file does not belong to this package
+		offset = 0
+		if err := f.Truncate(0); err != nil {
+			f.Close()
+			return nil, nil, trace.ConvertSystemError(err)
+		}
+	}
+	if offset < envelope.SizeBytes {
+		if offset > 0 {
+			log.Infof("Resuming download of %v at %v/%v bytes.", locator, offset, envelope.SizeBytes)
+		}
+		err = downloadRange(f, rangeReader, *envelope, offset, progress)
+		if err != nil && offset > 0 && trace.IsCompareFailed(err) {
+			// the server has not served the requested range (e.g. it does not
+			// support conditional range requests), so start over
+			log.Infof("Could not resume download of %v: %v, restarting.", locator, err)
+			err = restartDownload(f, rangeReader, *envelope, progress)
+		}
+		if err != nil {
+			// keep the partial file to resume the download later
+			f.Close()
+			return nil, nil, trace.Wrap(err)
+		}
+	} else {
+		progress.Report(offset, envelope.SizeBytes)
+	}
+	if err := verifyDownload(f, *envelope); err != nil {
+		file.Close()
+		return nil, nil, trace.Wrap(err)
+	}
+	return envelope, file, nil
+}
+
+// PartialDownloadPath returns the path of the partial download file
+// for the package with the specified envelope in the directory dir
+func PartialDownloadPath(dir string, envelope PackageEnvelope) string {
+	return filepath.Join(dir, fmt.Sprintf("%v%v", envelope.SHA512, PartialDownloadSuffix))
+}
+
+// restartDownload discards the contents of f and downloads the package from the beginning
+func restartDownload(f *os.File, packages RangeReader, envelope PackageEnvelope, progress ProgressReporter) error {
+	if err := f.Truncate(0); err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	return downloadRange(f, packages, envelope, 0, progress)
+}
+
+// downloadRange appends package contents starting at the specified offset to f
+func downloadRange(f *os.File, packages RangeReader, envelope PackageEnvelope, offset int64, progress ProgressReporter) error {
+	rangeEnvelope, reader, err := packages.ReadPackageRange(envelope.Locator, offset)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	defer reader.Close()
+	if rangeEnvelope.SHA512 != envelope.SHA512 {
+		return trace.CompareFailed("package %v has changed during download", envelope.Locator)
+	}
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	written, err := io.Copy(f, io.TeeReader(reader, &ProgressWriter{
+		Size:    envelope.SizeBytes,
+		current: offset,
+		R:       progress,
+	}))
+	if err != nil {
+		// interrupted downloads are retried
+		return trace.ConnectionProblem(err, "download of %v interrupted at %v/%v bytes",
+			envelope.Locator, offset+written, envelope.SizeBytes)
+	}
+	if err := f.Sync(); err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	return nil
+}
+
+// verifyDownload verifies the contents of f against the envelope checksum
+// and rewinds f to the beginning
+func verifyDownload(f *os.File, envelope PackageEnvelope) error {
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	hasher := sha512.New()
+	if _, err := io.Copy(hasher, f); err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	hash := fmt.Sprintf("%x", hasher.Sum(nil)[:sha512.Size/2])
+	if hash != envelope.SHA512 {
+		return trace.BadParameter("checksum mismatch for %v: expected %v, got %v",
+			envelope.Locator, envelope.SHA512, hash)
+	}
+	_, err := f.Seek(0, io.SeekStart)
+	return trace.ConvertSystemError(err)
+}
+
+const (
+	// PartialDownloadSuffix is the file name suffix of partially downloaded packages
+	PartialDownloadSuffix = ".partial"
+)
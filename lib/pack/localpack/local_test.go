@@ -18,6 +18,8 @@ package localpack
 
 import (
 	"bytes"
+	"io/ioutil"
+	"math/rand"
 	"os"
 	"path/filepath"
 	"testing"
@@ -28,6 +30,7 @@ import (
 	"github.com/gravitational/gravity/lib/compare"
 	"github.com/gravitational/gravity/lib/defaults"
 	"github.com/gravitational/gravity/lib/loc"
+	"github.com/gravitational/gravity/lib/pack"
 	"github.com/gravitational/gravity/lib/pack/suite"
 	"github.com/gravitational/gravity/lib/storage"
 	"github.com/gravitational/gravity/lib/storage/keyval"
@@ -46,6 +49,8 @@ type LocalSuite struct {
 	suite   suite.PackageSuite
 	dir     string
 	clock   *timetools.FreezedTime
+	// chunked specifies whether the BLOB storage stores packages as chunks
+	chunked bool
 }
 
 var _ = Suite(&LocalSuite{
@@ -54,6 +59,13 @@ var _ = Suite(&LocalSuite{
 	},
 })
 
+var _ = Suite(&LocalSuite{
+	clock: &timetools.FreezedTime{
+		CurrentTime: time.Date(2015, 11, 16, 1, 2, 3, 0, time.UTC),
+	},
+	chunked: true,
+})
+
 func (s *LocalSuite) SetUpTest(c *C) {
 	log.SetOutput(os.Stderr)
 	s.dir = c.MkDir()
@@ -64,7 +76,7 @@ func (s *LocalSuite) SetUpTest(c *C) {
 	})
 	c.Assert(err, IsNil)
 
-	objects, err := fs.New(s.dir)
+	objects, err := fs.NewWithConfig(fs.Config{Path: s.dir, Chunked: s.chunked})
 	c.Assert(err, IsNil)
 
 	s.server, err = New(Config{
@@ -147,3 +159,62 @@ func (s *LocalSuite) TestDoesnotDeleteCollidingBlobs(c *C) {
 	c.Assert(blobsBefore, compare.DeepEquals, []string{package1.SHA512})
 	c.Assert(blobsAfter, compare.DeepEquals, []string{package1.SHA512})
 }
+
+func (s *LocalSuite) TestTransfersPackageDelta(c *C) {
+	// setup
+	random := rand.New(rand.NewSource(1))
+	data1 := make([]byte, 16*1024*1024)
+	random.Read(data1)
+	data2 := append([]byte{}, data1...)
+	copy(data2[8*1024*1024:], []byte("updated package contents"))
+	loc1 := loc.MustParseLocator("gravitational.io/app:0.0.1")
+	loc2 := loc.MustParseLocator("gravitational.io/app:0.0.2")
+	err := s.server.UpsertRepository("gravitational.io", time.Time{})
+	c.Assert(err, IsNil)
+	_, err = s.server.CreatePackage(loc1, bytes.NewReader(data1))
+	c.Assert(err, IsNil)
+	_, err = s.server.CreatePackage(loc2, bytes.NewReader(data2))
+	c.Assert(err, IsNil)
+	dst := s.newServer(c)
+	err = dst.UpsertRepository("gravitational.io", time.Time{})
+	c.Assert(err, IsNil)
+	_, err = dst.CreatePackage(loc1, bytes.NewReader(data1))
+	c.Assert(err, IsNil)
+
+	// exercise
+	bases, err := pack.FindDeltaBases(dst, loc2)
+	c.Assert(err, IsNil)
+	c.Assert(bases, compare.DeepEquals, []loc.Locator{loc1})
+	envelope, reader, err := pack.ReadPackageDelta(s.server, loc2, bases)
+	c.Assert(err, IsNil)
+	delta, err := ioutil.ReadAll(reader)
+	c.Assert(err, IsNil)
+	c.Assert(reader.Close(), IsNil)
+	applied, err := pack.ApplyPackageDelta(dst, *envelope, bytes.NewReader(delta))
+	c.Assert(err, IsNil)
+	defer applied.Close()
+
+	// validate
+	c.Assert(len(delta) < len(data2)/2, Equals, true, Commentf("delta is %v bytes", len(delta)))
+	data, err := ioutil.ReadAll(applied)
+	c.Assert(err, IsNil)
+	c.Assert(bytes.Equal(data, data2), Equals, true)
+}
+
+func (s *LocalSuite) newServer(c *C) *PackageServer {
+	dir := c.MkDir()
+	backend, err := keyval.NewBolt(keyval.BoltConfig{
+		Path: filepath.Join(dir, "storage.db"),
+	})
+	c.Assert(err, IsNil)
+	objects, err := fs.NewWithConfig(fs.Config{Path: dir, Chunked: s.chunked})
+	c.Assert(err, IsNil)
+	server, err := New(Config{
+		Backend:     backend,
+		UnpackedDir: filepath.Join(dir, defaults.UnpackedDir),
+		Clock:       s.clock,
+		Objects:     objects,
+	})
+	c.Assert(err, IsNil)
+	return server
+}
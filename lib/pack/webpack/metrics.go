@@ -0,0 +1,108 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webpack
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	requestLatencies = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name: "gravity_package_request_seconds",
+			Help: "Latency of package service requests, by method and response code",
+			// lowest bucket start of upper bound 0.001 sec (1 ms) with factor 2
+			// highest bucket start of 0.001 sec * 2^17 == 131.072 sec
+			Buckets: prometheus.ExponentialBuckets(0.001, 2, 18),
+		},
+		[]string{"method", "code"},
+	)
+	bytesTransferred = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "gravity_package_bytes_total",
+			Help: "Number of bytes transferred by the package service, by direction (received or sent)",
+		},
+		[]string{"direction"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(requestLatencies)
+	prometheus.MustRegister(bytesTransferred)
+}
+
+// ServeHTTP serves the package service request and records its latency
+// and the number of bytes transferred
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	writer := &countingWriter{ResponseWriter: w, code: http.StatusOK}
+	reader := &countingReader{ReadCloser: r.Body}
+	if r.Body != nil {
+		r.Body = reader
+	}
+	s.Router.ServeHTTP(writer, r)
+	requestLatencies.WithLabelValues(r.Method, strconv.Itoa(writer.code)).
+		Observe(time.Since(start).Seconds())
+	bytesTransferred.WithLabelValues(directionReceived).Add(float64(reader.count))
+	bytesTransferred.WithLabelValues(directionSent).Add(float64(writer.count))
+}
+
+// countingReader counts the bytes read from the request body
+type countingReader struct {
+	io.ReadCloser
+	count int64
+}
+
+// Read reads from the underlying request body
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.count += int64(n)
+	return n, err
+}
+
+// countingWriter captures the response code and counts the bytes
+// written to the response
+type countingWriter struct {
+	http.ResponseWriter
+	code  int
+	count int64
+}
+
+// WriteHeader records the response code and writes it to the underlying response
+func (r *countingWriter) WriteHeader(code int) {
+	r.code = code
+	r.ResponseWriter.WriteHeader(code)
+}
+
+// Write writes to the underlying response
+func (r *countingWriter) Write(p []byte) (int, error) {
+	n, err := r.ResponseWriter.Write(p)
+	r.count += int64(n)
+	return n, err
+}
+
+const (
+	// directionReceived labels the bytes received with requests
+	directionReceived = "received"
+	// directionSent labels the bytes sent with responses
+	directionSent = "sent"
+)
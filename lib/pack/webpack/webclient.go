@@ -21,6 +21,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"net/http"
 	"net/url"
 	"time"
@@ -225,6 +226,64 @@ func (c *Client) ReadPackage(loc loc.Locator) (*pack.PackageEnvelope, io.ReadClo
 	return envelope, re.Body(), nil
 }
 
+// ReadPackageDelta returns the envelope of the specified package and
+// its contents encoded as a delta against the specified base packages
+func (c *Client) ReadPackageDelta(loc loc.Locator, bases []loc.Locator) (*pack.PackageEnvelope, io.ReadCloser, error) {
+	envelope, err := c.ReadPackageEnvelope(loc)
+	if err != nil {
+		return nil, nil, trace.Wrap(err)
+	}
+	query := url.Values{}
+	for _, base := range bases {
+		query.Add("base", base.String())
+	}
+	re, err := c.Client.GetFile(context.TODO(),
+		c.Endpoint("repositories", loc.Repository, "packages", loc.Name, loc.Version, "delta"), query)
+	if err != nil {
+		return nil, nil, trace.Wrap(err)
+	}
+	if re.Code() != http.StatusOK {
+		return nil, nil, trace.Wrap(readError(re.Code(), re.Body()))
+	}
+	return envelope, re.Body(), nil
+}
+
+// ReadPackageRange returns the envelope of the specified package and
+// the reader for its contents starting at the specified offset
+func (c *Client) ReadPackageRange(loc loc.Locator, offset int64) (*pack.PackageEnvelope, io.ReadCloser, error) {
+	envelope, err := c.ReadPackageEnvelope(loc)
+	if err != nil {
+		return nil, nil, trace.Wrap(err)
+	}
+	endpoint := c.Endpoint("repositories", loc.Repository, "packages", loc.Name, loc.Version, "file")
+	req, err := http.NewRequest(http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, nil, trace.Wrap(err)
+	}
+	c.SetAuthHeader(req.Header)
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%v-", offset))
+		// make sure the range is only served for the same package contents
+		req.Header.Set("If-Range", packageETag(*envelope))
+	}
+	resp, err := c.HTTPClient().Do(req)
+	if err != nil {
+		return nil, nil, trace.ConvertSystemError(err)
+	}
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		return envelope, resp.Body, nil
+	case http.StatusOK:
+		if offset == 0 {
+			return envelope, resp.Body, nil
+		}
+		resp.Body.Close()
+		return nil, nil, trace.CompareFailed("server did not serve the range of %v starting at %v", loc, offset)
+	default:
+		return nil, nil, trace.Wrap(readError(resp.StatusCode, resp.Body))
+	}
+}
+
 func (c *Client) ReadPackageEnvelope(loc loc.Locator) (*pack.PackageEnvelope, error) {
 	out, err := c.Get(
 		c.Endpoint("repositories", loc.Repository,
@@ -258,3 +317,13 @@ func (c *Client) Get(u string, params url.Values) (*roundtrip.Response, error) {
 func (c *Client) Delete(u string) (*roundtrip.Response, error) {
 	return telehttplib.ConvertResponse(c.Client.Delete(context.TODO(), u))
 }
+
+// readError returns the error from the response body of the failed request
+func readError(code int, body io.ReadCloser) error {
+	defer body.Close()
+	bytes, err := ioutil.ReadAll(body)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return trace.ReadError(code, bytes)
+}
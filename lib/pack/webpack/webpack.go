@@ -25,6 +25,7 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/gravitational/gravity/lib/defaults"
 	"github.com/gravitational/gravity/lib/httplib"
 	"github.com/gravitational/gravity/lib/loc"
 	"github.com/gravitational/gravity/lib/pack"
@@ -71,6 +72,7 @@ func NewHandler(cfg Config) (*Server, error) {
 	h.GET("/pack/v1/repositories/:repository/packages/:package_name/:package_version/file", h.needsAuth(h.getPackageFile))
 	h.HEAD("/pack/v1/repositories/:repository/packages/:package_name/:package_version/file", h.needsAuth(h.getPackageFile))
 	h.GET("/pack/v1/repositories/:repository/packages/:package_name/:package_version/envelope", h.needsAuth(h.getPackageEnvelope))
+	h.GET("/pack/v1/repositories/:repository/packages/:package_name/:package_version/delta", h.needsAuth(h.getPackageDelta))
 	h.POST("/pack/v1/repositories/:repository/packages/:package_name/:package_version", h.needsAuth(h.updatePackageLabels))
 	h.DELETE("/pack/v1/repositories/:repository/packages/:package_name/:package_version", h.needsAuth(h.deletePackage))
 
@@ -169,7 +171,7 @@ func (s *Server) getPackageFile(w http.ResponseWriter, r *http.Request, p httpro
 		return trace.BadParameter(err.Error())
 	}
 
-	_, fileObject, err := service.ReadPackage(*loc)
+	envelope, fileObject, err := service.ReadPackage(*loc)
 	if err != nil {
 		return trace.Wrap(err)
 	}
@@ -180,10 +182,43 @@ func (s *Server) getPackageFile(w http.ResponseWriter, r *http.Request, p httpro
 		return trace.BadParameter("expected read seeker object")
 	}
 	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename=%v`, loc.String()))
-	http.ServeContent(w, r, loc.String(), time.Now(), readSeeker)
+	// ETag lets clients resume interrupted downloads with range requests
+	// only if the package contents have not changed
+	w.Header().Set("ETag", packageETag(*envelope))
+	http.ServeContent(w, r, loc.String(), envelope.Created, readSeeker)
 	return nil
 }
 
+// getPackageDelta streams the package contents as a delta against
+// the base packages specified in the request
+func (s *Server) getPackageDelta(w http.ResponseWriter, r *http.Request, p httprouter.Params, service pack.PackageService) error {
+	locator, err := loc.NewLocator(p.ByName("repository"), p.ByName("package_name"), p.ByName("package_version"))
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	baseParams := r.URL.Query()["base"]
+	if len(baseParams) > defaults.DeltaBaseVersions {
+		return trace.BadParameter("at most %v base packages can be specified, got %v",
+			defaults.DeltaBaseVersions, len(baseParams))
+	}
+	var bases []loc.Locator
+	for _, base := range baseParams {
+		baseLocator, err := loc.ParseLocator(base)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		bases = append(bases, *baseLocator)
+	}
+	_, reader, err := pack.ReadPackageDelta(service, *locator, bases)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	defer reader.Close()
+	w.Header().Set("Content-Type", "application/octet-stream")
+	_, err = io.Copy(w, reader)
+	return trace.Wrap(err)
+}
+
 func (s *Server) createPackage(w http.ResponseWriter, r *http.Request, p httprouter.Params, service pack.PackageService) error {
 	var files form.Files
 	var labelsMap string
@@ -368,3 +403,9 @@ type labels struct {
 	AddLabels    map[string]string `json:"add_labels"`
 	RemoveLabels []string          `json:"remove_labels"`
 }
+
+// packageETag returns the entity tag for the contents of the package
+// with the specified envelope
+func packageETag(envelope pack.PackageEnvelope) string {
+	return fmt.Sprintf("%q", envelope.SHA512)
+}
@@ -19,6 +19,8 @@ package webpack
 import (
 	"bytes"
 	"crypto/tls"
+	"fmt"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"os"
@@ -30,6 +32,7 @@ import (
 	"github.com/gravitational/gravity/lib/blob/fs"
 	"github.com/gravitational/gravity/lib/defaults"
 	"github.com/gravitational/gravity/lib/loc"
+	"github.com/gravitational/gravity/lib/pack"
 	"github.com/gravitational/gravity/lib/pack/localpack"
 	"github.com/gravitational/gravity/lib/pack/suite"
 	"github.com/gravitational/gravity/lib/storage"
@@ -50,6 +53,7 @@ func TestWebpack(t *testing.T) { TestingT(t) }
 type WebpackSuite struct {
 	server    *Server
 	backend   storage.Backend
+	packages  pack.PackageService
 	suite     suite.PackageSuite
 	webServer *httptest.Server
 	users     users.Identity
@@ -105,6 +109,7 @@ func (s *WebpackSuite) SetUpTest(c *C) {
 		Objects:     objects,
 	})
 	c.Assert(err, IsNil)
+	s.packages = service
 	webHandler, err := NewHandler(Config{
 		Users:    s.users,
 		Packages: service,
@@ -293,3 +298,150 @@ func (s *WebpackSuite) TestUpsertPackages(c *C) {
 func (s *WebpackSuite) TestDeleteRepository(c *C) {
 	s.suite.DeleteRepository(c)
 }
+
+func (s *WebpackSuite) TestReadsPackageDelta(c *C) {
+	data1 := bytes.Repeat([]byte("package contents "), 1024*1024)
+	data2 := append([]byte{}, data1...)
+	copy(data2[len(data2)/2:], []byte("updated"))
+	loc1 := loc.MustParseLocator("gravitational.io/app:0.0.1")
+	loc2 := loc.MustParseLocator("gravitational.io/app:0.0.2")
+	err := s.packages.UpsertRepository("gravitational.io", time.Time{})
+	c.Assert(err, IsNil)
+	_, err = s.packages.CreatePackage(loc1, bytes.NewReader(data1))
+	c.Assert(err, IsNil)
+	_, err = s.packages.CreatePackage(loc2, bytes.NewReader(data2))
+	c.Assert(err, IsNil)
+
+	client, ok := s.suite.S.(pack.DeltaReader)
+	c.Assert(ok, Equals, true)
+	envelope, reader, err := client.ReadPackageDelta(loc2, []loc.Locator{loc1})
+	c.Assert(err, IsNil)
+	delta, err := ioutil.ReadAll(reader)
+	c.Assert(err, IsNil)
+	c.Assert(reader.Close(), IsNil)
+	c.Assert(envelope.Locator, Equals, loc2)
+	c.Assert(len(delta) < len(data2)/2, Equals, true, Commentf("delta is %v bytes", len(delta)))
+
+	applied, err := pack.ApplyPackageDelta(s.packages, *envelope, bytes.NewReader(delta))
+	c.Assert(err, IsNil)
+	defer applied.Close()
+	data, err := ioutil.ReadAll(applied)
+	c.Assert(err, IsNil)
+	c.Assert(bytes.Equal(data, data2), Equals, true)
+}
+
+func (s *WebpackSuite) TestRejectsTooManyDeltaBases(c *C) {
+	locator := loc.MustParseLocator("gravitational.io/app:1.0.0")
+	err := s.packages.UpsertRepository("gravitational.io", time.Time{})
+	c.Assert(err, IsNil)
+	_, err = s.packages.CreatePackage(locator, bytes.NewReader([]byte("package contents")))
+	c.Assert(err, IsNil)
+
+	client, ok := s.suite.S.(pack.DeltaReader)
+	c.Assert(ok, Equals, true)
+	var bases []loc.Locator
+	for i := 0; i <= defaults.DeltaBaseVersions; i++ {
+		bases = append(bases, loc.MustParseLocator(fmt.Sprintf("gravitational.io/app:0.0.%v", i)))
+	}
+	_, _, err = client.ReadPackageDelta(locator, bases)
+	c.Assert(trace.IsBadParameter(err), Equals, true, Commentf("%v", err))
+}
+
+func (s *WebpackSuite) TestResumesPackageDownload(c *C) {
+	data := bytes.Repeat([]byte("package contents "), 64*1024)
+	locator := loc.MustParseLocator("gravitational.io/app:0.0.1")
+	err := s.packages.UpsertRepository("gravitational.io", time.Time{})
+	c.Assert(err, IsNil)
+	envelope, err := s.packages.CreatePackage(locator, bytes.NewReader(data))
+	c.Assert(err, IsNil)
+
+	client, ok := s.suite.S.(pack.RangeReader)
+	c.Assert(ok, Equals, true)
+	_, reader, err := client.ReadPackageRange(locator, 1000)
+	c.Assert(err, IsNil)
+	tail, err := ioutil.ReadAll(reader)
+	c.Assert(err, IsNil)
+	c.Assert(reader.Close(), IsNil)
+	c.Assert(bytes.Equal(tail, data[1000:]), Equals, true)
+
+	// simulate an interrupted download
+	dir := c.MkDir()
+	path := pack.PartialDownloadPath(dir, *envelope)
+	err = ioutil.WriteFile(path, data[:len(data)/2], defaults.SharedReadMask)
+	c.Assert(err, IsNil)
+
+	var progress []int64
+	_, reader, err = pack.DownloadPackage(s.suite.S, locator, dir,
+		pack.ProgressReporterFn(func(current, target int64) {
+			progress = append(progress, current)
+		}))
+	c.Assert(err, IsNil)
+	downloaded, err := ioutil.ReadAll(reader)
+	c.Assert(err, IsNil)
+	c.Assert(bytes.Equal(downloaded, data), Equals, true)
+	c.Assert(progress[0] > int64(len(data)/2), Equals, true, Commentf("download was not resumed"))
+	c.Assert(progress[len(progress)-1], Equals, int64(len(data)))
+	c.Assert(reader.Close(), IsNil)
+	_, err = os.Stat(path)
+	c.Assert(os.IsNotExist(err), Equals, true)
+}
+
+func (s *WebpackSuite) TestRestartsDownloadWithoutRangeSupport(c *C) {
+	data := bytes.Repeat([]byte("package contents "), 64*1024)
+	locator := loc.MustParseLocator("gravitational.io/app:0.0.1")
+	err := s.packages.UpsertRepository("gravitational.io", time.Time{})
+	c.Assert(err, IsNil)
+	envelope, err := s.packages.CreatePackage(locator, bytes.NewReader(data))
+	c.Assert(err, IsNil)
+
+	// simulate a server that ignores range requests
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.Header.Del("Range")
+		r.Header.Del("If-Range")
+		s.webServer.Config.Handler.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+	client, err := NewAuthenticatedClient(server.URL, s.adminUser.GetName(), "admin-password")
+	c.Assert(err, IsNil)
+
+	dir := c.MkDir()
+	path := pack.PartialDownloadPath(dir, *envelope)
+	err = ioutil.WriteFile(path, data[:len(data)/2], defaults.SharedReadMask)
+	c.Assert(err, IsNil)
+
+	_, reader, err := pack.DownloadPackage(client, locator, dir, nil)
+	c.Assert(err, IsNil)
+	downloaded, err := ioutil.ReadAll(reader)
+	c.Assert(err, IsNil)
+	c.Assert(bytes.Equal(downloaded, data), Equals, true)
+	c.Assert(reader.Close(), IsNil)
+	_, err = os.Stat(path)
+	c.Assert(os.IsNotExist(err), Equals, true)
+}
+
+func (s *WebpackSuite) TestDiscardsCorruptedPartialDownload(c *C) {
+	data := bytes.Repeat([]byte("package contents "), 64*1024)
+	locator := loc.MustParseLocator("gravitational.io/app:0.0.1")
+	err := s.packages.UpsertRepository("gravitational.io", time.Time{})
+	c.Assert(err, IsNil)
+	envelope, err := s.packages.CreatePackage(locator, bytes.NewReader(data))
+	c.Assert(err, IsNil)
+
+	dir := c.MkDir()
+	path := pack.PartialDownloadPath(dir, *envelope)
+	err = ioutil.WriteFile(path, bytes.Repeat([]byte("x"), 1024), defaults.SharedReadMask)
+	c.Assert(err, IsNil)
+
+	_, _, err = pack.DownloadPackage(s.suite.S, locator, dir, nil)
+	c.Assert(trace.IsBadParameter(err), Equals, true, Commentf("%v", err))
+	_, err = os.Stat(path)
+	c.Assert(os.IsNotExist(err), Equals, true)
+
+	// next attempt starts from scratch
+	_, reader, err := pack.DownloadPackage(s.suite.S, locator, dir, nil)
+	c.Assert(err, IsNil)
+	defer reader.Close()
+	downloaded, err := ioutil.ReadAll(reader)
+	c.Assert(err, IsNil)
+	c.Assert(bytes.Equal(downloaded, data), Equals, true)
+}
@@ -0,0 +1,53 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package process
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	electedLeader = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "gravity_leader",
+			Help: "Whether this process is the elected cluster leader (1) or not (0)",
+		},
+	)
+	leaderChanges = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "gravity_leader_changes_total",
+			Help: "Number of cluster leader changes observed by this process",
+		},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(electedLeader)
+	prometheus.MustRegister(leaderChanges)
+}
+
+// observeLeader records the change of the cluster leader to the process specified with id
+func (p *Process) observeLeader(oldID, id string) {
+	if oldID != id {
+		leaderChanges.Inc()
+	}
+	if id == p.id {
+		electedLeader.Set(1)
+	} else {
+		electedLeader.Set(0)
+	}
+}
@@ -35,7 +35,10 @@ import (
 	"github.com/gravitational/gravity/lib/app"
 	apphandler "github.com/gravitational/gravity/lib/app/handler"
 	appservice "github.com/gravitational/gravity/lib/app/service"
+	"github.com/gravitational/gravity/lib/autoscale"
 	"github.com/gravitational/gravity/lib/autoscale/aws"
+	"github.com/gravitational/gravity/lib/autoscale/azure"
+	"github.com/gravitational/gravity/lib/autoscale/gce"
 	"github.com/gravitational/gravity/lib/blob"
 	blobclient "github.com/gravitational/gravity/lib/blob/client"
 	blobcluster "github.com/gravitational/gravity/lib/blob/cluster"
@@ -64,6 +67,7 @@ import (
 	pb "github.com/gravitational/gravity/lib/rpc/proto"
 	rpcserver "github.com/gravitational/gravity/lib/rpc/server"
 	"github.com/gravitational/gravity/lib/schema"
+	"github.com/gravitational/gravity/lib/status"
 	"github.com/gravitational/gravity/lib/storage"
 	"github.com/gravitational/gravity/lib/storage/keyval"
 	"github.com/gravitational/gravity/lib/users"
@@ -84,15 +88,18 @@ import (
 	teleutils "github.com/gravitational/teleport/lib/utils"
 	teleweb "github.com/gravitational/teleport/lib/web"
 
+	"cloud.google.com/go/compute/metadata"
 	"github.com/cloudflare/cfssl/csr"
 	"github.com/gravitational/license/authority"
 	"github.com/gravitational/roundtrip"
 	"github.com/gravitational/teleport"
 	"github.com/gravitational/trace"
 	"github.com/julienschmidt/httprouter"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/sirupsen/logrus"
 	"k8s.io/apimachinery/pkg/version"
 	"k8s.io/client-go/kubernetes"
+	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
 )
 
 type Process struct {
@@ -209,7 +216,10 @@ func New(ctx context.Context, cfg processconfig.Config, tcfg telecfg.FileConfig)
 		return nil, trace.Wrap(err)
 	}
 
-	objects, err := blobfs.New(filepath.Join(cfg.DataDir, defaults.PackagesDir))
+	objects, err := blobfs.NewWithConfig(blobfs.Config{
+		Path:    filepath.Join(cfg.DataDir, defaults.PackagesDir),
+		Chunked: cfg.Pack.ChunkedBLOBs,
+	})
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
@@ -238,7 +248,7 @@ func New(ctx context.Context, cfg processconfig.Config, tcfg telecfg.FileConfig)
 		GetPeer:       peerPool.GetPeer,
 		ID:            processID,
 		AdvertiseAddr: fmt.Sprintf("https://%v", peerAddr.Addr),
-		// TODO: set WriteFactor to the number of controller instances
+		WriteFactor:   cfg.Pack.WriteFactor,
 	})
 	if err != nil {
 		return nil, trace.Wrap(err)
@@ -466,6 +476,7 @@ func (p *Process) setLeader(id string) (oldID string) {
 	oldID = p.leaderID
 	p.Infof("setLeader(%v)", id)
 	p.leaderID = id
+	p.observeLeader(oldID, id)
 	return oldID
 }
 
@@ -476,38 +487,25 @@ func (p *Process) leaderStatus() (string, bool) {
 }
 
 func (p *Process) startAutoscale(ctx context.Context) error {
-	_, err := cloudaws.NewLocalInstance()
-	if err != nil {
-		p.Info("Not on AWS, skip autoscaler start.")
-		return nil
-	}
-	p.Info("Starting AWS autoscaler.")
 	site, err := p.operator.GetLocalSite()
 	if err != nil {
 		return trace.Wrap(err)
 	}
-	client, err := tryGetPrivilegedKubeClient()
-	if err != nil {
-		return trace.Wrap(err)
-	}
-	autoscaler, err := aws.New(aws.Config{
-		ClusterName: site.Domain,
-		Client:      client,
-	})
-	if err != nil {
-		return trace.Wrap(err)
-	}
-	queueURL, err := autoscaler.GetQueueURL(ctx)
+	autoscaler, err := p.newAutoscaler(ctx, site.Domain)
 	if err != nil {
-		p.Warningf("Failed to get Autoscale Queue URL: %v. Cluster will continue without autoscaling support. Fix the problem and restart the process.", trace.DebugReport(err))
+		if trace.IsNotFound(err) {
+			p.Infof("%v, skip autoscaler start.", err)
+			return nil
+		}
+		p.Warningf("Failed to start autoscaler: %v. Cluster will continue without autoscaling support. Fix the problem and restart the process.", trace.DebugReport(err))
 		return nil
 	}
 
-	// receive and process events from SQS notification service
+	// remove the nodes terminated by the autoscaling group
 	p.RegisterClusterService(func(ctx context.Context) error {
 		localCtx := context.WithValue(ctx, constants.UserContext,
 			constants.ServiceAutoscaler)
-		autoscaler.ProcessEvents(localCtx, queueURL, p.operator)
+		autoscaler.ProcessEvents(localCtx, p.operator)
 		return nil
 	})
 	// publish discovery information about this cluster
@@ -520,6 +518,68 @@ func (p *Process) startAutoscale(ctx context.Context) error {
 	return nil
 }
 
+// newAutoscaler returns the autoscaler for the cloud provider this process is running on.
+// Returns NotFound if the provider is not supported or the autoscaling group is not configured
+func (p *Process) newAutoscaler(ctx context.Context, clusterName string) (autoscale.Autoscaler, error) {
+	if _, err := cloudaws.NewLocalInstance(); err == nil {
+		p.Info("Starting AWS autoscaler.")
+		client, err := tryGetPrivilegedKubeClient()
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		safety, err := autoscale.NewSafety(autoscale.SafetyConfig{Client: client})
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		autoscaler, err := aws.New(aws.Config{
+			ClusterName: clusterName,
+			Client:      client,
+			Safety:      safety,
+		})
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		// receive events from SQS notification service
+		autoscaler.QueueURL, err = autoscaler.GetQueueURL(ctx)
+		if err != nil {
+			return nil, trace.Wrap(err, "failed to get autoscale queue URL")
+		}
+		return autoscaler, nil
+	}
+	var group autoscale.Group
+	var err error
+	switch {
+	case metadata.OnGCE():
+		p.Info("Starting GCE autoscaler.")
+		group, err = gce.NewLocalGroup(ctx, clusterName)
+	case azure.OnAzure(ctx):
+		p.Info("Starting Azure autoscaler.")
+		group, err = azure.NewLocalGroup(ctx, clusterName)
+	default:
+		return nil, trace.NotFound("not on a cloud provider with autoscaling support")
+	}
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	client, err := tryGetPrivilegedKubeClient()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	safety, err := autoscale.NewSafety(autoscale.SafetyConfig{Client: client})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	autoscaler, err := autoscale.New(autoscale.Config{
+		Group:  group,
+		Client: client,
+		Safety: safety,
+	})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return autoscaler, nil
+}
+
 // startApplicationsSynchronizer starts a service that periodically exports
 // Docker images of the cluster's application images to the local Docker
 // registry.
@@ -627,6 +687,66 @@ func (p *Process) startSiteStatusChecker(ctx context.Context) error {
 	}
 }
 
+// startAlertWatcher delivers the alerts triggered by the monitoring
+// system to the configured alert targets
+func (p *Process) startAlertWatcher(ctx context.Context) error {
+	site, err := p.operator.GetLocalSite()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	watcher, err := monitoring.NewAlertWatcher(monitoring.AlertWatcherConfig{
+		Dispatch: func(event monitoring.AlertEvent) error {
+			return p.operator.DispatchAlert(site.Key(), event)
+		},
+		State:       monitoring.NewConfigMapAlertState(p.client.CoreV1()),
+		FieldLogger: p.WithField(trace.Component, "alerts"),
+	})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	watcher.Run(ctx)
+	return nil
+}
+
+// startStatusSampler periodically samples the cluster health and records
+// the health transitions in the cluster status history
+func (p *Process) startStatusSampler(ctx context.Context) error {
+	site, err := p.operator.GetLocalSite()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	sampler, err := status.NewSampler(status.SamplerConfig{
+		Backend:     p.backend,
+		ClusterName: site.Domain,
+		FieldLogger: p.WithField(trace.Component, "status:history"),
+	})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	p.Info("Starting cluster status sampler.")
+	ticker := time.NewTicker(defaults.StatusSampleInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			site, err := p.operator.GetLocalSite()
+			if err != nil {
+				p.Errorf("Failed to query local cluster: %v.",
+					trace.DebugReport(err))
+				continue
+			}
+			err = sampler.Sample(ctx, site.ClusterState.Servers)
+			if err != nil {
+				p.Errorf("Failed to sample cluster status: %v.",
+					trace.DebugReport(err))
+			}
+		case <-ctx.Done():
+			p.Info("Stopping cluster status sampler.")
+			return nil
+		}
+	}
+}
+
 // startElection starts leader election process and watches the changes
 func (p *Process) startElection() error {
 	// elect gravity site leader - all other sites will remain
@@ -1049,6 +1169,9 @@ func (p *Process) initService(ctx context.Context) (err error) {
 	}
 
 	peerStore := opsservice.NewAgentPeerStore(p.backend, p.identity, p.proxy, p.WithField("process", p.id))
+	if err := prometheus.Register(peerStore); err != nil {
+		p.Warnf("Failed to register agent metrics: %v.", err)
+	}
 	p.agentServer, err = rpcserver.New(rpcserver.Config{
 		Credentials: *creds,
 		PeerStore:   peerStore,
@@ -1166,7 +1289,11 @@ func (p *Process) initService(ctx context.Context) (err error) {
 		Backend: p.backend,
 	})
 
-	mon, err := monitoring.NewInfluxDB()
+	var coreClient corev1.CoreV1Interface
+	if client != nil {
+		coreClient = client.CoreV1()
+	}
+	mon, err := monitoring.NewProvider(coreClient)
 	if err != nil {
 		return trace.Wrap(err)
 	}
@@ -1252,6 +1379,8 @@ func (p *Process) initService(ctx context.Context) (err error) {
 
 	// site status checker executes status hook periodically
 	p.RegisterClusterService(p.startSiteStatusChecker)
+	// status sampler records the cluster health history
+	p.RegisterClusterService(p.startStatusSampler)
 
 	// a few services that are running only when gravity is started in
 	// local site mode
@@ -1291,6 +1420,9 @@ func (p *Process) initService(ctx context.Context) (err error) {
 			return trace.Wrap(err)
 		}
 
+		// alert watcher delivers monitoring alerts to the alert targets
+		p.RegisterClusterService(p.startAlertWatcher)
+
 		if err := p.startElection(); err != nil {
 			return trace.Wrap(err)
 		}
@@ -1437,6 +1569,7 @@ func (p *Process) ServeHealth() error {
 	healthMux := &httprouter.Router{}
 	healthMux.HandlerFunc("GET", "/readyz", p.ReportReadiness)
 	healthMux.HandlerFunc("GET", "/healthz", p.ReportHealth)
+	healthMux.Handler("GET", "/metrics", prometheus.Handler())
 	p.RegisterFunc("gravity.healthz", func() error {
 		p.Infof("Start healthcheck server on %v.", p.cfg.HealthAddr)
 		return trace.Wrap(http.ListenAndServe(p.cfg.HealthAddr.Addr, healthMux))
@@ -1513,6 +1646,7 @@ func (p *Process) initMux(ctx context.Context) error {
 		mux.Handler(method, "/app/*apps", p.handlers.Apps)
 		mux.Handler(method, "/telekube/*rest", p.handlers.Apps)
 		mux.Handler(method, "/charts/*rest", p.handlers.Apps)
+		mux.Handler(method, "/helm/*rest", p.handlers.Apps)
 		mux.Handler(method, "/objects/*rest", p.handlers.BLOB)
 		mux.Handler(method, "/v2/*rest", p.handlers.Registry)
 		mux.HandlerFunc(method, "/readyz", p.ReportReadiness)
@@ -133,6 +133,7 @@ type Config struct {
 	DataDir string `yaml:"data_dir"`
 
 	// HealthAddr provides HTTP API for health and readiness checks
+	// and serves Prometheus metrics
 	HealthAddr teleutils.NetAddr `yaml:"health_addr"`
 
 	// BackendType is a type of storage backend
@@ -192,6 +193,11 @@ func (cfg *Config) CheckAndSetDefaults() error {
 		return trace.BadParameter("missing pack service advertise address")
 	}
 
+	if cfg.Pack.WriteFactor < 0 {
+		return trace.BadParameter("pack service write factor should not be negative: %v",
+			cfg.Pack.WriteFactor)
+	}
+
 	if cfg.HealthAddr.IsEmpty() {
 		cfg.HealthAddr = teleutils.NetAddr{
 			AddrNetwork: "tcp",
@@ -202,6 +208,7 @@ func (cfg *Config) CheckAndSetDefaults() error {
 	switch cfg.BackendType {
 	case "", constants.BoltBackend:
 		cfg.BackendType = constants.BoltBackend
+	case constants.SQLiteBackend:
 	case constants.ETCDBackend:
 		if err := cfg.ETCD.Check(); err != nil {
 			log.Errorf("error reading config: %#v", cfg.ETCD)
@@ -230,6 +237,9 @@ func (cfg Config) CreateBackend() (backend storage.Backend, err error) {
 		backend, err = keyval.NewBolt(keyval.BoltConfig{
 			Path: filepath.Join(cfg.DataDir, defaults.GravityDBFile),
 		})
+	case constants.SQLiteBackend:
+		log.Debug("using SQLite backend")
+		backend, err = cfg.createSQLBackend()
 	case constants.ETCDBackend:
 		log.Debug("using ETCD backend")
 		backend, err = keyval.NewETCD(cfg.ETCD)
@@ -237,6 +247,39 @@ func (cfg Config) CreateBackend() (backend storage.Backend, err error) {
 	return backend, trace.Wrap(err)
 }
 
+// createSQLBackend creates the SQLite backend.
+// If the SQLite database does not exist yet, the data is migrated
+// from the existing bolt database
+func (cfg Config) createSQLBackend() (storage.Backend, error) {
+	path := filepath.Join(cfg.DataDir, defaults.GravitySQLiteFile)
+	boltPath := filepath.Join(cfg.DataDir, defaults.GravityDBFile)
+	_, err := utils.StatFile(path)
+	if err != nil && !trace.IsNotFound(err) {
+		return nil, trace.Wrap(err)
+	}
+	sqlExists := err == nil
+	_, err = utils.StatFile(boltPath)
+	if err != nil && !trace.IsNotFound(err) {
+		return nil, trace.Wrap(err)
+	}
+	boltExists := err == nil
+	if !sqlExists && boltExists {
+		log.Infof("Migrating %v to %v.", boltPath, path)
+		err = keyval.MigrateBoltToSQL(boltPath, keyval.SQLConfig{Path: path})
+		if err != nil {
+			// remove the partially migrated database so the migration
+			// is attempted again on next start
+			os.Remove(path)
+			return nil, trace.Wrap(err, "failed to migrate %v", boltPath)
+		}
+	}
+	backend, err := keyval.NewSQL(keyval.SQLConfig{Path: path})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return backend, nil
+}
+
 func (cfg Config) ProcessID() string {
 	id := os.Getenv(constants.EnvPodIP)
 	if id == "" {
@@ -308,6 +351,15 @@ type PackageServiceConfig struct {
 
 	// ReadDir is an optional directory with extra packages
 	ReadDir string `yaml:"read_dir"`
+
+	// WriteFactor is the number of peers that have to acknowledge
+	// a package upload before it is considered successful.
+	// Defaults to defaults.WriteFactor
+	WriteFactor int `yaml:"write_factor"`
+
+	// ChunkedBLOBs enables storing package BLOBs as content-defined chunks
+	// so that similar packages share the storage
+	ChunkedBLOBs bool `yaml:"chunked_blobs"`
 }
 
 // PeerAddr returns peer address of the package service instance
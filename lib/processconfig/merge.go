@@ -64,6 +64,9 @@ func MergeConfig(into, from *Config) error {
 	if !from.Pack.PublicAdvertiseAddr.IsEmpty() {
 		into.Pack.PublicAdvertiseAddr = from.Pack.PublicAdvertiseAddr
 	}
+	if from.Pack.WriteFactor != 0 {
+		into.Pack.WriteFactor = from.Pack.WriteFactor
+	}
 	for i := range from.Users {
 		into.Users = append(into.Users, from.Users[i])
 	}
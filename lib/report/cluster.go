@@ -0,0 +1,269 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package report
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/storage"
+
+	"github.com/gravitational/trace"
+	"github.com/jonboulle/clockwork"
+	"github.com/sirupsen/logrus"
+)
+
+// NodeCollector collects diagnostics from a single cluster node
+type NodeCollector interface {
+	// Collect streams the gzipped tarball with diagnostics
+	// of the specified server to w
+	Collect(ctx context.Context, server storage.Server, w io.Writer) error
+}
+
+// ClusterConfig defines the configuration of the cluster-wide report collection
+type ClusterConfig struct {
+	// Servers lists the servers to collect diagnostics from
+	Servers []storage.Server
+	// Collector collects diagnostics from a single server
+	Collector NodeCollector
+	// Concurrency is the number of servers to collect diagnostics from concurrently
+	Concurrency int
+	// Clock is used to time the collection
+	Clock clockwork.Clock
+	// FieldLogger is used for logging
+	logrus.FieldLogger
+}
+
+// CheckAndSetDefaults validates the configuration and sets defaults
+func (r *ClusterConfig) CheckAndSetDefaults() error {
+	if len(r.Servers) == 0 {
+		return trace.BadParameter("no servers to collect diagnostics from")
+	}
+	if r.Collector == nil {
+		return trace.BadParameter("missing Collector")
+	}
+	if r.Concurrency == 0 {
+		r.Concurrency = defaults.MaxReportConcurrency
+	}
+	if r.Clock == nil {
+		r.Clock = clockwork.NewRealClock()
+	}
+	if r.FieldLogger == nil {
+		r.FieldLogger = logrus.WithField(trace.Component, "report:cluster")
+	}
+	return nil
+}
+
+// CollectCluster collects diagnostics from the configured servers in parallel
+// and stores them with the specified report writer, in a directory per server:
+//
+//	nodes/<hostname>/<file>
+//
+// The summary of the collection is stored in the index file.
+// Failure to collect diagnostics from a server is not fatal and is recorded
+// in the returned index
+func CollectCluster(ctx context.Context, config ClusterConfig, reportWriter Writer) (*ClusterIndex, error) {
+	if err := config.CheckAndSetDefaults(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	index := ClusterIndex{
+		Started: config.Clock.Now().UTC(),
+		Nodes:   make([]NodeIndex, len(config.Servers)),
+	}
+	semaphore := make(chan struct{}, config.Concurrency)
+	var wg sync.WaitGroup
+	for i, server := range config.Servers {
+		wg.Add(1)
+		go func(i int, server storage.Server) {
+			defer wg.Done()
+			select {
+			case semaphore <- struct{}{}:
+				defer func() { <-semaphore }()
+			case <-ctx.Done():
+				index.Nodes[i] = newNodeIndex(server, config.Clock.Now())
+				index.Nodes[i].Error = ctx.Err().Error()
+				return
+			}
+			index.Nodes[i] = collectNode(ctx, config, server, reportWriter)
+		}(i, server)
+	}
+	wg.Wait()
+	index.Duration = config.Clock.Now().Sub(index.Started)
+	if err := index.write(reportWriter); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &index, nil
+}
+
+// ClusterIndex summarizes the cluster-wide report collection
+type ClusterIndex struct {
+	// Started is the time the collection started
+	Started time.Time `json:"started"`
+	// Duration is the duration of the collection
+	Duration time.Duration `json:"duration"`
+	// Nodes lists the collection results for individual nodes
+	Nodes []NodeIndex `json:"nodes"`
+}
+
+// Failed returns the nodes diagnostics could not be collected from
+func (r ClusterIndex) Failed() (failed []NodeIndex) {
+	for _, node := range r.Nodes {
+		if node.Error != "" {
+			failed = append(failed, node)
+		}
+	}
+	return failed
+}
+
+func (r ClusterIndex) write(reportWriter Writer) error {
+	w, err := reportWriter(defaults.ClusterReportIndex)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	defer w.Close()
+	data, err := json.MarshalIndent(r, "", "    ")
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	_, err = w.Write(data)
+	return trace.ConvertSystemError(err)
+}
+
+// NodeIndex describes the result of diagnostics collection from a single node
+type NodeIndex struct {
+	// Hostname is the hostname of the node
+	Hostname string `json:"hostname"`
+	// AdvertiseIP is the advertise address of the node
+	AdvertiseIP string `json:"advertise_ip"`
+	// Role is the cluster role of the node
+	Role string `json:"role"`
+	// Dir is the directory in the report with the node diagnostics
+	Dir string `json:"dir"`
+	// Started is the time the collection started
+	Started time.Time `json:"started"`
+	// Duration is the duration of the collection
+	Duration time.Duration `json:"duration"`
+	// Files is the number of files collected
+	Files int `json:"files"`
+	// Bytes is the total size of the collected files
+	Bytes int64 `json:"bytes"`
+	// Error describes the collection failure.
+	// Partially collected diagnostics are kept
+	Error string `json:"error,omitempty"`
+}
+
+func newNodeIndex(server storage.Server, started time.Time) NodeIndex {
+	dir := server.Hostname
+	if dir == "" {
+		dir = server.AdvertiseIP
+	}
+	return NodeIndex{
+		Hostname:    server.Hostname,
+		AdvertiseIP: server.AdvertiseIP,
+		Role:        server.ClusterRole,
+		Dir:         path.Join(nodesDir, dir),
+		Started:     started.UTC(),
+	}
+}
+
+// collectNode collects diagnostics from the specified server and unpacks
+// them into the server directory using the specified report writer
+func collectNode(ctx context.Context, config ClusterConfig, server storage.Server, reportWriter Writer) NodeIndex {
+	index := newNodeIndex(server, config.Clock.Now())
+	logger := config.WithField("node", server.Hostname)
+	logger.Info("Collecting diagnostics.")
+	reader, writer := io.Pipe()
+	errC := make(chan error, 1)
+	go func() {
+		err := unpackNodeReport(reader, index.Dir, reportWriter, &index)
+		if err == nil {
+			// consume the trailing data after the end of the archive
+			_, err = io.Copy(ioutil.Discard, reader)
+		}
+		// unblock the collector if unpacking failed
+		reader.CloseWithError(err)
+		errC <- err
+	}()
+	err := config.Collector.Collect(ctx, server, writer)
+	writer.CloseWithError(err)
+	if errUnpack := <-errC; err == nil {
+		err = errUnpack
+	}
+	index.Duration = config.Clock.Now().Sub(index.Started)
+	if err != nil {
+		logger.WithError(err).Warn("Failed to collect diagnostics.")
+		index.Error = trace.UserMessage(err)
+	}
+	return index
+}
+
+// unpackNodeReport writes the files from the gzipped tarball read
+// from reader into the specified directory and updates the index
+func unpackNodeReport(reader io.Reader, dir string, reportWriter Writer, index *NodeIndex) error {
+	gzReader, err := gzip.NewReader(reader)
+	if err != nil {
+		return trace.Wrap(err, "failed to read node diagnostics")
+	}
+	defer gzReader.Close()
+	tarReader := tar.NewReader(gzReader)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return trace.Wrap(err, "failed to read node diagnostics")
+		}
+		if header.Typeflag != tar.TypeReg && header.Typeflag != tar.TypeRegA {
+			continue
+		}
+		name := path.Clean(strings.TrimPrefix(header.Name, "/"))
+		if name == ".." || strings.HasPrefix(name, "../") {
+			return trace.BadParameter("invalid file name %q in node diagnostics", header.Name)
+		}
+		n, err := writeNodeFile(path.Join(dir, name), tarReader, reportWriter)
+		index.Bytes += n
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		index.Files++
+	}
+}
+
+func writeNodeFile(name string, reader io.Reader, reportWriter Writer) (int64, error) {
+	w, err := reportWriter(name)
+	if err != nil {
+		return 0, trace.Wrap(err)
+	}
+	n, err := io.Copy(w, reader)
+	if errClose := w.Close(); err == nil {
+		err = errClose
+	}
+	return n, trace.ConvertSystemError(err)
+}
+
+// nodesDir is the report directory with per-node diagnostics
+const nodesDir = "nodes"
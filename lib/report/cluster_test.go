@@ -0,0 +1,120 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package report
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/gravitational/gravity/lib/archive"
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/storage"
+
+	"github.com/gravitational/trace"
+	. "gopkg.in/check.v1"
+)
+
+type ClusterSuite struct{}
+
+var _ = Suite(&ClusterSuite{})
+
+func (r *ClusterSuite) TestCollectsNodesIntoDirectories(c *C) {
+	dir := c.MkDir()
+	index, err := CollectCluster(context.TODO(), ClusterConfig{
+		Servers: []storage.Server{
+			{Hostname: "node-1", AdvertiseIP: "192.168.1.1", ClusterRole: "master"},
+			{Hostname: "node-2", AdvertiseIP: "192.168.1.2", ClusterRole: "node"},
+			{Hostname: "node-3", AdvertiseIP: "192.168.1.3", ClusterRole: "node"},
+		},
+		Collector: testNodeCollector{
+			"node-1": {files: map[string]string{"system/df": "disk usage", "docker-info": "info"}},
+			"node-2": {files: map[string]string{"system/df": "disk usage"}},
+			"node-3": {files: map[string]string{"partial": "data"}, err: trace.ConnectionProblem(nil, "agent is down")},
+		},
+	}, NewFileWriter(dir))
+	c.Assert(err, IsNil)
+
+	assertFile(c, filepath.Join(dir, "nodes", "node-1", "system", "df"), "disk usage")
+	assertFile(c, filepath.Join(dir, "nodes", "node-1", "docker-info"), "info")
+	assertFile(c, filepath.Join(dir, "nodes", "node-2", "system", "df"), "disk usage")
+
+	c.Assert(index.Nodes, HasLen, 3)
+	c.Assert(index.Nodes[0].Dir, Equals, "nodes/node-1")
+	c.Assert(index.Nodes[0].Files, Equals, 2)
+	c.Assert(index.Nodes[0].Bytes, Equals, int64(len("disk usage")+len("info")))
+	c.Assert(index.Nodes[0].Error, Equals, "")
+	c.Assert(index.Nodes[2].Error, Equals, "agent is down")
+	c.Assert(index.Failed(), HasLen, 1)
+	c.Assert(index.Failed()[0].Hostname, Equals, "node-3")
+
+	data, err := ioutil.ReadFile(filepath.Join(dir, defaults.ClusterReportIndex))
+	c.Assert(err, IsNil)
+	var stored ClusterIndex
+	c.Assert(json.Unmarshal(data, &stored), IsNil)
+	c.Assert(stored.Nodes, HasLen, 3)
+	c.Assert(stored.Nodes[2].Error, Equals, "agent is down")
+}
+
+func (r *ClusterSuite) TestRejectsEscapingPaths(c *C) {
+	dir := c.MkDir()
+	index, err := CollectCluster(context.TODO(), ClusterConfig{
+		Servers: []storage.Server{{Hostname: "node-1", AdvertiseIP: "192.168.1.1"}},
+		Collector: testNodeCollector{
+			"node-1": {files: map[string]string{"../../escape": "data"}},
+		},
+	}, NewFileWriter(dir))
+	c.Assert(err, IsNil)
+	c.Assert(index.Nodes[0].Error, Matches, ".*invalid file name.*")
+}
+
+// testNodeCollector maps the node hostname to its diagnostics
+type testNodeCollector map[string]testNodeReport
+
+type testNodeReport struct {
+	files map[string]string
+	err   error
+}
+
+func (r testNodeCollector) Collect(ctx context.Context, server storage.Server, w io.Writer) error {
+	node := r[server.Hostname]
+	var items []*archive.Item
+	for name, data := range node.files {
+		items = append(items, archive.ItemFromString(name, data))
+	}
+	tarball, err := archive.CreateMemArchive(items)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	gzWriter := gzip.NewWriter(w)
+	if _, err := io.Copy(gzWriter, tarball); err != nil {
+		return trace.Wrap(err)
+	}
+	if err := gzWriter.Close(); err != nil {
+		return trace.Wrap(err)
+	}
+	return node.err
+}
+
+func assertFile(c *C, path, expected string) {
+	data, err := ioutil.ReadFile(path)
+	c.Assert(err, IsNil)
+	c.Assert(string(data), Equals, expected)
+}
@@ -31,23 +31,41 @@ import (
 
 // KubernetesInfo returns a list of collectors to fetch kubernetes-related
 // diagnostics.
-func KubernetesInfo(ctx context.Context, runner utils.CommandRunner) Collectors {
+// The diagnostics are limited to the namespaces and the period specified with filter
+func KubernetesInfo(ctx context.Context, runner utils.CommandRunner, filter Filter) Collectors {
 	runner = planetContextRunner{runner}
 	// general kubernetes info
 	commands := Collectors{
 		Cmd("k8s-nodes", utils.PlanetCommand(kubectl.Command("get", "nodes", "-o", "yaml"))...),
 		Cmd("k8s-nodes-describe", utils.PlanetCommand(kubectl.Command("describe", "nodes"))...),
-		Cmd("k8s-podlist", utils.PlanetCommand(kubectl.Command(
-			"get", "pods", "--all-namespaces", "--output", "wide"))...),
-		Cmd("k8s-pod-yaml", utils.PlanetCommand(kubectl.Command(
-			"get", "pods", "-o", "yaml", "--all-namespaces"))...),
-		Cmd("k8s-events", utils.PlanetCommand(kubectl.Command(
-			"get", "events", "--all-namespaces"))...),
 	}
 
-	namespaces, err := kubectl.GetNamespaces(ctx, runner)
-	if err != nil || len(namespaces) == 0 {
-		namespaces = defaults.UsedNamespaces
+	namespaces := filter.Namespaces
+	if len(namespaces) == 0 {
+		commands = append(commands,
+			Cmd("k8s-podlist", utils.PlanetCommand(kubectl.Command(
+				"get", "pods", "--all-namespaces", "--output", "wide"))...),
+			Cmd("k8s-pod-yaml", utils.PlanetCommand(kubectl.Command(
+				"get", "pods", "-o", "yaml", "--all-namespaces"))...),
+			Cmd("k8s-events", utils.PlanetCommand(kubectl.Command(
+				"get", "events", "--all-namespaces"))...),
+		)
+		var err error
+		namespaces, err = kubectl.GetNamespaces(ctx, runner)
+		if err != nil || len(namespaces) == 0 {
+			namespaces = defaults.UsedNamespaces
+		}
+	} else {
+		for _, namespace := range namespaces {
+			commands = append(commands,
+				Cmd(fmt.Sprintf("k8s-podlist-%v", namespace), utils.PlanetCommand(kubectl.Command(
+					"get", "pods", "--namespace", namespace, "--output", "wide"))...),
+				Cmd(fmt.Sprintf("k8s-pod-yaml-%v", namespace), utils.PlanetCommand(kubectl.Command(
+					"get", "pods", "-o", "yaml", "--namespace", namespace))...),
+				Cmd(fmt.Sprintf("k8s-events-%v", namespace), utils.PlanetCommand(kubectl.Command(
+					"get", "events", "--namespace", namespace))...),
+			)
+		}
 	}
 	log.Debugf("kubernetes namespaces: %v", namespaces)
 
@@ -73,10 +91,12 @@ func KubernetesInfo(ctx context.Context, runner utils.CommandRunner) Collectors
 			}
 			for _, container := range containers {
 				name := fmt.Sprintf("k8s-logs-%v-%v-%v", namespace, pod, container)
-				commands = append(commands, Cmd(name, utils.PlanetCommand(kubectl.Command("logs", pod,
-					"--namespace", namespace,
-					fmt.Sprintf("-c=%v", container)))...),
-				)
+				args := []string{"logs", pod, "--namespace", namespace,
+					fmt.Sprintf("-c=%v", container)}
+				if filter.Since != 0 {
+					args = append(args, fmt.Sprintf("--since=%v", filter.Since))
+				}
+				commands = append(commands, Cmd(name, utils.PlanetCommand(kubectl.Command(args...))...))
 			}
 		}
 	}
@@ -0,0 +1,451 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package report
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"sync"
+
+	"github.com/gravitational/trace"
+)
+
+// NewRedactor returns a new redactor that scrubs private keys and certificates,
+// bearer tokens, passwords and the values matching any of the specified
+// regular expressions from the report files
+func NewRedactor(patterns []string) (*Redactor, error) {
+	rules := append([]redactionRule{}, builtinRules...)
+	for i, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, trace.BadParameter("invalid redaction pattern %q: %v", pattern, err)
+		}
+		rules = append(rules, redactionRule{
+			name: fmt.Sprintf("custom-%v", i+1),
+			re:   re,
+		})
+	}
+	return &Redactor{
+		rules: rules,
+		files: make(map[string]map[string]int),
+	}, nil
+}
+
+// Redactor scrubs sensitive data from the report files.
+// Compressed files and tarballs are redacted entry by entry
+type Redactor struct {
+	rules []redactionRule
+	// mu guards files
+	mu sync.Mutex
+	// files maps the file name to the number of redactions per rule
+	files map[string]map[string]int
+}
+
+// Writer returns a report writer that redacts the data before
+// forwarding it to the specified writer
+func (r *Redactor) Writer(reportWriter Writer) Writer {
+	return func(name string) (io.WriteCloser, error) {
+		w, err := reportWriter(name)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		reader, writer := io.Pipe()
+		errC := make(chan error, 1)
+		go func() {
+			err := r.redact(name, reader, w)
+			// unblock the writer if redaction failed
+			reader.CloseWithError(err)
+			errC <- err
+		}()
+		return &redactingWriter{
+			PipeWriter: writer,
+			target:     w,
+			errC:       errC,
+		}, nil
+	}
+}
+
+// Manifest returns the manifest of redactions made so far
+func (r *Redactor) Manifest() RedactionManifest {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var manifest RedactionManifest
+	for _, rule := range r.rules {
+		manifest.Rules = append(manifest.Rules, rule.name)
+	}
+	for name, redactions := range r.files {
+		counts := make(map[string]int, len(redactions))
+		for rule, count := range redactions {
+			counts[rule] = count
+		}
+		manifest.Files = append(manifest.Files, RedactedFile{
+			Name:       name,
+			Redactions: counts,
+		})
+	}
+	sort.Slice(manifest.Files, func(i, j int) bool {
+		return manifest.Files[i].Name < manifest.Files[j].Name
+	})
+	return manifest
+}
+
+// WriteManifest writes the manifest of redactions made so far
+// using the specified report writer
+func (r *Redactor) WriteManifest(reportWriter Writer) error {
+	w, err := reportWriter(RedactionManifestFilename)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	defer w.Close()
+	data, err := json.MarshalIndent(r.Manifest(), "", "    ")
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	_, err = w.Write(data)
+	return trace.ConvertSystemError(err)
+}
+
+// RedactionManifest lists the redactions made in a report
+type RedactionManifest struct {
+	// Rules lists the names of the applied redaction rules
+	Rules []string `json:"rules"`
+	// Files lists the files with redacted data
+	Files []RedactedFile `json:"files"`
+}
+
+// RedactedFile describes the redactions made in a single file
+type RedactedFile struct {
+	// Name is the name of the file.
+	// Files inside archives are named after the archive
+	Name string `json:"name"`
+	// Redactions maps the rule name to the number of redactions
+	Redactions map[string]int `json:"redactions"`
+}
+
+// EncodeRedactPatterns encodes the specified patterns so they can be safely
+// passed as command line arguments through a remote shell
+func EncodeRedactPatterns(patterns []string) (encoded []string) {
+	for _, pattern := range patterns {
+		encoded = append(encoded, base64.RawURLEncoding.EncodeToString([]byte(pattern)))
+	}
+	return encoded
+}
+
+// DecodeRedactPatterns decodes patterns encoded with EncodeRedactPatterns
+func DecodeRedactPatterns(encoded []string) (patterns []string, err error) {
+	for _, value := range encoded {
+		pattern, err := base64.RawURLEncoding.DecodeString(value)
+		if err != nil {
+			return nil, trace.BadParameter("invalid encoded pattern %q: %v", value, err)
+		}
+		patterns = append(patterns, string(pattern))
+	}
+	return patterns, nil
+}
+
+// redact copies the data of the named file from r to w, redacting it
+func (r *Redactor) redact(name string, reader io.Reader, w io.Writer) error {
+	br := bufio.NewReader(reader)
+	header, err := br.Peek(tarHeaderPeekSize)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return trace.ConvertSystemError(err)
+	}
+	switch {
+	case bytes.HasPrefix(header, gzipMagic):
+		return r.redactGzip(name, br, w)
+	case len(header) == tarHeaderPeekSize && bytes.Equal(header[tarMagicOffset:], tarMagic):
+		return r.redactTar(name, br, w)
+	default:
+		return r.redactText(name, br, w)
+	}
+}
+
+func (r *Redactor) redactGzip(name string, reader io.Reader, w io.Writer) error {
+	gzReader, err := gzip.NewReader(reader)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	defer gzReader.Close()
+	gzWriter := gzip.NewWriter(w)
+	if err := r.redact(name, gzReader, gzWriter); err != nil {
+		return trace.Wrap(err)
+	}
+	return trace.Wrap(gzWriter.Close())
+}
+
+// redactTar redacts the tarball entry by entry.
+// Redacted entries are spooled to a temporary file since
+// redaction changes the entry size recorded in the header
+func (r *Redactor) redactTar(name string, reader io.Reader, w io.Writer) error {
+	tarReader := tar.NewReader(reader)
+	tarWriter := tar.NewWriter(w)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		if header.Typeflag != tar.TypeReg && header.Typeflag != tar.TypeRegA {
+			if err := tarWriter.WriteHeader(header); err != nil {
+				return trace.Wrap(err)
+			}
+			continue
+		}
+		if err := r.redactTarEntry(path.Join(name, header.Name), header, tarReader, tarWriter); err != nil {
+			return trace.Wrap(err)
+		}
+	}
+	return trace.Wrap(tarWriter.Close())
+}
+
+func (r *Redactor) redactTarEntry(name string, header *tar.Header, reader io.Reader, tarWriter *tar.Writer) error {
+	f, err := ioutil.TempFile("", "redact")
+	if err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	defer func() {
+		f.Close()
+		os.Remove(f.Name())
+	}()
+	if err := r.redact(name, reader, f); err != nil {
+		return trace.Wrap(err)
+	}
+	size, err := f.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	header.Size = size
+	if err := tarWriter.WriteHeader(header); err != nil {
+		return trace.Wrap(err)
+	}
+	_, err = io.Copy(tarWriter, f)
+	return trace.Wrap(err)
+}
+
+// redactText redacts the data line by line.
+// Bodies of multi-line PEM blocks are replaced with a single placeholder line
+func (r *Redactor) redactText(name string, reader *bufio.Reader, w io.Writer) error {
+	counts := make(map[string]int)
+	defer r.record(name, counts)
+	// pemIndent is the indentation of the current PEM block
+	var pemIndent []byte
+	// pemPlaceholder is set if the body of the current PEM block
+	// only consists of the placeholder, i.e. it has already been redacted
+	var pemPlaceholder bool
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(line) != 0 {
+			var out []byte
+			switch {
+			case pemIndent != nil:
+				if pemEnd.Match(line) {
+					out = append(pemBody(pemIndent), line...)
+					if !pemPlaceholder {
+						counts[pemRule]++
+					}
+					pemIndent = nil
+				} else if !bytes.Equal(bytes.TrimSpace(line), redactedPlaceholder) {
+					pemPlaceholder = false
+				}
+			case pemBegin.Match(line) && !pemEnd.Match(line):
+				pemIndent = leadingSpace(line)
+				pemPlaceholder = true
+				out = line
+			default:
+				out = r.redactLine(line, counts)
+			}
+			if _, err := w.Write(out); err != nil {
+				return trace.ConvertSystemError(err)
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return trace.ConvertSystemError(err)
+		}
+	}
+	if pemIndent != nil {
+		// unterminated block
+		if _, err := w.Write(pemBody(pemIndent)); err != nil {
+			return trace.ConvertSystemError(err)
+		}
+		if !pemPlaceholder {
+			counts[pemRule]++
+		}
+	}
+	return nil
+}
+
+// redactLine applies the redaction rules to the line
+func (r *Redactor) redactLine(line []byte, counts map[string]int) []byte {
+	for _, rule := range r.rules {
+		line = rule.redact(line, counts)
+	}
+	return line
+}
+
+func (r *Redactor) record(name string, counts map[string]int) {
+	if len(counts) == 0 {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	redactions, ok := r.files[name]
+	if !ok {
+		redactions = make(map[string]int)
+		r.files[name] = redactions
+	}
+	for rule, count := range counts {
+		redactions[rule] += count
+	}
+}
+
+// redact replaces the matches of the rule in line with the placeholder.
+// If the rule has a group named secret, only the group is replaced
+func (r redactionRule) redact(line []byte, counts map[string]int) []byte {
+	matches := r.re.FindAllSubmatchIndex(line, -1)
+	if len(matches) == 0 {
+		return line
+	}
+	group := secretGroupIndex(r.re)
+	var out []byte
+	var last int
+	for _, match := range matches {
+		start, end := match[0], match[1]
+		if group > 0 {
+			start, end = match[2*group], match[2*group+1]
+		}
+		if start < 0 || bytes.Equal(line[start:end], redactedPlaceholder) {
+			continue
+		}
+		out = append(out, line[last:start]...)
+		out = append(out, redactedPlaceholder...)
+		last = end
+		counts[r.name]++
+	}
+	return append(out, line[last:]...)
+}
+
+// redactingWriter forwards the data to the redacting goroutine
+type redactingWriter struct {
+	*io.PipeWriter
+	target io.WriteCloser
+	errC   chan error
+}
+
+// Close waits for the redaction to complete and closes the target writer
+func (r *redactingWriter) Close() error {
+	r.PipeWriter.Close()
+	err := <-r.errC
+	return trace.NewAggregate(err, r.target.Close())
+}
+
+type redactionRule struct {
+	name string
+	re   *regexp.Regexp
+}
+
+// secretGroupIndex returns the index of the secret group in re
+// or -1 if re does not have the secret group
+func secretGroupIndex(re *regexp.Regexp) int {
+	for i, name := range re.SubexpNames() {
+		if name == secretGroup {
+			return i
+		}
+	}
+	return -1
+}
+
+func pemBody(indent []byte) []byte {
+	out := append([]byte{}, indent...)
+	out = append(out, redactedPlaceholder...)
+	return append(out, '\n')
+}
+
+func leadingSpace(line []byte) []byte {
+	trimmed := bytes.TrimLeft(line, " \t")
+	return append([]byte{}, line[:len(line)-len(trimmed)]...)
+}
+
+// builtinRules lists the redaction rules that are always applied
+var builtinRules = []redactionRule{
+	{
+		// PEM blocks serialized on a single line, i.e. in JSON
+		name: pemRule,
+		re:   regexp.MustCompile(`-----BEGIN [A-Z0-9 ]+-----(?P<secret>.+?)-----END [A-Z0-9 ]+-----`),
+	},
+	{
+		name: "bearer-token",
+		re:   regexp.MustCompile(`(?i)\bbearer\s+(?P<secret>[A-Za-z0-9\-._~+/]+=*)`),
+	},
+	{
+		name: "url-credentials",
+		re:   regexp.MustCompile(`[a-zA-Z][a-zA-Z0-9+.-]*://[^/\s:@]+:(?P<secret>[^/\s@]+)@`),
+	},
+	{
+		name: "password",
+		re: regexp.MustCompile(`(?i)(?:password|passwd|secret|token|api[_-]?key)[a-z_]*["']?\s*[:=]\s*["']?` +
+			`(?P<secret>[^\s"',;&]+)`),
+	},
+	{
+		name: "password-flag",
+		re:   regexp.MustCompile(`(?i)--(?:password|passwd|secret|token)[a-z-]*\s+["']?(?P<secret>[^\s"'-][^\s"']*)`),
+	},
+}
+
+var (
+	pemBegin = regexp.MustCompile(`-----BEGIN [A-Z0-9 ]+-----`)
+	pemEnd   = regexp.MustCompile(`-----END [A-Z0-9 ]+-----`)
+
+	redactedPlaceholder = []byte("REDACTED")
+
+	gzipMagic = []byte{0x1f, 0x8b}
+	tarMagic  = []byte("ustar")
+)
+
+const (
+	// RedactionManifestFilename is the name of the report file
+	// with the manifest of redactions
+	RedactionManifestFilename = "redactions.json"
+
+	// pemRule is the name of the PEM block redaction rule
+	pemRule = "pem"
+	// secretGroup names the part of the rule match to redact
+	secretGroup = "secret"
+
+	// tarMagicOffset is the offset of the magic in the tar header
+	tarMagicOffset = 257
+	// tarHeaderPeekSize is the number of bytes to peek to detect a tarball
+	tarHeaderPeekSize = tarMagicOffset + 5
+)
@@ -22,6 +22,7 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/gravitational/gravity/lib/defaults"
 	"github.com/gravitational/gravity/lib/utils"
@@ -38,7 +39,8 @@ func NewFileWriter(dir string) Writer {
 }
 
 // NewPendingFileWriter creates a new instance of the pendingWriter
-// for the specified path
+// for the specified path.
+// The parent directories of the path are created on the first write
 func NewPendingFileWriter(path string) *pendingWriter {
 	return &pendingWriter{path: path}
 }
@@ -51,7 +53,10 @@ func (r *pendingWriter) Write(data []byte) (n int, err error) {
 		return 0, nil
 	}
 	if r.file == nil {
-		var err error
+		err := os.MkdirAll(filepath.Dir(r.path), defaults.SharedDirMask)
+		if err != nil {
+			return 0, err
+		}
 		r.file, err = os.OpenFile(r.path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC,
 			defaults.SharedReadWriteMask)
 		if err != nil {
@@ -83,6 +88,22 @@ type pendingWriter struct {
 // data with a name.
 type Writer func(name string) (io.WriteCloser, error)
 
+// Filter limits the diagnostics collected into a report
+type Filter struct {
+	// Since limits the collected logs to the specified period.
+	// Logs are not limited if unspecified
+	Since time.Duration
+	// Namespaces limits the kubernetes diagnostics to the specified namespaces.
+	// All namespaces are collected if unspecified
+	Namespaces []string
+}
+
+// JournalSince returns the journalctl argument that limits
+// the journal entries to the specified period
+func JournalSince(since time.Duration) string {
+	return fmt.Sprintf("--since=-%vs", int64(since.Seconds()))
+}
+
 // Collector defines an interface to collect diagnostic information
 type Collector interface {
 	// Collect collects diagnostics using CommandRunner and serializes
@@ -20,13 +20,15 @@ import (
 	"fmt"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/gravitational/gravity/lib/defaults"
 	"github.com/gravitational/gravity/lib/utils"
 )
 
-// SystemInfo returns a list of collectors to fetch various bits of system information
-func SystemInfo() Collectors {
+// SystemInfo returns a list of collectors to fetch various bits of system information.
+// Journal logs are limited to the period specified with filter
+func SystemInfo(filter Filter) Collectors {
 	var collectors Collectors
 	add := func(additional ...Collector) {
 		collectors = append(collectors, additional...)
@@ -34,9 +36,9 @@ func SystemInfo() Collectors {
 
 	add(basicSystemInfo()...)
 	add(planetServices()...)
-	add(syslogExportLogs())
+	add(syslogExportLogs(filter.Since))
 	add(systemFileLogs()...)
-	add(planetLogs()...)
+	add(planetLogs(filter.Since)...)
 	add(bashHistoryCollector{})
 
 	return collectors
@@ -81,7 +83,7 @@ func planetServices() Collectors {
 
 // syslogExportLogs fetches logs for gravity binary invocations
 // (including installation logs)
-func syslogExportLogs() Collector {
+func syslogExportLogs(since time.Duration) Collector {
 	const script = `
 #!/bin/bash
 /bin/journalctl --no-pager --output=export %v | /bin/gzip -f`
@@ -94,6 +96,10 @@ func syslogExportLogs() Collector {
 		syslogID(defaults.GravityBin),
 	}
 
+	if since != 0 {
+		matches = append(matches, JournalSince(since))
+	}
+
 	return Script("gravity-system.log.gz", fmt.Sprintf(script, strings.Join(matches, " ")))
 }
 
@@ -109,7 +115,11 @@ cat %v 2> /dev/null || true`
 }
 
 // planetLogs fetches planet syslog messages as well as the fresh journal entries
-func planetLogs() Collectors {
+func planetLogs(since time.Duration) Collectors {
+	args := []string{"system", "export-runtime-journal"}
+	if since != 0 {
+		args = append(args, fmt.Sprintf("--since=%v", since))
+	}
 	return Collectors{
 		// Fetch planet syslog messages as a tarball
 		Script("planet-logs.tar.gz", tarball(defaults.InGravity("planet/log/messages*"))),
@@ -117,7 +127,6 @@ func planetLogs() Collectors {
 		// The log can be imported as a journal with systemd-journal-remote:
 		//
 		// $ cat ./node-1-planet-journal-export.log | /lib/systemd/systemd-journal-remote -o ./journal/system.journal -
-		Self("planet-journal-export.log.gz",
-			"system", "export-runtime-journal"),
+		Self("planet-journal-export.log.gz", args...),
 	}
 }
@@ -50,6 +50,27 @@ func (c *client) GravityCommand(ctx context.Context, log logrus.FieldLogger, w i
 	return trace.Wrap(err)
 }
 
+// GravityCommandOutput executes the gravity command specified with args on remote node
+// and streams its standard output to w.
+// Unlike GravityCommand, the standard error is not written to w and the output
+// is not logged which makes it suitable for commands with binary output
+func (c *client) GravityCommandOutput(ctx context.Context, log logrus.FieldLogger, w io.Writer, args ...string) error {
+	if len(args) < 1 {
+		return trace.BadParameter("at least one argument is required")
+	}
+
+	out, err := c.agent.Command(ctx, &pb.CommandArgs{
+		SelfCommand: true,
+		Args:        args,
+	})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	streamCtx := &streamContext{commands: map[int32][]string{}, log: log, stdoutOnly: true}
+	return trace.Wrap(streamCtx.process(out, w))
+}
+
 // Validate validates the node against the specified manifest and profile.
 // Returns the list of failed probes
 func (c *client) Validate(ctx context.Context, req *validationpb.ValidateRequest) ([]*agentpb.Probe, error) {
@@ -87,10 +108,17 @@ func (c *client) command(ctx context.Context, log logrus.FieldLogger, w io.Write
 type streamContext struct {
 	commands map[int32][]string
 	log      logrus.FieldLogger
+	// stdoutOnly specifies whether only the standard output
+	// is forwarded to the output writer
+	stdoutOnly bool
 }
 
 func processStream(stream pb.IncomingMessageStream, log logrus.FieldLogger, out io.Writer) error {
-	streamCtx := &streamContext{map[int32][]string{}, log}
+	streamCtx := &streamContext{commands: map[int32][]string{}, log: log}
+	return streamCtx.process(stream, out)
+}
+
+func (s *streamContext) process(stream pb.IncomingMessageStream, out io.Writer) error {
 	if out == nil {
 		out = ioutil.Discard
 	}
@@ -106,21 +134,21 @@ func processStream(stream pb.IncomingMessageStream, log logrus.FieldLogger, out
 
 		switch elem := msg.Element.(type) {
 		case *pb.Message_ExecOutput:
-			err = trace.Wrap(streamCtx.processExecOutput(elem.ExecOutput, out))
+			err = trace.Wrap(s.processExecOutput(elem.ExecOutput, out))
 		case *pb.Message_ExecStarted:
-			err = trace.Wrap(streamCtx.processExecStarted(elem.ExecStarted))
+			err = trace.Wrap(s.processExecStarted(elem.ExecStarted))
 		case *pb.Message_ExecCompleted:
-			err = trace.Wrap(streamCtx.processExecCompleted(elem.ExecCompleted))
+			err = trace.Wrap(s.processExecCompleted(elem.ExecCompleted))
 		case *pb.Message_LogEntry:
-			err = trace.Wrap(streamCtx.processLogEntry(elem.LogEntry))
+			err = trace.Wrap(s.processLogEntry(elem.LogEntry))
 		case *pb.Message_Error:
-			err = trace.Wrap(streamCtx.processError(elem.Error))
+			err = trace.Wrap(s.processError(elem.Error))
 		default:
 			err = trace.BadParameter("unexpected message %+v", msg.Element)
 		}
 
 		if err != nil {
-			log.WithError(err).Error("error processing stream")
+			s.log.WithError(err).Error("error processing stream")
 		}
 	}
 }
@@ -133,6 +161,19 @@ func (s *streamContext) processExecOutput(msg *pb.ExecOutput, out io.Writer) err
 		entry = s.log.WithField("CMD", fmt.Sprintf("%s#%d", args[0], msg.Seq))
 	}
 
+	if s.stdoutOnly {
+		switch msg.Fd {
+		case pb.ExecOutput_STDOUT:
+			_, err := out.Write(msg.Data)
+			return trace.Wrap(err)
+		case pb.ExecOutput_STDERR:
+			entry.Warnf("%q", msg.Data)
+			return nil
+		default:
+			return trace.BadParameter("unexpected output descriptor value %v", msg.Fd)
+		}
+	}
+
 	if _, err := out.Write(msg.Data); err != nil {
 		entry.WithError(err).Warn("failed to output")
 	}
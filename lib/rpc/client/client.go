@@ -40,6 +40,9 @@ type Client interface {
 	Command(ctx context.Context, log logrus.FieldLogger, out io.Writer, args ...string) error
 	// GravityCommand executes the gravity command specified with args remotely
 	GravityCommand(ctx context.Context, log logrus.FieldLogger, out io.Writer, args ...string) error
+	// GravityCommandOutput executes the gravity command specified with args remotely
+	// and streams only its standard output to out
+	GravityCommandOutput(ctx context.Context, log logrus.FieldLogger, out io.Writer, args ...string) error
 	// Validate validates the node against the specified manifest and profile.
 	// Returns the list of failed probes
 	Validate(ctx context.Context, req *validationpb.ValidateRequest) ([]*agentpb.Probe, error)
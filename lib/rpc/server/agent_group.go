@@ -240,6 +240,10 @@ func (r errorPeer) GravityCommand(context.Context, log.FieldLogger, io.Writer, .
 	return trace.Wrap(r.error)
 }
 
+func (r errorPeer) GravityCommandOutput(context.Context, log.FieldLogger, io.Writer, ...string) error {
+	return trace.Wrap(r.error)
+}
+
 func (r errorPeer) Validate(context.Context, *validationpb.ValidateRequest) ([]*agentpb.Probe, error) {
 	return nil, trace.Wrap(r.error)
 }
@@ -0,0 +1,50 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	healthChecks = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "gravity_rpc_agent_health_checks_total",
+			Help: "Number of RPC agent health checks, by result",
+		},
+		[]string{"result"},
+	)
+	reconnects = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "gravity_rpc_agent_reconnects_total",
+			Help: "Number of attempts to reconnect to an RPC agent, by result",
+		},
+		[]string{"result"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(healthChecks)
+	prometheus.MustRegister(reconnects)
+}
+
+const (
+	// resultSuccess labels successful health checks and reconnects
+	resultSuccess = "success"
+	// resultFailure labels failed health checks and reconnects
+	resultFailure = "failure"
+)
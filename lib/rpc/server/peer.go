@@ -366,6 +366,15 @@ func (r *peer) GravityCommand(ctx context.Context, log log.FieldLogger, out io.W
 	return trace.Wrap(r.Client.Client().GravityCommand(ctx, log, out, args...))
 }
 
+// GravityCommandOutput executes the gravity command specified with args on this peer
+// and streams only its standard output to out
+func (r *peer) GravityCommandOutput(ctx context.Context, log log.FieldLogger, out io.Writer, args ...string) error {
+	if r.Client == nil {
+		return trace.ConnectionProblem(nil, "%v not connected", r.Addr())
+	}
+	return trace.Wrap(r.Client.Client().GravityCommandOutput(ctx, log, out, args...))
+}
+
 // GetSystemInfo queries remote system information
 func (r *peer) GetSystemInfo(ctx context.Context) (storage.System, error) {
 	if r.Client == nil {
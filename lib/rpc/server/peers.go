@@ -177,8 +177,10 @@ func (r *peers) checkPeer(p Peer, clt Client, reconnectCh chan<- chan clientUpda
 	if clt != nil {
 		resp, err := clt.Check(r.ctx, &healthpb.HealthCheckRequest{})
 		if err == nil && isPeerHealthy(*resp) {
+			healthChecks.WithLabelValues(resultSuccess).Inc()
 			return clt, nil
 		}
+		healthChecks.WithLabelValues(resultFailure).Inc()
 		log.Warnf("Failed health check: %+v (%v).", resp, err)
 	}
 	select {
@@ -226,7 +228,10 @@ func (r *peers) reconnectPeer(p Peer, reqCh <-chan chan clientUpdate, doneCh cha
 			})
 			select {
 			case respCh <- clientUpdate{clt, err}:
-				if err == nil {
+				if err != nil {
+					reconnects.WithLabelValues(resultFailure).Inc()
+				} else {
+					reconnects.WithLabelValues(resultSuccess).Inc()
 					log.Info("Peer reconnected.")
 				}
 			case <-r.ctx.Done():
@@ -642,6 +642,8 @@ type Requirements struct {
 	Devices []Device `json:"devices,omitempty"`
 	// CustomChecks lists additional preflight checks as inline scripts
 	CustomChecks []CustomCheck `json:"customChecks,omitempty"`
+	// Checks lists additional typed preflight checks
+	Checks []Check `json:"checks,omitempty"`
 }
 
 // Device describes a device that should be created inside container
@@ -719,6 +721,34 @@ type CustomCheck struct {
 	Script string `json:"script,omitempty"`
 }
 
+// Check defines a typed preflight check.
+//
+// The check is executed by the checker registered for its type,
+// for example, kernelModules or filesystem
+type Check struct {
+	// Type is the type of the check
+	Type string `json:"type"`
+	// Description provides a readable description for the check
+	Description string `json:"description,omitempty"`
+	// Severity defines whether the check failure is fatal or a warning.
+	// Defaults to fatal
+	Severity string `json:"severity,omitempty"`
+	// Spec is the check type specific configuration
+	Spec json.RawMessage `json:"spec,omitempty"`
+}
+
+// IsWarning returns true if the check failure is a warning
+func (c Check) IsWarning() bool {
+	return c.Severity == CheckSeverityWarning
+}
+
+const (
+	// CheckSeverityWarning defines checks whose failure is reported as a warning
+	CheckSeverityWarning = "warning"
+	// CheckSeverityFatal defines checks whose failure prevents the operation
+	CheckSeverityFatal = "fatal"
+)
+
 // DevicesForProfile returns a list of required devices for the specified profile
 func (m Manifest) DevicesForProfile(profileName string) ([]Device, error) {
 	profile, err := m.NodeProfiles.ByName(profileName)
@@ -272,6 +272,20 @@ const manifestSchema = `
                         "script": {"type": "string"}
                       }
                     }
+                  },
+                  "checks": {
+                    "type": "array",
+                    "items": {
+                      "type": "object",
+                      "required": ["type"],
+                      "additionalProperties": false,
+                      "properties": {
+                        "type": {"type": "string"},
+                        "description": {"type": "string"},
+                        "severity": {"type": "string", "enum": ["warning", "fatal"]},
+                        "spec": {"type": "object"}
+                      }
+                    }
                   }
                 }
               },
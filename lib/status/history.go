@@ -0,0 +1,385 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package status
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/storage"
+
+	pb "github.com/gravitational/satellite/agent/proto/agentpb"
+	"github.com/gravitational/trace"
+	"github.com/jonboulle/clockwork"
+	"github.com/sirupsen/logrus"
+)
+
+// SamplerConfig defines the configuration of the status history sampler
+type SamplerConfig struct {
+	// Backend is the backend the status history is stored in
+	Backend storage.StatusHistory
+	// ClusterName is the name of the cluster to sample
+	ClusterName string
+	// Capacity is the maximum number of transitions to keep in the history
+	Capacity int
+	// Clock is used to timestamp the samples
+	Clock clockwork.Clock
+	// FieldLogger is used for logging
+	logrus.FieldLogger
+}
+
+// CheckAndSetDefaults validates the configuration and sets defaults
+func (r *SamplerConfig) CheckAndSetDefaults() error {
+	if r.Backend == nil {
+		return trace.BadParameter("missing Backend")
+	}
+	if r.ClusterName == "" {
+		return trace.BadParameter("missing ClusterName")
+	}
+	if r.Capacity == 0 {
+		r.Capacity = defaults.StatusHistoryCapacity
+	}
+	if r.Clock == nil {
+		r.Clock = clockwork.NewRealClock()
+	}
+	if r.FieldLogger == nil {
+		r.FieldLogger = logrus.WithField(trace.Component, "status:history")
+	}
+	return nil
+}
+
+// NewSampler returns a new sampler that records the health transitions
+// of the cluster in its status history
+func NewSampler(config SamplerConfig) (*Sampler, error) {
+	if err := config.CheckAndSetDefaults(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &Sampler{SamplerConfig: config}, nil
+}
+
+// Sampler samples the health of the cluster and records
+// the transitions between the samples in the status history
+type Sampler struct {
+	// SamplerConfig is the sampler configuration
+	SamplerConfig
+	// last maps the subject of a transition to its last known health.
+	// It is loaded from the status history on the first sample
+	last map[string]HealthState
+}
+
+// Sample collects the health of the cluster with the specified servers
+// and records the transitions since the previous sample
+func (r *Sampler) Sample(ctx context.Context, servers []storage.Server) error {
+	states := collectHealth(ctx, servers)
+	return trace.Wrap(r.Record(states))
+}
+
+// Record records the transitions from the last known health to the
+// specified health states.
+// Subjects missing in states keep their last known health
+func (r *Sampler) Record(states []HealthState) error {
+	if r.last == nil {
+		if err := r.loadLast(); err != nil {
+			return trace.Wrap(err)
+		}
+	}
+	now := r.Clock.Now().UTC()
+	var transitions []storage.StatusTransition
+	for _, state := range states {
+		previous, known := r.last[state.subject()]
+		if !known && state.Probe != "" {
+			// probes without a history are assumed to be healthy
+			// to avoid recording every passing probe
+			previous.Status = ProbeHealthy
+		}
+		if previous.Status == state.Status {
+			continue
+		}
+		transitions = append(transitions, storage.StatusTransition{
+			Node:     state.Node,
+			Hostname: state.Hostname,
+			Probe:    state.Probe,
+			Status:   state.Status,
+			Previous: previous.Status,
+			Detail:   state.Detail,
+			Time:     now,
+		})
+	}
+	if len(transitions) == 0 {
+		return nil
+	}
+	err := r.Backend.AddStatusTransitions(r.ClusterName, transitions, r.Capacity)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	for _, state := range states {
+		r.last[state.subject()] = state
+	}
+	r.WithField("transitions", len(transitions)).Debug("Recorded status transitions.")
+	return nil
+}
+
+func (r *Sampler) loadLast() error {
+	transitions, err := r.Backend.GetStatusTransitions(r.ClusterName)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	r.last = make(map[string]HealthState)
+	for _, transition := range transitions {
+		state := stateFromTransition(transition)
+		r.last[state.subject()] = state
+	}
+	return nil
+}
+
+// HealthState describes the health of the cluster,
+// a cluster node or a health probe on a node
+type HealthState struct {
+	// Node is the advertise address of the node.
+	// Empty for the cluster
+	Node string
+	// Hostname is the hostname of the node
+	Hostname string
+	// Probe is the name of the probe.
+	// Empty for the cluster and nodes
+	Probe string
+	// Status is the health status
+	Status string
+	// Detail optionally describes the status
+	Detail string
+}
+
+// subject returns the key that identifies the subject of the health state
+func (r HealthState) subject() string {
+	return r.Node + "/" + r.Probe
+}
+
+func stateFromTransition(transition storage.StatusTransition) HealthState {
+	return HealthState{
+		Node:     transition.Node,
+		Hostname: transition.Hostname,
+		Probe:    transition.Probe,
+		Status:   transition.Status,
+		Detail:   transition.Detail,
+	}
+}
+
+// collectHealth returns the health of the cluster, its nodes and probes
+// as reported by the planet agents and the status extension.
+// Only the cluster health is returned if the agents cannot be queried
+func collectHealth(ctx context.Context, servers []storage.Server) (states []HealthState) {
+	extension := newExtension()
+	if err := extension.Collect(); err != nil {
+		states = append(states, HealthState{
+			Probe:  extensionProbe,
+			Status: ProbeFailed,
+			Detail: err.Error(),
+		})
+	} else {
+		states = append(states, HealthState{
+			Probe:  extensionProbe,
+			Status: ProbeHealthy,
+		})
+	}
+	systemStatus, err := planetAgentStatus(ctx, false)
+	if err != nil {
+		logrus.WithError(err).Warn("Failed to query cluster status from agent.")
+		return append(states, HealthState{
+			Status: NodeOffline,
+			Detail: trace.UserMessage(err),
+		})
+	}
+	return append(states, fromSystemStatusHealth(*systemStatus, servers)...)
+}
+
+// fromSystemStatusHealth returns the health states of the cluster,
+// the specified servers and their probes from the agent system status
+func fromSystemStatusHealth(systemStatus pb.SystemStatus, servers []storage.Server) (states []HealthState) {
+	cluster := HealthState{Status: NodeHealthy}
+	if systemStatus.Status != pb.SystemStatus_Running {
+		cluster.Status = NodeDegraded
+		cluster.Detail = systemStatus.Summary
+	}
+	states = append(states, cluster)
+	nodes := nodes(systemStatus)
+	for _, server := range fromClusterState(systemStatus, servers) {
+		states = append(states, HealthState{
+			Node:     server.AdvertiseIP,
+			Hostname: server.Hostname,
+			Status:   server.Status,
+			Detail:   strings.Join(server.FailedProbes, ", "),
+		})
+		node, ok := nodes[server.AdvertiseIP]
+		if !ok {
+			continue
+		}
+		states = append(states, fromProbes(server, node.Probes)...)
+	}
+	return states
+}
+
+// fromProbes returns the health states of the probes on the specified server.
+// Probes reported by the same checker are combined into a single state
+// that fails if any of the probes fails
+func fromProbes(server ClusterServer, probes []*pb.Probe) (states []HealthState) {
+	byChecker := make(map[string]*HealthState)
+	var checkers []string
+	for _, probe := range probes {
+		state, ok := byChecker[probe.Checker]
+		if !ok {
+			state = &HealthState{
+				Node:     server.AdvertiseIP,
+				Hostname: server.Hostname,
+				Probe:    probe.Checker,
+				Status:   ProbeHealthy,
+			}
+			byChecker[probe.Checker] = state
+			checkers = append(checkers, probe.Checker)
+		}
+		if probe.Status != pb.Probe_Running {
+			state.Status = ProbeFailed
+			state.Detail = probeErrorDetail(*probe)
+		}
+	}
+	sort.Strings(checkers)
+	for _, checker := range checkers {
+		states = append(states, *byChecker[checker])
+	}
+	return states
+}
+
+// NewHistory computes the health history of the cluster over the period
+// between since and until from the specified status transitions.
+// The transitions are expected to be ordered from the oldest
+func NewHistory(transitions []storage.StatusTransition, since, until time.Time) History {
+	history := History{
+		Since: since,
+		Until: until,
+	}
+	subjects := make(map[string][]storage.StatusTransition)
+	var order []string
+	for _, transition := range transitions {
+		if !transition.Time.Before(since) && !transition.Time.After(until) {
+			history.Transitions = append(history.Transitions, transition)
+		}
+		if transition.Probe != "" {
+			continue
+		}
+		subject := stateFromTransition(transition).subject()
+		if _, ok := subjects[subject]; !ok {
+			order = append(order, subject)
+		}
+		subjects[subject] = append(subjects[subject], transition)
+	}
+	for _, subject := range order {
+		availability, ok := newAvailability(subjects[subject], since, until)
+		if !ok {
+			continue
+		}
+		if availability.Node == "" {
+			history.Cluster = availability
+		} else {
+			history.Nodes = append(history.Nodes, *availability)
+		}
+	}
+	sort.Slice(history.Nodes, func(i, j int) bool {
+		return history.Nodes[i].Hostname < history.Nodes[j].Hostname
+	})
+	return history
+}
+
+// newAvailability computes the availability of a single subject
+// from its transitions over the period between since and until.
+// Returns false if the subject status is not known during the period
+func newAvailability(transitions []storage.StatusTransition, since, until time.Time) (*Availability, bool) {
+	var availability *Availability
+	for i, transition := range transitions {
+		if transition.Time.After(until) {
+			break
+		}
+		if availability == nil {
+			availability = &Availability{Node: transition.Node}
+		}
+		availability.Hostname = transition.Hostname
+		availability.Status = transition.Status
+		start, end := transition.Time, until
+		if i+1 < len(transitions) && transitions[i+1].Time.Before(until) {
+			end = transitions[i+1].Time
+		}
+		if start.Before(since) {
+			start = since
+		}
+		if !end.After(start) {
+			continue
+		}
+		availability.Observed += end.Sub(start)
+		if transition.Status == NodeHealthy {
+			availability.healthy += end.Sub(start)
+		}
+	}
+	if availability == nil || availability.Observed == 0 {
+		return nil, false
+	}
+	availability.Percentage = 100 * float64(availability.healthy) / float64(availability.Observed)
+	return availability, true
+}
+
+// History describes the health of the cluster over a period of time
+type History struct {
+	// Since is the start of the period
+	Since time.Time `json:"since"`
+	// Until is the end of the period
+	Until time.Time `json:"until"`
+	// Cluster is the availability of the cluster as a whole
+	Cluster *Availability `json:"cluster,omitempty"`
+	// Nodes lists the availability of individual nodes
+	Nodes []Availability `json:"nodes,omitempty"`
+	// Transitions lists the health transitions during the period
+	Transitions []storage.StatusTransition `json:"transitions,omitempty"`
+}
+
+// Availability describes the availability of the cluster
+// or a cluster node over a period of time
+type Availability struct {
+	// Node is the advertise address of the node.
+	// Empty for the cluster
+	Node string `json:"node,omitempty"`
+	// Hostname is the hostname of the node
+	Hostname string `json:"hostname,omitempty"`
+	// Status is the last known status
+	Status string `json:"status"`
+	// Percentage is the percentage of the observed time the subject was healthy
+	Percentage float64 `json:"availability"`
+	// Observed is the part of the period the status was known for
+	Observed time.Duration `json:"observed"`
+	// healthy is the part of the period the subject was healthy for
+	healthy time.Duration
+}
+
+const (
+	// ProbeHealthy is the status of a passing health probe
+	ProbeHealthy = "healthy"
+	// ProbeFailed is the status of a failing health probe
+	ProbeFailed = "failed"
+
+	// extensionProbe is the name of the probe that describes
+	// the health of the status extension
+	extensionProbe = "status-extension"
+)
@@ -0,0 +1,154 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package status
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/storage/keyval"
+
+	pb "github.com/gravitational/satellite/agent/proto/agentpb"
+	"github.com/jonboulle/clockwork"
+	"gopkg.in/check.v1"
+)
+
+func TestStatus(t *testing.T) { check.TestingT(t) }
+
+type HistorySuite struct {
+	backend storage.Backend
+	clock   clockwork.FakeClock
+}
+
+var _ = check.Suite(&HistorySuite{})
+
+func (s *HistorySuite) SetUpTest(c *check.C) {
+	var err error
+	s.clock = clockwork.NewFakeClockAt(time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC))
+	s.backend, err = keyval.NewBolt(keyval.BoltConfig{
+		Path: filepath.Join(c.MkDir(), "bolt.db"),
+	})
+	c.Assert(err, check.IsNil)
+}
+
+func (s *HistorySuite) TearDownTest(c *check.C) {
+	s.backend.Close()
+}
+
+func (s *HistorySuite) TestRecordsTransitions(c *check.C) {
+	sampler := s.newSampler(c)
+	start := s.clock.Now()
+	healthy := []HealthState{
+		{Status: NodeHealthy},
+		{Node: "192.168.1.1", Hostname: "node-1", Status: NodeHealthy},
+		{Node: "192.168.1.1", Hostname: "node-1", Probe: "docker", Status: ProbeHealthy},
+	}
+	c.Assert(sampler.Record(healthy), check.IsNil)
+	s.clock.Advance(time.Hour)
+	c.Assert(sampler.Record(healthy), check.IsNil)
+	s.clock.Advance(time.Hour)
+	degraded := []HealthState{
+		{Status: NodeDegraded},
+		{Node: "192.168.1.1", Hostname: "node-1", Status: NodeDegraded},
+		{Node: "192.168.1.1", Hostname: "node-1", Probe: "docker", Status: ProbeFailed, Detail: "docker is down"},
+	}
+	c.Assert(sampler.Record(degraded), check.IsNil)
+	s.clock.Advance(time.Hour)
+
+	// the sampler resumes from the recorded history after a restart
+	sampler = s.newSampler(c)
+	c.Assert(sampler.Record(healthy), check.IsNil)
+	s.clock.Advance(time.Hour)
+
+	transitions, err := s.backend.GetStatusTransitions("example.com")
+	c.Assert(err, check.IsNil)
+	var statuses []string
+	for _, transition := range transitions {
+		statuses = append(statuses, transition.Previous+"->"+transition.Status)
+	}
+	c.Assert(statuses, check.DeepEquals, []string{
+		// passing probes are not recorded initially
+		"->healthy", "->healthy",
+		"healthy->degraded", "healthy->degraded", "healthy->failed",
+		"degraded->healthy", "degraded->healthy", "failed->healthy",
+	})
+
+	history := NewHistory(transitions, start.Add(time.Hour), s.clock.Now())
+	c.Assert(history.Transitions, check.HasLen, 6)
+	c.Assert(history.Cluster, check.NotNil)
+	c.Assert(history.Cluster.Observed, check.Equals, 3*time.Hour)
+	c.Assert(history.Nodes, check.HasLen, 1)
+	c.Assert(history.Nodes[0].Hostname, check.Equals, "node-1")
+	c.Assert(history.Nodes[0].Status, check.Equals, NodeHealthy)
+	c.Assert(int(history.Nodes[0].Percentage), check.Equals, 66)
+}
+
+func (s *HistorySuite) TestIgnoresUnobservedPeriods(c *check.C) {
+	now := s.clock.Now()
+	transitions := []storage.StatusTransition{
+		{Node: "192.168.1.1", Status: NodeDegraded, Time: now.Add(time.Hour)},
+	}
+	history := NewHistory(transitions, now, now.Add(2*time.Hour))
+	c.Assert(history.Nodes, check.HasLen, 1)
+	c.Assert(history.Nodes[0].Observed, check.Equals, time.Hour)
+	c.Assert(history.Nodes[0].Percentage, check.Equals, float64(0))
+
+	history = NewHistory(transitions, now, now.Add(30*time.Minute))
+	c.Assert(history.Nodes, check.HasLen, 0)
+}
+
+func (s *HistorySuite) TestCombinesProbes(c *check.C) {
+	status := pb.SystemStatus{
+		Status: pb.SystemStatus_Degraded,
+		Nodes: []*pb.NodeStatus{{
+			Status: pb.NodeStatus_Degraded,
+			MemberStatus: &pb.MemberStatus{Tags: map[string]string{
+				publicIPAddrTag: "192.168.1.1",
+			}},
+			Probes: []*pb.Probe{
+				{Checker: "etcd-healthz", Status: pb.Probe_Running},
+				{Checker: "etcd-healthz", Status: pb.Probe_Failed, Detail: "etcd is unhealthy"},
+				{Checker: "docker", Status: pb.Probe_Running},
+			},
+		}},
+	}
+	states := fromSystemStatusHealth(status, []storage.Server{
+		{AdvertiseIP: "192.168.1.1", Hostname: "node-1"},
+		{AdvertiseIP: "192.168.1.2", Hostname: "node-2"},
+	})
+	c.Assert(states, check.DeepEquals, []HealthState{
+		{Status: NodeDegraded},
+		{Node: "192.168.1.1", Hostname: "node-1", Status: NodeDegraded,
+			Detail: "etcd is unhealthy ()"},
+		{Node: "192.168.1.1", Hostname: "node-1", Probe: "docker", Status: ProbeHealthy},
+		{Node: "192.168.1.1", Hostname: "node-1", Probe: "etcd-healthz", Status: ProbeFailed,
+			Detail: "etcd is unhealthy ()"},
+		{Node: "192.168.1.2", Hostname: "node-2", Status: NodeOffline},
+	})
+}
+
+func (s *HistorySuite) newSampler(c *check.C) *Sampler {
+	sampler, err := NewSampler(SamplerConfig{
+		Backend:     s.backend,
+		ClusterName: "example.com",
+		Clock:       s.clock,
+	})
+	c.Assert(err, check.IsNil)
+	return sampler
+}
@@ -19,6 +19,7 @@ package keyval
 import (
 	"encoding/base64"
 	"encoding/json"
+	"io"
 	"time"
 
 	log "github.com/sirupsen/logrus"
@@ -26,6 +27,13 @@ import (
 	"github.com/jonboulle/clockwork"
 )
 
+// Snapshotter is implemented by backends that can write
+// a consistent snapshot of their data while in use
+type Snapshotter interface {
+	// Snapshot writes a consistent snapshot of the backend data to w
+	Snapshot(w io.Writer) error
+}
+
 // backend implements storage interface, it also acts as a codec
 type backend struct {
 	clockwork.Clock
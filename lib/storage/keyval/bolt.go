@@ -18,6 +18,7 @@ package keyval
 
 import (
 	"bytes"
+	"io"
 	"os"
 	"path/filepath"
 	"sort"
@@ -246,15 +247,27 @@ func getBucket(tx *bolt.Tx, buckets []string) (*bolt.Bucket, error) {
 	return bkt, nil
 }
 
+// update executes fn in a read-write transaction and records its latency
+func (b *blt) update(fn func(*bolt.Tx) error) error {
+	defer observeLatency(backendBolt, "update", time.Now())
+	return b.db.Update(fn)
+}
+
+// view executes fn in a read-only transaction and records its latency
+func (b *blt) view(fn func(*bolt.Tx) error) error {
+	defer observeLatency(backendBolt, "view", time.Now())
+	return b.db.View(fn)
+}
+
 func (b *blt) createDir(key key, ttl time.Duration) error {
-	return b.db.Update(func(tx *bolt.Tx) error {
+	return b.update(func(tx *bolt.Tx) error {
 		_, err := createBucket(tx, key)
 		return trace.Wrap(boltErr(err))
 	})
 }
 
 func (b *blt) upsertDir(key key, ttl time.Duration) error {
-	return b.db.Update(func(tx *bolt.Tx) error {
+	return b.update(func(tx *bolt.Tx) error {
 		_, err := upsertBucket(tx, key)
 		return trace.Wrap(boltErr(err))
 	})
@@ -262,7 +275,7 @@ func (b *blt) upsertDir(key key, ttl time.Duration) error {
 
 func (b *blt) createValBytes(k key, data []byte, ttl time.Duration) error {
 	buckets, key := b.split(k)
-	return b.db.Update(func(tx *bolt.Tx) error {
+	return b.update(func(tx *bolt.Tx) error {
 		bkt, err := upsertBucket(tx, buckets)
 		if err != nil {
 			return trace.Wrap(err)
@@ -281,7 +294,7 @@ func (b *blt) createVal(k key, val interface{}, ttl time.Duration) error {
 		return trace.Wrap(err)
 	}
 	buckets, key := b.split(k)
-	return b.db.Update(func(tx *bolt.Tx) error {
+	return b.update(func(tx *bolt.Tx) error {
 		bkt, err := upsertBucket(tx, buckets)
 		if err != nil {
 			return trace.Wrap(err)
@@ -296,7 +309,7 @@ func (b *blt) createVal(k key, val interface{}, ttl time.Duration) error {
 
 func (b *blt) upsertValBytes(k key, encoded []byte, ttl time.Duration) error {
 	buckets, key := b.split(k)
-	return b.db.Update(func(tx *bolt.Tx) error {
+	return b.update(func(tx *bolt.Tx) error {
 		bkt, err := upsertBucket(tx, buckets)
 		if err != nil {
 			return trace.Wrap(err)
@@ -311,7 +324,7 @@ func (b *blt) upsertVal(k key, val interface{}, ttl time.Duration) error {
 		return trace.Wrap(err)
 	}
 	buckets, key := b.split(k)
-	return b.db.Update(func(tx *bolt.Tx) error {
+	return b.update(func(tx *bolt.Tx) error {
 		bkt, err := upsertBucket(tx, buckets)
 		if err != nil {
 			return trace.Wrap(err)
@@ -322,7 +335,7 @@ func (b *blt) upsertVal(k key, val interface{}, ttl time.Duration) error {
 
 func (b *blt) updateValBytes(k key, data []byte, ttl time.Duration) error {
 	buckets, key := b.split(k)
-	return b.db.Update(func(tx *bolt.Tx) error {
+	return b.update(func(tx *bolt.Tx) error {
 		bkt, err := upsertBucket(tx, buckets)
 		if err != nil {
 			return trace.Wrap(err)
@@ -341,7 +354,7 @@ func (b *blt) updateVal(k key, val interface{}, ttl time.Duration) error {
 		return trace.Wrap(err)
 	}
 	buckets, key := b.split(k)
-	return b.db.Update(func(tx *bolt.Tx) error {
+	return b.update(func(tx *bolt.Tx) error {
 		bkt, err := upsertBucket(tx, buckets)
 		if err != nil {
 			return trace.Wrap(err)
@@ -387,7 +400,7 @@ func (b *blt) compareAndSwap(k key, val interface{}, prevVal interface{}, outVal
 
 func (b *blt) compareAndSwapBytes(k key, val, prevVal []byte, outVal *[]byte, ttl time.Duration) error {
 	buckets, key := b.split(k)
-	return b.db.Update(func(tx *bolt.Tx) error {
+	return b.update(func(tx *bolt.Tx) error {
 		bkt, err := upsertBucket(tx, buckets)
 		if err != nil {
 			return trace.Wrap(err)
@@ -419,7 +432,7 @@ func (b *blt) compareAndSwapBytes(k key, val, prevVal []byte, outVal *[]byte, tt
 func (b *blt) getValBytes(k key) ([]byte, error) {
 	buckets, key := b.split(k)
 	var out []byte
-	err := b.db.View(func(tx *bolt.Tx) error {
+	err := b.view(func(tx *bolt.Tx) error {
 		bkt, err := getBucket(tx, buckets)
 		if err != nil {
 			return trace.Wrap(err)
@@ -444,7 +457,7 @@ func (b *blt) getValBytes(k key) ([]byte, error) {
 
 func (b *blt) getVal(k key, outVal interface{}) error {
 	buckets, key := b.split(k)
-	return b.db.View(func(tx *bolt.Tx) error {
+	return b.view(func(tx *bolt.Tx) error {
 		bkt, err := getBucket(tx, buckets)
 		if err != nil {
 			return trace.Wrap(err)
@@ -463,7 +476,7 @@ func (b *blt) getVal(k key, outVal interface{}) error {
 
 func (b *blt) compareAndDelete(k key, prevVal interface{}) error {
 	buckets, key := b.split(k)
-	return b.db.Update(func(tx *bolt.Tx) error {
+	return b.update(func(tx *bolt.Tx) error {
 		bkt, err := getBucket(tx, buckets)
 		if err != nil {
 			return trace.Wrap(err)
@@ -486,7 +499,7 @@ func (b *blt) compareAndDelete(k key, prevVal interface{}) error {
 
 func (b *blt) deleteKey(k key) error {
 	buckets, key := b.split(k)
-	return b.db.Update(func(tx *bolt.Tx) error {
+	return b.update(func(tx *bolt.Tx) error {
 		bkt, err := getBucket(tx, buckets)
 		if err != nil {
 			return trace.Wrap(err)
@@ -500,7 +513,7 @@ func (b *blt) deleteKey(k key) error {
 
 func (b *blt) deleteDir(k key) error {
 	buckets, key := b.split(k)
-	return b.db.Update(func(tx *bolt.Tx) error {
+	return b.update(func(tx *bolt.Tx) error {
 		bkt, err := getBucket(tx, buckets)
 		if err != nil {
 			return trace.Wrap(err)
@@ -538,7 +551,7 @@ func (b *blt) releaseLock(key key) error {
 func (b *blt) getKeys(key key) ([]string, error) {
 	out := []string{}
 	buckets := key
-	err := b.db.View(func(tx *bolt.Tx) error {
+	err := b.view(func(tx *bolt.Tx) error {
 		bkt, err := getBucket(tx, buckets)
 		if err != nil {
 			if trace.IsNotFound(err) {
@@ -559,6 +572,13 @@ func (b *blt) getKeys(key key) ([]string, error) {
 	return out, nil
 }
 
+func (b *blt) snapshot(w io.Writer) error {
+	return boltErr(b.db.View(func(tx *bolt.Tx) error {
+		_, err := tx.WriteTo(w)
+		return trace.ConvertSystemError(err)
+	}))
+}
+
 // Close closes the backend resources
 func (b *blt) Close() error {
 	b.Lock()
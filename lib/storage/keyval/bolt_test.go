@@ -149,6 +149,10 @@ func (s *BSuite) TestOperationsCRUD(c *C) {
 	s.suite.OperationsCRUD(c)
 }
 
+func (s *BSuite) TestOperationPlanChangelogCRUD(c *C) {
+	s.suite.OperationPlanChangelogCRUD(c)
+}
+
 func (s *BSuite) TestCreatesApplication(c *C) {
 	s.suite.CreatesApplication(c)
 }
@@ -240,3 +244,15 @@ func (s *BSuite) TestClusterLogin(c *C) {
 func (s *BSuite) TestIndexFile(c *C) {
 	s.suite.IndexFile(c)
 }
+
+func (s *BSuite) TestRepositoryIndexFile(c *C) {
+	s.suite.RepositoryIndexFile(c)
+}
+
+func (s *BSuite) TestChartProvenance(c *C) {
+	s.suite.ChartProvenance(c)
+}
+
+func (s *BSuite) TestStatusHistory(c *C) {
+	s.suite.StatusHistory(c)
+}
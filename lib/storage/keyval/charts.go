@@ -17,31 +17,47 @@ limitations under the License.
 package keyval
 
 import (
-	"k8s.io/helm/pkg/repo"
+	"github.com/gravitational/gravity/lib/defaults"
 
 	"github.com/gravitational/trace"
+	"k8s.io/helm/pkg/repo"
 )
 
 // GetIndexFile returns the chart repository index file.
 func (b *backend) GetIndexFile() (*repo.IndexFile, error) {
+	return b.GetRepositoryIndexFile(defaults.SystemAccountOrg)
+}
+
+// CompareAndSwapIndexFile updates the chart repository index file.
+func (b *backend) CompareAndSwapIndexFile(new, existing *repo.IndexFile) (err error) {
+	return b.CompareAndSwapRepositoryIndexFile(defaults.SystemAccountOrg, new, existing)
+}
+
+// UpsertIndexFile creates or replaces chart repository index file.
+func (b *backend) UpsertIndexFile(indexFile repo.IndexFile) error {
+	return b.UpsertRepositoryIndexFile(defaults.SystemAccountOrg, indexFile)
+}
+
+// GetRepositoryIndexFile returns the index file of the specified chart repository.
+func (b *backend) GetRepositoryIndexFile(repository string) (*repo.IndexFile, error) {
 	var indexFile repo.IndexFile
-	err := b.getVal(b.key(chartsP, indexP), &indexFile)
+	err := b.getVal(b.indexFileKey(repository), &indexFile)
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
 	return &indexFile, nil
 }
 
-// CompareAndSwapIndexFile updates the chart repository index file.
-func (b *backend) CompareAndSwapIndexFile(new, existing *repo.IndexFile) (err error) {
+// CompareAndSwapRepositoryIndexFile updates the index file of the specified chart repository.
+func (b *backend) CompareAndSwapRepositoryIndexFile(repository string, new, existing *repo.IndexFile) (err error) {
 	var out repo.IndexFile
 	if existing == nil {
-		err = b.compareAndSwap(b.key(chartsP, indexP), new, nil, &out, 0)
+		err = b.compareAndSwap(b.indexFileKey(repository), new, nil, &out, 0)
 		if err != nil && trace.IsAlreadyExists(err) {
 			return trace.CompareFailed("index file is already initialized")
 		}
 	} else {
-		err = b.compareAndSwap(b.key(chartsP, indexP), new, existing, &out, 0)
+		err = b.compareAndSwap(b.indexFileKey(repository), new, existing, &out, 0)
 	}
 	if err != nil {
 		return trace.Wrap(err)
@@ -49,11 +65,54 @@ func (b *backend) CompareAndSwapIndexFile(new, existing *repo.IndexFile) (err er
 	return nil
 }
 
-// UpsertIndexFile creates or replaces chart repository index file.
-func (b *backend) UpsertIndexFile(indexFile repo.IndexFile) error {
-	err := b.upsertVal(b.key(chartsP, indexP), indexFile, 0)
+// UpsertRepositoryIndexFile creates or replaces the index file of the specified chart repository.
+func (b *backend) UpsertRepositoryIndexFile(repository string, indexFile repo.IndexFile) error {
+	err := b.upsertVal(b.indexFileKey(repository), indexFile, 0)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return nil
+}
+
+// GetChartProvenance returns the provenance file of the specified chart.
+func (b *backend) GetChartProvenance(repository, name, version string) ([]byte, error) {
+	var data []byte
+	err := b.getVal(b.key(chartsP, repositoriesP, repository, provenanceP, name, version), &data)
+	if err != nil {
+		if trace.IsNotFound(err) {
+			return nil, trace.NotFound("provenance of chart %v:%v not found", name, version)
+		}
+		return nil, trace.Wrap(err)
+	}
+	return data, nil
+}
+
+// UpsertChartProvenance creates or replaces the provenance file of the specified chart.
+func (b *backend) UpsertChartProvenance(repository, name, version string, data []byte) error {
+	err := b.upsertVal(b.key(chartsP, repositoriesP, repository, provenanceP, name, version), data, 0)
 	if err != nil {
 		return trace.Wrap(err)
 	}
 	return nil
 }
+
+// DeleteChartProvenance deletes the provenance file of the specified chart.
+func (b *backend) DeleteChartProvenance(repository, name, version string) error {
+	err := b.deleteKey(b.key(chartsP, repositoriesP, repository, provenanceP, name, version))
+	if err != nil {
+		if trace.IsNotFound(err) {
+			return trace.NotFound("provenance of chart %v:%v not found", name, version)
+		}
+		return trace.Wrap(err)
+	}
+	return nil
+}
+
+// indexFileKey returns the key of the index file of the specified chart repository.
+// The index of the default repository is kept under the original key
+func (b *backend) indexFileKey(repository string) key {
+	if repository == defaults.SystemAccountOrg {
+		return b.key(chartsP, indexP)
+	}
+	return b.key(chartsP, repositoriesP, repository, indexP)
+}
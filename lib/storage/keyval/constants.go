@@ -76,7 +76,9 @@ const (
 	systemP                     = "system"
 	dnsP                        = "dns"
 	chartsP                     = "charts"
+	statusHistoryP              = "statushistory"
 	indexP                      = "index"
+	provenanceP                 = "provenance"
 
 	// AllCollectionIDs identifies a collection without a specification (an ID)
 	AllCollectionIDs = "__all__"
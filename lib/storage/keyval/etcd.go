@@ -486,7 +486,7 @@ func suffix(key string) string {
 
 // Get retrieves a set of Nodes from etcd
 func (r retryApi) Get(ctx context.Context, key string, opts *client.GetOptions) (*client.Response, error) {
-	resp, err := r.retry(ctx, func() (*client.Response, error) {
+	resp, err := r.retry(ctx, "get", func() (*client.Response, error) {
 		return r.api.Get(ctx, key, opts)
 	})
 	if err != nil {
@@ -499,7 +499,7 @@ func (r retryApi) Get(ctx context.Context, key string, opts *client.GetOptions)
 // may define a set of conditions in the SetOptions. If SetOptions.Dir=true
 // then value is ignored.
 func (r retryApi) Set(ctx context.Context, key, value string, opts *client.SetOptions) (*client.Response, error) {
-	resp, err := r.retry(ctx, func() (*client.Response, error) {
+	resp, err := r.retry(ctx, "set", func() (*client.Response, error) {
 		return r.api.Set(ctx, key, value, opts)
 	})
 	if err != nil {
@@ -512,7 +512,7 @@ func (r retryApi) Set(ctx context.Context, key, value string, opts *client.SetOp
 // all of its children as well. The caller may define a set of required
 // conditions in an DeleteOptions object.
 func (r retryApi) Delete(ctx context.Context, key string, opts *client.DeleteOptions) (*client.Response, error) {
-	resp, err := r.retry(ctx, func() (*client.Response, error) {
+	resp, err := r.retry(ctx, "delete", func() (*client.Response, error) {
 		return r.api.Delete(ctx, key, opts)
 	})
 	if err != nil {
@@ -523,7 +523,7 @@ func (r retryApi) Delete(ctx context.Context, key string, opts *client.DeleteOpt
 
 // Create is an alias for Set w/ PrevExist=false
 func (r retryApi) Create(ctx context.Context, key, value string) (*client.Response, error) {
-	resp, err := r.retry(ctx, func() (*client.Response, error) {
+	resp, err := r.retry(ctx, "create", func() (*client.Response, error) {
 		return r.api.Create(ctx, key, value)
 	})
 	if err != nil {
@@ -534,7 +534,7 @@ func (r retryApi) Create(ctx context.Context, key, value string) (*client.Respon
 
 // CreateInOrder is used to atomically create in-order keys within the given directory.
 func (r retryApi) CreateInOrder(ctx context.Context, dir, value string, opts *client.CreateInOrderOptions) (*client.Response, error) {
-	resp, err := r.retry(ctx, func() (*client.Response, error) {
+	resp, err := r.retry(ctx, "create_in_order", func() (*client.Response, error) {
 		return r.api.CreateInOrder(ctx, dir, value, opts)
 	})
 	if err != nil {
@@ -545,7 +545,7 @@ func (r retryApi) CreateInOrder(ctx context.Context, dir, value string, opts *cl
 
 // Update is an alias for Set w/ PrevExist=true
 func (r retryApi) Update(ctx context.Context, key, value string) (*client.Response, error) {
-	resp, err := r.retry(ctx, func() (*client.Response, error) {
+	resp, err := r.retry(ctx, "update", func() (*client.Response, error) {
 		return r.api.Update(ctx, key, value)
 	})
 	if err != nil {
@@ -562,7 +562,10 @@ func (r retryApi) Watcher(key string, opts *client.WatcherOptions) client.Watche
 	return r.api.Watcher(key, opts)
 }
 
-func (r retryApi) retry(ctx context.Context, fn apiCall) (resp *client.Response, err error) {
+// retry executes fn retrying on transient errors and records the latency
+// of the operation specified with op
+func (r retryApi) retry(ctx context.Context, op string, fn apiCall) (resp *client.Response, err error) {
+	defer observeLatency(backendEtcd, op, time.Now())
 	interval := backoff.NewExponentialBackOff()
 	interval.MaxElapsedTime = defaults.RetrySmallerMaxInterval
 	if r.interval != 0 {
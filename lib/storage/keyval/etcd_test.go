@@ -173,6 +173,10 @@ func (s *ESuite) TestOperationsCRUD(c *C) {
 	s.suite.OperationsCRUD(c)
 }
 
+func (s *ESuite) TestOperationPlanChangelogCRUD(c *C) {
+	s.suite.OperationPlanChangelogCRUD(c)
+}
+
 func (s *ESuite) TestCreatesApplication(c *C) {
 	s.suite.CreatesApplication(c)
 }
@@ -264,3 +268,15 @@ func (s *ESuite) TestClusterLogin(c *C) {
 func (s *ESuite) TestIndexFile(c *C) {
 	s.suite.IndexFile(c)
 }
+
+func (s *ESuite) TestRepositoryIndexFile(c *C) {
+	s.suite.RepositoryIndexFile(c)
+}
+
+func (s *ESuite) TestChartProvenance(c *C) {
+	s.suite.ChartProvenance(c)
+}
+
+func (s *ESuite) TestStatusHistory(c *C) {
+	s.suite.StatusHistory(c)
+}
@@ -19,6 +19,8 @@ package keyval
 import (
 	"io"
 	"time"
+
+	"github.com/gravitational/trace"
 )
 
 type kvengine interface {
@@ -47,6 +49,70 @@ type kvengine interface {
 	getKeys(key key) ([]string, error)
 }
 
+// valueLister is implemented by engines that can retrieve values
+// of multiple keys with a single query
+type valueLister interface {
+	// getVals returns the values of all keys below prefix matching the pattern.
+	// anyKey in the pattern matches any key
+	getVals(prefix key, pattern ...string) ([][]byte, error)
+}
+
+// snapshotter is implemented by engines that can write
+// a consistent snapshot of their data
+type snapshotter interface {
+	// snapshot writes the snapshot of the database to w
+	snapshot(w io.Writer) error
+}
+
+// Snapshot writes a consistent snapshot of the backend data to w.
+// The snapshot is in the native format of the underlying database
+// and can be used in place of the database file.
+// Returns trace.NotImplemented if the backend does not support snapshots
+func (b *backend) Snapshot(w io.Writer) error {
+	engine, ok := b.kvengine.(snapshotter)
+	if !ok {
+		return trace.NotImplemented("backend does not support snapshots")
+	}
+	return trace.Wrap(engine.snapshot(w))
+}
+
+// getVals returns the values of all keys below prefix matching the pattern.
+// anyKey in the pattern matches any key.
+// Keys that do not have a value are skipped
+func (b *backend) getVals(prefix key, pattern ...string) ([][]byte, error) {
+	if lister, ok := b.kvengine.(valueLister); ok {
+		vals, err := lister.getVals(prefix, pattern...)
+		return vals, trace.Wrap(err)
+	}
+	if len(pattern) == 0 {
+		val, err := b.getValBytes(prefix)
+		if err != nil {
+			if trace.IsNotFound(err) || trace.IsBadParameter(err) {
+				return nil, nil
+			}
+			return nil, trace.Wrap(err)
+		}
+		return [][]byte{val}, nil
+	}
+	names := []string{pattern[0]}
+	if pattern[0] == anyKey {
+		var err error
+		names, err = b.getKeys(prefix)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+	}
+	var out [][]byte
+	for _, name := range names {
+		vals, err := b.getVals(append(append(key{}, prefix...), name), pattern[1:]...)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		out = append(out, vals...)
+	}
+	return out, nil
+}
+
 type key []string
 
 func (k key) split() ([]string, string) {
@@ -55,3 +121,8 @@ func (k key) split() ([]string, string) {
 	}
 	return k[:len(k)-1], k[len(k)-1]
 }
+
+const (
+	// anyKey matches any key in value listing patterns
+	anyKey = "*"
+)
@@ -0,0 +1,53 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package keyval
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var backendLatencies = prometheus.NewHistogramVec(
+	prometheus.HistogramOpts{
+		Name: "gravity_backend_request_seconds",
+		Help: "Latency of requests to the cluster state backend",
+		// lowest bucket start of upper bound 0.0001 sec (100 us) with factor 2
+		// highest bucket start of 0.0001 sec * 2^17 == 13.1072 sec
+		Buckets: prometheus.ExponentialBuckets(0.0001, 2, 18),
+	},
+	[]string{"backend", "operation"},
+)
+
+func init() {
+	prometheus.MustRegister(backendLatencies)
+}
+
+// observeLatency records the time elapsed since start as the latency
+// of the specified backend operation
+func observeLatency(backend, operation string, start time.Time) {
+	backendLatencies.WithLabelValues(backend, operation).Observe(time.Since(start).Seconds())
+}
+
+const (
+	// backendBolt names the BoltDB backend in metrics
+	backendBolt = "bolt"
+	// backendEtcd names the etcd backend in metrics
+	backendEtcd = "etcd"
+	// backendSQLite names the SQLite backend in metrics
+	backendSQLite = "sqlite"
+)
@@ -17,6 +17,7 @@ limitations under the License.
 package keyval
 
 import (
+	"io"
 	"time"
 
 	"github.com/gravitational/trace"
@@ -161,6 +162,12 @@ func (b *multiBolt) getKeys(key key) (keys []string, err error) {
 	return keys, trace.Wrap(err)
 }
 
+func (b *multiBolt) snapshot(w io.Writer) error {
+	return trace.Wrap(b.withBolt(func(b *blt) error {
+		return trace.Wrap(b.snapshot(w))
+	}))
+}
+
 func (b *multiBolt) key(prefix string, keys ...string) key {
 	return append([]string{"root", prefix}, keys...)
 }
@@ -17,6 +17,7 @@ limitations under the License.
 package keyval
 
 import (
+	"encoding/json"
 	"sort"
 
 	"github.com/gravitational/gravity/lib/storage"
@@ -89,7 +90,7 @@ func (b *backend) GetSiteOperations(siteDomain string) ([]storage.SiteOperation,
 	if siteDomain == "" {
 		return nil, trace.BadParameter("missing parameter SiteDomain")
 	}
-	ids, err := b.getKeys(b.key(sitesP, siteDomain, operationsP))
+	vals, err := b.getVals(b.key(sitesP, siteDomain, operationsP), anyKey, valP)
 	if err != nil {
 		if trace.IsNotFound(err) {
 			return nil, nil
@@ -97,14 +98,10 @@ func (b *backend) GetSiteOperations(siteDomain string) ([]storage.SiteOperation,
 		return nil, trace.Wrap(err)
 	}
 	var out []storage.SiteOperation
-	for _, id := range ids {
+	for _, val := range vals {
 		var op storage.SiteOperation
-		err = b.getVal(b.key(sitesP, siteDomain, operationsP, id, valP), &op)
-		if err != nil {
-			if !trace.IsNotFound(err) {
-				return nil, trace.Wrap(err)
-			}
-			continue
+		if err := json.Unmarshal(val, &op); err != nil {
+			return nil, trace.Wrap(err)
 		}
 		utils.UTC(&op.Created)
 		utils.UTC(&op.Updated)
@@ -186,16 +183,14 @@ func (b *backend) CreateOperationPlanChange(ch storage.PlanChange) (*storage.Pla
 
 // GetOperationPlanChangelog returns all state transition entries for a plan
 func (b *backend) GetOperationPlanChangelog(clusterName, operationID string) (storage.PlanChangelog, error) {
-	ids, err := b.getKeys(b.key(sitesP, clusterName, operationsP, operationID, changelogP))
+	vals, err := b.getVals(b.key(sitesP, clusterName, operationsP, operationID, changelogP), anyKey, valP)
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
 	var out []storage.PlanChange
-	for _, id := range ids {
+	for _, val := range vals {
 		var ch storage.PlanChange
-		err = b.getVal(b.key(
-			sitesP, clusterName, operationsP, operationID, changelogP, id, valP), &ch)
-		if err != nil {
+		if err := json.Unmarshal(val, &ch); err != nil {
 			return nil, trace.Wrap(err)
 		}
 		utils.UTC(&ch.Created)
@@ -204,6 +199,21 @@ func (b *backend) GetOperationPlanChangelog(clusterName, operationID string) (st
 	return storage.PlanChangelog(out), nil
 }
 
+// DeleteOperationPlanChange deletes the specified state transition entry of a plan
+func (b *backend) DeleteOperationPlanChange(clusterName, operationID, changeID string) error {
+	if changeID == "" {
+		return trace.BadParameter("missing change id")
+	}
+	err := b.deleteDir(b.key(sitesP, clusterName, operationsP, operationID, changelogP, changeID))
+	if err != nil {
+		if trace.IsNotFound(err) {
+			return trace.NotFound("plan change(%v) for operation %v not found", changeID, operationID)
+		}
+		return trace.Wrap(err)
+	}
+	return nil
+}
+
 // CreateAppOperation creates a new application operation
 func (b *backend) CreateAppOperation(op storage.AppOperation) (*storage.AppOperation, error) {
 	err := op.Check()
@@ -17,6 +17,7 @@ limitations under the License.
 package keyval
 
 import (
+	"encoding/json"
 	"sort"
 
 	"github.com/gravitational/gravity/lib/storage"
@@ -135,26 +136,24 @@ func (b *backend) GetPackage(repository string, packageName, packageVersion stri
 }
 
 func (b *backend) GetPackages(repository string) ([]storage.Package, error) {
-	packageNames, err := b.getKeys(b.key(repositoriesP, repository, packagesP))
+	vals, err := b.getVals(b.key(repositoriesP, repository, packagesP), anyKey, versionsP, anyKey)
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
-	sort.Sort(sort.StringSlice(packageNames))
-	out := make([]storage.Package, 0)
-	for _, packageName := range packageNames {
-		packageVers, err := b.getKeys(b.key(repositoriesP, repository, packagesP, packageName, versionsP))
-		if err != nil {
+	out := make([]storage.Package, 0, len(vals))
+	for _, val := range vals {
+		var p storage.Package
+		if err := json.Unmarshal(val, &p); err != nil {
 			return nil, trace.Wrap(err)
 		}
-		sort.Sort(sort.StringSlice(packageVers))
-		for _, packageVer := range packageVers {
-			p, err := b.GetPackage(repository, packageName, packageVer)
-			if err != nil {
-				return nil, trace.Wrap(err)
-			}
-			out = append(out, *p)
-		}
+		out = append(out, p)
 	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Name != out[j].Name {
+			return out[i].Name < out[j].Name
+		}
+		return out[i].Version < out[j].Version
+	})
 	return out, nil
 }
 
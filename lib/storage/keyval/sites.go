@@ -17,6 +17,8 @@ limitations under the License.
 package keyval
 
 import (
+	"encoding/json"
+
 	"github.com/gravitational/gravity/lib/storage"
 
 	"github.com/gravitational/trace"
@@ -77,21 +79,20 @@ func (b *backend) DeleteSite(domain string) error {
 }
 
 func (b *backend) GetSites(accountID string) ([]storage.Site, error) {
-	domains, err := b.getKeys(b.key(sitesP))
+	vals, err := b.getVals(b.key(sitesP), anyKey, valP)
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
 	var out []storage.Site
-	for _, domain := range domains {
-		site, err := b.GetSite(domain)
-		if err != nil {
+	for _, val := range vals {
+		var site storage.Site
+		if err := json.Unmarshal(val, &site); err != nil {
 			return nil, trace.Wrap(err)
 		}
-
 		if site.AccountID != accountID {
 			continue
 		}
-		out = append(out, *site)
+		out = append(out, site)
 	}
 	return out, nil
 }
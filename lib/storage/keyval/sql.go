@@ -0,0 +1,763 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package keyval
+
+import (
+	"bytes"
+	"database/sql"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/storage"
+
+	"github.com/boltdb/bolt"
+	"github.com/gravitational/trace"
+	"github.com/jonboulle/clockwork"
+	// register sqlite3 database driver
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/sirupsen/logrus"
+)
+
+// NewSQL returns new SQLite-backed storage backend
+func NewSQL(cfg SQLConfig) (storage.Backend, error) {
+	engine, err := newSQL(cfg)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &backend{
+		Clock:    engine.clock,
+		kvengine: engine,
+	}, nil
+}
+
+// SQLConfig is a SQLite backend configuration
+type SQLConfig struct {
+	// Path is a path to the database file
+	Path string `json:"path"`
+	// Clock is a clock interface, used in tests
+	Clock clockwork.Clock `json:"-"`
+	// Readonly opens an existing database file without modifying it
+	Readonly bool `json:"readonly"`
+}
+
+// Check validates the configuration
+func (r *SQLConfig) Check() error {
+	if r.Path == "" {
+		return trace.BadParameter("missing Path parameter")
+	}
+	path, err := filepath.Abs(r.Path)
+	if err != nil {
+		return trace.Wrap(err, "expected a valid path")
+	}
+	dir := filepath.Dir(path)
+	s, err := os.Stat(dir)
+	if err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	if !s.IsDir() {
+		return trace.BadParameter("path '%v' should be a valid directory", dir)
+	}
+	return nil
+}
+
+// MigrateBoltToSQL copies the contents of the bolt database file boltPath
+// into the SQLite database specified with cfg.
+// The SQLite database must be empty
+func MigrateBoltToSQL(boltPath string, cfg SQLConfig) error {
+	boltDB, err := bolt.Open(boltPath, defaults.PrivateFileMask, &bolt.Options{
+		Timeout:  defaults.DBOpenTimeout,
+		ReadOnly: true,
+	})
+	if err != nil {
+		if err == bolt.ErrTimeout {
+			return trace.ConnectionProblem(err,
+				"database %v is locked, is another instance running?", boltPath)
+		}
+		return trace.Wrap(err)
+	}
+	defer boltDB.Close()
+	e, err := newSQL(cfg)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	defer e.Close()
+	var count int
+	if err := e.db.QueryRow(`SELECT COUNT(*) FROM kv`).Scan(&count); err != nil {
+		return trace.Wrap(sqlErr(err))
+	}
+	if count != 0 {
+		return trace.AlreadyExists("database %v is not empty", e.path)
+	}
+	var keys int
+	err = e.update(func(sqlTx *sql.Tx) error {
+		return boltDB.View(func(boltTx *bolt.Tx) error {
+			return boltTx.ForEach(func(name []byte, bucket *bolt.Bucket) error {
+				n, err := copyBucket(sqlTx, key{string(name)}, bucket)
+				keys += n
+				return trace.Wrap(err)
+			})
+		})
+	})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	e.WithField("from", boltPath).Infof("Migrated %v keys.", keys)
+	return nil
+}
+
+// copyBucket copies the bucket with the specified key and all its contents
+// and returns the number of copied keys
+func copyBucket(tx *sql.Tx, k key, bucket *bolt.Bucket) (count int, err error) {
+	if err := insertRow(tx, k, nil, true, 0); err != nil {
+		return 0, trace.Wrap(err)
+	}
+	err = bucket.ForEach(func(name, value []byte) error {
+		childKey := append(append(key{}, k...), string(name))
+		if value == nil {
+			n, err := copyBucket(tx, childKey, bucket.Bucket(name))
+			count += n
+			return trace.Wrap(err)
+		}
+		count++
+		return trace.Wrap(insertRow(tx, childKey, value, false, 0))
+	})
+	return count, trace.Wrap(err)
+}
+
+// sqlEngine is a SQLite-backed engine.
+//
+// Sites, operations, plan changes and packages are stored in dedicated tables
+// indexed by their natural keys (see sqlTables). All other keys are stored in
+// the kv table with one row per key or directory. Each kv row records the path
+// of its parent so listing a directory is an index lookup, and its depth so
+// values of all keys matching a pattern are retrieved with a single index
+// range scan.
+type sqlEngine struct {
+	logrus.FieldLogger
+
+	codec Codec
+	db    *sql.DB
+	clock clockwork.Clock
+	path  string
+	// readonly is whether the database has been opened read-only
+	readonly bool
+}
+
+func newSQL(cfg SQLConfig) (*sqlEngine, error) {
+	if err := cfg.Check(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	path, err := filepath.Abs(cfg.Path)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	e := &sqlEngine{
+		codec:    &v1codec{},
+		clock:    cfg.Clock,
+		path:     path,
+		readonly: cfg.Readonly,
+		FieldLogger: logrus.WithFields(logrus.Fields{
+			trace.Component: "sqlite",
+			"path":          path,
+		}),
+	}
+	if e.clock == nil {
+		e.clock = clockwork.NewRealClock()
+	}
+	if err := e.open(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return e, nil
+}
+
+func (e *sqlEngine) open() error {
+	if e.readonly {
+		return e.openReadonly()
+	}
+	if _, err := os.Stat(e.path); os.IsNotExist(err) {
+		// make sure the database file is only accessible to the owner
+		f, err := os.OpenFile(e.path, os.O_CREATE|os.O_WRONLY, defaults.PrivateFileMask)
+		if err != nil {
+			return trace.ConvertSystemError(err)
+		}
+		f.Close()
+	}
+	dsn := fmt.Sprintf("file:%v?_busy_timeout=%v&_journal_mode=WAL&_txlock=immediate",
+		e.path, int64(defaults.DBOpenTimeout/time.Millisecond))
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	// SQLite serializes writers, so a single connection avoids
+	// lock contention between connections of the same process
+	db.SetMaxOpenConns(1)
+	for _, stmt := range sqlSchema {
+		if _, err := db.Exec(stmt); err != nil {
+			db.Close()
+			return trace.Wrap(sqlErr(err), "failed to initialize database %v", e.path)
+		}
+	}
+	e.db = db
+	return nil
+}
+
+// openReadonly opens the existing database file without changing it:
+// neither the journal mode nor the schema are updated
+func (e *sqlEngine) openReadonly() error {
+	if _, err := os.Stat(e.path); err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	dsn := fmt.Sprintf("file:%v?mode=ro&immutable=1", e.path)
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	db.SetMaxOpenConns(1)
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return trace.Wrap(sqlErr(err), "failed to open database %v", e.path)
+	}
+	e.db = db
+	return nil
+}
+
+func (e *sqlEngine) key(prefix string, keys ...string) key {
+	return append([]string{"root", prefix}, keys...)
+}
+
+func (e *sqlEngine) createDir(k key, ttl time.Duration) error {
+	return e.update(func(tx *sql.Tx) error {
+		existing, err := e.lookup(tx, k)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		if existing != nil {
+			return trace.AlreadyExists("%v already exists", k)
+		}
+		return trace.Wrap(e.put(tx, k, nil, true, ttl))
+	})
+}
+
+func (e *sqlEngine) upsertDir(k key, ttl time.Duration) error {
+	return e.update(func(tx *sql.Tx) error {
+		existing, err := e.lookup(tx, k)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		if existing != nil && !existing.dir {
+			return trace.BadParameter("%v is not a directory", k)
+		}
+		return trace.Wrap(e.put(tx, k, nil, true, ttl))
+	})
+}
+
+func (e *sqlEngine) deleteDir(k key) error {
+	return e.update(func(tx *sql.Tx) error {
+		existing, err := e.lookup(tx, k)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		if existing == nil || !existing.dir {
+			return trace.NotFound("%v is not found", k)
+		}
+		path := sqlPath(k)
+		_, err = tx.Exec(`DELETE FROM kv WHERE path = ? OR (path > ? AND path < ?)`,
+			path, path+"/", path+"0")
+		if err != nil {
+			return trace.Wrap(sqlErr(err))
+		}
+		return trace.Wrap(deleteTableRows(tx, k))
+	})
+}
+
+func (e *sqlEngine) createVal(k key, val interface{}, ttl time.Duration) error {
+	encoded, err := e.codec.EncodeToBytes(val)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return trace.Wrap(e.createValBytes(k, encoded, ttl))
+}
+
+func (e *sqlEngine) createValBytes(k key, data []byte, ttl time.Duration) error {
+	return e.update(func(tx *sql.Tx) error {
+		existing, err := e.lookup(tx, k)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		if existing != nil {
+			return trace.AlreadyExists("'%v' already exists", k)
+		}
+		return trace.Wrap(e.put(tx, k, data, false, ttl))
+	})
+}
+
+func (e *sqlEngine) upsertVal(k key, val interface{}, ttl time.Duration) error {
+	encoded, err := e.codec.EncodeToBytes(val)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return trace.Wrap(e.upsertValBytes(k, encoded, ttl))
+}
+
+func (e *sqlEngine) upsertValBytes(k key, data []byte, ttl time.Duration) error {
+	return e.update(func(tx *sql.Tx) error {
+		existing, err := e.lookup(tx, k)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		if existing != nil && existing.dir {
+			return trace.BadParameter("key %q is a directory", k)
+		}
+		return trace.Wrap(e.put(tx, k, data, false, ttl))
+	})
+}
+
+func (e *sqlEngine) updateVal(k key, val interface{}, ttl time.Duration) error {
+	encoded, err := e.codec.EncodeToBytes(val)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return trace.Wrap(e.updateValBytes(k, encoded, ttl))
+}
+
+func (e *sqlEngine) updateValBytes(k key, data []byte, ttl time.Duration) error {
+	return e.update(func(tx *sql.Tx) error {
+		existing, err := e.lookup(tx, k)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		if existing == nil || existing.dir {
+			return trace.NotFound("%q not found", k)
+		}
+		return trace.Wrap(e.put(tx, k, data, false, ttl))
+	})
+}
+
+func (e *sqlEngine) updateTTL(k key, ttl time.Duration) error {
+	return e.update(func(tx *sql.Tx) error {
+		existing, err := e.lookup(tx, k)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		if existing == nil {
+			return trace.NotFound("%q not found", k)
+		}
+		if table, where, args := findTable(k); table != nil {
+			_, err = tx.Exec(`UPDATE `+table.name+` SET expires = ? WHERE `+where,
+				append([]interface{}{e.expires(ttl)}, args...)...)
+			return trace.Wrap(sqlErr(err))
+		}
+		_, err = tx.Exec(`UPDATE kv SET expires = ? WHERE path = ?`, e.expires(ttl), sqlPath(k))
+		return trace.Wrap(sqlErr(err))
+	})
+}
+
+func (e *sqlEngine) compareAndSwap(k key, val interface{}, prevVal interface{}, outVal interface{}, ttl time.Duration) error {
+	encoded, err := e.codec.EncodeToBytes(val)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	var prevEncoded []byte
+	if prevVal != nil {
+		prevEncoded, err = e.codec.EncodeToBytes(prevVal)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+	}
+	var outEncoded []byte
+	err = e.compareAndSwapBytes(k, encoded, prevEncoded, &outEncoded, ttl)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if prevVal != nil {
+		err = e.codec.DecodeFromBytes(outEncoded, outVal)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+	}
+	return nil
+}
+
+func (e *sqlEngine) compareAndSwapBytes(k key, val, prevVal []byte, outVal *[]byte, ttl time.Duration) error {
+	return e.update(func(tx *sql.Tx) error {
+		existing, err := e.lookup(tx, k)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		if prevVal == nil { // we don't expect the value to exist
+			if existing != nil {
+				return trace.AlreadyExists("key %q already exists", k)
+			}
+			return trace.Wrap(e.put(tx, k, val, false, ttl))
+		}
+		// we expect the previous value to exist
+		if existing == nil || existing.dir {
+			return trace.NotFound("key %q not found", k)
+		}
+		if !bytes.Equal(existing.value, prevVal) {
+			return trace.CompareFailed("expected %q got %q",
+				string(prevVal), string(existing.value))
+		}
+		if err := e.put(tx, k, val, false, ttl); err != nil {
+			return trace.Wrap(err)
+		}
+		*outVal = existing.value
+		return nil
+	})
+}
+
+func (e *sqlEngine) getValBytes(k key) ([]byte, error) {
+	existing, err := e.get(k)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return existing.value, nil
+}
+
+func (e *sqlEngine) getVal(k key, val interface{}) error {
+	existing, err := e.get(k)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return e.codec.DecodeFromBytes(existing.value, val)
+}
+
+func (e *sqlEngine) get(k key) (*sqlRow, error) {
+	defer observeLatency(backendSQLite, "view", time.Now())
+	existing, err := e.lookup(e.db, k)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if existing == nil {
+		return nil, trace.NotFound("%q not found", k)
+	}
+	if existing.dir {
+		return nil, trace.BadParameter("key %q is a bucket", k)
+	}
+	return existing, nil
+}
+
+func (e *sqlEngine) compareAndDelete(k key, prevVal interface{}) error {
+	return e.update(func(tx *sql.Tx) error {
+		existing, err := e.lookup(tx, k)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		if existing == nil || existing.dir {
+			return trace.NotFound("%v is not found", k)
+		}
+		var outVal interface{}
+		err = e.codec.DecodeFromBytes(existing.value, &outVal)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		if outVal != prevVal {
+			return trace.BadParameter("%v: expected %v, but got %v", k, prevVal, outVal)
+		}
+		return trace.Wrap(deleteRow(tx, k))
+	})
+}
+
+func (e *sqlEngine) deleteKey(k key) error {
+	return e.update(func(tx *sql.Tx) error {
+		existing, err := e.lookup(tx, k)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		if existing == nil || existing.dir {
+			return trace.NotFound("%v is not found", k)
+		}
+		return trace.Wrap(deleteRow(tx, k))
+	})
+}
+
+func (e *sqlEngine) acquireLock(token key, ttl time.Duration) error {
+	for {
+		err := e.tryAcquireLock(token, ttl)
+		if err != nil {
+			if !trace.IsCompareFailed(err) && !trace.IsAlreadyExists(err) {
+				return trace.Wrap(err)
+			}
+			time.Sleep(delayBetweenLockAttempts)
+		} else {
+			return nil
+		}
+	}
+}
+
+func (e *sqlEngine) tryAcquireLock(token key, ttl time.Duration) error {
+	return e.createVal(token, "locked", ttl)
+}
+
+func (e *sqlEngine) releaseLock(token key) error {
+	return e.deleteKey(token)
+}
+
+func (e *sqlEngine) getKeys(k key) ([]string, error) {
+	defer observeLatency(backendSQLite, "view", time.Now())
+	names, err := e.queryStrings(`SELECT name FROM kv WHERE parent = ? AND (expires = 0 OR expires > ?)`,
+		sqlPath(k), e.now())
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	tableNames, err := e.getTableKeys(k)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return mergeNames(names, tableNames), nil
+}
+
+// getVals returns the values of all keys below prefix that match
+// the specified pattern with a single query
+func (e *sqlEngine) getVals(prefix key, pattern ...string) ([][]byte, error) {
+	defer observeLatency(backendSQLite, "view", time.Now())
+	vals, ok, err := e.getTableVals(append(append(key{}, prefix...), pattern...))
+	if ok {
+		return vals, trace.Wrap(err)
+	}
+	path := sqlPath(prefix)
+	rows, err := e.db.Query(`SELECT path, value FROM kv
+WHERE depth = ? AND path > ? AND path < ? AND dir = 0 AND (expires = 0 OR expires > ?)
+ORDER BY path`,
+		len(prefix)+len(pattern), path+"/", path+"0", e.now())
+	if err != nil {
+		return nil, trace.Wrap(sqlErr(err))
+	}
+	defer rows.Close()
+	var out [][]byte
+	for rows.Next() {
+		var keyPath string
+		var value []byte
+		if err := rows.Scan(&keyPath, &value); err != nil {
+			return nil, trace.Wrap(sqlErr(err))
+		}
+		if matchPattern(strings.Split(strings.TrimPrefix(keyPath, path+"/"), "/"), pattern) {
+			out = append(out, value)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, trace.Wrap(sqlErr(err))
+	}
+	return out, nil
+}
+
+func (e *sqlEngine) snapshot(w io.Writer) error {
+	dir, err := ioutil.TempDir(filepath.Dir(e.path), "snapshot")
+	if err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, filepath.Base(e.path))
+	// VACUUM INTO creates a transactionally consistent copy of the database
+	if _, err := e.db.Exec(`VACUUM INTO ?`, path); err != nil {
+		return trace.Wrap(sqlErr(err))
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	defer f.Close()
+	_, err = io.Copy(w, f)
+	return trace.ConvertSystemError(err)
+}
+
+// Close closes the backend resources.
+// Requests issued after the database has been closed fail
+func (e *sqlEngine) Close() error {
+	return trace.Wrap(e.db.Close())
+}
+
+// update executes fn in a write transaction and records its latency
+func (e *sqlEngine) update(fn func(tx *sql.Tx) error) error {
+	defer observeLatency(backendSQLite, "update", time.Now())
+	tx, err := e.db.Begin()
+	if err != nil {
+		return trace.Wrap(sqlErr(err))
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return trace.Wrap(err)
+	}
+	return trace.Wrap(sqlErr(tx.Commit()))
+}
+
+// lookup returns the row for the specified key or nil if the key
+// does not exist or has expired
+func (e *sqlEngine) lookup(q queryer, k key) (*sqlRow, error) {
+	if table, where, args := findTable(k); table != nil {
+		row, err := e.lookupTable(q, table, where, args)
+		return row, trace.Wrap(err)
+	}
+	var row sqlRow
+	err := q.QueryRow(`SELECT dir, value, expires FROM kv WHERE path = ?`, sqlPath(k)).
+		Scan(&row.dir, &row.value, &row.expires)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, trace.Wrap(sqlErr(err))
+	}
+	if row.expires != 0 && row.expires <= e.now() {
+		return nil, nil
+	}
+	return &row, nil
+}
+
+// put writes the row for the specified key creating all parent directories
+func (e *sqlEngine) put(tx *sql.Tx, k key, value []byte, dir bool, ttl time.Duration) error {
+	for i := 1; i < len(k); i++ {
+		parent, err := e.lookup(tx, k[:i])
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		if parent == nil {
+			if err := insertRow(tx, k[:i], nil, true, 0); err != nil {
+				return trace.Wrap(err)
+			}
+			continue
+		}
+		if !parent.dir {
+			return trace.BadParameter("%v is not a directory", k[:i])
+		}
+	}
+	return trace.Wrap(insertRow(tx, k, value, dir, e.expires(ttl)))
+}
+
+func insertRow(tx *sql.Tx, k key, value []byte, dir bool, expires int64) error {
+	if table, _, _ := findTable(k); table != nil {
+		if dir {
+			return trace.BadParameter("%v cannot be a directory", k)
+		}
+		return trace.Wrap(insertTableRow(tx, table, k, value, expires))
+	}
+	parent, name := k.split()
+	_, err := tx.Exec(`INSERT OR REPLACE INTO kv (path, parent, name, depth, dir, value, expires)
+VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		sqlPath(k), sqlPath(parent), name, len(k), dir, value, expires)
+	return trace.Wrap(sqlErr(err))
+}
+
+func (e *sqlEngine) expires(ttl time.Duration) int64 {
+	if ttl == forever {
+		return 0
+	}
+	return e.clock.Now().Add(ttl).UnixNano()
+}
+
+func (e *sqlEngine) now() int64 {
+	return e.clock.Now().UnixNano()
+}
+
+// sqlPath returns the path for the specified key.
+// Key components are escaped so the path separator only appears
+// between components
+func sqlPath(k key) string {
+	parts := make([]string, 0, len(k))
+	for _, part := range k {
+		parts = append(parts, url.PathEscape(part))
+	}
+	return strings.Join(parts, "/")
+}
+
+// matchPattern returns true if the escaped key components match the pattern.
+// Empty pattern element matches any component
+func matchPattern(parts []string, pattern []string) bool {
+	if len(parts) != len(pattern) {
+		return false
+	}
+	for i, part := range parts {
+		if pattern[i] != anyKey && url.PathEscape(pattern[i]) != part {
+			return false
+		}
+	}
+	return true
+}
+
+func sqlErr(err error) error {
+	if err == nil {
+		return nil
+	}
+	if strings.Contains(err.Error(), "database is locked") {
+		return trace.ConnectionProblem(err, "database is locked, is another instance running?")
+	}
+	return err
+}
+
+type sqlRow struct {
+	dir     bool
+	value   []byte
+	expires int64
+}
+
+// queryer is either a database or a transaction
+type queryer interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// sqlSchema defines the database schema
+var sqlSchema = []string{
+	`CREATE TABLE IF NOT EXISTS kv (
+	path TEXT NOT NULL PRIMARY KEY,
+	parent TEXT NOT NULL,
+	name TEXT NOT NULL,
+	depth INTEGER NOT NULL,
+	dir INTEGER NOT NULL,
+	value BLOB,
+	expires INTEGER NOT NULL DEFAULT 0
+)`,
+	`CREATE INDEX IF NOT EXISTS kv_parent ON kv (parent, name)`,
+	`CREATE INDEX IF NOT EXISTS kv_depth ON kv (depth, path)`,
+	`CREATE TABLE IF NOT EXISTS sites (
+	domain TEXT NOT NULL PRIMARY KEY,
+	value BLOB,
+	expires INTEGER NOT NULL DEFAULT 0
+)`,
+	`CREATE TABLE IF NOT EXISTS operations (
+	site_domain TEXT NOT NULL,
+	id TEXT NOT NULL,
+	created INTEGER NOT NULL,
+	value BLOB,
+	expires INTEGER NOT NULL DEFAULT 0,
+	PRIMARY KEY (site_domain, id)
+)`,
+	`CREATE INDEX IF NOT EXISTS operations_created ON operations (site_domain, created)`,
+	`CREATE TABLE IF NOT EXISTS plan_changes (
+	site_domain TEXT NOT NULL,
+	operation_id TEXT NOT NULL,
+	id TEXT NOT NULL,
+	created INTEGER NOT NULL,
+	value BLOB,
+	expires INTEGER NOT NULL DEFAULT 0,
+	PRIMARY KEY (site_domain, operation_id, id)
+)`,
+	`CREATE INDEX IF NOT EXISTS plan_changes_created ON plan_changes (site_domain, operation_id, created)`,
+	`CREATE TABLE IF NOT EXISTS packages (
+	repository TEXT NOT NULL,
+	name TEXT NOT NULL,
+	version TEXT NOT NULL,
+	value BLOB,
+	expires INTEGER NOT NULL DEFAULT 0,
+	PRIMARY KEY (repository, name, version)
+)`,
+}
@@ -0,0 +1,345 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package keyval
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/gravitational/gravity/lib/compare"
+	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/storage/suite"
+
+	"github.com/gravitational/trace"
+	"github.com/jonboulle/clockwork"
+	log "github.com/sirupsen/logrus"
+	. "gopkg.in/check.v1"
+)
+
+type SQLSuite struct {
+	backend storage.Backend
+	clock   clockwork.FakeClock
+	dir     string
+	suite   suite.StorageSuite
+}
+
+var _ = Suite(&SQLSuite{})
+
+func (s *SQLSuite) SetUpTest(c *C) {
+	log.SetOutput(os.Stderr)
+
+	s.dir = c.MkDir()
+	s.clock = clockwork.NewFakeClock()
+	var err error
+	s.backend, err = NewSQL(SQLConfig{
+		Path:  filepath.Join(s.dir, "gravity.sqlite"),
+		Clock: s.clock,
+	})
+	c.Assert(err, IsNil)
+
+	s.suite.Backend = s.backend
+	s.suite.Clock = s.clock
+}
+
+func (s *SQLSuite) TearDownTest(c *C) {
+	c.Assert(s.backend.Close(), IsNil)
+}
+
+func (s *SQLSuite) TestExpiresKeys(c *C) {
+	token := storage.ProvisioningToken{
+		Token:      "token",
+		Expires:    s.clock.Now().Add(time.Hour),
+		Type:       storage.ProvisioningTokenTypeInstall,
+		AccountID:  "account",
+		SiteDomain: "example.com",
+	}
+	_, err := s.backend.CreateProvisioningToken(token)
+	c.Assert(err, IsNil)
+	_, err = s.backend.GetProvisioningToken(token.Token)
+	c.Assert(err, IsNil)
+
+	s.clock.Advance(2 * time.Hour)
+	_, err = s.backend.GetProvisioningToken(token.Token)
+	c.Assert(trace.IsNotFound(err), Equals, true, Commentf("%v", err))
+}
+
+func (s *SQLSuite) TestMigratesBolt(c *C) {
+	bolt, err := NewBolt(BoltConfig{
+		Path:  filepath.Join(s.dir, "gravity.db"),
+		Clock: s.clock,
+	})
+	c.Assert(err, IsNil)
+	boltSuite := suite.StorageSuite{Backend: bolt, Clock: s.clock}
+	boltSuite.OperationsCRUD(c)
+	boltSuite.RepositoriesCRUD(c)
+	sites, err := bolt.GetAllSites()
+	c.Assert(err, IsNil)
+	var operations []storage.SiteOperation
+	for _, site := range sites {
+		siteOperations, err := bolt.GetSiteOperations(site.Domain)
+		c.Assert(err, IsNil)
+		operations = append(operations, siteOperations...)
+	}
+	c.Assert(operations, Not(HasLen), 0)
+	repositories, err := bolt.GetRepositories()
+	c.Assert(err, IsNil)
+	c.Assert(bolt.Close(), IsNil)
+
+	config := SQLConfig{
+		Path:  filepath.Join(s.dir, "migrated.sqlite"),
+		Clock: s.clock,
+	}
+	err = MigrateBoltToSQL(filepath.Join(s.dir, "gravity.db"), config)
+	c.Assert(err, IsNil)
+	// migration only works into an empty database
+	err = MigrateBoltToSQL(filepath.Join(s.dir, "gravity.db"), config)
+	c.Assert(trace.IsAlreadyExists(err), Equals, true, Commentf("%v", err))
+
+	backend, err := NewSQL(config)
+	c.Assert(err, IsNil)
+	defer backend.Close()
+	migratedSites, err := backend.GetAllSites()
+	c.Assert(err, IsNil)
+	c.Assert(migratedSites, compare.DeepEquals, sites)
+	var migratedOperations []storage.SiteOperation
+	for _, site := range migratedSites {
+		siteOperations, err := backend.GetSiteOperations(site.Domain)
+		c.Assert(err, IsNil)
+		migratedOperations = append(migratedOperations, siteOperations...)
+	}
+	c.Assert(migratedOperations, compare.DeepEquals, operations)
+	migratedRepositories, err := backend.GetRepositories()
+	c.Assert(err, IsNil)
+	c.Assert(migratedRepositories, compare.DeepEquals, repositories)
+}
+
+func (s *SQLSuite) TestStoresRecordsInTables(c *C) {
+	s.suite.OperationsCRUD(c)
+	_, err := s.backend.CreateOperationPlanChange(storage.PlanChange{
+		ClusterName: "a.example.com",
+		OperationID: "1",
+		PhaseID:     "/init",
+		NewState:    storage.OperationPhaseStateCompleted,
+		Created:     s.clock.Now(),
+	})
+	c.Assert(err, IsNil)
+
+	db := s.backend.(*backend).kvengine.(*sqlEngine).db
+	count := func(query string, args ...interface{}) (n int) {
+		c.Assert(db.QueryRow(query, args...).Scan(&n), IsNil)
+		return n
+	}
+	operations, err := s.backend.GetSiteOperations("a.example.com")
+	c.Assert(err, IsNil)
+	c.Assert(count(`SELECT COUNT(*) FROM sites WHERE domain = ?`, "a.example.com"), Equals, 1)
+	c.Assert(count(`SELECT COUNT(*) FROM operations WHERE site_domain = ?`, "a.example.com"), Equals, len(operations))
+	c.Assert(count(`SELECT COUNT(*) FROM plan_changes WHERE site_domain = ?`, "a.example.com"), Equals, 1)
+	c.Assert(count(`SELECT COUNT(*) FROM packages WHERE repository = ?`, "example.com"), Equals, 1)
+	// none of the records are kept in the generic table
+	c.Assert(count(`SELECT COUNT(*) FROM kv WHERE dir = 0 AND (path LIKE 'root/sites/%' OR path LIKE 'root/repos/%/packages/%')`), Equals, 0)
+
+	c.Assert(s.backend.DeleteSite("a.example.com"), IsNil)
+	c.Assert(count(`SELECT COUNT(*) FROM sites WHERE domain = ?`, "a.example.com"), Equals, 0)
+	c.Assert(count(`SELECT COUNT(*) FROM operations WHERE site_domain = ?`, "a.example.com"), Equals, 0)
+	c.Assert(count(`SELECT COUNT(*) FROM plan_changes WHERE site_domain = ?`, "a.example.com"), Equals, 0)
+	_, err = s.backend.GetSiteOperation("a.example.com", operations[0].ID)
+	c.Assert(trace.IsNotFound(err), Equals, true, Commentf("%v", err))
+
+	c.Assert(s.backend.DeleteRepository("example.com"), IsNil)
+	c.Assert(count(`SELECT COUNT(*) FROM packages`), Equals, 0)
+}
+
+func (s *SQLSuite) TestAccountsCRUD(c *C) {
+	s.suite.AccountsCRUD(c)
+}
+
+func (s *SQLSuite) TestRepositoriesCRUD(c *C) {
+	s.suite.RepositoriesCRUD(c)
+}
+
+func (s *SQLSuite) TestSitesCRUD(c *C) {
+	s.suite.SitesCRUD(c)
+}
+
+func (s *SQLSuite) TestProgressEntriesCRUD(c *C) {
+	s.suite.ProgressEntriesCRUD(c)
+}
+
+func (s *SQLSuite) TestConnectorsCRUD(c *C) {
+	s.suite.ConnectorsCRUD(c)
+}
+
+func (s *SQLSuite) TestUsersCRUD(c *C) {
+	s.suite.UsersCRUD(c)
+}
+
+func (s *SQLSuite) TestUserTokensCRUD(c *C) {
+	s.suite.UserTokensCRUD(c)
+}
+
+func (s *SQLSuite) TestProvisioningTokensCRUD(c *C) {
+	s.suite.ProvisioningTokensCRUD(c)
+}
+
+func (s *SQLSuite) TestAPIKeys(c *C) {
+	s.suite.APIKeysCRUD(c)
+}
+
+func (s *SQLSuite) TestUserInvites(c *C) {
+	s.suite.UserInvitesCRUD(c)
+}
+
+func (s *SQLSuite) TestLoginEntriesCRUD(c *C) {
+	s.suite.LoginEntriesCRUD(c)
+}
+
+func (s *SQLSuite) TestPermissionsCRUD(c *C) {
+	s.suite.PermissionsCRUD(c)
+}
+
+func (s *SQLSuite) TestOperationsCRUD(c *C) {
+	s.suite.OperationsCRUD(c)
+}
+
+func (s *SQLSuite) TestOperationPlanChangelogCRUD(c *C) {
+	s.suite.OperationPlanChangelogCRUD(c)
+}
+
+func (s *SQLSuite) TestCreatesApplication(c *C) {
+	s.suite.CreatesApplication(c)
+}
+
+func (s *SQLSuite) TestDeletesApplication(c *C) {
+	s.suite.DeletesApplication(c)
+}
+
+func (s *SQLSuite) TestRetrievesApplications(c *C) {
+	s.suite.RetrievesApplications(c)
+}
+
+func (s *SQLSuite) TestCreatesAppImportOperation(c *C) {
+	s.suite.CreatesAppImportOperation(c)
+}
+
+func (s *SQLSuite) TestUpdatesAppImportOperation(c *C) {
+	s.suite.UpdatesAppImportOperation(c)
+}
+
+func (s *SQLSuite) TestConnectors(c *C) {
+	s.suite.ConnectorsCRUD(c)
+}
+
+func (s *SQLSuite) TestWebSessions(c *C) {
+	s.suite.WebSessionsCRUD(c)
+}
+
+func (s *SQLSuite) TestAuthoritiesCRUD(c *C) {
+	s.suite.AuthoritiesCRUD(c)
+}
+
+func (s *SQLSuite) TestNodesCRUD(c *C) {
+	s.suite.NodesCRUD(c)
+}
+
+func (s *SQLSuite) TestReverseTunnelsCRUD(c *C) {
+	s.suite.ReverseTunnelsCRUD(c)
+}
+
+func (s *SQLSuite) TestLocksCRUD(c *C) {
+	s.suite.LocksCRUD(c)
+}
+
+func (s *SQLSuite) TestPeersCRUD(c *C) {
+	s.suite.PeersCRUD(c)
+}
+
+func (s *SQLSuite) TestObjectsCRUD(c *C) {
+	s.suite.ObjectsCRUD(c)
+}
+
+func (s *SQLSuite) TestChangesetsCRUD(c *C) {
+	s.suite.ChangesetsCRUD(c)
+}
+
+func (s *SQLSuite) TestOpsCenterLinksCRUD(c *C) {
+	s.suite.OpsCenterLinksCRUD(c)
+}
+
+func (s *SQLSuite) TestRolesCRUD(c *C) {
+	s.suite.RolesCRUD(c)
+}
+
+func (s *SQLSuite) TestNamespacesCRUD(c *C) {
+	s.suite.NamespacesCRUD(c)
+}
+
+func (s *SQLSuite) TestLoginAttempts(c *C) {
+	s.suite.LoginAttempts(c)
+}
+
+func (s *SQLSuite) TestLocalCluster(c *C) {
+	s.suite.LocalCluster(c)
+}
+
+func (s *SQLSuite) TestSAMLCRUD(c *C) {
+	s.suite.SAMLCRUD(c)
+}
+
+func (s *SQLSuite) TestClusterAgentCreds(c *C) {
+	s.suite.ClusterAgentCreds(c)
+}
+
+func (s *SQLSuite) TestClusterLogin(c *C) {
+	s.suite.ClusterLogin(c)
+}
+
+func (s *SQLSuite) TestIndexFile(c *C) {
+	s.suite.IndexFile(c)
+}
+
+func (s *SQLSuite) TestRepositoryIndexFile(c *C) {
+	s.suite.RepositoryIndexFile(c)
+}
+
+func (s *SQLSuite) TestChartProvenance(c *C) {
+	s.suite.ChartProvenance(c)
+}
+
+func (s *SQLSuite) TestStatusHistory(c *C) {
+	s.suite.StatusHistory(c)
+}
+
+func (s *SQLSuite) TestSnapshot(c *C) {
+	s.suite.RepositoriesCRUD(c)
+	repositories, err := s.backend.GetRepositories()
+	c.Assert(err, IsNil)
+
+	path := filepath.Join(s.dir, "snapshot.sqlite")
+	f, err := os.Create(path)
+	c.Assert(err, IsNil)
+	err = s.backend.(Snapshotter).Snapshot(f)
+	c.Assert(err, IsNil)
+	c.Assert(f.Close(), IsNil)
+
+	snapshot, err := NewSQL(SQLConfig{Path: path, Clock: s.clock})
+	c.Assert(err, IsNil)
+	defer snapshot.Close()
+	snapshotRepositories, err := snapshot.GetRepositories()
+	c.Assert(err, IsNil)
+	c.Assert(snapshotRepositories, compare.DeepEquals, repositories)
+}
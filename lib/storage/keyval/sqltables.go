@@ -0,0 +1,287 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package keyval
+
+import (
+	"database/sql"
+	"encoding/json"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gravitational/trace"
+)
+
+// sqlTable describes a table that stores the values of the keys matching
+// its pattern instead of the generic kv table.
+//
+// Sites, operations, plan changes and packages are kept in dedicated tables
+// keyed by their natural keys, so that listing e.g. all operations of a cluster
+// is an index lookup instead of a scan of the key hierarchy.
+// The parent directories of these keys are still kept in the kv table
+type sqlTable struct {
+	// name is the table name
+	name string
+	// pattern is the pattern of keys stored in the table.
+	// Components set to anyKey are stored in the key columns
+	pattern key
+	// columns names the key column of each anyKey component of the pattern
+	columns []string
+	// created specifies whether the table records the creation time
+	// decoded from the value
+	created bool
+	// orderBy defines the order of listed values
+	orderBy string
+}
+
+// sqlTables lists the tables for specific keys
+var sqlTables = []sqlTable{
+	{
+		name:    "sites",
+		pattern: key{"root", sitesP, anyKey, valP},
+		columns: []string{"domain"},
+		orderBy: "domain",
+	},
+	{
+		name:    "operations",
+		pattern: key{"root", sitesP, anyKey, operationsP, anyKey, valP},
+		columns: []string{"site_domain", "id"},
+		created: true,
+		orderBy: "created DESC, id",
+	},
+	{
+		name:    "plan_changes",
+		pattern: key{"root", sitesP, anyKey, operationsP, anyKey, changelogP, anyKey, valP},
+		columns: []string{"site_domain", "operation_id", "id"},
+		created: true,
+		orderBy: "created, id",
+	},
+	{
+		name:    "packages",
+		pattern: key{"root", repositoriesP, anyKey, packagesP, anyKey, versionsP, anyKey},
+		columns: []string{"repository", "name", "version"},
+		orderBy: "name, version",
+	},
+}
+
+// match matches the key (or the key pattern) k against the table pattern.
+// k can be shorter than the table pattern to match all keys below it.
+// Returns the condition on the key columns for all components of k
+// that are not anyKey
+func (t sqlTable) match(k key) (where string, args []interface{}, ok bool) {
+	if len(k) > len(t.pattern) {
+		return "", nil, false
+	}
+	conditions := []string{"1"}
+	column := 0
+	for i, part := range k {
+		if t.pattern[i] != anyKey {
+			if part != t.pattern[i] {
+				return "", nil, false
+			}
+			continue
+		}
+		if part != anyKey {
+			conditions = append(conditions, t.columns[column]+" = ?")
+			args = append(args, part)
+		}
+		column++
+	}
+	return strings.Join(conditions, " AND "), args, true
+}
+
+// values returns the values of the key columns of the specified key
+func (t sqlTable) values(k key) (values []interface{}) {
+	for i, part := range t.pattern {
+		if part == anyKey {
+			values = append(values, k[i])
+		}
+	}
+	return values
+}
+
+// findTable returns the table that stores the value of the specified key
+// and the condition that selects its row
+func findTable(k key) (table *sqlTable, where string, args []interface{}) {
+	for i := range sqlTables {
+		if len(k) != len(sqlTables[i].pattern) {
+			continue
+		}
+		where, args, ok := sqlTables[i].match(k)
+		if ok {
+			return &sqlTables[i], where, args
+		}
+	}
+	return nil, "", nil
+}
+
+// lookupTable returns the row for the specified key stored in table
+func (e *sqlEngine) lookupTable(q queryer, table *sqlTable, where string, args []interface{}) (*sqlRow, error) {
+	var row sqlRow
+	err := q.QueryRow(`SELECT value, expires FROM `+table.name+` WHERE `+where, args...).
+		Scan(&row.value, &row.expires)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, trace.Wrap(sqlErr(err))
+	}
+	if row.expires != 0 && row.expires <= e.now() {
+		return nil, nil
+	}
+	return &row, nil
+}
+
+// insertTableRow writes the value of the specified key into table
+func insertTableRow(tx *sql.Tx, table *sqlTable, k key, value []byte, expires int64) error {
+	columns := append([]string{}, table.columns...)
+	args := table.values(k)
+	if table.created {
+		var object struct {
+			Created time.Time `json:"created"`
+		}
+		if err := json.Unmarshal(value, &object); err != nil {
+			return trace.Wrap(err)
+		}
+		columns = append(columns, "created")
+		args = append(args, object.Created.UnixNano())
+	}
+	columns = append(columns, "value", "expires")
+	args = append(args, value, expires)
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", ")
+	_, err := tx.Exec(`INSERT OR REPLACE INTO `+table.name+` (`+strings.Join(columns, ", ")+`)
+VALUES (`+placeholders+`)`, args...)
+	return trace.Wrap(sqlErr(err))
+}
+
+// deleteRow deletes the row of the specified key
+func deleteRow(tx *sql.Tx, k key) error {
+	if table, where, args := findTable(k); table != nil {
+		_, err := tx.Exec(`DELETE FROM `+table.name+` WHERE `+where, args...)
+		return trace.Wrap(sqlErr(err))
+	}
+	_, err := tx.Exec(`DELETE FROM kv WHERE path = ?`, sqlPath(k))
+	return trace.Wrap(sqlErr(err))
+}
+
+// deleteTableRows deletes the rows of all keys below the specified directory
+func deleteTableRows(tx *sql.Tx, dir key) error {
+	for _, table := range sqlTables {
+		if len(dir) >= len(table.pattern) {
+			continue
+		}
+		where, args, ok := table.match(dir)
+		if !ok {
+			continue
+		}
+		_, err := tx.Exec(`DELETE FROM `+table.name+` WHERE `+where, args...)
+		if err != nil {
+			return trace.Wrap(sqlErr(err))
+		}
+	}
+	return nil
+}
+
+// getTableKeys returns the names of the keys stored in tables
+// directly below the specified directory
+func (e *sqlEngine) getTableKeys(dir key) (names []string, err error) {
+	for _, table := range sqlTables {
+		if len(dir)+1 != len(table.pattern) {
+			continue
+		}
+		where, args, ok := table.match(dir)
+		if !ok {
+			continue
+		}
+		column := `'` + table.pattern[len(dir)] + `'`
+		if table.pattern[len(dir)] == anyKey {
+			column = table.columns[len(table.columns)-1]
+		}
+		tableNames, err := e.queryStrings(`SELECT DISTINCT `+column+` FROM `+table.name+`
+WHERE `+where+` AND (expires = 0 OR expires > ?)`, append(args, e.now())...)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		names = append(names, tableNames...)
+	}
+	return names, nil
+}
+
+// getTableVals returns the values of all keys matching the specified pattern
+// if the keys are stored in a table
+func (e *sqlEngine) getTableVals(pattern key) (vals [][]byte, ok bool, err error) {
+	for _, table := range sqlTables {
+		if len(pattern) != len(table.pattern) {
+			continue
+		}
+		where, args, ok := table.match(pattern)
+		if !ok {
+			continue
+		}
+		rows, err := e.db.Query(`SELECT value FROM `+table.name+`
+WHERE `+where+` AND (expires = 0 OR expires > ?)
+ORDER BY `+table.orderBy, append(args, e.now())...)
+		if err != nil {
+			return nil, true, trace.Wrap(sqlErr(err))
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var value []byte
+			if err := rows.Scan(&value); err != nil {
+				return nil, true, trace.Wrap(sqlErr(err))
+			}
+			vals = append(vals, value)
+		}
+		return vals, true, trace.Wrap(sqlErr(rows.Err()))
+	}
+	return nil, false, nil
+}
+
+// queryStrings returns the values of the single string column
+// selected by the query
+func (e *sqlEngine) queryStrings(query string, args ...interface{}) (out []string, err error) {
+	rows, err := e.db.Query(query, args...)
+	if err != nil {
+		return nil, trace.Wrap(sqlErr(err))
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var value string
+		if err := rows.Scan(&value); err != nil {
+			return nil, trace.Wrap(sqlErr(err))
+		}
+		out = append(out, value)
+	}
+	return out, trace.Wrap(sqlErr(rows.Err()))
+}
+
+// mergeNames returns the sorted union of the specified names
+func mergeNames(names ...[]string) []string {
+	seen := make(map[string]struct{})
+	out := []string{}
+	for _, list := range names {
+		for _, name := range list {
+			if _, ok := seen[name]; ok {
+				continue
+			}
+			seen[name] = struct{}{}
+			out = append(out, name)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
@@ -0,0 +1,95 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package keyval
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/utils"
+
+	"github.com/gravitational/trace"
+	"github.com/pborman/uuid"
+)
+
+// AddStatusTransitions records the specified transitions in the status
+// history of the cluster, evicting the oldest transitions to keep
+// at most capacity transitions
+func (b *backend) AddStatusTransitions(clusterName string, transitions []storage.StatusTransition, capacity int) error {
+	if clusterName == "" {
+		return trace.BadParameter("missing cluster name")
+	}
+	if capacity <= 0 {
+		return trace.BadParameter("capacity should be positive")
+	}
+	for i, transition := range transitions {
+		if err := transition.Check(); err != nil {
+			return trace.Wrap(err)
+		}
+		if transition.ID == "" {
+			transition.ID = uuid.New()
+		}
+		// keys sort in the order transitions were observed and added in
+		name := fmt.Sprintf("%020d-%06d-%v", transition.Time.UnixNano(), i, transition.ID)
+		err := b.upsertVal(b.key(sitesP, clusterName, statusHistoryP, name), transition, forever)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+	}
+	names, err := b.getKeys(b.key(sitesP, clusterName, statusHistoryP))
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if len(names) <= capacity {
+		return nil
+	}
+	sort.Strings(names)
+	for _, name := range names[:len(names)-capacity] {
+		err := b.deleteKey(b.key(sitesP, clusterName, statusHistoryP, name))
+		if err != nil && !trace.IsNotFound(err) {
+			return trace.Wrap(err)
+		}
+	}
+	return nil
+}
+
+// GetStatusTransitions returns the status history of the cluster
+// ordered from the oldest to the most recent transition
+func (b *backend) GetStatusTransitions(clusterName string) ([]storage.StatusTransition, error) {
+	if clusterName == "" {
+		return nil, trace.BadParameter("missing cluster name")
+	}
+	vals, err := b.getVals(b.key(sitesP, clusterName, statusHistoryP), anyKey)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	out := make([]storage.StatusTransition, 0, len(vals))
+	for _, val := range vals {
+		var transition storage.StatusTransition
+		if err := json.Unmarshal(val, &transition); err != nil {
+			return nil, trace.Wrap(err)
+		}
+		utils.UTC(&transition.Time)
+		out = append(out, transition)
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		return out[i].Time.Before(out[j].Time)
+	})
+	return out, nil
+}
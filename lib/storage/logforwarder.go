@@ -17,12 +17,17 @@ limitations under the License.
 package storage
 
 import (
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
 	"fmt"
+	"net"
+	"net/url"
 	"strings"
 	"time"
 
 	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/utils"
 
 	teleservices "github.com/gravitational/teleport/lib/services"
 	teleutils "github.com/gravitational/teleport/lib/utils"
@@ -37,6 +42,10 @@ type LogForwarder interface {
 	GetAddress() string
 	// GetProtocol returns log forwarder protocol
 	GetProtocol() string
+	// GetFormat returns the format of forwarded log messages
+	GetFormat() string
+	// GetTLS returns log forwarder TLS configuration
+	GetTLS() *LogForwarderTLS
 	// CheckAndSetDefaults validates log forwarder configuration
 	CheckAndSetDefaults() error
 }
@@ -115,6 +124,16 @@ func (l *LogForwarderV2) GetProtocol() string {
 	return l.Spec.Protocol
 }
 
+// GetFormat returns the format of forwarded log messages
+func (l *LogForwarderV2) GetFormat() string {
+	return l.Spec.Format
+}
+
+// GetTLS returns log forwarder TLS configuration
+func (l *LogForwarderV2) GetTLS() *LogForwarderTLS {
+	return l.Spec.TLS
+}
+
 // CheckAndSetDefaults validates log forwarder configuration
 func (l *LogForwarderV2) CheckAndSetDefaults() error {
 	if l.Metadata.Name == "" {
@@ -123,25 +142,251 @@ func (l *LogForwarderV2) CheckAndSetDefaults() error {
 	if l.Spec.Address == "" {
 		return trace.BadParameter("missing parameter Address")
 	}
-	if l.Spec.Protocol != "" {
-		if l.Spec.Protocol != "tcp" && l.Spec.Protocol != "udp" {
-			return trace.BadParameter(
-				"unsupported protocol %q, must be one of: tcp, udp", l.Spec.Protocol)
+	if l.Spec.Protocol == "" {
+		l.Spec.Protocol = LogForwarderProtocolTCP
+	}
+	var err error
+	switch l.Spec.Protocol {
+	case LogForwarderProtocolTCP, LogForwarderProtocolUDP:
+		err = l.Spec.checkSyslog()
+	case LogForwarderProtocolHTTP:
+		err = l.Spec.checkHTTP()
+	default:
+		return trace.BadParameter(
+			"unsupported protocol %q, must be one of: tcp, udp, http", l.Spec.Protocol)
+	}
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if l.Spec.TLS != nil {
+		if err := l.Spec.TLS.Check(); err != nil {
+			return trace.Wrap(err)
+		}
+	}
+	for _, filter := range []*LogForwarderFilter{l.Spec.Include, l.Spec.Exclude} {
+		if filter == nil {
+			continue
+		}
+		if err := filter.Check(); err != nil {
+			return trace.Wrap(err)
 		}
-	} else {
-		l.Spec.Protocol = "tcp"
+	}
+	return nil
+}
+
+// checkSyslog validates the configuration of a syslog forwarder
+func (r LogForwarderSpecV2) checkSyslog() error {
+	if _, _, err := net.SplitHostPort(r.Address); err != nil {
+		return trace.BadParameter("address %q must be in host:port format", r.Address)
+	}
+	if r.HTTP != nil {
+		return trace.BadParameter("http endpoint requires protocol %q", LogForwarderProtocolHTTP)
+	}
+	if r.Format != "" && !utils.StringInSlice(LogForwarderFormats, r.Format) {
+		return trace.BadParameter("unsupported format %q, must be one of: %v",
+			r.Format, strings.Join(LogForwarderFormats, ", "))
+	}
+	if r.Protocol == LogForwarderProtocolUDP && r.TLS != nil {
+		return trace.BadParameter("TLS is not supported with protocol %q", LogForwarderProtocolUDP)
+	}
+	return nil
+}
+
+// checkHTTP validates the configuration of an HTTP endpoint forwarder
+func (r LogForwarderSpecV2) checkHTTP() error {
+	u, err := url.Parse(r.Address)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return trace.BadParameter("address %q must be an http or https URL", r.Address)
+	}
+	if r.HTTP == nil {
+		return trace.BadParameter("protocol %q requires http endpoint configuration",
+			LogForwarderProtocolHTTP)
+	}
+	if r.Format != "" && r.Format != LogForwarderFormatJSON {
+		return trace.BadParameter("http endpoints only support format %q",
+			LogForwarderFormatJSON)
+	}
+	if r.TLS != nil && u.Scheme != "https" {
+		return trace.BadParameter("TLS requires an https address")
+	}
+	switch r.HTTP.Type {
+	case LogForwarderElasticsearch:
+	case LogForwarderSplunk:
+		if r.HTTP.Token == "" && r.HTTP.TokenRef == nil {
+			return trace.BadParameter("Splunk HTTP Event Collector requires a token")
+		}
+	default:
+		return trace.BadParameter("unsupported http endpoint type %q, must be one of: %v, %v",
+			r.HTTP.Type, LogForwarderElasticsearch, LogForwarderSplunk)
 	}
 	return nil
 }
 
 // LogForwarderSpecV2 is the log forwarder spec
 type LogForwarderSpecV2 struct {
-	// Address is log forwarder address
+	// Address is log forwarder address.
+	// It is host:port for syslog forwarders and a URL for http endpoints
 	Address string `json:"address"`
-	// Protocol is log forwarder protocol
+	// Protocol is log forwarder protocol: tcp, udp or http
 	Protocol string `json:"protocol,omitempty"`
+	// Format is the format of forwarded messages: rfc3164 (default), rfc5424, json or gelf.
+	// Http endpoints always receive JSON
+	Format string `json:"format,omitempty"`
+	// TLS specifies TLS configuration for tcp and https forwarders
+	TLS *LogForwarderTLS `json:"tls,omitempty"`
+	// HTTP specifies the http endpoint to forward logs to
+	HTTP *LogForwarderHTTP `json:"http,omitempty"`
+	// Include optionally limits forwarded logs to the ones matching the filter
+	Include *LogForwarderFilter `json:"include,omitempty"`
+	// Exclude optionally excludes logs matching the filter from forwarding
+	Exclude *LogForwarderFilter `json:"exclude,omitempty"`
+}
+
+// LogForwarderTLS defines TLS configuration of a log forwarder
+type LogForwarderTLS struct {
+	// CACert is the PEM-encoded certificate authority to verify the server with.
+	// System roots are used if unspecified
+	CACert string `json:"ca_cert,omitempty"`
+	// ClientCert is the PEM-encoded client certificate
+	ClientCert string `json:"client_cert,omitempty"`
+	// ClientKey is the PEM-encoded client private key
+	ClientKey string `json:"client_key,omitempty"`
+	// ClientKeyRef references the client private key stored in a Secret
+	ClientKeyRef *LogForwarderSecretRef `json:"client_key_ref,omitempty"`
+	// ServerName is the name to verify the server certificate against.
+	// Defaults to the host of the forwarder address
+	ServerName string `json:"server_name,omitempty"`
+	// InsecureSkipVerify disables verification of the server certificate
+	InsecureSkipVerify bool `json:"insecure_skip_verify,omitempty"`
 }
 
+// Check validates TLS configuration
+func (r LogForwarderTLS) Check() error {
+	if r.CACert != "" {
+		if !x509.NewCertPool().AppendCertsFromPEM([]byte(r.CACert)) {
+			return trace.BadParameter("failed to parse CA certificate")
+		}
+	}
+	hasKey := r.ClientKey != "" || r.ClientKeyRef != nil
+	if (r.ClientCert != "") != hasKey {
+		return trace.BadParameter("client certificate and key must be specified together")
+	}
+	if r.ClientCert != "" && r.ClientKey != "" {
+		if _, err := tls.X509KeyPair([]byte(r.ClientCert), []byte(r.ClientKey)); err != nil {
+			return trace.BadParameter("invalid client certificate: %v", err)
+		}
+	}
+	return nil
+}
+
+// LogForwarderHTTP defines an http endpoint to forward logs to
+type LogForwarderHTTP struct {
+	// Type is the endpoint type: elasticsearch (bulk API) or splunk (HTTP Event Collector)
+	Type string `json:"type"`
+	// Index is the Elasticsearch or Splunk index to store logs in
+	Index string `json:"index,omitempty"`
+	// Token is the Splunk HTTP Event Collector token
+	Token string `json:"token,omitempty"`
+	// TokenRef references the Splunk token stored in a Secret
+	TokenRef *LogForwarderSecretRef `json:"token_ref,omitempty"`
+	// Username is the Elasticsearch user name
+	Username string `json:"username,omitempty"`
+	// Password is the Elasticsearch user password
+	Password string `json:"password,omitempty"`
+	// PasswordRef references the Elasticsearch password stored in a Secret
+	PasswordRef *LogForwarderSecretRef `json:"password_ref,omitempty"`
+}
+
+// LogForwarderSecretRef references a credential of a log forwarder
+// kept in a Secret in the kube-system namespace
+type LogForwarderSecretRef struct {
+	// Name is the name of the Secret
+	Name string `json:"name"`
+	// Key is the key of the credential in the Secret
+	Key string `json:"key"`
+}
+
+// LogForwarderWithoutSecrets returns a copy of the log forwarder without
+// credentials: the TLS client key, the Splunk token and the Elasticsearch
+// password. References to the credentials are kept
+func LogForwarderWithoutSecrets(forwarder LogForwarder) LogForwarder {
+	v2, ok := forwarder.(*LogForwarderV2)
+	if !ok {
+		return forwarder
+	}
+	out := *v2
+	if out.Spec.TLS != nil {
+		tlsConfig := *out.Spec.TLS
+		tlsConfig.ClientKey = ""
+		out.Spec.TLS = &tlsConfig
+	}
+	if out.Spec.HTTP != nil {
+		endpoint := *out.Spec.HTTP
+		endpoint.Token = ""
+		endpoint.Password = ""
+		out.Spec.HTTP = &endpoint
+	}
+	return &out
+}
+
+// LogForwarderFilter selects logs by their origin and severity.
+// A log message matches the filter if it matches all specified criteria
+type LogForwarderFilter struct {
+	// Namespaces lists Kubernetes namespaces of the pods that produced the logs
+	Namespaces []string `json:"namespaces,omitempty"`
+	// PodLabels specifies labels of the pods that produced the logs
+	PodLabels map[string]string `json:"pod_labels,omitempty"`
+	// Severities lists syslog severities of the logs
+	Severities []string `json:"severities,omitempty"`
+}
+
+// Check validates the filter
+func (r LogForwarderFilter) Check() error {
+	for _, severity := range r.Severities {
+		if !utils.StringInSlice(LogSeverities, severity) {
+			return trace.BadParameter("unsupported severity %q, must be one of: %v",
+				severity, strings.Join(LogSeverities, ", "))
+		}
+	}
+	return nil
+}
+
+const (
+	// LogForwarderProtocolTCP forwards logs to a syslog server over TCP
+	LogForwarderProtocolTCP = "tcp"
+	// LogForwarderProtocolUDP forwards logs to a syslog server over UDP
+	LogForwarderProtocolUDP = "udp"
+	// LogForwarderProtocolHTTP forwards logs to an http endpoint
+	LogForwarderProtocolHTTP = "http"
+
+	// LogForwarderFormatRFC3164 is the traditional BSD syslog format
+	LogForwarderFormatRFC3164 = "rfc3164"
+	// LogForwarderFormatRFC5424 is the IETF syslog format
+	LogForwarderFormatRFC5424 = "rfc5424"
+	// LogForwarderFormatJSON formats log messages as JSON objects
+	LogForwarderFormatJSON = "json"
+	// LogForwarderFormatGELF is the Graylog extended log format
+	LogForwarderFormatGELF = "gelf"
+
+	// LogForwarderElasticsearch is the Elasticsearch bulk API endpoint
+	LogForwarderElasticsearch = "elasticsearch"
+	// LogForwarderSplunk is the Splunk HTTP Event Collector endpoint
+	LogForwarderSplunk = "splunk"
+)
+
+var (
+	// LogForwarderFormats lists the supported formats of syslog forwarders
+	LogForwarderFormats = []string{
+		LogForwarderFormatRFC3164,
+		LogForwarderFormatRFC5424,
+		LogForwarderFormatJSON,
+		LogForwarderFormatGELF,
+	}
+
+	// LogSeverities lists syslog severities
+	LogSeverities = []string{"emerg", "alert", "crit", "err", "warning", "notice", "info", "debug"}
+)
+
 // LogForwarderV2Scheme is the log forwarder JSON schema
 const LogForwarderV2Schema = `{
   "type": "object",
@@ -149,7 +394,58 @@ const LogForwarderV2Schema = `{
   "required": ["address"],
   "properties": {
     "address": {"type": "string"},
-    "protocol": {"type": "string"}
+    "protocol": {"type": "string"},
+    "format": {"type": "string"},
+    "tls": {
+      "type": "object",
+      "additionalProperties": false,
+      "properties": {
+        "ca_cert": {"type": "string"},
+        "client_cert": {"type": "string"},
+        "client_key": {"type": "string"},
+        "client_key_ref": %[2]v,
+        "server_name": {"type": "string"},
+        "insecure_skip_verify": {"type": "boolean"}
+      }
+    },
+    "http": {
+      "type": "object",
+      "additionalProperties": false,
+      "required": ["type"],
+      "properties": {
+        "type": {"type": "string"},
+        "index": {"type": "string"},
+        "token": {"type": "string"},
+        "token_ref": %[2]v,
+        "username": {"type": "string"},
+        "password": {"type": "string"},
+        "password_ref": %[2]v
+      }
+    },
+    "include": %[1]v,
+    "exclude": %[1]v
+  }
+}`
+
+// LogForwarderFilterSchema is the JSON schema of a log forwarder filter
+const LogForwarderFilterSchema = `{
+  "type": "object",
+  "additionalProperties": false,
+  "properties": {
+    "namespaces": {"type": "array", "items": {"type": "string"}},
+    "pod_labels": {"type": "object", "patternProperties": {"^.+$": {"type": "string"}}},
+    "severities": {"type": "array", "items": {"type": "string"}}
+  }
+}`
+
+// LogForwarderSecretRefSchema is the JSON schema of a reference to a log forwarder credential
+const LogForwarderSecretRefSchema = `{
+  "type": "object",
+  "additionalProperties": false,
+  "required": ["name", "key"],
+  "properties": {
+    "name": {"type": "string"},
+    "key": {"type": "string"}
   }
 }`
 
@@ -215,7 +511,8 @@ func (m *logForwarderMarshaler) Marshal(l LogForwarder, opts ...teleservices.Mar
 // GetLogForwarderSchema returns log forwarder JSON schema
 func GetLogForwarderSchema() string {
 	return fmt.Sprintf(teleservices.V2SchemaTemplate,
-		teleservices.MetadataSchema, LogForwarderV2Schema, "")
+		teleservices.MetadataSchema, fmt.Sprintf(LogForwarderV2Schema,
+			LogForwarderFilterSchema, LogForwarderSecretRefSchema), "")
 }
 
 // LogForwarderV1 is the legacy log forwarder spec
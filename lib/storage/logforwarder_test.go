@@ -0,0 +1,160 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"fmt"
+	"strconv"
+
+	teleutils "github.com/gravitational/teleport/lib/utils"
+	"github.com/gravitational/trace"
+	. "gopkg.in/check.v1"
+)
+
+type LogForwarderSuite struct{}
+
+var _ = Suite(&LogForwarderSuite{})
+
+func (*LogForwarderSuite) TestValidatesLogForwarders(c *C) {
+	keyPair, err := teleutils.GenerateSelfSignedCert([]string{"splunk.example.com"})
+	c.Assert(err, IsNil)
+	cert := strconv.Quote(string(keyPair.Cert))
+	key := strconv.Quote(string(keyPair.PrivateKey))
+
+	testCases := []struct {
+		spec    string
+		error   string
+		comment string
+	}{
+		{
+			spec:    `{"address": "192.168.100.1:514", "protocol": "udp"}`,
+			comment: "legacy syslog forwarder",
+		},
+		{
+			spec: fmt.Sprintf(`{"address": "logs.example.com:6514", "format": "rfc5424",
+"tls": {"ca_cert": %v, "client_cert": %v, "client_key": %v, "server_name": "logs.example.com"},
+"include": {"namespaces": ["default"], "severities": ["err", "crit"]},
+"exclude": {"pod_labels": {"app": "noisy"}}}`, cert, cert, key),
+			comment: "syslog forwarder over TLS with filters",
+		},
+		{
+			spec: fmt.Sprintf(`{"address": "https://splunk.example.com:8088/services/collector", "protocol": "http",
+"http": {"type": "splunk", "token": "token", "index": "k8s"}, "tls": {"ca_cert": %v}}`, cert),
+			comment: "Splunk HTTP Event Collector over TLS",
+		},
+		{
+			spec:    `{"address": "http://elasticsearch:9200", "protocol": "http", "http": {"type": "elasticsearch", "index": "logs"}}`,
+			comment: "Elasticsearch bulk endpoint",
+		},
+		{
+			spec: fmt.Sprintf(`{"address": "https://splunk.example.com:8088/services/collector", "protocol": "http",
+"http": {"type": "splunk", "token_ref": {"name": "log-forwarders", "key": "splunk.token"}},
+"tls": {"client_cert": %v, "client_key_ref": {"name": "log-forwarders", "key": "splunk.client_key"}}}`, cert),
+			comment: "Splunk HTTP Event Collector with credentials in a secret",
+		},
+		{
+			spec:    `{"address": "logs.example.com:514", "protocol": "sctp"}`,
+			error:   "unsupported protocol.*",
+			comment: "unsupported protocol",
+		},
+		{
+			spec:    `{"address": "logs.example.com:514", "format": "xml"}`,
+			error:   "unsupported format.*",
+			comment: "unsupported format",
+		},
+		{
+			spec:    `{"address": "logs.example.com:514", "protocol": "udp", "tls": {"server_name": "logs"}}`,
+			error:   "TLS is not supported.*",
+			comment: "TLS over UDP",
+		},
+		{
+			spec:    `{"address": "https://splunk.example.com:8088", "protocol": "http", "http": {"type": "splunk"}}`,
+			error:   ".*requires a token",
+			comment: "Splunk without token",
+		},
+		{
+			spec:    `{"address": "splunk.example.com:8088", "protocol": "http", "http": {"type": "splunk", "token": "token"}}`,
+			error:   ".*must be an http or https URL",
+			comment: "http endpoint without URL",
+		},
+		{
+			spec:    `{"address": "http://splunk.example.com:8088", "protocol": "http", "http": {"type": "splunk", "token": "token"}, "tls": {}}`,
+			error:   "TLS requires an https address",
+			comment: "TLS with http URL",
+		},
+		{
+			spec:    `{"address": "https://logs.example.com", "protocol": "http", "http": {"type": "loki"}}`,
+			error:   "unsupported http endpoint type.*",
+			comment: "unsupported http endpoint",
+		},
+		{
+			spec:    fmt.Sprintf(`{"address": "logs.example.com:6514", "tls": {"client_cert": %v}}`, cert),
+			error:   "client certificate and key must be specified together",
+			comment: "client certificate without key",
+		},
+		{
+			spec:    `{"address": "logs.example.com:6514", "tls": {"ca_cert": "bad"}}`,
+			error:   "failed to parse CA certificate",
+			comment: "invalid CA certificate",
+		},
+		{
+			spec:    `{"address": "logs.example.com:514", "include": {"severities": ["fatal"]}}`,
+			error:   "unsupported severity.*",
+			comment: "unsupported severity",
+		},
+	}
+	for _, tc := range testCases {
+		comment := Commentf(tc.comment)
+		forwarder, err := GetLogForwarderMarshaler().Unmarshal([]byte(fmt.Sprintf(
+			`{"kind": "logforwarder", "version": "v2", "metadata": {"name": "forwarder"}, "spec": %v}`, tc.spec)))
+		c.Assert(err, IsNil, comment)
+		err = forwarder.CheckAndSetDefaults()
+		if tc.error != "" {
+			c.Assert(trace.IsBadParameter(err), Equals, true, comment)
+			c.Assert(err, ErrorMatches, tc.error, comment)
+			continue
+		}
+		c.Assert(err, IsNil, comment)
+		data, err := GetLogForwarderMarshaler().Marshal(forwarder)
+		c.Assert(err, IsNil, comment)
+		parsed, err := GetLogForwarderMarshaler().Unmarshal(data)
+		c.Assert(err, IsNil, comment)
+		c.Assert(parsed, DeepEquals, forwarder, comment)
+	}
+}
+
+func (*LogForwarderSuite) TestRemovesSecrets(c *C) {
+	forwarder := NewLogForwarder("elasticsearch", "https://elasticsearch:9200", LogForwarderProtocolHTTP)
+	spec := &forwarder.(*LogForwarderV2).Spec
+	spec.TLS = &LogForwarderTLS{ClientCert: "cert", ClientKey: "key"}
+	spec.HTTP = &LogForwarderHTTP{
+		Type:        LogForwarderElasticsearch,
+		Username:    "user",
+		Password:    "password",
+		PasswordRef: &LogForwarderSecretRef{Name: "log-forwarders", Key: "elasticsearch.password"},
+	}
+
+	redacted := LogForwarderWithoutSecrets(forwarder).(*LogForwarderV2)
+	c.Assert(redacted.Spec.TLS, DeepEquals, &LogForwarderTLS{ClientCert: "cert"})
+	c.Assert(redacted.Spec.HTTP, DeepEquals, &LogForwarderHTTP{
+		Type:        LogForwarderElasticsearch,
+		Username:    "user",
+		PasswordRef: &LogForwarderSecretRef{Name: "log-forwarders", Key: "elasticsearch.password"},
+	})
+	c.Assert(spec.TLS.ClientKey, Equals, "key", Commentf("original should be left intact"))
+	c.Assert(spec.HTTP.Password, Equals, "password", Commentf("original should be left intact"))
+}
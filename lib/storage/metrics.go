@@ -0,0 +1,444 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/gravitational/gravity/lib/defaults"
+
+	teleservices "github.com/gravitational/teleport/lib/services"
+	teleutils "github.com/gravitational/teleport/lib/utils"
+	"github.com/gravitational/trace"
+)
+
+// RetentionPolicy describes the retention of collected metrics
+type RetentionPolicy interface {
+	// Resource provides common resource methods
+	teleservices.Resource
+	// CheckAndSetDefaults validates the retention policy
+	CheckAndSetDefaults() error
+	// GetDuration returns the duration metrics are retained for
+	GetDuration() time.Duration
+}
+
+// NewRetentionPolicy creates a new retention policy resource
+func NewRetentionPolicy(name string, duration time.Duration) RetentionPolicy {
+	return &RetentionPolicyV2{
+		Kind:    KindRetentionPolicy,
+		Version: teleservices.V2,
+		Metadata: teleservices.Metadata{
+			Name:      name,
+			Namespace: defaults.Namespace,
+		},
+		Spec: RetentionPolicySpecV2{
+			Duration: teleservices.NewDuration(duration),
+		},
+	}
+}
+
+// RetentionPolicyV2 defines the retention of collected metrics
+type RetentionPolicyV2 struct {
+	// Metadata is resource metadata
+	teleservices.Metadata `json:"metadata"`
+	// Kind is a resource kind
+	Kind string `json:"kind"`
+	// Version is a resource version
+	Version string `json:"version"`
+	// Spec defines the retention policy
+	Spec RetentionPolicySpecV2 `json:"spec"`
+}
+
+// RetentionPolicySpecV2 defines the retention policy
+type RetentionPolicySpecV2 struct {
+	// Duration is the duration metrics are retained for
+	Duration teleservices.Duration `json:"duration"`
+}
+
+// GetDuration returns the duration metrics are retained for
+func (r *RetentionPolicyV2) GetDuration() time.Duration {
+	return r.Spec.Duration.Duration
+}
+
+// CheckAndSetDefaults checks validity of all parameters and sets defaults
+func (r *RetentionPolicyV2) CheckAndSetDefaults() error {
+	if r.Metadata.Name == "" {
+		return trace.BadParameter("missing parameter Name")
+	}
+	if r.Spec.Duration.Duration <= 0 {
+		return trace.BadParameter("duration must be > 0")
+	}
+	return nil
+}
+
+// UnmarshalRetentionPolicy unmarshals a retention policy from JSON
+func UnmarshalRetentionPolicy(data []byte) (RetentionPolicy, error) {
+	if len(data) == 0 {
+		return nil, trace.BadParameter("empty retention policy")
+	}
+
+	jsonData, err := teleutils.ToJSON(data)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+
+	var hdr teleservices.ResourceHeader
+	err = json.Unmarshal(jsonData, &hdr)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+
+	switch hdr.Version {
+	case teleservices.V2:
+		var policy RetentionPolicyV2
+		err := teleutils.UnmarshalWithSchema(GetRetentionPolicySchema(), &policy, jsonData)
+		if err != nil {
+			return nil, trace.BadParameter("%v", err)
+		}
+		policy.Metadata.CheckAndSetDefaults()
+		return &policy, nil
+	}
+	return nil, trace.BadParameter(
+		"%v resource version %q is not supported", KindRetentionPolicy, hdr.Version)
+}
+
+// MarshalRetentionPolicy marshals a retention policy into JSON
+func MarshalRetentionPolicy(policy RetentionPolicy, opts ...teleservices.MarshalOption) ([]byte, error) {
+	return json.Marshal(policy)
+}
+
+// RetentionPolicySpecV2Schema is JSON schema for a retention policy
+const RetentionPolicySpecV2Schema = `{
+  "type": "object",
+  "additionalProperties": false,
+  "required": ["duration"],
+  "properties": {
+    "duration": {"type": "string"}
+  }
+}`
+
+// GetRetentionPolicySchema returns retention policy schema for version V2
+func GetRetentionPolicySchema() string {
+	return fmt.Sprintf(teleservices.V2SchemaTemplate, teleservices.MetadataSchema,
+		RetentionPolicySpecV2Schema, "")
+}
+
+// RemoteWrite describes a remote endpoint collected metrics are forwarded to
+type RemoteWrite interface {
+	// Resource provides common resource methods
+	teleservices.Resource
+	// CheckAndSetDefaults validates the remote-write target
+	CheckAndSetDefaults() error
+	// GetSpec returns the remote-write target specification
+	GetSpec() RemoteWriteSpecV2
+}
+
+// NewRemoteWrite creates a new remote-write target resource
+func NewRemoteWrite(name string, spec RemoteWriteSpecV2) RemoteWrite {
+	return &RemoteWriteV2{
+		Kind:    KindRemoteWrite,
+		Version: teleservices.V2,
+		Metadata: teleservices.Metadata{
+			Name:      name,
+			Namespace: defaults.Namespace,
+		},
+		Spec: spec,
+	}
+}
+
+// RemoteWriteV2 defines a remote endpoint collected metrics are forwarded to
+type RemoteWriteV2 struct {
+	// Metadata is resource metadata
+	teleservices.Metadata `json:"metadata"`
+	// Kind is a resource kind
+	Kind string `json:"kind"`
+	// Version is a resource version
+	Version string `json:"version"`
+	// Spec defines the remote-write target
+	Spec RemoteWriteSpecV2 `json:"spec"`
+}
+
+// RemoteWriteSpecV2 defines the remote-write target
+type RemoteWriteSpecV2 struct {
+	// URL is the URL of the remote-write endpoint
+	URL string `json:"url"`
+	// RemoteTimeout is the timeout of requests to the remote-write endpoint
+	RemoteTimeout *teleservices.Duration `json:"remote_timeout,omitempty"`
+	// BasicAuth specifies the credentials to authenticate with using basic authentication
+	BasicAuth *RemoteWriteBasicAuth `json:"basic_auth,omitempty"`
+	// BearerToken specifies the token to authenticate with using bearer authentication
+	BearerToken string `json:"bearer_token,omitempty"`
+	// InsecureSkipVerify disables the verification of the endpoint's certificate
+	InsecureSkipVerify bool `json:"insecure_skip_verify,omitempty"`
+}
+
+// RemoteWriteBasicAuth defines the basic authentication credentials
+type RemoteWriteBasicAuth struct {
+	// Username is the name of the user
+	Username string `json:"username"`
+	// Password is the user password
+	Password string `json:"password,omitempty"`
+}
+
+// GetSpec returns the remote-write target specification
+func (r *RemoteWriteV2) GetSpec() RemoteWriteSpecV2 {
+	return r.Spec
+}
+
+// CheckAndSetDefaults checks validity of all parameters and sets defaults
+func (r *RemoteWriteV2) CheckAndSetDefaults() error {
+	if r.Metadata.Name == "" {
+		return trace.BadParameter("missing parameter Name")
+	}
+	if err := checkURL(r.Spec.URL); err != nil {
+		return trace.Wrap(err)
+	}
+	if r.Spec.RemoteTimeout != nil && r.Spec.RemoteTimeout.Duration <= 0 {
+		return trace.BadParameter("remote timeout must be > 0")
+	}
+	if r.Spec.BasicAuth != nil {
+		if r.Spec.BasicAuth.Username == "" {
+			return trace.BadParameter("basic authentication requires a username")
+		}
+		if r.Spec.BearerToken != "" {
+			return trace.BadParameter("only one of basic authentication or bearer token can be specified")
+		}
+	}
+	return nil
+}
+
+// RemoteWriteWithoutSecrets returns a copy of the remote-write target without
+// credentials: the basic authentication password and the bearer token
+func RemoteWriteWithoutSecrets(target RemoteWrite) RemoteWrite {
+	v2, ok := target.(*RemoteWriteV2)
+	if !ok {
+		return target
+	}
+	out := *v2
+	out.Spec.BearerToken = ""
+	if out.Spec.BasicAuth != nil {
+		basicAuth := *out.Spec.BasicAuth
+		basicAuth.Password = ""
+		out.Spec.BasicAuth = &basicAuth
+	}
+	return &out
+}
+
+// UnmarshalRemoteWrite unmarshals a remote-write target from JSON
+func UnmarshalRemoteWrite(data []byte) (RemoteWrite, error) {
+	if len(data) == 0 {
+		return nil, trace.BadParameter("empty remote-write target")
+	}
+
+	jsonData, err := teleutils.ToJSON(data)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+
+	var hdr teleservices.ResourceHeader
+	err = json.Unmarshal(jsonData, &hdr)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+
+	switch hdr.Version {
+	case teleservices.V2:
+		var target RemoteWriteV2
+		err := teleutils.UnmarshalWithSchema(GetRemoteWriteSchema(), &target, jsonData)
+		if err != nil {
+			return nil, trace.BadParameter("%v", err)
+		}
+		target.Metadata.CheckAndSetDefaults()
+		return &target, nil
+	}
+	return nil, trace.BadParameter(
+		"%v resource version %q is not supported", KindRemoteWrite, hdr.Version)
+}
+
+// MarshalRemoteWrite marshals a remote-write target into JSON
+func MarshalRemoteWrite(target RemoteWrite, opts ...teleservices.MarshalOption) ([]byte, error) {
+	return json.Marshal(target)
+}
+
+// RemoteWriteSpecV2Schema is JSON schema for a remote-write target
+const RemoteWriteSpecV2Schema = `{
+  "type": "object",
+  "additionalProperties": false,
+  "required": ["url"],
+  "properties": {
+    "url": {"type": "string"},
+    "remote_timeout": {"type": "string"},
+    "basic_auth": {
+      "type": "object",
+      "additionalProperties": false,
+      "required": ["username"],
+      "properties": {
+        "username": {"type": "string"},
+        "password": {"type": "string"}
+      }
+    },
+    "bearer_token": {"type": "string"},
+    "insecure_skip_verify": {"type": "boolean"}
+  }
+}`
+
+// GetRemoteWriteSchema returns remote-write target schema for version V2
+func GetRemoteWriteSchema() string {
+	return fmt.Sprintf(teleservices.V2SchemaTemplate, teleservices.MetadataSchema,
+		RemoteWriteSpecV2Schema, "")
+}
+
+// RecordingRule describes a rule that precomputes an expression
+// into a new time series
+type RecordingRule interface {
+	// Resource provides common resource methods
+	teleservices.Resource
+	// CheckAndSetDefaults validates the recording rule
+	CheckAndSetDefaults() error
+	// GetSpec returns the recording rule specification
+	GetSpec() RecordingRuleSpecV2
+}
+
+// NewRecordingRule creates a new recording rule resource
+func NewRecordingRule(name string, spec RecordingRuleSpecV2) RecordingRule {
+	return &RecordingRuleV2{
+		Kind:    KindRecordingRule,
+		Version: teleservices.V2,
+		Metadata: teleservices.Metadata{
+			Name:      name,
+			Namespace: defaults.Namespace,
+		},
+		Spec: spec,
+	}
+}
+
+// RecordingRuleV2 defines a recording rule
+type RecordingRuleV2 struct {
+	// Metadata is resource metadata
+	teleservices.Metadata `json:"metadata"`
+	// Kind is a resource kind
+	Kind string `json:"kind"`
+	// Version is a resource version
+	Version string `json:"version"`
+	// Spec defines the recording rule
+	Spec RecordingRuleSpecV2 `json:"spec"`
+}
+
+// RecordingRuleSpecV2 defines the recording rule
+type RecordingRuleSpecV2 struct {
+	// Record is the name of the time series to record the expression into
+	Record string `json:"record"`
+	// Expr is the PromQL expression to evaluate
+	Expr string `json:"expr"`
+	// Labels specifies the labels to add to the recorded time series
+	Labels map[string]string `json:"labels,omitempty"`
+	// Interval is how often the rule is evaluated.
+	// Defaults to the global evaluation interval
+	Interval *teleservices.Duration `json:"interval,omitempty"`
+}
+
+// GetSpec returns the recording rule specification
+func (r *RecordingRuleV2) GetSpec() RecordingRuleSpecV2 {
+	return r.Spec
+}
+
+// CheckAndSetDefaults checks validity of all parameters and sets defaults
+func (r *RecordingRuleV2) CheckAndSetDefaults() error {
+	if r.Metadata.Name == "" {
+		return trace.BadParameter("missing parameter Name")
+	}
+	if !metricNameRegexp.MatchString(r.Spec.Record) {
+		return trace.BadParameter("invalid metric name %q", r.Spec.Record)
+	}
+	if r.Spec.Expr == "" {
+		return trace.BadParameter("missing parameter Expr")
+	}
+	for name := range r.Spec.Labels {
+		if !labelNameRegexp.MatchString(name) {
+			return trace.BadParameter("invalid label name %q", name)
+		}
+	}
+	if r.Spec.Interval != nil && r.Spec.Interval.Duration <= 0 {
+		return trace.BadParameter("interval must be > 0")
+	}
+	return nil
+}
+
+// UnmarshalRecordingRule unmarshals a recording rule from JSON
+func UnmarshalRecordingRule(data []byte) (RecordingRule, error) {
+	if len(data) == 0 {
+		return nil, trace.BadParameter("empty recording rule")
+	}
+
+	jsonData, err := teleutils.ToJSON(data)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+
+	var hdr teleservices.ResourceHeader
+	err = json.Unmarshal(jsonData, &hdr)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+
+	switch hdr.Version {
+	case teleservices.V2:
+		var rule RecordingRuleV2
+		err := teleutils.UnmarshalWithSchema(GetRecordingRuleSchema(), &rule, jsonData)
+		if err != nil {
+			return nil, trace.BadParameter("%v", err)
+		}
+		rule.Metadata.CheckAndSetDefaults()
+		return &rule, nil
+	}
+	return nil, trace.BadParameter(
+		"%v resource version %q is not supported", KindRecordingRule, hdr.Version)
+}
+
+// MarshalRecordingRule marshals a recording rule into JSON
+func MarshalRecordingRule(rule RecordingRule, opts ...teleservices.MarshalOption) ([]byte, error) {
+	return json.Marshal(rule)
+}
+
+// RecordingRuleSpecV2Schema is JSON schema for a recording rule
+const RecordingRuleSpecV2Schema = `{
+  "type": "object",
+  "additionalProperties": false,
+  "required": ["record", "expr"],
+  "properties": {
+    "record": {"type": "string"},
+    "expr": {"type": "string"},
+    "labels": {"type": "object", "patternProperties": {"^.+$": {"type": "string"}}},
+    "interval": {"type": "string"}
+  }
+}`
+
+// GetRecordingRuleSchema returns recording rule schema for version V2
+func GetRecordingRuleSchema() string {
+	return fmt.Sprintf(teleservices.V2SchemaTemplate, teleservices.MetadataSchema,
+		RecordingRuleSpecV2Schema, "")
+}
+
+var (
+	// metricNameRegexp matches valid Prometheus metric names
+	metricNameRegexp = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+	// labelNameRegexp matches valid Prometheus label names
+	labelNameRegexp = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+)
@@ -0,0 +1,145 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"time"
+
+	teleservices "github.com/gravitational/teleport/lib/services"
+	"github.com/gravitational/trace"
+	. "gopkg.in/check.v1"
+)
+
+type MetricsSuite struct{}
+
+var _ = Suite(&MetricsSuite{})
+
+func (*MetricsSuite) TestParsesRetentionPolicies(c *C) {
+	policy, err := UnmarshalRetentionPolicy([]byte(
+		`{"kind": "retentionpolicy", "version": "v2", "metadata": {"name": "default"}, "spec": {"duration": "720h"}}`))
+	c.Assert(err, IsNil)
+	c.Assert(policy.CheckAndSetDefaults(), IsNil)
+	c.Assert(policy.GetName(), Equals, "default")
+	c.Assert(policy.GetDuration(), Equals, 720*time.Hour)
+
+	policy, err = UnmarshalRetentionPolicy([]byte(
+		`{"kind": "retentionpolicy", "version": "v2", "metadata": {"name": "default"}, "spec": {"duration": "0s"}}`))
+	c.Assert(err, IsNil)
+	c.Assert(policy.CheckAndSetDefaults(), ErrorMatches, "duration must be > 0")
+}
+
+func (*MetricsSuite) TestParsesRemoteWrites(c *C) {
+	testCases := []struct {
+		in      string
+		spec    *RemoteWriteSpecV2
+		error   string
+		comment string
+	}{
+		{
+			in: `{"kind": "remotewrite", "version": "v2", "metadata": {"name": "thanos"}, "spec": {"url": "https://thanos:19291/api/v1/receive", "remote_timeout": "30s", "basic_auth": {"username": "gravity", "password": "secret"}}}`,
+			spec: &RemoteWriteSpecV2{
+				URL:           "https://thanos:19291/api/v1/receive",
+				RemoteTimeout: &teleservices.Duration{Duration: 30 * time.Second},
+				BasicAuth:     &RemoteWriteBasicAuth{Username: "gravity", Password: "secret"},
+			},
+			comment: "remote-write target with basic authentication",
+		},
+		{
+			in:      `{"kind": "remotewrite", "version": "v2", "metadata": {"name": "cortex"}, "spec": {"url": "ftp://cortex"}}`,
+			error:   "URL .* must use http or https scheme",
+			comment: "invalid URL scheme",
+		},
+		{
+			in:      `{"kind": "remotewrite", "version": "v2", "metadata": {"name": "cortex"}, "spec": {"url": "https://cortex", "bearer_token": "token", "basic_auth": {"username": "gravity"}}}`,
+			error:   "only one of basic authentication or bearer token can be specified",
+			comment: "conflicting authentication",
+		},
+	}
+	for _, tc := range testCases {
+		comment := Commentf(tc.comment)
+		target, err := UnmarshalRemoteWrite([]byte(tc.in))
+		c.Assert(err, IsNil, comment)
+		err = target.CheckAndSetDefaults()
+		if tc.error != "" {
+			c.Assert(trace.IsBadParameter(err), Equals, true, comment)
+			c.Assert(err, ErrorMatches, tc.error, comment)
+			continue
+		}
+		c.Assert(err, IsNil, comment)
+		c.Assert(target.GetSpec(), DeepEquals, *tc.spec, comment)
+	}
+}
+
+func (*MetricsSuite) TestParsesRecordingRules(c *C) {
+	testCases := []struct {
+		in      string
+		spec    *RecordingRuleSpecV2
+		error   string
+		comment string
+	}{
+		{
+			in: `{"kind": "recordingrule", "version": "v2", "metadata": {"name": "cpu"}, "spec": {"record": "node:cpu_usage:rate5m", "expr": "rate(node_cpu_seconds_total[5m])", "labels": {"team": "ops"}, "interval": "1m"}}`,
+			spec: &RecordingRuleSpecV2{
+				Record:   "node:cpu_usage:rate5m",
+				Expr:     "rate(node_cpu_seconds_total[5m])",
+				Labels:   map[string]string{"team": "ops"},
+				Interval: &teleservices.Duration{Duration: time.Minute},
+			},
+			comment: "recording rule",
+		},
+		{
+			in:      `{"kind": "recordingrule", "version": "v2", "metadata": {"name": "cpu"}, "spec": {"record": "node-cpu", "expr": "up"}}`,
+			error:   "invalid metric name.*",
+			comment: "invalid metric name",
+		},
+		{
+			in:      `{"kind": "recordingrule", "version": "v2", "metadata": {"name": "cpu"}, "spec": {"record": "node_cpu", "expr": "up", "labels": {"team:name": "ops"}}}`,
+			error:   "invalid label name.*",
+			comment: "invalid label name",
+		},
+	}
+	for _, tc := range testCases {
+		comment := Commentf(tc.comment)
+		rule, err := UnmarshalRecordingRule([]byte(tc.in))
+		c.Assert(err, IsNil, comment)
+		err = rule.CheckAndSetDefaults()
+		if tc.error != "" {
+			c.Assert(trace.IsBadParameter(err), Equals, true, comment)
+			c.Assert(err, ErrorMatches, tc.error, comment)
+			continue
+		}
+		c.Assert(err, IsNil, comment)
+		c.Assert(rule.GetSpec(), DeepEquals, *tc.spec, comment)
+	}
+}
+
+func (*MetricsSuite) TestRemovesRemoteWriteSecrets(c *C) {
+	target := NewRemoteWrite("thanos", RemoteWriteSpecV2{
+		URL:       "https://thanos:19291/api/v1/receive",
+		BasicAuth: &RemoteWriteBasicAuth{Username: "gravity", Password: "secret"},
+	})
+	redacted := RemoteWriteWithoutSecrets(target)
+	c.Assert(redacted.GetSpec().BasicAuth, DeepEquals, &RemoteWriteBasicAuth{Username: "gravity"})
+	c.Assert(target.GetSpec().BasicAuth.Password, Equals, "secret")
+
+	target = NewRemoteWrite("cortex", RemoteWriteSpecV2{
+		URL:         "https://cortex/api/prom/push",
+		BearerToken: "token",
+	})
+	c.Assert(RemoteWriteWithoutSecrets(target).GetSpec().BearerToken, Equals, "")
+	c.Assert(target.GetSpec().BearerToken, Equals, "token")
+}
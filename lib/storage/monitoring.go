@@ -19,6 +19,12 @@ package storage
 import (
 	"encoding/json"
 	"fmt"
+	"net/http"
+	"net/url"
+	"text/template"
+	"time"
+
+	"github.com/gravitational/gravity/lib/utils"
 
 	teleservices "github.com/gravitational/teleport/lib/services"
 	teleutils "github.com/gravitational/teleport/lib/utils"
@@ -33,6 +39,10 @@ type Alert interface {
 	CheckAndSetDefaults() error
 	// GetFormula returns the kapacitor formula
 	GetFormula() string
+	// SetFormula sets the kapacitor formula
+	SetFormula(string)
+	// GetSpec returns the alert specification
+	GetSpec() AlertSpecV2
 }
 
 // AlertV2 defines a monitoring alert
@@ -52,17 +62,34 @@ func (r *AlertV2) GetFormula() string {
 	return r.Spec.Formula
 }
 
+// SetFormula sets alert's kapacitor formula
+func (r *AlertV2) SetFormula(formula string) {
+	r.Spec.Formula = formula
+}
+
+// GetSpec returns the alert specification
+func (r *AlertV2) GetSpec() AlertSpecV2 {
+	return r.Spec
+}
+
 // CheckAndSetDefaults checks validity of all parameters and sets defaults
 func (r *AlertV2) CheckAndSetDefaults() error {
-	if r.Spec.Formula == "" {
-		return trace.BadParameter("missing parameter Formula")
-	}
-
 	if r.Metadata.Name == "" {
 		return trace.BadParameter("missing parameter Name")
 	}
 
-	return nil
+	if !r.Spec.IsStructured() {
+		if r.Spec.Formula == "" {
+			return trace.BadParameter("missing parameter Formula")
+		}
+		return nil
+	}
+
+	if r.Spec.Formula != "" {
+		return trace.BadParameter("formula and metric cannot be specified together")
+	}
+
+	return trace.Wrap(r.Spec.checkAndSetDefaults())
 }
 
 // UnmarshalAlert unmarshals an alert from JSON
@@ -101,22 +128,135 @@ func MarshalAlert(alert Alert, opts ...teleservices.MarshalOption) ([]byte, erro
 	return json.Marshal(alert)
 }
 
-// AlertSpecV2 defines a monitoring alert
+// AlertSpecV2 defines a monitoring alert.
+//
+// An alert is either defined with a raw kapacitor formula or with a metric
+// and a threshold the aggregated metric is compared with.
+// The formula of the latter is generated from the alert specification
 type AlertSpecV2 struct {
 	// Formula defines a formula for kapacitor
-	Formula string `json:"formula"`
+	Formula string `json:"formula,omitempty"`
+	// Metric is the name of the InfluxDB measurement to alert on
+	Metric string `json:"metric,omitempty"`
+	// Field is the name of the measurement field, "value" by default
+	Field string `json:"field,omitempty"`
+	// Aggregation is the function to aggregate the metric over Duration with,
+	// "mean" by default
+	Aggregation string `json:"aggregation,omitempty"`
+	// Comparison is the operator to compare the aggregated metric with Threshold
+	Comparison string `json:"comparison,omitempty"`
+	// Threshold is the value to compare the aggregated metric with
+	Threshold float64 `json:"threshold,omitempty"`
+	// Duration is the period to aggregate the metric over, 5 minutes by default
+	Duration *teleservices.Duration `json:"duration,omitempty"`
+	// Severity is the level of the triggered alert, "critical" by default
+	Severity string `json:"severity,omitempty"`
+	// Filter limits the alert to the series with the specified tags
+	Filter map[string]string `json:"filter,omitempty"`
+	// GroupBy lists the tags to group the series by
+	GroupBy []string `json:"group_by,omitempty"`
+	// Labels specifies additional labels of the triggered alert
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// IsStructured returns true if the alert is defined with a metric
+// rather than a raw kapacitor formula
+func (r AlertSpecV2) IsStructured() bool {
+	return r.Metric != ""
+}
+
+// Condition returns the human-readable alert condition
+func (r AlertSpecV2) Condition() string {
+	if !r.IsStructured() {
+		return r.Formula
+	}
+	var duration time.Duration
+	if r.Duration != nil {
+		duration = r.Duration.Duration
+	}
+	return fmt.Sprintf("%v(%v.%v) %v %v over %v", r.Aggregation, r.Metric, r.Field,
+		r.Comparison, r.Threshold, duration)
+}
+
+func (r *AlertSpecV2) checkAndSetDefaults() error {
+	if r.Field == "" {
+		r.Field = AlertFieldValue
+	}
+	if r.Aggregation == "" {
+		r.Aggregation = AlertAggregationMean
+	}
+	if !utils.StringInSlice(AlertAggregations, r.Aggregation) {
+		return trace.BadParameter("unsupported aggregation %q, supported are: %v",
+			r.Aggregation, AlertAggregations)
+	}
+	if r.Comparison == "" {
+		return trace.BadParameter("missing parameter Comparison")
+	}
+	if !utils.StringInSlice(AlertComparisons, r.Comparison) {
+		return trace.BadParameter("unsupported comparison %q, supported are: %v",
+			r.Comparison, AlertComparisons)
+	}
+	if r.Duration == nil || r.Duration.Duration == 0 {
+		r.Duration = &teleservices.Duration{Duration: AlertDefaultDuration}
+	}
+	if r.Duration.Duration < time.Second || r.Duration.Duration%time.Second != 0 {
+		return trace.BadParameter("duration must be a whole number of seconds, got %v",
+			r.Duration.Duration)
+	}
+	if r.Severity == "" {
+		r.Severity = AlertSeverityCritical
+	}
+	if !utils.StringInSlice(AlertSeverities, r.Severity) {
+		return trace.BadParameter("unsupported severity %q, supported are: %v",
+			r.Severity, AlertSeverities)
+	}
+	return nil
 }
 
 // AlertSpecV2Schema is JSON schema for a monitoring alert
 const AlertSpecV2Schema = `{
   "type": "object",
   "additionalProperties": false,
-  "required": ["formula"],
   "properties": {
-    "formula": {"type": "string"}
+    "formula": {"type": "string"},
+    "metric": {"type": "string"},
+    "field": {"type": "string"},
+    "aggregation": {"type": "string"},
+    "comparison": {"type": "string"},
+    "threshold": {"type": "number"},
+    "duration": {"type": "string"},
+    "severity": {"type": "string"},
+    "filter": {"type": "object", "patternProperties": {"^.+$": {"type": "string"}}},
+    "group_by": {"type": "array", "items": {"type": "string"}},
+    "labels": {"type": "object", "patternProperties": {"^.+$": {"type": "string"}}}
   }
 }`
 
+const (
+	// AlertFieldValue is the default measurement field of a structured alert
+	AlertFieldValue = "value"
+	// AlertAggregationMean is the default aggregation of a structured alert
+	AlertAggregationMean = "mean"
+	// AlertDefaultDuration is the default aggregation period of a structured alert
+	AlertDefaultDuration = 5 * time.Minute
+
+	// AlertSeverityCritical is the severity of critical alerts
+	AlertSeverityCritical = "critical"
+	// AlertSeverityWarning is the severity of warning alerts
+	AlertSeverityWarning = "warning"
+	// AlertSeverityInfo is the severity of informational alerts
+	AlertSeverityInfo = "info"
+)
+
+var (
+	// AlertAggregations lists the supported aggregations of structured alerts
+	AlertAggregations = []string{"mean", "median", "min", "max", "sum", "count", "last"}
+	// AlertComparisons lists the supported comparison operators of structured alerts
+	AlertComparisons = []string{">", ">=", "<", "<=", "==", "!="}
+	// AlertSeverities lists the supported severities of structured alerts
+	AlertSeverities = []string{AlertSeverityCritical, AlertSeverityWarning, AlertSeverityInfo}
+)
+
 // GetAlertSchema returns alert schema for version V2
 func GetAlertSchema() string {
 	return fmt.Sprintf(teleservices.V2SchemaTemplate, teleservices.MetadataSchema,
@@ -131,6 +271,11 @@ type AlertTarget interface {
 	CheckAndSetDefaults() error
 	// GetEmail returns the recipient's email
 	GetEmail() string
+	// GetTargetType returns the type of this alert target
+	GetTargetType() string
+	// Matches returns true if the alert with the specified name and labels
+	// should be routed to this target
+	Matches(alert string, labels map[string]string) bool
 }
 
 // AlertTargetV2 defines a monitoring alert target
@@ -150,6 +295,18 @@ func (r *AlertTargetV2) GetEmail() string {
 	return r.Spec.Email
 }
 
+// GetTargetType returns the type of this alert target
+func (r *AlertTargetV2) GetTargetType() string {
+	return AlertTargetEmail
+}
+
+// Matches returns true if the alert with the specified name and labels
+// should be routed to this target.
+// Email targets receive all alerts
+func (r *AlertTargetV2) Matches(alert string, labels map[string]string) bool {
+	return true
+}
+
 // CheckAndSetDefaults checks validity of all parameters and sets defaults
 func (r *AlertTargetV2) CheckAndSetDefaults() error {
 	if r.Spec.Email == "" {
@@ -160,7 +317,7 @@ func (r *AlertTargetV2) CheckAndSetDefaults() error {
 }
 
 // UnmarshalAlertTarget unmarshals an alert target from JSON
-func UnmarshalAlertTarget(data []byte) (*AlertTargetV2, error) {
+func UnmarshalAlertTarget(data []byte) (AlertTarget, error) {
 	if len(data) == 0 {
 		return nil, trace.BadParameter("empty alert target")
 	}
@@ -185,6 +342,17 @@ func UnmarshalAlertTarget(data []byte) (*AlertTargetV2, error) {
 		}
 		target.Metadata.CheckAndSetDefaults()
 		return &target, nil
+	case teleservices.V3:
+		var target AlertTargetV3
+		err := teleutils.UnmarshalWithSchema(GetAlertTargetSchemaV3(), &target, jsonData)
+		if err != nil {
+			return nil, trace.BadParameter("%v", err)
+		}
+		target.Metadata.CheckAndSetDefaults()
+		if err := target.CheckAndSetDefaults(); err != nil {
+			return nil, trace.Wrap(err)
+		}
+		return &target, nil
 	}
 	return nil, trace.BadParameter(
 		"%v resource version %q is not supported", KindAlertTarget, hdr.Version)
@@ -216,3 +384,319 @@ func GetAlertTargetSchema() string {
 	return fmt.Sprintf(teleservices.V2SchemaTemplate, teleservices.MetadataSchema,
 		AlertTargetSpecV2Schema, "")
 }
+
+// AlertTargetV3 defines a named monitoring alert target of a specific type.
+//
+// Unlike the email target of version V2, several targets can be configured
+// in a cluster and each target receives the alerts matching its route
+type AlertTargetV3 struct {
+	// Metadata is resource metadata
+	teleservices.Metadata `json:"metadata"`
+	// Kind is a resource kind
+	Kind string `json:"kind"`
+	// Version is a resource version
+	Version string `json:"version"`
+	// Spec defines the alert target
+	Spec AlertTargetSpecV3 `json:"spec"`
+}
+
+// AlertTargetSpecV3 defines a monitoring alert target.
+// Exactly one of the target types must be specified
+type AlertTargetSpecV3 struct {
+	// Webhook defines a generic webhook target
+	Webhook *WebhookTarget `json:"webhook,omitempty"`
+	// Slack defines a Slack incoming webhook target
+	Slack *SlackTarget `json:"slack,omitempty"`
+	// PagerDuty defines a PagerDuty Events API v2 target
+	PagerDuty *PagerDutyTarget `json:"pagerduty,omitempty"`
+	// OpsGenie defines an OpsGenie target
+	OpsGenie *OpsGenieTarget `json:"opsgenie,omitempty"`
+	// Route optionally limits the alerts sent to this target
+	Route *AlertRoute `json:"route,omitempty"`
+}
+
+// WebhookTarget defines a generic webhook alert target
+type WebhookTarget struct {
+	// URL is the webhook URL
+	URL string `json:"url"`
+	// Method is the HTTP method, POST by default
+	Method string `json:"method,omitempty"`
+	// Headers specifies additional request headers
+	Headers map[string]string `json:"headers,omitempty"`
+	// Body is the request body template in Go text/template format.
+	// The alert is sent as JSON if unspecified
+	Body string `json:"body,omitempty"`
+	// Secret is the key to sign the request body with.
+	// If specified, the hex-encoded HMAC-SHA256 of the body is sent
+	// in the signature header
+	Secret string `json:"secret,omitempty"`
+}
+
+// SlackTarget defines a Slack incoming webhook alert target
+type SlackTarget struct {
+	// URL is the incoming webhook URL
+	URL string `json:"url"`
+	// Channel optionally overrides the webhook channel
+	Channel string `json:"channel,omitempty"`
+	// Username optionally overrides the webhook username
+	Username string `json:"username,omitempty"`
+}
+
+// PagerDutyTarget defines a PagerDuty Events API v2 alert target
+type PagerDutyTarget struct {
+	// RoutingKey is the integration key of the PagerDuty service
+	RoutingKey string `json:"routing_key"`
+	// URL optionally overrides the Events API v2 URL
+	URL string `json:"url,omitempty"`
+}
+
+// OpsGenieTarget defines an OpsGenie alert target
+type OpsGenieTarget struct {
+	// APIKey is the OpsGenie API integration key
+	APIKey string `json:"api_key"`
+	// URL optionally overrides the OpsGenie alert API URL
+	URL string `json:"url,omitempty"`
+	// Teams lists the names of the teams to notify
+	Teams []string `json:"teams,omitempty"`
+}
+
+// AlertRoute limits the alerts sent to an alert target
+type AlertRoute struct {
+	// Alerts lists the names of the alerts to send
+	Alerts []string `json:"alerts,omitempty"`
+	// Labels specifies the labels the alert must have to be sent
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// Matches returns true if the alert with the specified name and labels
+// satisfies this route
+func (r AlertRoute) Matches(alert string, labels map[string]string) bool {
+	if len(r.Alerts) != 0 && !utils.StringInSlice(r.Alerts, alert) {
+		return false
+	}
+	for key, value := range r.Labels {
+		if labels[key] != value {
+			return false
+		}
+	}
+	return true
+}
+
+// GetEmail returns recipient's email.
+// Alert targets of version V3 do not send emails
+func (r *AlertTargetV3) GetEmail() string {
+	return ""
+}
+
+// GetTargetType returns the type of this alert target
+func (r *AlertTargetV3) GetTargetType() string {
+	switch {
+	case r.Spec.Webhook != nil:
+		return AlertTargetWebhook
+	case r.Spec.Slack != nil:
+		return AlertTargetSlack
+	case r.Spec.PagerDuty != nil:
+		return AlertTargetPagerDuty
+	case r.Spec.OpsGenie != nil:
+		return AlertTargetOpsGenie
+	}
+	return ""
+}
+
+// Matches returns true if the alert with the specified name and labels
+// should be routed to this target
+func (r *AlertTargetV3) Matches(alert string, labels map[string]string) bool {
+	if r.Spec.Route == nil {
+		return true
+	}
+	return r.Spec.Route.Matches(alert, labels)
+}
+
+// AlertTargetWithoutSecrets returns a copy of the alert target without
+// credentials: the PagerDuty routing key, the OpsGenie API key, the Slack
+// webhook URL, the webhook signing secret and the webhook header values
+func AlertTargetWithoutSecrets(target AlertTarget) AlertTarget {
+	v3, ok := target.(*AlertTargetV3)
+	if !ok {
+		return target
+	}
+	out := *v3
+	if out.Spec.Webhook != nil {
+		webhook := *out.Spec.Webhook
+		webhook.Secret = ""
+		if len(webhook.Headers) != 0 {
+			webhook.Headers = make(map[string]string, len(v3.Spec.Webhook.Headers))
+			for key := range v3.Spec.Webhook.Headers {
+				webhook.Headers[key] = ""
+			}
+		}
+		out.Spec.Webhook = &webhook
+	}
+	if out.Spec.Slack != nil {
+		slack := *out.Spec.Slack
+		slack.URL = ""
+		out.Spec.Slack = &slack
+	}
+	if out.Spec.PagerDuty != nil {
+		pagerDuty := *out.Spec.PagerDuty
+		pagerDuty.RoutingKey = ""
+		out.Spec.PagerDuty = &pagerDuty
+	}
+	if out.Spec.OpsGenie != nil {
+		opsGenie := *out.Spec.OpsGenie
+		opsGenie.APIKey = ""
+		out.Spec.OpsGenie = &opsGenie
+	}
+	return &out
+}
+
+// CheckAndSetDefaults checks validity of all parameters and sets defaults
+func (r *AlertTargetV3) CheckAndSetDefaults() error {
+	if r.Metadata.Name == "" {
+		return trace.BadParameter("missing parameter Name")
+	}
+	var types int
+	if r.Spec.Webhook != nil {
+		types++
+		if err := checkURL(r.Spec.Webhook.URL); err != nil {
+			return trace.Wrap(err, "invalid webhook URL")
+		}
+		if r.Spec.Webhook.Method == "" {
+			r.Spec.Webhook.Method = http.MethodPost
+		}
+		if r.Spec.Webhook.Body != "" {
+			if _, err := template.New("body").Parse(r.Spec.Webhook.Body); err != nil {
+				return trace.BadParameter("invalid webhook body template: %v", err)
+			}
+		}
+	}
+	if r.Spec.Slack != nil {
+		types++
+		if err := checkURL(r.Spec.Slack.URL); err != nil {
+			return trace.Wrap(err, "invalid Slack webhook URL")
+		}
+	}
+	if r.Spec.PagerDuty != nil {
+		types++
+		if r.Spec.PagerDuty.RoutingKey == "" {
+			return trace.BadParameter("missing PagerDuty routing key")
+		}
+		if r.Spec.PagerDuty.URL == "" {
+			r.Spec.PagerDuty.URL = PagerDutyEventsURL
+		}
+		if err := checkURL(r.Spec.PagerDuty.URL); err != nil {
+			return trace.Wrap(err, "invalid PagerDuty URL")
+		}
+	}
+	if r.Spec.OpsGenie != nil {
+		types++
+		if r.Spec.OpsGenie.APIKey == "" {
+			return trace.BadParameter("missing OpsGenie API key")
+		}
+		if r.Spec.OpsGenie.URL == "" {
+			r.Spec.OpsGenie.URL = OpsGenieAlertsURL
+		}
+		if err := checkURL(r.Spec.OpsGenie.URL); err != nil {
+			return trace.Wrap(err, "invalid OpsGenie URL")
+		}
+	}
+	if types != 1 {
+		return trace.BadParameter("alert target must specify exactly one of webhook, slack, pagerduty or opsgenie")
+	}
+	return nil
+}
+
+func checkURL(addr string) error {
+	if addr == "" {
+		return trace.BadParameter("URL is required")
+	}
+	u, err := url.Parse(addr)
+	if err != nil {
+		return trace.BadParameter("failed to parse URL %q: %v", addr, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return trace.BadParameter("URL %q must use http or https scheme", addr)
+	}
+	return nil
+}
+
+// AlertTargetSpecV3Schema is JSON schema for a monitoring alert target of version V3
+const AlertTargetSpecV3Schema = `{
+  "type": "object",
+  "additionalProperties": false,
+  "properties": {
+    "webhook": {
+      "type": "object",
+      "additionalProperties": false,
+      "required": ["url"],
+      "properties": {
+        "url": {"type": "string"},
+        "method": {"type": "string"},
+        "headers": {"type": "object", "patternProperties": {"^.+$": {"type": "string"}}},
+        "body": {"type": "string"},
+        "secret": {"type": "string"}
+      }
+    },
+    "slack": {
+      "type": "object",
+      "additionalProperties": false,
+      "required": ["url"],
+      "properties": {
+        "url": {"type": "string"},
+        "channel": {"type": "string"},
+        "username": {"type": "string"}
+      }
+    },
+    "pagerduty": {
+      "type": "object",
+      "additionalProperties": false,
+      "required": ["routing_key"],
+      "properties": {
+        "routing_key": {"type": "string"},
+        "url": {"type": "string"}
+      }
+    },
+    "opsgenie": {
+      "type": "object",
+      "additionalProperties": false,
+      "required": ["api_key"],
+      "properties": {
+        "api_key": {"type": "string"},
+        "url": {"type": "string"},
+        "teams": {"type": "array", "items": {"type": "string"}}
+      }
+    },
+    "route": {
+      "type": "object",
+      "additionalProperties": false,
+      "properties": {
+        "alerts": {"type": "array", "items": {"type": "string"}},
+        "labels": {"type": "object", "patternProperties": {"^.+$": {"type": "string"}}}
+      }
+    }
+  }
+}`
+
+// GetAlertTargetSchemaV3 returns alert target schema for version V3
+func GetAlertTargetSchemaV3() string {
+	return fmt.Sprintf(teleservices.V2SchemaTemplate, teleservices.MetadataSchema,
+		AlertTargetSpecV3Schema, "")
+}
+
+const (
+	// AlertTargetEmail is the type of the email alert target
+	AlertTargetEmail = "email"
+	// AlertTargetWebhook is the type of the generic webhook alert target
+	AlertTargetWebhook = "webhook"
+	// AlertTargetSlack is the type of the Slack alert target
+	AlertTargetSlack = "slack"
+	// AlertTargetPagerDuty is the type of the PagerDuty alert target
+	AlertTargetPagerDuty = "pagerduty"
+	// AlertTargetOpsGenie is the type of the OpsGenie alert target
+	AlertTargetOpsGenie = "opsgenie"
+
+	// PagerDutyEventsURL is the default PagerDuty Events API v2 URL
+	PagerDutyEventsURL = "https://events.pagerduty.com/v2/enqueue"
+	// OpsGenieAlertsURL is the default OpsGenie alert API URL
+	OpsGenieAlertsURL = "https://api.opsgenie.com/v2/alerts"
+)
@@ -0,0 +1,253 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"time"
+
+	teleservices "github.com/gravitational/teleport/lib/services"
+	"github.com/gravitational/trace"
+	. "gopkg.in/check.v1"
+)
+
+type AlertSuite struct{}
+
+var _ = Suite(&AlertSuite{})
+
+func (*AlertSuite) TestParsesAlerts(c *C) {
+	testCases := []struct {
+		in      string
+		spec    *AlertSpecV2
+		error   string
+		comment string
+	}{
+		{
+			in:      `{"kind": "alert", "version": "v2", "metadata": {"name": "raw"}, "spec": {"formula": "stream"}}`,
+			spec:    &AlertSpecV2{Formula: "stream"},
+			comment: "raw formula",
+		},
+		{
+			in: `{"kind": "alert", "version": "v2", "metadata": {"name": "cpu"}, "spec": {"metric": "cpu/usage_rate", "comparison": ">", "threshold": 90}}`,
+			spec: &AlertSpecV2{
+				Metric:      "cpu/usage_rate",
+				Field:       AlertFieldValue,
+				Aggregation: AlertAggregationMean,
+				Comparison:  ">",
+				Threshold:   90,
+				Duration:    &teleservices.Duration{Duration: AlertDefaultDuration},
+				Severity:    AlertSeverityCritical,
+			},
+			comment: "structured alert with defaults",
+		},
+		{
+			in: `{"kind": "alert", "version": "v2", "metadata": {"name": "disk"}, "spec": {"metric": "filesystem/usage", "aggregation": "max", "comparison": "<=", "duration": "1h", "severity": "info", "labels": {"team": "ops"}}}`,
+			spec: &AlertSpecV2{
+				Metric:      "filesystem/usage",
+				Field:       AlertFieldValue,
+				Aggregation: "max",
+				Comparison:  "<=",
+				Duration:    &teleservices.Duration{Duration: time.Hour},
+				Severity:    AlertSeverityInfo,
+				Labels:      map[string]string{"team": "ops"},
+			},
+			comment: "structured alert",
+		},
+		{
+			in:      `{"kind": "alert", "version": "v2", "metadata": {"name": "empty"}, "spec": {}}`,
+			error:   "missing parameter Formula",
+			comment: "neither formula nor metric",
+		},
+		{
+			in:      `{"kind": "alert", "version": "v2", "metadata": {"name": "cpu"}, "spec": {"formula": "stream", "metric": "cpu/usage_rate", "comparison": ">"}}`,
+			error:   "formula and metric cannot be specified together",
+			comment: "both formula and metric",
+		},
+		{
+			in:      `{"kind": "alert", "version": "v2", "metadata": {"name": "cpu"}, "spec": {"metric": "cpu/usage_rate"}}`,
+			error:   "missing parameter Comparison",
+			comment: "missing comparison",
+		},
+		{
+			in:      `{"kind": "alert", "version": "v2", "metadata": {"name": "cpu"}, "spec": {"metric": "cpu/usage_rate", "comparison": "=~"}}`,
+			error:   "unsupported comparison.*",
+			comment: "invalid comparison",
+		},
+		{
+			in:      `{"kind": "alert", "version": "v2", "metadata": {"name": "cpu"}, "spec": {"metric": "cpu/usage_rate", "comparison": ">", "aggregation": "stddev"}}`,
+			error:   "unsupported aggregation.*",
+			comment: "invalid aggregation",
+		},
+		{
+			in:      `{"kind": "alert", "version": "v2", "metadata": {"name": "cpu"}, "spec": {"metric": "cpu/usage_rate", "comparison": ">", "duration": "1500ms"}}`,
+			error:   "duration must be a whole number of seconds.*",
+			comment: "invalid duration",
+		},
+		{
+			in:      `{"kind": "alert", "version": "v2", "metadata": {"name": "cpu"}, "spec": {"metric": "cpu/usage_rate", "comparison": ">", "severity": "fatal"}}`,
+			error:   "unsupported severity.*",
+			comment: "invalid severity",
+		},
+	}
+	for _, tc := range testCases {
+		comment := Commentf(tc.comment)
+		alert, err := UnmarshalAlert([]byte(tc.in))
+		c.Assert(err, IsNil, comment)
+		err = alert.CheckAndSetDefaults()
+		if tc.error != "" {
+			c.Assert(trace.IsBadParameter(err), Equals, true, comment)
+			c.Assert(err, ErrorMatches, tc.error, comment)
+			continue
+		}
+		c.Assert(err, IsNil, comment)
+		c.Assert(alert.GetSpec(), DeepEquals, *tc.spec, comment)
+	}
+}
+
+type AlertTargetSuite struct{}
+
+var _ = Suite(&AlertTargetSuite{})
+
+func (*AlertTargetSuite) TestParsesAlertTargets(c *C) {
+	testCases := []struct {
+		in       string
+		typ      string
+		error    string
+		comment  string
+		validate func(AlertTarget)
+	}{
+		{
+			in:      `{"kind": "alerttarget", "version": "v2", "metadata": {"name": "email"}, "spec": {"email": "ops@example.com"}}`,
+			typ:     AlertTargetEmail,
+			comment: "email alert target",
+		},
+		{
+			in:      `{"kind": "alerttarget", "version": "v3", "metadata": {"name": "oncall"}, "spec": {"pagerduty": {"routing_key": "key"}}}`,
+			typ:     AlertTargetPagerDuty,
+			comment: "pagerduty alert target with default URL",
+			validate: func(target AlertTarget) {
+				c.Assert(target.(*AlertTargetV3).Spec.PagerDuty.URL, Equals, PagerDutyEventsURL)
+			},
+		},
+		{
+			in:      `{"kind": "alerttarget", "version": "v3", "metadata": {"name": "hook"}, "spec": {"webhook": {"url": "https://example.com/alerts", "secret": "s3cr3t", "body": "{{.Name}}"}}}`,
+			typ:     AlertTargetWebhook,
+			comment: "webhook alert target with default method",
+			validate: func(target AlertTarget) {
+				c.Assert(target.(*AlertTargetV3).Spec.Webhook.Method, Equals, "POST")
+			},
+		},
+		{
+			in:      `{"kind": "alerttarget", "version": "v3", "metadata": {"name": "chat"}, "spec": {"slack": {"url": "https://hooks.slack.com/services/x"}, "opsgenie": {"api_key": "key"}}}`,
+			error:   "exactly one",
+			comment: "several target types",
+		},
+		{
+			in:      `{"kind": "alerttarget", "version": "v3", "metadata": {"name": "chat"}, "spec": {}}`,
+			error:   "exactly one",
+			comment: "no target type",
+		},
+		{
+			in:      `{"kind": "alerttarget", "version": "v3", "metadata": {"name": "chat"}, "spec": {"slack": {"url": "ftp://example.com"}}}`,
+			error:   "http or https",
+			comment: "invalid URL scheme",
+		},
+		{
+			in:      `{"kind": "alerttarget", "version": "v3", "metadata": {"name": "hook"}, "spec": {"webhook": {"url": "https://example.com", "body": "{{.Name"}}}`,
+			error:   "invalid webhook body template",
+			comment: "invalid body template",
+		},
+		{
+			in:      `{"kind": "alerttarget", "version": "v3", "metadata": {"name": "hook"}, "spec": {"email": "ops@example.com"}}`,
+			error:   "exactly one",
+			comment: "email is not supported in v3",
+		},
+	}
+	for _, tc := range testCases {
+		comment := Commentf(tc.comment)
+		target, err := UnmarshalAlertTarget([]byte(tc.in))
+		if tc.error != "" {
+			c.Assert(err, NotNil, comment)
+			c.Assert(trace.IsBadParameter(err), Equals, true, comment)
+			c.Assert(err.Error(), Matches, ".*"+tc.error+".*", comment)
+			continue
+		}
+		c.Assert(err, IsNil, comment)
+		c.Assert(target.GetTargetType(), Equals, tc.typ, comment)
+		if tc.validate != nil {
+			tc.validate(target)
+		}
+		data, err := MarshalAlertTarget(target)
+		c.Assert(err, IsNil, comment)
+		parsed, err := UnmarshalAlertTarget(data)
+		c.Assert(err, IsNil, comment)
+		c.Assert(parsed, DeepEquals, target, comment)
+	}
+}
+
+func (*AlertTargetSuite) TestRoutesAlerts(c *C) {
+	route := AlertRoute{
+		Alerts: []string{"high-cpu", "disk-full"},
+		Labels: map[string]string{"severity": "critical"},
+	}
+	target := &AlertTargetV3{Spec: AlertTargetSpecV3{Route: &route}}
+	c.Assert(target.Matches("high-cpu", map[string]string{"severity": "critical", "host": "node-1"}), Equals, true)
+	c.Assert(target.Matches("high-cpu", map[string]string{"severity": "warning"}), Equals, false)
+	c.Assert(target.Matches("high-cpu", nil), Equals, false)
+	c.Assert(target.Matches("low-memory", map[string]string{"severity": "critical"}), Equals, false)
+
+	target.Spec.Route = &AlertRoute{Labels: map[string]string{"team": "db"}}
+	c.Assert(target.Matches("any", map[string]string{"team": "db"}), Equals, true)
+
+	target.Spec.Route = nil
+	c.Assert(target.Matches("any", nil), Equals, true)
+	c.Assert((&AlertTargetV2{}).Matches("any", nil), Equals, true)
+}
+
+func (*AlertTargetSuite) TestRemovesSecrets(c *C) {
+	target := &AlertTargetV3{
+		Kind:     KindAlertTarget,
+		Version:  teleservices.V3,
+		Metadata: teleservices.Metadata{Name: "incidents", Namespace: "default"},
+		Spec: AlertTargetSpecV3{
+			Webhook: &WebhookTarget{
+				URL:     "https://incidents.example.com",
+				Headers: map[string]string{"Authorization": "Bearer token"},
+				Secret:  "secret",
+			},
+		},
+	}
+	redacted := AlertTargetWithoutSecrets(target).(*AlertTargetV3)
+	c.Assert(redacted.Spec.Webhook, DeepEquals, &WebhookTarget{
+		URL:     "https://incidents.example.com",
+		Headers: map[string]string{"Authorization": ""},
+	})
+	// the original target is not modified
+	c.Assert(target.Spec.Webhook.Secret, Equals, "secret")
+	c.Assert(target.Spec.Webhook.Headers["Authorization"], Equals, "Bearer token")
+
+	target.Spec = AlertTargetSpecV3{PagerDuty: &PagerDutyTarget{RoutingKey: "key", URL: PagerDutyEventsURL}}
+	redacted = AlertTargetWithoutSecrets(target).(*AlertTargetV3)
+	c.Assert(redacted.Spec.PagerDuty, DeepEquals, &PagerDutyTarget{URL: PagerDutyEventsURL})
+
+	target.Spec = AlertTargetSpecV3{OpsGenie: &OpsGenieTarget{APIKey: "key", Teams: []string{"ops"}}}
+	redacted = AlertTargetWithoutSecrets(target).(*AlertTargetV3)
+	c.Assert(redacted.Spec.OpsGenie, DeepEquals, &OpsGenieTarget{Teams: []string{"ops"}})
+
+	target.Spec = AlertTargetSpecV3{Slack: &SlackTarget{URL: "https://hooks.slack.com/services/1", Channel: "#alerts"}}
+	redacted = AlertTargetWithoutSecrets(target).(*AlertTargetV3)
+	c.Assert(redacted.Spec.Slack, DeepEquals, &SlackTarget{Channel: "#alerts"})
+}
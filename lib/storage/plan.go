@@ -17,6 +17,7 @@ limitations under the License.
 package storage
 
 import (
+	"sort"
 	"time"
 
 	"github.com/gravitational/gravity/lib/loc"
@@ -87,6 +88,44 @@ type OperationPhase struct {
 	Data *OperationPhaseData `json:"data,omitempty" yaml:"data,omitempty"`
 	// Error is the error that happened during phase execution
 	Error *trace.RawTrace `json:"error,omitempty"`
+	// History lists all execution and rollback attempts of the phase.
+	// It is populated from the plan changelog when the plan is resolved
+	// and is serialized so remote clients can display it
+	History []PhaseAttempt `json:"history,omitempty" yaml:"history,omitempty"`
+}
+
+// PhaseAttempt describes a single execution or rollback attempt of a phase
+type PhaseAttempt struct {
+	// Action is the attempted action, either execute or rollback
+	Action string `json:"action"`
+	// Started is the time the attempt has started.
+	// Zero if the phase state has been changed without executing the phase
+	Started time.Time `json:"started"`
+	// Finished is the time the attempt has finished.
+	// Zero if the attempt is still in progress
+	Finished time.Time `json:"finished"`
+	// State is the phase state the attempt has resulted in
+	State string `json:"state"`
+	// Server is the name of the server the attempt was made on
+	Server string `json:"server,omitempty"`
+	// User is the name of the user that made the attempt
+	User string `json:"user,omitempty"`
+	// Error is the error the attempt has failed with
+	Error *trace.RawTrace `json:"error,omitempty"`
+}
+
+// Duration returns the duration of the attempt.
+// Returns 0 if the attempt has not finished yet
+func (r PhaseAttempt) Duration() time.Duration {
+	if r.Started.IsZero() || r.Finished.IsZero() {
+		return 0
+	}
+	return r.Finished.Sub(r.Started)
+}
+
+// IsFinished returns true if the attempt has finished
+func (r PhaseAttempt) IsFinished() bool {
+	return !r.Finished.IsZero()
 }
 
 // OperationPhaseData represents data attached to an operation phase
@@ -139,6 +178,12 @@ type ElectionChange struct {
 type GarbageCollectOperationData struct {
 	// RemoteApps lists remote applications known to cluster
 	RemoteApps []Application `json:"remote_apps,omitempty" yaml:"remote_apps,omitempty"`
+	// KeepOperations specifies the number of most recent operations
+	// exempt from pruning of stale operation data
+	KeepOperations int `json:"keep_operations,omitempty" yaml:"keep_operations,omitempty"`
+	// KeepPeriod specifies the period of time recent operations are
+	// exempt from pruning of stale operation data for
+	KeepPeriod time.Duration `json:"keep_period,omitempty" yaml:"keep_period,omitempty"`
 }
 
 // UpdateOperationData describes configuration for update operations
@@ -232,11 +277,88 @@ type PlanChange struct {
 	Created time.Time `json:"created"`
 	// Error is the error that happened during phase execution
 	Error *trace.RawTrace `json:"error"`
+	// Action is the action that caused the change, either execute or rollback
+	Action string `json:"action,omitempty"`
+	// Server is the name of the server the change was made on
+	Server string `json:"server,omitempty"`
+	// User is the name of the user that made the change
+	User string `json:"user,omitempty"`
 }
 
 // PlanChangelog is a list of plan state changes
 type PlanChangelog []PlanChange
 
+// Attempts returns the history of execution and rollback attempts for
+// the specified phase in chronological order.
+//
+// An attempt starts with the phase moving into the in-progress state and ends
+// with the next change into any other state. A change into a final state
+// without a preceding in-progress change (e.g. when the phase state is set
+// manually) is recorded as an attempt without start time
+func (c PlanChangelog) Attempts(phaseID string) (attempts []PhaseAttempt) {
+	var changes PlanChangelog
+	for _, change := range c {
+		if change.PhaseID == phaseID {
+			changes = append(changes, change)
+		}
+	}
+	sort.SliceStable(changes, func(i, j int) bool {
+		return changes[i].Created.Before(changes[j].Created)
+	})
+	var current *PhaseAttempt
+	for _, change := range changes {
+		if change.NewState == OperationPhaseStateInProgress {
+			if current != nil {
+				// The previous attempt has been interrupted
+				attempts = append(attempts, *current)
+			}
+			current = &PhaseAttempt{
+				Action:  change.Action,
+				Started: change.Created,
+				State:   change.NewState,
+				Server:  change.Server,
+				User:    change.User,
+			}
+			continue
+		}
+		if current == nil {
+			last := len(attempts) - 1
+			if last >= 0 && attempts[last].State == change.NewState {
+				// Duplicate of the previous result, e.g. a remote phase
+				// recorded as completed on both the remote and the local node
+				continue
+			}
+			current = &PhaseAttempt{
+				Action: change.Action,
+				Server: change.Server,
+				User:   change.User,
+			}
+		}
+		if current.Action == "" {
+			current.Action = change.Action
+		}
+		current.Finished = change.Created
+		current.State = change.NewState
+		current.Error = change.Error
+		attempts = append(attempts, *current)
+		current = nil
+	}
+	if current != nil {
+		attempts = append(attempts, *current)
+	}
+	for i := range attempts {
+		if attempts[i].Action != "" {
+			continue
+		}
+		// Changelog entries created by older versions do not record the action
+		attempts[i].Action = PhaseActionExecute
+		if attempts[i].State == OperationPhaseStateRolledBack {
+			attempts[i].Action = PhaseActionRollback
+		}
+	}
+	return attempts
+}
+
 // Latest returns the most recent plan change entry for the specified phase
 func (c PlanChangelog) Latest(phaseID string) *PlanChange {
 	var latest *PlanChange
@@ -281,6 +403,43 @@ func (p OperationPhase) IsRolledBack() bool {
 	return p.GetState() == OperationPhaseStateRolledBack
 }
 
+// GetDuration returns the total time spent executing the phase.
+//
+// For a phase without sub-phases this is the sum of durations of all its
+// attempts. For a composite phase this is the time elapsed between the start
+// of the first and the end of the last attempt of any of its sub-phases
+func (p OperationPhase) GetDuration() time.Duration {
+	if len(p.Phases) == 0 {
+		var duration time.Duration
+		for _, attempt := range p.History {
+			duration += attempt.Duration()
+		}
+		return duration
+	}
+	var started, finished time.Time
+	for _, attempt := range p.getAttempts() {
+		if !attempt.Started.IsZero() && (started.IsZero() || attempt.Started.Before(started)) {
+			started = attempt.Started
+		}
+		if attempt.Finished.After(finished) {
+			finished = attempt.Finished
+		}
+	}
+	if started.IsZero() || finished.Before(started) {
+		return 0
+	}
+	return finished.Sub(started)
+}
+
+// getAttempts returns attempts of this phase and all its sub-phases
+func (p OperationPhase) getAttempts() (attempts []PhaseAttempt) {
+	attempts = append(attempts, p.History...)
+	for _, phase := range p.Phases {
+		attempts = append(attempts, phase.getAttempts()...)
+	}
+	return attempts
+}
+
 // GetLastUpdateTime returns the phase last updated time
 func (p OperationPhase) GetLastUpdateTime() time.Time {
 	if len(p.Phases) == 0 {
@@ -334,3 +493,10 @@ const (
 	// OperationPhaseStateRolledBack means that the phase or all of its subphases have been rolled back
 	OperationPhaseStateRolledBack = "rolled_back"
 )
+
+const (
+	// PhaseActionExecute is the action of executing a phase
+	PhaseActionExecute = "execute"
+	// PhaseActionRollback is the action of rolling back a phase
+	PhaseActionRollback = "rollback"
+)
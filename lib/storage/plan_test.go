@@ -0,0 +1,94 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"time"
+
+	"github.com/gravitational/gravity/lib/compare"
+
+	"github.com/gravitational/trace"
+	check "gopkg.in/check.v1"
+)
+
+type PlanSuite struct{}
+
+var _ = check.Suite(&PlanSuite{})
+
+func (s *PlanSuite) TestAttemptsFromChangelog(c *check.C) {
+	start := time.Date(2019, time.January, 1, 0, 0, 0, 0, time.UTC)
+	at := func(minutes int) time.Time {
+		return start.Add(time.Duration(minutes) * time.Minute)
+	}
+	failure := &trace.RawTrace{Err: []byte(`{"message":"failure"}`)}
+	changelog := PlanChangelog{
+		// Changes are not necessarily sorted
+		{PhaseID: "/a", NewState: OperationPhaseStateFailed, Created: at(2), Action: PhaseActionExecute, Error: failure},
+		{PhaseID: "/a", NewState: OperationPhaseStateInProgress, Created: at(0), Action: PhaseActionExecute, Server: "node-1", User: "root"},
+		{PhaseID: "/b", NewState: OperationPhaseStateInProgress, Created: at(1)},
+		{PhaseID: "/a", NewState: OperationPhaseStateInProgress, Created: at(3), Action: PhaseActionRollback, Server: "node-1"},
+		{PhaseID: "/a", NewState: OperationPhaseStateRolledBack, Created: at(4), Action: PhaseActionRollback, Server: "node-1"},
+		{PhaseID: "/a", NewState: OperationPhaseStateInProgress, Created: at(5), Action: PhaseActionExecute, Server: "node-2"},
+		{PhaseID: "/a", NewState: OperationPhaseStateCompleted, Created: at(8), Action: PhaseActionExecute, Server: "node-2"},
+		// Duplicate completion recorded on a different node
+		{PhaseID: "/a", NewState: OperationPhaseStateCompleted, Created: at(9), Action: PhaseActionExecute, Server: "node-1"},
+	}
+	attempts := changelog.Attempts("/a")
+	c.Assert(attempts, compare.DeepEquals, []PhaseAttempt{
+		{
+			Action:   PhaseActionExecute,
+			Started:  at(0),
+			Finished: at(2),
+			State:    OperationPhaseStateFailed,
+			Server:   "node-1",
+			User:     "root",
+			Error:    failure,
+		},
+		{
+			Action:   PhaseActionRollback,
+			Started:  at(3),
+			Finished: at(4),
+			State:    OperationPhaseStateRolledBack,
+			Server:   "node-1",
+		},
+		{
+			Action:   PhaseActionExecute,
+			Started:  at(5),
+			Finished: at(8),
+			State:    OperationPhaseStateCompleted,
+			Server:   "node-2",
+		},
+	})
+	c.Assert(changelog.Attempts("/b"), compare.DeepEquals, []PhaseAttempt{
+		{
+			Action:  PhaseActionExecute,
+			Started: at(1),
+			State:   OperationPhaseStateInProgress,
+		},
+	})
+
+	phase := OperationPhase{
+		ID: "/",
+		Phases: []OperationPhase{
+			{ID: "/a", History: attempts},
+			{ID: "/b", History: changelog.Attempts("/b")},
+		},
+	}
+	c.Assert(phase.Phases[0].GetDuration(), check.Equals, 6*time.Minute)
+	c.Assert(phase.Phases[1].GetDuration(), check.Equals, time.Duration(0))
+	c.Assert(phase.GetDuration(), check.Equals, 8*time.Minute)
+}
@@ -59,6 +59,12 @@ const (
 	KindAlert = "alert"
 	// KindAlertTarget defines the monitoring alert target resource type
 	KindAlertTarget = "alerttarget"
+	// KindRetentionPolicy defines the metrics retention policy resource type
+	KindRetentionPolicy = "retentionpolicy"
+	// KindRemoteWrite defines the metrics remote-write target resource type
+	KindRemoteWrite = "remotewrite"
+	// KindRecordingRule defines the metrics recording rule resource type
+	KindRecordingRule = "recordingrule"
 	// KindSystemInfo defines the system information resource
 	KindSystemInfo = "systeminfo"
 	// KindEndpoints defines the Ops Center endpoints resource type
@@ -97,6 +103,12 @@ func CanonicalKind(kind string) string {
 		return KindAlert
 	case KindAlertTarget, "alerttargets":
 		return KindAlertTarget
+	case KindRetentionPolicy, "retentionpolicies", "retention":
+		return KindRetentionPolicy
+	case KindRemoteWrite, "remotewrites":
+		return KindRemoteWrite
+	case KindRecordingRule, "recordingrules", "rules":
+		return KindRecordingRule
 	case KindRuntimeEnvironment, "environment", "env":
 		return KindRuntimeEnvironment
 	case KindClusterConfiguration, "config":
@@ -165,6 +177,9 @@ var SupportedGravityResources = []string{
 	KindSMTPConfig,
 	KindAlert,
 	KindAlertTarget,
+	KindRetentionPolicy,
+	KindRemoteWrite,
+	KindRecordingRule,
 	KindTLSKeyPair,
 	KindAuthGateway,
 	KindRuntimeEnvironment,
@@ -181,6 +196,8 @@ var SupportedGravityResourcesToRemove = []string{
 	KindSMTPConfig,
 	KindAlert,
 	KindAlertTarget,
+	KindRemoteWrite,
+	KindRecordingRule,
 	KindTLSKeyPair,
 	KindRuntimeEnvironment,
 	KindClusterConfiguration,
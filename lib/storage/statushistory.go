@@ -0,0 +1,71 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"time"
+
+	"github.com/gravitational/trace"
+)
+
+// StatusHistory stores the history of cluster health transitions.
+// The history is a ring buffer: once it reaches its capacity, the oldest
+// transitions are evicted
+type StatusHistory interface {
+	// AddStatusTransitions records the specified transitions in the status
+	// history of the cluster, evicting the oldest transitions to keep
+	// at most capacity transitions
+	AddStatusTransitions(clusterName string, transitions []StatusTransition, capacity int) error
+	// GetStatusTransitions returns the status history of the cluster
+	// ordered from the oldest to the most recent transition
+	GetStatusTransitions(clusterName string) ([]StatusTransition, error)
+}
+
+// StatusTransition describes a change in the health of the cluster,
+// a cluster node or an individual health probe on a node
+type StatusTransition struct {
+	// ID uniquely identifies the transition
+	ID string `json:"id"`
+	// Node is the advertise address of the node.
+	// Empty for cluster-wide transitions
+	Node string `json:"node,omitempty"`
+	// Hostname is the hostname of the node
+	Hostname string `json:"hostname,omitempty"`
+	// Probe is the name of the health probe.
+	// Empty for cluster and node transitions
+	Probe string `json:"probe,omitempty"`
+	// Status is the status after the transition
+	Status string `json:"status"`
+	// Previous is the status before the transition.
+	// Empty if the status was not known before
+	Previous string `json:"previous,omitempty"`
+	// Detail optionally describes the reason for the transition
+	Detail string `json:"detail,omitempty"`
+	// Time is the time the transition was observed
+	Time time.Time `json:"time"`
+}
+
+// Check makes sure the transition is valid
+func (r StatusTransition) Check() error {
+	if r.Status == "" {
+		return trace.BadParameter("missing status")
+	}
+	if r.Time.IsZero() {
+		return trace.BadParameter("missing time")
+	}
+	return nil
+}
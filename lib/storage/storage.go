@@ -488,6 +488,8 @@ type SiteOperations interface {
 	CreateOperationPlanChange(PlanChange) (*PlanChange, error)
 	// GetOperationPlanChangelog returns all state transition entries for a plan
 	GetOperationPlanChangelog(clusterName, operationID string) (PlanChangelog, error)
+	// DeleteOperationPlanChange deletes the specified state transition entry of a plan
+	DeleteOperationPlanChange(clusterName, operationID, changeID string) error
 }
 
 // Reason details the reason a site is in a particular state
@@ -848,6 +850,10 @@ type ProgressEntries interface {
 	CreateProgressEntry(p ProgressEntry) (*ProgressEntry, error)
 	// GetLastProgressEntry gets a progress entry for this site
 	GetLastProgressEntry(siteDomain, operationID string) (*ProgressEntry, error)
+	// GetProgressEntries returns all progress entries for the specified operation
+	GetProgressEntries(siteDomain, operationID string) ([]ProgressEntry, error)
+	// DeleteProgressEntry deletes the specified progress entry
+	DeleteProgressEntry(siteDomain, operationID, id string) error
 }
 
 // Package is any named and versioned blob with an optional manifest
@@ -1306,6 +1312,9 @@ type Peer struct {
 	ID            string    `json:"id"`
 	AdvertiseAddr string    `json:"advertise_addr"`
 	LastHeartbeat time.Time `json:"last_heartbeat"`
+	// WriteFactor is the number of peers that have to acknowledge
+	// the objects written by this peer
+	WriteFactor int `json:"write_factor,omitempty"`
 }
 
 func (p Peer) String() string {
@@ -1480,6 +1489,7 @@ type Backend interface {
 	LegacyRoles
 	SystemMetadata
 	Charts
+	StatusHistory
 }
 
 const (
@@ -2102,4 +2112,17 @@ type Charts interface {
 	CompareAndSwapIndexFile(new, existing *repo.IndexFile) error
 	// UpsertIndexFile creates or replaces chart repository index file.
 	UpsertIndexFile(repo.IndexFile) error
+	// GetRepositoryIndexFile returns the index file of the specified chart repository.
+	// The index file of the default repository is the chart repository index file
+	GetRepositoryIndexFile(repository string) (*repo.IndexFile, error)
+	// CompareAndSwapRepositoryIndexFile updates the index file of the specified chart repository.
+	CompareAndSwapRepositoryIndexFile(repository string, new, existing *repo.IndexFile) error
+	// UpsertRepositoryIndexFile creates or replaces the index file of the specified chart repository.
+	UpsertRepositoryIndexFile(repository string, indexFile repo.IndexFile) error
+	// GetChartProvenance returns the provenance file of the specified chart.
+	GetChartProvenance(repository, name, version string) ([]byte, error)
+	// UpsertChartProvenance creates or replaces the provenance file of the specified chart.
+	UpsertChartProvenance(repository, name, version string, data []byte) error
+	// DeleteChartProvenance deletes the provenance file of the specified chart.
+	DeleteChartProvenance(repository, name, version string) error
 }
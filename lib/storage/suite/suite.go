@@ -894,6 +894,19 @@ func (s *StorageSuite) ProgressEntriesCRUD(c *C) {
 	c.Assert(err, IsNil)
 	c.Assert(*ope2, DeepEquals, pe2)
 
+	entries, err := s.Backend.GetProgressEntries(sa.Domain, op.ID)
+	c.Assert(err, IsNil)
+	c.Assert(entries, DeepEquals, []storage.ProgressEntry{pe1, pe2})
+
+	err = s.Backend.DeleteProgressEntry(sa.Domain, op.ID, pe1.ID)
+	c.Assert(err, IsNil)
+	entries, err = s.Backend.GetProgressEntries(sa.Domain, op.ID)
+	c.Assert(err, IsNil)
+	c.Assert(entries, DeepEquals, []storage.ProgressEntry{pe2})
+
+	err = s.Backend.DeleteProgressEntry(sa.Domain, op.ID, pe1.ID)
+	c.Assert(trace.IsNotFound(err), Equals, true, Commentf("%#v", err))
+
 	// Create for non existent site should fail
 	_, err = s.Backend.CreateProgressEntry(storage.ProgressEntry{
 		SiteDomain:  "nothere.com",
@@ -989,6 +1002,39 @@ func (s *StorageSuite) OperationsCRUD(c *C) {
 	})
 }
 
+func (s *StorageSuite) OperationPlanChangelogCRUD(c *C) {
+	now := time.Date(2015, 11, 16, 1, 2, 3, 0, time.UTC)
+	change1, err := s.Backend.CreateOperationPlanChange(storage.PlanChange{
+		ClusterName: "a.example.com",
+		OperationID: "1",
+		PhaseID:     "/init",
+		NewState:    storage.OperationPhaseStateInProgress,
+		Created:     now,
+	})
+	c.Assert(err, IsNil)
+	change2, err := s.Backend.CreateOperationPlanChange(storage.PlanChange{
+		ClusterName: "a.example.com",
+		OperationID: "1",
+		PhaseID:     "/init",
+		NewState:    storage.OperationPhaseStateCompleted,
+		Created:     now.Add(time.Second),
+	})
+	c.Assert(err, IsNil)
+
+	changelog, err := s.Backend.GetOperationPlanChangelog("a.example.com", "1")
+	c.Assert(err, IsNil)
+	c.Assert(changelog, HasLen, 2)
+
+	err = s.Backend.DeleteOperationPlanChange("a.example.com", "1", change1.ID)
+	c.Assert(err, IsNil)
+	changelog, err = s.Backend.GetOperationPlanChangelog("a.example.com", "1")
+	c.Assert(err, IsNil)
+	c.Assert(changelog, DeepEquals, storage.PlanChangelog{*change2})
+
+	err = s.Backend.DeleteOperationPlanChange("a.example.com", "1", change1.ID)
+	c.Assert(trace.IsNotFound(err), Equals, true, Commentf("%#v", err))
+}
+
 func (s *StorageSuite) LoginEntriesCRUD(c *C) {
 	// Create
 	entry := storage.LoginEntry{
@@ -1954,6 +2000,89 @@ func (s *StorageSuite) IndexFile(c *C) {
 	compare.DeepCompare(c, retrievedFile, updatedIndex2)
 }
 
+func (s *StorageSuite) RepositoryIndexFile(c *C) {
+	_, err := s.Backend.CreateAccount(storage.Account{Org: "test"})
+	c.Assert(err, IsNil)
+
+	defaultIndex, _ := addToIndex(*newIndex(), "alpine", "0.1.0")
+	err = s.Backend.CompareAndSwapIndexFile(defaultIndex, nil)
+	c.Assert(err, IsNil)
+
+	// The index of the default repository is the chart repository index.
+	retrievedFile, err := s.Backend.GetRepositoryIndexFile(defaults.SystemAccountOrg)
+	c.Assert(err, IsNil)
+	compare.DeepCompare(c, retrievedFile, defaultIndex)
+
+	// Other repositories have their own index files.
+	_, err = s.Backend.GetRepositoryIndexFile("example.com")
+	c.Assert(err, FitsTypeOf, trace.NotFound(""))
+	indexFile, _ := addToIndex(*newIndex(), "nginx", "0.2.0")
+	err = s.Backend.CompareAndSwapRepositoryIndexFile("example.com", indexFile, nil)
+	c.Assert(err, IsNil)
+	retrievedFile, err = s.Backend.GetRepositoryIndexFile("example.com")
+	c.Assert(err, IsNil)
+	compare.DeepCompare(c, retrievedFile, indexFile)
+	retrievedFile, err = s.Backend.GetIndexFile()
+	c.Assert(err, IsNil)
+	compare.DeepCompare(c, retrievedFile, defaultIndex)
+
+	updatedIndex, previousIndex := addToIndex(*indexFile, "kafka", "0.3.0")
+	err = s.Backend.CompareAndSwapRepositoryIndexFile("example.com", updatedIndex, previousIndex)
+	c.Assert(err, IsNil)
+	err = s.Backend.CompareAndSwapRepositoryIndexFile("example.com", updatedIndex, previousIndex)
+	c.Assert(err, FitsTypeOf, trace.CompareFailed(""))
+}
+
+func (s *StorageSuite) ChartProvenance(c *C) {
+	_, err := s.Backend.CreateAccount(storage.Account{Org: "test"})
+	c.Assert(err, IsNil)
+
+	_, err = s.Backend.GetChartProvenance("example.com", "alpine", "0.1.0")
+	c.Assert(err, FitsTypeOf, trace.NotFound(""))
+
+	provenance := []byte("-----BEGIN PGP SIGNED MESSAGE-----")
+	err = s.Backend.UpsertChartProvenance("example.com", "alpine", "0.1.0", provenance)
+	c.Assert(err, IsNil)
+	data, err := s.Backend.GetChartProvenance("example.com", "alpine", "0.1.0")
+	c.Assert(err, IsNil)
+	c.Assert(data, DeepEquals, provenance)
+
+	err = s.Backend.DeleteChartProvenance("example.com", "alpine", "0.1.0")
+	c.Assert(err, IsNil)
+	_, err = s.Backend.GetChartProvenance("example.com", "alpine", "0.1.0")
+	c.Assert(err, FitsTypeOf, trace.NotFound(""))
+}
+
+func (s *StorageSuite) StatusHistory(c *C) {
+	transitions, err := s.Backend.GetStatusTransitions("example.com")
+	c.Assert(err, IsNil)
+	c.Assert(transitions, HasLen, 0)
+
+	var expected []storage.StatusTransition
+	for i := 0; i < 4; i++ {
+		expected = append(expected, storage.StatusTransition{
+			ID:       fmt.Sprintf("transition-%v", i),
+			Node:     "192.168.1.1",
+			Hostname: "node-1",
+			Status:   "degraded",
+			Previous: "healthy",
+			Time:     now.Add(time.Duration(i) * time.Minute),
+		})
+	}
+	err = s.Backend.AddStatusTransitions("example.com", expected[:2], 3)
+	c.Assert(err, IsNil)
+	err = s.Backend.AddStatusTransitions("example.com", expected[2:], 3)
+	c.Assert(err, IsNil)
+
+	// the oldest transition is evicted once the history is full
+	transitions, err = s.Backend.GetStatusTransitions("example.com")
+	c.Assert(err, IsNil)
+	compare.DeepCompare(c, transitions, expected[1:])
+
+	err = s.Backend.AddStatusTransitions("example.com", []storage.StatusTransition{{Time: now}}, 3)
+	c.Assert(trace.IsBadParameter(err), Equals, true)
+}
+
 func newIndex() *repo.IndexFile {
 	return &repo.IndexFile{
 		APIVersion: repo.APIVersionV1,
@@ -0,0 +1,575 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package state
+
+import (
+	"archive/tar"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/gravitational/gravity/lib/archive"
+	"github.com/gravitational/gravity/lib/blob"
+	"github.com/gravitational/gravity/lib/blob/fs"
+	"github.com/gravitational/gravity/lib/constants"
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/storage/keyval"
+	"github.com/gravitational/gravity/lib/utils"
+
+	"github.com/gravitational/trace"
+	log "github.com/sirupsen/logrus"
+)
+
+// BackupConfig defines the configuration of the state backup
+type BackupConfig struct {
+	// Backend is the state database to back up
+	Backend storage.Backend
+	// BackendType specifies the type of the state database
+	BackendType string
+	// Objects is the BLOB storage of the local package service
+	Objects blob.Objects
+	// TempDir specifies the directory for temporary files
+	TempDir string
+	// FieldLogger is used for logging
+	log.FieldLogger
+}
+
+// CheckAndSetDefaults validates the configuration and sets default values
+func (r *BackupConfig) CheckAndSetDefaults() error {
+	if r.Backend == nil {
+		return trace.BadParameter("missing Backend")
+	}
+	if r.Objects == nil {
+		return trace.BadParameter("missing Objects")
+	}
+	if r.BackendType == "" {
+		r.BackendType = constants.BoltBackend
+	}
+	if _, err := databaseFile(r.BackendType); err != nil {
+		return trace.Wrap(err)
+	}
+	if r.FieldLogger == nil {
+		r.FieldLogger = log.WithField(trace.Component, "state:backup")
+	}
+	return nil
+}
+
+// Backup writes a consistent backup of the state database and the packages
+// it references to w.
+//
+// The state database is snapshotted while in use so the backup can be taken
+// while gravity is running. Package BLOBs are content-addressed and never
+// modified in place, so the packages referenced by the snapshot are copied
+// after the snapshot has been taken.
+//
+// The backup is a tarball with the following layout:
+//
+//	manifest.json  - backup manifest
+//	gravity.db     - state database snapshot
+//	blobs/<hash>   - package BLOBs
+//
+// Returns the manifest of the written backup
+func Backup(config BackupConfig, w io.Writer) (*BackupManifest, error) {
+	if err := config.CheckAndSetDefaults(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	snapshotter, ok := config.Backend.(keyval.Snapshotter)
+	if !ok {
+		return nil, trace.BadParameter("%v backend does not support snapshots", config.BackendType)
+	}
+	dir, err := ioutil.TempDir(config.TempDir, "backup")
+	if err != nil {
+		return nil, trace.ConvertSystemError(err)
+	}
+	defer os.RemoveAll(dir)
+
+	dbFile, _ := databaseFile(config.BackendType)
+	dbPath := filepath.Join(dir, dbFile)
+	database, err := snapshotDatabase(snapshotter, dbPath)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	database.Path = dbFile
+	config.Infof("Snapshotted state database: %v bytes.", database.SizeBytes)
+
+	hashes, err := packageBLOBs(config.BackendType, dbPath)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	manifest := BackupManifest{
+		Version:  BackupVersion,
+		Created:  time.Now().UTC(),
+		Backend:  config.BackendType,
+		Database: *database,
+	}
+	manifest.Hostname, _ = os.Hostname()
+	for _, hash := range hashes {
+		envelope, err := config.Objects.GetBLOBEnvelope(hash)
+		if err != nil {
+			return nil, trace.Wrap(err, "failed to find package BLOB %v", hash)
+		}
+		manifest.BLOBs = append(manifest.BLOBs, BackupFile{
+			Path:      blobPath(hash),
+			SHA512:    hash,
+			SizeBytes: envelope.SizeBytes,
+		})
+	}
+
+	tw := tar.NewWriter(w)
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if err := writeItem(tw, archive.ItemFromStringMode(manifestFile, string(data), defaults.SharedReadMask)); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	f, err := os.Open(dbPath)
+	if err != nil {
+		return nil, trace.ConvertSystemError(err)
+	}
+	err = writeItem(tw, archive.ItemFromStream(database.Path, f, database.SizeBytes, defaults.PrivateFileMask))
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	for _, file := range manifest.BLOBs {
+		reader, err := config.Objects.OpenBLOB(file.SHA512)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		verifier := newVerifier(reader, file)
+		err = writeItem(tw, archive.ItemFromStream(file.Path, verifier, file.SizeBytes, defaults.SharedReadMask))
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		if err := verifier.verify(); err != nil {
+			return nil, trace.Wrap(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, trace.ConvertSystemError(err)
+	}
+	config.Infof("Backed up state database and %v package BLOBs.", len(manifest.BLOBs))
+	return &manifest, nil
+}
+
+// Verify verifies the integrity of the backup read from r.
+//
+// It validates the checksums of all files in the backup, makes sure the state
+// database snapshot can be opened and that the backup contains all packages
+// referenced by the snapshot.
+// Returns the manifest of the verified backup
+func Verify(r io.Reader, tempDir string) (*BackupManifest, error) {
+	dir, err := ioutil.TempDir(tempDir, "verify")
+	if err != nil {
+		return nil, trace.ConvertSystemError(err)
+	}
+	defer os.RemoveAll(dir)
+	manifest, err := unpack(r, dir)
+	return manifest, trace.Wrap(err)
+}
+
+// RestoreConfig defines the configuration of the state restore
+type RestoreConfig struct {
+	// StateDir is the local state directory to restore the backup into
+	StateDir string
+	// Objects is the BLOB storage of the local package service.
+	// If unspecified, the default package storage of the state directory is used
+	Objects blob.Objects
+	// FieldLogger is used for logging
+	log.FieldLogger
+}
+
+// CheckAndSetDefaults validates the configuration and sets default values
+func (r *RestoreConfig) CheckAndSetDefaults() error {
+	if r.StateDir == "" {
+		return trace.BadParameter("missing StateDir")
+	}
+	if r.FieldLogger == nil {
+		r.FieldLogger = log.WithField(trace.Component, "state:restore")
+	}
+	return nil
+}
+
+// Restore restores the backup read from r into the configured state directory.
+//
+// The backup is verified before any changes are made to the state directory.
+// The package BLOBs are added to the package storage and the existing state
+// database, if any, is replaced with the snapshot from the backup.
+// The replaced database is kept next to the restored one with a timestamp suffix.
+//
+// No gravity process should be using the state directory during restore.
+// Returns the manifest of the restored backup
+func Restore(config RestoreConfig, r io.Reader) (*BackupManifest, error) {
+	if err := config.CheckAndSetDefaults(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if err := os.MkdirAll(config.StateDir, defaults.PrivateDirMask); err != nil {
+		return nil, trace.ConvertSystemError(err)
+	}
+	if config.Objects == nil {
+		objects, err := fs.New(filepath.Join(config.StateDir, defaults.PackagesDir))
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		defer objects.Close()
+		config.Objects = objects
+	}
+	// unpack into the state directory so the database can be moved
+	// into place atomically
+	dir, err := ioutil.TempDir(config.StateDir, "restore")
+	if err != nil {
+		return nil, trace.ConvertSystemError(err)
+	}
+	defer os.RemoveAll(dir)
+	manifest, err := unpack(r, dir)
+	if err != nil {
+		return nil, trace.Wrap(err, "backup failed verification")
+	}
+	for _, file := range manifest.BLOBs {
+		if err := restoreBLOB(config.Objects, filepath.Join(dir, file.Path), file); err != nil {
+			return nil, trace.Wrap(err)
+		}
+	}
+	config.Infof("Restored %v package BLOBs.", len(manifest.BLOBs))
+	target := filepath.Join(config.StateDir, manifest.Database.Path)
+	suffix := fmt.Sprintf(".%v", time.Now().UTC().Format(backupSuffixFormat))
+	// move the existing database aside along with the SQLite journal files
+	// as the stale journal would otherwise be applied to the restored database
+	for _, path := range []string{target, target + "-wal", target + "-shm"} {
+		err := os.Rename(path, path+suffix)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, trace.ConvertSystemError(err)
+		}
+		if err == nil {
+			config.Infof("Moved %v to %v.", path, path+suffix)
+		}
+	}
+	err = os.Rename(filepath.Join(dir, manifest.Database.Path), target)
+	if err != nil {
+		return nil, trace.ConvertSystemError(err)
+	}
+	config.Infof("Restored state database %v from backup created at %v.", target, manifest.Created)
+	return manifest, nil
+}
+
+// BackupManifest describes the contents of the state backup
+type BackupManifest struct {
+	// Version is the backup format version
+	Version string `json:"version"`
+	// Created is the time the backup was taken
+	Created time.Time `json:"created"`
+	// Hostname is the name of the host the backup was taken on
+	Hostname string `json:"hostname,omitempty"`
+	// Backend is the type of the state database
+	Backend string `json:"backend"`
+	// Database describes the state database snapshot
+	Database BackupFile `json:"database"`
+	// BLOBs lists the package BLOBs
+	BLOBs []BackupFile `json:"blobs,omitempty"`
+}
+
+// BackupFile describes a single file in the backup
+type BackupFile struct {
+	// Path is the path of the file in the backup
+	Path string `json:"path"`
+	// SHA512 is the half SHA512 hash of the file contents
+	SHA512 string `json:"sha512"`
+	// SizeBytes is the file size in bytes
+	SizeBytes int64 `json:"size_bytes"`
+}
+
+func (r BackupManifest) check() error {
+	if r.Version != BackupVersion {
+		return trace.BadParameter("unsupported backup version %q", r.Version)
+	}
+	dbFile, err := databaseFile(r.Backend)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if r.Database.Path != dbFile {
+		return trace.BadParameter("unexpected database file %q", r.Database.Path)
+	}
+	for _, file := range r.BLOBs {
+		if !isHash(file.SHA512) || file.Path != blobPath(file.SHA512) {
+			return trace.BadParameter("unexpected BLOB file %q", file.Path)
+		}
+	}
+	return nil
+}
+
+// files returns all files described by the manifest indexed by path
+func (r BackupManifest) files() map[string]BackupFile {
+	files := make(map[string]BackupFile, len(r.BLOBs)+1)
+	files[r.Database.Path] = r.Database
+	for _, file := range r.BLOBs {
+		files[file.Path] = file
+	}
+	return files
+}
+
+// snapshotDatabase writes the snapshot of the database to path
+func snapshotDatabase(snapshotter keyval.Snapshotter, path string) (*BackupFile, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, defaults.PrivateFileMask)
+	if err != nil {
+		return nil, trace.ConvertSystemError(err)
+	}
+	defer f.Close()
+	hasher := sha512.New()
+	if err := snapshotter.Snapshot(io.MultiWriter(f, hasher)); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if err := f.Sync(); err != nil {
+		return nil, trace.ConvertSystemError(err)
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, trace.ConvertSystemError(err)
+	}
+	return &BackupFile{
+		SHA512:    hashString(hasher),
+		SizeBytes: fi.Size(),
+	}, nil
+}
+
+// packageBLOBs returns the sorted list of BLOBs referenced by the packages
+// in the database at the specified path
+func packageBLOBs(backendType, path string) ([]string, error) {
+	backend, err := openDatabase(backendType, path)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	defer backend.Close()
+	repositories, err := backend.GetRepositories()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	hashes := utils.NewStringSet()
+	for _, repository := range repositories {
+		packages, err := backend.GetPackages(repository.GetName())
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		for _, pkg := range packages {
+			hashes.Add(pkg.SHA512)
+		}
+	}
+	result := hashes.Slice()
+	sort.Strings(result)
+	return result, nil
+}
+
+func openDatabase(backendType, path string) (storage.Backend, error) {
+	switch backendType {
+	case constants.BoltBackend:
+		backend, err := keyval.NewBolt(keyval.BoltConfig{Path: path, Readonly: true})
+		return backend, trace.Wrap(err)
+	case constants.SQLiteBackend:
+		backend, err := keyval.NewSQL(keyval.SQLConfig{Path: path, Readonly: true})
+		return backend, trace.Wrap(err)
+	}
+	return nil, trace.BadParameter("unsupported backend type %q", backendType)
+}
+
+// unpack extracts the backup read from r into dir verifying the checksums
+// of all files and the consistency of the state database
+func unpack(r io.Reader, dir string) (*BackupManifest, error) {
+	tr := tar.NewReader(r)
+	header, err := tr.Next()
+	if err != nil {
+		return nil, trace.BadParameter("failed to read backup: %v", err)
+	}
+	if header.Name != manifestFile {
+		return nil, trace.BadParameter("backup does not start with %v", manifestFile)
+	}
+	var manifest BackupManifest
+	if err := json.NewDecoder(io.LimitReader(tr, maxManifestSize)).Decode(&manifest); err != nil {
+		return nil, trace.BadParameter("failed to read backup manifest: %v", err)
+	}
+	if err := manifest.check(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	files := manifest.files()
+	blobs := manifest.files()
+	if err := os.MkdirAll(filepath.Join(dir, blobsDir), defaults.PrivateDirMask); err != nil {
+		return nil, trace.ConvertSystemError(err)
+	}
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, trace.BadParameter("failed to read backup: %v", err)
+		}
+		file, ok := files[header.Name]
+		if !ok {
+			return nil, trace.BadParameter("unexpected file %v in backup", header.Name)
+		}
+		delete(files, header.Name)
+		if err := unpackFile(tr, filepath.Join(dir, file.Path), file); err != nil {
+			return nil, trace.Wrap(err)
+		}
+	}
+	for path := range files {
+		return nil, trace.NotFound("file %v is missing from backup", path)
+	}
+	hashes, err := packageBLOBs(manifest.Backend, filepath.Join(dir, manifest.Database.Path))
+	if err != nil {
+		return nil, trace.Wrap(err, "failed to read state database snapshot")
+	}
+	for _, hash := range hashes {
+		if _, ok := blobs[blobPath(hash)]; !ok {
+			return nil, trace.NotFound("package BLOB %v is missing from backup", hash)
+		}
+	}
+	return &manifest, nil
+}
+
+func unpackFile(r io.Reader, path string, file BackupFile) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, defaults.PrivateFileMask)
+	if err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	defer f.Close()
+	verifier := newVerifier(ioutil.NopCloser(r), file)
+	if _, err := io.Copy(f, verifier); err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	if err := verifier.verify(); err != nil {
+		return trace.Wrap(err)
+	}
+	return trace.ConvertSystemError(f.Sync())
+}
+
+func restoreBLOB(objects blob.Objects, path string, file BackupFile) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	defer f.Close()
+	envelope, err := objects.WriteBLOB(f)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if envelope.SHA512 != file.SHA512 {
+		return trace.BadParameter("checksum mismatch for BLOB %v: got %v", file.SHA512, envelope.SHA512)
+	}
+	return nil
+}
+
+func writeItem(tw *tar.Writer, item *archive.Item) error {
+	defer item.Data.Close()
+	item.ModTime = time.Now()
+	if err := tw.WriteHeader(&item.Header); err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	_, err := io.Copy(tw, item.Data)
+	return trace.ConvertSystemError(err)
+}
+
+func newVerifier(r io.ReadCloser, file BackupFile) *verifier {
+	hasher := sha512.New()
+	return &verifier{
+		ReadCloser: r,
+		reader:     io.TeeReader(r, hasher),
+		hasher:     hasher,
+		file:       file,
+	}
+}
+
+// verifier computes the checksum of the data read through it
+type verifier struct {
+	io.ReadCloser
+	reader io.Reader
+	hasher hash.Hash
+	read   int64
+	file   BackupFile
+}
+
+// Read reads the data from the underlying reader
+func (r *verifier) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.read += int64(n)
+	return n, err
+}
+
+// verify compares the size and the checksum of the data read so far
+// with the expected values
+func (r *verifier) verify() error {
+	if r.read != r.file.SizeBytes {
+		return trace.BadParameter("size mismatch for %v: expected %v bytes, got %v",
+			r.file.Path, r.file.SizeBytes, r.read)
+	}
+	if hash := hashString(r.hasher); hash != r.file.SHA512 {
+		return trace.BadParameter("checksum mismatch for %v: expected %v, got %v",
+			r.file.Path, r.file.SHA512, hash)
+	}
+	return nil
+}
+
+func hashString(hasher hash.Hash) string {
+	return fmt.Sprintf("%x", hasher.Sum(nil)[:sha512.Size/2])
+}
+
+// databaseFile returns the name of the database file for the specified backend type
+func databaseFile(backendType string) (string, error) {
+	switch backendType {
+	case constants.BoltBackend:
+		return defaults.GravityDBFile, nil
+	case constants.SQLiteBackend:
+		return defaults.GravitySQLiteFile, nil
+	}
+	return "", trace.BadParameter("unsupported backend type %q", backendType)
+}
+
+// isHash returns true if value is a half SHA512 hash in hex encoding
+func isHash(value string) bool {
+	if len(value) != sha512.Size {
+		return false
+	}
+	_, err := hex.DecodeString(value)
+	return err == nil
+}
+
+func blobPath(hash string) string {
+	return path.Join(blobsDir, hash)
+}
+
+const (
+	// BackupVersion is the current version of the backup format
+	BackupVersion = "v1"
+
+	// manifestFile is the name of the backup manifest file
+	manifestFile = "manifest.json"
+	// blobsDir is the backup directory with package BLOBs
+	blobsDir = "blobs"
+	// maxManifestSize limits the size of the backup manifest
+	maxManifestSize = 64 * 1024 * 1024
+	// backupSuffixFormat is the time format of the suffix
+	// of the state database replaced during restore
+	backupSuffixFormat = "20060102-150405"
+)
@@ -0,0 +1,207 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package state
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gravitational/gravity/lib/blob"
+	"github.com/gravitational/gravity/lib/blob/fs"
+	"github.com/gravitational/gravity/lib/constants"
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/loc"
+	"github.com/gravitational/gravity/lib/pack"
+	"github.com/gravitational/gravity/lib/pack/localpack"
+	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/storage/keyval"
+
+	"github.com/gravitational/trace"
+	"gopkg.in/check.v1"
+)
+
+func TestState(t *testing.T) { check.TestingT(t) }
+
+type BackupSuite struct {
+	dir string
+}
+
+var _ = check.Suite(&BackupSuite{})
+
+func (s *BackupSuite) SetUpTest(c *check.C) {
+	s.dir = c.MkDir()
+}
+
+func (s *BackupSuite) TestBackupAndRestore(c *check.C) {
+	s.testBackupAndRestore(c, constants.BoltBackend)
+}
+
+func (s *BackupSuite) TestBackupAndRestoreSQLite(c *check.C) {
+	s.testBackupAndRestore(c, constants.SQLiteBackend)
+}
+
+func (s *BackupSuite) testBackupAndRestore(c *check.C, backendType string) {
+	stateDir := filepath.Join(s.dir, "state")
+	packages, closer := newPackageService(c, stateDir, backendType)
+	locator := loc.MustParseLocator("gravitational.io/package:0.0.1")
+	data := []byte("package contents")
+	_, err := packages.CreatePackage(locator, bytes.NewReader(data))
+	c.Assert(err, check.IsNil)
+
+	var buf bytes.Buffer
+	manifest, err := Backup(BackupConfig{
+		Backend:     packages.backend,
+		BackendType: backendType,
+		Objects:     packages.objects,
+		TempDir:     s.dir,
+	}, &buf)
+	c.Assert(err, check.IsNil)
+	c.Assert(manifest.Backend, check.Equals, backendType)
+	c.Assert(manifest.BLOBs, check.HasLen, 1)
+	closer()
+
+	verified, err := Verify(bytes.NewReader(buf.Bytes()), s.dir)
+	c.Assert(err, check.IsNil)
+	c.Assert(verified.BLOBs, check.DeepEquals, manifest.BLOBs)
+
+	// restore on a replacement node
+	restoreDir := filepath.Join(s.dir, "restored")
+	_, err = Restore(RestoreConfig{StateDir: restoreDir}, bytes.NewReader(buf.Bytes()))
+	c.Assert(err, check.IsNil)
+
+	restored, closer := newPackageService(c, restoreDir, backendType)
+	defer closer()
+	_, reader, err := restored.ReadPackage(locator)
+	c.Assert(err, check.IsNil)
+	defer reader.Close()
+	contents, err := ioutil.ReadAll(reader)
+	c.Assert(err, check.IsNil)
+	c.Assert(contents, check.DeepEquals, data)
+}
+
+func (s *BackupSuite) TestRestoreReplacesDatabase(c *check.C) {
+	stateDir := filepath.Join(s.dir, "state")
+	packages, closer := newPackageService(c, stateDir, constants.BoltBackend)
+	locator := loc.MustParseLocator("gravitational.io/package:0.0.1")
+	_, err := packages.CreatePackage(locator, bytes.NewReader([]byte("data")))
+	c.Assert(err, check.IsNil)
+
+	var buf bytes.Buffer
+	_, err = Backup(BackupConfig{
+		Backend: packages.backend,
+		Objects: packages.objects,
+		TempDir: s.dir,
+	}, &buf)
+	c.Assert(err, check.IsNil)
+
+	// the package created after the backup is not restored
+	newLocator := loc.MustParseLocator("gravitational.io/package:0.0.2")
+	_, err = packages.CreatePackage(newLocator, bytes.NewReader([]byte("new data")))
+	c.Assert(err, check.IsNil)
+	closer()
+
+	_, err = Restore(RestoreConfig{StateDir: stateDir}, &buf)
+	c.Assert(err, check.IsNil)
+
+	restored, closer := newPackageService(c, stateDir, constants.BoltBackend)
+	defer closer()
+	_, err = restored.ReadPackageEnvelope(locator)
+	c.Assert(err, check.IsNil)
+	_, err = restored.ReadPackageEnvelope(newLocator)
+	c.Assert(trace.IsNotFound(err), check.Equals, true)
+
+	matches, err := filepath.Glob(filepath.Join(stateDir, defaults.GravityDBFile+".*"))
+	c.Assert(err, check.IsNil)
+	c.Assert(matches, check.HasLen, 1, check.Commentf("expected the replaced database to be kept"))
+}
+
+func (s *BackupSuite) TestVerifyDetectsCorruption(c *check.C) {
+	packages, closer := newPackageService(c, filepath.Join(s.dir, "state"), constants.BoltBackend)
+	defer closer()
+	data := bytes.Repeat([]byte("package contents"), 1024)
+	_, err := packages.CreatePackage(loc.MustParseLocator("gravitational.io/package:0.0.1"), bytes.NewReader(data))
+	c.Assert(err, check.IsNil)
+
+	var buf bytes.Buffer
+	_, err = Backup(BackupConfig{
+		Backend: packages.backend,
+		Objects: packages.objects,
+		TempDir: s.dir,
+	}, &buf)
+	c.Assert(err, check.IsNil)
+
+	corrupted := buf.Bytes()
+	offset := bytes.Index(corrupted, data)
+	c.Assert(offset > 0, check.Equals, true)
+	corrupted[offset] ^= 0xff
+
+	_, err = Verify(bytes.NewReader(corrupted), s.dir)
+	c.Assert(trace.IsBadParameter(err), check.Equals, true, check.Commentf("%v", err))
+
+	restoreDir := filepath.Join(s.dir, "restored")
+	_, err = Restore(RestoreConfig{StateDir: restoreDir}, bytes.NewReader(corrupted))
+	c.Assert(err, check.NotNil)
+	_, err = ioutil.ReadFile(filepath.Join(restoreDir, defaults.GravityDBFile))
+	c.Assert(err, check.NotNil, check.Commentf("expected the database not to be restored"))
+}
+
+func newPackageService(c *check.C, stateDir, backendType string) (*packageService, func()) {
+	c.Assert(os.MkdirAll(stateDir, defaults.SharedDirMask), check.IsNil)
+	var backend storage.Backend
+	var err error
+	switch backendType {
+	case constants.BoltBackend:
+		backend, err = keyval.NewBolt(keyval.BoltConfig{
+			Path: filepath.Join(stateDir, defaults.GravityDBFile),
+		})
+	case constants.SQLiteBackend:
+		backend, err = keyval.NewSQL(keyval.SQLConfig{
+			Path: filepath.Join(stateDir, defaults.GravitySQLiteFile),
+		})
+	default:
+		c.Fatalf("unsupported backend type %q", backendType)
+	}
+	c.Assert(err, check.IsNil)
+	objects, err := fs.New(filepath.Join(stateDir, defaults.PackagesDir))
+	c.Assert(err, check.IsNil)
+	packages, err := localpack.New(localpack.Config{
+		Backend:     backend,
+		Objects:     objects,
+		UnpackedDir: filepath.Join(stateDir, defaults.PackagesDir, defaults.UnpackedDir),
+	})
+	c.Assert(err, check.IsNil)
+	c.Assert(packages.UpsertRepository("gravitational.io", time.Time{}), check.IsNil)
+	service := &packageService{
+		PackageService: packages,
+		backend:        backend,
+		objects:        objects,
+	}
+	return service, func() {
+		backend.Close()
+		objects.Close()
+	}
+}
+
+type packageService struct {
+	pack.PackageService
+	backend storage.Backend
+	objects blob.Objects
+}
@@ -19,7 +19,6 @@ package cluster
 import (
 	"bytes"
 	"context"
-	"time"
 
 	"github.com/gravitational/gravity/lib/app"
 	"github.com/gravitational/gravity/lib/checks"
@@ -30,10 +29,8 @@ import (
 	"github.com/gravitational/gravity/lib/storage"
 	"github.com/gravitational/gravity/lib/update"
 	"github.com/gravitational/gravity/lib/users"
-	"github.com/gravitational/gravity/lib/utils"
 
 	"github.com/gravitational/trace"
-	"github.com/pborman/uuid"
 	"github.com/sirupsen/logrus"
 	"k8s.io/client-go/kubernetes"
 )
@@ -279,15 +276,8 @@ func (f *engine) activateCluster(cluster storage.Site) error {
 func (f *engine) ChangePhaseState(ctx context.Context, change fsm.StateChange) error {
 	f.WithField("change", change).Debug("Apply.")
 
-	_, err := f.LocalBackend.CreateOperationPlanChange(storage.PlanChange{
-		ID:          uuid.New(),
-		ClusterName: f.plan.ClusterName,
-		OperationID: f.plan.OperationID,
-		PhaseID:     change.Phase,
-		NewState:    change.State,
-		Error:       utils.ToRawTrace(change.Error),
-		Created:     time.Now().UTC(),
-	})
+	_, err := f.LocalBackend.CreateOperationPlanChange(
+		fsm.NewPlanChange(f.plan.ClusterName, f.plan.OperationID, change))
 	if err != nil {
 		f.WithError(err).Warnf("Error recording phase state change %+v.", change)
 		return trace.Wrap(err)
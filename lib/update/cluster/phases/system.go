@@ -18,6 +18,7 @@ package phases
 
 import (
 	"context"
+	"fmt"
 	"path/filepath"
 	"time"
 
@@ -29,6 +30,7 @@ import (
 	"github.com/gravitational/gravity/lib/pack"
 	"github.com/gravitational/gravity/lib/state"
 	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/systemservice"
 	"github.com/gravitational/gravity/lib/update"
 	"github.com/gravitational/gravity/lib/update/system"
 	"github.com/gravitational/gravity/lib/utils"
@@ -138,6 +140,36 @@ func (p *updatePhaseSystem) Execute(ctx context.Context) error {
 	return trace.Wrap(err)
 }
 
+// Describe returns the description of changes this phase makes
+func (p *updatePhaseSystem) Describe(ctx context.Context) (*fsm.PhaseDescription, error) {
+	description := &fsm.PhaseDescription{
+		Servers:  []string{p.Server.Hostname},
+		Packages: []loc.Locator{p.GravityPackage},
+		Actions:  []string{fmt.Sprintf("Update gravity binary to %v", p.GravityPackage.Version)},
+	}
+	runtime := p.Server.Runtime.Installed
+	if p.Server.Runtime.Update != nil {
+		runtime = p.Server.Runtime.Update.Package
+		description.Packages = append(description.Packages,
+			runtime, p.Server.Runtime.Update.ConfigPackage)
+		description.Actions = append(description.Actions, fmt.Sprintf(
+			"Update runtime from %v to %v", p.Server.Runtime.Installed, runtime))
+	}
+	description.Units = append(description.Units, systemservice.PackageServiceName(runtime))
+	if p.Server.Teleport.Update != nil {
+		teleport := p.Server.Teleport.Update.Package
+		description.Packages = append(description.Packages,
+			teleport, p.Server.Teleport.Update.NodeConfigPackage)
+		description.Units = append(description.Units, systemservice.PackageServiceName(teleport))
+		description.Actions = append(description.Actions, fmt.Sprintf(
+			"Update teleport from %v to %v", p.Server.Teleport.Installed, teleport))
+	}
+	if p.Server.Runtime.SecretsPackage != nil {
+		description.Packages = append(description.Packages, *p.Server.Runtime.SecretsPackage)
+	}
+	return description, nil
+}
+
 // Rollback runs rolls back the system upgrade on the node
 func (p *updatePhaseSystem) Rollback(ctx context.Context) error {
 	updater, err := system.New(system.Config{
@@ -28,6 +28,7 @@ import (
 	"github.com/gravitational/gravity/lib/archive"
 	"github.com/gravitational/gravity/lib/constants"
 	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/fsm"
 	"github.com/gravitational/gravity/lib/loc"
 	"github.com/gravitational/gravity/lib/localenv"
 	"github.com/gravitational/gravity/lib/ops"
@@ -100,6 +101,11 @@ func InitOperationPlan(
 		return nil, trace.Wrap(err)
 	}
 
+	err = fsm.CheckPlan(*plan)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+
 	_, err = clusterEnv.Backend.CreateOperationPlan(*plan)
 	if err != nil {
 		return nil, trace.Wrap(err)
@@ -28,7 +28,6 @@ import (
 	"github.com/gravitational/gravity/lib/utils"
 
 	"github.com/gravitational/trace"
-	"github.com/pborman/uuid"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -39,8 +38,9 @@ func NewMachine(ctx context.Context, config Config, engine *Engine) (*fsm.FSM, e
 		return nil, trace.Wrap(err)
 	}
 	machine, err := fsm.New(fsm.Config{
-		Engine: engine,
-		Runner: config.Runner,
+		Engine:            engine,
+		Runner:            config.Runner,
+		ParallelPerServer: config.ParallelPerServer,
 	})
 	if err != nil {
 		return nil, trace.Wrap(err)
@@ -140,15 +140,8 @@ func (r *Engine) Complete(fsmErr error) error {
 // ChangePhaseState creates a new changelog entry
 func (r *Engine) ChangePhaseState(ctx context.Context, change fsm.StateChange) error {
 	r.WithField("change", change).Debug("Apply.")
-	_, err := r.LocalBackend.CreateOperationPlanChange(storage.PlanChange{
-		ID:          uuid.New(),
-		ClusterName: r.Operation.SiteDomain,
-		OperationID: r.Operation.ID,
-		PhaseID:     change.Phase,
-		NewState:    change.State,
-		Error:       utils.ToRawTrace(change.Error),
-		Created:     time.Now().UTC(),
-	})
+	_, err := r.LocalBackend.CreateOperationPlanChange(
+		fsm.NewPlanChange(r.Operation.SiteDomain, r.Operation.ID, change))
 	if err != nil {
 		return trace.Wrap(err)
 	}
@@ -99,6 +99,22 @@ func (r *Updater) RunPhase(ctx context.Context, phase string, phaseTimeout time.
 	}))
 }
 
+// SimulatePhase reports what the specified phase would do
+// without executing it.
+func (r *Updater) SimulatePhase(ctx context.Context, phase string, phaseTimeout time.Duration, force bool) (*fsm.SimulationReport, error) {
+	ctx, cancel := context.WithTimeout(ctx, phaseTimeout)
+	defer cancel()
+
+	report, err := r.machine.SimulatePhase(ctx, fsm.Params{
+		PhaseID: phase,
+		Force:   force,
+	})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return report, nil
+}
+
 // RollbackPhase rolls back the specified phase.
 func (r *Updater) RollbackPhase(ctx context.Context, phase string, phaseTimeout time.Duration, force bool) error {
 	ctx, cancel := context.WithTimeout(ctx, phaseTimeout)
@@ -221,6 +237,9 @@ type Config struct {
 	log.FieldLogger
 	// Silent controls whether the process outputs messages to stdout
 	localenv.Silent
+	// ParallelPerServer optionally enables concurrent plan execution
+	// with the specified number of phases executing per server
+	ParallelPerServer int
 }
 
 // Updater manages the operation specified with machine
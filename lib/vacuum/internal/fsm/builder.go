@@ -24,24 +24,29 @@ import (
 	"github.com/gravitational/gravity/lib/ops"
 	"github.com/gravitational/gravity/lib/storage"
 	libphase "github.com/gravitational/gravity/lib/vacuum/internal/phases"
+	prunestate "github.com/gravitational/gravity/lib/vacuum/prune/state"
 
 	"github.com/gravitational/trace"
 )
 
 // NewOperationPlan returns a new plan for the specified operation
 // and the given set of servers
-func NewOperationPlan(operation ops.SiteOperation, servers []storage.Server, remoteApps []storage.Application) (*storage.OperationPlan, error) {
+func NewOperationPlan(operation ops.SiteOperation, servers []storage.Server, remoteApps []storage.Application, retention prunestate.Retention) (*storage.OperationPlan, error) {
 	masters, _ := libfsm.SplitServers(servers)
 	if len(masters) == 0 {
 		return nil, trace.NotFound("no master servers found in cluster state")
 	}
 
-	builder := phaseBuilder{remoteApps: remoteApps}
+	builder := phaseBuilder{
+		remoteApps: remoteApps,
+		retention:  retention,
+	}
 
 	registry := *builder.registry(masters)
 	packages := *builder.packages(servers)
 	journals := *builder.journals(servers)
-	phases := phases{registry, packages, journals}
+	state := *builder.state(servers)
+	phases := phases{registry, packages, journals, state}
 
 	plan := &storage.OperationPlan{
 		OperationID:   operation.ID,
@@ -61,6 +66,9 @@ func (r phaseBuilder) registry(masters []storage.Server) *phase {
 		Description: "Prune unused docker images",
 	})
 
+	// Each master runs its own registry which is stopped while its storage
+	// is collected, so the masters are processed one at a time to keep
+	// the other registries available
 	for i, master := range masters {
 		node := r.node(master, root, "Prune unused docker images on node %q")
 		node.Data = &storage.OperationPhaseData{
@@ -116,6 +124,36 @@ func (r phaseBuilder) journals(servers []storage.Server) *phase {
 	return &root
 }
 
+func (r phaseBuilder) state(servers []storage.Server) *phase {
+	root := root(phase{
+		ID:          libphase.State,
+		Description: "Prune stale operation data and leftover state",
+	})
+
+	root.AddParallel(r.clusterState(root))
+	for i, server := range servers {
+		node := r.node(server, root, "Prune leftover state on node %q")
+		node.Data = &storage.OperationPhaseData{
+			Server: &servers[i],
+		}
+		root.AddParallel(node)
+	}
+	return &root
+}
+
+func (r phaseBuilder) clusterState(parent phase) phase {
+	return phase{
+		ID:          parent.ChildLiteral("cluster"),
+		Description: "Prune stale operation data and expired tokens",
+		Data: &storage.OperationPhaseData{
+			GarbageCollect: &storage.GarbageCollectOperationData{
+				KeepOperations: r.retention.Operations,
+				KeepPeriod:     r.retention.Period,
+			},
+		},
+	}
+}
+
 func (r phaseBuilder) node(server storage.Server, parent phase, format string) phase {
 	return phase{
 		ID:          parent.ChildLiteral(server.Hostname),
@@ -125,6 +163,7 @@ func (r phaseBuilder) node(server storage.Server, parent phase, format string) p
 
 type phaseBuilder struct {
 	remoteApps []storage.Application
+	retention  prunestate.Retention
 }
 
 // AddSequential will append sub-phases which depend one upon another
@@ -18,12 +18,14 @@ package fsm
 
 import (
 	"testing"
+	"time"
 
 	"github.com/gravitational/gravity/lib/compare"
 	"github.com/gravitational/gravity/lib/loc"
 	"github.com/gravitational/gravity/lib/ops"
 	"github.com/gravitational/gravity/lib/schema"
 	"github.com/gravitational/gravity/lib/storage"
+	prunestate "github.com/gravitational/gravity/lib/vacuum/prune/state"
 
 	. "gopkg.in/check.v1"
 )
@@ -50,7 +52,7 @@ func (S) TestSingleNodePlan(c *C) {
 		},
 	}
 
-	plan, err := NewOperationPlan(operation, servers, remoteApps)
+	plan, err := NewOperationPlan(operation, servers, remoteApps, prunestate.Retention{})
 	c.Assert(err, IsNil)
 	c.Assert(plan, compare.DeepEquals, &storage.OperationPlan{
 		OperationID:   operation.ID,
@@ -107,6 +109,26 @@ func (S) TestSingleNodePlan(c *C) {
 					},
 				},
 			},
+			{
+				ID:          "/state",
+				Description: "Prune stale operation data and leftover state",
+				Phases: []storage.OperationPhase{
+					{
+						ID:          "/state/cluster",
+						Description: "Prune stale operation data and expired tokens",
+						Data: &storage.OperationPhaseData{
+							GarbageCollect: &storage.GarbageCollectOperationData{},
+						},
+					},
+					{
+						ID:          "/state/node-1",
+						Description: `Prune leftover state on node "node-1"`,
+						Data: &storage.OperationPhaseData{
+							Server: &servers[0],
+						},
+					},
+				},
+			},
 		},
 	})
 }
@@ -129,7 +151,12 @@ func (S) TestMultiNodePlan(c *C) {
 		},
 	}
 
-	plan, err := NewOperationPlan(operation, servers, remoteApps)
+	retention := prunestate.Retention{
+		Operations: 5,
+		Period:     24 * time.Hour,
+	}
+
+	plan, err := NewOperationPlan(operation, servers, remoteApps, retention)
 	c.Assert(err, IsNil)
 	c.Assert(plan, compare.DeepEquals, &storage.OperationPlan{
 		OperationID:   operation.ID,
@@ -222,6 +249,43 @@ func (S) TestMultiNodePlan(c *C) {
 					},
 				},
 			},
+			{
+				ID:          "/state",
+				Description: "Prune stale operation data and leftover state",
+				Phases: []storage.OperationPhase{
+					{
+						ID:          "/state/cluster",
+						Description: "Prune stale operation data and expired tokens",
+						Data: &storage.OperationPhaseData{
+							GarbageCollect: &storage.GarbageCollectOperationData{
+								KeepOperations: 5,
+								KeepPeriod:     24 * time.Hour,
+							},
+						},
+					},
+					{
+						ID:          "/state/node-1",
+						Description: `Prune leftover state on node "node-1"`,
+						Data: &storage.OperationPhaseData{
+							Server: &servers[0],
+						},
+					},
+					{
+						ID:          "/state/node-2",
+						Description: `Prune leftover state on node "node-2"`,
+						Data: &storage.OperationPhaseData{
+							Server: &servers[1],
+						},
+					},
+					{
+						ID:          "/state/node-3",
+						Description: `Prune leftover state on node "node-3"`,
+						Data: &storage.OperationPhaseData{
+							Server: &servers[2],
+						},
+					},
+				},
+			},
 		},
 	})
 }
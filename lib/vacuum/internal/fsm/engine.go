@@ -32,7 +32,6 @@ import (
 	libphase "github.com/gravitational/gravity/lib/vacuum/internal/phases"
 
 	"github.com/gravitational/trace"
-	"github.com/pborman/uuid"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -174,15 +173,7 @@ func (r *engine) Complete(fsmErr error) error {
 // ChangePhaseState creates an new changelog entry
 func (r *engine) ChangePhaseState(ctx context.Context, change libfsm.StateChange) error {
 	err := r.Operator.CreateOperationPlanChange(r.Operation.Key(),
-		storage.PlanChange{
-			ID:          uuid.New(),
-			ClusterName: r.Operation.SiteDomain,
-			OperationID: r.Operation.ID,
-			PhaseID:     change.Phase,
-			NewState:    change.State,
-			Error:       utils.ToRawTrace(change.Error),
-			Created:     time.Now().UTC(),
-		})
+		libfsm.NewPlanChange(r.Operation.SiteDomain, r.Operation.ID, change))
 	if err != nil {
 		return trace.Wrap(err)
 	}
@@ -252,6 +243,16 @@ func configToExecutor(config Config) libfsm.FSMSpecFunc {
 				config.LocalPackages,
 				config.Silent, logger)
 
+		case params.Phase.ID == libphase.ClusterState:
+			return libphase.NewClusterState(params, config.Silent, logger)
+
+		case strings.HasPrefix(params.Phase.ID, libphase.State):
+			return libphase.NewNodeState(
+				params,
+				config.LocalPackages,
+				config.Packages,
+				config.Silent, logger)
+
 		case strings.HasPrefix(params.Phase.ID, libphase.Registry):
 			return libphase.NewRegistry(
 				params,
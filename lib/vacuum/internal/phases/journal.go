@@ -18,6 +18,7 @@ package phases
 
 import (
 	"context"
+	"fmt"
 	"path/filepath"
 
 	"github.com/gravitational/gravity/lib/constants"
@@ -57,6 +58,7 @@ func NewJournal(params libfsm.ExecutorParams, runtimePath string, silent localen
 	return &journalExecutor{
 		FieldLogger: logger,
 		Pruner:      pruner,
+		logDir:      logDir,
 	}, nil
 }
 
@@ -66,6 +68,13 @@ func (r *journalExecutor) Execute(ctx context.Context) error {
 	return trace.Wrap(err)
 }
 
+// Describe returns the description of changes this phase makes
+func (r *journalExecutor) Describe(context.Context) (*libfsm.PhaseDescription, error) {
+	return &libfsm.PhaseDescription{
+		Actions: []string{fmt.Sprintf("Remove obsolete systemd journal directories in %v", r.logDir)},
+	}, nil
+}
+
 // PreCheck is a no-op
 func (r *journalExecutor) PreCheck(context.Context) error {
 	return nil
@@ -86,4 +95,6 @@ type journalExecutor struct {
 	log.FieldLogger
 	// Pruner is the actual clean up implementation
 	prune.Pruner
+	// logDir is the journal log directory
+	logDir string
 }
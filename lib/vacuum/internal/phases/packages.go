@@ -18,8 +18,10 @@ package phases
 
 import (
 	"context"
+	"fmt"
 
 	libfsm "github.com/gravitational/gravity/lib/fsm"
+	"github.com/gravitational/gravity/lib/loc"
 	"github.com/gravitational/gravity/lib/localenv"
 	libpack "github.com/gravitational/gravity/lib/pack"
 	"github.com/gravitational/gravity/lib/storage"
@@ -58,6 +60,7 @@ func NewPackages(
 	return &packageExecutor{
 		FieldLogger: logger,
 		Pruner:      pruner,
+		app:         app.Locator,
 	}, nil
 }
 
@@ -67,6 +70,13 @@ func (r *packageExecutor) Execute(ctx context.Context) error {
 	return trace.Wrap(err)
 }
 
+// Describe returns the description of changes this phase makes
+func (r *packageExecutor) Describe(context.Context) (*libfsm.PhaseDescription, error) {
+	return &libfsm.PhaseDescription{
+		Actions: []string{fmt.Sprintf("Remove packages not used by application %v", r.app)},
+	}, nil
+}
+
 // PreCheck is a no-op
 func (r *packageExecutor) PreCheck(context.Context) error {
 	return nil
@@ -87,4 +97,6 @@ type packageExecutor struct {
 	log.FieldLogger
 	// Pruner is the actual clean up implementation
 	prune.Pruner
+	// app is the cluster application
+	app loc.Locator
 }
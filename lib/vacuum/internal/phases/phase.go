@@ -26,4 +26,9 @@ const (
 	ClusterPackages = "/packages/cluster"
 	// Registry is the phase to remove unused docker images
 	Registry = "/registry"
+	// State is the phase to remove stale operation data and leftover node state
+	State = "/state"
+	// ClusterState is the sub-phase to remove stale operation data
+	// and expired provisioning tokens from the cluster backend
+	ClusterState = "/state/cluster"
 )
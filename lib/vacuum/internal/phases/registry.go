@@ -18,6 +18,7 @@ package phases
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/gravitational/gravity/lib/app"
 	"github.com/gravitational/gravity/lib/app/docker"
@@ -59,7 +60,12 @@ func NewRegistry(
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
+	var node string
+	if params.Phase.Data != nil && params.Phase.Data.Server != nil {
+		node = params.Phase.Data.Server.Hostname
+	}
 	pruner, err := registry.New(registry.Config{
+		Node:         node,
 		App:          &clusterApp,
 		Apps:         clusterApps,
 		Packages:     clusterPackages,
@@ -76,6 +82,7 @@ func NewRegistry(
 		FieldLogger: logger,
 		Pruner:      pruner,
 		silent:      silent,
+		app:         clusterApp,
 	}, nil
 }
 
@@ -85,6 +92,17 @@ func (r *registryExecutor) Execute(ctx context.Context) error {
 	return trace.Wrap(err)
 }
 
+// Describe returns the description of changes this phase makes
+func (r *registryExecutor) Describe(context.Context) (*libfsm.PhaseDescription, error) {
+	return &libfsm.PhaseDescription{
+		Actions: []string{
+			fmt.Sprintf("Remove docker images not used by application %v from registry %v",
+				r.app, constants.LocalRegistryAddr),
+			"Stop the registry and remove the blobs no longer referenced by any image from its storage",
+		},
+	}, nil
+}
+
 // PreCheck is a no-op
 func (r *registryExecutor) PreCheck(context.Context) error {
 	return nil
@@ -106,4 +124,6 @@ type registryExecutor struct {
 	// Pruner is the actual clean up implementation
 	prune.Pruner
 	silent localenv.Silent
+	// app is the cluster application
+	app loc.Locator
 }
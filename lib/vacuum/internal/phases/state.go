@@ -0,0 +1,206 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package phases
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"github.com/gravitational/gravity/lib/defaults"
+	libfsm "github.com/gravitational/gravity/lib/fsm"
+	"github.com/gravitational/gravity/lib/localenv"
+	"github.com/gravitational/gravity/lib/pack"
+	"github.com/gravitational/gravity/lib/state"
+	"github.com/gravitational/gravity/lib/vacuum/prune"
+	prunestate "github.com/gravitational/gravity/lib/vacuum/prune/state"
+
+	"github.com/gravitational/trace"
+	log "github.com/sirupsen/logrus"
+)
+
+// NewClusterState returns a new executor to remove stale operation data
+// and expired provisioning tokens from the cluster backend.
+// The executor can only run on a master node as it accesses
+// the cluster backend directly
+func NewClusterState(params libfsm.ExecutorParams, silent localenv.Silent, logger log.FieldLogger) (*clusterStateExecutor, error) {
+	var retention prunestate.Retention
+	if params.Phase.Data != nil && params.Phase.Data.GarbageCollect != nil {
+		retention = prunestate.Retention{
+			Operations: params.Phase.Data.GarbageCollect.KeepOperations,
+			Period:     params.Phase.Data.GarbageCollect.KeepPeriod,
+		}
+	}
+	logDir, err := localenv.SiteDir()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &clusterStateExecutor{
+		FieldLogger: logger,
+		config: prunestate.ClusterConfig{
+			ClusterName:     params.Plan.ClusterName,
+			OperationLogDir: logDir,
+			Retention:       retention,
+			Config: prune.Config{
+				Silent:      silent,
+				FieldLogger: logger,
+			},
+		},
+	}, nil
+}
+
+// Execute executes phase
+func (r *clusterStateExecutor) Execute(ctx context.Context) error {
+	env, err := localenv.NewClusterEnvironment()
+	if err != nil {
+		return trace.Wrap(err, "stale cluster state can only be pruned on a master node")
+	}
+	defer env.Backend.Close()
+	config := r.config
+	config.Backend = env.Backend
+	pruner, err := prunestate.NewCluster(config)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return trace.Wrap(pruner.Prune(ctx))
+}
+
+// Describe returns the description of changes this phase makes
+func (r *clusterStateExecutor) Describe(context.Context) (*libfsm.PhaseDescription, error) {
+	retention := r.config.Retention
+	retention.CheckAndSetDefaults()
+	return &libfsm.PhaseDescription{
+		Actions: []string{
+			fmt.Sprintf("Prune plan changelogs, progress entries and logs of operations except the %v", retention),
+			"Remove expired provisioning tokens",
+		},
+	}, nil
+}
+
+// PreCheck is a no-op
+func (r *clusterStateExecutor) PreCheck(context.Context) error {
+	return nil
+}
+
+// PostCheck is a no-op
+func (r *clusterStateExecutor) PostCheck(context.Context) error {
+	return nil
+}
+
+// Rollback is a no-op
+func (r *clusterStateExecutor) Rollback(context.Context) error {
+	return nil
+}
+
+type clusterStateExecutor struct {
+	// FieldLogger is the logger the executor uses
+	log.FieldLogger
+	// config is the pruner configuration.
+	// The backend is set when the phase is executed
+	config prunestate.ClusterConfig
+}
+
+// NewNodeState returns a new executor to remove leftover state on a node:
+// unpacked directories of deleted packages and the directory of the RPC agent
+// that is no longer running
+func NewNodeState(
+	params libfsm.ExecutorParams,
+	localPackages pack.PackageService,
+	clusterPackages pack.PackageService,
+	silent localenv.Silent,
+	logger log.FieldLogger,
+) (*nodeStateExecutor, error) {
+	stateDir, err := state.GetStateDir()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	siteUnpackedDir, err := localenv.SiteUnpackedDir()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	unpackedDirs := []prunestate.UnpackedDir{
+		{
+			Dir:      filepath.Join(stateDir, defaults.LocalDir, defaults.PackagesDir, defaults.UnpackedDir),
+			Packages: localPackages,
+		},
+		{
+			Dir:      siteUnpackedDir,
+			Packages: clusterPackages,
+		},
+	}
+	agentDir := state.GravityRPCAgentDir(stateDir)
+	pruner, err := prunestate.NewNode(prunestate.NodeConfig{
+		UnpackedDirs: unpackedDirs,
+		AgentDir:     agentDir,
+		Config: prune.Config{
+			Silent:      silent,
+			FieldLogger: logger,
+		},
+	})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+
+	return &nodeStateExecutor{
+		FieldLogger:  logger,
+		Pruner:       pruner,
+		unpackedDirs: unpackedDirs,
+		agentDir:     agentDir,
+	}, nil
+}
+
+// Execute executes phase
+func (r *nodeStateExecutor) Execute(ctx context.Context) error {
+	err := r.Prune(ctx)
+	return trace.Wrap(err)
+}
+
+// Describe returns the description of changes this phase makes
+func (r *nodeStateExecutor) Describe(context.Context) (*libfsm.PhaseDescription, error) {
+	var actions []string
+	for _, dir := range r.unpackedDirs {
+		actions = append(actions, fmt.Sprintf("Remove unpacked directories of deleted packages in %v", dir.Dir))
+	}
+	actions = append(actions, fmt.Sprintf("Remove agent directory %v unless the agent is running", r.agentDir))
+	return &libfsm.PhaseDescription{Actions: actions}, nil
+}
+
+// PreCheck is a no-op
+func (r *nodeStateExecutor) PreCheck(context.Context) error {
+	return nil
+}
+
+// PostCheck is a no-op
+func (r *nodeStateExecutor) PostCheck(context.Context) error {
+	return nil
+}
+
+// Rollback is a no-op
+func (r *nodeStateExecutor) Rollback(context.Context) error {
+	return nil
+}
+
+type nodeStateExecutor struct {
+	// FieldLogger is the logger the executor uses
+	log.FieldLogger
+	// Pruner is the actual clean up implementation
+	prune.Pruner
+	// unpackedDirs lists the directories with unpacked packages
+	unpackedDirs []prunestate.UnpackedDir
+	// agentDir is the directory of the RPC agent
+	agentDir string
+}
@@ -30,7 +30,7 @@ func (r *Collector) getOrCreateOperationPlan() (plan *storage.OperationPlan, err
 	}
 
 	if trace.IsNotFound(err) {
-		plan, err = fsm.NewOperationPlan(*r.Operation, r.Servers, r.RemoteApps)
+		plan, err = r.newOperationPlan()
 		if err != nil {
 			return nil, trace.Wrap(err)
 		}
@@ -47,3 +47,24 @@ func (r *Collector) getOrCreateOperationPlan() (plan *storage.OperationPlan, err
 
 	return plan, nil
 }
+
+// getOrGenerateOperationPlan returns the existing operation plan
+// or generates a new one without persisting it
+func (r *Collector) getOrGenerateOperationPlan() (*storage.OperationPlan, error) {
+	plan, err := r.Operator.GetOperationPlan(r.Operation.Key())
+	if err == nil {
+		return plan, nil
+	}
+	if !trace.IsNotFound(err) {
+		return nil, trace.Wrap(err)
+	}
+	return r.newOperationPlan()
+}
+
+func (r *Collector) newOperationPlan() (*storage.OperationPlan, error) {
+	plan, err := fsm.NewOperationPlan(*r.Operation, r.Servers, r.RemoteApps, r.Retention)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return plan, nil
+}
@@ -0,0 +1,303 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import (
+	"context"
+	"sort"
+
+	"github.com/gravitational/gravity/lib/app/docker"
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/vacuum/prune"
+
+	"github.com/docker/distribution"
+	"github.com/docker/distribution/manifest/manifestlist"
+	"github.com/docker/distribution/reference"
+	registrystorage "github.com/docker/distribution/registry/storage"
+	"github.com/docker/distribution/registry/storage/driver"
+	"github.com/docker/distribution/registry/storage/driver/filesystem"
+	"github.com/gravitational/trace"
+	"github.com/opencontainers/go-digest"
+	log "github.com/sirupsen/logrus"
+)
+
+// collectorConfig describes configuration of the registry garbage collector
+type collectorConfig struct {
+	// Config specifies the common pruner configuration
+	prune.Config
+	// Dir specifies the root directory of the registry storage
+	Dir string
+	// Keep lists the tags to retain.
+	// All other tags are removed
+	Keep []docker.TagSpec
+}
+
+// gcResult describes the outcome of the garbage collection
+type gcResult struct {
+	// Tags is the number of removed tags
+	Tags int
+	// Manifests is the number of removed manifests
+	Manifests int
+	// Blobs is the number of removed blobs
+	Blobs int
+	// Bytes is the total size of removed blobs
+	Bytes int64
+}
+
+// collectGarbage performs a mark and sweep over the registry storage
+// in the configured directory.
+//
+// In the mark phase, tags not listed in config.Keep are removed together
+// with the manifests they no longer reference, and all blobs referenced by
+// the remaining manifests are marked.
+// In the sweep phase, the blobs that have not been marked are deleted.
+//
+// The registry serving this storage must not accept writes while the
+// collector is running: an upload in progress has blobs not yet referenced
+// by any manifest and these would be swept
+func collectGarbage(ctx context.Context, config collectorConfig) (*gcResult, error) {
+	if config.FieldLogger == nil {
+		config.FieldLogger = log.WithField(trace.Component, "gc:registry")
+	}
+	storageDriver, registry, err := openStorage(ctx, config.Dir, registrystorage.EnableDelete)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	c := &collector{
+		collectorConfig: config,
+		driver:          storageDriver,
+		registry:        registry,
+		keep:            make(map[docker.TagSpec]struct{}),
+		marked:          make(map[digest.Digest]struct{}),
+	}
+	for _, tag := range config.Keep {
+		c.keep[tag] = struct{}{}
+	}
+	if err := c.mark(ctx); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if err := c.sweep(ctx); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &c.result, nil
+}
+
+// mark removes the obsolete tags and manifests and marks the blobs
+// referenced by the remaining manifests in all repositories
+func (r *collector) mark(ctx context.Context) error {
+	enumerator, ok := r.registry.(distribution.RepositoryEnumerator)
+	if !ok {
+		return trace.BadParameter("registry does not support repository enumeration")
+	}
+	// Collect the repositories first as marking modifies the storage
+	var repoNames []string
+	err := enumerator.Enumerate(ctx, func(repoName string) error {
+		repoNames = append(repoNames, repoName)
+		return nil
+	})
+	if err != nil && !isPathNotFound(err) {
+		return trace.Wrap(err, "failed to enumerate registry repositories")
+	}
+	var emptyRepos []string
+	for _, repoName := range repoNames {
+		empty, err := r.markRepository(ctx, repoName)
+		if err != nil {
+			return trace.Wrap(err, "failed to mark blobs in repository %v", repoName)
+		}
+		if empty {
+			emptyRepos = append(emptyRepos, repoName)
+		}
+	}
+	if r.DryRun {
+		return nil
+	}
+	vacuum := registrystorage.NewVacuum(ctx, r.driver)
+	for _, repoName := range emptyRepos {
+		r.WithField("repository", repoName).Debug("Remove empty repository.")
+		if err := vacuum.RemoveRepository(repoName); err != nil && !isPathNotFound(err) {
+			return trace.Wrap(err, "failed to remove repository %v", repoName)
+		}
+	}
+	return nil
+}
+
+// markRepository removes the tags of the repository specified with repoName
+// that are not retained along with the manifests no longer referenced by tags.
+// It marks the remaining manifests and the blobs they reference.
+// Returns true if no tags are left in the repository
+func (r *collector) markRepository(ctx context.Context, repoName string) (empty bool, err error) {
+	named, err := reference.WithName(repoName)
+	if err != nil {
+		return false, trace.Wrap(err, "invalid repository name %q", repoName)
+	}
+	repo, err := r.registry.Repository(ctx, named)
+	if err != nil {
+		return false, trace.Wrap(err)
+	}
+	manifests, err := repo.Manifests(ctx)
+	if err != nil {
+		return false, trace.Wrap(err)
+	}
+	tagService := repo.Tags(ctx)
+	tags, err := tagService.All(ctx)
+	if err != nil && !isPathNotFound(err) && !isRepositoryUnknown(err) {
+		return false, trace.Wrap(err)
+	}
+	logger := r.WithField("repository", repoName)
+	// referenced lists manifests referenced by the retained tags in this repository
+	referenced := make(map[digest.Digest]struct{})
+	var retained int
+	for _, tag := range tags {
+		tagSpec := docker.TagSpec{Name: repoName, Version: tag}
+		if _, ok := r.keep[tagSpec]; !ok {
+			r.PrintStep("Remove image %v.", tagSpec)
+			r.result.Tags++
+			if r.DryRun {
+				continue
+			}
+			if err := tagService.Untag(ctx, tag); err != nil {
+				return false, trace.Wrap(err, "failed to remove tag %v", tagSpec)
+			}
+			continue
+		}
+		retained++
+		desc, err := tagService.Get(ctx, tag)
+		if err != nil {
+			return false, trace.Wrap(err, "failed to resolve tag %v", tagSpec)
+		}
+		if err := r.markManifest(ctx, manifests, desc.Digest, referenced); err != nil {
+			return false, trace.Wrap(err)
+		}
+	}
+
+	enumerator, ok := manifests.(distribution.ManifestEnumerator)
+	if !ok {
+		return false, trace.BadParameter("registry does not support manifest enumeration")
+	}
+	var obsolete []digest.Digest
+	err = enumerator.Enumerate(ctx, func(dgst digest.Digest) error {
+		if _, ok := referenced[dgst]; !ok {
+			obsolete = append(obsolete, dgst)
+		}
+		return nil
+	})
+	if err != nil && !isPathNotFound(err) {
+		return false, trace.Wrap(err)
+	}
+	for _, dgst := range obsolete {
+		logger.WithField("manifest", dgst).Debug("Remove unreferenced manifest.")
+		r.result.Manifests++
+		if r.DryRun {
+			continue
+		}
+		if err := manifests.Delete(ctx, dgst); err != nil && !isPathNotFound(err) {
+			return false, trace.Wrap(err, "failed to remove manifest %v in %v", dgst, repoName)
+		}
+	}
+	return retained == 0, nil
+}
+
+// markManifest marks the manifest given with dgst and the blobs it references.
+// Manifests of a manifest list are marked recursively
+func (r *collector) markManifest(ctx context.Context, manifests distribution.ManifestService, dgst digest.Digest, referenced map[digest.Digest]struct{}) error {
+	if _, ok := referenced[dgst]; ok {
+		return nil
+	}
+	referenced[dgst] = struct{}{}
+	r.marked[dgst] = struct{}{}
+	manifest, err := manifests.Get(ctx, dgst)
+	if err != nil {
+		return trace.Wrap(err, "failed to retrieve manifest %v", dgst)
+	}
+	_, isList := manifest.(*manifestlist.DeserializedManifestList)
+	for _, desc := range manifest.References() {
+		if isList {
+			if err := r.markManifest(ctx, manifests, desc.Digest, referenced); err != nil {
+				return trace.Wrap(err)
+			}
+			continue
+		}
+		r.marked[desc.Digest] = struct{}{}
+	}
+	return nil
+}
+
+// sweep removes the blobs that have not been marked
+func (r *collector) sweep(ctx context.Context) error {
+	var unmarked []digest.Digest
+	err := r.registry.Blobs().Enumerate(ctx, func(dgst digest.Digest) error {
+		if _, ok := r.marked[dgst]; !ok {
+			unmarked = append(unmarked, dgst)
+		}
+		return nil
+	})
+	if err != nil && !isPathNotFound(err) {
+		return trace.Wrap(err, "failed to enumerate registry blobs")
+	}
+	sort.Slice(unmarked, func(i, j int) bool { return unmarked[i] < unmarked[j] })
+	vacuum := registrystorage.NewVacuum(ctx, r.driver)
+	for _, dgst := range unmarked {
+		desc, err := r.registry.BlobStatter().Stat(ctx, dgst)
+		if err != nil {
+			return trace.Wrap(err, "failed to query blob %v", dgst)
+		}
+		r.WithField("blob", dgst).WithField("size", desc.Size).Debug("Remove unreferenced blob.")
+		r.result.Blobs++
+		r.result.Bytes += desc.Size
+		if r.DryRun {
+			continue
+		}
+		if err := vacuum.RemoveBlob(string(dgst)); err != nil {
+			return trace.Wrap(err, "failed to remove blob %v", dgst)
+		}
+	}
+	return nil
+}
+
+// openStorage opens the registry storage in dir using the filesystem driver
+func openStorage(ctx context.Context, dir string, options ...registrystorage.RegistryOption) (driver.StorageDriver, distribution.Namespace, error) {
+	storageDriver := filesystem.New(filesystem.DriverParameters{
+		RootDirectory: dir,
+		MaxThreads:    defaults.ImageServiceMaxThreads,
+	})
+	registry, err := registrystorage.NewRegistry(ctx, storageDriver, options...)
+	if err != nil {
+		return nil, nil, trace.Wrap(err)
+	}
+	return storageDriver, registry, nil
+}
+
+type collector struct {
+	collectorConfig
+	driver   driver.StorageDriver
+	registry distribution.Namespace
+	// keep is the set of tags to retain
+	keep map[docker.TagSpec]struct{}
+	// marked is the set of blobs referenced by the remaining manifests
+	marked map[digest.Digest]struct{}
+	result gcResult
+}
+
+func isPathNotFound(err error) bool {
+	_, ok := trace.Unwrap(err).(driver.PathNotFoundError)
+	return ok
+}
+
+func isRepositoryUnknown(err error) bool {
+	_, ok := trace.Unwrap(err).(distribution.ErrRepositoryUnknown)
+	return ok
+}
@@ -0,0 +1,152 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/gravitational/gravity/lib/app/docker"
+
+	"github.com/docker/distribution"
+	"github.com/docker/distribution/manifest/schema2"
+	"github.com/docker/distribution/reference"
+	. "gopkg.in/check.v1"
+)
+
+func TestRegistry(t *testing.T) { TestingT(t) }
+
+type S struct {
+	dir string
+}
+
+var _ = Suite(&S{})
+
+func (s *S) SetUpTest(c *C) {
+	s.dir = c.MkDir()
+}
+
+func (s *S) TestCollectsGarbage(c *C) {
+	current := s.pushImage(c, "app", "2.0.0", "base", "app-v2")
+	previous := s.pushImage(c, "app", "1.0.0", "base", "app-v1")
+	unused := s.pushImage(c, "tools", "latest", "tools")
+
+	result, err := collectGarbage(context.TODO(), collectorConfig{
+		Dir:  s.dir,
+		Keep: []docker.TagSpec{{Name: "app", Version: "2.0.0"}},
+	})
+	c.Assert(err, IsNil)
+	c.Assert(result.Tags, Equals, 2)
+	c.Assert(result.Manifests, Equals, 2)
+	// manifest, config and the unshared layers of both images
+	c.Assert(result.Blobs, Equals, 6)
+	c.Assert(result.Bytes, Equals, previous.size-previous.shared+unused.size)
+
+	s.assertBlobs(c, current.blobs, true)
+	s.assertBlobs(c, previous.blobs[:1], true)
+	s.assertBlobs(c, previous.blobs[1:], false)
+	s.assertBlobs(c, unused.blobs, false)
+
+	tags, err := listTags(context.TODO(), s.dir)
+	c.Assert(err, IsNil)
+	c.Assert(tags, DeepEquals, []docker.TagSpec{{Name: "app", Version: "2.0.0"}})
+}
+
+func (s *S) TestDryRunKeepsImages(c *C) {
+	image := s.pushImage(c, "app", "1.0.0", "base")
+
+	config := collectorConfig{Dir: s.dir}
+	config.DryRun = true
+	result, err := collectGarbage(context.TODO(), config)
+	c.Assert(err, IsNil)
+	c.Assert(result.Tags, Equals, 1)
+	c.Assert(result.Blobs, Equals, 3)
+	c.Assert(result.Bytes, Equals, image.size)
+
+	s.assertBlobs(c, image.blobs, true)
+	tags, err := listTags(context.TODO(), s.dir)
+	c.Assert(err, IsNil)
+	c.Assert(tags, DeepEquals, []docker.TagSpec{{Name: "app", Version: "1.0.0"}})
+}
+
+// pushImage creates an image in the registry storage with the specified
+// layers. The first layer is treated as shared with other images
+func (s *S) pushImage(c *C, repoName, tag string, layers ...string) testImage {
+	ctx := context.TODO()
+	_, registry, err := openStorage(ctx, s.dir)
+	c.Assert(err, IsNil)
+	named, err := reference.WithName(repoName)
+	c.Assert(err, IsNil)
+	repo, err := registry.Repository(ctx, named)
+	c.Assert(err, IsNil)
+
+	var image testImage
+	blobs := repo.Blobs(ctx)
+	config := []byte(fmt.Sprintf(`{"architecture":"amd64","os":"linux","config":{"Labels":{"tag":%q}}}`, tag))
+	builder := schema2.NewManifestBuilder(blobs, schema2.MediaTypeImageConfig, config)
+	for i, layer := range layers {
+		desc, err := blobs.Put(ctx, schema2.MediaTypeLayer, []byte(layer))
+		c.Assert(err, IsNil)
+		c.Assert(builder.AppendReference(desc), IsNil)
+		image.add(desc)
+		if i == 0 {
+			image.shared = desc.Size
+		}
+	}
+	manifest, err := builder.Build(ctx)
+	c.Assert(err, IsNil)
+	image.add(manifest.References()[0])
+
+	manifests, err := repo.Manifests(ctx)
+	c.Assert(err, IsNil)
+	dgst, err := manifests.Put(ctx, manifest)
+	c.Assert(err, IsNil)
+	desc, err := registry.BlobStatter().Stat(ctx, dgst)
+	c.Assert(err, IsNil)
+	image.add(desc)
+	c.Assert(repo.Tags(ctx).Tag(ctx, tag, desc), IsNil)
+	return image
+}
+
+func (s *S) assertBlobs(c *C, blobs []distribution.Descriptor, exist bool) {
+	_, registry, err := openStorage(context.TODO(), s.dir)
+	c.Assert(err, IsNil)
+	for _, blob := range blobs {
+		_, err := registry.BlobStatter().Stat(context.TODO(), blob.Digest)
+		if exist {
+			c.Assert(err, IsNil, Commentf("expected blob %v to exist", blob.Digest))
+		} else {
+			c.Assert(err, Equals, distribution.ErrBlobUnknown, Commentf("expected blob %v to be removed", blob.Digest))
+		}
+	}
+}
+
+// testImage describes the blobs of an image
+type testImage struct {
+	// blobs lists the layers, the config and the manifest blobs of the image
+	blobs []distribution.Descriptor
+	// size is the total size of image blobs
+	size int64
+	// shared is the size of the layer shared with other images
+	shared int64
+}
+
+func (r *testImage) add(desc distribution.Descriptor) {
+	r.blobs = append(r.blobs, desc)
+	r.size += desc.Size
+}
@@ -18,6 +18,7 @@ package registry
 
 import (
 	"context"
+	"os"
 	"strings"
 
 	apps "github.com/gravitational/gravity/lib/app"
@@ -31,6 +32,10 @@ import (
 	"github.com/gravitational/gravity/lib/utils"
 	"github.com/gravitational/gravity/lib/vacuum/prune"
 
+	registrycontext "github.com/docker/distribution/context"
+	"github.com/docker/distribution/reference"
+
+	"github.com/dustin/go-humanize"
 	"github.com/gravitational/trace"
 	log "github.com/sirupsen/logrus"
 )
@@ -59,6 +64,20 @@ func (r *Config) checkAndSetDefaults() error {
 	if r.Apps == nil {
 		return trace.BadParameter("cluster application service is required")
 	}
+	if r.Dir == "" {
+		stateDir, err := state.GetStateDir()
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		r.Dir = state.RegistryDir(stateDir)
+	}
+	if r.Node == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return trace.ConvertSystemError(err)
+		}
+		r.Node = hostname
+	}
 	if r.FieldLogger == nil {
 		r.FieldLogger = log.WithField(trace.Component, "gc:registry")
 	}
@@ -77,12 +96,38 @@ type Config struct {
 	Apps apps.Applications
 	// ImageService specifies the docker image service
 	ImageService docker.ImageService
+	// Dir optionally specifies the root directory of the registry storage.
+	// Defaults to the registry directory in the planet state directory
+	Dir string
+	// Node optionally specifies the name of the node to report
+	// the reclaimed storage for. Defaults to the hostname
+	Node string
 }
 
-// Prune removes unused docker images.
-// The registry state is reset by deleting the state from the filesystem
-// and re-running the docker image export for the cluster application.
+// Prune removes docker images not used by the cluster application
+// and reclaims the storage of their blobs.
+//
+// The application images are synchronized with the registry first so the
+// registry has all images the application requires. The registry service is
+// then stopped for the duration of the mark and sweep over its storage
+// so that no uploads can race with the sweep.
+// In dry-run mode, the registry is left running and the storage is only read
 func (r *cleanup) Prune(ctx context.Context) (err error) {
+	r.PrintStep("Sync application state with registry")
+	images := &tagRecorder{
+		ImageService: r.ImageService,
+		dryRun:       r.DryRun,
+	}
+	err = appservice.SyncApp(ctx, appservice.SyncRequest{
+		PackService:  r.Packages,
+		AppService:   r.Apps,
+		ImageService: images,
+		Package:      *r.App,
+	})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
 	r.PrintStep("Stop registry service")
 	if !r.DryRun {
 		err = r.registryStop(ctx)
@@ -99,21 +144,16 @@ func (r *cleanup) Prune(ctx context.Context) (err error) {
 		}
 	}
 
-	stateDir, err := state.GetStateDir()
+	r.PrintStep("Collect garbage in registry storage %v", r.Dir)
+	result, err := collectGarbage(ctx, collectorConfig{
+		Config: r.Config.Config,
+		Dir:    r.Dir,
+		Keep:   images.tags,
+	})
 	if err != nil {
 		return trace.Wrap(err)
 	}
 
-	dir := state.RegistryDir(stateDir)
-	r.PrintStep("Delete registry state directory %v", dir)
-	if !r.DryRun {
-		err = utils.RemoveContents(dir)
-		if err != nil {
-			return trace.Wrap(trace.ConvertSystemError(err),
-				"failed to remove old registry state from %v.", dir)
-		}
-	}
-
 	r.PrintStep("Start registry service")
 	if !r.DryRun {
 		err = r.registryStart(ctx)
@@ -122,20 +162,15 @@ func (r *cleanup) Prune(ctx context.Context) (err error) {
 		}
 	}
 
-	r.PrintStep("Sync application state with registry")
-	if r.DryRun {
-		return nil
-	}
-	err = appservice.SyncApp(ctx, appservice.SyncRequest{
-		PackService:  r.Packages,
-		AppService:   r.Apps,
-		ImageService: r.ImageService,
-		Package:      *r.App,
-	})
-	if err != nil {
-		return trace.Wrap(err)
-	}
-
+	r.WithFields(log.Fields{
+		"node":      r.Node,
+		"tags":      result.Tags,
+		"manifests": result.Manifests,
+		"blobs":     result.Blobs,
+		"bytes":     result.Bytes,
+	}).Info("Collected registry garbage.")
+	r.PrintStep("Removed %v images and %v blobs, reclaimed %v of registry storage on node %v.",
+		result.Tags, result.Blobs, humanize.Bytes(uint64(result.Bytes)), r.Node)
 	return nil
 }
 
@@ -203,3 +238,58 @@ func serviceCtl(ctx context.Context, log log.FieldLogger, args ...string) (outpu
 	output, err = utils.RunCommand(ctx, log, utils.PlanetCommandArgs(args...)...)
 	return output, trace.Wrap(err)
 }
+
+// tagRecorder is an image service that records the tags of the synchronized images.
+// In dry-run mode, the images are not pushed to the registry and only the tags
+// of the images in the source directory are recorded
+type tagRecorder struct {
+	docker.ImageService
+	dryRun bool
+	// tags lists the tags of all synchronized images
+	tags []docker.TagSpec
+}
+
+// Sync synchronizes the images in dir with the registry
+// and records their tags
+func (r *tagRecorder) Sync(ctx registrycontext.Context, dir string, progress utils.Emitter) (tags []docker.TagSpec, err error) {
+	if r.dryRun {
+		tags, err = listTags(ctx, dir)
+	} else {
+		tags, err = r.ImageService.Sync(ctx, dir, progress)
+	}
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	r.tags = append(r.tags, tags...)
+	return tags, nil
+}
+
+// listTags returns the tags of all images in the registry storage in dir
+func listTags(ctx context.Context, dir string) (tags []docker.TagSpec, err error) {
+	_, registry, err := openStorage(ctx, dir)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	repoNames, err := docker.ListRepos(ctx, registry)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	for _, repoName := range repoNames {
+		named, err := reference.WithName(repoName)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		repo, err := registry.Repository(ctx, named)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		repoTags, err := repo.Tags(ctx).All(ctx)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		for _, tag := range repoTags {
+			tags = append(tags, docker.TagSpec{Name: repoName, Version: tag})
+		}
+	}
+	return tags, nil
+}
@@ -0,0 +1,257 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package state
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/gravitational/gravity/lib/constants"
+	"github.com/gravitational/gravity/lib/ops"
+	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/vacuum/prune"
+
+	"github.com/dustin/go-humanize"
+	"github.com/gravitational/trace"
+	"github.com/jonboulle/clockwork"
+	log "github.com/sirupsen/logrus"
+)
+
+// NewCluster creates a new cleaner of stale cluster operation data
+// and expired provisioning tokens
+func NewCluster(config ClusterConfig) (*clusterCleanup, error) {
+	if err := config.checkAndSetDefaults(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &clusterCleanup{ClusterConfig: config}, nil
+}
+
+func (r *ClusterConfig) checkAndSetDefaults() error {
+	if r.ClusterName == "" {
+		return trace.BadParameter("cluster name is required")
+	}
+	if r.Backend == nil {
+		return trace.BadParameter("cluster backend is required")
+	}
+	r.Retention.CheckAndSetDefaults()
+	if r.Clock == nil {
+		r.Clock = clockwork.NewRealClock()
+	}
+	if r.FieldLogger == nil {
+		r.FieldLogger = log.WithField(trace.Component, "gc:state")
+	}
+	return nil
+}
+
+// ClusterConfig describes configuration for the cleaner of stale
+// cluster operation data
+type ClusterConfig struct {
+	// Config specifies the common pruner configuration
+	prune.Config
+	// Retention specifies the operations exempt from pruning
+	Retention
+	// ClusterName specifies the name of the cluster
+	ClusterName string
+	// Backend specifies the cluster state backend
+	Backend clusterBackend
+	// OperationLogDir optionally specifies the directory with operation logs.
+	// The log of an operation is stored as <operation-id>/<operation-id>.log
+	// in this directory
+	OperationLogDir string
+	// Clock is used to determine token expiration and operation age
+	Clock clockwork.Clock
+}
+
+// clusterBackend defines the subset of backend APIs as required for pruning
+type clusterBackend interface {
+	GetSiteOperations(siteDomain string) ([]storage.SiteOperation, error)
+	GetOperationPlanChangelog(clusterName, operationID string) (storage.PlanChangelog, error)
+	DeleteOperationPlanChange(clusterName, operationID, changeID string) error
+	GetProgressEntries(siteDomain, operationID string) ([]storage.ProgressEntry, error)
+	DeleteProgressEntry(siteDomain, operationID, id string) error
+	GetSiteProvisioningTokens(siteDomain string) ([]storage.ProvisioningToken, error)
+	DeleteProvisioningToken(token string) error
+}
+
+// Prune removes stale data of the finished operations outside of the
+// configured retention and expired provisioning tokens.
+//
+// The operation records are kept, while the plan changelog is compacted
+// to the latest change per phase so the plan state can still be resolved,
+// the progress entries are reduced to the last one and the operation log
+// is removed.
+func (r *clusterCleanup) Prune(context.Context) error {
+	operations, err := r.Backend.GetSiteOperations(r.ClusterName)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	for _, operation := range r.staleOperations(operations) {
+		if err := r.pruneOperation(operation); err != nil {
+			return trace.Wrap(err)
+		}
+	}
+	return trace.Wrap(r.pruneTokens())
+}
+
+// staleOperations returns the finished operations outside of the retention
+func (r *clusterCleanup) staleOperations(operations []storage.SiteOperation) (stale []storage.SiteOperation) {
+	sort.Slice(operations, func(i, j int) bool {
+		return operations[i].Created.After(operations[j].Created)
+	})
+	cutoff := r.Clock.Now().Add(-r.Period)
+	for i, operation := range operations {
+		logger := r.WithField("operation", operation.ID)
+		switch {
+		case i < r.Operations:
+			logger.Debug("Keep one of the most recent operations.")
+		case operation.Created.After(cutoff):
+			logger.Debug("Keep a recent operation.")
+		case !(*ops.SiteOperation)(&operations[i]).IsFinished():
+			logger.Debug("Keep an active operation.")
+		default:
+			stale = append(stale, operation)
+		}
+	}
+	return stale
+}
+
+func (r *clusterCleanup) pruneOperation(operation storage.SiteOperation) error {
+	description := fmt.Sprintf("%v operation %v", operation.Type, operation.ID)
+	if err := r.pruneChangelog(operation, description); err != nil {
+		return trace.Wrap(err)
+	}
+	if err := r.pruneProgress(operation, description); err != nil {
+		return trace.Wrap(err)
+	}
+	return trace.Wrap(r.pruneLog(operation, description))
+}
+
+// pruneChangelog removes all but the latest plan change for each phase
+func (r *clusterCleanup) pruneChangelog(operation storage.SiteOperation, description string) error {
+	changelog, err := r.Backend.GetOperationPlanChangelog(operation.SiteDomain, operation.ID)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	latest := make(map[string]string)
+	for _, change := range changelog {
+		if _, ok := latest[change.PhaseID]; !ok {
+			latest[change.PhaseID] = changelog.Latest(change.PhaseID).ID
+		}
+	}
+	var obsolete []storage.PlanChange
+	for _, change := range changelog {
+		if latest[change.PhaseID] != change.ID {
+			obsolete = append(obsolete, change)
+		}
+	}
+	if len(obsolete) == 0 {
+		return nil
+	}
+	r.PrintStep("Prune %v plan changes of %v.", len(obsolete), description)
+	if r.DryRun {
+		return nil
+	}
+	for _, change := range obsolete {
+		err := r.Backend.DeleteOperationPlanChange(operation.SiteDomain, operation.ID, change.ID)
+		if err != nil && !trace.IsNotFound(err) {
+			return trace.Wrap(err)
+		}
+	}
+	return nil
+}
+
+// pruneProgress removes all but the last progress entry
+func (r *clusterCleanup) pruneProgress(operation storage.SiteOperation, description string) error {
+	entries, err := r.Backend.GetProgressEntries(operation.SiteDomain, operation.ID)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if len(entries) < 2 {
+		return nil
+	}
+	obsolete := entries[:len(entries)-1]
+	r.PrintStep("Prune %v progress entries of %v.", len(obsolete), description)
+	if r.DryRun {
+		return nil
+	}
+	for _, entry := range obsolete {
+		err := r.Backend.DeleteProgressEntry(operation.SiteDomain, operation.ID, entry.ID)
+		if err != nil && !trace.IsNotFound(err) {
+			return trace.Wrap(err)
+		}
+	}
+	return nil
+}
+
+func (r *clusterCleanup) pruneLog(operation storage.SiteOperation, description string) error {
+	if r.OperationLogDir == "" {
+		return nil
+	}
+	dir := filepath.Join(r.OperationLogDir, operation.ID)
+	path := filepath.Join(dir, fmt.Sprintf("%v.log", operation.ID))
+	fi, err := os.Stat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return trace.ConvertSystemError(err)
+	}
+	r.PrintStep("Remove log of %v (%v).", description, humanize.Bytes(uint64(fi.Size())))
+	if r.DryRun {
+		return nil
+	}
+	if err := os.Remove(path); err != nil {
+		return trace.Wrap(trace.ConvertSystemError(err),
+			"failed to remove operation log %q", path)
+	}
+	// Remove the operation directory unless it has other files
+	if err := os.Remove(dir); err != nil {
+		r.WithError(err).WithField("dir", dir).Debug("Keep operation directory.")
+	}
+	return nil
+}
+
+// pruneTokens removes the expired provisioning tokens
+func (r *clusterCleanup) pruneTokens() error {
+	tokens, err := r.Backend.GetSiteProvisioningTokens(r.ClusterName)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	now := r.Clock.Now()
+	for _, token := range tokens {
+		if token.Expires.IsZero() || token.Expires.After(now) {
+			continue
+		}
+		r.PrintStep("Remove %v provisioning token for operation %v expired at %v.",
+			token.Type, token.OperationID, token.Expires.Format(constants.HumanDateFormatSeconds))
+		if r.DryRun {
+			continue
+		}
+		err := r.Backend.DeleteProvisioningToken(token.Token)
+		if err != nil && !trace.IsNotFound(err) {
+			return trace.Wrap(err)
+		}
+	}
+	return nil
+}
+
+type clusterCleanup struct {
+	ClusterConfig
+}
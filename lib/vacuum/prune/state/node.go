@@ -0,0 +1,232 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package state
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/loc"
+	"github.com/gravitational/gravity/lib/pack"
+	"github.com/gravitational/gravity/lib/systemservice"
+	"github.com/gravitational/gravity/lib/utils"
+	"github.com/gravitational/gravity/lib/vacuum/prune"
+
+	"github.com/dustin/go-humanize"
+	"github.com/gravitational/trace"
+	log "github.com/sirupsen/logrus"
+)
+
+// NewNode creates a new cleaner of leftover node-local state
+func NewNode(config NodeConfig) (*nodeCleanup, error) {
+	if err := config.checkAndSetDefaults(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &nodeCleanup{NodeConfig: config}, nil
+}
+
+func (r *NodeConfig) checkAndSetDefaults() error {
+	for _, dir := range r.UnpackedDirs {
+		if dir.Dir == "" {
+			return trace.BadParameter("unpacked directory is required")
+		}
+		if dir.Packages == nil {
+			return trace.BadParameter("package service for %v is required", dir.Dir)
+		}
+	}
+	if r.AgentDir != "" && r.Services == nil {
+		services, err := systemservice.New()
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		r.Services = services
+	}
+	if r.FieldLogger == nil {
+		r.FieldLogger = log.WithField(trace.Component, "gc:state")
+	}
+	return nil
+}
+
+// NodeConfig describes configuration for the cleaner of leftover
+// node-local state
+type NodeConfig struct {
+	// Config specifies the common pruner configuration
+	prune.Config
+	// UnpackedDirs lists the directories with unpacked packages
+	// to remove the packages no longer present in the respective
+	// package service from
+	UnpackedDirs []UnpackedDir
+	// AgentDir optionally specifies the directory the RPC agent is deployed to
+	// for cluster operations. The directory is removed unless the agent is running
+	AgentDir string
+	// Services specifies the service manager to query the agent status.
+	// Only required if AgentDir is specified
+	Services serviceManager
+}
+
+// UnpackedDir describes a directory with unpacked packages
+type UnpackedDir struct {
+	// Dir specifies the directory packages are unpacked into
+	Dir string
+	// Packages specifies the package service the packages are unpacked from
+	Packages packageService
+}
+
+// packageService defines the subset of package APIs as required for pruning
+type packageService interface {
+	ReadPackageEnvelope(loc.Locator) (*pack.PackageEnvelope, error)
+}
+
+// serviceManager defines the subset of service manager APIs as required for pruning
+type serviceManager interface {
+	StatusService(name string) (string, error)
+}
+
+// Prune removes the unpacked directories of packages that no longer exist
+// and the directory of the RPC agent if the agent is not running
+func (r *nodeCleanup) Prune(context.Context) error {
+	for _, dir := range r.UnpackedDirs {
+		if err := r.pruneUnpackedDir(dir); err != nil {
+			return trace.Wrap(err)
+		}
+	}
+	return trace.Wrap(r.pruneAgentDir())
+}
+
+// pruneUnpackedDir removes the unpacked packages from dir that do not exist
+// in the package service. Unpacked packages are stored as
+// <repository>/<name>/<version> in dir
+func (r *nodeCleanup) pruneUnpackedDir(dir UnpackedDir) error {
+	repositories, err := readDirs(dir.Dir)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	for _, repository := range repositories {
+		names, err := readDirs(filepath.Join(dir.Dir, repository))
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		for _, name := range names {
+			versions, err := readDirs(filepath.Join(dir.Dir, repository, name))
+			if err != nil {
+				return trace.Wrap(err)
+			}
+			for _, version := range versions {
+				locator, err := loc.NewLocator(repository, name, version)
+				if err != nil {
+					r.WithError(err).Warn("Skip unrecognized unpacked directory.")
+					continue
+				}
+				if err := r.pruneUnpackedPackage(dir, *locator); err != nil {
+					return trace.Wrap(err)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (r *nodeCleanup) pruneUnpackedPackage(dir UnpackedDir, locator loc.Locator) error {
+	_, err := dir.Packages.ReadPackageEnvelope(locator)
+	if err == nil {
+		r.WithField("package", locator).Debug("Keep unpacked package.")
+		return nil
+	}
+	if !trace.IsNotFound(err) {
+		return trace.Wrap(err)
+	}
+	path := pack.PackagePath(dir.Dir, locator)
+	return trace.Wrap(r.removeDir(path, fmt.Sprintf("unpacked directory of deleted package %v", locator)))
+}
+
+// pruneAgentDir removes the RPC agent directory unless the agent is running
+func (r *nodeCleanup) pruneAgentDir() error {
+	if r.AgentDir == "" {
+		return nil
+	}
+	if _, err := utils.StatDir(r.AgentDir); err != nil {
+		if trace.IsNotFound(err) {
+			return nil
+		}
+		return trace.Wrap(err)
+	}
+	status, err := r.Services.StatusService(defaults.GravityRPCAgentServiceName)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if status == systemservice.ServiceStatusActive || status == systemservice.ServiceStatusActivating {
+		r.WithField("status", status).Info("Keep the directory of the running agent.")
+		return nil
+	}
+	return trace.Wrap(r.removeDir(r.AgentDir, "leftover agent directory"))
+}
+
+func (r *nodeCleanup) removeDir(path, description string) error {
+	size, err := dirSize(path)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	r.PrintStep("Remove %v %v (%v).", description, path, humanize.Bytes(uint64(size)))
+	if r.DryRun {
+		return nil
+	}
+	if err := os.RemoveAll(path); err != nil {
+		return trace.Wrap(trace.ConvertSystemError(err),
+			"failed to remove directory %q", path)
+	}
+	return nil
+}
+
+// readDirs returns the names of directories in dir.
+// Returns no directories if dir does not exist
+func readDirs(dir string) (dirs []string, err error) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, trace.ConvertSystemError(err)
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			dirs = append(dirs, entry.Name())
+		}
+	}
+	return dirs, nil
+}
+
+// dirSize returns the total size of regular files in dir
+func dirSize(dir string) (size int64, err error) {
+	err = filepath.Walk(dir, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return trace.ConvertSystemError(err)
+		}
+		if fi.Mode().IsRegular() {
+			size += fi.Size()
+		}
+		return nil
+	})
+	return size, trace.Wrap(err)
+}
+
+type nodeCleanup struct {
+	NodeConfig
+}
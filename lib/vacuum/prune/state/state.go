@@ -0,0 +1,53 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package state implements pruners of stale operation data in the cluster
+// backend and of leftover node-local state in the gravity state directory
+package state
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gravitational/gravity/lib/defaults"
+)
+
+// Retention defines the operations exempt from pruning.
+// An operation is kept if it satisfies any of the conditions
+type Retention struct {
+	// Operations specifies the number of most recent operations to keep.
+	// Defaults to defaults.PruneKeepOperations if unspecified
+	Operations int
+	// Period specifies the period of time to keep the operations for.
+	// Defaults to defaults.PruneKeepPeriod if unspecified
+	Period time.Duration
+}
+
+// String returns a textual representation of this retention
+func (r Retention) String() string {
+	return fmt.Sprintf("last %v operations and operations from the last %v",
+		r.Operations, r.Period)
+}
+
+// CheckAndSetDefaults sets the default retention for unspecified values
+func (r *Retention) CheckAndSetDefaults() {
+	if r.Operations == 0 {
+		r.Operations = defaults.PruneKeepOperations
+	}
+	if r.Period == 0 {
+		r.Period = defaults.PruneKeepPeriod
+	}
+}
@@ -0,0 +1,288 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package state
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gravitational/gravity/lib/loc"
+	"github.com/gravitational/gravity/lib/ops"
+	"github.com/gravitational/gravity/lib/pack"
+	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/storage/keyval"
+	"github.com/gravitational/gravity/lib/systemservice"
+
+	"github.com/gravitational/trace"
+	"github.com/jonboulle/clockwork"
+	. "gopkg.in/check.v1"
+)
+
+func TestState(t *testing.T) { TestingT(t) }
+
+type S struct {
+	backend storage.Backend
+	clock   clockwork.FakeClock
+	logDir  string
+}
+
+var _ = Suite(&S{})
+
+func (s *S) SetUpTest(c *C) {
+	dir := c.MkDir()
+	s.clock = clockwork.NewFakeClockAt(time.Date(2019, 6, 1, 0, 0, 0, 0, time.UTC))
+	var err error
+	s.backend, err = keyval.NewBolt(keyval.BoltConfig{
+		Path:  filepath.Join(dir, "bolt.db"),
+		Clock: s.clock,
+	})
+	c.Assert(err, IsNil)
+	s.logDir = filepath.Join(dir, "site")
+
+	account, err := s.backend.CreateAccount(storage.Account{Org: "example.com"})
+	c.Assert(err, IsNil)
+	_, err = s.backend.CreateSite(storage.Site{
+		AccountID: account.ID,
+		Domain:    clusterName,
+		Created:   s.clock.Now(),
+		App: storage.Package{
+			Repository: "example.com",
+			Name:       "app",
+			Version:    "0.0.1",
+		},
+	})
+	c.Assert(err, IsNil)
+}
+
+func (s *S) TearDownTest(c *C) {
+	s.backend.Close()
+}
+
+func (s *S) TestPrunesStaleOperations(c *C) {
+	now := s.clock.Now()
+	// 5 operations, a day apart, the oldest one is still active
+	var operations []storage.SiteOperation
+	for i := 4; i >= 0; i-- {
+		state := ops.OperationStateCompleted
+		if i == 4 {
+			state = ops.OperationStateExpandProvisioning
+		}
+		operations = append(operations, s.createOperation(c, now.Add(-time.Duration(i)*24*time.Hour), state))
+	}
+	active, stale, recent := operations[0], operations[1:3], operations[3:]
+
+	pruner, err := NewCluster(ClusterConfig{
+		ClusterName:     clusterName,
+		Backend:         s.backend,
+		OperationLogDir: s.logDir,
+		Clock:           s.clock,
+		Retention: Retention{
+			Operations: 1,
+			Period:     36 * time.Hour,
+		},
+	})
+	c.Assert(err, IsNil)
+	c.Assert(pruner.Prune(context.TODO()), IsNil)
+
+	for _, operation := range append(recent, active) {
+		s.assertOperation(c, operation, 3, 2, true)
+	}
+	for _, operation := range stale {
+		s.assertOperation(c, operation, 2, 1, false)
+		// the plan state is still resolvable
+		changelog, err := s.backend.GetOperationPlanChangelog(clusterName, operation.ID)
+		c.Assert(err, IsNil)
+		c.Assert(changelog.Latest("/init").NewState, Equals, storage.OperationPhaseStateCompleted)
+		c.Assert(changelog.Latest("/start").NewState, Equals, storage.OperationPhaseStateInProgress)
+		// the operation record is kept
+		_, err = s.backend.GetSiteOperation(clusterName, operation.ID)
+		c.Assert(err, IsNil)
+	}
+}
+
+func (s *S) TestDryRunKeepsOperations(c *C) {
+	operation := s.createOperation(c, s.clock.Now().Add(-365*24*time.Hour), ops.OperationStateFailed)
+	for i := 0; i < 2; i++ {
+		s.createOperation(c, s.clock.Now(), ops.OperationStateCompleted)
+	}
+
+	pruner, err := NewCluster(ClusterConfig{
+		ClusterName:     clusterName,
+		Backend:         s.backend,
+		OperationLogDir: s.logDir,
+		Clock:           s.clock,
+		Retention:       Retention{Operations: 1, Period: time.Hour},
+	})
+	c.Assert(err, IsNil)
+	pruner.DryRun = true
+	c.Assert(pruner.Prune(context.TODO()), IsNil)
+
+	s.assertOperation(c, operation, 3, 2, true)
+}
+
+func (s *S) TestPrunesExpiredTokens(c *C) {
+	for token, expires := range map[string]time.Time{
+		"expired":   s.clock.Now().Add(-time.Minute),
+		"active":    s.clock.Now().Add(time.Minute),
+		"permanent": {},
+	} {
+		_, err := s.backend.CreateProvisioningToken(storage.ProvisioningToken{
+			Token:      token,
+			Expires:    expires,
+			Type:       storage.ProvisioningTokenTypeExpand,
+			AccountID:  "account",
+			SiteDomain: clusterName,
+		})
+		c.Assert(err, IsNil)
+	}
+
+	pruner, err := NewCluster(ClusterConfig{
+		ClusterName: clusterName,
+		Backend:     s.backend,
+		Clock:       s.clock,
+	})
+	c.Assert(err, IsNil)
+	c.Assert(pruner.Prune(context.TODO()), IsNil)
+
+	tokens, err := s.backend.GetSiteProvisioningTokens(clusterName)
+	c.Assert(err, IsNil)
+	var names []string
+	for _, token := range tokens {
+		names = append(names, token.Token)
+	}
+	c.Assert(names, DeepEquals, []string{"active", "permanent"})
+}
+
+func (s *S) TestPrunesUnpackedPackages(c *C) {
+	dir := c.MkDir()
+	packages := testPackages{"example.com/existing:1.0.0": {}}
+	for _, path := range []string{
+		"example.com/existing/1.0.0/rootfs/bin/tool",
+		"example.com/existing/0.0.1/rootfs/bin/tool",
+		"example.com/deleted/2.0.0/resources/app.yaml",
+	} {
+		writeFile(c, filepath.Join(dir, path))
+	}
+	agentDir := filepath.Join(c.MkDir(), "agent")
+	writeFile(c, filepath.Join(agentDir, "gravity"))
+
+	pruner, err := NewNode(NodeConfig{
+		UnpackedDirs: []UnpackedDir{
+			{Dir: dir, Packages: packages},
+			{Dir: filepath.Join(dir, "missing"), Packages: packages},
+		},
+		AgentDir: agentDir,
+		Services: testServices(systemservice.ServiceStatusActive),
+	})
+	c.Assert(err, IsNil)
+	c.Assert(pruner.Prune(context.TODO()), IsNil)
+
+	assertExists(c, filepath.Join(dir, "example.com/existing/1.0.0/rootfs/bin/tool"), true)
+	assertExists(c, filepath.Join(dir, "example.com/existing/0.0.1"), false)
+	assertExists(c, filepath.Join(dir, "example.com/deleted/2.0.0"), false)
+	assertExists(c, agentDir, true)
+
+	pruner.Services = testServices(systemservice.ServiceStatusUnknown)
+	c.Assert(pruner.Prune(context.TODO()), IsNil)
+	assertExists(c, agentDir, false)
+}
+
+// createOperation creates a new operation with a log file, a plan changelog
+// with 3 changes for 2 phases and 2 progress entries
+func (s *S) createOperation(c *C, created time.Time, state string) storage.SiteOperation {
+	operation, err := s.backend.CreateSiteOperation(storage.SiteOperation{
+		AccountID:  "account",
+		SiteDomain: clusterName,
+		Type:       ops.OperationExpand,
+		Created:    created,
+		Updated:    created,
+		State:      state,
+	})
+	c.Assert(err, IsNil)
+	for i, change := range []storage.PlanChange{
+		{PhaseID: "/init", NewState: storage.OperationPhaseStateInProgress},
+		{PhaseID: "/init", NewState: storage.OperationPhaseStateCompleted},
+		{PhaseID: "/start", NewState: storage.OperationPhaseStateInProgress},
+	} {
+		change.ClusterName = clusterName
+		change.OperationID = operation.ID
+		change.Created = created.Add(time.Duration(i) * time.Second)
+		_, err := s.backend.CreateOperationPlanChange(change)
+		c.Assert(err, IsNil)
+	}
+	for i := 0; i < 2; i++ {
+		_, err := s.backend.CreateProgressEntry(storage.ProgressEntry{
+			SiteDomain:  clusterName,
+			OperationID: operation.ID,
+			Created:     created.Add(time.Duration(i) * time.Second),
+			Completion:  i * 100,
+			State:       ops.ProgressStateInProgress,
+		})
+		c.Assert(err, IsNil)
+	}
+	writeFile(c, filepath.Join(s.logDir, operation.ID, operation.ID+".log"))
+	return *operation
+}
+
+func (s *S) assertOperation(c *C, operation storage.SiteOperation, changes, entries int, hasLog bool) {
+	comment := Commentf("operation created at %v", operation.Created)
+	changelog, err := s.backend.GetOperationPlanChangelog(clusterName, operation.ID)
+	c.Assert(err, IsNil)
+	c.Assert(changelog, HasLen, changes, comment)
+	progress, err := s.backend.GetProgressEntries(clusterName, operation.ID)
+	c.Assert(err, IsNil)
+	c.Assert(progress, HasLen, entries, comment)
+	c.Assert(progress[len(progress)-1].Completion, Equals, 100, comment)
+	assertExists(c, filepath.Join(s.logDir, operation.ID), hasLog)
+}
+
+func writeFile(c *C, path string) {
+	c.Assert(os.MkdirAll(filepath.Dir(path), 0755), IsNil)
+	c.Assert(ioutil.WriteFile(path, []byte("data"), 0644), IsNil)
+}
+
+func assertExists(c *C, path string, exists bool) {
+	_, err := os.Stat(path)
+	if exists {
+		c.Assert(err, IsNil)
+	} else {
+		c.Assert(os.IsNotExist(err), Equals, true, Commentf("expected %v to be removed", path))
+	}
+}
+
+// testPackages is a package service with the specified packages
+type testPackages map[string]struct{}
+
+func (r testPackages) ReadPackageEnvelope(locator loc.Locator) (*pack.PackageEnvelope, error) {
+	if _, ok := r[locator.String()]; !ok {
+		return nil, trace.NotFound("package %v not found", locator)
+	}
+	return &pack.PackageEnvelope{Locator: locator}, nil
+}
+
+// testServices is a service manager that reports the specified status for all services
+type testServices string
+
+func (r testServices) StatusService(string) (string, error) {
+	return string(r), nil
+}
+
+const clusterName = "example.com"
@@ -31,6 +31,7 @@ import (
 	"github.com/gravitational/gravity/lib/storage"
 	"github.com/gravitational/gravity/lib/utils"
 	"github.com/gravitational/gravity/lib/vacuum/internal/fsm"
+	"github.com/gravitational/gravity/lib/vacuum/prune/state"
 
 	"github.com/gravitational/trace"
 	log "github.com/sirupsen/logrus"
@@ -103,6 +104,33 @@ func (r *Collector) RunPhase(ctx context.Context, phase string, phaseTimeout tim
 	}))
 }
 
+// SimulatePhase reports what the specified garbage collection phase
+// would do without executing it.
+// If the operation has no plan yet, the plan is generated but not persisted
+func (r *Collector) SimulatePhase(ctx context.Context, phase string, phaseTimeout time.Duration, force bool) (*libfsm.SimulationReport, error) {
+	plan, err := r.getOrGenerateOperationPlan()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+
+	machine, err := r.newMachine()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, phaseTimeout)
+	defer cancel()
+
+	report, err := machine.SimulatePlanPhase(ctx, *plan, libfsm.Params{
+		PhaseID: phase,
+		Force:   force,
+	})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return report, nil
+}
+
 // Create creates the garbage collection operation but does not start it.
 func (r *Collector) Create(ctx context.Context) error {
 	_, err := r.init()
@@ -119,6 +147,10 @@ func (r *Collector) init() (*libfsm.FSM, error) {
 		return nil, trace.Wrap(err)
 	}
 
+	return r.newMachine()
+}
+
+func (r *Collector) newMachine() (*libfsm.FSM, error) {
 	machine, err := fsm.New(fsm.Config{
 		App:           r.App,
 		RemoteApps:    r.RemoteApps,
@@ -228,6 +260,8 @@ type Config struct {
 	Runner libfsm.AgentRepository
 	// RuntimePath is the path to the runtime container's rootfs
 	RuntimePath string
+	// Retention specifies the operations exempt from pruning of stale operation data
+	Retention state.Retention
 	// FieldLogger is the logger to use
 	log.FieldLogger
 	// Silent controls whether the process outputs messages to stdout
@@ -1709,9 +1709,11 @@ func (m *Handler) getSiteEndpoints(w http.ResponseWriter, r *http.Request, p htt
 //
 //   report.tar
 func (m *Handler) getSiteReport(w http.ResponseWriter, r *http.Request, p httprouter.Params, context *AuthContext) (interface{}, error) {
-	reader, err := context.Operator.GetSiteReport(ops.SiteKey{
-		AccountID:  context.User.GetAccountID(),
-		SiteDomain: p.ByName("domain"),
+	reader, err := context.Operator.GetSiteReport(ops.GetClusterReportRequest{
+		SiteKey: ops.SiteKey{
+			AccountID:  context.User.GetAccountID(),
+			SiteDomain: p.ByName("domain"),
+		},
 	})
 	if err != nil {
 		return nil, trace.Wrap(err)
@@ -0,0 +1,98 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	blobcluster "github.com/gravitational/gravity/lib/blob/cluster"
+	"github.com/gravitational/gravity/lib/constants"
+	"github.com/gravitational/gravity/lib/localenv"
+
+	"github.com/fatih/color"
+	"github.com/gravitational/trace"
+)
+
+// blobStatus displays the replication health of objects in the cluster object storage
+func blobStatus(env *localenv.LocalEnvironment, format constants.Format) error {
+	clusterEnv, err := env.NewClusterEnvironment()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	status, err := blobcluster.GetStatus(blobcluster.StatusConfig{
+		Backend: clusterEnv.Backend,
+	})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	switch format {
+	case constants.EncodingJSON:
+		bytes, err := json.MarshalIndent(status, "", "    ")
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		fmt.Println(string(bytes))
+	case constants.EncodingText:
+		printBlobStatus(*status, os.Stdout)
+	default:
+		return trace.BadParameter("unsupported output format %q", format)
+	}
+	return nil
+}
+
+func printBlobStatus(status blobcluster.Status, out io.Writer) {
+	w := tabwriter.NewWriter(out, 0, 8, 1, '\t', 0)
+	fmt.Fprintf(w, "Write factor:\t%v\n", status.WriteFactor)
+	fmt.Fprintln(w, "Peers:")
+	for _, peer := range status.Peers {
+		state := color.GreenString("active")
+		if !peer.Active {
+			state = color.YellowString("inactive")
+		}
+		fmt.Fprintf(w, "    * %v (%v)\t%v, last heartbeat %v\n", peer.ID, peer.AdvertiseAddr,
+			state, peer.LastHeartbeat.Format(constants.HumanDateFormatSeconds))
+	}
+	if len(status.Objects) == 0 {
+		fmt.Fprintln(w, "No objects in the storage")
+		w.Flush()
+		return
+	}
+	fmt.Fprintln(w, "Objects:")
+	fmt.Fprintln(w, "Hash\tState\tReplicas\tActive peers")
+	fmt.Fprintln(w, "----\t-----\t--------\t------------")
+	for _, object := range status.Objects {
+		fmt.Fprintf(w, "%v\t%v\t%v/%v\t%v\n", object.Hash, colorObjectState(object.State),
+			len(object.ActivePeers), status.WriteFactor, strings.Join(object.ActivePeers, ", "))
+	}
+	w.Flush()
+}
+
+func colorObjectState(state string) string {
+	switch state {
+	case blobcluster.ObjectHealthy:
+		return color.GreenString(state)
+	case blobcluster.ObjectUnderReplicated:
+		return color.YellowString(state)
+	default:
+		return color.RedString(state)
+	}
+}
@@ -48,6 +48,11 @@ func checkManifest(env *localenv.LocalEnvironment, manifestPath, profileName str
 		return trace.Wrap(err)
 	}
 
+	if len(result.Warnings) > 0 {
+		fmt.Printf("The following checks produced warnings:\n%v",
+			checks.FormatFailedChecks(result.Warnings))
+	}
+
 	var failedErr, fixableErr error
 	if len(result.Failed) > 0 {
 		failedErr = trace.BadParameter(fmt.Sprintf("The following checks failed:\n%v",
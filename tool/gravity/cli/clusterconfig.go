@@ -83,17 +83,20 @@ func newConfigUpdater(ctx context.Context, localEnv, updateEnv *localenv.LocalEn
 }
 
 func executeConfigPhase(env, updateEnv *localenv.LocalEnvironment, params PhaseParams, operation ops.SiteOperation) error {
-	updater, err := getConfigUpdater(env, updateEnv, operation)
+	updater, err := getConfigUpdater(env, updateEnv, operation, params.ParallelPerServer)
 	if err != nil {
 		return trace.Wrap(err)
 	}
 	defer updater.Close()
+	if params.DryRun {
+		return trace.Wrap(simulateUpdatePhase(updater, params))
+	}
 	err = updater.RunPhase(context.TODO(), params.PhaseID, params.Timeout, params.Force)
 	return trace.Wrap(err)
 }
 
 func rollbackConfigPhase(env, updateEnv *localenv.LocalEnvironment, params PhaseParams, operation ops.SiteOperation) error {
-	updater, err := getConfigUpdater(env, updateEnv, operation)
+	updater, err := getConfigUpdater(env, updateEnv, operation, 0)
 	if err != nil {
 		return trace.Wrap(err)
 	}
@@ -103,7 +106,7 @@ func rollbackConfigPhase(env, updateEnv *localenv.LocalEnvironment, params Phase
 }
 
 func completeConfigPlan(env, updateEnv *localenv.LocalEnvironment, operation ops.SiteOperation) error {
-	updater, err := getConfigUpdater(env, updateEnv, operation)
+	updater, err := getConfigUpdater(env, updateEnv, operation, 0)
 	if err != nil {
 		return trace.Wrap(err)
 	}
@@ -111,7 +114,7 @@ func completeConfigPlan(env, updateEnv *localenv.LocalEnvironment, operation ops
 	return trace.Wrap(updater.Complete(nil))
 }
 
-func getConfigUpdater(localEnv, updateEnv *localenv.LocalEnvironment, operation ops.SiteOperation) (*update.Updater, error) {
+func getConfigUpdater(localEnv, updateEnv *localenv.LocalEnvironment, operation ops.SiteOperation, parallelPerServer int) (*update.Updater, error) {
 	clusterEnv, err := localEnv.NewClusterEnvironment()
 	if err != nil {
 		return nil, trace.Wrap(err)
@@ -126,12 +129,13 @@ func getConfigUpdater(localEnv, updateEnv *localenv.LocalEnvironment, operation
 
 	updater, err := clusterconfig.New(context.TODO(), clusterconfig.Config{
 		Config: update.Config{
-			Operation:    &operation,
-			Operator:     operator,
-			Backend:      clusterEnv.Backend,
-			LocalBackend: updateEnv.Backend,
-			Runner:       runner,
-			Silent:       localEnv.Silent,
+			Operation:         &operation,
+			Operator:          operator,
+			Backend:           clusterEnv.Backend,
+			LocalBackend:      updateEnv.Backend,
+			Runner:            runner,
+			Silent:            localEnv.Silent,
+			ParallelPerServer: parallelPerServer,
 			FieldLogger: logrus.WithFields(logrus.Fields{
 				trace.Component: "update:clusterconfig",
 				"operation":     operation,
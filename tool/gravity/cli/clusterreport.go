@@ -0,0 +1,270 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"time"
+
+	"github.com/gravitational/gravity/lib/archive"
+	"github.com/gravitational/gravity/lib/constants"
+	"github.com/gravitational/gravity/lib/defaults"
+	libfsm "github.com/gravitational/gravity/lib/fsm"
+	"github.com/gravitational/gravity/lib/localenv"
+	"github.com/gravitational/gravity/lib/ops"
+	"github.com/gravitational/gravity/lib/ops/opsservice"
+	"github.com/gravitational/gravity/lib/report"
+	"github.com/gravitational/gravity/lib/rpc"
+	"github.com/gravitational/gravity/lib/storage"
+	"github.com/gravitational/gravity/lib/systeminfo"
+	"github.com/gravitational/gravity/lib/utils"
+
+	"github.com/gravitational/trace"
+)
+
+// clusterReport collects diagnostics from the cluster nodes in parallel
+// using the RPC agents and packages them into a single tarball with
+// a directory per node and the summary index
+func clusterReport(env *localenv.LocalEnvironment, targetFile string, filter clusterReportFilter) error {
+	operator, err := env.SiteOperator()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	cluster, err := operator.GetLocalSite()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	servers := opsservice.FilterReportServers(cluster.ClusterState.Servers, filter.nodes)
+	if len(servers) == 0 {
+		return trace.NotFound("no cluster nodes match %v", filter.nodes)
+	}
+
+	// kubernetes diagnostics are collected on the local node
+	// regardless of the node filter
+	collector := &agentReportCollector{
+		req: ops.GetClusterReportRequest{
+			Since:          filter.since,
+			Namespaces:     filter.namespaces,
+			RedactPatterns: filter.redactPatterns,
+		},
+		localSystem: true,
+	}
+	local, err := findLocalServer(*cluster)
+	if err != nil && !trace.IsNotFound(err) {
+		return trace.Wrap(err)
+	}
+	if err != nil {
+		log.WithError(err).Warn("Failed to find local node, kubernetes diagnostics will not be collected.")
+	} else if len(opsservice.FilterReportServers(servers, []string{local.AdvertiseIP})) == 0 {
+		servers = append(servers, *local)
+		collector.localSystem = false
+	}
+
+	ctx := context.Background()
+	runner, err := connectReportAgents(ctx, env, *cluster, servers)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	defer runner.Close()
+
+	dir, err := ioutil.TempDir("", "report")
+	if err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	defer os.RemoveAll(dir)
+
+	env.Printf("Collecting diagnostics from %v nodes.\n", len(servers))
+	collector.runner = runner
+	index, err := report.CollectCluster(ctx, report.ClusterConfig{
+		Servers:   servers,
+		Collector: collector,
+	}, report.NewFileWriter(dir))
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	f, err := os.Create(targetFile)
+	if err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	defer f.Close()
+	gzWriter := gzip.NewWriter(f)
+	if err := archive.CompressDirectory(dir, gzWriter); err != nil {
+		return trace.Wrap(err)
+	}
+	if err := gzWriter.Close(); err != nil {
+		return trace.Wrap(err)
+	}
+
+	for _, node := range index.Nodes {
+		status := "collected"
+		if node.Error != "" {
+			status = fmt.Sprintf("failed: %v", node.Error)
+		}
+		env.Printf("    * %v (%v) in %v: %v\n", node.Hostname, node.AdvertiseIP,
+			node.Duration.Round(time.Millisecond), status)
+	}
+	env.Printf("Report for %v exported to %v.\n", cluster.Domain, targetFile)
+	if failed := index.Failed(); len(failed) != 0 {
+		return trace.BadParameter("failed to collect diagnostics from %v of %v nodes, see %v in the report",
+			len(failed), len(index.Nodes), defaults.ClusterReportIndex)
+	}
+	return nil
+}
+
+// connectReportAgents returns the runner that uses the RPC agents
+// running on the specified servers.
+// The agents are reused if they are already running, otherwise
+// they are deployed for the duration of the collection and shut down
+// when the returned runner is closed
+func connectReportAgents(ctx context.Context, env *localenv.LocalEnvironment, cluster ops.Site, servers []storage.Server) (*reportAgentRunner, error) {
+	creds, err := libfsm.GetClientCredentials()
+	if err == nil {
+		runner := libfsm.NewAgentRunner(creds)
+		err = checkReportAgents(ctx, runner, servers)
+		if err == nil {
+			log.Info("Reusing running agents.")
+			return &reportAgentRunner{AgentRepository: runner}, nil
+		}
+		runner.Close()
+	}
+	log.WithError(err).Info("Agents are not running, deploying.")
+
+	clusterEnv, err := env.NewClusterEnvironment()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+
+	teleportClient, err := env.TeleportClient(constants.Localhost)
+	if err != nil {
+		return nil, trace.Wrap(err, "failed to create a teleport client")
+	}
+
+	proxy, err := teleportClient.ConnectToProxy(ctx)
+	if err != nil {
+		return nil, trace.Wrap(err, "failed to connect to teleport proxy")
+	}
+
+	deployCtx, cancel := context.WithTimeout(ctx, defaults.AgentDeployTimeout)
+	defer cancel()
+	clusterState := cluster.ClusterState
+	clusterState.Servers = servers
+	creds, err = deployAgents(deployCtx, deployAgentsRequest{
+		clusterState: clusterState,
+		clusterName:  cluster.Domain,
+		clusterEnv:   clusterEnv,
+		proxy:        proxy,
+	})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &reportAgentRunner{
+		AgentRepository: libfsm.NewAgentRunner(creds),
+		servers:         servers,
+	}, nil
+}
+
+// checkReportAgents verifies that the agents are running on the specified servers
+func checkReportAgents(ctx context.Context, runner libfsm.AgentRepository, servers []storage.Server) error {
+	for _, server := range servers {
+		if systeminfo.HasInterface(server.AdvertiseIP) == nil {
+			continue
+		}
+		ctx, cancel := context.WithTimeout(ctx, defaults.DialTimeout)
+		err := runner.CanExecute(ctx, server)
+		cancel()
+		if err != nil {
+			return trace.Wrap(err)
+		}
+	}
+	return nil
+}
+
+// reportAgentRunner provides access to the RPC agents
+// used for the report collection
+type reportAgentRunner struct {
+	libfsm.AgentRepository
+	// servers lists the servers the agents have been deployed to.
+	// Empty if the running agents are reused
+	servers []storage.Server
+}
+
+// Close shuts down the agents deployed for the report collection
+// and closes the agent clients
+func (r *reportAgentRunner) Close() error {
+	if len(r.servers) != 0 {
+		var addrs []string
+		for _, server := range r.servers {
+			addrs = append(addrs, server.AdvertiseIP)
+		}
+		err := rpc.ShutdownAgents(context.TODO(), addrs, log, r.AgentRepository)
+		if err != nil {
+			log.WithError(err).Warn("Failed to shut down agents.")
+		}
+	}
+	return r.AgentRepository.Close()
+}
+
+// agentReportCollector collects the diagnostics on a cluster node
+// with the gravity system report command executed by the node's agent.
+// The local node additionally collects kubernetes diagnostics
+type agentReportCollector struct {
+	runner libfsm.AgentRepository
+	// req specifies the diagnostics to collect
+	req ops.GetClusterReportRequest
+	// localSystem specifies whether to collect system diagnostics
+	// on the local node. Only kubernetes diagnostics are collected on the
+	// local node if it has been excluded by the node filter
+	localSystem bool
+}
+
+// Collect streams the diagnostics of the specified server to w.
+// Implements report.NodeCollector
+func (r *agentReportCollector) Collect(ctx context.Context, server storage.Server, w io.Writer) error {
+	logger := log.WithField("node", server.Hostname)
+	if systeminfo.HasInterface(server.AdvertiseIP) == nil {
+		filters := []string{constants.ReportFilterKubernetes}
+		if r.localSystem {
+			filters = []string{constants.ReportFilterSystem, constants.ReportFilterKubernetes}
+		}
+		args := opsservice.SystemReportArgs(r.req, filters...)
+		var stderr bytes.Buffer
+		cmd := exec.CommandContext(ctx, utils.Exe.Path, args...)
+		cmd.Stdout = w
+		cmd.Stderr = &stderr
+		err := cmd.Run()
+		if err != nil {
+			return trace.Wrap(err, "failed to collect diagnostics: %s", stderr.String())
+		}
+		return nil
+	}
+	clt, err := r.runner.GetClient(ctx, server.AdvertiseIP)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return trace.Wrap(clt.GravityCommandOutput(ctx, logger, w,
+		opsservice.SystemReportArgs(r.req, constants.ReportFilterSystem)...))
+}
@@ -103,17 +103,20 @@ func newClusterUpdater(
 }
 
 func executeUpdatePhase(env, updateEnv *localenv.LocalEnvironment, params PhaseParams, operation ops.SiteOperation) error {
-	updater, err := getClusterUpdater(env, updateEnv, operation, params.SkipVersionCheck)
+	updater, err := getClusterUpdater(env, updateEnv, operation, params.SkipVersionCheck, params.ParallelPerServer)
 	if err != nil {
 		return trace.Wrap(err)
 	}
 	defer updater.Close()
+	if params.DryRun {
+		return trace.Wrap(simulateUpdatePhase(updater, params))
+	}
 	err = updater.RunPhase(context.TODO(), params.PhaseID, params.Timeout, params.Force)
 	return trace.Wrap(err)
 }
 
 func rollbackUpdatePhase(env, updateEnv *localenv.LocalEnvironment, params PhaseParams, operation ops.SiteOperation) error {
-	updater, err := getClusterUpdater(env, updateEnv, operation, params.SkipVersionCheck)
+	updater, err := getClusterUpdater(env, updateEnv, operation, params.SkipVersionCheck, 0)
 	if err != nil {
 		return trace.Wrap(err)
 	}
@@ -123,7 +126,7 @@ func rollbackUpdatePhase(env, updateEnv *localenv.LocalEnvironment, params Phase
 }
 
 func completeUpdatePlan(env, updateEnv *localenv.LocalEnvironment, operation ops.SiteOperation) error {
-	updater, err := getClusterUpdater(env, updateEnv, operation, true)
+	updater, err := getClusterUpdater(env, updateEnv, operation, true, 0)
 	if err != nil {
 		return trace.Wrap(err)
 	}
@@ -131,7 +134,7 @@ func completeUpdatePlan(env, updateEnv *localenv.LocalEnvironment, operation ops
 	return trace.Wrap(updater.Complete(nil))
 }
 
-func getClusterUpdater(localEnv, updateEnv *localenv.LocalEnvironment, operation ops.SiteOperation, noValidateVersion bool) (*update.Updater, error) {
+func getClusterUpdater(localEnv, updateEnv *localenv.LocalEnvironment, operation ops.SiteOperation, noValidateVersion bool, parallelPerServer int) (*update.Updater, error) {
 	clusterEnv, err := localEnv.NewClusterEnvironment()
 	if err != nil {
 		return nil, trace.Wrap(err)
@@ -146,12 +149,13 @@ func getClusterUpdater(localEnv, updateEnv *localenv.LocalEnvironment, operation
 
 	updater, err := clusterupdate.New(context.TODO(), clusterupdate.Config{
 		Config: update.Config{
-			Operation:    &operation,
-			Operator:     operator,
-			Backend:      clusterEnv.Backend,
-			LocalBackend: updateEnv.Backend,
-			Runner:       runner,
-			Silent:       localEnv.Silent,
+			Operation:         &operation,
+			Operator:          operator,
+			Backend:           clusterEnv.Backend,
+			LocalBackend:      updateEnv.Backend,
+			Runner:            runner,
+			Silent:            localEnv.Silent,
+			ParallelPerServer: parallelPerServer,
 		},
 		Apps:              clusterEnv.Apps,
 		Client:            clusterEnv.Client,
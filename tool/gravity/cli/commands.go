@@ -96,6 +96,10 @@ type Application struct {
 	UpgradeCmd UpgradeCmd
 	// StatusCmd displays cluster status
 	StatusCmd StatusCmd
+	// StatusClusterCmd displays the current cluster status
+	StatusClusterCmd StatusClusterCmd
+	// StatusHistoryCmd displays the cluster health history
+	StatusHistoryCmd StatusHistoryCmd
 	// StatusResetCmd resets the cluster to active state
 	StatusResetCmd StatusResetCmd
 	// BackupCmd launches app backup hook
@@ -266,6 +270,14 @@ type Application struct {
 	SystemReportCmd SystemReportCmd
 	// SystemStateDirCmd shows local state directory
 	SystemStateDirCmd SystemStateDirCmd
+	// SystemStateCmd combines local state related subcommands
+	SystemStateCmd SystemStateCmd
+	// SystemStateBackupCmd backs up the local state
+	SystemStateBackupCmd SystemStateBackupCmd
+	// SystemStateRestoreCmd restores the local state from a backup
+	SystemStateRestoreCmd SystemStateRestoreCmd
+	// SystemStateVerifyCmd verifies the local state backup
+	SystemStateVerifyCmd SystemStateVerifyCmd
 	// SystemDevicemapperCmd combines devicemapper related subcommands
 	SystemDevicemapperCmd SystemDevicemapperCmd
 	// SystemDevicemapperMountCmd configures devicemapper environment
@@ -288,6 +300,10 @@ type Application struct {
 	SystemGCPackageCmd SystemGCPackageCmd
 	// SystemGCRegistryCmd removes unused docker images
 	SystemGCRegistryCmd SystemGCRegistryCmd
+	// SystemGCStateCmd removes stale operation data and leftover node state
+	SystemGCStateCmd SystemGCStateCmd
+	// SystemBlobStatusCmd displays replication health of the cluster object storage
+	SystemBlobStatusCmd SystemBlobStatusCmd
 	// GarbageCollectCmd prunes unused resources (package/journal files/docker images)
 	// in the cluster
 	GarbageCollectCmd GarbageCollectCmd
@@ -429,6 +445,8 @@ type JoinCmd struct {
 	Force *bool
 	// OperationID is the ID of the operation created via UI
 	OperationID *string
+	// Parallel is the number of phases to execute concurrently on each server
+	Parallel *int
 }
 
 // AutoJoinCmd uses cloud provider info to join existing cluster
@@ -480,6 +498,8 @@ type PlanDisplayCmd struct {
 	*kingpin.CmdClause
 	// Output is output format
 	Output *constants.Format
+	// Timeline displays the history of phase execution attempts
+	Timeline *bool
 }
 
 // PlanExecuteCmd executes a phase of an active operation
@@ -491,6 +511,12 @@ type PlanExecuteCmd struct {
 	Force *bool
 	// PhaseTimeout is the execution timeout
 	PhaseTimeout *time.Duration
+	// DryRun only reports what the phase would do without executing it
+	DryRun *bool
+	// Output is the format of the dry run report
+	Output *constants.Format
+	// Parallel is the number of phases to execute concurrently on each server
+	Parallel *int
 }
 
 // PlanRollbackCmd rolls back a phase of an active operation
@@ -511,6 +537,8 @@ type PlanResumeCmd struct {
 	Force *bool
 	// PhaseTimeout is the rollback timeout
 	PhaseTimeout *time.Duration
+	// Parallel is the number of phases to execute concurrently on each server
+	Parallel *int
 }
 
 // PlanCompleteCmd completes the operation plan
@@ -620,6 +648,8 @@ type UpgradeCmd struct {
 	Resume *bool
 	// SkipVersionCheck suppresses version mismatch errors
 	SkipVersionCheck *bool
+	// Parallel is the number of phases to execute concurrently on each server
+	Parallel *int
 }
 
 // StatusCmd displays cluster status
@@ -637,6 +667,18 @@ type StatusCmd struct {
 	Output *constants.Format
 }
 
+// StatusClusterCmd displays the current cluster status
+type StatusClusterCmd struct {
+	*kingpin.CmdClause
+}
+
+// StatusHistoryCmd displays the cluster health history
+type StatusHistoryCmd struct {
+	*kingpin.CmdClause
+	// Since is the period to display the history for
+	Since *time.Duration
+}
+
 // StatusResetCmd resets cluster to active state
 type StatusResetCmd struct {
 	*kingpin.CmdClause
@@ -1205,6 +1247,16 @@ type ReportCmd struct {
 	*kingpin.CmdClause
 	// FilePath is the report tarball path
 	FilePath *string
+	// Since limits the collected logs to the specified period
+	Since *time.Duration
+	// Namespaces limits kubernetes diagnostics to the specified namespaces
+	Namespaces *[]string
+	// Nodes limits node diagnostics to the specified nodes
+	Nodes *[]string
+	// RedactPatterns lists additional regular expressions to redact
+	RedactPatterns *[]string
+	// Cluster collects diagnostics from all cluster nodes using the RPC agents
+	Cluster *bool
 }
 
 // SiteCmd combines cluster related subcommands
@@ -1451,6 +1503,12 @@ type SystemReportCmd struct {
 	Filter *[]string
 	// Compressed allows to gzip the tarball
 	Compressed *bool
+	// Since limits the collected logs to the specified period
+	Since *time.Duration
+	// Namespaces limits kubernetes diagnostics to the specified namespaces
+	Namespaces *[]string
+	// EncodedRedactPatterns lists additional encoded regular expressions to redact
+	EncodedRedactPatterns *[]string
 }
 
 // SystemStateDirCmd shows local state directory
@@ -1458,6 +1516,34 @@ type SystemStateDirCmd struct {
 	*kingpin.CmdClause
 }
 
+// SystemStateCmd combines local state related subcommands
+type SystemStateCmd struct {
+	*kingpin.CmdClause
+}
+
+// SystemStateBackupCmd backs up the local state
+type SystemStateBackupCmd struct {
+	*kingpin.CmdClause
+	// File is the path of the resulting backup file
+	File *string
+}
+
+// SystemStateRestoreCmd restores the local state from a backup
+type SystemStateRestoreCmd struct {
+	*kingpin.CmdClause
+	// File is the path of the backup file
+	File *string
+	// Confirmed suppresses confirmation prompt
+	Confirmed *bool
+}
+
+// SystemStateVerifyCmd verifies the local state backup
+type SystemStateVerifyCmd struct {
+	*kingpin.CmdClause
+	// File is the path of the backup file
+	File *string
+}
+
 // SystemDevicemapperCmd combines devicemapper related subcommands
 type SystemDevicemapperCmd struct {
 	*kingpin.CmdClause
@@ -1499,11 +1585,15 @@ type SystemExportRuntimeJournalCmd struct {
 	*kingpin.CmdClause
 	// OutputFile specifies the path of the resulting tarball
 	OutputFile *string
+	// Since limits the exported entries to the specified period
+	Since *time.Duration
 }
 
 // SystemStreamRuntimeJournalCmd streams contents of the runtime journal
 type SystemStreamRuntimeJournalCmd struct {
 	*kingpin.CmdClause
+	// Since limits the streamed entries to the specified period
+	Since *time.Duration
 }
 
 // SystemGCJournalCmd manages cleanup of journal files
@@ -1539,6 +1629,28 @@ type SystemGCRegistryCmd struct {
 	DryRun *bool
 }
 
+// SystemGCStateCmd removes stale operation data and leftover node state
+type SystemGCStateCmd struct {
+	*kingpin.CmdClause
+	// DryRun displays the state to be removed
+	// without actually removing anything
+	DryRun *bool
+	// Cluster specifies whether to prune stale operation data
+	// in the cluster backend
+	Cluster *bool
+	// KeepOperations is the number of most recent operations to keep
+	KeepOperations *int
+	// KeepPeriod is the period of time to keep the operations for
+	KeepPeriod *time.Duration
+}
+
+// SystemBlobStatusCmd displays replication health of the cluster object storage
+type SystemBlobStatusCmd struct {
+	*kingpin.CmdClause
+	// Output is the output format
+	Output *constants.Format
+}
+
 // GarbageCollectCmd prunes unused cluster resources
 type GarbageCollectCmd struct {
 	*kingpin.CmdClause
@@ -1547,6 +1659,12 @@ type GarbageCollectCmd struct {
 	// Confirmed is whether the user has confirmed the removal of custom docker
 	// images
 	Confirmed *bool
+	// KeepOperations is the number of most recent operations
+	// exempt from pruning of stale operation data
+	KeepOperations *int
+	// KeepPeriod is the period of time recent operations are exempt
+	// from pruning of stale operation data for
+	KeepPeriod *time.Duration
 }
 
 // GarbageCollectPlanCmd displays the plan of the garbage collection operation
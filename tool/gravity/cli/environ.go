@@ -73,17 +73,20 @@ func newEnvironUpdater(ctx context.Context, localEnv, updateEnv *localenv.LocalE
 }
 
 func executeEnvironPhase(env, updateEnv *localenv.LocalEnvironment, params PhaseParams, operation ops.SiteOperation) error {
-	updater, err := getEnvironUpdater(env, updateEnv, operation)
+	updater, err := getEnvironUpdater(env, updateEnv, operation, params.ParallelPerServer)
 	if err != nil {
 		return trace.Wrap(err)
 	}
 	defer updater.Close()
+	if params.DryRun {
+		return trace.Wrap(simulateUpdatePhase(updater, params))
+	}
 	err = updater.RunPhase(context.TODO(), params.PhaseID, params.Timeout, params.Force)
 	return trace.Wrap(err)
 }
 
 func rollbackEnvironPhase(env, updateEnv *localenv.LocalEnvironment, params PhaseParams, operation ops.SiteOperation) error {
-	updater, err := getEnvironUpdater(env, updateEnv, operation)
+	updater, err := getEnvironUpdater(env, updateEnv, operation, 0)
 	if err != nil {
 		return trace.Wrap(err)
 	}
@@ -93,7 +96,7 @@ func rollbackEnvironPhase(env, updateEnv *localenv.LocalEnvironment, params Phas
 }
 
 func completeEnvironPlan(env, updateEnv *localenv.LocalEnvironment, operation ops.SiteOperation) error {
-	updater, err := getEnvironUpdater(env, updateEnv, operation)
+	updater, err := getEnvironUpdater(env, updateEnv, operation, 0)
 	if err != nil {
 		return trace.Wrap(err)
 	}
@@ -101,7 +104,7 @@ func completeEnvironPlan(env, updateEnv *localenv.LocalEnvironment, operation op
 	return trace.Wrap(updater.Complete(nil))
 }
 
-func getEnvironUpdater(env, updateEnv *localenv.LocalEnvironment, operation ops.SiteOperation) (*update.Updater, error) {
+func getEnvironUpdater(env, updateEnv *localenv.LocalEnvironment, operation ops.SiteOperation, parallelPerServer int) (*update.Updater, error) {
 	clusterEnv, err := env.NewClusterEnvironment()
 	if err != nil {
 		return nil, trace.Wrap(err)
@@ -116,12 +119,13 @@ func getEnvironUpdater(env, updateEnv *localenv.LocalEnvironment, operation ops.
 
 	updater, err := environ.New(context.TODO(), environ.Config{
 		Config: update.Config{
-			Operation:    &operation,
-			Operator:     operator,
-			Backend:      clusterEnv.Backend,
-			LocalBackend: updateEnv.Backend,
-			Silent:       env.Silent,
-			Runner:       runner,
+			Operation:         &operation,
+			Operator:          operator,
+			Backend:           clusterEnv.Backend,
+			LocalBackend:      updateEnv.Backend,
+			Silent:            env.Silent,
+			Runner:            runner,
+			ParallelPerServer: parallelPerServer,
 			FieldLogger: logrus.WithFields(logrus.Fields{
 				trace.Component: "update:environ",
 				"operation":     operation,
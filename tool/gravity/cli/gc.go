@@ -18,6 +18,7 @@ package cli
 
 import (
 	"context"
+	"path/filepath"
 
 	"github.com/gravitational/gravity/lib/app/docker"
 	"github.com/gravitational/gravity/lib/constants"
@@ -32,12 +33,13 @@ import (
 	"github.com/gravitational/gravity/lib/vacuum/prune/journal"
 	"github.com/gravitational/gravity/lib/vacuum/prune/pack"
 	"github.com/gravitational/gravity/lib/vacuum/prune/registry"
+	prunestate "github.com/gravitational/gravity/lib/vacuum/prune/state"
 
 	"github.com/gravitational/trace"
 	"github.com/sirupsen/logrus"
 )
 
-func garbageCollect(env *localenv.LocalEnvironment, manual, confirmed bool) error {
+func garbageCollect(env *localenv.LocalEnvironment, manual, confirmed bool, retention prunestate.Retention) error {
 	if !confirmed {
 		env.Println("This operation will also remove docker images that " +
 			"you manually pushed to the docker registry. Are you sure?")
@@ -51,7 +53,7 @@ func garbageCollect(env *localenv.LocalEnvironment, manual, confirmed bool) erro
 		}
 	}
 
-	collector, err := newCollector(env)
+	collector, err := newCollector(env, retention)
 	if err != nil {
 		return trace.Wrap(err)
 	}
@@ -84,7 +86,7 @@ $ gravity plan resume`)
 	return nil
 }
 
-func newCollector(env *localenv.LocalEnvironment) (*vacuum.Collector, error) {
+func newCollector(env *localenv.LocalEnvironment, retention prunestate.Retention) (*vacuum.Collector, error) {
 	clusterPackages, err := env.ClusterPackages()
 	if err != nil {
 		return nil, trace.Wrap(err)
@@ -195,6 +197,7 @@ func newCollector(env *localenv.LocalEnvironment) (*vacuum.Collector, error) {
 		Servers:       cluster.ClusterState.Servers,
 		ClusterKey:    cluster.Key(),
 		RuntimePath:   runtimePath,
+		Retention:     retention,
 		Silent:        env.Silent,
 		Runner:        runner,
 	})
@@ -263,6 +266,13 @@ func executeGarbageCollectPhase(env *localenv.LocalEnvironment, params PhasePara
 		return trace.Wrap(err)
 	}
 
+	if params.DryRun {
+		report, err := collector.SimulatePhase(context.TODO(), params.PhaseID, params.Timeout, params.Force)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		return outputSimulationReport(*report, params.Output)
+	}
 	err = collector.RunPhase(context.TODO(), params.PhaseID, params.Timeout, params.Force)
 	return trace.Wrap(err)
 }
@@ -437,3 +447,83 @@ func removeUnusedJournalFiles(env *localenv.LocalEnvironment, machineIDFile, log
 	err = pruner.Prune(context.TODO())
 	return trace.Wrap(err)
 }
+
+func removeStaleState(env *localenv.LocalEnvironment, dryRun, pruneClusterState bool, retention prunestate.Retention) error {
+	stateDir, err := state.GetStateDir()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	clusterPackages, err := env.ClusterPackages()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	siteUnpackedDir, err := localenv.SiteUnpackedDir()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	config := prune.Config{
+		DryRun:      dryRun,
+		FieldLogger: logrus.WithField(trace.Component, "gc:state"),
+		Silent:      env.Silent,
+	}
+	pruner, err := prunestate.NewNode(prunestate.NodeConfig{
+		UnpackedDirs: []prunestate.UnpackedDir{
+			{
+				Dir:      filepath.Join(env.StateDir, defaults.PackagesDir, defaults.UnpackedDir),
+				Packages: env.Packages,
+			},
+			{
+				Dir:      siteUnpackedDir,
+				Packages: clusterPackages,
+			},
+		},
+		AgentDir: state.GravityRPCAgentDir(stateDir),
+		Config:   config,
+	})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	ctx := context.TODO()
+	err = pruner.Prune(ctx)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	if !pruneClusterState {
+		return nil
+	}
+
+	clusterEnv, err := localenv.NewClusterEnvironment()
+	if err != nil {
+		return trace.Wrap(err, "stale cluster state can only be pruned on a master node")
+	}
+	defer clusterEnv.Backend.Close()
+
+	cluster, err := clusterEnv.Operator.GetLocalSite()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	logDir, err := localenv.SiteDir()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	clusterPruner, err := prunestate.NewCluster(prunestate.ClusterConfig{
+		ClusterName:     cluster.Domain,
+		Backend:         clusterEnv.Backend,
+		OperationLogDir: logDir,
+		Retention:       retention,
+		Config:          config,
+	})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	err = clusterPruner.Prune(ctx)
+	return trace.Wrap(err)
+}
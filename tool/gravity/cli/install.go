@@ -23,7 +23,10 @@ import (
 	"strings"
 	"time"
 
+	"github.com/gravitational/gravity/lib/autoscale"
 	autoscaleaws "github.com/gravitational/gravity/lib/autoscale/aws"
+	"github.com/gravitational/gravity/lib/autoscale/azure"
+	"github.com/gravitational/gravity/lib/autoscale/gce"
 	cloudaws "github.com/gravitational/gravity/lib/cloudprovider/aws"
 	"github.com/gravitational/gravity/lib/defaults"
 	"github.com/gravitational/gravity/lib/expand"
@@ -41,6 +44,7 @@ import (
 	"github.com/gravitational/gravity/lib/systemservice"
 	"github.com/gravitational/gravity/lib/utils"
 
+	"cloud.google.com/go/compute/metadata"
 	"github.com/gravitational/configure"
 	"github.com/gravitational/trace"
 	"github.com/sirupsen/logrus"
@@ -277,24 +281,17 @@ func autojoin(env, joinEnv *localenv.LocalEnvironment, d autojoinConfig) error {
 		return trace.Wrap(err)
 	}
 
-	instance, err := cloudaws.NewLocalInstance()
-	if err != nil {
-		return trace.BadParameter("autojoin only supports AWS")
-	}
-
-	autoscaler, err := autoscaleaws.New(autoscaleaws.Config{
-		ClusterName: d.clusterName,
-	})
+	discovery, advertiseAddr, err := newAutojoinDiscovery(context.TODO(), d.clusterName)
 	if err != nil {
 		return trace.Wrap(err)
 	}
 
-	joinToken, err := autoscaler.GetJoinToken(context.TODO())
+	joinToken, err := discovery.GetJoinToken(context.TODO())
 	if err != nil {
 		return trace.Wrap(err)
 	}
 
-	serviceURL, err := autoscaler.GetServiceURL(context.TODO())
+	serviceURL, err := discovery.GetServiceURL(context.TODO())
 	if err != nil {
 		return trace.Wrap(err)
 	}
@@ -304,7 +301,7 @@ func autojoin(env, joinEnv *localenv.LocalEnvironment, d autojoinConfig) error {
 	return Join(env, joinEnv, JoinConfig{
 		SystemLogFile: d.systemLogFile,
 		UserLogFile:   d.userLogFile,
-		AdvertiseAddr: instance.PrivateIP,
+		AdvertiseAddr: advertiseAddr,
 		PeerAddrs:     serviceURL,
 		Token:         joinToken,
 		Role:          d.role,
@@ -314,6 +311,35 @@ func autojoin(env, joinEnv *localenv.LocalEnvironment, d autojoinConfig) error {
 	})
 }
 
+// newAutojoinDiscovery returns the discovery of the cluster join parameters
+// and the address to advertise for the cloud provider this instance runs on
+func newAutojoinDiscovery(ctx context.Context, clusterName string) (autoscale.Discovery, string, error) {
+	if instance, err := cloudaws.NewLocalInstance(); err == nil {
+		autoscaler, err := autoscaleaws.New(autoscaleaws.Config{
+			ClusterName: clusterName,
+		})
+		if err != nil {
+			return nil, "", trace.Wrap(err)
+		}
+		return autoscaler, instance.PrivateIP, nil
+	}
+	if metadata.OnGCE() {
+		advertiseAddr, err := metadata.InternalIP()
+		if err != nil {
+			return nil, "", trace.Wrap(err)
+		}
+		return gce.NewDiscovery(clusterName), advertiseAddr, nil
+	}
+	if azure.OnAzure(ctx) {
+		advertiseAddr, err := azure.GetPrivateIP(ctx)
+		if err != nil {
+			return nil, "", trace.Wrap(err)
+		}
+		return azure.NewDiscovery(clusterName), advertiseAddr, nil
+	}
+	return nil, "", trace.BadParameter("autojoin only supports AWS, GCE and Azure")
+}
+
 func (r *agentConfig) checkAndSetDefaults() (err error) {
 	if r.serviceUID == "" {
 		return trace.BadParameter("service user ID is required")
@@ -487,6 +513,7 @@ func executeInstallPhase(localEnv *localenv.LocalEnvironment, p PhaseParams, ope
 		LocalApps:          localApps,
 		LocalBackend:       localEnv.Backend,
 		Insecure:           localEnv.Insecure,
+		ParallelPerServer:  p.ParallelPerServer,
 	})
 	if err != nil {
 		return trace.Wrap(err)
@@ -494,6 +521,9 @@ func executeInstallPhase(localEnv *localenv.LocalEnvironment, p PhaseParams, ope
 
 	ctx, cancel := context.WithTimeout(context.Background(), p.Timeout)
 	defer cancel()
+	if p.DryRun {
+		return trace.Wrap(simulatePhase(ctx, installFSM, p))
+	}
 	progress := utils.NewProgress(ctx, fmt.Sprintf("Executing install phase %q", p.PhaseID), -1, false)
 	defer progress.Stop()
 
@@ -534,22 +564,26 @@ func executeJoinPhase(localEnv, joinEnv *localenv.LocalEnvironment, p PhaseParam
 		}
 	}
 	joinFSM, err := expand.NewFSM(expand.FSMConfig{
-		OperationKey:  operation.Key(),
-		Operator:      operator,
-		Apps:          apps,
-		Packages:      packages,
-		LocalBackend:  localEnv.Backend,
-		LocalPackages: localEnv.Packages,
-		LocalApps:     localEnv.Apps,
-		JoinBackend:   joinEnv.Backend,
-		DebugMode:     localEnv.Debug,
-		Insecure:      localEnv.Insecure,
+		OperationKey:      operation.Key(),
+		Operator:          operator,
+		Apps:              apps,
+		Packages:          packages,
+		LocalBackend:      localEnv.Backend,
+		LocalPackages:     localEnv.Packages,
+		LocalApps:         localEnv.Apps,
+		JoinBackend:       joinEnv.Backend,
+		DebugMode:         localEnv.Debug,
+		Insecure:          localEnv.Insecure,
+		ParallelPerServer: p.ParallelPerServer,
 	})
 	if err != nil {
 		return trace.Wrap(err)
 	}
 	ctx, cancel := context.WithTimeout(context.Background(), p.Timeout)
 	defer cancel()
+	if p.DryRun {
+		return trace.Wrap(simulatePhase(ctx, joinFSM, p))
+	}
 	progress := utils.NewProgress(ctx, fmt.Sprintf("Executing join phase %q", p.PhaseID), -1, false)
 	defer progress.Stop()
 	if p.PhaseID == fsm.RootPhase {
@@ -19,15 +19,18 @@ package cli
 import (
 	"compress/gzip"
 	"context"
+	"fmt"
 	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"syscall"
+	"time"
 
 	"github.com/gravitational/gravity/lib/defaults"
 	"github.com/gravitational/gravity/lib/localenv"
 	"github.com/gravitational/gravity/lib/pack"
+	"github.com/gravitational/gravity/lib/report"
 	"github.com/gravitational/gravity/lib/state"
 	"github.com/gravitational/gravity/lib/system"
 	"github.com/gravitational/gravity/lib/system/mount"
@@ -37,7 +40,7 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
-func exportRuntimeJournal(env *localenv.LocalEnvironment, outputFile string) error {
+func exportRuntimeJournal(env *localenv.LocalEnvironment, outputFile string, since time.Duration) error {
 	stateDir, err := state.GetStateDir()
 	if err != nil {
 		return trace.Wrap(err)
@@ -90,7 +93,11 @@ func exportRuntimeJournal(env *localenv.LocalEnvironment, outputFile string) err
 
 	zip := gzip.NewWriter(w)
 	defer zip.Close()
-	cmd := exec.CommandContext(ctx, utils.Exe.Path, "system", "stream-runtime-journal")
+	args := []string{"system", "stream-runtime-journal"}
+	if since != 0 {
+		args = append(args, fmt.Sprintf("--since=%v", since))
+	}
+	cmd := exec.CommandContext(ctx, utils.Exe.Path, args...)
 	cmd.Stdout = zip
 	cmd.Stderr = zip
 	if err = cmd.Run(); err != nil {
@@ -100,7 +107,7 @@ func exportRuntimeJournal(env *localenv.LocalEnvironment, outputFile string) err
 	return nil
 }
 
-func streamRuntimeJournal(env *localenv.LocalEnvironment) error {
+func streamRuntimeJournal(env *localenv.LocalEnvironment, since time.Duration) error {
 	runtimePackage, err := pack.FindRuntimePackage(env.Packages)
 	if err != nil {
 		return trace.Wrap(err)
@@ -127,6 +134,9 @@ func streamRuntimeJournal(env *localenv.LocalEnvironment) error {
 		"--output", "export",
 		"-D", journalDir,
 	}
+	if since != 0 {
+		args = append(args, report.JournalSince(since))
+	}
 	if err := syscall.Exec(cmd, args, nil); err != nil {
 		return trace.Wrap(trace.ConvertSystemError(err),
 			"failed to execve(%q, %q)", cmd, args)
@@ -21,6 +21,8 @@ import (
 	"strings"
 	"time"
 
+	"github.com/gravitational/gravity/lib/constants"
+	"github.com/gravitational/gravity/lib/fsm"
 	"github.com/gravitational/gravity/lib/localenv"
 	"github.com/gravitational/gravity/lib/ops"
 	"github.com/gravitational/gravity/lib/storage"
@@ -43,6 +45,62 @@ type PhaseParams struct {
 	Timeout time.Duration
 	// SkipVersionCheck overrides the verification of binary version compatibility
 	SkipVersionCheck bool
+	// DryRun only reports what the phase would do without executing it
+	DryRun bool
+	// Output is the format of the dry run report
+	Output constants.Format
+	// ParallelPerServer is the number of phases to execute concurrently
+	// on each server when executing the whole plan.
+	// Phases are executed sequentially if unspecified
+	ParallelPerServer int
+}
+
+// planExecuteParams returns parameters for the phase specified with "plan execute"
+func planExecuteParams(g *Application) PhaseParams {
+	return PhaseParams{
+		PhaseID:           *g.PlanExecuteCmd.Phase,
+		Force:             *g.PlanExecuteCmd.Force,
+		Timeout:           *g.PlanExecuteCmd.PhaseTimeout,
+		SkipVersionCheck:  *g.PlanCmd.SkipVersionCheck,
+		OperationID:       *g.PlanCmd.OperationID,
+		DryRun:            *g.PlanExecuteCmd.DryRun,
+		Output:            *g.PlanExecuteCmd.Output,
+		ParallelPerServer: *g.PlanExecuteCmd.Parallel,
+	}
+}
+
+// planResumeParams returns parameters to resume the operation with "plan resume"
+func planResumeParams(g *Application) PhaseParams {
+	return PhaseParams{
+		PhaseID:           fsm.RootPhase,
+		Force:             *g.PlanResumeCmd.Force,
+		Timeout:           *g.PlanResumeCmd.PhaseTimeout,
+		SkipVersionCheck:  *g.PlanCmd.SkipVersionCheck,
+		OperationID:       *g.PlanCmd.OperationID,
+		ParallelPerServer: *g.PlanResumeCmd.Parallel,
+	}
+}
+
+// upgradePhaseParams returns parameters for the phase specified with "upgrade"
+func upgradePhaseParams(g *Application) PhaseParams {
+	return PhaseParams{
+		PhaseID:           *g.UpgradeCmd.Phase,
+		Force:             *g.UpgradeCmd.Force,
+		Timeout:           *g.UpgradeCmd.Timeout,
+		SkipVersionCheck:  *g.UpgradeCmd.SkipVersionCheck,
+		ParallelPerServer: *g.UpgradeCmd.Parallel,
+	}
+}
+
+// joinPhaseParams returns parameters for the phase specified with "join"
+func joinPhaseParams(g *Application) PhaseParams {
+	return PhaseParams{
+		PhaseID:           *g.JoinCmd.Phase,
+		Force:             *g.JoinCmd.Force,
+		Timeout:           *g.JoinCmd.PhaseTimeout,
+		OperationID:       *g.JoinCmd.OperationID,
+		ParallelPerServer: *g.JoinCmd.Parallel,
+	}
 }
 
 func executePhase(localEnv, updateEnv, joinEnv *localenv.LocalEnvironment, params PhaseParams) error {
@@ -50,6 +108,9 @@ func executePhase(localEnv, updateEnv, joinEnv *localenv.LocalEnvironment, param
 	if err != nil {
 		return trace.Wrap(err)
 	}
+	if params.DryRun && params.PhaseID == "" {
+		params.PhaseID = fsm.RootPhase
+	}
 	switch op.Type {
 	case ops.OperationInstall:
 		return executeInstallPhase(localEnv, params, op)
@@ -0,0 +1,77 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"testing"
+
+	"gopkg.in/alecthomas/kingpin.v2"
+	"gopkg.in/check.v1"
+)
+
+func TestCLI(t *testing.T) { check.TestingT(t) }
+
+type OperationSuite struct{}
+
+var _ = check.Suite(&OperationSuite{})
+
+func (s *OperationSuite) TestParsesParallelPerServer(c *check.C) {
+	var testCases = []struct {
+		args     []string
+		params   func(*Application) PhaseParams
+		expected int
+		comment  string
+	}{
+		{
+			args:     []string{"plan", "resume", "--parallel=3"},
+			params:   planResumeParams,
+			expected: 3,
+			comment:  "plan resume",
+		},
+		{
+			args:     []string{"plan", "execute", "--phase=/", "--parallel=2"},
+			params:   planExecuteParams,
+			expected: 2,
+			comment:  "plan execute",
+		},
+		{
+			args:     []string{"upgrade", "--resume", "--parallel=4"},
+			params:   upgradePhaseParams,
+			expected: 4,
+			comment:  "upgrade",
+		},
+		{
+			args:     []string{"join", "--resume", "--parallel=5"},
+			params:   joinPhaseParams,
+			expected: 5,
+			comment:  "join",
+		},
+		{
+			args:     []string{"plan", "resume"},
+			params:   planResumeParams,
+			expected: 0,
+			comment:  "sequential by default",
+		},
+	}
+	for _, tc := range testCases {
+		comment := check.Commentf(tc.comment)
+		g := RegisterCommands(kingpin.New("gravity", ""))
+		_, err := g.Parse(tc.args)
+		c.Assert(err, check.IsNil, comment)
+		c.Assert(tc.params(g).ParallelPerServer, check.Equals, tc.expected, comment)
+	}
+}
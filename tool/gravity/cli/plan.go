@@ -60,33 +60,33 @@ func initUpdateOperationPlan(localEnv, updateEnv *localenv.LocalEnvironment) err
 	return trace.Wrap(err)
 }
 
-func displayOperationPlan(localEnv, updateEnv, joinEnv *localenv.LocalEnvironment, operationID string, format constants.Format) error {
+func displayOperationPlan(localEnv, updateEnv, joinEnv *localenv.LocalEnvironment, operationID string, opts displayPlanOptions) error {
 	op, err := getLastOperation(localEnv, updateEnv, joinEnv, operationID)
 	if err != nil {
 		return trace.Wrap(err)
 	}
 	if op.IsCompleted() {
-		return displayClusterOperationPlan(localEnv, op.Key(), format)
+		return displayClusterOperationPlan(localEnv, op.Key(), opts)
 	}
 	switch op.Type {
 	case ops.OperationInstall:
-		return displayInstallOperationPlan(op.Key(), format)
+		return displayInstallOperationPlan(op.Key(), opts)
 	case ops.OperationExpand:
-		return displayExpandOperationPlan(joinEnv, op.Key(), format)
+		return displayExpandOperationPlan(joinEnv, op.Key(), opts)
 	case ops.OperationUpdate:
-		return displayUpdateOperationPlan(localEnv, updateEnv, op.Key(), format)
+		return displayUpdateOperationPlan(localEnv, updateEnv, op.Key(), opts)
 	case ops.OperationUpdateRuntimeEnviron:
-		return displayUpdateOperationPlan(localEnv, updateEnv, op.Key(), format)
+		return displayUpdateOperationPlan(localEnv, updateEnv, op.Key(), opts)
 	case ops.OperationUpdateConfig:
-		return displayUpdateOperationPlan(localEnv, updateEnv, op.Key(), format)
+		return displayUpdateOperationPlan(localEnv, updateEnv, op.Key(), opts)
 	case ops.OperationGarbageCollect:
-		return displayClusterOperationPlan(localEnv, op.Key(), format)
+		return displayClusterOperationPlan(localEnv, op.Key(), opts)
 	default:
 		return trace.BadParameter("unknown operation type %q", op.Type)
 	}
 }
 
-func displayClusterOperationPlan(env *localenv.LocalEnvironment, opKey ops.SiteOperationKey, format constants.Format) error {
+func displayClusterOperationPlan(env *localenv.LocalEnvironment, opKey ops.SiteOperationKey, opts displayPlanOptions) error {
 	clusterEnv, err := env.NewClusterEnvironment()
 	if err != nil {
 		return trace.Wrap(err)
@@ -95,11 +95,11 @@ func displayClusterOperationPlan(env *localenv.LocalEnvironment, opKey ops.SiteO
 	if err != nil {
 		return trace.Wrap(err)
 	}
-	err = outputPlan(*plan, format)
+	err = outputPlan(*plan, opts)
 	return trace.Wrap(err)
 }
 
-func displayUpdateOperationPlan(localEnv, updateEnv *localenv.LocalEnvironment, opKey ops.SiteOperationKey, format constants.Format) error {
+func displayUpdateOperationPlan(localEnv, updateEnv *localenv.LocalEnvironment, opKey ops.SiteOperationKey, opts displayPlanOptions) error {
 	plan, err := fsm.GetOperationPlan(updateEnv.Backend, opKey.SiteDomain, opKey.OperationID)
 	if err != nil {
 		return trace.Wrap(err)
@@ -110,14 +110,14 @@ func displayUpdateOperationPlan(localEnv, updateEnv *localenv.LocalEnvironment,
 	} else {
 		plan = reconciledPlan
 	}
-	err = outputPlan(*plan, format)
+	err = outputPlan(*plan, opts)
 	if err != nil {
 		return trace.Wrap(err)
 	}
 	return nil
 }
 
-func displayInstallOperationPlan(opKey ops.SiteOperationKey, format constants.Format) error {
+func displayInstallOperationPlan(opKey ops.SiteOperationKey, opts displayPlanOptions) error {
 	wizardEnv, err := localenv.NewRemoteEnvironment()
 	if err != nil {
 		return trace.Wrap(err)
@@ -141,7 +141,7 @@ was run.`)
 		return trace.Wrap(err)
 	}
 	log.Debug("Showing install operation plan retrieved from wizard process.")
-	err = outputPlan(*plan, format)
+	err = outputPlan(*plan, opts)
 	if err != nil {
 		return trace.Wrap(err)
 	}
@@ -149,17 +149,21 @@ was run.`)
 }
 
 // displayExpandOperationPlan shows plan of the join operation from the local join backend
-func displayExpandOperationPlan(joinEnv *localenv.LocalEnvironment, opKey ops.SiteOperationKey, format constants.Format) error {
+func displayExpandOperationPlan(joinEnv *localenv.LocalEnvironment, opKey ops.SiteOperationKey, opts displayPlanOptions) error {
 	plan, err := fsm.GetOperationPlan(joinEnv.Backend, opKey.SiteDomain, opKey.OperationID)
 	if err != nil {
 		return trace.Wrap(err)
 	}
 	log.Debug("Showing join operation plan retrieved from local join backend.")
-	return outputPlan(*plan, format)
+	return outputPlan(*plan, opts)
 }
 
-func outputPlan(plan storage.OperationPlan, format constants.Format) (err error) {
-	switch format {
+func outputPlan(plan storage.OperationPlan, opts displayPlanOptions) (err error) {
+	if opts.timeline {
+		fsm.FormatOperationPlanTimeline(os.Stdout, plan)
+		return nil
+	}
+	switch opts.format {
 	case constants.EncodingYAML:
 		err = fsm.FormatOperationPlanYAML(os.Stdout, plan)
 	case constants.EncodingJSON:
@@ -168,7 +172,7 @@ func outputPlan(plan storage.OperationPlan, format constants.Format) (err error)
 		fsm.FormatOperationPlanText(os.Stdout, plan)
 		err = explainPlan(plan.Phases)
 	default:
-		return trace.BadParameter("unknown output format %q", format)
+		return trace.BadParameter("unknown output format %q", opts.format)
 	}
 
 	if err != nil {
@@ -177,6 +181,42 @@ func outputPlan(plan storage.OperationPlan, format constants.Format) (err error)
 	return nil
 }
 
+// simulatePhase outputs the report of what the specified phase
+// would do if executed with the provided machine
+func simulatePhase(ctx context.Context, machine *fsm.FSM, params PhaseParams) error {
+	report, err := machine.SimulatePhase(ctx, fsm.Params{
+		PhaseID: params.PhaseID,
+		Force:   params.Force,
+	})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return outputSimulationReport(*report, params.Output)
+}
+
+// simulateUpdatePhase outputs the report of what the specified phase
+// of an update operation would do if executed
+func simulateUpdatePhase(updater *update.Updater, params PhaseParams) error {
+	report, err := updater.SimulatePhase(context.TODO(), params.PhaseID, params.Timeout, params.Force)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return outputSimulationReport(*report, params.Output)
+}
+
+// outputSimulationReport outputs the plan simulation report in the specified format
+func outputSimulationReport(report fsm.SimulationReport, format constants.Format) error {
+	switch format {
+	case constants.EncodingText, "":
+		fsm.FormatSimulationReportText(os.Stdout, report)
+		return nil
+	case constants.EncodingJSON:
+		return trace.Wrap(fsm.FormatSimulationReportJSON(os.Stdout, report))
+	default:
+		return trace.BadParameter("unknown output format %q, must be text or json", format)
+	}
+}
+
 func explainPlan(phases []storage.OperationPhase) (err error) {
 	for _, phase := range phases {
 		if phase.State == storage.OperationPhaseStateFailed {
@@ -219,3 +259,12 @@ func tryReconcilePlan(ctx context.Context, localEnv, updateEnv *localenv.LocalEn
 }
 
 const recoveryModeWarning = "Failed to retrieve plan from etcd, showing cached plan. If etcd went down as a result of a system upgrade, you can perform a rollback phase. Run 'gravity plan --repair' when etcd connection is restored.\n"
+
+// displayPlanOptions controls how an operation plan is displayed
+type displayPlanOptions struct {
+	// format is the plan output format
+	format constants.Format
+	// timeline specifies whether to display the history of phase
+	// attempts in chronological order instead of the plan
+	timeline bool
+}
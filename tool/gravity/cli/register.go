@@ -113,6 +113,7 @@ func RegisterCommands(app *kingpin.Application) *Application {
 	g.JoinCmd.Phase = g.JoinCmd.Flag("phase", "Execute specific operation phase").String()
 	g.JoinCmd.PhaseTimeout = g.JoinCmd.Flag("timeout", "Phase execution timeout").Default(defaults.PhaseTimeout).Hidden().Duration()
 	g.JoinCmd.Resume = g.JoinCmd.Flag("resume", "Resume joining from last failed step").Bool()
+	g.JoinCmd.Parallel = g.JoinCmd.Flag("parallel", "Maximum number of phases to run concurrently on each server, 0 runs phases sequentially").Int()
 	g.JoinCmd.Force = g.JoinCmd.Flag("force", "Force phase execution").Bool()
 	g.JoinCmd.OperationID = g.JoinCmd.Flag("operation-id", "ID of the operation that was created via UI").Hidden().String()
 
@@ -139,11 +140,15 @@ func RegisterCommands(app *kingpin.Application) *Application {
 
 	g.PlanDisplayCmd.CmdClause = g.PlanCmd.Command("display", "Display a plan for an ongoing operation").Default()
 	g.PlanDisplayCmd.Output = common.Format(g.PlanDisplayCmd.Flag("output", "Output format for the plan, text, json or yaml").Short('o').Default(string(constants.EncodingText)))
+	g.PlanDisplayCmd.Timeline = g.PlanDisplayCmd.Flag("timeline", "Display all phase execution and rollback attempts in chronological order").Bool()
 
 	g.PlanExecuteCmd.CmdClause = g.PlanCmd.Command("execute", "Execute specified operation phase")
 	g.PlanExecuteCmd.Phase = g.PlanExecuteCmd.Flag("phase", "Phase ID to execute").String()
 	g.PlanExecuteCmd.Force = g.PlanExecuteCmd.Flag("force", "Force execution of specified phase").Bool()
 	g.PlanExecuteCmd.PhaseTimeout = g.PlanExecuteCmd.Flag("timeout", "Phase timeout").Default(defaults.PhaseTimeout).Hidden().Duration()
+	g.PlanExecuteCmd.DryRun = g.PlanExecuteCmd.Flag("dry-run", "Only report what the phase and its sub-phases would do without executing them").Bool()
+	g.PlanExecuteCmd.Output = common.Format(g.PlanExecuteCmd.Flag("output", "Output format of the dry run report, text or json").Short('o').Default(string(constants.EncodingText)))
+	g.PlanExecuteCmd.Parallel = g.PlanExecuteCmd.Flag("parallel", "Maximum number of phases to run concurrently on each server, 0 runs phases sequentially").Int()
 
 	g.PlanRollbackCmd.CmdClause = g.PlanCmd.Command("rollback", "Rollback specified operation phase")
 	g.PlanRollbackCmd.Phase = g.PlanRollbackCmd.Flag("phase", "Phase ID to execute").String()
@@ -153,6 +158,7 @@ func RegisterCommands(app *kingpin.Application) *Application {
 	g.PlanResumeCmd.CmdClause = g.PlanCmd.Command("resume", "Resume last aborted operation")
 	g.PlanResumeCmd.Force = g.PlanResumeCmd.Flag("force", "Force execution of specified phase").Bool()
 	g.PlanResumeCmd.PhaseTimeout = g.PlanResumeCmd.Flag("timeout", "Phase timeout").Default(defaults.PhaseTimeout).Hidden().Duration()
+	g.PlanResumeCmd.Parallel = g.PlanResumeCmd.Flag("parallel", "Maximum number of phases to run concurrently on each server, 0 runs phases sequentially").Int()
 
 	g.PlanCompleteCmd.CmdClause = g.PlanCmd.Command("complete", "Mark operation as completed")
 
@@ -184,6 +190,7 @@ func RegisterCommands(app *kingpin.Application) *Application {
 	g.UpgradeCmd.Timeout = g.UpgradeCmd.Flag("timeout", "Phase execution timeout").Default(defaults.PhaseTimeout).Hidden().Duration()
 	g.UpgradeCmd.Force = g.UpgradeCmd.Flag("force", "Force phase execution even if pre-conditions are not satisfied").Bool()
 	g.UpgradeCmd.Resume = g.UpgradeCmd.Flag("resume", "Resume upgrade from the last failed step").Bool()
+	g.UpgradeCmd.Parallel = g.UpgradeCmd.Flag("parallel", "Maximum number of phases to run concurrently on each server, 0 runs phases sequentially").Int()
 	g.UpgradeCmd.SkipVersionCheck = g.UpgradeCmd.Flag("skip-version-check", "Bypass version compatibility check").Hidden().Bool()
 
 	g.UpdateUploadCmd.CmdClause = g.UpdateCmd.Command("upload", "Upload update package to locally running site").Hidden()
@@ -207,6 +214,11 @@ func RegisterCommands(app *kingpin.Application) *Application {
 	g.StatusCmd.Seconds = g.StatusCmd.Flag("seconds", "Continuously display status every N seconds").Short('s').Int()
 	g.StatusCmd.Output = common.Format(g.StatusCmd.Flag("output", "output format: json or text").Default(string(constants.EncodingText)))
 
+	g.StatusClusterCmd.CmdClause = g.StatusCmd.Command("cluster", "Show the current status of the cluster").Default()
+
+	g.StatusHistoryCmd.CmdClause = g.StatusCmd.Command("history", "Show the cluster health history with per-node availability")
+	g.StatusHistoryCmd.Since = g.StatusHistoryCmd.Flag("since", "Show the history for the specified period, e.g. 24h").Default(defaults.StatusHistoryPeriod.String()).Duration()
+
 	// reset cluster state, for debugging/emergencies
 	g.StatusResetCmd.CmdClause = g.Command("status-reset", "Reset the cluster state to 'active'").Hidden()
 
@@ -510,6 +522,11 @@ func RegisterCommands(app *kingpin.Application) *Application {
 	// get cluster diagnostics report
 	g.ReportCmd.CmdClause = g.Command("report", "Generate cluster diagnostics report")
 	g.ReportCmd.FilePath = g.ReportCmd.Flag("file", "target report file name").Default("report.tar.gz").String()
+	g.ReportCmd.Since = g.ReportCmd.Flag("since", "Only collect logs for the specified period, e.g. 24h").Duration()
+	g.ReportCmd.Namespaces = g.ReportCmd.Flag("namespace", "Only collect kubernetes diagnostics for the specified namespace. Can be repeated").Strings()
+	g.ReportCmd.Nodes = g.ReportCmd.Flag("node", "Only collect diagnostics from the node with the specified hostname or address. Can be repeated").Strings()
+	g.ReportCmd.RedactPatterns = g.ReportCmd.Flag("redact", "Redact the values matching the specified regular expression in addition to keys, tokens and passwords. Can be repeated").Strings()
+	g.ReportCmd.Cluster = g.ReportCmd.Flag("cluster", "Collect diagnostics from all cluster nodes in parallel using the update agents").Bool()
 
 	// operations on sites
 	g.SiteCmd.CmdClause = g.Command("site", "operations on gravity sites")
@@ -633,9 +650,22 @@ func RegisterCommands(app *kingpin.Application) *Application {
 	g.SystemReportCmd.CmdClause = g.SystemCmd.Command("report", "collect system diagnostics and output as gzipped tarball to terminal").Hidden()
 	g.SystemReportCmd.Filter = g.SystemReportCmd.Flag("filter", "collect only specific diagnostics ('system', 'kubernetes'). Collect everything if unspecified").Strings()
 	g.SystemReportCmd.Compressed = g.SystemReportCmd.Flag("compressed", "whether to compress the tarball").Default("true").Bool()
+	g.SystemReportCmd.Since = g.SystemReportCmd.Flag("since", "only collect logs for the specified period").Duration()
+	g.SystemReportCmd.Namespaces = g.SystemReportCmd.Flag("namespace", "only collect kubernetes diagnostics for the specified namespace").Strings()
+	g.SystemReportCmd.EncodedRedactPatterns = g.SystemReportCmd.Flag("encoded-redact", "additional base64-encoded regular expression to redact").Strings()
 
 	g.SystemStateDirCmd.CmdClause = g.SystemCmd.Command("state-dir", "show where all gravity data is stored on the node").Hidden()
 
+	// local state backup and restore
+	g.SystemStateCmd.CmdClause = g.SystemCmd.Command("state", "operations on the local gravity state")
+	g.SystemStateBackupCmd.CmdClause = g.SystemStateCmd.Command("backup", "Back up the local state database and packages while gravity is running")
+	g.SystemStateBackupCmd.File = g.SystemStateBackupCmd.Arg("file", "Path of the resulting backup file").Required().String()
+	g.SystemStateRestoreCmd.CmdClause = g.SystemStateCmd.Command("restore", "Restore the local state database and packages from a backup, gravity should not be running on the node")
+	g.SystemStateRestoreCmd.File = g.SystemStateRestoreCmd.Arg("file", "Path of the backup file").Required().String()
+	g.SystemStateRestoreCmd.Confirmed = g.SystemStateRestoreCmd.Flag("confirm", "Do not ask for confirmation").Bool()
+	g.SystemStateVerifyCmd.CmdClause = g.SystemStateCmd.Command("verify", "Verify the integrity of the local state backup")
+	g.SystemStateVerifyCmd.File = g.SystemStateVerifyCmd.Arg("file", "Path of the backup file").Required().String()
+
 	// manage docker devicemapper environment
 	g.SystemDevicemapperCmd.CmdClause = g.SystemCmd.Command("devicemapper", "manage docker devicemapper environment").Hidden()
 	g.SystemDevicemapperMountCmd.CmdClause = g.SystemDevicemapperCmd.Command("mount", "configure devicemapper environment").Hidden()
@@ -652,13 +682,17 @@ func RegisterCommands(app *kingpin.Application) *Application {
 
 	g.SystemExportRuntimeJournalCmd.CmdClause = g.SystemCmd.Command("export-runtime-journal", "Export runtime journal logs to a file").Hidden()
 	g.SystemExportRuntimeJournalCmd.OutputFile = g.SystemExportRuntimeJournalCmd.Flag("output", "Name of resulting tarball. Output to stdout if unspecified").String()
+	g.SystemExportRuntimeJournalCmd.Since = g.SystemExportRuntimeJournalCmd.Flag("since", "Only export entries for the specified period").Duration()
 
 	g.SystemStreamRuntimeJournalCmd.CmdClause = g.SystemCmd.Command("stream-runtime-journal", "Stream runtime journal to stdout").Hidden()
+	g.SystemStreamRuntimeJournalCmd.Since = g.SystemStreamRuntimeJournalCmd.Flag("since", "Only stream entries for the specified period").Duration()
 
 	// pruning cluster resources
 	g.GarbageCollectCmd.CmdClause = g.Command("gc", "Prune cluster resources")
 	g.GarbageCollectCmd.Manual = g.GarbageCollectCmd.Flag("manual", "Do not start the operation automatically").Short('m').Bool()
 	g.GarbageCollectCmd.Confirmed = g.GarbageCollectCmd.Flag("confirm", "Confirm to remove unrelated docker images").Short('c').Bool()
+	g.GarbageCollectCmd.KeepOperations = g.GarbageCollectCmd.Flag("keep-operations", "Number of most recent operations to keep the data of").Default(strconv.Itoa(defaults.PruneKeepOperations)).Int()
+	g.GarbageCollectCmd.KeepPeriod = g.GarbageCollectCmd.Flag("keep-period", "Period of time to keep the operation data for").Default(defaults.PruneKeepPeriod.String()).Duration()
 
 	// system clean up tasks
 	systemGCCmd := g.SystemCmd.Command("gc", "Run system clean up tasks")
@@ -681,6 +715,16 @@ func RegisterCommands(app *kingpin.Application) *Application {
 	g.SystemGCRegistryCmd.Confirm = g.SystemGCRegistryCmd.Flag("confirm", "Confirm to remove unrelated docker").Bool()
 	g.SystemGCRegistryCmd.DryRun = g.SystemGCRegistryCmd.Flag("dry-run", "Only list docker images to remove w/o removing them").Bool()
 
+	g.SystemGCStateCmd.CmdClause = systemGCCmd.Command("state", "Prune stale operation data and leftover state on this node.")
+	g.SystemGCStateCmd.DryRun = g.SystemGCStateCmd.Flag("dry-run", "Only list state to remove w/o removing it").Bool()
+	g.SystemGCStateCmd.Cluster = g.SystemGCStateCmd.Flag("cluster", "Whether to prune stale operation data in the cluster state").Bool()
+	g.SystemGCStateCmd.KeepOperations = g.SystemGCStateCmd.Flag("keep-operations", "Number of most recent operations to keep the data of").Default(strconv.Itoa(defaults.PruneKeepOperations)).Int()
+	g.SystemGCStateCmd.KeepPeriod = g.SystemGCStateCmd.Flag("keep-period", "Period of time to keep the operation data for").Default(defaults.PruneKeepPeriod.String()).Duration()
+
+	systemBlobCmd := g.SystemCmd.Command("blob", "Operations on the cluster object storage.")
+	g.SystemBlobStatusCmd.CmdClause = systemBlobCmd.Command("status", "Show replication health of objects in the cluster object storage.")
+	g.SystemBlobStatusCmd.Output = common.Format(g.SystemBlobStatusCmd.Flag("output", "Output format: json or text").Short('o').Default(string(constants.EncodingText)))
+
 	// operations on planet (planet plugin)
 	g.PlanetCmd.CmdClause = g.Command("planet", "operations with planet").Hidden()
 
@@ -44,21 +44,38 @@ import (
 // to the stdout.
 // filters define the specific diagnostics to collect ('system', 'kubernetes'),
 // if empty all diagnostics are collected.
-func systemReport(env *localenv.LocalEnvironment, filters []string, compressed bool) error {
+// since and namespaces optionally limit the collected logs and kubernetes diagnostics.
+// The collected data is redacted of secrets and values matching the specified
+// base64-encoded patterns
+func systemReport(env *localenv.LocalEnvironment, filters []string, compressed bool,
+	since time.Duration, namespaces, encodedRedactPatterns []string) error {
+	redactPatterns, err := report.DecodeRedactPatterns(encodedRedactPatterns)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	redactor, err := report.NewRedactor(redactPatterns)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	filter := report.Filter{
+		Since:      since,
+		Namespaces: namespaces,
+	}
 	var collectors report.Collectors
 	ctx := context.Background()
-	for _, filter := range teleutils.Deduplicate(filters) {
-		switch filter {
+	for _, name := range teleutils.Deduplicate(filters) {
+		switch name {
 		case constants.ReportFilterSystem:
-			collectors = append(collectors, report.SystemInfo()...)
+			collectors = append(collectors, report.SystemInfo(filter)...)
 			collectors = append(collectors, packageCollector{env})
 		case constants.ReportFilterKubernetes:
-			collectors = append(collectors, report.KubernetesInfo(ctx, utils.Runner)...)
+			collectors = append(collectors, report.KubernetesInfo(ctx, utils.Runner, filter)...)
 		}
 	}
 	if len(filters) == 0 {
-		collectors = append(collectors, report.SystemInfo()...)
-		collectors = append(collectors, report.KubernetesInfo(ctx, utils.Runner)...)
+		collectors = append(collectors, report.SystemInfo(filter)...)
+		collectors = append(collectors, report.KubernetesInfo(ctx, utils.Runner, filter)...)
 		collectors = append(collectors, packageCollector{env})
 	}
 
@@ -68,12 +85,17 @@ func systemReport(env *localenv.LocalEnvironment, filters []string, compressed b
 	}
 	defer os.RemoveAll(dir)
 
-	rw := report.NewFileWriter(dir)
+	rw := redactor.Writer(report.NewFileWriter(dir))
 	err = collectors.Collect(ctx, rw, utils.Runner)
 	if err != nil {
 		log.Errorf("failed to collect some diagnostics: %v", trace.DebugReport(err))
 	}
 
+	err = redactor.WriteManifest(report.NewFileWriter(dir))
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
 	reader, writer := io.Pipe()
 
 	go func() {
@@ -38,6 +38,7 @@ import (
 	"github.com/gravitational/gravity/lib/schema"
 	"github.com/gravitational/gravity/lib/systemservice"
 	"github.com/gravitational/gravity/lib/utils"
+	prunestate "github.com/gravitational/gravity/lib/vacuum/prune/state"
 
 	"github.com/gravitational/configure/cstrings"
 	teleutils "github.com/gravitational/teleport/lib/utils"
@@ -190,6 +191,7 @@ func InitAndCheck(g *Application, cmd string) error {
 		g.RestoreCmd.FullCommand(),
 		g.GarbageCollectCmd.FullCommand(),
 		g.SystemGCRegistryCmd.FullCommand(),
+		g.SystemGCStateCmd.FullCommand(),
 		g.CheckCmd.FullCommand():
 		if err := checkRunningAsRoot(); err != nil {
 			return trace.Wrap(err)
@@ -233,6 +235,18 @@ func Execute(g *Application, cmd string, extraArgs []string) error {
 		return initCluster(*g.SiteInitCmd.ConfigPath, *g.SiteInitCmd.InitPath)
 	case g.SiteStatusCmd.FullCommand():
 		return statusSite()
+	case g.SystemStateVerifyCmd.FullCommand():
+		return verifyStateBackup(*g.SystemStateVerifyCmd.File)
+	case g.SystemStateRestoreCmd.FullCommand():
+		// restore does not use the local environment as the state
+		// it is restoring might be corrupted
+		stateDir, err := g.stateDir(cmd)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		return restoreState(stateDir,
+			*g.SystemStateRestoreCmd.File,
+			*g.SystemStateRestoreCmd.Confirmed)
 	}
 
 	localEnv, err := g.LocalEnv(cmd)
@@ -319,12 +333,7 @@ func Execute(g *Application, cmd string, extraArgs []string) error {
 			*g.JoinCmd.Phase = fsm.RootPhase
 		}
 		if *g.JoinCmd.Phase != "" {
-			return executeJoinPhase(localEnv, joinEnv, PhaseParams{
-				PhaseID:     *g.JoinCmd.Phase,
-				Force:       *g.JoinCmd.Force,
-				Timeout:     *g.JoinCmd.PhaseTimeout,
-				OperationID: *g.JoinCmd.OperationID,
-			}, nil)
+			return executeJoinPhase(localEnv, joinEnv, joinPhaseParams(g), nil)
 		}
 		return Join(localEnv, joinEnv, NewJoinConfig(g))
 	case g.AutoJoinCmd.FullCommand():
@@ -354,13 +363,7 @@ func Execute(g *Application, cmd string, extraArgs []string) error {
 			*g.UpgradeCmd.Phase = fsm.RootPhase
 		}
 		if *g.UpgradeCmd.Phase != "" {
-			return executePhase(localEnv, updateEnv, joinEnv,
-				PhaseParams{
-					PhaseID:          *g.UpgradeCmd.Phase,
-					Force:            *g.UpgradeCmd.Force,
-					Timeout:          *g.UpgradeCmd.Timeout,
-					SkipVersionCheck: *g.UpgradeCmd.SkipVersionCheck,
-				})
+			return executePhase(localEnv, updateEnv, joinEnv, upgradePhaseParams(g))
 		}
 		return updateTrigger(localEnv,
 			updateEnv,
@@ -370,23 +373,9 @@ func Execute(g *Application, cmd string, extraArgs []string) error {
 			*g.UpgradeCmd.SkipVersionCheck,
 		)
 	case g.PlanExecuteCmd.FullCommand():
-		return executePhase(localEnv, updateEnv, joinEnv,
-			PhaseParams{
-				PhaseID:          *g.PlanExecuteCmd.Phase,
-				Force:            *g.PlanExecuteCmd.Force,
-				Timeout:          *g.PlanExecuteCmd.PhaseTimeout,
-				SkipVersionCheck: *g.PlanCmd.SkipVersionCheck,
-				OperationID:      *g.PlanCmd.OperationID,
-			})
+		return executePhase(localEnv, updateEnv, joinEnv, planExecuteParams(g))
 	case g.PlanResumeCmd.FullCommand():
-		return executePhase(localEnv, updateEnv, joinEnv,
-			PhaseParams{
-				PhaseID:          fsm.RootPhase,
-				Force:            *g.PlanResumeCmd.Force,
-				Timeout:          *g.PlanResumeCmd.PhaseTimeout,
-				SkipVersionCheck: *g.PlanCmd.SkipVersionCheck,
-				OperationID:      *g.PlanCmd.OperationID,
-			})
+		return executePhase(localEnv, updateEnv, joinEnv, planResumeParams(g))
 	case g.PlanRollbackCmd.FullCommand():
 		return rollbackPhase(localEnv, updateEnv, joinEnv,
 			PhaseParams{
@@ -398,7 +387,10 @@ func Execute(g *Application, cmd string, extraArgs []string) error {
 			})
 	case g.PlanDisplayCmd.FullCommand():
 		return displayOperationPlan(localEnv, updateEnv, joinEnv,
-			*g.PlanCmd.OperationID, *g.PlanDisplayCmd.Output)
+			*g.PlanCmd.OperationID, displayPlanOptions{
+				format:   *g.PlanDisplayCmd.Output,
+				timeline: *g.PlanDisplayCmd.Timeline,
+			})
 	case g.PlanCompleteCmd.FullCommand():
 		return completeOperationPlan(localEnv, updateEnv, joinEnv, *g.PlanCmd.OperationID)
 	case g.LeaveCmd.FullCommand():
@@ -412,7 +404,7 @@ func Execute(g *Application, cmd string, extraArgs []string) error {
 			force:     *g.RemoveCmd.Force,
 			confirmed: *g.RemoveCmd.Confirm,
 		})
-	case g.StatusCmd.FullCommand():
+	case g.StatusClusterCmd.FullCommand():
 		printOptions := printOptions{
 			token:       *g.StatusCmd.Token,
 			operationID: *g.StatusCmd.OperationID,
@@ -427,6 +419,8 @@ func Execute(g *Application, cmd string, extraArgs []string) error {
 		} else {
 			return status(localEnv, printOptions)
 		}
+	case g.StatusHistoryCmd.FullCommand():
+		return statusHistory(localEnv, *g.StatusHistoryCmd.Since, *g.StatusCmd.Output)
 	case g.UpdateUploadCmd.FullCommand():
 		return uploadUpdate(localEnv, *g.UpdateUploadCmd.OpsCenterURL)
 	case g.AppPackageCmd.FullCommand():
@@ -672,7 +666,16 @@ func Execute(g *Application, cmd string, extraArgs []string) error {
 			*g.APIKeyDeleteCmd.Email,
 			*g.APIKeyDeleteCmd.Token)
 	case g.ReportCmd.FullCommand():
-		return getClusterReport(localEnv, *g.ReportCmd.FilePath)
+		filter := clusterReportFilter{
+			since:          *g.ReportCmd.Since,
+			namespaces:     *g.ReportCmd.Namespaces,
+			nodes:          *g.ReportCmd.Nodes,
+			redactPatterns: *g.ReportCmd.RedactPatterns,
+		}
+		if *g.ReportCmd.Cluster {
+			return clusterReport(localEnv, *g.ReportCmd.FilePath, filter)
+		}
+		return getClusterReport(localEnv, *g.ReportCmd.FilePath, filter)
 	// cluster commands
 	case g.SiteListCmd.FullCommand():
 		return listSites(localEnv, *g.SiteListCmd.OpsCenterURL)
@@ -774,19 +777,29 @@ func Execute(g *Application, cmd string, extraArgs []string) error {
 	case g.SystemReportCmd.FullCommand():
 		return systemReport(localEnv,
 			*g.SystemReportCmd.Filter,
-			*g.SystemReportCmd.Compressed)
+			*g.SystemReportCmd.Compressed,
+			*g.SystemReportCmd.Since,
+			*g.SystemReportCmd.Namespaces,
+			*g.SystemReportCmd.EncodedRedactPatterns)
 	case g.SystemStateDirCmd.FullCommand():
 		return printStateDir()
+	case g.SystemStateBackupCmd.FullCommand():
+		return backupState(localEnv, *g.SystemStateBackupCmd.File)
 	case g.SystemEnablePromiscModeCmd.FullCommand():
 		return enablePromiscMode(localEnv, *g.SystemEnablePromiscModeCmd.Iface)
 	case g.SystemDisablePromiscModeCmd.FullCommand():
 		return disablePromiscMode(localEnv, *g.SystemDisablePromiscModeCmd.Iface)
 	case g.SystemExportRuntimeJournalCmd.FullCommand():
-		return exportRuntimeJournal(localEnv, *g.SystemExportRuntimeJournalCmd.OutputFile)
+		return exportRuntimeJournal(localEnv, *g.SystemExportRuntimeJournalCmd.OutputFile,
+			*g.SystemExportRuntimeJournalCmd.Since)
 	case g.SystemStreamRuntimeJournalCmd.FullCommand():
-		return streamRuntimeJournal(localEnv)
+		return streamRuntimeJournal(localEnv, *g.SystemStreamRuntimeJournalCmd.Since)
 	case g.GarbageCollectCmd.FullCommand():
-		return garbageCollect(localEnv, *g.GarbageCollectCmd.Manual, *g.GarbageCollectCmd.Confirmed)
+		return garbageCollect(localEnv, *g.GarbageCollectCmd.Manual, *g.GarbageCollectCmd.Confirmed,
+			prunestate.Retention{
+				Operations: *g.GarbageCollectCmd.KeepOperations,
+				Period:     *g.GarbageCollectCmd.KeepPeriod,
+			})
 	case g.SystemGCJournalCmd.FullCommand():
 		return removeUnusedJournalFiles(localEnv,
 			*g.SystemGCJournalCmd.MachineIDFile,
@@ -799,6 +812,16 @@ func Execute(g *Application, cmd string, extraArgs []string) error {
 		return removeUnusedImages(localEnv,
 			*g.SystemGCRegistryCmd.DryRun,
 			*g.SystemGCRegistryCmd.Confirm)
+	case g.SystemGCStateCmd.FullCommand():
+		return removeStaleState(localEnv,
+			*g.SystemGCStateCmd.DryRun,
+			*g.SystemGCStateCmd.Cluster,
+			prunestate.Retention{
+				Operations: *g.SystemGCStateCmd.KeepOperations,
+				Period:     *g.SystemGCStateCmd.KeepPeriod,
+			})
+	case g.SystemBlobStatusCmd.FullCommand():
+		return blobStatus(localEnv, *g.SystemBlobStatusCmd.Output)
 	case g.PlanetEnterCmd.FullCommand(), g.EnterCmd.FullCommand():
 		return planetEnter(localEnv, extraArgs)
 	case g.ExecCmd.FullCommand():
@@ -122,7 +122,15 @@ func listSites(env *localenv.LocalEnvironment, opsCenterURL string) error {
 	return nil
 }
 
-func getClusterReport(env *localenv.LocalEnvironment, targetFile string) error {
+// clusterReportFilter limits the diagnostics collected in the cluster report
+type clusterReportFilter struct {
+	since          time.Duration
+	namespaces     []string
+	nodes          []string
+	redactPatterns []string
+}
+
+func getClusterReport(env *localenv.LocalEnvironment, targetFile string, filter clusterReportFilter) error {
 	f, err := os.Create(targetFile)
 	if err != nil {
 		return trace.Wrap(err)
@@ -139,9 +147,12 @@ func getClusterReport(env *localenv.LocalEnvironment, targetFile string) error {
 		return trace.Wrap(err)
 	}
 
-	report, err := operator.GetSiteReport(ops.SiteKey{
-		AccountID:  site.AccountID,
-		SiteDomain: site.Domain,
+	report, err := operator.GetSiteReport(ops.GetClusterReportRequest{
+		SiteKey:        site.Key(),
+		Since:          filter.since,
+		Namespaces:     filter.namespaces,
+		Nodes:          filter.nodes,
+		RedactPatterns: filter.redactPatterns,
 	})
 	if err != nil {
 		return trace.Wrap(err)
@@ -0,0 +1,125 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/gravitational/gravity/lib/constants"
+	"github.com/gravitational/gravity/lib/defaults"
+	"github.com/gravitational/gravity/lib/localenv"
+	systemstate "github.com/gravitational/gravity/lib/system/state"
+
+	"github.com/gravitational/trace"
+)
+
+// backupState writes the backup of the local state to the specified file
+func backupState(env *localenv.LocalEnvironment, path string) error {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	// write the backup into a temporary file next to the target
+	// so an incomplete backup never replaces an existing one
+	f, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path))
+	if err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	defer func() {
+		f.Close()
+		os.Remove(f.Name())
+	}()
+	env.PrintStep("Backing up state directory %v", env.StateDir)
+	manifest, err := systemstate.Backup(systemstate.BackupConfig{
+		Backend:     env.Backend,
+		BackendType: env.BackendType,
+		Objects:     env.Objects,
+	}, f)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if err := f.Sync(); err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	env.PrintStep("Verifying backup")
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	if _, err := systemstate.Verify(f, ""); err != nil {
+		return trace.Wrap(err, "backup failed verification")
+	}
+	if err := os.Chmod(f.Name(), defaults.PrivateFileMask); err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	if err := os.Rename(f.Name(), path); err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	env.Printf("Backup with state database and %v packages written to %v.\n",
+		len(manifest.BLOBs), path)
+	return nil
+}
+
+// restoreState restores the local state in stateDir from the specified backup file
+func restoreState(stateDir, path string, confirmed bool) error {
+	if !confirmed {
+		fmt.Printf("This will replace the state database in %v with the one from %v. "+
+			"Make sure gravity is not running on this node. Proceed?\n", stateDir, path)
+		resp, err := confirm()
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		if !resp {
+			fmt.Println("Action cancelled by user.")
+			return nil
+		}
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	defer f.Close()
+	manifest, err := systemstate.Restore(systemstate.RestoreConfig{
+		StateDir: stateDir,
+	}, f)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	fmt.Printf("Restored state directory %v from backup of %v created at %v.\n",
+		stateDir, manifest.Hostname, manifest.Created.Format(constants.HumanDateFormatSeconds))
+	return nil
+}
+
+// verifyStateBackup verifies the integrity of the specified local state backup
+func verifyStateBackup(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	defer f.Close()
+	manifest, err := systemstate.Verify(f, "")
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	fmt.Printf("Backup of %v created at %v is valid: %v database, %v packages.\n",
+		manifest.Hostname, manifest.Created.Format(constants.HumanDateFormatSeconds),
+		manifest.Backend, len(manifest.BLOBs))
+	return nil
+}
@@ -0,0 +1,130 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/gravitational/gravity/lib/constants"
+	"github.com/gravitational/gravity/lib/localenv"
+	statusapi "github.com/gravitational/gravity/lib/status"
+	"github.com/gravitational/gravity/lib/storage"
+
+	"github.com/fatih/color"
+	"github.com/gravitational/trace"
+)
+
+// statusHistory displays the cluster health history for the specified period
+func statusHistory(env *localenv.LocalEnvironment, since time.Duration, format constants.Format) error {
+	if since <= 0 {
+		return trace.BadParameter("--since should be positive")
+	}
+	clusterEnv, err := env.NewClusterEnvironment()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	cluster, err := clusterEnv.Operator.GetLocalSite()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	transitions, err := clusterEnv.Backend.GetStatusTransitions(cluster.Domain)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	now := clusterEnv.Backend.Now().UTC()
+	history := statusapi.NewHistory(transitions, now.Add(-since), now)
+	switch format {
+	case constants.EncodingJSON:
+		bytes, err := json.MarshalIndent(history, "", "    ")
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		fmt.Println(string(bytes))
+	case constants.EncodingText:
+		printStatusHistory(history, os.Stdout)
+	default:
+		return trace.BadParameter("unsupported output format %q", format)
+	}
+	return nil
+}
+
+func printStatusHistory(history statusapi.History, out io.Writer) {
+	w := tabwriter.NewWriter(out, 0, 8, 1, '\t', 0)
+	fmt.Fprintf(w, "Cluster health since %v (%v):\n",
+		history.Since.Format(constants.HumanDateFormatSeconds),
+		history.Until.Sub(history.Since))
+	if history.Cluster == nil && len(history.Nodes) == 0 {
+		fmt.Fprintln(w, color.YellowString("No health samples recorded for the period"))
+		w.Flush()
+		return
+	}
+	if history.Cluster != nil {
+		fmt.Fprintf(w, "Cluster availability:\t%v\n", formatAvailability(*history.Cluster))
+	}
+	if len(history.Nodes) != 0 {
+		fmt.Fprintln(w, "Node availability:")
+		for _, node := range history.Nodes {
+			fmt.Fprintf(w, "    * %v (%v)\t%v\n", unknownFallback(node.Hostname),
+				node.Node, formatAvailability(node))
+		}
+	}
+	if len(history.Transitions) != 0 {
+		fmt.Fprintln(w, "Transitions:")
+		for _, transition := range history.Transitions {
+			fmt.Fprintf(w, "    %v\t%v\t%v -> %v\t%v\n",
+				transition.Time.Format(constants.HumanDateFormatSeconds),
+				formatSubject(transition),
+				unknownFallback(transition.Previous),
+				colorStatus(transition.Status),
+				transition.Detail)
+		}
+	}
+	w.Flush()
+}
+
+func formatAvailability(availability statusapi.Availability) string {
+	return fmt.Sprintf("%.2f%% of %v observed, currently %v",
+		availability.Percentage, availability.Observed.Round(time.Second),
+		colorStatus(availability.Status))
+}
+
+func formatSubject(transition storage.StatusTransition) string {
+	subject := "cluster"
+	if transition.Node != "" {
+		subject = fmt.Sprintf("%v (%v)", unknownFallback(transition.Hostname), transition.Node)
+	}
+	if transition.Probe != "" {
+		subject = fmt.Sprintf("%v probe %v", subject, transition.Probe)
+	}
+	return subject
+}
+
+func colorStatus(status string) string {
+	switch status {
+	case statusapi.NodeHealthy:
+		return color.GreenString(status)
+	case statusapi.NodeOffline:
+		return color.YellowString(status)
+	default:
+		return color.RedString(status)
+	}
+}
@@ -44,6 +44,8 @@ type BuildParameters struct {
 	Silent bool
 	// Insecure turns on insecure verify mode
 	Insecure bool
+	// Hub is the URL of the hub to download runtimes from
+	Hub string
 }
 
 // build builds an installer tarball according to the provided parameters
@@ -56,6 +58,7 @@ func build(ctx context.Context, params BuildParameters, req service.VendorReques
 		OutPath:          params.OutPath,
 		Overwrite:        params.Overwrite,
 		Repository:       params.Repository,
+		Hub:              params.Hub,
 		SkipVersionCheck: params.SkipVersionCheck,
 		VendorReq:        req,
 		Progress:         utils.NewProgress(ctx, "Build", 6, params.Silent),
@@ -33,6 +33,8 @@ type Application struct {
 	Insecure *bool
 	// StateDir is the local state directory
 	StateDir *string
+	// Hub is the URL of the hub to use instead of the default one
+	Hub *string
 	// VersionCmd outputs the binary version
 	VersionCmd VersionCmd
 	// BuildCmd builds app installer tarball
@@ -41,6 +43,8 @@ type Application struct {
 	ListCmd ListCmd
 	// PullCmd downloads app installer from Ops Center
 	PullCmd PullCmd
+	// PublishCmd publishes app installers into a hub directory
+	PublishCmd PublishCmd
 }
 
 // VersionCmd outputs the binary version
@@ -103,3 +107,12 @@ type PullCmd struct {
 	// Quiet allows to suppress console output
 	Quiet *bool
 }
+
+// PublishCmd publishes app installers into a hub directory
+type PublishCmd struct {
+	*kingpin.CmdClause
+	// Paths lists installer tarballs to publish
+	Paths *[]string
+	// Dir is the hub directory
+	Dir *string
+}
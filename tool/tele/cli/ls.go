@@ -19,17 +19,18 @@ package cli
 import (
 	"github.com/gravitational/gravity/lib/catalog"
 	"github.com/gravitational/gravity/lib/constants"
+	"github.com/gravitational/gravity/lib/hub"
 	"github.com/gravitational/gravity/lib/localenv"
 
 	"github.com/gravitational/trace"
 )
 
-func list(env localenv.LocalEnvironment, all bool, format constants.Format) error {
-	lister, err := catalog.NewLister()
+func list(env localenv.LocalEnvironment, hubURL string, all bool, format constants.Format) error {
+	hub, err := hub.NewForURL(hubURL, env.Insecure)
 	if err != nil {
 		return trace.Wrap(err)
 	}
-	err = catalog.List(lister, all, format)
+	err = catalog.List(catalog.NewHubLister(hub), all, format)
 	if err != nil {
 		return trace.Wrap(err)
 	}
@@ -0,0 +1,41 @@
+/*
+Copyright 2019 Gravitational, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"fmt"
+
+	"github.com/gravitational/gravity/lib/hub"
+
+	"github.com/gravitational/trace"
+)
+
+// publish publishes the installer tarballs at the specified paths into
+// the hub directory dir and updates the hub index
+func publish(dir string, paths []string) error {
+	for _, path := range paths {
+		app, err := hub.Publish(hub.PublishConfig{
+			Dir:  dir,
+			Path: path,
+		})
+		if err != nil {
+			return trace.Wrap(err, "failed to publish %v", path)
+		}
+		fmt.Printf("Published %v:%v into %v.\n", app.Name, app.Version, dir)
+	}
+	return nil
+}
@@ -36,6 +36,7 @@ func RegisterCommands(app *kingpin.Application) Application {
 	tele.Debug = app.Flag("debug", "Enable debug mode").Bool()
 	tele.Insecure = app.Flag("insecure", "Skip TLS verification when making HTTP requests").Default("false").Bool()
 	tele.StateDir = app.Flag("state-dir", "Directory for temporary local state").Hidden().String()
+	tele.Hub = app.Flag("hub", "URL of the hub to use instead of the default one: a directory path, file:// or http(s):// URL of a static server").String()
 
 	tele.VersionCmd.CmdClause = app.Command("version", "Print version and exit")
 	tele.VersionCmd.Output = common.Format(tele.VersionCmd.Flag("output", "Output format, text or json").Short('o').Default(string(constants.EncodingText)))
@@ -66,5 +67,9 @@ func RegisterCommands(app *kingpin.Application) Application {
 	tele.PullCmd.Force = tele.PullCmd.Flag("force", "Overwrite existing tarball").Short('f').Bool()
 	tele.PullCmd.Quiet = tele.PullCmd.Flag("quiet", "Suppress any extra output to stdout").Short('q').Bool()
 
+	tele.PublishCmd.CmdClause = app.Command("publish", "Publish application installers into a hub directory and update its index")
+	tele.PublishCmd.Paths = tele.PublishCmd.Arg("path", "Paths to the installer tarballs to publish").Required().Strings()
+	tele.PublishCmd.Dir = tele.PublishCmd.Flag("dir", "Hub directory to publish into, can be served by any static HTTP server").Required().String()
+
 	return tele
 }
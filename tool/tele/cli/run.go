@@ -59,6 +59,7 @@ func Run(tele Application) error {
 			SkipVersionCheck: *tele.BuildCmd.SkipVersionCheck,
 			Silent:           *tele.BuildCmd.Quiet,
 			Insecure:         *tele.Insecure,
+			Hub:              *tele.Hub,
 		}, service.VendorRequest{
 			PackageName:            *tele.BuildCmd.Name,
 			PackageVersion:         *tele.BuildCmd.Version,
@@ -69,6 +70,8 @@ func Run(tele Application) error {
 			Parallel:               *tele.BuildCmd.Parallel,
 			VendorRuntime:          true,
 		})
+	case tele.PublishCmd.FullCommand():
+		return publish(*tele.PublishCmd.Dir, *tele.PublishCmd.Paths)
 	}
 
 	keystoreDir := *tele.StateDir
@@ -94,12 +97,14 @@ func Run(tele Application) error {
 	switch cmd {
 	case tele.PullCmd.FullCommand():
 		return pull(*env,
+			*tele.Hub,
 			*tele.PullCmd.App,
 			*tele.PullCmd.OutFile,
 			*tele.PullCmd.Force,
 			*tele.PullCmd.Quiet)
 	case tele.ListCmd.FullCommand():
 		return list(*env,
+			*tele.Hub,
 			*tele.ListCmd.All,
 			*tele.ListCmd.Format)
 	}